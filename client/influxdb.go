@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"net"
 	"net/http"
 	"net/url"
@@ -426,7 +427,7 @@ func (r Response) Error() error {
 // Point defines the fields that will be written to the database
 // Measurement, Time, and Fields are required
 // Precision can be specified if the time is in epoch format (integer).
-// Valid values for Precision are n, u, ms, s, m, and h
+// Valid values for Precision are n (or ns), u (or us), ms, s, m, and h
 type Point struct {
 	Measurement string
 	Tags        map[string]string
@@ -546,7 +547,7 @@ func normalizeFields(fields map[string]interface{}) map[string]interface{} {
 // If tags are specified, they will be "merged" with all points.  If a point already has that tag, it is ignored.
 // If time is specified, it will be applied to any point with an empty time.
 // Precision can be specified if the time is in epoch format (integer).
-// Valid values for Precision are n, u, ms, s, m, and h
+// Valid values for Precision are n (or ns), u (or us), ms, s, m, and h
 type BatchPoints struct {
 	Points           []Point           `json:"points,omitempty"`
 	Database         string            `json:"database,omitempty"`
@@ -628,40 +629,20 @@ func EpochToTime(epoch int64, precision string) (time.Time, error) {
 	if precision == "" {
 		precision = "s"
 	}
-	var t time.Time
-	switch precision {
-	case "h":
-		t = time.Unix(0, epoch*int64(time.Hour))
-	case "m":
-		t = time.Unix(0, epoch*int64(time.Minute))
-	case "s":
-		t = time.Unix(0, epoch*int64(time.Second))
-	case "ms":
-		t = time.Unix(0, epoch*int64(time.Millisecond))
-	case "u":
-		t = time.Unix(0, epoch*int64(time.Microsecond))
-	case "n":
-		t = time.Unix(0, epoch)
-	default:
+	d, err := tsdb.ParsePrecision(precision)
+	if err != nil {
 		return time.Time{}, fmt.Errorf("Unknown precision %q", precision)
 	}
-	return t, nil
+	if epoch > math.MaxInt64/int64(d) || epoch < math.MinInt64/int64(d) {
+		return time.Time{}, tsdb.ErrTimestampOutOfRange
+	}
+	return time.Unix(0, epoch*int64(d)), nil
 }
 
 // SetPrecision will round a time to the specified precision
 func SetPrecision(t time.Time, precision string) time.Time {
-	switch precision {
-	case "n":
-	case "u":
-		return t.Round(time.Microsecond)
-	case "ms":
-		return t.Round(time.Millisecond)
-	case "s":
-		return t.Round(time.Second)
-	case "m":
-		return t.Round(time.Minute)
-	case "h":
-		return t.Round(time.Hour)
+	if d, err := tsdb.ParsePrecision(precision); err == nil && d > time.Nanosecond {
+		return t.Round(d)
 	}
 	return t
 }
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/influxdb/influxdb/client"
+	"github.com/influxdb/influxdb/tsdb"
 )
 
 func BenchmarkUnmarshalJSON2Tags(b *testing.B) {
@@ -435,6 +436,8 @@ func TestEpochToTime(t *testing.T) {
 		{name: "seconds", epoch: now.Round(time.Second).UnixNano() / int64(time.Second), precision: "s", expected: now.Round(time.Second)},
 		{name: "minutes", epoch: now.Round(time.Minute).UnixNano() / int64(time.Minute), precision: "m", expected: now.Round(time.Minute)},
 		{name: "hours", epoch: now.Round(time.Hour).UnixNano() / int64(time.Hour), precision: "h", expected: now.Round(time.Hour)},
+		{name: "nanoseconds (ns)", epoch: now.UnixNano(), precision: "ns", expected: now},
+		{name: "microseconds (us)", epoch: now.Round(time.Microsecond).UnixNano() / int64(time.Microsecond), precision: "us", expected: now.Round(time.Microsecond)},
 	}
 
 	for _, test := range tests {
@@ -449,6 +452,14 @@ func TestEpochToTime(t *testing.T) {
 	}
 }
 
+func TestEpochToTime_OutOfRange(t *testing.T) {
+	if _, err := client.EpochToTime(1<<40, "s"); err != tsdb.ErrTimestampOutOfRange {
+		t.Fatalf("unexpected error: %v", err)
+	} else if _, err := client.EpochToTime(1, "d"); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
 // helper functions
 
 func emptyTestServer() *httptest.Server {
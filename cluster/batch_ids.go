@@ -0,0 +1,87 @@
+package cluster
+
+import (
+	"sync"
+	"time"
+)
+
+// batchIDCache remembers the IDs of recently written batches by database.
+// The zero value is ready to use.
+type batchIDCache struct {
+	mu  sync.Mutex
+	dbs map[string]*batchIDs
+}
+
+// batchIDs holds the IDs of the batches written to a database, along with
+// the IDs in the order they were written so the oldest can be expired, and
+// the IDs of the batches still being written.
+type batchIDs struct {
+	written map[string]time.Time
+	order   []string
+	pending map[string]bool
+}
+
+// reserve marks the batch id as being written to database, unless it was
+// written within ttl of now. Returns false if it was, or ErrBatchInProgress
+// if another write of the batch hasn't finished. A reserved id must be added
+// once written, or released if the write fails.
+func (c *batchIDCache) reserve(database, id string, ttl time.Duration, now time.Time) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ids := c.ids(database)
+	if t, ok := ids.written[id]; ok && now.Sub(t) < ttl {
+		return false, nil
+	} else if ids.pending[id] {
+		return false, ErrBatchInProgress
+	}
+	ids.pending[id] = true
+	return true, nil
+}
+
+// release removes the reservation of a batch id whose write failed, so the
+// batch can be retried.
+func (c *batchIDCache) release(database, id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.ids(database).pending, id)
+}
+
+// ids returns the IDs of database, creating them if needed. The lock must be
+// held.
+func (c *batchIDCache) ids(database string) *batchIDs {
+	if c.dbs == nil {
+		c.dbs = make(map[string]*batchIDs)
+	}
+	ids := c.dbs[database]
+	if ids == nil {
+		ids = &batchIDs{written: make(map[string]time.Time), pending: make(map[string]bool)}
+		c.dbs[database] = ids
+	}
+	return ids
+}
+
+// add records that the reserved batch id was written to database at now.
+// IDs older than ttl are expired, as are the oldest IDs past max, if max is
+// positive.
+func (c *batchIDCache) add(database, id string, ttl time.Duration, max int, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ids := c.ids(database)
+	delete(ids.pending, id)
+	if _, ok := ids.written[id]; !ok {
+		ids.order = append(ids.order, id)
+	}
+	ids.written[id] = now
+
+	// Expire from the oldest.
+	for len(ids.order) > 0 {
+		oldest := ids.order[0]
+		if now.Sub(ids.written[oldest]) < ttl && (max <= 0 || len(ids.order) <= max) {
+			break
+		}
+		delete(ids.written, oldest)
+		ids.order = ids.order[1:]
+	}
+}
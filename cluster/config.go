@@ -1,6 +1,8 @@
 package cluster
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/influxdb/influxdb/toml"
@@ -15,6 +17,44 @@ const (
 
 	// DefaultShardMapperTimeout is the default timeout set on shard mappers.
 	DefaultShardMapperTimeout = 5 * time.Second
+
+	// DefaultShardMapperWindowSize is the default number of chunks a node
+	// may stream to a remote shard mapper before they are acknowledged.
+	DefaultShardMapperWindowSize = 16
+
+	// DefaultCoalesceMaxPoints is the default number of points after which
+	// a coalesced write is sent.
+	DefaultCoalesceMaxPoints = 1000
+
+	// DefaultCoalesceMaxDelay is the default time a write waits to be
+	// coalesced with others.
+	DefaultCoalesceMaxDelay = 10 * time.Millisecond
+
+	// DefaultBatchIDTTL is the default time the IDs of written batches are remembered.
+	DefaultBatchIDTTL = 10 * time.Minute
+
+	// DefaultMaxBatchIDs is the default number of batch IDs remembered per database.
+	DefaultMaxBatchIDs = 100000
+
+	// DefaultWriteRetries is the default number of times a failed remote
+	// write is retried.
+	DefaultWriteRetries = 2
+
+	// DefaultWriteRetryInterval is the default wait before the first retry
+	// of a failed remote write.
+	DefaultWriteRetryInterval = 100 * time.Millisecond
+
+	// DefaultBreakerFailures is the default number of consecutive failed
+	// writes to a node after which writes stop being sent to it.
+	DefaultBreakerFailures = 5
+
+	// DefaultBreakerCooldown is the default time writes aren't sent to a
+	// node once its breaker opens.
+	DefaultBreakerCooldown = 10 * time.Second
+
+	// DefaultDedupMaxPoints is the default number of points remembered per
+	// database with a dedup window.
+	DefaultDedupMaxPoints = 1000000
 )
 
 // Config represents the configuration for the clustering service.
@@ -23,13 +63,210 @@ type Config struct {
 	WriteTimeout            toml.Duration `toml:"write-timeout"`
 	ShardWriterTimeout      toml.Duration `toml:"shard-writer-timeout"`
 	ShardMapperTimeout      toml.Duration `toml:"shard-mapper-timeout"`
+	ShardMapperWindowSize   int           `toml:"shard-mapper-window-size"`
+	ShardMapperCompression  bool          `toml:"shard-mapper-compression"`
+
+	// Points with a time further in the future or past than these durations
+	// are dropped from writes. Zero disables the bound.
+	MaxFutureTime toml.Duration `toml:"max-future-time"`
+	MaxPastTime   toml.Duration `toml:"max-past-time"`
+
+	// RejectBeyondRetention drops points older than the duration of the
+	// retention policy they're written to.
+	RejectBeyondRetention bool `toml:"reject-beyond-retention"`
+
+	// CoalesceWrites merges small concurrent HTTP writes into larger batches
+	// of up to CoalesceMaxPoints points, delaying each write by at most
+	// CoalesceMaxDelay.
+	CoalesceWrites    bool          `toml:"coalesce-writes"`
+	CoalesceMaxPoints int           `toml:"coalesce-max-points"`
+	CoalesceMaxDelay  toml.Duration `toml:"coalesce-max-delay"`
+
+	// Writes with a batch ID are ignored if a write with the same ID to the
+	// same database succeeded within BatchIDTTL. At most MaxBatchIDs IDs are
+	// remembered per database. A zero BatchIDTTL disables the check.
+	BatchIDTTL  toml.Duration `toml:"batch-id-ttl"`
+	MaxBatchIDs int           `toml:"max-batch-ids"`
+
+	// Remote writes failing with a retryable error are retried up to
+	// WriteRetries times, as long as the write doesn't time out. Retries wait
+	// WriteRetryInterval, doubled after each attempt, with jitter.
+	WriteRetries       int           `toml:"write-retries"`
+	WriteRetryInterval toml.Duration `toml:"write-retry-interval"`
+
+	// Writes aren't sent to a node for BreakerCooldown after BreakerFailures
+	// consecutive failed writes to it, and go to hinted handoff instead.
+	// Zero BreakerFailures disables the breakers.
+	BreakerFailures int           `toml:"breaker-failures"`
+	BreakerCooldown toml.Duration `toml:"breaker-cooldown"`
+
+	// Routes direct the points of measurements written without a retention
+	// policy to a retention policy other than the database default.
+	Routes []Route `toml:"route"`
+
+	// DatabaseLimits limit the resources used by databases so one database
+	// can't degrade the others on a shared server.
+	DatabaseLimits []DatabaseLimit `toml:"database-limit"`
+
+	// DedupWindows drop points identical to points written to the same
+	// database shortly before, to absorb the duplicates of at-least-once
+	// writers.
+	DedupWindows []DedupWindow `toml:"dedup-window"`
+
+	// Transforms change the points written to a database before they're
+	// checked against its schema and routed, for installations that can't
+	// change every writer.
+	Transforms []Transform `toml:"transform"`
+
+	// SELECT statements returning more than MaxSelectPointN points or
+	// MaxSelectSeriesN series fail, or are truncated at the limit and marked
+	// partial if TruncateSelect is set. Zero is unlimited.
+	MaxSelectPointN  int  `toml:"max-select-point"`
+	MaxSelectSeriesN int  `toml:"max-select-series"`
+	TruncateSelect   bool `toml:"truncate-select"`
+
+	// At most MaxConcurrentSelects SELECT statements run at once. The others
+	// wait, and those of interactive queries run before those of batch
+	// queries. Zero is unlimited.
+	MaxConcurrentSelects int `toml:"max-concurrent-selects"`
+}
+
+// Route represents a rule routing measurements to a retention policy.
+type Route struct {
+	Database        string   `toml:"database"`
+	Measurements    []string `toml:"measurements"`
+	RetentionPolicy string   `toml:"retention-policy"`
+}
+
+// DatabaseLimit represents the resources a database may use. Zero is unlimited.
+type DatabaseLimit struct {
+	Database string `toml:"database"`
+
+	// Points written per second. Up to a second's worth may be written at once.
+	MaxPointsPerSecond int `toml:"max-points-per-second"`
+
+	// Statements run against the database at once.
+	MaxQueries int `toml:"max-queries"`
+
+	// Estimated bytes of points read by the running SELECT statements.
+	MaxQueryMemory int64 `toml:"max-query-memory"`
+}
+
+// DedupWindow represents the window in which points identical to a point
+// written to a database, with the same series, time and fields, are dropped.
+type DedupWindow struct {
+	Database string        `toml:"database"`
+	Window   toml.Duration `toml:"window"`
+
+	// Points remembered. The oldest are forgotten first. Zero uses
+	// DefaultDedupMaxPoints.
+	MaxPoints int `toml:"max-points"`
+}
+
+// Transform represents rules applied in order to the points written to the
+// measurements of a database. A transform without measurements applies to all
+// of them. Rules are "drop-field <field>", "add-tag <key>=<value>", which
+// replaces the tag if the point has it, and "rename <measurement>".
+type Transform struct {
+	Database     string   `toml:"database"`
+	Measurements []string `toml:"measurements"`
+	Rules        []string `toml:"rules"`
 }
 
 // NewConfig returns an instance of Config with defaults.
 func NewConfig() Config {
 	return Config{
-		WriteTimeout:       toml.Duration(DefaultWriteTimeout),
-		ShardWriterTimeout: toml.Duration(DefaultShardWriterTimeout),
-		ShardMapperTimeout: toml.Duration(DefaultShardMapperTimeout),
+		WriteTimeout:           toml.Duration(DefaultWriteTimeout),
+		ShardWriterTimeout:     toml.Duration(DefaultShardWriterTimeout),
+		ShardMapperTimeout:     toml.Duration(DefaultShardMapperTimeout),
+		ShardMapperWindowSize:  DefaultShardMapperWindowSize,
+		ShardMapperCompression: true,
+		CoalesceMaxPoints:      DefaultCoalesceMaxPoints,
+		CoalesceMaxDelay:       toml.Duration(DefaultCoalesceMaxDelay),
+		BatchIDTTL:             toml.Duration(DefaultBatchIDTTL),
+		MaxBatchIDs:            DefaultMaxBatchIDs,
+		WriteRetries:           DefaultWriteRetries,
+		WriteRetryInterval:     toml.Duration(DefaultWriteRetryInterval),
+		BreakerFailures:        DefaultBreakerFailures,
+		BreakerCooldown:        toml.Duration(DefaultBreakerCooldown),
+	}
+}
+
+// Validate returns an error if the config is invalid.
+func (c Config) Validate() error {
+	if c.CoalesceWrites {
+		if c.CoalesceMaxPoints <= 0 {
+			return errors.New("coalesce-max-points must be greater than zero")
+		} else if c.CoalesceMaxDelay <= 0 {
+			return errors.New("coalesce-max-delay must be greater than zero")
+		}
+	}
+
+	if c.WriteRetries < 0 {
+		return errors.New("write-retries must not be negative")
+	} else if c.WriteRetries > 0 && c.WriteRetryInterval <= 0 {
+		return errors.New("write-retry-interval must be greater than zero")
+	} else if c.BreakerFailures < 0 {
+		return errors.New("breaker-failures must not be negative")
+	} else if c.BreakerFailures > 0 && c.BreakerCooldown <= 0 {
+		return errors.New("breaker-cooldown must be greater than zero")
+	}
+
+	if c.MaxSelectPointN < 0 {
+		return errors.New("max-select-point must not be negative")
+	} else if c.MaxSelectSeriesN < 0 {
+		return errors.New("max-select-series must not be negative")
+	} else if c.MaxConcurrentSelects < 0 {
+		return errors.New("max-concurrent-selects must not be negative")
+	}
+
+	seen := make(map[string]bool)
+	for _, r := range c.Routes {
+		if r.Database == "" {
+			return errors.New("route database required")
+		} else if r.RetentionPolicy == "" {
+			return fmt.Errorf("route retention policy required in database %q", r.Database)
+		} else if len(r.Measurements) == 0 {
+			return fmt.Errorf("route measurements required in database %q", r.Database)
+		}
+
+		for _, name := range r.Measurements {
+			key := r.Database + "." + name
+			if seen[key] {
+				return fmt.Errorf("duplicate route for measurement %q in database %q", name, r.Database)
+			}
+			seen[key] = true
+		}
+	}
+
+	limited := make(map[string]bool)
+	for _, l := range c.DatabaseLimits {
+		if l.Database == "" {
+			return errors.New("database limit database required")
+		} else if limited[l.Database] {
+			return fmt.Errorf("duplicate limits for database %q", l.Database)
+		} else if l.MaxPointsPerSecond < 0 || l.MaxQueries < 0 || l.MaxQueryMemory < 0 {
+			return fmt.Errorf("negative limit for database %q", l.Database)
+		}
+		limited[l.Database] = true
+	}
+
+	deduped := make(map[string]bool)
+	for _, d := range c.DedupWindows {
+		if d.Database == "" {
+			return errors.New("dedup window database required")
+		} else if deduped[d.Database] {
+			return fmt.Errorf("duplicate dedup window for database %q", d.Database)
+		} else if d.Window <= 0 {
+			return fmt.Errorf("dedup window must be greater than zero for database %q", d.Database)
+		} else if d.MaxPoints < 0 {
+			return fmt.Errorf("dedup max-points must not be negative for database %q", d.Database)
+		}
+		deduped[d.Database] = true
+	}
+
+	if _, err := NewTransformRules(c.Transforms); err != nil {
+		return err
 	}
+	return nil
 }
@@ -1,11 +1,13 @@
 package cluster_test
 
 import (
+	"reflect"
 	"testing"
 	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/influxdb/influxdb/cluster"
+	itoml "github.com/influxdb/influxdb/toml"
 )
 
 func TestConfig_Parse(t *testing.T) {
@@ -14,6 +16,29 @@ func TestConfig_Parse(t *testing.T) {
 	if _, err := toml.Decode(`
 shard-writer-timeout = "10s"
 write-timeout = "20s"
+shard-mapper-window-size = 4
+shard-mapper-compression = true
+max-future-time = "24h"
+max-past-time = "720h"
+reject-beyond-retention = true
+batch-id-ttl = "1h"
+max-batch-ids = 10
+
+[[route]]
+database = "db0"
+measurements = ["cpu", "mem"]
+retention-policy = "7d"
+
+[[database-limit]]
+database = "db0"
+max-points-per-second = 1000
+max-queries = 4
+max-query-memory = 1048576
+
+[[dedup-window]]
+database = "db0"
+window = "10m"
+max-points = 100
 `, &c); err != nil {
 		t.Fatal(err)
 	}
@@ -23,5 +48,155 @@ write-timeout = "20s"
 		t.Fatalf("unexpected shard-writer timeout: %s", c.ShardWriterTimeout)
 	} else if time.Duration(c.WriteTimeout) != 20*time.Second {
 		t.Fatalf("unexpected write timeout s: %s", c.WriteTimeout)
+	} else if c.ShardMapperWindowSize != 4 {
+		t.Fatalf("unexpected shard-mapper window size: %d", c.ShardMapperWindowSize)
+	} else if !c.ShardMapperCompression {
+		t.Fatalf("unexpected shard-mapper compression: %v", c.ShardMapperCompression)
+	} else if time.Duration(c.MaxFutureTime) != 24*time.Hour {
+		t.Fatalf("unexpected max future time: %s", c.MaxFutureTime)
+	} else if time.Duration(c.MaxPastTime) != 720*time.Hour {
+		t.Fatalf("unexpected max past time: %s", c.MaxPastTime)
+	} else if !c.RejectBeyondRetention {
+		t.Fatalf("unexpected reject beyond retention: %v", c.RejectBeyondRetention)
+	} else if time.Duration(c.BatchIDTTL) != time.Hour {
+		t.Fatalf("unexpected batch id ttl: %s", c.BatchIDTTL)
+	} else if c.MaxBatchIDs != 10 {
+		t.Fatalf("unexpected max batch ids: %d", c.MaxBatchIDs)
+	} else if !reflect.DeepEqual(c.Routes, []cluster.Route{{Database: "db0", Measurements: []string{"cpu", "mem"}, RetentionPolicy: "7d"}}) {
+		t.Fatalf("unexpected routes: %#v", c.Routes)
+	} else if !reflect.DeepEqual(c.DatabaseLimits, []cluster.DatabaseLimit{{Database: "db0", MaxPointsPerSecond: 1000, MaxQueries: 4, MaxQueryMemory: 1048576}}) {
+		t.Fatalf("unexpected database limits: %#v", c.DatabaseLimits)
+	} else if len(c.DedupWindows) != 1 || c.DedupWindows[0].Database != "db0" || time.Duration(c.DedupWindows[0].Window) != 10*time.Minute || c.DedupWindows[0].MaxPoints != 100 {
+		t.Fatalf("unexpected dedup windows: %#v", c.DedupWindows)
+	}
+}
+
+// Ensure invalid routes are rejected.
+func TestConfig_Validate_Routes(t *testing.T) {
+	for i, tt := range []struct {
+		routes []cluster.Route
+		err    string
+	}{
+		{routes: []cluster.Route{{Measurements: []string{"cpu"}, RetentionPolicy: "7d"}}, err: `route database required`},
+		{routes: []cluster.Route{{Database: "db0", Measurements: []string{"cpu"}}}, err: `route retention policy required in database "db0"`},
+		{routes: []cluster.Route{{Database: "db0", RetentionPolicy: "7d"}}, err: `route measurements required in database "db0"`},
+		{
+			routes: []cluster.Route{
+				{Database: "db0", Measurements: []string{"cpu"}, RetentionPolicy: "7d"},
+				{Database: "db0", Measurements: []string{"cpu"}, RetentionPolicy: "30d"},
+			},
+			err: `duplicate route for measurement "cpu" in database "db0"`,
+		},
+	} {
+		c := cluster.NewConfig()
+		c.Routes = tt.routes
+		if err := c.Validate(); err == nil || err.Error() != tt.err {
+			t.Errorf("%d. unexpected error: %v", i, err)
+		}
+	}
+}
+
+// Ensure write coalescing requires a batch size and delay.
+func TestConfig_Validate_Coalesce(t *testing.T) {
+	c := cluster.NewConfig()
+	c.CoalesceWrites = true
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	c.CoalesceMaxPoints = 0
+	if err := c.Validate(); err == nil || err.Error() != "coalesce-max-points must be greater than zero" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c = cluster.NewConfig()
+	c.CoalesceWrites = true
+	c.CoalesceMaxDelay = 0
+	if err := c.Validate(); err == nil || err.Error() != "coalesce-max-delay must be greater than zero" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure retries require an interval and breakers a cooldown.
+func TestConfig_Validate_Retries(t *testing.T) {
+	for i, tt := range []struct {
+		fn  func(c *cluster.Config)
+		err string
+	}{
+		{fn: func(c *cluster.Config) { c.WriteRetries = -1 }, err: `write-retries must not be negative`},
+		{fn: func(c *cluster.Config) { c.WriteRetryInterval = 0 }, err: `write-retry-interval must be greater than zero`},
+		{fn: func(c *cluster.Config) { c.BreakerFailures = -1 }, err: `breaker-failures must not be negative`},
+		{fn: func(c *cluster.Config) { c.BreakerCooldown = 0 }, err: `breaker-cooldown must be greater than zero`},
+	} {
+		c := cluster.NewConfig()
+		tt.fn(&c)
+		if err := c.Validate(); err == nil || err.Error() != tt.err {
+			t.Errorf("%d. unexpected error: %v", i, err)
+		}
+	}
+
+	// Disabled retries and breakers need no interval or cooldown.
+	c := cluster.NewConfig()
+	c.WriteRetries, c.WriteRetryInterval = 0, 0
+	c.BreakerFailures, c.BreakerCooldown = 0, 0
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Ensure invalid database limits are rejected.
+func TestConfig_Validate_DatabaseLimits(t *testing.T) {
+	for i, tt := range []struct {
+		limits []cluster.DatabaseLimit
+		err    string
+	}{
+		{limits: []cluster.DatabaseLimit{{MaxQueries: 1}}, err: `database limit database required`},
+		{limits: []cluster.DatabaseLimit{{Database: "db0"}, {Database: "db0"}}, err: `duplicate limits for database "db0"`},
+		{limits: []cluster.DatabaseLimit{{Database: "db0", MaxQueryMemory: -1}}, err: `negative limit for database "db0"`},
+	} {
+		c := cluster.NewConfig()
+		c.DatabaseLimits = tt.limits
+		if err := c.Validate(); err == nil || err.Error() != tt.err {
+			t.Errorf("%d. unexpected error: %v", i, err)
+		}
+	}
+}
+
+// Ensure invalid dedup windows are rejected.
+func TestConfig_Validate_DedupWindows(t *testing.T) {
+	for i, tt := range []struct {
+		windows []cluster.DedupWindow
+		err     string
+	}{
+		{windows: []cluster.DedupWindow{{Window: itoml.Duration(time.Minute)}}, err: `dedup window database required`},
+		{windows: []cluster.DedupWindow{{Database: "db0", Window: itoml.Duration(time.Minute)}, {Database: "db0", Window: itoml.Duration(time.Minute)}}, err: `duplicate dedup window for database "db0"`},
+		{windows: []cluster.DedupWindow{{Database: "db0"}}, err: `dedup window must be greater than zero for database "db0"`},
+		{windows: []cluster.DedupWindow{{Database: "db0", Window: itoml.Duration(time.Minute), MaxPoints: -1}}, err: `dedup max-points must not be negative for database "db0"`},
+	} {
+		c := cluster.NewConfig()
+		c.DedupWindows = tt.windows
+		if err := c.Validate(); err == nil || err.Error() != tt.err {
+			t.Errorf("%d. unexpected error: %v", i, err)
+		}
+	}
+}
+
+// Ensure invalid transforms are rejected.
+func TestConfig_Validate_Transforms(t *testing.T) {
+	for i, tt := range []struct {
+		transforms []cluster.Transform
+		err        string
+	}{
+		{transforms: []cluster.Transform{{Rules: []string{"rename mem"}}}, err: `transform database required`},
+		{transforms: []cluster.Transform{{Database: "db0"}}, err: `transform rules required in database "db0"`},
+		{transforms: []cluster.Transform{{Database: "db0", Rules: []string{"rename"}}}, err: `invalid transform rule "rename"`},
+		{transforms: []cluster.Transform{{Database: "db0", Rules: []string{"add-tag dc"}}}, err: `invalid transform rule "add-tag dc": tag must be key=value`},
+		{transforms: []cluster.Transform{{Database: "db0", Rules: []string{"drop-tag dc"}}}, err: `invalid transform rule "drop-tag dc": unknown operation "drop-tag"`},
+	} {
+		c := cluster.NewConfig()
+		c.Transforms = tt.transforms
+		if err := c.Validate(); err == nil || err.Error() != tt.err {
+			t.Errorf("%d. unexpected error: %v", i, err)
+		}
 	}
 }
@@ -0,0 +1,136 @@
+package cluster
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// pointDedup remembers the points recently written to the databases with a
+// dedup window. The zero value is ready to use and deduplicates nothing.
+type pointDedup struct {
+	mu  sync.Mutex
+	dbs map[string]*dedupPoints
+}
+
+// dedupPoints holds the hashes of the points written to a database, along
+// with the hashes in the order they were written so the oldest can be expired.
+type dedupPoints struct {
+	window  time.Duration
+	max     int
+	written map[uint64]time.Time
+	order   []dedupEntry
+}
+
+// dedupEntry is a hash in the order points were written. It's stale once the
+// hash is released or written again at a later time.
+type dedupEntry struct {
+	hash uint64
+	at   time.Time
+}
+
+// set sets the dedup window of each database in windows. The points already
+// remembered for databases still in windows are kept.
+func (d *pointDedup) set(windows []DedupWindow) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	dbs := make(map[string]*dedupPoints, len(windows))
+	for _, w := range windows {
+		p := d.dbs[w.Database]
+		if p == nil {
+			p = &dedupPoints{written: make(map[uint64]time.Time)}
+		}
+		p.window, p.max = time.Duration(w.Window), w.MaxPoints
+		if p.max == 0 {
+			p.max = DefaultDedupMaxPoints
+		}
+		dbs[w.Database] = p
+	}
+	d.dbs = dbs
+}
+
+// filter removes the points of a request written to its database within the
+// dedup window of now, and the repeats of points within the request. The
+// hashes of the remaining points are reserved right away, so concurrent writes
+// of the same points are removed too. Returns the reserved hashes, to be
+// released if the write fails, and the number of points removed.
+func (d *pointDedup) filter(p *WritePointsRequest, now time.Time) ([]uint64, int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	dp := d.dbs[p.Database]
+	if dp == nil {
+		return nil, 0
+	}
+
+	hashes := make([]uint64, 0, len(p.Points))
+	points := make([]tsdb.Point, 0, len(p.Points))
+	for _, pt := range p.Points {
+		h := dedupHash(pt)
+		if t, ok := dp.written[h]; ok && now.Sub(t) < dp.window {
+			continue
+		}
+		dp.written[h] = now
+		dp.order = append(dp.order, dedupEntry{hash: h, at: now})
+		hashes = append(hashes, h)
+		points = append(points, pt)
+	}
+	dp.expire(now)
+
+	n := len(p.Points) - len(points)
+	if n > 0 {
+		p.Points = points
+	}
+	return hashes, n
+}
+
+// release forgets the hashes reserved by filter at now for a write to
+// database which failed, so the write can be retried.
+func (d *pointDedup) release(database string, hashes []uint64, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	dp := d.dbs[database]
+	if dp == nil {
+		return
+	}
+	for _, h := range hashes {
+		if t, ok := dp.written[h]; ok && t.Equal(now) {
+			delete(dp.written, h)
+		}
+	}
+}
+
+// expire forgets the points older than the window, and the oldest points past
+// the maximum.
+func (dp *dedupPoints) expire(now time.Time) {
+	for len(dp.order) > 0 {
+		oldest := dp.order[0]
+		if t, ok := dp.written[oldest.hash]; !ok || !t.Equal(oldest.at) {
+			dp.order = dp.order[1:] // stale
+			continue
+		} else if now.Sub(t) < dp.window && len(dp.order) <= dp.max {
+			break
+		}
+		delete(dp.written, oldest.hash)
+		dp.order = dp.order[1:]
+	}
+}
+
+// dedupHash returns the hash of the series, time and fields of a point. The
+// fields are encoded sorted, so their order in the write doesn't matter.
+func dedupHash(p tsdb.Point) uint64 {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(p.UnixNano()))
+
+	h := fnv.New64a()
+	h.Write(p.Key())
+	h.Write([]byte{0})
+	h.Write(buf[:])
+	h.Write(p.Fields().MarshalBinary())
+	return h.Sum64()
+}
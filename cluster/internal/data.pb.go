@@ -13,6 +13,7 @@ It has these top-level messages:
 	WriteShardResponse
 	MapShardRequest
 	MapShardResponse
+	MapShardAck
 */
 package internal
 
@@ -75,6 +76,8 @@ type MapShardRequest struct {
 	ShardID          *uint64 `protobuf:"varint,1,req" json:"ShardID,omitempty"`
 	Query            *string `protobuf:"bytes,2,req" json:"Query,omitempty"`
 	ChunkSize        *int32  `protobuf:"varint,3,req" json:"ChunkSize,omitempty"`
+	WindowSize       *int32  `protobuf:"varint,4,opt" json:"WindowSize,omitempty"`
+	Compress         *bool   `protobuf:"varint,5,opt" json:"Compress,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
@@ -103,12 +106,27 @@ func (m *MapShardRequest) GetChunkSize() int32 {
 	return 0
 }
 
+func (m *MapShardRequest) GetWindowSize() int32 {
+	if m != nil && m.WindowSize != nil {
+		return *m.WindowSize
+	}
+	return 0
+}
+
+func (m *MapShardRequest) GetCompress() bool {
+	if m != nil && m.Compress != nil {
+		return *m.Compress
+	}
+	return false
+}
+
 type MapShardResponse struct {
 	Code             *int32   `protobuf:"varint,1,req" json:"Code,omitempty"`
 	Message          *string  `protobuf:"bytes,2,opt" json:"Message,omitempty"`
 	Data             []byte   `protobuf:"bytes,3,opt" json:"Data,omitempty"`
 	TagSets          []string `protobuf:"bytes,4,rep" json:"TagSets,omitempty"`
 	Fields           []string `protobuf:"bytes,5,rep" json:"Fields,omitempty"`
+	Compressed       *bool    `protobuf:"varint,6,opt" json:"Compressed,omitempty"`
 	XXX_unrecognized []byte   `json:"-"`
 }
 
@@ -151,5 +169,28 @@ func (m *MapShardResponse) GetFields() []string {
 	return nil
 }
 
+func (m *MapShardResponse) GetCompressed() bool {
+	if m != nil && m.Compressed != nil {
+		return *m.Compressed
+	}
+	return false
+}
+
+type MapShardAck struct {
+	N                *int32 `protobuf:"varint,1,req" json:"N,omitempty"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *MapShardAck) Reset()         { *m = MapShardAck{} }
+func (m *MapShardAck) String() string { return proto.CompactTextString(m) }
+func (*MapShardAck) ProtoMessage()    {}
+
+func (m *MapShardAck) GetN() int32 {
+	if m != nil && m.N != nil {
+		return *m.N
+	}
+	return 0
+}
+
 func init() {
 }
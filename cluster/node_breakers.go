@@ -0,0 +1,71 @@
+package cluster
+
+import (
+	"sync"
+	"time"
+)
+
+// nodeBreakers are circuit breakers for the remote nodes written to. A
+// breaker opens after a number of consecutive failed writes to its node, so
+// writes fail fast instead of waiting on a node that is down. Once the
+// cooldown has passed a single write is let through to probe the node: the
+// breaker closes if it succeeds and stays open for another cooldown if not.
+// The zero value is ready to use.
+type nodeBreakers struct {
+	mu    sync.Mutex
+	nodes map[uint64]*nodeBreaker
+}
+
+// nodeBreaker is the state of the breaker of a node.
+type nodeBreaker struct {
+	failures int       // consecutive failed writes
+	until    time.Time // writes aren't sent before this time, once open
+}
+
+// allow returns true if a write may be sent to the node at now. Writes are
+// always allowed if max, the failures opening the breaker, is zero.
+func (b *nodeBreakers) allow(nodeID uint64, max int, cooldown time.Duration, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n := b.nodes[nodeID]
+	if max <= 0 || n == nil || n.failures < max {
+		return true
+	} else if now.Before(n.until) {
+		return false
+	}
+
+	// Let this write probe the node and hold the others back meanwhile.
+	n.until = now.Add(cooldown)
+	return true
+}
+
+// success records a successful write to the node, closing its breaker.
+func (b *nodeBreakers) success(nodeID uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.nodes, nodeID)
+}
+
+// failure records a failed write to the node at now. The breaker opens for
+// cooldown once the node has failed max writes in a row.
+func (b *nodeBreakers) failure(nodeID uint64, max int, cooldown time.Duration, now time.Time) {
+	if max <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.nodes == nil {
+		b.nodes = make(map[uint64]*nodeBreaker)
+	}
+	n := b.nodes[nodeID]
+	if n == nil {
+		n = &nodeBreaker{}
+		b.nodes[nodeID] = n
+	}
+	if n.failures++; n.failures >= max {
+		n.until = now.Add(cooldown)
+	}
+}
@@ -4,13 +4,14 @@ import (
 	"errors"
 	"expvar"
 	"fmt"
-	"log"
+	"math/rand"
 	"os"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/influxdb/influxdb"
+	"github.com/influxdb/influxdb/logger"
 	"github.com/influxdb/influxdb/meta"
 	"github.com/influxdb/influxdb/tsdb"
 )
@@ -30,6 +31,37 @@ const (
 	statWriteTimeout        = "write_timeout"
 	statWriteErr            = "write_error"
 	statWritePointReqHH     = "point_req_hh"
+	statSubWriteOK          = "sub_write_ok"
+	statSubWriteDrop        = "sub_write_drop"
+	statPointsNormalized    = "points_normalized"
+	statPointsRouted        = "points_routed"
+	statPointsFuture        = "points_rejected_future"
+	statPointsPast          = "points_rejected_past"
+	statWriteDuplicate      = "write_duplicate"
+	statWriteLimited        = "write_limited"
+	statWriteRetry          = "write_retry"
+	statWriteBreakerOpen    = "write_breaker_open"
+	statPointsDeduplicated  = "points_deduplicated"
+	statPointsTransformed   = "points_transformed"
+)
+
+// The statistics tracked per database.
+const (
+	statDatabaseWriteReq      = "write_req"      // Number of write requests
+	statDatabaseWriteErr      = "write_error"    // Number of write requests that failed
+	statDatabasePointsWritten = "points_written" // Number of points written
+	statDatabasePointsDropped = "points_dropped" // Number of points that could not be written
+	statDatabaseWriteLimited  = "write_limited"  // Number of write requests rejected by the rate limit
+
+	// Points dropped, by reason. The reasons don't cover all points_dropped.
+	statDatabasePointsDroppedSchema       = "points_dropped_schema"        // Points not conforming to the schema
+	statDatabasePointsDroppedTypeConflict = "points_dropped_type_conflict" // Points with a field type conflict
+	statDatabasePointsDroppedRetention    = "points_dropped_retention"     // Points older than the retention policy
+	statDatabasePointsDroppedPast         = "points_dropped_past"          // Points older than the max past time
+	statDatabasePointsDroppedFuture       = "points_dropped_future"        // Points newer than the max future time
+
+	// Duplicate points ignored in the dedup window. They aren't points_dropped.
+	statDatabasePointsDeduplicated = "points_deduplicated"
 )
 
 const (
@@ -60,6 +92,18 @@ var (
 	// ErrInvalidConsistencyLevel is returned when parsing the string version
 	// of a consistency level.
 	ErrInvalidConsistencyLevel = errors.New("invalid consistency level")
+
+	// ErrWriteLimitExceeded is returned when points are written to a database
+	// faster than its limit allows.
+	ErrWriteLimitExceeded = errors.New("write rate limit exceeded for database")
+
+	// ErrNodeUnavailable is returned when a write isn't sent to a node because
+	// too many writes to it failed recently.
+	ErrNodeUnavailable = errors.New("node unavailable")
+
+	// ErrBatchInProgress is returned when a batch is written while another
+	// write of the same batch ID hasn't finished.
+	ErrBatchInProgress = errors.New("batch write in progress")
 )
 
 func ParseConsistencyLevel(level string) (ConsistencyLevel, error) {
@@ -82,7 +126,35 @@ type PointsWriter struct {
 	mu           sync.RWMutex
 	closing      chan struct{}
 	WriteTimeout time.Duration
-	Logger       *log.Logger
+	Logger       *logger.Logger
+
+	// Bounds on the time of points written, relative to now. Points outside
+	// of them are dropped and the write returns an error. Zero disables a bound.
+	MaxFutureTime time.Duration
+	MaxPastTime   time.Duration
+
+	// RejectBeyondRetention drops points older than the duration of the
+	// retention policy they're written to.
+	RejectBeyondRetention bool
+
+	// Writes with the batch ID of a write to the same database that
+	// succeeded within BatchIDTTL are ignored. At most MaxBatchIDs IDs are
+	// remembered per database. Zero BatchIDTTL disables the check.
+	BatchIDTTL  time.Duration
+	MaxBatchIDs int
+	batchIDs    batchIDCache
+
+	// Remote writes failing with a retryable error are retried up to
+	// WriteRetries times before the write times out, waiting RetryInterval
+	// doubled after each attempt, with jitter.
+	WriteRetries  int
+	RetryInterval time.Duration
+
+	// Writes aren't sent to a node for BreakerCooldown after BreakerFailures
+	// consecutive failed writes to it. Zero BreakerFailures disables it.
+	BreakerFailures int
+	BreakerCooldown time.Duration
+	breakers        nodeBreakers
 
 	MetaStore interface {
 		NodeID() uint64
@@ -105,6 +177,30 @@ type PointsWriter struct {
 		WriteShard(shardID, ownerID uint64, points []tsdb.Point) error
 	}
 
+	Subscriber interface {
+		Points() chan<- *WritePointsRequest
+	}
+	subPoints chan<- *WritePointsRequest
+
+	// Schema validates points against the schema of their database, if set.
+	Schema interface {
+		Filter(database string, points []tsdb.Point) ([]tsdb.Point, error)
+	}
+
+	// Retention policy of each routed measurement, by database.
+	routes map[string]map[string]string
+
+	// The transformers of the configured transforms and the registered
+	// transformers, applied in that order.
+	rules        *TransformRules
+	transformers []PointTransformer
+
+	// Limits the points written per second to each database.
+	limiter rateLimiter
+
+	// Drops points written to a database within its dedup window.
+	dedup pointDedup
+
 	statMap *expvar.Map
 }
 
@@ -113,7 +209,7 @@ func NewPointsWriter() *PointsWriter {
 	return &PointsWriter{
 		closing:      make(chan struct{}),
 		WriteTimeout: DefaultWriteTimeout,
-		Logger:       log.New(os.Stderr, "[write] ", log.LstdFlags),
+		Logger:       logger.New(os.Stderr, "write"),
 		statMap:      influxdb.NewStatistics("write", "write", nil),
 	}
 }
@@ -143,12 +239,72 @@ func (s *ShardMapping) MapPoint(shardInfo *meta.ShardInfo, p tsdb.Point) {
 	s.Shards[shardInfo.ID] = shardInfo
 }
 
+// SetRoutes sets the rules routing measurements written without a retention
+// policy. It is safe to call while points are being written.
+func (w *PointsWriter) SetRoutes(routes []Route) {
+	m := make(map[string]map[string]string)
+	for _, r := range routes {
+		if m[r.Database] == nil {
+			m[r.Database] = make(map[string]string)
+		}
+		for _, name := range r.Measurements {
+			m[r.Database][name] = r.RetentionPolicy
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.routes = m
+}
+
+// SetTransforms sets the transforms applied to the points written to each
+// database. It is safe to call while points are being written.
+func (w *PointsWriter) SetTransforms(transforms []Transform) error {
+	rules, err := NewTransformRules(transforms)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.rules = rules
+	return nil
+}
+
+// RegisterTransformer adds a transformer applied to the points written, after
+// the configured transforms and the transformers registered before it. It is
+// safe to call while points are being written.
+func (w *PointsWriter) RegisterTransformer(t PointTransformer) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.transformers = append(w.transformers, t)
+}
+
+// SetDatabaseLimits sets the write rate allowed for each database in limits.
+// It is safe to call while points are being written.
+func (w *PointsWriter) SetDatabaseLimits(limits []DatabaseLimit) {
+	rates := make(map[string]int)
+	for _, l := range limits {
+		rates[l.Database] = l.MaxPointsPerSecond
+	}
+	w.limiter.set(rates, time.Now())
+}
+
+// SetDedupWindows sets the dedup window of each database in windows. It is
+// safe to call while points are being written.
+func (w *PointsWriter) SetDedupWindows(windows []DedupWindow) {
+	w.dedup.set(windows)
+}
+
 func (w *PointsWriter) Open() error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 	if w.closing == nil {
 		w.closing = make(chan struct{})
 	}
+	if w.Subscriber != nil {
+		w.subPoints = w.Subscriber.Points()
+	}
 	return nil
 }
 
@@ -159,6 +315,12 @@ func (w *PointsWriter) Close() error {
 		close(w.closing)
 		w.closing = nil
 	}
+	if w.subPoints != nil {
+		// 'nil' channels always block so this makes the
+		// select statement in WritePoints hit its default case
+		// dropping any in-flight writes.
+		w.subPoints = nil
+	}
 	return nil
 }
 
@@ -197,23 +359,273 @@ func (w *PointsWriter) MapShards(wp *WritePointsRequest) (*ShardMapping, error)
 	return mapping, nil
 }
 
+// WritePointsInto writes the results of a SELECT INTO statement with a
+// consistency level of one.
+func (w *PointsWriter) WritePointsInto(p *tsdb.IntoWriteRequest) error {
+	return w.WritePoints(&WritePointsRequest{
+		Database:         p.Database,
+		RetentionPolicy:  p.RetentionPolicy,
+		ConsistencyLevel: ConsistencyLevelOne,
+		Points:           p.Points,
+	})
+}
+
 // WritePoints writes across multiple local and remote data nodes according the consistency level.
 func (w *PointsWriter) WritePoints(p *WritePointsRequest) error {
 	w.statMap.Add(statWriteReq, 1)
 	w.statMap.Add(statPointWriteReq, int64(len(p.Points)))
 
+	// Ignore retries of batches that were already written. The batch ID is
+	// reserved until the write finishes so concurrent retries aren't
+	// written twice.
+	if p.BatchID != "" && w.BatchIDTTL > 0 {
+		if ok, err := w.batchIDs.reserve(p.Database, p.BatchID, w.BatchIDTTL, time.Now()); err != nil {
+			return err
+		} else if !ok {
+			w.statMap.Add(statWriteDuplicate, 1)
+			return nil
+		}
+
+		if err := w.writeDatabase(p); err != nil {
+			w.batchIDs.release(p.Database, p.BatchID)
+			return err
+		}
+		w.batchIDs.add(p.Database, p.BatchID, w.BatchIDTTL, w.MaxBatchIDs, time.Now())
+		return nil
+	}
+	return w.writeDatabase(p)
+}
+
+// writeDatabase writes the points of a request, unless its database is
+// written to faster than allowed.
+func (w *PointsWriter) writeDatabase(p *WritePointsRequest) error {
+	dbStats := influxdb.DatabaseStatistics(p.Database)
+	dbStats.Add(statDatabaseWriteReq, 1)
+
+	// Reject the write if the database is written to faster than allowed.
+	if !w.limiter.allow(p.Database, len(p.Points), time.Now()) {
+		w.statMap.Add(statWriteLimited, 1)
+		dbStats.Add(statDatabaseWriteLimited, 1)
+		return ErrWriteLimitExceeded
+	}
+
+	if err := w.writePoints(p, dbStats); err != nil {
+		dbStats.Add(statDatabaseWriteErr, 1)
+		return err
+	}
+	return nil
+}
+
+// writePoints maps the points to shards and writes each shard. The number of
+// points written and dropped is added to dbStats.
+func (w *PointsWriter) writePoints(p *WritePointsRequest, dbStats *expvar.Map) error {
+	di, err := w.MetaStore.Database(p.Database)
+	if err != nil {
+		return err
+	}
+
+	// Only points written without a retention policy are routed.
+	routed := p.RetentionPolicy == ""
 	if p.RetentionPolicy == "" {
-		db, err := w.MetaStore.Database(p.Database)
+		if di == nil {
+			return influxdb.ErrDatabaseNotFound(p.Database)
+		}
+		p.RetentionPolicy = di.DefaultRetentionPolicy
+	}
+
+	w.transform(p)
+
+	// Normalize tag values before the points are checked against the schema
+	// so that both see the series that will be written.
+	if di != nil && len(di.TagRules) > 0 {
+		w.normalizeTags(di, p.Points)
+	}
+
+	// Remove or reject the points that don't conform to the database's schema.
+	if w.Schema != nil {
+		points, err := w.Schema.Filter(p.Database, p.Points)
 		if err != nil {
+			dbStats.Add(statDatabasePointsDropped, int64(len(p.Points)))
+			dbStats.Add(statDatabasePointsDroppedSchema, int64(len(p.Points)))
 			return err
-		} else if db == nil {
-			return influxdb.ErrDatabaseNotFound(p.Database)
 		}
-		p.RetentionPolicy = db.DefaultRetentionPolicy
+		if n := len(p.Points) - len(points); n > 0 {
+			dbStats.Add(statDatabasePointsDropped, int64(n))
+			dbStats.Add(statDatabasePointsDroppedSchema, int64(n))
+		}
+		p.Points = points
+	}
+
+	reqs := []*WritePointsRequest{p}
+	if routed {
+		reqs = w.route(p)
+	}
+
+	// Points outside the allowed time range are dropped before they can
+	// create shard groups. The rest of the write still goes through.
+	now := time.Now()
+	var total, dropped int
+	for _, req := range reqs {
+		total += len(req.Points)
+		n, err := w.filterTimes(req, now, dbStats)
+		if err != nil {
+			return err
+		}
+		dropped += n
+		if n > 0 && len(req.Points) == 0 {
+			continue
+		}
+
+		// Duplicates are ignored without failing the write. The points are
+		// forgotten if the write fails so it can be retried.
+		hashes, dups := w.dedup.filter(req, now)
+		if dups > 0 {
+			w.statMap.Add(statPointsDeduplicated, int64(dups))
+			dbStats.Add(statDatabasePointsDeduplicated, int64(dups))
+			if len(req.Points) == 0 {
+				continue
+			}
+		}
+
+		if err := w.writeShards(req, dbStats); err != nil {
+			w.dedup.release(req.Database, hashes, now)
+			return err
+		}
+	}
+
+	if dropped == 0 {
+		return nil
+	}
+	dbStats.Add(statDatabasePointsDropped, int64(dropped))
+	if dropped == total {
+		return fmt.Errorf("%s: dropped %d points", influxdb.ErrTimeOutOfRange, dropped)
+	}
+	return fmt.Errorf("partial write: %s: dropped %d of %d points", influxdb.ErrTimeOutOfRange, dropped, total)
+}
+
+// transform applies the transformers to the points of a request.
+func (w *PointsWriter) transform(p *WritePointsRequest) {
+	w.mu.RLock()
+	rules, transformers := w.rules, w.transformers
+	w.mu.RUnlock()
+
+	if rules != nil {
+		var n int
+		if p.Points, n = rules.transform(p.Database, p.Points); n > 0 {
+			w.statMap.Add(statPointsTransformed, int64(n))
+		}
+	}
+	for _, t := range transformers {
+		p.Points = t.Transform(p.Database, p.Points)
+	}
+}
+
+// filterTimes removes the points of a request with a time outside the
+// allowed range. Returns the number of points removed. The reasons the
+// points were removed for are added to dbStats.
+func (w *PointsWriter) filterTimes(p *WritePointsRequest, now time.Time, dbStats *expvar.Map) (int, error) {
+	var min, max, retentionMin time.Time
+	if w.MaxFutureTime > 0 {
+		max = now.Add(w.MaxFutureTime)
+	}
+	if w.MaxPastTime > 0 {
+		min = now.Add(-w.MaxPastTime)
+	}
+	if w.RejectBeyondRetention {
+		rp, err := w.MetaStore.RetentionPolicy(p.Database, p.RetentionPolicy)
+		if err != nil {
+			return 0, err
+		} else if rp != nil && rp.Duration > 0 {
+			if retentionMin = now.Add(-rp.Duration); retentionMin.After(min) {
+				min = retentionMin
+			}
+		}
+	}
+	if min.IsZero() && max.IsZero() {
+		return 0, nil
+	}
+
+	var future, past, retention int64
+	points := make([]tsdb.Point, 0, len(p.Points))
+	for _, pt := range p.Points {
+		if t := pt.Time(); !max.IsZero() && t.After(max) {
+			future++
+		} else if !retentionMin.IsZero() && t.Before(retentionMin) {
+			retention++
+		} else if !min.IsZero() && t.Before(min) {
+			past++
+		} else {
+			points = append(points, pt)
+		}
+	}
+	if future == 0 && past == 0 && retention == 0 {
+		return 0, nil
+	}
+	p.Points = points
+
+	if future > 0 {
+		w.statMap.Add(statPointsFuture, future)
+		dbStats.Add(statDatabasePointsDroppedFuture, future)
+	}
+	if past+retention > 0 {
+		w.statMap.Add(statPointsPast, past+retention)
+	}
+	if past > 0 {
+		dbStats.Add(statDatabasePointsDroppedPast, past)
+	}
+	if retention > 0 {
+		dbStats.Add(statDatabasePointsDroppedRetention, retention)
+	}
+	return int(future + past + retention), nil
+}
+
+// route splits a write request by the retention policy each point is routed
+// to. Points of measurements without a route stay in the original request.
+func (w *PointsWriter) route(p *WritePointsRequest) []*WritePointsRequest {
+	w.mu.RLock()
+	routes := w.routes[p.Database]
+	w.mu.RUnlock()
+	if len(routes) == 0 {
+		return []*WritePointsRequest{p}
+	}
+
+	// Requests are kept in the order their retention policy first appears.
+	var reqs []*WritePointsRequest
+	byPolicy := make(map[string]*WritePointsRequest)
+	var n int64
+	for _, pt := range p.Points {
+		policy, ok := routes[pt.Name()]
+		if !ok {
+			policy = p.RetentionPolicy
+		} else {
+			n++
+		}
+
+		req := byPolicy[policy]
+		if req == nil {
+			req = &WritePointsRequest{
+				Database:         p.Database,
+				RetentionPolicy:  policy,
+				ConsistencyLevel: p.ConsistencyLevel,
+			}
+			byPolicy[policy] = req
+			reqs = append(reqs, req)
+		}
+		req.Points = append(req.Points, pt)
 	}
 
+	if n > 0 {
+		w.statMap.Add(statPointsRouted, n)
+	}
+	return reqs
+}
+
+// writeShards writes the points of a request to their shards and mirrors
+// them to subscribers.
+func (w *PointsWriter) writeShards(p *WritePointsRequest, dbStats *expvar.Map) error {
 	shardMappings, err := w.MapShards(p)
 	if err != nil {
+		dbStats.Add(statDatabasePointsDropped, int64(len(p.Points)))
 		return err
 	}
 
@@ -222,7 +634,18 @@ func (w *PointsWriter) WritePoints(p *WritePointsRequest) error {
 	ch := make(chan error, len(shardMappings.Points))
 	for shardID, points := range shardMappings.Points {
 		go func(shard *meta.ShardInfo, database, retentionPolicy string, points []tsdb.Point) {
-			ch <- w.writeToShard(shard, p.Database, p.RetentionPolicy, p.ConsistencyLevel, points)
+			err := w.writeToShard(shard, p.Database, p.RetentionPolicy, p.ConsistencyLevel, points)
+
+			// A partial write still stored the points on at least one owner.
+			if err == nil || err == ErrPartialWrite {
+				dbStats.Add(statDatabasePointsWritten, int64(len(points)))
+			} else {
+				dbStats.Add(statDatabasePointsDropped, int64(len(points)))
+				if strings.Contains(err.Error(), influxdb.ErrFieldTypeConflict.Error()) {
+					dbStats.Add(statDatabasePointsDroppedTypeConflict, int64(len(points)))
+				}
+			}
+			ch <- err
 		}(shardMappings.Shards[shardID], p.Database, p.RetentionPolicy, points)
 	}
 
@@ -236,9 +659,39 @@ func (w *PointsWriter) WritePoints(p *WritePointsRequest) error {
 			}
 		}
 	}
+
+	// Mirror the accepted points to subscribers without blocking the write.
+	w.mu.RLock()
+	if w.subPoints != nil {
+		select {
+		case w.subPoints <- p:
+			w.statMap.Add(statSubWriteOK, 1)
+		default:
+			w.statMap.Add(statSubWriteDrop, 1)
+		}
+	}
+	w.mu.RUnlock()
+
 	return nil
 }
 
+// normalizeTags applies the tag rules of a database to the tags of points.
+func (w *PointsWriter) normalizeTags(di *meta.DatabaseInfo, points []tsdb.Point) {
+	// Points of the same series share their parsed tags.
+	tagSets := tsdb.NewTagSetCache()
+
+	var n int64
+	for _, p := range points {
+		if tags, changed := di.NormalizeTags(tagSets.SeriesTags(p.Key())); changed {
+			p.SetTags(tags)
+			n++
+		}
+	}
+	if n > 0 {
+		w.statMap.Add(statPointsNormalized, n)
+	}
+}
+
 // writeToShards writes points to a shard and ensures a write consistency level has been met.  If the write
 // partially succeeds, ErrPartialWrite is returned.
 func (w *PointsWriter) writeToShard(shard *meta.ShardInfo, database, retentionPolicy string,
@@ -258,6 +711,7 @@ func (w *PointsWriter) writeToShard(shard *meta.ShardInfo, database, retentionPo
 		Err   error
 	}
 	ch := make(chan *AsyncWriteResult, len(shard.Owners))
+	deadline := time.Now().Add(w.WriteTimeout)
 
 	for _, owner := range shard.Owners {
 		go func(shardID uint64, owner meta.ShardOwner, points []tsdb.Point) {
@@ -280,7 +734,7 @@ func (w *PointsWriter) writeToShard(shard *meta.ShardInfo, database, retentionPo
 			}
 
 			w.statMap.Add(statPointWriteReqRemote, int64(len(points)))
-			err := w.ShardWriter.WriteShard(shardID, owner.NodeID, points)
+			err := w.writeRemoteShard(shardID, owner.NodeID, points, deadline)
 			if err != nil && tsdb.IsRetryable(err) {
 				// The remote write failed so queue it via hinted handoff
 				w.statMap.Add(statWritePointReqHH, int64(len(points)))
@@ -314,7 +768,7 @@ func (w *PointsWriter) writeToShard(shard *meta.ShardInfo, database, retentionPo
 			// If the write returned an error, continue to the next response
 			if result.Err != nil {
 				w.statMap.Add(statWriteErr, 1)
-				w.Logger.Printf("write failed for shard %d on node %d: %v", shard.ID, result.Owner.NodeID, result.Err)
+				w.Logger.Error("write failed", "database", database, "retention_policy", retentionPolicy, "shard", shard.ID, "node", result.Owner.NodeID, "err", result.Err)
 
 				// Keep track of the first error we see to return back to the client
 				if writeError == nil {
@@ -344,3 +798,41 @@ func (w *PointsWriter) writeToShard(shard *meta.ShardInfo, database, retentionPo
 
 	return ErrWriteFailed
 }
+
+// writeRemoteShard writes points to a shard on a remote node. Retryable
+// errors are retried with exponential backoff and jitter while the retry
+// would end before deadline. Writes to a node whose breaker is open fail
+// with ErrNodeUnavailable without being sent.
+func (w *PointsWriter) writeRemoteShard(shardID, nodeID uint64, points []tsdb.Point, deadline time.Time) error {
+	for attempt := 0; ; attempt++ {
+		if !w.breakers.allow(nodeID, w.BreakerFailures, w.BreakerCooldown, time.Now()) {
+			w.statMap.Add(statWriteBreakerOpen, 1)
+			return ErrNodeUnavailable
+		}
+
+		err := w.ShardWriter.WriteShard(shardID, nodeID, points)
+		if err == nil || !tsdb.IsRetryable(err) {
+			// The node answered, whether or not it accepted the points.
+			w.breakers.success(nodeID)
+			return err
+		}
+		w.breakers.failure(nodeID, w.BreakerFailures, w.BreakerCooldown, time.Now())
+
+		if attempt >= w.WriteRetries || w.RetryInterval <= 0 {
+			return err
+		}
+
+		// Wait half to all of the interval so nodes retrying at once spread out.
+		d := w.RetryInterval << uint(attempt)
+		d = d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+		if time.Now().Add(d).After(deadline) {
+			return err
+		}
+		select {
+		case <-w.closing:
+			return err
+		case <-time.After(d):
+		}
+		w.statMap.Add(statWriteRetry, 1)
+	}
+}
@@ -1,14 +1,19 @@
 package cluster_test
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"reflect"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/influxdb/influxdb"
 	"github.com/influxdb/influxdb/cluster"
 	"github.com/influxdb/influxdb/meta"
+	"github.com/influxdb/influxdb/toml"
 	"github.com/influxdb/influxdb/tsdb"
 )
 
@@ -303,6 +308,12 @@ func TestPointsWriter_WritePoints(t *testing.T) {
 			},
 		}
 
+		subPoints := make(chan *cluster.WritePointsRequest, 1)
+		sub := Subscriber{}
+		sub.PointsFn = func() chan<- *cluster.WritePointsRequest {
+			return subPoints
+		}
+
 		ms := NewMetaStore()
 		ms.DatabaseFn = func(database string) (*meta.DatabaseInfo, error) {
 			return nil, nil
@@ -313,6 +324,9 @@ func TestPointsWriter_WritePoints(t *testing.T) {
 		c.ShardWriter = sw
 		c.TSDBStore = store
 		c.HintedHandoff = hh
+		c.Subscriber = sub
+
+		c.Open()
 
 		err := c.WritePoints(pr)
 		if err == nil && test.expErr != nil {
@@ -325,11 +339,711 @@ func TestPointsWriter_WritePoints(t *testing.T) {
 		if err != nil && test.expErr != nil && err.Error() != test.expErr.Error() {
 			t.Errorf("PointsWriter.WritePoints(): '%s' error: got %v, exp %v", test.name, err, test.expErr)
 		}
+
+		// Successful writes should be mirrored to subscribers.
+		if err == nil {
+			select {
+			case p := <-subPoints:
+				if p != pr {
+					t.Errorf("PointsWriter.WritePoints(): '%s' error: unexpected WritePointsRequest got %v, exp %v", test.name, p, pr)
+				}
+			default:
+				t.Errorf("PointsWriter.WritePoints(): '%s' error: Subscriber.Points not called", test.name)
+			}
+		}
+		c.Close()
+	}
+}
+
+// Ensures the points writer counts the points written and dropped per database.
+func TestPointsWriter_WritePoints_DatabaseStatistics(t *testing.T) {
+	var fail int32
+	writeFn := func(shardID uint64, points []tsdb.Point) error {
+		if atomic.LoadInt32(&fail) == 1 {
+			return fmt.Errorf("a failure")
+		}
+		return nil
+	}
+
+	ms := NewMetaStore()
+	ms.NodeIDFn = func() uint64 { return 1 }
+	c := cluster.NewPointsWriter()
+	c.MetaStore = ms
+	c.ShardWriter = &fakeShardWriter{ShardWriteFn: func(shardID, nodeID uint64, points []tsdb.Point) error { return writeFn(shardID, points) }}
+	c.TSDBStore = &fakeStore{WriteFn: writeFn}
+	c.HintedHandoff = &fakeShardWriter{ShardWriteFn: func(shardID, nodeID uint64, points []tsdb.Point) error { return nil }}
+	c.Open()
+	defer c.Close()
+
+	// Write n points, each an hour apart so they map to different shards.
+	write := func(n int) error {
+		pr := &cluster.WritePointsRequest{Database: "statsdb", RetentionPolicy: "myrp", ConsistencyLevel: cluster.ConsistencyLevelOne}
+		for i := 0; i < n; i++ {
+			pr.AddPoint("cpu", float64(i), time.Unix(0, 0).Add(time.Duration(i)*time.Hour), nil)
+		}
+		return c.WritePoints(pr)
+	}
+
+	// The statistics are process-wide so compare against the starting values.
+	stats := func() map[string]int64 {
+		m := make(map[string]int64)
+		if err := json.Unmarshal([]byte(influxdb.DatabaseStatistics("statsdb").String()), &m); err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	before := stats()
+
+	if err := write(2); err != nil {
+		t.Fatal(err)
+	}
+
+	// A failed write returns as soon as one shard fails so only write one.
+	atomic.StoreInt32(&fail, 1)
+	if err := write(1); err == nil {
+		t.Fatal("expected error")
+	}
+
+	after := stats()
+	for k, exp := range map[string]int64{"write_req": 2, "write_error": 1, "points_written": 2, "points_dropped": 1} {
+		if n := after[k] - before[k]; n != exp {
+			t.Errorf("unexpected %s: got %d, exp %d", k, n, exp)
+		}
+	}
+}
+
+// Ensures the points writer only writes the points accepted by the schema.
+func TestPointsWriter_WritePoints_Schema(t *testing.T) {
+	var written int32
+	writeFn := func(shardID uint64, points []tsdb.Point) error {
+		atomic.AddInt32(&written, int32(len(points)))
+		return nil
+	}
+
+	ms := NewMetaStore()
+	ms.NodeIDFn = func() uint64 { return 1 }
+	c := cluster.NewPointsWriter()
+	c.MetaStore = ms
+	c.ShardWriter = &fakeShardWriter{ShardWriteFn: func(shardID, nodeID uint64, points []tsdb.Point) error { return writeFn(shardID, points) }}
+	c.TSDBStore = &fakeStore{WriteFn: writeFn}
+	c.HintedHandoff = &fakeShardWriter{ShardWriteFn: func(shardID, nodeID uint64, points []tsdb.Point) error { return nil }}
+	c.Schema = &Schema{FilterFn: func(database string, points []tsdb.Point) ([]tsdb.Point, error) {
+		if database != "db0" {
+			t.Fatalf("unexpected database: %s", database)
+		} else if len(points) == 1 {
+			return nil, fmt.Errorf("schema violation")
+		}
+		return points[:1], nil
+	}}
+	c.Open()
+	defer c.Close()
+
+	pr := &cluster.WritePointsRequest{Database: "db0", RetentionPolicy: "myrp", ConsistencyLevel: cluster.ConsistencyLevelOne}
+	pr.AddPoint("cpu", 1.0, time.Unix(0, 0), nil)
+	if err := c.WritePoints(pr); err == nil || err.Error() != "schema violation" {
+		t.Fatalf("unexpected error: %v", err)
+	} else if n := atomic.LoadInt32(&written); n != 0 {
+		t.Fatalf("unexpected points written: %d", n)
+	}
+
+	pr.AddPoint("cpu", 2.0, time.Unix(0, 0), nil)
+	if err := c.WritePoints(pr); err != nil {
+		t.Fatal(err)
+	} else if n := atomic.LoadInt32(&written); n != 1 {
+		t.Fatalf("unexpected points written: %d", n)
+	}
+}
+
+// Ensures the points writer applies the tag rules of the database.
+func TestPointsWriter_WritePoints_TagRules(t *testing.T) {
+	var mu sync.Mutex
+	var keys []string
+	writeFn := func(shardID uint64, points []tsdb.Point) error {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, p := range points {
+			keys = append(keys, string(p.Key()))
+		}
+		return nil
+	}
+
+	ms := NewMetaStore()
+	ms.NodeIDFn = func() uint64 { return 1 }
+	ms.DatabaseFn = func(database string) (*meta.DatabaseInfo, error) {
+		return &meta.DatabaseInfo{
+			Name:                   database,
+			DefaultRetentionPolicy: "myp",
+			TagRules: []meta.TagRuleInfo{
+				{Name: "lower", Key: "host", Action: meta.TagRuleLowercase},
+			},
+		}, nil
+	}
+	c := cluster.NewPointsWriter()
+	c.MetaStore = ms
+	c.ShardWriter = &fakeShardWriter{ShardWriteFn: func(shardID, nodeID uint64, points []tsdb.Point) error { return writeFn(shardID, points) }}
+	c.TSDBStore = &fakeStore{WriteFn: writeFn}
+	c.HintedHandoff = &fakeShardWriter{ShardWriteFn: func(shardID, nodeID uint64, points []tsdb.Point) error { return nil }}
+	c.Open()
+	defer c.Close()
+
+	pr := &cluster.WritePointsRequest{Database: "db0", ConsistencyLevel: cluster.ConsistencyLevelAll}
+	pr.AddPoint("cpu", 1.0, time.Unix(0, 0), map[string]string{"host": "WebA", "region": "US"})
+	if err := c.WritePoints(pr); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(keys, []string{"cpu,host=weba,region=US", "cpu,host=weba,region=US", "cpu,host=weba,region=US"}) {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+}
+
+// Ensures the points writer routes measurements written without a retention
+// policy.
+func TestPointsWriter_WritePoints_Routes(t *testing.T) {
+	var mu sync.Mutex
+	policies := make(map[string]bool)
+	ms := NewMetaStore()
+	ms.NodeIDFn = func() uint64 { return 1 }
+	ms.RetentionPolicyFn = func(database, policy string) (*meta.RetentionPolicyInfo, error) {
+		rp := NewRetentionPolicy(policy, time.Hour, 1)
+		AttachShardGroupInfo(rp, []meta.ShardOwner{{NodeID: 1}})
+		return rp, nil
+	}
+	ms.CreateShardGroupIfNotExistsFn = func(database, policy string, timestamp time.Time) (*meta.ShardGroupInfo, error) {
+		sg := &meta.ShardGroupInfo{ID: 1, StartTime: timestamp, EndTime: timestamp.Add(time.Hour)}
+		sg.Shards = []meta.ShardInfo{{ID: 1, Owners: []meta.ShardOwner{{NodeID: 1}}}}
+
+		mu.Lock()
+		defer mu.Unlock()
+		policies[policy] = true
+		return sg, nil
+	}
+
+	c := cluster.NewPointsWriter()
+	c.MetaStore = ms
+	c.SetRoutes([]cluster.Route{{Database: "db0", Measurements: []string{"mem"}, RetentionPolicy: "7d"}})
+	c.TSDBStore = &fakeStore{WriteFn: func(shardID uint64, points []tsdb.Point) error { return nil }}
+	c.Open()
+	defer c.Close()
+
+	pr := &cluster.WritePointsRequest{Database: "db0", ConsistencyLevel: cluster.ConsistencyLevelOne}
+	pr.AddPoint("cpu", 1.0, time.Unix(0, 0), nil)
+	pr.AddPoint("mem", 1.0, time.Unix(0, 0), nil)
+	if err := c.WritePoints(pr); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(policies, map[string]bool{"myp": true, "7d": true}) {
+		t.Fatalf("unexpected retention policies: %v", policies)
+	}
+
+	// Points written to an explicit retention policy aren't routed.
+	policies = make(map[string]bool)
+	pr = &cluster.WritePointsRequest{Database: "db0", RetentionPolicy: "30d", ConsistencyLevel: cluster.ConsistencyLevelOne}
+	pr.AddPoint("mem", 1.0, time.Unix(0, 0), nil)
+	if err := c.WritePoints(pr); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(policies, map[string]bool{"30d": true}) {
+		t.Fatalf("unexpected retention policies: %v", policies)
+	}
+}
+
+// Ensures the points writer drops points outside the allowed time range.
+func TestPointsWriter_WritePoints_TimeBounds(t *testing.T) {
+	var written int32
+	writeFn := func(shardID uint64, points []tsdb.Point) error {
+		atomic.AddInt32(&written, int32(len(points)))
+		return nil
+	}
+
+	ms := NewMetaStore()
+	ms.NodeIDFn = func() uint64 { return 1 }
+	ms.RetentionPolicyFn = func(database, policy string) (*meta.RetentionPolicyInfo, error) {
+		rp := NewRetentionPolicy(policy, time.Hour, 1)
+		rp.Duration = 7 * 24 * time.Hour
+		AttachShardGroupInfo(rp, []meta.ShardOwner{{NodeID: 1}})
+		return rp, nil
+	}
+	ms.CreateShardGroupIfNotExistsFn = func(database, policy string, timestamp time.Time) (*meta.ShardGroupInfo, error) {
+		sg := &meta.ShardGroupInfo{ID: 1, StartTime: timestamp, EndTime: timestamp.Add(time.Hour)}
+		sg.Shards = []meta.ShardInfo{{ID: 1, Owners: []meta.ShardOwner{{NodeID: 1}}}}
+		return sg, nil
+	}
+
+	c := cluster.NewPointsWriter()
+	c.MetaStore = ms
+	c.MaxFutureTime = 24 * time.Hour
+	c.RejectBeyondRetention = true
+	c.TSDBStore = &fakeStore{WriteFn: writeFn}
+	c.Open()
+	defer c.Close()
+
+	now := time.Now()
+	pr := &cluster.WritePointsRequest{Database: "db0", RetentionPolicy: "myrp", ConsistencyLevel: cluster.ConsistencyLevelOne}
+	pr.AddPoint("cpu", 1.0, now, nil)
+	pr.AddPoint("cpu", 2.0, now.Add(48*time.Hour), nil)
+	pr.AddPoint("cpu", 3.0, now.Add(-8*24*time.Hour), nil)
+	if err := c.WritePoints(pr); err == nil || err.Error() != "partial write: point time outside the allowed range: dropped 2 of 3 points" {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !influxdb.IsClientError(err) {
+		t.Fatalf("expected client error: %v", err)
+	} else if n := atomic.LoadInt32(&written); n != 1 {
+		t.Fatalf("unexpected points written: %d", n)
+	}
+
+	// A write is rejected if none of its points are in range.
+	pr = &cluster.WritePointsRequest{Database: "db0", RetentionPolicy: "myrp", ConsistencyLevel: cluster.ConsistencyLevelOne}
+	pr.AddPoint("cpu", 1.0, now.Add(48*time.Hour), nil)
+	if err := c.WritePoints(pr); err == nil || err.Error() != "point time outside the allowed range: dropped 1 points" {
+		t.Fatalf("unexpected error: %v", err)
+	} else if n := atomic.LoadInt32(&written); n != 1 {
+		t.Fatalf("unexpected points written: %d", n)
+	}
+}
+
+// Ensures the points writer records the reasons points were dropped for.
+func TestPointsWriter_WritePoints_DroppedStatistics(t *testing.T) {
+	var conflict int32
+	writeFn := func(shardID uint64, points []tsdb.Point) error {
+		if atomic.LoadInt32(&conflict) == 1 {
+			return fmt.Errorf("write failed: %s", influxdb.ErrFieldTypeConflict)
+		}
+		return nil
+	}
+
+	ms := NewMetaStore()
+	ms.NodeIDFn = func() uint64 { return 1 }
+	ms.RetentionPolicyFn = func(database, policy string) (*meta.RetentionPolicyInfo, error) {
+		rp := NewRetentionPolicy(policy, time.Hour, 1)
+		rp.Duration = 7 * 24 * time.Hour
+		AttachShardGroupInfo(rp, []meta.ShardOwner{{NodeID: 1}})
+		return rp, nil
+	}
+	ms.CreateShardGroupIfNotExistsFn = func(database, policy string, timestamp time.Time) (*meta.ShardGroupInfo, error) {
+		sg := &meta.ShardGroupInfo{ID: 1, StartTime: timestamp, EndTime: timestamp.Add(time.Hour)}
+		sg.Shards = []meta.ShardInfo{{ID: 1, Owners: []meta.ShardOwner{{NodeID: 1}}}}
+		return sg, nil
+	}
+
+	c := cluster.NewPointsWriter()
+	c.MetaStore = ms
+	c.MaxFutureTime = 24 * time.Hour
+	c.MaxPastTime = 24 * time.Hour
+	c.RejectBeyondRetention = true
+	c.TSDBStore = &fakeStore{WriteFn: writeFn}
+	c.Schema = &Schema{FilterFn: func(database string, points []tsdb.Point) ([]tsdb.Point, error) {
+		var a []tsdb.Point
+		for _, p := range points {
+			if p.Name() != "bad" {
+				a = append(a, p)
+			}
+		}
+		return a, nil
+	}}
+	c.Open()
+	defer c.Close()
+
+	stats := func() map[string]int64 {
+		m := make(map[string]int64)
+		if err := json.Unmarshal([]byte(influxdb.DatabaseStatistics("dropdb").String()), &m); err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	before := stats()
+
+	now := time.Now()
+	pr := &cluster.WritePointsRequest{Database: "dropdb", RetentionPolicy: "myrp", ConsistencyLevel: cluster.ConsistencyLevelOne}
+	pr.AddPoint("cpu", 1.0, now, nil)
+	pr.AddPoint("bad", 1.0, now, nil)
+	pr.AddPoint("cpu", 2.0, now.Add(48*time.Hour), nil)
+	pr.AddPoint("cpu", 3.0, now.Add(-48*time.Hour), nil)
+	pr.AddPoint("cpu", 4.0, now.Add(-8*24*time.Hour), nil)
+	if err := c.WritePoints(pr); err == nil {
+		t.Fatal("expected error")
+	}
+
+	atomic.StoreInt32(&conflict, 1)
+	pr = &cluster.WritePointsRequest{Database: "dropdb", RetentionPolicy: "myrp", ConsistencyLevel: cluster.ConsistencyLevelOne}
+	pr.AddPoint("cpu", 1.0, now, nil)
+	if err := c.WritePoints(pr); err == nil {
+		t.Fatal("expected error")
+	}
+
+	after := stats()
+	for k, exp := range map[string]int64{
+		"points_written":               1,
+		"points_dropped":               5,
+		"points_dropped_schema":        1,
+		"points_dropped_future":        1,
+		"points_dropped_past":          1,
+		"points_dropped_retention":     1,
+		"points_dropped_type_conflict": 1,
+	} {
+		if n := after[k] - before[k]; n != exp {
+			t.Errorf("unexpected %s: got %d, exp %d", k, n, exp)
+		}
+	}
+}
+
+// Ensures the points writer ignores retried writes of a batch that was written.
+func TestPointsWriter_WritePoints_BatchID(t *testing.T) {
+	var written int32
+	var fail bool
+	writeFn := func(shardID uint64, points []tsdb.Point) error {
+		if fail {
+			return errors.New("marker")
+		}
+		atomic.AddInt32(&written, int32(len(points)))
+		return nil
+	}
+
+	ms := NewMetaStore()
+	ms.NodeIDFn = func() uint64 { return 1 }
+	ms.RetentionPolicyFn = func(database, policy string) (*meta.RetentionPolicyInfo, error) {
+		rp := NewRetentionPolicy(policy, time.Hour, 1)
+		AttachShardGroupInfo(rp, []meta.ShardOwner{{NodeID: 1}})
+		return rp, nil
+	}
+	ms.CreateShardGroupIfNotExistsFn = func(database, policy string, timestamp time.Time) (*meta.ShardGroupInfo, error) {
+		sg := &meta.ShardGroupInfo{ID: 1, StartTime: timestamp, EndTime: timestamp.Add(time.Hour)}
+		sg.Shards = []meta.ShardInfo{{ID: 1, Owners: []meta.ShardOwner{{NodeID: 1}}}}
+		return sg, nil
+	}
+
+	c := cluster.NewPointsWriter()
+	c.MetaStore = ms
+	c.BatchIDTTL = time.Minute
+	c.MaxBatchIDs = 2
+	c.TSDBStore = &fakeStore{WriteFn: writeFn}
+	c.Open()
+	defer c.Close()
+
+	write := func(database, id string) error {
+		pr := &cluster.WritePointsRequest{Database: database, RetentionPolicy: "myrp", ConsistencyLevel: cluster.ConsistencyLevelOne, BatchID: id}
+		pr.AddPoint("cpu", 1.0, time.Unix(0, 0), nil)
+		return c.WritePoints(pr)
+	}
+
+	// A failed write is not remembered, so it can be retried.
+	fail = true
+	if err := write("db0", "a"); err == nil {
+		t.Fatal("expected error")
+	}
+	fail = false
+	for i, tt := range []struct {
+		database string
+		id       string
+		written  int32
+	}{
+		{"db0", "a", 1},
+		{"db0", "a", 1}, // duplicate
+		{"db1", "a", 2}, // IDs are per database
+		{"db0", "", 3},  // writes without an ID are never duplicates
+		{"db0", "", 4},
+		{"db0", "b", 5},
+		{"db0", "c", 6}, // evicts "a"
+		{"db0", "a", 7},
+	} {
+		if err := write(tt.database, tt.id); err != nil {
+			t.Fatalf("%d: %s", i, err)
+		} else if n := atomic.LoadInt32(&written); n != tt.written {
+			t.Fatalf("%d: unexpected points written: %d", i, n)
+		}
+	}
+}
+
+// Ensures the points writer doesn't write a batch while another write of the
+// same batch ID is in progress.
+func TestPointsWriter_WritePoints_BatchIDInProgress(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	var written int32
+	writeFn := func(shardID uint64, points []tsdb.Point) error {
+		close(started)
+		<-release
+		atomic.AddInt32(&written, int32(len(points)))
+		return nil
+	}
+
+	ms := NewMetaStore()
+	ms.NodeIDFn = func() uint64 { return 1 }
+	ms.RetentionPolicyFn = func(database, policy string) (*meta.RetentionPolicyInfo, error) {
+		rp := NewRetentionPolicy(policy, time.Hour, 1)
+		AttachShardGroupInfo(rp, []meta.ShardOwner{{NodeID: 1}})
+		return rp, nil
+	}
+	ms.CreateShardGroupIfNotExistsFn = func(database, policy string, timestamp time.Time) (*meta.ShardGroupInfo, error) {
+		sg := &meta.ShardGroupInfo{ID: 1, StartTime: timestamp, EndTime: timestamp.Add(time.Hour)}
+		sg.Shards = []meta.ShardInfo{{ID: 1, Owners: []meta.ShardOwner{{NodeID: 1}}}}
+		return sg, nil
+	}
+
+	c := cluster.NewPointsWriter()
+	c.MetaStore = ms
+	c.BatchIDTTL = time.Minute
+	c.TSDBStore = &fakeStore{WriteFn: writeFn}
+	c.Open()
+	defer c.Close()
+
+	write := func() error {
+		pr := &cluster.WritePointsRequest{Database: "db0", RetentionPolicy: "myrp", ConsistencyLevel: cluster.ConsistencyLevelOne, BatchID: "a"}
+		pr.AddPoint("cpu", 1.0, time.Unix(0, 0), nil)
+		return c.WritePoints(pr)
+	}
+
+	errs := make(chan error)
+	go func() { errs <- write() }()
+	<-started
+
+	// A concurrent retry fails until the first write finishes.
+	if err := write(); err != cluster.ErrBatchInProgress {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	close(release)
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+
+	// Once written, retries are ignored.
+	if err := write(); err != nil {
+		t.Fatal(err)
+	} else if n := atomic.LoadInt32(&written); n != 1 {
+		t.Fatalf("unexpected points written: %d", n)
+	}
+}
+
+// Ensures the points writer ignores points written within the dedup window.
+func TestPointsWriter_WritePoints_Dedup(t *testing.T) {
+	var written int32
+	var fail bool
+	var block, entered chan struct{}
+	writeFn := func(shardID uint64, points []tsdb.Point) error {
+		if fail {
+			return errors.New("marker")
+		} else if block != nil {
+			close(entered)
+			<-block
+		}
+		atomic.AddInt32(&written, int32(len(points)))
+		return nil
+	}
+
+	ms := NewMetaStore()
+	ms.NodeIDFn = func() uint64 { return 1 }
+	ms.RetentionPolicyFn = func(database, policy string) (*meta.RetentionPolicyInfo, error) {
+		rp := NewRetentionPolicy(policy, time.Hour, 1)
+		AttachShardGroupInfo(rp, []meta.ShardOwner{{NodeID: 1}})
+		return rp, nil
+	}
+	ms.CreateShardGroupIfNotExistsFn = func(database, policy string, timestamp time.Time) (*meta.ShardGroupInfo, error) {
+		sg := &meta.ShardGroupInfo{ID: 1, StartTime: timestamp, EndTime: timestamp.Add(time.Hour)}
+		sg.Shards = []meta.ShardInfo{{ID: 1, Owners: []meta.ShardOwner{{NodeID: 1}}}}
+		return sg, nil
+	}
+
+	c := cluster.NewPointsWriter()
+	c.MetaStore = ms
+	c.SetDedupWindows([]cluster.DedupWindow{{Database: "db0", Window: toml.Duration(time.Minute), MaxPoints: 2}})
+	c.TSDBStore = &fakeStore{WriteFn: writeFn}
+	c.Open()
+	defer c.Close()
+
+	write := func(database string, values ...float64) error {
+		pr := &cluster.WritePointsRequest{Database: database, RetentionPolicy: "myrp", ConsistencyLevel: cluster.ConsistencyLevelOne}
+		for _, v := range values {
+			pr.AddPoint("cpu", v, time.Unix(0, 0), nil)
+		}
+		return c.WritePoints(pr)
+	}
+
+	// A failed write is not remembered, so it can be retried.
+	fail = true
+	if err := write("db0", 1); err == nil {
+		t.Fatal("expected error")
+	}
+	fail = false
+	for i, tt := range []struct {
+		database string
+		values   []float64
+		written  int32
+	}{
+		{"db0", []float64{1}, 1},
+		{"db0", []float64{1}, 1},       // duplicate
+		{"db0", []float64{2, 2, 1}, 2}, // duplicates within the write
+		{"db1", []float64{1}, 3},       // databases without a window aren't deduplicated
+		{"db1", []float64{1}, 4},
+		{"db0", []float64{3}, 5}, // evicts 1
+		{"db0", []float64{1}, 6},
+	} {
+		if err := write(tt.database, tt.values...); err != nil {
+			t.Fatalf("%d: %s", i, err)
+		} else if n := atomic.LoadInt32(&written); n != tt.written {
+			t.Fatalf("%d: unexpected points written: %d", i, n)
+		}
+	}
+
+	// A point being written is a duplicate in a concurrent write.
+	block, entered = make(chan struct{}), make(chan struct{})
+	done := make(chan error)
+	go func() { done <- write("db0", 4) }()
+	<-entered
+	if err := write("db0", 4); err != nil {
+		t.Fatal(err)
+	}
+	close(block)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	} else if n := atomic.LoadInt32(&written); n != 7 {
+		t.Fatalf("unexpected points written: %d", n)
+	}
+}
+
+// Ensures the points writer rejects writes to a database over its rate limit.
+func TestPointsWriter_WritePoints_DatabaseLimits(t *testing.T) {
+	var written int32
+	writeFn := func(shardID uint64, points []tsdb.Point) error {
+		atomic.AddInt32(&written, int32(len(points)))
+		return nil
+	}
+
+	ms := NewMetaStore()
+	ms.NodeIDFn = func() uint64 { return 1 }
+	ms.RetentionPolicyFn = func(database, policy string) (*meta.RetentionPolicyInfo, error) {
+		rp := NewRetentionPolicy(policy, time.Hour, 1)
+		AttachShardGroupInfo(rp, []meta.ShardOwner{{NodeID: 1}})
+		return rp, nil
+	}
+	ms.CreateShardGroupIfNotExistsFn = func(database, policy string, timestamp time.Time) (*meta.ShardGroupInfo, error) {
+		sg := &meta.ShardGroupInfo{ID: 1, StartTime: timestamp, EndTime: timestamp.Add(time.Hour)}
+		sg.Shards = []meta.ShardInfo{{ID: 1, Owners: []meta.ShardOwner{{NodeID: 1}}}}
+		return sg, nil
+	}
+
+	c := cluster.NewPointsWriter()
+	c.MetaStore = ms
+	c.SetDatabaseLimits([]cluster.DatabaseLimit{{Database: "db0", MaxPointsPerSecond: 2}, {Database: "db1", MaxQueries: 1}})
+	c.TSDBStore = &fakeStore{WriteFn: writeFn}
+	c.Open()
+	defer c.Close()
+
+	write := func(database string, n int) error {
+		pr := &cluster.WritePointsRequest{Database: database, RetentionPolicy: "myrp", ConsistencyLevel: cluster.ConsistencyLevelOne}
+		for i := 0; i < n; i++ {
+			pr.AddPoint("cpu", float64(i), time.Unix(int64(i), 0), nil)
+		}
+		return c.WritePoints(pr)
+	}
+
+	// A write larger than the rate succeeds, but the next is rejected.
+	if err := write("db0", 3); err != nil {
+		t.Fatal(err)
+	} else if err := write("db0", 1); err != cluster.ErrWriteLimitExceeded {
+		t.Fatalf("unexpected error: %v", err)
+	} else if n := atomic.LoadInt32(&written); n != 3 {
+		t.Fatalf("unexpected points written: %d", n)
+	}
+
+	// Other databases aren't limited.
+	for i := 0; i < 3; i++ {
+		if err := write("db1", 10); err != nil {
+			t.Fatal(err)
+		} else if err := write("db2", 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Removing the limit allows writes again.
+	c.SetDatabaseLimits(nil)
+	if err := write("db0", 1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Ensures failed remote writes are retried and writes to a failing node stop
+// being sent until its breaker's cooldown passes.
+func TestPointsWriter_WritePoints_RetryBreaker(t *testing.T) {
+	var attempts, failures, hh int32
+	ms := NewMetaStore()
+	ms.NodeIDFn = func() uint64 { return 1 }
+	ms.RetentionPolicyFn = func(database, policy string) (*meta.RetentionPolicyInfo, error) {
+		rp := NewRetentionPolicy(policy, time.Hour, 1)
+		AttachShardGroupInfo(rp, []meta.ShardOwner{{NodeID: 2}})
+		return rp, nil
+	}
+	ms.CreateShardGroupIfNotExistsFn = func(database, policy string, timestamp time.Time) (*meta.ShardGroupInfo, error) {
+		sg := &meta.ShardGroupInfo{ID: 1, StartTime: timestamp, EndTime: timestamp.Add(time.Hour)}
+		sg.Shards = []meta.ShardInfo{{ID: 1, Owners: []meta.ShardOwner{{NodeID: 2}}}}
+		return sg, nil
+	}
+
+	c := cluster.NewPointsWriter()
+	c.MetaStore = ms
+	c.WriteTimeout = time.Second
+	c.WriteRetries, c.RetryInterval = 2, time.Millisecond
+	c.BreakerFailures, c.BreakerCooldown = 3, 50*time.Millisecond
+	c.ShardWriter = &fakeShardWriter{ShardWriteFn: func(shardID, nodeID uint64, points []tsdb.Point) error {
+		atomic.AddInt32(&attempts, 1)
+		if atomic.AddInt32(&failures, -1) >= 0 {
+			return fmt.Errorf("timeout")
+		}
+		return nil
+	}}
+	c.HintedHandoff = &fakeShardWriter{ShardWriteFn: func(shardID, nodeID uint64, points []tsdb.Point) error {
+		atomic.AddInt32(&hh, 1)
+		return nil
+	}}
+	c.Open()
+	defer c.Close()
+
+	write := func(fail int32) error {
+		atomic.StoreInt32(&attempts, 0)
+		atomic.StoreInt32(&failures, fail)
+		pr := &cluster.WritePointsRequest{Database: "db0", RetentionPolicy: "myrp", ConsistencyLevel: cluster.ConsistencyLevelOne}
+		pr.AddPoint("cpu", 1.0, time.Unix(0, 0), nil)
+		return c.WritePoints(pr)
+	}
+
+	// Failures up to the retries succeed on a retry.
+	if err := write(2); err != nil {
+		t.Fatal(err)
+	} else if n := atomic.LoadInt32(&attempts); n != 3 {
+		t.Fatalf("unexpected attempts: %d", n)
+	}
+
+	// Failing every retry opens the breaker, so the next write isn't sent.
+	if err := write(3); err == nil {
+		t.Fatal("expected error")
+	} else if err := write(0); err == nil || err.Error() != "write failed: node unavailable" {
+		t.Fatalf("unexpected error: %v", err)
+	} else if n := atomic.LoadInt32(&attempts); n != 0 {
+		t.Fatalf("unexpected attempts: %d", n)
+	} else if n := atomic.LoadInt32(&hh); n != 2 {
+		t.Fatalf("unexpected hinted handoff writes: %d", n)
+	}
+
+	// Once the cooldown passes a write probes the node and closes the breaker.
+	time.Sleep(50 * time.Millisecond)
+	if err := write(0); err != nil {
+		t.Fatal(err)
+	} else if err := write(0); err != nil {
+		t.Fatal(err)
 	}
 }
 
 var shardID uint64
 
+type Schema struct {
+	FilterFn func(database string, points []tsdb.Point) ([]tsdb.Point, error)
+}
+
+func (s *Schema) Filter(database string, points []tsdb.Point) ([]tsdb.Point, error) {
+	return s.FilterFn(database, points)
+}
+
+type Subscriber struct {
+	PointsFn func() chan<- *cluster.WritePointsRequest
+}
+
+func (s Subscriber) Points() chan<- *cluster.WritePointsRequest {
+	return s.PointsFn()
+}
+
 type fakeShardWriter struct {
 	ShardWriteFn func(shardID, nodeID uint64, points []tsdb.Point) error
 }
@@ -377,6 +1091,10 @@ func NewMetaStore() *MetaStore {
 		}
 		panic("should not get here")
 	}
+
+	ms.DatabaseFn = func(database string) (*meta.DatabaseInfo, error) {
+		return &meta.DatabaseInfo{Name: database, DefaultRetentionPolicy: "myp"}, nil
+	}
 	return ms
 }
 
@@ -0,0 +1,66 @@
+package cluster
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter limits the rate of points written to each database.
+// The zero value is ready to use and limits nothing.
+type rateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// set replaces the points per second allowed for each database. Databases
+// not in rates, or with a rate of zero, are unlimited.
+func (l *rateLimiter) set(rates map[string]int, now time.Time) {
+	buckets := make(map[string]*tokenBucket)
+	for name, rate := range rates {
+		if rate > 0 {
+			buckets[name] = newTokenBucket(float64(rate), now)
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.buckets = buckets
+}
+
+// allow returns true if n points may be written to database at now.
+func (l *rateLimiter) allow(database string, n int, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b := l.buckets[database]
+	return b == nil || b.take(float64(n), now)
+}
+
+// tokenBucket refills at rate tokens per second, up to one second's worth.
+// A take may leave the bucket in debt so requests larger than the bucket
+// still succeed, but later ones fail until the debt is refilled.
+type tokenBucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket returns a full bucket refilling at rate tokens per second.
+func newTokenBucket(rate float64, now time.Time) *tokenBucket {
+	return &tokenBucket{rate: rate, tokens: rate, last: now}
+}
+
+// take removes n tokens at now. Returns false, taking nothing, if the bucket is empty.
+func (b *tokenBucket) take(n float64, now time.Time) bool {
+	if now.After(b.last) {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.rate {
+			b.tokens = b.rate
+		}
+		b.last = now
+	}
+	if b.tokens <= 0 {
+		return false
+	}
+	b.tokens -= n
+	return true
+}
@@ -16,13 +16,17 @@ type MapShardRequest struct {
 	pb internal.MapShardRequest
 }
 
-func (m *MapShardRequest) ShardID() uint64  { return m.pb.GetShardID() }
-func (m *MapShardRequest) Query() string    { return m.pb.GetQuery() }
-func (m *MapShardRequest) ChunkSize() int32 { return m.pb.GetChunkSize() }
+func (m *MapShardRequest) ShardID() uint64   { return m.pb.GetShardID() }
+func (m *MapShardRequest) Query() string     { return m.pb.GetQuery() }
+func (m *MapShardRequest) ChunkSize() int32  { return m.pb.GetChunkSize() }
+func (m *MapShardRequest) WindowSize() int32 { return m.pb.GetWindowSize() }
+func (m *MapShardRequest) Compress() bool    { return m.pb.GetCompress() }
 
 func (m *MapShardRequest) SetShardID(id uint64)         { m.pb.ShardID = &id }
 func (m *MapShardRequest) SetQuery(query string)        { m.pb.Query = &query }
 func (m *MapShardRequest) SetChunkSize(chunkSize int32) { m.pb.ChunkSize = &chunkSize }
+func (m *MapShardRequest) SetWindowSize(n int32)        { m.pb.WindowSize = &n }
+func (m *MapShardRequest) SetCompress(compress bool)    { m.pb.Compress = &compress }
 
 // MarshalBinary encodes the object to a binary format.
 func (m *MapShardRequest) MarshalBinary() ([]byte, error) {
@@ -54,12 +58,14 @@ func (r *MapShardResponse) Message() string   { return r.pb.GetMessage() }
 func (r *MapShardResponse) TagSets() []string { return r.pb.GetTagSets() }
 func (r *MapShardResponse) Fields() []string  { return r.pb.GetFields() }
 func (r *MapShardResponse) Data() []byte      { return r.pb.GetData() }
+func (r *MapShardResponse) Compressed() bool  { return r.pb.GetCompressed() }
 
-func (r *MapShardResponse) SetCode(code int)            { r.pb.Code = proto.Int32(int32(code)) }
-func (r *MapShardResponse) SetMessage(message string)   { r.pb.Message = &message }
-func (r *MapShardResponse) SetTagSets(tagsets []string) { r.pb.TagSets = tagsets }
-func (r *MapShardResponse) SetFields(fields []string)   { r.pb.Fields = fields }
-func (r *MapShardResponse) SetData(data []byte)         { r.pb.Data = data }
+func (r *MapShardResponse) SetCode(code int)              { r.pb.Code = proto.Int32(int32(code)) }
+func (r *MapShardResponse) SetMessage(message string)     { r.pb.Message = &message }
+func (r *MapShardResponse) SetTagSets(tagsets []string)   { r.pb.TagSets = tagsets }
+func (r *MapShardResponse) SetFields(fields []string)     { r.pb.Fields = fields }
+func (r *MapShardResponse) SetData(data []byte)           { r.pb.Data = data }
+func (r *MapShardResponse) SetCompressed(compressed bool) { r.pb.Compressed = &compressed }
 
 // MarshalBinary encodes the object to a binary format.
 func (r *MapShardResponse) MarshalBinary() ([]byte, error) {
@@ -74,12 +80,38 @@ func (r *MapShardResponse) UnmarshalBinary(buf []byte) error {
 	return nil
 }
 
+// MapShardAck is sent by a remote mapper to grant the serving node credit
+// to send N more chunks of a windowed map shard response stream.
+type MapShardAck struct {
+	pb internal.MapShardAck
+}
+
+func (a *MapShardAck) N() int32     { return a.pb.GetN() }
+func (a *MapShardAck) SetN(n int32) { a.pb.N = &n }
+
+// MarshalBinary encodes the object to a binary format.
+func (a *MapShardAck) MarshalBinary() ([]byte, error) {
+	return proto.Marshal(&a.pb)
+}
+
+// UnmarshalBinary populates MapShardAck from a binary format.
+func (a *MapShardAck) UnmarshalBinary(buf []byte) error {
+	if err := proto.Unmarshal(buf, &a.pb); err != nil {
+		return err
+	}
+	return nil
+}
+
 // WritePointsRequest represents a request to write point data to the cluster
 type WritePointsRequest struct {
 	Database         string
 	RetentionPolicy  string
 	ConsistencyLevel ConsistencyLevel
 	Points           []tsdb.Point
+
+	// BatchID optionally identifies the batch of points, so that a retried
+	// write of a batch that already succeeded is ignored.
+	BatchID string
 }
 
 // AddPoint adds a point to the WritePointRequest with field name 'value'
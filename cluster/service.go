@@ -5,13 +5,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net"
 	"os"
 	"strings"
 	"sync"
 
+	"github.com/golang/snappy"
 	"github.com/influxdb/influxdb/influxql"
+	"github.com/influxdb/influxdb/logger"
 	"github.com/influxdb/influxdb/meta"
 	"github.com/influxdb/influxdb/tsdb"
 )
@@ -41,21 +42,21 @@ type Service struct {
 		CreateMapper(shardID uint64, stmt influxql.Statement, chunkSize int) (tsdb.Mapper, error)
 	}
 
-	Logger *log.Logger
+	Logger *logger.Logger
 }
 
 // NewService returns a new instance of Service.
 func NewService(c Config) *Service {
 	return &Service{
 		closing: make(chan struct{}),
-		Logger:  log.New(os.Stderr, "[tcp] ", log.LstdFlags),
+		Logger:  logger.New(os.Stderr, "cluster"),
 	}
 }
 
 // Open opens the network listener and begins serving requests.
 func (s *Service) Open() error {
 
-	s.Logger.Println("Starting cluster service")
+	s.Logger.Info("Starting cluster service")
 	// Begin serving conections.
 	s.wg.Add(1)
 	go s.serve()
@@ -64,7 +65,7 @@ func (s *Service) Open() error {
 }
 
 // SetLogger sets the internal logger to the logger passed in.
-func (s *Service) SetLogger(l *log.Logger) {
+func (s *Service) SetLogger(l *logger.Logger) {
 	s.Logger = l
 }
 
@@ -84,10 +85,10 @@ func (s *Service) serve() {
 		conn, err := s.Listener.Accept()
 		if err != nil {
 			if strings.Contains(err.Error(), "connection closed") {
-				s.Logger.Printf("cluster service accept error: %s", err)
+				s.Logger.Info("cluster service accept error", "err", err)
 				return
 			}
-			s.Logger.Printf("accept error: %s", err)
+			s.Logger.Error("accept error", "err", err)
 			continue
 		}
 
@@ -126,9 +127,9 @@ func (s *Service) handleConn(conn net.Conn) {
 		conn.Close()
 	}()
 
-	s.Logger.Printf("accept remote write connection from %v\n", conn.RemoteAddr())
+	s.Logger.Debug("accept remote connection", "addr", conn.RemoteAddr())
 	defer func() {
-		s.Logger.Printf("close remote write connection from %v\n", conn.RemoteAddr())
+		s.Logger.Debug("close remote connection", "addr", conn.RemoteAddr())
 	}()
 	for {
 		// Read type-length-value.
@@ -137,7 +138,7 @@ func (s *Service) handleConn(conn net.Conn) {
 			if strings.HasSuffix(err.Error(), "EOF") {
 				return
 			}
-			s.Logger.Printf("unable to read type-length-value %s", err)
+			s.Logger.Error("unable to read type-length-value", "addr", conn.RemoteAddr(), "err", err)
 			return
 		}
 
@@ -146,19 +147,22 @@ func (s *Service) handleConn(conn net.Conn) {
 		case writeShardRequestMessage:
 			err := s.processWriteShardRequest(buf)
 			if err != nil {
-				s.Logger.Printf("process write shard error: %s", err)
+				s.Logger.Error("process write shard error", "addr", conn.RemoteAddr(), "err", err)
 			}
 			s.writeShardResponse(conn, err)
 		case mapShardRequestMessage:
 			err := s.processMapShardRequest(conn, buf)
 			if err != nil {
-				s.Logger.Printf("process map shard error: %s", err)
+				s.Logger.Error("process map shard error", "addr", conn.RemoteAddr(), "err", err)
 				if err := writeMapShardResponseMessage(conn, NewMapShardResponse(1, err.Error())); err != nil {
-					s.Logger.Printf("process map shard error writing response: %s", err.Error())
+					s.Logger.Error("process map shard error writing response", "addr", conn.RemoteAddr(), "err", err)
 				}
 			}
+		case mapShardAckMessage:
+			// Acks can still be in flight after the final chunk of a map
+			// shard stream has been sent. They no longer grant anything.
 		default:
-			s.Logger.Printf("cluster service message type not found: %d", typ)
+			s.Logger.Warn("cluster service message type not found", "addr", conn.RemoteAddr(), "type", typ)
 		}
 	}
 }
@@ -185,7 +189,7 @@ func (s *Service) processWriteShardRequest(buf []byte) error {
 			// If we can't find it, then we need to drop this request
 			// as it is no longer valid.  This could happen if writes were queued via
 			// hinted handoff and delivered after a shard group was deleted.
-			s.Logger.Printf("drop write request: shard=%d. shard group does not exist or was deleted", req.ShardID())
+			s.Logger.Warn("drop write request: shard group does not exist or was deleted", "shard", req.ShardID())
 			return nil
 		}
 
@@ -216,17 +220,20 @@ func (s *Service) writeShardResponse(w io.Writer, e error) {
 	// Marshal response to binary.
 	buf, err := resp.MarshalBinary()
 	if err != nil {
-		s.Logger.Printf("error marshalling shard response: %s", err)
+		s.Logger.Error("error marshalling shard response", "err", err)
 		return
 	}
 
 	// Write to connection.
 	if err := WriteTLV(w, writeShardResponseMessage, buf); err != nil {
-		s.Logger.Printf("write shard response error: %s", err)
+		s.Logger.Error("write shard response error", "err", err)
 	}
 }
 
-func (s *Service) processMapShardRequest(w io.Writer, buf []byte) error {
+// processMapShardRequest streams the chunks of a mapper back over conn. If the
+// request has a window size then at most that many chunks are sent before
+// waiting for the remote mapper to acknowledge them.
+func (s *Service) processMapShardRequest(conn io.ReadWriter, buf []byte) error {
 	// Decode request
 	var req MapShardRequest
 	if err := req.UnmarshalBinary(buf); err != nil {
@@ -246,7 +253,7 @@ func (s *Service) processMapShardRequest(w io.Writer, buf []byte) error {
 		return fmt.Errorf("create mapper: %s", err)
 	}
 	if m == nil {
-		return writeMapShardResponseMessage(w, NewMapShardResponse(0, ""))
+		return writeMapShardResponseMessage(conn, NewMapShardResponse(0, ""))
 	}
 
 	if err := m.Open(); err != nil {
@@ -254,8 +261,20 @@ func (s *Service) processMapShardRequest(w io.Writer, buf []byte) error {
 	}
 	defer m.Close()
 
+	window := int(req.WindowSize())
+	credits := window
+
 	var metaSent bool
 	for {
+		// Wait for the remote mapper to make room in its window.
+		for window > 0 && credits <= 0 {
+			n, err := readMapShardAck(conn)
+			if err != nil {
+				return fmt.Errorf("read ack: %s", err)
+			}
+			credits += n
+		}
+
 		var resp MapShardResponse
 
 		if !metaSent {
@@ -277,14 +296,19 @@ func (s *Service) processMapShardRequest(w io.Writer, buf []byte) error {
 			if err != nil {
 				return fmt.Errorf("encoding: %s", err)
 			}
+			if req.Compress() {
+				b = snappy.Encode(nil, b)
+				resp.SetCompressed(true)
+			}
 			resp.SetData(b)
 		}
 
 		// Write to connection.
 		resp.SetCode(0)
-		if err := writeMapShardResponseMessage(w, &resp); err != nil {
+		if err := writeMapShardResponseMessage(conn, &resp); err != nil {
 			return err
 		}
+		credits--
 
 		if chunk == nil {
 			// All mapper data sent.
@@ -301,6 +325,22 @@ func writeMapShardResponseMessage(w io.Writer, msg *MapShardResponse) error {
 	return WriteTLV(w, mapShardResponseMessage, buf)
 }
 
+// readMapShardAck reads an ack from r and returns the number of chunks it grants.
+func readMapShardAck(r io.Reader) (int, error) {
+	typ, buf, err := ReadTLV(r)
+	if err != nil {
+		return 0, err
+	} else if typ != mapShardAckMessage {
+		return 0, fmt.Errorf("unexpected message type: %d", typ)
+	}
+
+	var ack MapShardAck
+	if err := ack.UnmarshalBinary(buf); err != nil {
+		return 0, err
+	}
+	return int(ack.N()), nil
+}
+
 // ReadTLV reads a type-length-value record from r.
 func ReadTLV(r io.Reader) (byte, []byte, error) {
 	var typ [1]byte
@@ -6,6 +6,7 @@ import (
 	"net"
 	"time"
 
+	"github.com/golang/snappy"
 	"github.com/influxdb/influxdb/influxql"
 	"github.com/influxdb/influxdb/meta"
 	"github.com/influxdb/influxdb/tsdb"
@@ -17,6 +18,11 @@ import (
 type ShardMapper struct {
 	ForceRemoteMapping bool // All shards treated as remote. Useful for testing.
 
+	// Flow control and compression settings for remote mappers.
+	// See RemoteMapper for details.
+	WindowSize int
+	Compress   bool
+
 	MetaStore interface {
 		NodeID() uint64
 		Node(id uint64) (ni *meta.NodeInfo, err error)
@@ -26,6 +32,12 @@ type ShardMapper struct {
 		CreateMapper(shardID uint64, stmt influxql.Statement, chunkSize int) (tsdb.Mapper, error)
 	}
 
+	// HintedHandoff reports how long writes to a node have been queued.
+	// Optional; without it staleness is not tracked.
+	HintedHandoff interface {
+		Lag(nodeID uint64) time.Duration
+	}
+
 	timeout time.Duration
 	pool    *clientPool
 }
@@ -38,27 +50,58 @@ func NewShardMapper(timeout time.Duration) *ShardMapper {
 	}
 }
 
-// CreateMapper returns a Mapper for the given shard ID.
-func (s *ShardMapper) CreateMapper(sh meta.ShardInfo, stmt influxql.Statement, chunkSize int) (tsdb.Mapper, error) {
+// CreateMapper returns a Mapper for the given shard ID. The replica read is
+// chosen by opt's read preference and its lag recorded as opt's staleness.
+func (s *ShardMapper) CreateMapper(sh meta.ShardInfo, stmt influxql.Statement, chunkSize int, opt *tsdb.ReadOptions) (tsdb.Mapper, error) {
 	m, err := s.TSDBStore.CreateMapper(sh.ID, stmt, chunkSize)
 	if err != nil {
 		return nil, err
 	}
 
-	if !sh.OwnedBy(s.MetaStore.NodeID()) || s.ForceRemoteMapping {
-		// Pick a node in a pseudo-random manner.
-		conn, err := s.dial(sh.Owners[rand.Intn(len(sh.Owners))].NodeID)
+	var pref tsdb.ReadPreference
+	if opt != nil {
+		pref = opt.Preference
+	}
+
+	if ownerID := s.readOwner(sh, pref); ownerID != s.MetaStore.NodeID() || s.ForceRemoteMapping {
+		conn, err := s.dial(ownerID)
 		if err != nil {
 			return nil, err
 		}
-		conn.SetDeadline(time.Now().Add(s.timeout))
 
-		m.SetRemote(NewRemoteMapper(conn, sh.ID, stmt, chunkSize))
+		rm := NewRemoteMapper(conn, sh.ID, stmt, chunkSize)
+		rm.WindowSize = s.WindowSize
+		rm.Compress = s.Compress
+		rm.Timeout = s.timeout
+		m.SetRemote(rm)
+
+		// Writes to the owner still queued on this node are not visible to the read.
+		if opt != nil && s.HintedHandoff != nil {
+			if lag := s.HintedHandoff.Lag(ownerID); lag > opt.Staleness {
+				opt.Staleness = lag
+			}
+		}
 	}
 
 	return m, nil
 }
 
+// readOwner returns the ID of the node a shard should be read from.
+func (s *ShardMapper) readOwner(sh meta.ShardInfo, pref tsdb.ReadPreference) uint64 {
+	switch pref {
+	case tsdb.ReadPreferenceLeader:
+		return sh.Owners[0].NodeID
+	case tsdb.ReadPreferenceRandom:
+		return sh.Owners[rand.Intn(len(sh.Owners))].NodeID
+	default:
+		if nodeID := s.MetaStore.NodeID(); sh.OwnedBy(nodeID) && !s.ForceRemoteMapping {
+			return nodeID
+		}
+		// Pick a node in a pseudo-random manner.
+		return sh.Owners[rand.Intn(len(sh.Owners))].NodeID
+	}
+}
+
 func (s *ShardMapper) dial(nodeID uint64) (net.Conn, error) {
 	ni, err := s.MetaStore.Node(nodeID)
 	if err != nil {
@@ -87,6 +130,19 @@ type RemoteMapper struct {
 
 	conn             net.Conn
 	bufferedResponse *MapShardResponse
+	consumed         int // chunks consumed since the last ack
+
+	// WindowSize is the number of chunks the remote node may send before
+	// they are consumed. Consumed chunks are acknowledged every half window.
+	// Zero lets the remote node send chunks as fast as the connection allows.
+	WindowSize int
+
+	// Compress requests that the remote node snappy compresses each chunk.
+	Compress bool
+
+	// Timeout bounds each read from and write to the remote node rather
+	// than the whole query. Zero disables the timeout.
+	Timeout time.Duration
 }
 
 // NewRemoteMapper returns a new remote mapper using the given connection.
@@ -111,6 +167,8 @@ func (r *RemoteMapper) Open() (err error) {
 	request.SetShardID(r.shardID)
 	request.SetQuery(r.stmt.String())
 	request.SetChunkSize(int32(r.chunkSize))
+	request.SetWindowSize(int32(r.WindowSize))
+	request.SetCompress(r.Compress)
 
 	// Marshal into protocol buffers.
 	buf, err := request.MarshalBinary()
@@ -119,26 +177,17 @@ func (r *RemoteMapper) Open() (err error) {
 	}
 
 	// Write request.
+	r.setDeadline()
 	if err := WriteTLV(r.conn, mapShardRequestMessage, buf); err != nil {
 		return err
 	}
 
-	// Read the response.
-	_, buf, err = ReadTLV(r.conn)
+	// Read the first response.
+	r.bufferedResponse, err = r.readResponse()
 	if err != nil {
 		return err
 	}
 
-	// Unmarshal response.
-	r.bufferedResponse = &MapShardResponse{}
-	if err := r.bufferedResponse.UnmarshalBinary(buf); err != nil {
-		return err
-	}
-
-	if r.bufferedResponse.Code() != 0 {
-		return fmt.Errorf("error code %d: %s", r.bufferedResponse.Code(), r.bufferedResponse.Message())
-	}
-
 	// Decode the first response to get the TagSets.
 	r.tagsets = r.bufferedResponse.TagSets()
 	r.fields = r.bufferedResponse.Fields()
@@ -160,34 +209,76 @@ func (r *RemoteMapper) Fields() []string {
 
 // NextChunk returns the next chunk read from the remote node to the client.
 func (r *RemoteMapper) NextChunk() (chunk interface{}, err error) {
-	var response *MapShardResponse
-	if r.bufferedResponse != nil {
-		response = r.bufferedResponse
-		r.bufferedResponse = nil
-	} else {
-		response = &MapShardResponse{}
-
-		// Read the response.
-		_, buf, err := ReadTLV(r.conn)
-		if err != nil {
+	response := r.bufferedResponse
+	r.bufferedResponse = nil
+	if response == nil {
+		if response, err = r.readResponse(); err != nil {
 			return nil, err
 		}
+	}
 
-		// Unmarshal response.
-		if err := response.UnmarshalBinary(buf); err != nil {
-			return nil, err
+	if response.Data() == nil {
+		return nil, nil
+	}
+
+	data := response.Data()
+	if response.Compressed() {
+		if data, err = snappy.Decode(nil, data); err != nil {
+			return nil, fmt.Errorf("decode chunk: %s", err)
 		}
+	}
 
-		if response.Code() != 0 {
-			return nil, fmt.Errorf("error code %d: %s", response.Code(), response.Message())
+	// Let the remote node send more chunks once half of the window is consumed.
+	if r.WindowSize > 0 {
+		r.consumed++
+		if r.consumed >= (r.WindowSize+1)/2 {
+			if err := r.ack(r.consumed); err != nil {
+				return nil, err
+			}
+			r.consumed = 0
 		}
 	}
 
-	if response.Data() == nil {
-		return nil, nil
+	return data, nil
+}
+
+// readResponse reads and decodes the next response from the remote node.
+func (r *RemoteMapper) readResponse() (*MapShardResponse, error) {
+	r.setDeadline()
+	_, buf, err := ReadTLV(r.conn)
+	if err != nil {
+		return nil, err
 	}
 
-	return response.Data(), err
+	response := &MapShardResponse{}
+	if err := response.UnmarshalBinary(buf); err != nil {
+		return nil, err
+	}
+
+	if response.Code() != 0 {
+		return nil, fmt.Errorf("error code %d: %s", response.Code(), response.Message())
+	}
+	return response, nil
+}
+
+// ack grants the remote node credit to send n more chunks.
+func (r *RemoteMapper) ack(n int) error {
+	var a MapShardAck
+	a.SetN(int32(n))
+	buf, err := a.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	r.setDeadline()
+	return WriteTLV(r.conn, mapShardAckMessage, buf)
+}
+
+// setDeadline extends the connection deadline, if a timeout is set.
+func (r *RemoteMapper) setDeadline() {
+	if r.Timeout > 0 {
+		r.conn.SetDeadline(time.Now().Add(r.Timeout))
+	}
 }
 
 // Close the Mapper
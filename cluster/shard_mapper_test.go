@@ -7,8 +7,10 @@ import (
 	"io"
 	"net"
 	"testing"
+	"time"
 
 	"github.com/influxdb/influxdb/influxql"
+	"github.com/influxdb/influxdb/meta"
 	"github.com/influxdb/influxdb/tsdb"
 )
 
@@ -102,6 +104,205 @@ func TestShardWriter_RemoteMapper_Success(t *testing.T) {
 	}
 }
 
+// Ensure the cluster service stops streaming chunks once a window is exhausted.
+func TestService_MapShard_Window(t *testing.T) {
+	m := newTestMapper(10)
+	s := NewService(Config{})
+	s.TSDBStore = &testMapperStore{mapper: m}
+
+	var req MapShardRequest
+	req.SetShardID(1234)
+	req.SetQuery("SELECT * FROM cpu")
+	req.SetChunkSize(10)
+	req.SetWindowSize(2)
+	req.SetCompress(true)
+	buf, _ := req.MarshalBinary()
+
+	// Acks are read from a pipe and responses are buffered.
+	pr, pw := io.Pipe()
+	var out bytes.Buffer
+	done := make(chan error)
+	go func() {
+		done <- s.processMapShardRequest(struct {
+			io.Reader
+			io.Writer
+		}{pr, &out}, buf)
+	}()
+
+	// Only the window should be produced until chunks are acknowledged.
+	for i := 0; i < 2; i++ {
+		select {
+		case <-m.next:
+		case <-time.After(time.Second):
+			t.Fatalf("expected chunk %d", i)
+		}
+	}
+	select {
+	case <-m.next:
+		t.Fatal("chunk produced beyond window")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	var ack MapShardAck
+	ack.SetN(10)
+	b, _ := ack.MarshalBinary()
+	if err := WriteTLV(pw, mapShardAckMessage, b); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// Verify every chunk was compressed and the stream was terminated.
+	for i := 0; i <= 10; i++ {
+		typ, buf, err := ReadTLV(&out)
+		if err != nil {
+			t.Fatalf("read response %d: %s", i, err)
+		} else if typ != mapShardResponseMessage {
+			t.Fatalf("unexpected message type: %d", typ)
+		}
+		var resp MapShardResponse
+		if err := resp.UnmarshalBinary(buf); err != nil {
+			t.Fatal(err)
+		}
+		if i == 10 {
+			if resp.Data() != nil {
+				t.Fatal("expected empty final response")
+			}
+		} else if !resp.Compressed() {
+			t.Fatalf("chunk %d not compressed", i)
+		}
+	}
+}
+
+// Ensure a RemoteMapper can stream windowed, compressed chunks from the cluster service.
+func TestShardWriter_RemoteMapper_Windowed(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewService(Config{})
+	s.Listener = ln
+	s.TSDBStore = &testMapperStore{mapper: newTestMapper(5)}
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRemoteMapper(conn, 1234, mustParseStmt("SELECT * FROM cpu"), 10)
+	r.WindowSize = 2
+	r.Compress = true
+	r.Timeout = 5 * time.Second
+	if err := r.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if fields := r.Fields(); len(fields) != 1 || fields[0] != "value" {
+		t.Fatalf("unexpected fields: %v", fields)
+	}
+
+	for i := 0; ; i++ {
+		chunk, err := r.NextChunk()
+		if err != nil {
+			t.Fatal(err)
+		} else if chunk == nil {
+			if i != 5 {
+				t.Fatalf("unexpected chunk count: %d", i)
+			}
+			break
+		}
+
+		var output tsdb.MapperOutput
+		if err := json.Unmarshal(chunk.([]byte), &output); err != nil {
+			t.Fatal(err)
+		} else if exp := fmt.Sprintf("server%02d", i); output.Tags["host"] != exp {
+			t.Fatalf("unexpected host for chunk %d: got %s, exp %s", i, output.Tags["host"], exp)
+		}
+	}
+}
+
+// Ensure the shard mapper reads from the replica chosen by the read preference.
+func TestShardMapper_ReadOwner(t *testing.T) {
+	s := NewShardMapper(time.Second)
+	s.MetaStore = &testNodeMetaStore{nodeID: 2}
+	sh := meta.ShardInfo{ID: 1, Owners: []meta.ShardOwner{{NodeID: 1}, {NodeID: 2}, {NodeID: 3}}}
+
+	if id := s.readOwner(sh, tsdb.ReadPreferenceNearest); id != 2 {
+		t.Fatalf("unexpected nearest owner: %d", id)
+	} else if id := s.readOwner(sh, tsdb.ReadPreferenceLeader); id != 1 {
+		t.Fatalf("unexpected leader owner: %d", id)
+	}
+
+	// Random reads should eventually hit every replica.
+	seen := make(map[uint64]bool)
+	for i := 0; i < 1000 && len(seen) < 3; i++ {
+		seen[s.readOwner(sh, tsdb.ReadPreferenceRandom)] = true
+	}
+	if len(seen) != 3 {
+		t.Fatalf("random reads did not reach every replica: %v", seen)
+	}
+
+	// Remote owners are used if the local node doesn't own the shard.
+	sh.Owners = []meta.ShardOwner{{NodeID: 3}}
+	if id := s.readOwner(sh, tsdb.ReadPreferenceNearest); id != 3 {
+		t.Fatalf("unexpected nearest owner: %d", id)
+	}
+}
+
+// testNodeMetaStore returns a fixed local node ID.
+type testNodeMetaStore struct {
+	nodeID uint64
+}
+
+func (m *testNodeMetaStore) NodeID() uint64 { return m.nodeID }
+func (m *testNodeMetaStore) Node(id uint64) (*meta.NodeInfo, error) {
+	return &meta.NodeInfo{ID: id}, nil
+}
+
+// testMapper is a tsdb.Mapper that returns n chunks and signals each call to NextChunk.
+type testMapper struct {
+	n, i int
+	next chan struct{}
+}
+
+func newTestMapper(n int) *testMapper {
+	return &testMapper{n: n, next: make(chan struct{}, n+1)}
+}
+
+func (m *testMapper) Open() error                 { return nil }
+func (m *testMapper) SetRemote(tsdb.Mapper) error { return nil }
+func (m *testMapper) TagSets() []string           { return []string{"cpu"} }
+func (m *testMapper) Fields() []string            { return []string{"value"} }
+func (m *testMapper) Close()                      {}
+func (m *testMapper) NextChunk() (interface{}, error) {
+	m.next <- struct{}{}
+	if m.i >= m.n {
+		return nil, nil
+	}
+	m.i++
+	return &tsdb.MapperOutput{
+		Name: "cpu",
+		Tags: map[string]string{"host": fmt.Sprintf("server%02d", m.i-1)},
+	}, nil
+}
+
+// testMapperStore returns the same mapper for every shard.
+type testMapperStore struct {
+	mapper tsdb.Mapper
+}
+
+func (s *testMapperStore) CreateShard(database, policy string, shardID uint64) error { return nil }
+func (s *testMapperStore) WriteToShard(shardID uint64, points []tsdb.Point) error    { return nil }
+func (s *testMapperStore) CreateMapper(shardID uint64, stmt influxql.Statement, chunkSize int) (tsdb.Mapper, error) {
+	return s.mapper, nil
+}
+
 // mustParseStmt parses a single statement or panics.
 func mustParseStmt(stmt string) influxql.Statement {
 	q, err := influxql.ParseQuery(stmt)
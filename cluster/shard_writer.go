@@ -15,6 +15,7 @@ const (
 	writeShardResponseMessage
 	mapShardRequestMessage
 	mapShardResponseMessage
+	mapShardAckMessage
 )
 
 // ShardWriter writes a set of points to a shard.
@@ -0,0 +1,163 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// PointTransformer transforms the points written to a database before they're
+// checked against its schema and routed. Points may be changed in place,
+// replaced or removed.
+type PointTransformer interface {
+	Transform(database string, points []tsdb.Point) []tsdb.Point
+}
+
+// transformRule is a parsed transform rule.
+type transformRule struct {
+	op    string // "drop-field", "add-tag" or "rename"
+	key   string
+	value string
+}
+
+// parseTransformRule parses a transform rule. Rules are one of:
+//
+//	drop-field <field>
+//	add-tag <key>=<value>
+//	rename <measurement>
+func parseTransformRule(s string) (transformRule, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return transformRule{}, fmt.Errorf("invalid transform rule %q", s)
+	}
+
+	r := transformRule{op: fields[0], key: fields[1]}
+	switch r.op {
+	case "drop-field", "rename":
+	case "add-tag":
+		i := strings.Index(r.key, "=")
+		if i <= 0 || i == len(r.key)-1 {
+			return transformRule{}, fmt.Errorf("invalid transform rule %q: tag must be key=value", s)
+		}
+		r.key, r.value = r.key[:i], r.key[i+1:]
+	default:
+		return transformRule{}, fmt.Errorf("invalid transform rule %q: unknown operation %q", s, r.op)
+	}
+	return r, nil
+}
+
+// ruleTransform is a transform with its rules parsed.
+type ruleTransform struct {
+	measurements map[string]bool // nil matches all measurements
+	rules        []transformRule
+}
+
+// TransformRules is a PointTransformer applying the transforms of a config.
+type TransformRules struct {
+	dbs map[string][]ruleTransform
+}
+
+// NewTransformRules returns the transformer applying transforms. Returns an
+// error if a rule is invalid.
+func NewTransformRules(transforms []Transform) (*TransformRules, error) {
+	t := &TransformRules{dbs: make(map[string][]ruleTransform)}
+	for _, tr := range transforms {
+		if tr.Database == "" {
+			return nil, errors.New("transform database required")
+		} else if len(tr.Rules) == 0 {
+			return nil, fmt.Errorf("transform rules required in database %q", tr.Database)
+		}
+
+		rt := ruleTransform{}
+		if len(tr.Measurements) > 0 {
+			rt.measurements = make(map[string]bool, len(tr.Measurements))
+			for _, name := range tr.Measurements {
+				rt.measurements[name] = true
+			}
+		}
+		for _, s := range tr.Rules {
+			r, err := parseTransformRule(s)
+			if err != nil {
+				return nil, err
+			}
+			rt.rules = append(rt.rules, r)
+		}
+		t.dbs[tr.Database] = append(t.dbs[tr.Database], rt)
+	}
+	return t, nil
+}
+
+// Transform applies the transforms of database to points, in order. Points
+// left without fields are removed.
+func (t *TransformRules) Transform(database string, points []tsdb.Point) []tsdb.Point {
+	points, _ = t.transform(database, points)
+	return points
+}
+
+// transform applies the transforms of database to points and returns the
+// transformed points along with the number of points changed or removed.
+func (t *TransformRules) transform(database string, points []tsdb.Point) ([]tsdb.Point, int) {
+	transforms := t.dbs[database]
+	if len(transforms) == 0 {
+		return points, 0
+	}
+
+	var n int
+	other := points[:0]
+	for _, p := range points {
+		var changed bool
+		for _, rt := range transforms {
+			var ok bool
+			p, ok = rt.apply(p)
+			changed = changed || ok
+			if p == nil {
+				break
+			}
+		}
+		if changed {
+			n++
+		}
+		if p != nil {
+			other = append(other, p)
+		}
+	}
+	return other, n
+}
+
+// apply applies the rules of a transform to a point of a measurement it
+// matches and returns true if it changed the point. Returns a nil point if it
+// is left without fields.
+func (rt *ruleTransform) apply(p tsdb.Point) (tsdb.Point, bool) {
+	if rt.measurements != nil && !rt.measurements[p.Name()] {
+		return p, false
+	}
+
+	var changed bool
+	for _, r := range rt.rules {
+		switch r.op {
+		case "drop-field":
+			fields := p.Fields()
+			if _, ok := fields[r.key]; !ok {
+				continue
+			}
+			other := make(tsdb.Fields, len(fields))
+			for k, v := range fields {
+				if k != r.key {
+					other[k] = v
+				}
+			}
+			if len(other) == 0 {
+				return nil, true
+			}
+			p = tsdb.NewPoint(p.Name(), p.Tags(), other, p.Time())
+		case "add-tag":
+			p.AddTag(r.key, r.value)
+		case "rename":
+			p.SetName(r.key)
+		}
+		changed = true
+	}
+	return p, changed
+}
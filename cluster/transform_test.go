@@ -0,0 +1,44 @@
+package cluster_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/cluster"
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// Ensure the rules of transforms are applied to the points of their measurements.
+func TestTransformRules_Transform(t *testing.T) {
+	rules, err := cluster.NewTransformRules([]cluster.Transform{
+		{Database: "db0", Measurements: []string{"cpu"}, Rules: []string{"drop-field guest", "add-tag dc=east", "rename cpu_usage"}},
+		{Database: "db0", Rules: []string{"add-tag region=us", "drop-field idle"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(0, 0)
+	points := rules.Transform("db0", []tsdb.Point{
+		tsdb.NewPoint("cpu", tsdb.Tags{"host": "a", "dc": "west"}, tsdb.Fields{"user": 1.0, "guest": 2.0}, now),
+		tsdb.NewPoint("mem", tsdb.Tags{"host": "a"}, tsdb.Fields{"used": 3.0}, now),
+		tsdb.NewPoint("disk", nil, tsdb.Fields{"idle": 4.0}, now),
+	})
+
+	var got []string
+	for _, p := range points {
+		got = append(got, p.String())
+	}
+	if exp := []string{
+		"cpu_usage,dc=east,host=a,region=us user=1 0",
+		"mem,host=a,region=us used=3 0",
+	}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected points:\n\nexp=%#v\n\ngot=%#v", exp, got)
+	}
+
+	// Points of other databases aren't transformed.
+	if points := rules.Transform("db1", []tsdb.Point{tsdb.NewPoint("cpu", nil, tsdb.Fields{"guest": 1.0}, now)}); len(points) != 1 || points[0].Name() != "cpu" {
+		t.Fatalf("unexpected points: %v", points)
+	}
+}
@@ -0,0 +1,149 @@
+package cluster
+
+import (
+	"expvar"
+	"sync"
+	"time"
+
+	"github.com/influxdb/influxdb"
+)
+
+// The statistics generated by the write coalescer.
+const (
+	statCoalesceReq     = "req"           // Number of write requests received
+	statCoalesceBypass  = "req_bypass"    // Number of requests large enough, or with a batch ID, written directly
+	statCoalesceBatch   = "batch"         // Number of batches written
+	statCoalescePoints  = "batch_points"  // Number of points in the batches written
+	statCoalesceTimeout = "batch_timeout" // Number of batches written because their delay expired
+)
+
+// WriteCoalescer merges small concurrent write requests to the same database,
+// retention policy and consistency level into larger batches. A request
+// returns once the batch it was merged into is written, so a failed batch
+// fails every request in it.
+type WriteCoalescer struct {
+	mu      sync.Mutex
+	batches map[coalesceKey]*coalescedBatch
+	closed  bool
+
+	// MaxPoints is the number of points after which a batch is written.
+	// Requests with at least this many points are written directly.
+	MaxPoints int
+
+	// MaxDelay is the longest a request waits for its batch to fill.
+	MaxDelay time.Duration
+
+	PointsWriter interface {
+		WritePoints(p *WritePointsRequest) error
+	}
+
+	statMap *expvar.Map
+}
+
+// coalesceKey identifies the requests that can be written together.
+type coalesceKey struct {
+	database         string
+	retentionPolicy  string
+	consistencyLevel ConsistencyLevel
+}
+
+// coalescedBatch is a write request merged from other requests.
+type coalescedBatch struct {
+	req   *WritePointsRequest
+	timer *time.Timer
+	done  chan struct{}
+	err   error
+}
+
+// NewWriteCoalescer returns a new instance of WriteCoalescer.
+func NewWriteCoalescer(maxPoints int, maxDelay time.Duration) *WriteCoalescer {
+	return &WriteCoalescer{
+		batches:   make(map[coalesceKey]*coalescedBatch),
+		MaxPoints: maxPoints,
+		MaxDelay:  maxDelay,
+		statMap:   influxdb.NewStatistics("coalesce", "coalesce", nil),
+	}
+}
+
+// WritePoints adds the points of a request to the pending batch for its
+// destination and waits for the batch to be written.
+func (c *WriteCoalescer) WritePoints(p *WritePointsRequest) error {
+	c.statMap.Add(statCoalesceReq, 1)
+
+	// Batches with an ID are written on their own so duplicates can be found.
+	if len(p.Points) >= c.MaxPoints || p.BatchID != "" {
+		c.statMap.Add(statCoalesceBypass, 1)
+		return c.PointsWriter.WritePoints(p)
+	}
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return c.PointsWriter.WritePoints(p)
+	}
+
+	key := coalesceKey{database: p.Database, retentionPolicy: p.RetentionPolicy, consistencyLevel: p.ConsistencyLevel}
+	b := c.batches[key]
+	if b == nil {
+		b = &coalescedBatch{
+			req: &WritePointsRequest{
+				Database:         p.Database,
+				RetentionPolicy:  p.RetentionPolicy,
+				ConsistencyLevel: p.ConsistencyLevel,
+			},
+			done: make(chan struct{}),
+		}
+		b.timer = time.AfterFunc(c.MaxDelay, func() {
+			if c.flush(key, b) {
+				c.statMap.Add(statCoalesceTimeout, 1)
+			}
+		})
+		c.batches[key] = b
+	}
+	b.req.Points = append(b.req.Points, p.Points...)
+	full := len(b.req.Points) >= c.MaxPoints
+	c.mu.Unlock()
+
+	if full {
+		c.flush(key, b)
+	}
+	<-b.done
+	return b.err
+}
+
+// flush writes a batch unless it was already written. Returns true if the
+// batch was written by this call.
+func (c *WriteCoalescer) flush(key coalesceKey, b *coalescedBatch) bool {
+	// No more points are added to the batch once it's removed.
+	c.mu.Lock()
+	if c.batches[key] != b {
+		c.mu.Unlock()
+		return false
+	}
+	delete(c.batches, key)
+	c.mu.Unlock()
+	b.timer.Stop()
+
+	c.statMap.Add(statCoalesceBatch, 1)
+	c.statMap.Add(statCoalescePoints, int64(len(b.req.Points)))
+	b.err = c.PointsWriter.WritePoints(b.req)
+	close(b.done)
+	return true
+}
+
+// Close writes the pending batches. Requests received afterwards are written
+// directly.
+func (c *WriteCoalescer) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	batches := make(map[coalesceKey]*coalescedBatch, len(c.batches))
+	for k, b := range c.batches {
+		batches[k] = b
+	}
+	c.mu.Unlock()
+
+	for k, b := range batches {
+		c.flush(k, b)
+	}
+	return nil
+}
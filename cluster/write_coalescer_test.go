@@ -0,0 +1,143 @@
+package cluster_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/cluster"
+)
+
+// Ensure concurrent writes to the same destination are written as one batch.
+func TestWriteCoalescer_WritePoints(t *testing.T) {
+	var mu sync.Mutex
+	var batches []*cluster.WritePointsRequest
+	c := cluster.NewWriteCoalescer(3, time.Hour)
+	c.PointsWriter = &PointsWriter{WritePointsFn: func(p *cluster.WritePointsRequest) error {
+		mu.Lock()
+		defer mu.Unlock()
+		batches = append(batches, p)
+		return nil
+	}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			pr := &cluster.WritePointsRequest{Database: "db0", RetentionPolicy: "rp0"}
+			pr.AddPoint("cpu", float64(i), time.Unix(0, 0), nil)
+			if err := c.WritePoints(pr); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if len(batches) != 1 {
+		t.Fatalf("unexpected batch count: %d", len(batches))
+	} else if p := batches[0]; p.Database != "db0" || p.RetentionPolicy != "rp0" || len(p.Points) != 3 {
+		t.Fatalf("unexpected batch: %s.%s %d", p.Database, p.RetentionPolicy, len(p.Points))
+	}
+}
+
+// Ensure a batch is written once its delay expires and errors are returned to
+// every request in it.
+func TestWriteCoalescer_WritePoints_MaxDelay(t *testing.T) {
+	c := cluster.NewWriteCoalescer(100, 50*time.Millisecond)
+	c.PointsWriter = &PointsWriter{WritePointsFn: func(p *cluster.WritePointsRequest) error {
+		if len(p.Points) != 2 {
+			t.Errorf("unexpected point count: %d", len(p.Points))
+		}
+		return errors.New("marker")
+	}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pr := &cluster.WritePointsRequest{Database: "db0"}
+			pr.AddPoint("cpu", 1.0, time.Unix(0, 0), nil)
+			if err := c.WritePoints(pr); err == nil || err.Error() != "marker" {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// Ensure requests to different destinations or with many points aren't merged.
+func TestWriteCoalescer_WritePoints_Separate(t *testing.T) {
+	var mu sync.Mutex
+	var n int
+	c := cluster.NewWriteCoalescer(2, time.Millisecond)
+	c.PointsWriter = &PointsWriter{WritePointsFn: func(p *cluster.WritePointsRequest) error {
+		mu.Lock()
+		defer mu.Unlock()
+		n++
+		return nil
+	}}
+
+	pr := &cluster.WritePointsRequest{Database: "db0"}
+	pr.AddPoint("cpu", 1.0, time.Unix(0, 0), nil)
+	pr.AddPoint("cpu", 2.0, time.Unix(1, 0), nil)
+	if err := c.WritePoints(pr); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for _, db := range []string{"db0", "db1"} {
+		wg.Add(1)
+		go func(db string) {
+			defer wg.Done()
+			pr := &cluster.WritePointsRequest{Database: db}
+			pr.AddPoint("cpu", 1.0, time.Unix(0, 0), nil)
+			if err := c.WritePoints(pr); err != nil {
+				t.Error(err)
+			}
+		}(db)
+	}
+	wg.Wait()
+
+	if n != 3 {
+		t.Fatalf("unexpected write count: %d", n)
+	}
+}
+
+// Ensure pending batches are written on close.
+func TestWriteCoalescer_Close(t *testing.T) {
+	written := make(chan int, 1)
+	c := cluster.NewWriteCoalescer(100, time.Hour)
+	c.PointsWriter = &PointsWriter{WritePointsFn: func(p *cluster.WritePointsRequest) error {
+		written <- len(p.Points)
+		return nil
+	}}
+
+	errCh := make(chan error, 1)
+	go func() {
+		pr := &cluster.WritePointsRequest{Database: "db0"}
+		pr.AddPoint("cpu", 1.0, time.Unix(0, 0), nil)
+		errCh <- c.WritePoints(pr)
+	}()
+
+	// Wait for the request to be added to a batch.
+	time.Sleep(10 * time.Millisecond)
+	c.Close()
+
+	if n := <-written; n != 1 {
+		t.Fatalf("unexpected point count: %d", n)
+	} else if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// PointsWriter is a mockable points writer.
+type PointsWriter struct {
+	WritePointsFn func(p *cluster.WritePointsRequest) error
+}
+
+func (w *PointsWriter) WritePoints(p *cluster.WritePointsRequest) error {
+	return w.WritePointsFn(p)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bufio"
 	"encoding/csv"
 	"encoding/json"
 	"flag"
@@ -19,6 +20,7 @@ import (
 	"github.com/influxdb/influxdb/client"
 	"github.com/influxdb/influxdb/cluster"
 	"github.com/influxdb/influxdb/importer/v8"
+	"github.com/influxdb/influxdb/influxql"
 	"github.com/peterh/liner"
 )
 
@@ -37,6 +39,12 @@ const (
 	// defaultPPS is the default points per second that the import will throttle at
 	// by default it's 0, which means it will not throttle
 	defaultPPS = 0
+
+	// defaultBatchSize is the default number of points per write when importing
+	defaultBatchSize = v8.DefaultBatchSize
+
+	// defaultRetries is the default number of times a failed import write is retried
+	defaultRetries = v8.DefaultRetries
 )
 
 type CommandLine struct {
@@ -55,9 +63,12 @@ type CommandLine struct {
 	Precision        string
 	WriteConsistency string
 	Execute          string
+	File             string // Path to a file of statements to execute, or "-" for stdin
 	ShowVersion      bool
 	Import           bool
 	PPS              int // Controls how many points per second the import will allow via throttling
+	BatchSize        int // Controls how many points are written per request when importing
+	Retries          int // Controls how many times a failed import write is retried
 	Path             string
 	Compressed       bool
 }
@@ -77,9 +88,12 @@ func main() {
 	fs.StringVar(&c.WriteConsistency, "consistency", "any", "Set write consistency level: any, one, quorum, or all.")
 	fs.BoolVar(&c.Pretty, "pretty", false, "Turns on pretty print for the json format.")
 	fs.StringVar(&c.Execute, "execute", c.Execute, "Execute command and quit.")
+	fs.StringVar(&c.File, "file", c.File, `Execute the statements in a file and quit. Use "-" to read from stdin.`)
 	fs.BoolVar(&c.ShowVersion, "version", false, "Displays the InfluxDB version.")
 	fs.BoolVar(&c.Import, "import", false, "Import a previous database.")
 	fs.IntVar(&c.PPS, "pps", defaultPPS, "How many points per second the import will allow.  By default it is zero and will not throttle importing.")
+	fs.IntVar(&c.BatchSize, "batch-size", defaultBatchSize, "How many points the import will write per request.")
+	fs.IntVar(&c.Retries, "retries", defaultRetries, "How many times the import will retry a failed write.")
 	fs.StringVar(&c.Path, "path", "", "path to the file to import")
 	fs.BoolVar(&c.Compressed, "compressed", false, "set to true if the import file is compressed")
 
@@ -102,6 +116,9 @@ func main() {
         Use https for requests.
   -execute 'command'
        Execute command and quit.
+  -file 'path'
+       Execute the statements in a file and quit.  Use '-' to read statements from stdin.
+       Statements may span multiple lines.  Lines starting with '--' are ignored.
   -format 'json|csv|column'
        Format specifies the format of the server responses:  json, csv, or column.
   -precision 'rfc3339|h|m|s|ms|u|ns'
@@ -111,21 +128,31 @@ func main() {
   -pretty
        Turns on pretty print for the json format.
   -import
-       Import a previous database export from file
+       Import a previous database export or a line protocol file
   -pps
        How many points per second the import will allow.  By default it is zero and will not throttle importing.
+  -batch-size
+       How many points the import will write per request.  Defaults to 5000.
+  -retries
+       How many times the import will retry a failed write.  Defaults to 3.
   -path
        Path to file to import
   -compressed
-       Set to true if the import file is compressed
+       Set to true if the import file is compressed.  Files ending in .gz are always decompressed.
 
 Examples:
 
     # Use influx in a non-interactive mode to query the database "metrics" and pretty print json:
     $ influx -database 'metrics' -execute 'select * from cpu' -format 'json' -pretty
 
+    # Run the statements in a file and exit with a non-zero status if any of them fail:
+    $ influx -database 'metrics' -file 'queries.txt' -format 'csv'
+
     # Connect to a specific database on startup and set database context:
     $ influx -database 'metrics' -host 'localhost' -port '8086'
+
+    # Import a gzipped line protocol file into the database "metrics" at 10000 points per second:
+    $ influx -import -database 'metrics' -path 'metrics.txt.gz' -pps 10000
 `)
 	}
 	fs.Parse(os.Args[1:])
@@ -157,21 +184,42 @@ Examples:
 		}
 	}
 
-	if err := c.connect(""); err != nil {
-
+	batch := c.Execute != "" || c.File != ""
+	if err := c.connect(""); err != nil && batch {
+		fmt.Fprintf(os.Stderr, "ERR: %s\n", err)
+		c.Line.Close()
+		os.Exit(1)
 	}
-	if c.Execute == "" && !c.Import {
+	if !batch && !c.Import {
 		fmt.Printf("Connected to %s version %s\n", c.Client.Addr(), c.Version)
 	}
 
-	if c.Execute != "" {
+	if batch {
 		// Modify precision before executing query
 		c.SetPrecision(c.Precision)
-		if err := c.ExecuteQuery(c.Execute); err != nil {
-			c.Line.Close()
-			os.Exit(1)
+
+		var r io.Reader = strings.NewReader(c.Execute)
+		if c.File == "-" {
+			r = os.Stdin
+		} else if c.File != "" {
+			f, err := os.Open(c.File)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERR: %s\n", err)
+				c.Line.Close()
+				os.Exit(1)
+			}
+			defer f.Close()
+			r = f
+		}
+
+		failed, err := c.ExecuteStatements(r)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERR: %s\n", err)
 		}
 		c.Line.Close()
+		if err != nil || failed > 0 {
+			os.Exit(1)
+		}
 		os.Exit(0)
 	}
 
@@ -194,6 +242,10 @@ Examples:
 		config.Compressed = c.Compressed
 		config.PPS = c.PPS
 		config.Precision = c.Precision
+		config.Database = c.Database
+		config.RetentionPolicy = c.RetentionPolicy
+		config.BatchSize = c.BatchSize
+		config.Retries = c.Retries
 
 		i := v8.NewImporter(config)
 		if err := i.Import(); err != nil {
@@ -201,6 +253,11 @@ Examples:
 			c.Line.Close()
 			os.Exit(1)
 		}
+		if n := i.Summary().Failed; n > 0 {
+			fmt.Fprintf(os.Stderr, "ERROR: %d points failed to import\n", n)
+			c.Line.Close()
+			os.Exit(1)
+		}
 		c.Line.Close()
 		os.Exit(0)
 	}
@@ -219,16 +276,31 @@ Examples:
 		}
 	}
 
+	// Statements that span multiple lines are buffered until they are
+	// complete. Each statement is saved to the history as a single line.
+	var buf []string
 	for {
-		l, e := c.Line.Prompt("> ")
+		prompt := "> "
+		if len(buf) > 0 {
+			prompt = "... "
+		}
+		l, e := c.Line.Prompt(prompt)
 		if e != nil {
 			break
 		}
-		if c.ParseCommand(l) {
+
+		buf = append(buf, l)
+		stmt := strings.TrimSpace(strings.Join(buf, "\n"))
+		if stmt != "" && !StatementComplete(stmt) {
+			continue
+		}
+		buf = nil
+
+		if c.ParseCommand(stmt) {
 			// write out the history
-			if len(historyFile) > 0 {
-				c.Line.AppendHistory(l)
-				if f, err := os.Create(historyFile); err == nil {
+			if len(historyFile) > 0 && stmt != "" {
+				c.Line.AppendHistory(strings.Join(strings.Fields(stmt), " "))
+				if f, err := os.OpenFile(historyFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600); err == nil {
 					c.Line.WriteHistory(f)
 					f.Close()
 				}
@@ -239,6 +311,92 @@ Examples:
 	}
 }
 
+// shellCommands are the commands handled by the shell itself. They are
+// always complete on a single line.
+var shellCommands = []string{"exit", "gopher", "connect", "auth", "help", "format", "precision", "consistency", "settings", "pretty", "use", "insert"}
+
+// isShellCommand returns true if the lowercased statement is handled by the shell.
+func isShellCommand(lstmt string) bool {
+	for _, cmd := range shellCommands {
+		if strings.HasPrefix(lstmt, cmd) {
+			return true
+		}
+	}
+	return false
+}
+
+// StatementComplete returns true if stmt can be executed. Statements ending
+// with a semicolon are always complete. Otherwise the statement is complete
+// unless parsing it runs out of input, in which case the shell prompts for
+// the rest of the statement.
+func StatementComplete(stmt string) bool {
+	if isShellCommand(strings.ToLower(stmt)) {
+		return true
+	}
+
+	if strings.HasSuffix(stmt, ";") {
+		return true
+	}
+
+	_, err := influxql.ParseQuery(stmt)
+	if e, ok := err.(*influxql.ParseError); ok && e.Found == "EOF" {
+		return false
+	}
+	return true
+}
+
+// ExecuteStatements executes the statements read from r in order and writes
+// their results to stdout. Statements are separated the same way as in the
+// interactive shell. Execution continues after a statement fails and the
+// number of failed statements is returned. An error is returned if r can't
+// be read.
+func (c *CommandLine) ExecuteStatements(r io.Reader) (failed int, err error) {
+	var buf []string
+	scanner := bufio.NewScanner(r)
+	for {
+		more := scanner.Scan()
+		if more {
+			l := scanner.Text()
+			if strings.HasPrefix(strings.TrimSpace(l), "--") {
+				continue
+			}
+			buf = append(buf, l)
+		}
+
+		// Execute the buffered statement once it is complete or the input ends.
+		stmt := strings.TrimSpace(strings.Join(buf, "\n"))
+		if stmt != "" && (!more || StatementComplete(stmt)) {
+			buf = nil
+
+			exit, err := c.executeStatement(stmt)
+			if err != nil {
+				failed++
+			}
+			if exit {
+				return failed, nil
+			}
+		}
+
+		if !more {
+			return failed, scanner.Err()
+		}
+	}
+}
+
+// executeStatement executes a single shell command or query. Returns true
+// if the statement asks the shell to exit.
+func (c *CommandLine) executeStatement(stmt string) (exit bool, err error) {
+	lstmt := strings.ToLower(stmt)
+	switch {
+	case strings.HasPrefix(lstmt, "insert"):
+		return false, c.Insert(stmt)
+	case isShellCommand(lstmt):
+		return !c.ParseCommand(stmt), nil
+	default:
+		return false, c.ExecuteQuery(stmt)
+	}
+}
+
 func showVersion() {
 	fmt.Println("InfluxDB shell " + version)
 }
@@ -308,6 +466,10 @@ func (c *CommandLine) connect(cmd string) error {
 	config.Password = c.Password
 	config.UserAgent = "InfluxDBShell/" + version
 	config.Precision = c.Precision
+	if strings.ToLower(config.Precision) == "rfc3339" {
+		// RFC3339 timestamps are returned when no epoch is requested.
+		config.Precision = ""
+	}
 	cl, err := client.NewClient(config)
 	if err != nil {
 		return fmt.Errorf("Could not create client %s", err)
@@ -571,13 +733,13 @@ func (c *CommandLine) writeCSV(response *client.Response, w io.Writer) {
 func (c *CommandLine) writeColumns(response *client.Response, w io.Writer) {
 	for _, result := range response.Results {
 		// Create a tabbed writer for each result a they won't always line up
-		w := new(tabwriter.Writer)
-		w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+		tw := new(tabwriter.Writer)
+		tw.Init(w, 0, 8, 1, '\t', 0)
 		csv := c.formatResults(result, "\t")
 		for _, r := range csv {
-			fmt.Fprintln(w, r)
+			fmt.Fprintln(tw, r)
 		}
-		w.Flush()
+		tw.Flush()
 	}
 }
 
@@ -693,6 +855,11 @@ func (c *CommandLine) Settings() {
 	fmt.Fprintf(w, "Database\t%s\n", c.Database)
 	fmt.Fprintf(w, "Pretty\t%v\n", c.Pretty)
 	fmt.Fprintf(w, "Format\t%s\n", c.Format)
+	if c.Precision == "" {
+		fmt.Fprintf(w, "Precision\trfc3339\n")
+	} else {
+		fmt.Fprintf(w, "Precision\t%s\n", c.Precision)
+	}
 	fmt.Fprintf(w, "Write Consistency\t%s\n", c.WriteConsistency)
 	fmt.Fprintln(w)
 	w.Flush()
@@ -705,7 +872,7 @@ func (c *CommandLine) help() {
         pretty                toggle pretty print
         use <db_name>         set current databases
         format <format>       set the output format: json, csv, or column
-        precision <format>    set the timestamp format: h,m,s,ms,u,ns
+        precision <format>    set the timestamp format: rfc3339,h,m,s,ms,u,ns
         consistency <level>   set write consistency level: any, one, quorum, or all
         settings              output the current settings for the shell
         exit                  quit the influx shell
@@ -716,6 +883,9 @@ func (c *CommandLine) help() {
         show tag keys         show tag key information
         show tag values       show tag value information
 
+        statements may span multiple lines and are executed once they are
+        complete or end with a semicolon
+
         a full list of influxql commands can be found at:
         https://influxdb.com/docs/v0.9/query_language/spec.html
 `)
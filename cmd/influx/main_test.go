@@ -1,14 +1,18 @@
 package main_test
 
 import (
+	"bytes"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/influxdb/influxdb/client"
 	main "github.com/influxdb/influxdb/cmd/influx"
+	"github.com/influxdb/influxdb/influxql"
 )
 
 func TestParseCommand_CommandsExist(t *testing.T) {
@@ -217,3 +221,102 @@ func TestParseCommand_InsertInto(t *testing.T) {
 		}
 	}
 }
+
+func TestStatementComplete(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		stmt     string
+		complete bool
+	}{
+		{stmt: "show databases", complete: true},
+		{stmt: "select * from cpu", complete: true},
+		{stmt: "select * from cpu;", complete: true},
+		{stmt: "select * from", complete: false},
+		{stmt: "select *\nfrom cpu\nwhere", complete: false},
+		{stmt: "select *\nfrom cpu\nwhere host = 'a';", complete: true},
+		{stmt: "select value from cpu where time > now() - 1h group by", complete: false},
+		{stmt: "selec * from cpu", complete: true}, // errors are reported by the server
+		{stmt: "use db0", complete: true},
+		{stmt: "insert cpu value=1", complete: true},
+	}
+
+	for _, test := range tests {
+		if got := main.StatementComplete(test.stmt); got != test.complete {
+			t.Errorf("%q: got complete=%v, exp %v", test.stmt, got, test.complete)
+		}
+	}
+}
+
+func TestFormatResponse_Column(t *testing.T) {
+	t.Parallel()
+	c := main.CommandLine{Format: "column"}
+	resp := &client.Response{Results: []client.Result{{
+		Series: []influxql.Row{{
+			Name:    "cpu",
+			Columns: []string{"time", "value"},
+			Values:  [][]interface{}{{"2015-01-01T00:00:00Z", 1}},
+		}},
+	}}}
+
+	var buf bytes.Buffer
+	c.FormatResponse(resp, &buf)
+	if exp := "name: cpu\n---------\ntime\t\t\tvalue\n2015-01-01T00:00:00Z\t1\n\n"; buf.String() != exp {
+		t.Fatalf("unexpected output:\n%q\nexp:\n%q", buf.String(), exp)
+	}
+}
+
+func TestFormatResponse_CSV(t *testing.T) {
+	t.Parallel()
+	c := main.CommandLine{Format: "csv"}
+	resp := &client.Response{Results: []client.Result{{
+		Series: []influxql.Row{{
+			Name:    "cpu",
+			Tags:    map[string]string{"host": "a"},
+			Columns: []string{"time", "value"},
+			Values:  [][]interface{}{{"2015-01-01T00:00:00Z", 1}},
+		}},
+	}}}
+
+	var buf bytes.Buffer
+	c.FormatResponse(resp, &buf)
+	if exp := "name,tags,time,value\ncpu,host=a,2015-01-01T00:00:00Z,1\n"; buf.String() != exp {
+		t.Fatalf("unexpected output:\n%q\nexp:\n%q", buf.String(), exp)
+	}
+}
+
+func TestExecuteStatements(t *testing.T) {
+	t.Parallel()
+	var queries, databases []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("q")
+		queries = append(queries, q)
+		databases = append(databases, r.URL.Query().Get("db"))
+		if strings.Contains(q, "bad") {
+			w.Write([]byte(`{"results":[{"error":"bad query"}]}`))
+			return
+		}
+		w.Write([]byte(`{"results":[{}]}`))
+	}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	c, err := client.NewClient(client.Config{URL: *u})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := main.CommandLine{Client: c, Format: "column"}
+
+	input := "use db0\nselect *\nfrom cpu\n-- a comment\nselect bad from cpu;\n\nshow measurements\nexit\nshow databases\n"
+	failed, err := m.ExecuteStatements(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	} else if failed != 1 {
+		t.Fatalf("unexpected failed count: %d", failed)
+	}
+
+	if exp := []string{"select *\nfrom cpu", "select bad from cpu;", "show measurements"}; !reflect.DeepEqual(queries, exp) {
+		t.Fatalf("unexpected queries: %q", queries)
+	} else if exp := []string{"db0", "db0", "db0"}; !reflect.DeepEqual(databases, exp) {
+		t.Fatalf("unexpected databases: %q", databases)
+	}
+}
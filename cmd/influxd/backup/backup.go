@@ -1,15 +1,22 @@
 package backup
 
 import (
-	"encoding/json"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
-	"net"
 	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
 
+	"github.com/influxdb/influxdb/meta"
 	"github.com/influxdb/influxdb/services/snapshotter"
 	"github.com/influxdb/influxdb/snapshot"
 )
@@ -33,6 +40,16 @@ func NewCommand() *Command {
 	}
 }
 
+// Options represents the command line options for a backup.
+type Options struct {
+	Host            string
+	Database        string
+	RetentionPolicy string
+	ShardID         uint64
+	Since           time.Time
+	Path            string
+}
+
 // Run executes the program.
 func (cmd *Command) Run(args ...string) error {
 	// Set up logger.
@@ -40,31 +57,26 @@ func (cmd *Command) Run(args ...string) error {
 	cmd.Logger.Printf("influxdb backup")
 
 	// Parse command line arguments.
-	host, path, err := cmd.parseFlags(args)
+	opt, err := cmd.parseFlags(args)
 	if err != nil {
 		return err
 	}
 
-	// Retrieve snapshot from local file.
-	m, err := snapshot.ReadFileManifest(path)
-	if err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("read file snapshot: %s", err)
-	}
-
 	// Determine temporary path to download to.
-	tmppath := path + Suffix
+	tmppath := opt.Path + Suffix
 
 	// Calculate path of next backup file.
 	// This uses the path if it doesn't exist.
 	// Otherwise it appends an autoincrementing number.
-	path, err = cmd.nextPath(path)
+	path, err := cmd.nextPath(opt.Path)
 	if err != nil {
 		return fmt.Errorf("next path: %s", err)
 	}
 
 	// Retrieve snapshot.
-	if err := cmd.download(host, m, tmppath); err != nil {
-		return fmt.Errorf("download: %s", err)
+	if err := cmd.backup(opt, tmppath); err != nil {
+		os.Remove(tmppath)
+		return fmt.Errorf("backup: %s", err)
 	}
 
 	// Rename temporary file to final path.
@@ -72,33 +84,49 @@ func (cmd *Command) Run(args ...string) error {
 		return fmt.Errorf("rename: %s", err)
 	}
 
-	// TODO: Check file integrity.
-
 	// Notify user of completion.
-	cmd.Logger.Println("backup complete")
+	cmd.Logger.Printf("backup complete: %s", path)
 
 	return nil
 }
 
 // parseFlags parses and validates the command line arguments.
-func (cmd *Command) parseFlags(args []string) (host string, path string, err error) {
+func (cmd *Command) parseFlags(args []string) (*Options, error) {
+	var opt Options
+	var since string
 	fs := flag.NewFlagSet("", flag.ContinueOnError)
-	fs.StringVar(&host, "host", "localhost:8088", "")
+	fs.StringVar(&opt.Host, "host", "localhost:8088", "")
+	fs.StringVar(&opt.Database, "database", "", "")
+	fs.StringVar(&opt.RetentionPolicy, "retention", "", "")
+	fs.Uint64Var(&opt.ShardID, "shard", 0, "")
+	fs.StringVar(&since, "since", "", "")
 	fs.SetOutput(cmd.Stderr)
 	fs.Usage = cmd.printUsage
 	if err := fs.Parse(args); err != nil {
-		return "", "", err
+		return nil, err
+	}
+
+	if opt.RetentionPolicy != "" && opt.Database == "" {
+		return nil, errors.New("database required with retention policy")
+	}
+
+	if since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return nil, fmt.Errorf("invalid since time: %s", err)
+		}
+		opt.Since = t
 	}
 
 	// Ensure that only one arg is specified.
 	if fs.NArg() == 0 {
-		return "", "", errors.New("snapshot path required")
+		return nil, errors.New("snapshot path required")
 	} else if fs.NArg() != 1 {
-		return "", "", errors.New("only one snapshot path allowed")
+		return nil, errors.New("only one snapshot path allowed")
 	}
-	path = fs.Arg(0)
+	opt.Path = fs.Arg(0)
 
-	return host, path, nil
+	return &opt, nil
 }
 
 // nextPath returns the next file to write to.
@@ -121,50 +149,222 @@ func (cmd *Command) nextPath(path string) (string, error) {
 	}
 }
 
-// download downloads a snapshot from a host to a given path.
-func (cmd *Command) download(host string, m *snapshot.Manifest, path string) error {
-	// Create local file to write to.
-	f, err := os.Create(path)
+// backup downloads the meta store and the selected shards from the cluster
+// and writes them as a snapshot archive to path.
+func (cmd *Command) backup(opt *Options, path string) error {
+	// Shards are staged on disk so their checksums are known before the
+	// archive's manifest is written.
+	dir, err := ioutil.TempDir(filepath.Dir(path), filepath.Base(path)+".")
 	if err != nil {
-		return fmt.Errorf("open temp file: %s", err)
+		return fmt.Errorf("create staging dir: %s", err)
 	}
-	defer f.Close()
+	defer os.RemoveAll(dir)
+
+	sw := snapshot.NewWriter()
+	defer sw.Close()
+
+	// Retrieve the meta store from the host.
+	buf, f, err := cmd.downloadMeta(opt.Host)
+	if err != nil {
+		return fmt.Errorf("download meta: %s", err)
+	}
+	sw.Manifest.Files = append(sw.Manifest.Files, *f)
+	sw.FileWriters[f.Name] = &nopWriteToCloser{bytes.NewReader(buf)}
+
+	var data meta.Data
+	if err := data.UnmarshalBinary(buf); err != nil {
+		return fmt.Errorf("unmarshal meta: %s", err)
+	}
+
+	// Stream each selected shard from one of its owners.
+	shards := selectShards(&data, opt)
+	if opt.ShardID != 0 && len(shards) == 0 {
+		return fmt.Errorf("shard not found: %d", opt.ShardID)
+	}
+	for _, sh := range shards {
+		f, err := cmd.downloadShard(&data, sh, opt.Since, dir)
+		if err != nil {
+			return fmt.Errorf("download shard %d: %s", sh.ID, err)
+		} else if f == nil {
+			cmd.Logger.Printf("skipping shard %d: not modified since %s", sh.ID, opt.Since.Format(time.RFC3339))
+			continue
+		}
 
-	// Connect to snapshotter service.
-	conn, err := net.Dial("tcp", host)
+		sw.Manifest.Files = append(sw.Manifest.Files, *f)
+		sw.FileWriters[f.Name] = &stagedFile{path: filepath.Join(dir, strconv.FormatUint(sh.ID, 10))}
+	}
+
+	// Write the archive.
+	out, err := os.Create(path)
 	if err != nil {
+		return fmt.Errorf("create: %s", err)
+	}
+	defer out.Close()
+
+	if _, err := sw.WriteTo(out); err != nil {
 		return err
 	}
-	defer conn.Close()
+	return out.Sync()
+}
+
+// downloadMeta returns the serialized meta store from host and its manifest entry.
+func (cmd *Command) downloadMeta(host string) ([]byte, *snapshot.File, error) {
+	b, err := snapshotter.NewClient(host).MetastoreBackup()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer b.Close()
+
+	buf, err := ioutil.ReadAll(b)
+	if err != nil {
+		return nil, nil, err
+	} else if int64(len(buf)) != b.Size {
+		return nil, nil, io.ErrUnexpectedEOF
+	}
+
+	sum := sha256.Sum256(buf)
+	return buf, &snapshot.File{
+		Name:     "meta",
+		Size:     b.Size,
+		ModTime:  b.ModTime,
+		Checksum: hex.EncodeToString(sum[:]),
+	}, nil
+}
+
+// downloadShard stages a shard from the first owner that can serve it in
+// dir and returns its manifest entry. Returns nil if the shard was not
+// modified after since.
+func (cmd *Command) downloadShard(data *meta.Data, sh shardInfo, since time.Time, dir string) (*snapshot.File, error) {
+	var lastErr error = errors.New("no owners")
+	for _, o := range sh.Owners {
+		ni := data.Node(o.NodeID)
+		if ni == nil {
+			continue
+		}
+
+		f, err := cmd.downloadShardFrom(ni.Host, sh, since, dir)
+		if err != nil {
+			cmd.Logger.Printf("unable to download shard %d from %s: %s", sh.ID, ni.Host, err)
+			lastErr = err
+			continue
+		}
+		return f, nil
+	}
+	return nil, lastErr
+}
 
-	// Send snapshotter marker byte.
-	if _, err := conn.Write([]byte{snapshotter.MuxHeader}); err != nil {
-		return fmt.Errorf("write snapshot header byte: %s", err)
+func (cmd *Command) downloadShardFrom(host string, sh shardInfo, since time.Time, dir string) (*snapshot.File, error) {
+	b, err := snapshotter.NewClient(host).ShardBackup(sh.ID, since)
+	if err != nil {
+		return nil, err
+	} else if b == nil {
+		return nil, nil
 	}
+	defer b.Close()
 
-	// Write the manifest we currently have.
-	if err := json.NewEncoder(conn).Encode(m); err != nil {
-		return fmt.Errorf("encode snapshot manifest: %s", err)
+	cmd.Logger.Printf("downloading shard %d from %s (%d bytes)", sh.ID, host, b.Size)
+
+	f, err := os.Create(filepath.Join(dir, strconv.FormatUint(sh.ID, 10)))
+	if err != nil {
+		return nil, err
 	}
+	defer f.Close()
 
-	// Read snapshot from the connection.
-	if _, err := io.Copy(f, conn); err != nil {
-		return fmt.Errorf("copy snapshot to file: %s", err)
+	// Checksum the data as it is staged.
+	h := sha256.New()
+	if n, err := io.Copy(io.MultiWriter(f, h), b); err != nil {
+		return nil, err
+	} else if n != b.Size {
+		return nil, io.ErrUnexpectedEOF
 	}
 
-	// FIXME(benbjohnson): Verify integrity of snapshot.
+	return &snapshot.File{
+		Name:     path.Join(sh.Database, sh.RetentionPolicy, strconv.FormatUint(sh.ID, 10)),
+		Size:     b.Size,
+		ModTime:  b.ModTime,
+		Checksum: hex.EncodeToString(h.Sum(nil)),
+	}, nil
+}
+
+// shardInfo is a shard along with the database and retention policy it belongs to.
+type shardInfo struct {
+	meta.ShardInfo
+	Database        string
+	RetentionPolicy string
+}
 
-	return nil
+// selectShards returns the shards in data matching the backup options.
+func selectShards(data *meta.Data, opt *Options) []shardInfo {
+	var a []shardInfo
+	for _, di := range data.Databases {
+		if opt.Database != "" && di.Name != opt.Database {
+			continue
+		}
+		for _, rpi := range di.RetentionPolicies {
+			if opt.RetentionPolicy != "" && rpi.Name != opt.RetentionPolicy {
+				continue
+			}
+			for _, sgi := range rpi.ShardGroups {
+				if sgi.Deleted() {
+					continue
+				}
+				for _, sh := range sgi.Shards {
+					if opt.ShardID != 0 && sh.ID != opt.ShardID {
+						continue
+					}
+					a = append(a, shardInfo{ShardInfo: sh, Database: di.Name, RetentionPolicy: rpi.Name})
+				}
+			}
+		}
+	}
+	return a
+}
+
+// stagedFile writes a staged shard to the archive.
+type stagedFile struct {
+	path string
+}
+
+func (f *stagedFile) WriteTo(w io.Writer) (int64, error) {
+	fd, err := os.Open(f.path)
+	if err != nil {
+		return 0, err
+	}
+	defer fd.Close()
+	return io.Copy(w, fd)
 }
 
+func (f *stagedFile) Close() error { return nil }
+
+type nopWriteToCloser struct {
+	io.WriterTo
+}
+
+func (w *nopWriteToCloser) Close() error { return nil }
+
 // printUsage prints the usage message to STDERR.
 func (cmd *Command) printUsage() {
 	fmt.Fprintf(cmd.Stderr, `usage: influxd backup [flags] PATH
 
-backup downloads a snapshot of a data node and saves it to disk.
+backup downloads a snapshot of the meta store and the data of the selected
+shards from every node that owns them and saves it to a single archive. If
+PATH already exists then the backup is saved as an incremental backup.
 
         -host <host:port>
                           The host to connect to snapshot.
                           Defaults to 127.0.0.1:8088.
+
+        -database <name>
+                          Only back up shards of this database.
+
+        -retention <name>
+                          Only back up shards of this retention policy.
+                          Requires -database.
+
+        -shard <id>
+                          Only back up this shard.
+
+        -since <time>
+                          Only back up shards modified after this RFC3339 time.
 `)
 }
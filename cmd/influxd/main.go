@@ -84,15 +84,25 @@ func (m *Main) Run(args ...string) error {
 
 		signalCh := make(chan os.Signal, 1)
 		signal.Notify(signalCh, os.Interrupt, syscall.SIGTERM)
+		reloadCh := make(chan os.Signal, 1)
+		signal.Notify(reloadCh, syscall.SIGHUP)
 		m.Logger.Println("Listening for signals")
 
-		// Block until one of the signals above is received
-		select {
-		case <-signalCh:
-			m.Logger.Println("Signal received, initializing clean shutdown...")
-			go func() {
-				cmd.Close()
-			}()
+		// Block until one of the signals above is received, reloading the
+		// configuration on each SIGHUP.
+	Loop:
+		for {
+			select {
+			case <-reloadCh:
+				m.Logger.Println("SIGHUP received, reloading configuration...")
+				m.reload(cmd)
+			case <-signalCh:
+				m.Logger.Println("Signal received, initializing clean shutdown...")
+				go func() {
+					cmd.Close()
+				}()
+				break Loop
+			}
 		}
 
 		// Block again until another signal is received, a shutdown timeout elapses,
@@ -138,6 +148,25 @@ func (m *Main) Run(args ...string) error {
 	return nil
 }
 
+// reload reloads the configuration of a running server and logs which
+// settings were applied and which require a restart.
+func (m *Main) reload(cmd *run.Command) {
+	r, err := cmd.Reload()
+	if err != nil {
+		m.Logger.Printf("reload failed: %s", err)
+		return
+	}
+
+	if len(r.Applied) == 0 {
+		m.Logger.Println("configuration reloaded, no settings applied")
+	} else {
+		m.Logger.Printf("configuration reloaded, applied: %s", strings.Join(r.Applied, ", "))
+	}
+	if len(r.RestartRequired) > 0 {
+		m.Logger.Printf("changed settings require a restart: %s", strings.Join(r.RestartRequired, ", "))
+	}
+}
+
 // ParseCommandName extracts the command name and args from the args list.
 func ParseCommandName(args []string) (string, []string) {
 	// Retrieve command name as first argument.
@@ -2,6 +2,8 @@ package restore
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"flag"
 	"fmt"
@@ -140,9 +142,13 @@ func (cmd *Command) unpack(mr *snapshot.MultiReader, config *Config) error {
 func (cmd *Command) unpackMeta(mr *snapshot.MultiReader, sf snapshot.File, config *Config) error {
 	// Read meta into buffer.
 	var buf bytes.Buffer
-	if _, err := io.CopyN(&buf, mr, sf.Size); err != nil {
+	h := sha256.New()
+	if _, err := io.CopyN(io.MultiWriter(&buf, h), mr, sf.Size); err != nil {
 		return fmt.Errorf("copy: %s", err)
 	}
+	if err := verifyChecksum(sf, h.Sum(nil)); err != nil {
+		return err
+	}
 
 	// Unpack into metadata.
 	var data meta.Data
@@ -209,10 +215,23 @@ func (cmd *Command) unpackData(mr *snapshot.MultiReader, sf snapshot.File, confi
 	defer f.Close()
 
 	// Copy contents from reader.
-	if _, err := io.CopyN(f, mr, sf.Size); err != nil {
+	h := sha256.New()
+	if _, err := io.CopyN(io.MultiWriter(f, h), mr, sf.Size); err != nil {
 		return fmt.Errorf("copy: entry=%s, err=%s", sf.Name, err)
 	}
 
+	return verifyChecksum(sf, h.Sum(nil))
+}
+
+// verifyChecksum returns an error if the file has a checksum in the manifest
+// and it doesn't match sum. Older snapshots don't record checksums.
+func verifyChecksum(sf snapshot.File, sum []byte) error {
+	if sf.Checksum == "" {
+		return nil
+	}
+	if got := hex.EncodeToString(sum); got != sf.Checksum {
+		return fmt.Errorf("checksum mismatch: entry=%s, got=%s, exp=%s", sf.Name, got, sf.Checksum)
+	}
 	return nil
 }
 
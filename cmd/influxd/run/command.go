@@ -42,6 +42,9 @@ type Command struct {
 	Stderr io.Writer
 
 	Server *Server
+
+	// The options the server was started with.
+	options Options
 }
 
 // NewCommand return a new instance of Command.
@@ -78,29 +81,10 @@ func (cmd *Command) Run(args ...string) error {
 	// Turn on block profiling to debug stuck databases
 	runtime.SetBlockProfileRate(int(1 * time.Second))
 
-	// Parse config
-	config, err := cmd.ParseConfig(options.ConfigPath)
+	// Parse and validate config
+	config, err := cmd.loadConfig(options)
 	if err != nil {
-		return fmt.Errorf("parse config: %s", err)
-	}
-
-	// Apply any environment variables on top of the parsed config
-	if err := config.ApplyEnvOverrides(); err != nil {
-		return fmt.Errorf("apply env config: %v", err)
-	}
-
-	// Override config hostname if specified in the command line args.
-	if options.Hostname != "" {
-		config.Meta.Hostname = options.Hostname
-	}
-
-	if options.Join != "" {
-		config.Meta.Peers = strings.Split(options.Join, ",")
-	}
-
-	// Validate the configuration.
-	if err := config.Validate(); err != nil {
-		return fmt.Errorf("%s. To generate a valid configuration file run `influxd config > influxdb.generated.conf`.", err)
+		return err
 	}
 
 	// Create server from config and start it.
@@ -115,6 +99,7 @@ func (cmd *Command) Run(args ...string) error {
 		return fmt.Errorf("open server: %s", err)
 	}
 	cmd.Server = s
+	cmd.options = options
 
 	// Begin monitoring the server's error channel.
 	go cmd.monitorServerErrors()
@@ -122,6 +107,50 @@ func (cmd *Command) Run(args ...string) error {
 	return nil
 }
 
+// Reload reads the config file again and applies the settings that can be
+// changed while the server is running. The environment and command line
+// overrides the server was started with are applied again first.
+func (cmd *Command) Reload() (*ReloadReport, error) {
+	if cmd.Server == nil {
+		return nil, fmt.Errorf("server not running")
+	}
+
+	config, err := cmd.loadConfig(cmd.options)
+	if err != nil {
+		return nil, err
+	}
+	return cmd.Server.Reload(config)
+}
+
+// loadConfig parses the config for options and applies the environment and
+// command line overrides. Returns an error if the config is invalid.
+func (cmd *Command) loadConfig(options Options) (*Config, error) {
+	config, err := cmd.ParseConfig(options.ConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("parse config: %s", err)
+	}
+
+	// Apply any environment variables on top of the parsed config
+	if err := config.ApplyEnvOverrides(); err != nil {
+		return nil, fmt.Errorf("apply env config: %v", err)
+	}
+
+	// Override config hostname if specified in the command line args.
+	if options.Hostname != "" {
+		config.Meta.Hostname = options.Hostname
+	}
+
+	if options.Join != "" {
+		config.Meta.Peers = strings.Split(options.Join, ",")
+	}
+
+	// Validate the configuration.
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("%s. To generate a valid configuration file run `influxd config > influxdb.generated.conf`.", err)
+	}
+	return config, nil
+}
+
 // Close shuts down the server.
 func (cmd *Command) Close() error {
 	defer close(cmd.Closed)
@@ -220,6 +249,9 @@ is used.
 
         -pidfile <path>
                           Write process ID to a file.
+
+Any configuration option can be overridden with an INFLUXDB_<SECTION>_<KEY>
+environment variable, such as INFLUXDB_HTTP_BIND_ADDRESS.
 `
 
 // Options represents the command line options that can be parsed.
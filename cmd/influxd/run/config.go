@@ -1,6 +1,8 @@
 package run
 
 import (
+	"encoding"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -9,20 +11,24 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/influxdb/influxdb/cluster"
+	"github.com/influxdb/influxdb/logger"
 	"github.com/influxdb/influxdb/meta"
 	"github.com/influxdb/influxdb/monitor"
+	"github.com/influxdb/influxdb/schema"
 	"github.com/influxdb/influxdb/services/admin"
+	"github.com/influxdb/influxdb/services/audit"
 	"github.com/influxdb/influxdb/services/collectd"
 	"github.com/influxdb/influxdb/services/continuous_querier"
+	"github.com/influxdb/influxdb/services/downsampler"
 	"github.com/influxdb/influxdb/services/graphite"
 	"github.com/influxdb/influxdb/services/hh"
 	"github.com/influxdb/influxdb/services/httpd"
 	"github.com/influxdb/influxdb/services/opentsdb"
 	"github.com/influxdb/influxdb/services/precreator"
 	"github.com/influxdb/influxdb/services/retention"
+	"github.com/influxdb/influxdb/services/subscriber"
 	"github.com/influxdb/influxdb/services/udp"
 	"github.com/influxdb/influxdb/tsdb"
 )
@@ -34,6 +40,7 @@ type Config struct {
 	Cluster    cluster.Config    `toml:"cluster"`
 	Retention  retention.Config  `toml:"retention"`
 	Precreator precreator.Config `toml:"shard-precreation"`
+	Subscriber subscriber.Config `toml:"subscriber"`
 
 	Admin     admin.Config      `toml:"admin"`
 	Monitor   monitor.Config    `toml:"monitor"`
@@ -45,9 +52,16 @@ type Config struct {
 
 	// Snapshot SnapshotConfig `toml:"snapshot"`
 	ContinuousQuery continuous_querier.Config `toml:"continuous_queries"`
+	Downsampler     downsampler.Config        `toml:"downsampler"`
 
 	HintedHandoff hh.Config `toml:"hinted-handoff"`
 
+	Logging logger.Config `toml:"logging"`
+
+	Schema schema.Config `toml:"schema"`
+
+	Audit audit.Config `toml:"audit"`
+
 	// Server reporting
 	ReportingDisabled bool `toml:"reporting-disabled"`
 }
@@ -59,6 +73,7 @@ func NewConfig() *Config {
 	c.Data = tsdb.NewConfig()
 	c.Cluster = cluster.NewConfig()
 	c.Precreator = precreator.NewConfig()
+	c.Subscriber = subscriber.NewConfig()
 
 	c.Admin = admin.NewConfig()
 	c.Monitor = monitor.NewConfig()
@@ -67,8 +82,12 @@ func NewConfig() *Config {
 	c.OpenTSDB = opentsdb.NewConfig()
 
 	c.ContinuousQuery = continuous_querier.NewConfig()
+	c.Downsampler = downsampler.NewConfig()
 	c.Retention = retention.NewConfig()
 	c.HintedHandoff = hh.NewConfig()
+	c.Logging = logger.NewConfig()
+	c.Schema = schema.NewConfig()
+	c.Audit = audit.NewConfig()
 
 	return c
 }
@@ -110,6 +129,26 @@ func (c *Config) Validate() error {
 		return errors.New("Data.WALDir must be specified")
 	}
 
+	if err := c.Meta.Validate(); err != nil {
+		return fmt.Errorf("invalid meta config: %v", err)
+	}
+
+	if err := c.Logging.Validate(); err != nil {
+		return fmt.Errorf("invalid logging config: %v", err)
+	}
+
+	if err := c.Cluster.Validate(); err != nil {
+		return fmt.Errorf("invalid cluster config: %v", err)
+	}
+
+	if err := c.Schema.Validate(); err != nil {
+		return fmt.Errorf("invalid schema config: %v", err)
+	}
+
+	if err := c.Audit.Validate(); err != nil {
+		return fmt.Errorf("invalid audit config: %v", err)
+	}
+
 	for _, g := range c.Graphites {
 		if err := g.Validate(); err != nil {
 			return fmt.Errorf("invalid graphite config: %v", err)
@@ -118,6 +157,41 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// redact replaces the secret in *s, if it's set.
+func redact(s *string) {
+	if *s != "" {
+		*s = "[REDACTED]"
+	}
+}
+
+// Redacted returns a deep copy of the config with its passwords and keys
+// redacted. The copy is made by encoding and decoding the config, so redacting
+// it leaves the config as is.
+func (c *Config) Redacted() (*Config, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("encode config: %s", err)
+	}
+	other := &Config{}
+	if err := json.Unmarshal(b, other); err != nil {
+		return nil, fmt.Errorf("decode config: %s", err)
+	}
+
+	redact(&other.HTTPD.LDAP.BindPassword)
+	redact(&other.Admin.LDAP.BindPassword)
+	for i := range other.UDPs {
+		redact(&other.UDPs[i].SigningKey)
+	}
+	return other, nil
+}
+
+// ApplyEnvOverrides sets the options named by INFLUXDB_* environment variables.
+// The variable for an option is named by its section and key in upper case,
+// with hyphens and dots replaced by underscores, e.g. INFLUXDB_HTTP_BIND_ADDRESS
+// or INFLUXDB_DATA_WAL_FLUSH_INTERVAL. Sections that can be repeated, such as
+// [[graphite]], use the index of the section: INFLUXDB_GRAPHITE_0_TEMPLATES.
+// Lists are set from comma-separated values and tables from comma-separated
+// key=value pairs, e.g. INFLUXDB_LOGGING_LEVELS="httpd=debug,query=warn".
 func (c *Config) ApplyEnvOverrides() error {
 	return c.applyEnvOverrides("INFLUXDB", reflect.ValueOf(c))
 }
@@ -126,6 +200,9 @@ func (c *Config) applyEnvOverrides(prefix string, spec reflect.Value) error {
 	// If we have a pointer, dereference it
 	s := spec
 	if spec.Kind() == reflect.Ptr {
+		if spec.IsNil() {
+			return nil
+		}
 		s = spec.Elem()
 	}
 
@@ -138,88 +215,151 @@ func (c *Config) applyEnvOverrides(prefix string, spec reflect.Value) error {
 	for i := 0; i < s.NumField(); i++ {
 		f := s.Field(i)
 		// Get the toml tag to determine what env var name to use
-		configName := typeOfSpec.Field(i).Tag.Get("toml")
-		// Replace hyphens with underscores to avoid issues with shells
-		configName = strings.Replace(configName, "-", "_", -1)
+		configName := strings.Split(typeOfSpec.Field(i).Tag.Get("toml"), ",")[0]
+		if configName == "" || configName == "-" {
+			continue
+		}
+		// Replace hyphens and dots with underscores to avoid issues with shells
+		configName = strings.NewReplacer("-", "_", ".", "_").Replace(configName)
 		fieldName := typeOfSpec.Field(i).Name
 
 		// Skip any fields that we cannot set
-		if f.CanSet() || f.Kind() == reflect.Slice {
+		if !f.CanSet() {
+			continue
+		}
 
-			// Use the upper-case prefix and toml name for the env var
-			key := strings.ToUpper(configName)
-			if prefix != "" {
-				key = strings.ToUpper(fmt.Sprintf("%s_%s", prefix, configName))
-			}
-			value := os.Getenv(key)
-
-			// If the type is s slice, apply to each using the index as a suffix
-			// e.g. GRAPHITE_0
-			if f.Kind() == reflect.Slice || f.Kind() == reflect.Array {
-				for i := 0; i < f.Len(); i++ {
-					if err := c.applyEnvOverrides(fmt.Sprintf("%s_%d", key, i), f.Index(i)); err != nil {
-						return err
-					}
-				}
-				continue
+		// Use the upper-case prefix and toml name for the env var
+		key := strings.ToUpper(configName)
+		if prefix != "" {
+			key = strings.ToUpper(fmt.Sprintf("%s_%s", prefix, configName))
+		}
+		value := os.Getenv(key)
+
+		// If the type is a slice of sections, apply to each using the index
+		// as a suffix, e.g. GRAPHITE_0. Sections are added for indexes past
+		// the end of the slice that have variables set.
+		if f.Kind() == reflect.Slice && isSection(f.Type().Elem()) {
+			for hasEnvPrefix(fmt.Sprintf("%s_%d_", key, f.Len())) {
+				f.Set(reflect.Append(f, reflect.Zero(f.Type().Elem())))
 			}
-
-			// If it's a sub-config, recursively apply
-			if f.Kind() == reflect.Struct || f.Kind() == reflect.Ptr {
-				if err := c.applyEnvOverrides(key, f); err != nil {
+			for i := 0; i < f.Len(); i++ {
+				if err := c.applyEnvOverrides(fmt.Sprintf("%s_%d", key, i), f.Index(i)); err != nil {
 					return err
 				}
-				continue
 			}
+			continue
+		}
 
-			// Skip any fields we don't have a value to set
-			if value == "" {
-				continue
+		// If it's a sub-config, recursively apply
+		if isSection(f.Type()) {
+			if f.Kind() == reflect.Ptr && f.IsNil() && hasEnvPrefix(key+"_") {
+				f.Set(reflect.New(f.Type().Elem()))
 			}
+			if err := c.applyEnvOverrides(key, f); err != nil {
+				return err
+			}
+			continue
+		}
 
-			switch f.Kind() {
-			case reflect.String:
-				f.SetString(value)
-			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-
-				var intValue int64
-
-				// Handle toml.Duration
-				if f.Type().Name() == "Duration" {
-					dur, err := time.ParseDuration(value)
-					if err != nil {
-						return fmt.Errorf("failed to apply %v to %v using type %v and value '%v'", key, fieldName, f.Type().String(), value)
-					}
-					intValue = dur.Nanoseconds()
-				} else {
-					var err error
-					intValue, err = strconv.ParseInt(value, 0, f.Type().Bits())
-					if err != nil {
-						return fmt.Errorf("failed to apply %v to %v using type %v and value '%v'", key, fieldName, f.Type().String(), value)
-					}
-				}
+		// Skip any fields we don't have a value to set
+		if value == "" {
+			continue
+		}
 
-				f.SetInt(intValue)
-			case reflect.Bool:
-				boolValue, err := strconv.ParseBool(value)
-				if err != nil {
-					return fmt.Errorf("failed to apply %v to %v using type %v and value '%v'", key, fieldName, f.Type().String(), value)
+		if err := setEnvValue(f, value); err != nil {
+			return fmt.Errorf("failed to apply %v to %v using type %v and value '%v'", key, fieldName, f.Type().String(), value)
+		}
+	}
+	return nil
+}
 
-				}
-				f.SetBool(boolValue)
-			case reflect.Float32, reflect.Float64:
-				floatValue, err := strconv.ParseFloat(value, f.Type().Bits())
-				if err != nil {
-					return fmt.Errorf("failed to apply %v to %v using type %v and value '%v'", key, fieldName, f.Type().String(), value)
+// setEnvValue sets f from the value of an environment variable.
+func setEnvValue(f reflect.Value, value string) error {
+	// Use the type's own parsing, e.g. for toml.Duration.
+	if u, ok := f.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(value))
+	}
 
-				}
-				f.SetFloat(floatValue)
-			default:
-				if err := c.applyEnvOverrides(key, f); err != nil {
-					return err
-				}
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		intValue, err := strconv.ParseInt(value, 0, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetInt(intValue)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		uintValue, err := strconv.ParseUint(value, 0, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetUint(uintValue)
+	case reflect.Bool:
+		boolValue, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		f.SetBool(boolValue)
+	case reflect.Float32, reflect.Float64:
+		floatValue, err := strconv.ParseFloat(value, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetFloat(floatValue)
+	case reflect.Slice:
+		// Lists are comma-separated and replace the configured list.
+		items := strings.Split(value, ",")
+		slice := reflect.MakeSlice(f.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setEnvValue(slice.Index(i), strings.TrimSpace(item)); err != nil {
+				return err
+			}
+		}
+		f.Set(slice)
+	case reflect.Map:
+		// Tables are comma-separated key=value pairs merged into the
+		// configured table.
+		if f.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("unsupported map key type: %s", f.Type().Key())
+		}
+		if f.IsNil() {
+			f.Set(reflect.MakeMap(f.Type()))
+		}
+		for _, pair := range strings.Split(value, ",") {
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 {
+				return fmt.Errorf("invalid key=value pair: %q", pair)
 			}
+			v := reflect.New(f.Type().Elem()).Elem()
+			if err := setEnvValue(v, strings.TrimSpace(kv[1])); err != nil {
+				return err
+			}
+			f.SetMapIndex(reflect.ValueOf(strings.TrimSpace(kv[0])).Convert(f.Type().Key()), v)
 		}
+	default:
+		return fmt.Errorf("unsupported type: %s", f.Type())
 	}
 	return nil
 }
+
+// isSection returns true if t is a config section: a struct, or a pointer to
+// one, that isn't set from a single value.
+func isSection(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && !reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// hasEnvPrefix returns true if an environment variable starts with prefix.
+func hasEnvPrefix(prefix string) bool {
+	for _, kv := range os.Environ() {
+		if strings.HasPrefix(kv, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -2,10 +2,14 @@ package run_test
 
 import (
 	"os"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/influxdb/influxdb/cmd/influxd/run"
+	"github.com/influxdb/influxdb/services/graphite"
+	"github.com/influxdb/influxdb/services/udp"
 )
 
 // Ensure the configuration can be parsed.
@@ -47,6 +51,13 @@ enabled = true
 
 [continuous_queries]
 enabled = true
+
+[logging]
+level = "warn"
+format = "json"
+
+[logging.levels]
+httpd = "debug"
 `, &c); err != nil {
 		t.Fatal(err)
 	}
@@ -74,6 +85,10 @@ enabled = true
 		t.Fatalf("unexpected udp bind address: %s", c.UDPs[0].BindAddress)
 	} else if c.ContinuousQuery.Enabled != true {
 		t.Fatalf("unexpected continuous query enabled: %v", c.ContinuousQuery.Enabled)
+	} else if c.Logging.Level != "warn" || c.Logging.Format != "json" {
+		t.Fatalf("unexpected logging: %+v", c.Logging)
+	} else if c.Logging.Levels["httpd"] != "debug" {
+		t.Fatalf("unexpected logging levels: %v", c.Logging.Levels)
 	}
 }
 
@@ -140,3 +155,92 @@ enabled = true
 		t.Fatalf("unexpected graphite protocol(0): %s", c.Graphites[0].Protocol)
 	}
 }
+
+// Ensure lists, tables, durations and new repeated sections can be set from
+// the environment.
+func TestConfig_Parse_EnvOverride_Types(t *testing.T) {
+	c := run.NewConfig()
+	if _, err := toml.Decode(`
+[[graphite]]
+protocol = "tcp"
+`, c); err != nil {
+		t.Fatal(err)
+	}
+
+	for k, v := range map[string]string{
+		"INFLUXDB_GRAPHITE_0_TAGS":            "region=us-east, zone=1a",
+		"INFLUXDB_DATA_WAL_FLUSH_INTERVAL":    "5s",
+		"INFLUXDB_LOGGING_LEVELS":             "httpd=debug,query=warn",
+		"INFLUXDB_GRAPHITE_0_TEMPLATES":       "measurement.host",
+		"INFLUXDB_GRAPHITE_1_BIND_ADDRESS":    ":2004",
+		"INFLUXDB_HINTED_HANDOFF_MAX_SIZE":    "1024",
+		"INFLUXDB_CONTINUOUS_QUERIES_ENABLED": "false",
+	} {
+		if err := os.Setenv(k, v); err != nil {
+			t.Fatalf("failed to set env var: %v", err)
+		}
+		defer os.Unsetenv(k)
+	}
+
+	if err := c.ApplyEnvOverrides(); err != nil {
+		t.Fatalf("failed to apply env overrides: %v", err)
+	}
+
+	if !reflect.DeepEqual(c.Graphites[0].Tags, []string{"region=us-east", "zone=1a"}) {
+		t.Fatalf("unexpected graphite tags: %v", c.Graphites[0].Tags)
+	} else if time.Duration(c.Data.WALFlushInterval) != 5*time.Second {
+		t.Fatalf("unexpected wal flush interval: %s", c.Data.WALFlushInterval)
+	} else if !reflect.DeepEqual(c.Logging.Levels, map[string]string{"httpd": "debug", "query": "warn"}) {
+		t.Fatalf("unexpected logging levels: %v", c.Logging.Levels)
+	} else if len(c.Graphites) != 2 {
+		t.Fatalf("unexpected graphite count: %d", len(c.Graphites))
+	} else if !reflect.DeepEqual(c.Graphites[0].Templates, []string{"measurement.host"}) {
+		t.Fatalf("unexpected graphite templates: %v", c.Graphites[0].Templates)
+	} else if c.Graphites[1].BindAddress != ":2004" {
+		t.Fatalf("unexpected graphite bind address: %s", c.Graphites[1].BindAddress)
+	} else if c.HintedHandoff.MaxSize != 1024 {
+		t.Fatalf("unexpected hinted handoff max size: %d", c.HintedHandoff.MaxSize)
+	} else if c.ContinuousQuery.Enabled {
+		t.Fatal("expected continuous queries to be disabled")
+	}
+}
+
+// Ensure an invalid value in the environment returns an error.
+func TestConfig_Parse_EnvOverride_Invalid(t *testing.T) {
+	c := run.NewConfig()
+	if err := os.Setenv("INFLUXDB_DATA_WAL_FLUSH_INTERVAL", "often"); err != nil {
+		t.Fatalf("failed to set env var: %v", err)
+	}
+	defer os.Unsetenv("INFLUXDB_DATA_WAL_FLUSH_INTERVAL")
+
+	if err := c.ApplyEnvOverrides(); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+// Ensure the secrets of a redacted config are redacted without changing the
+// config.
+func TestConfig_Redacted(t *testing.T) {
+	c := run.NewConfig()
+	c.HTTPD.LDAP.BindPassword = "http"
+	c.Admin.LDAP.BindPassword = "admin"
+	c.UDPs = []udp.Config{{BindAddress: ":4444", SigningKey: "udp"}}
+	c.Graphites = []graphite.Config{{BindAddress: ":2003", Tags: []string{"region=us-east"}}}
+
+	other, err := c.Redacted()
+	if err != nil {
+		t.Fatal(err)
+	} else if other.HTTPD.LDAP.BindPassword != "[REDACTED]" {
+		t.Fatalf("unexpected http bind password: %s", other.HTTPD.LDAP.BindPassword)
+	} else if other.Admin.LDAP.BindPassword != "[REDACTED]" {
+		t.Fatalf("unexpected admin bind password: %s", other.Admin.LDAP.BindPassword)
+	} else if len(other.UDPs) != 1 || other.UDPs[0].SigningKey != "[REDACTED]" || other.UDPs[0].BindAddress != ":4444" {
+		t.Fatalf("unexpected udp config: %+v", other.UDPs)
+	} else if len(other.Graphites) != 1 || !reflect.DeepEqual(other.Graphites[0].Tags, []string{"region=us-east"}) {
+		t.Fatalf("unexpected graphite config: %+v", other.Graphites)
+	}
+
+	if c.HTTPD.LDAP.BindPassword != "http" || c.Admin.LDAP.BindPassword != "admin" || c.UDPs[0].SigningKey != "udp" {
+		t.Fatal("expected config to be unchanged")
+	}
+}
@@ -2,23 +2,32 @@ package run
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
 	"os"
+	"reflect"
 	"runtime"
 	"runtime/pprof"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/BurntSushi/toml"
 	"github.com/influxdb/influxdb/cluster"
+	"github.com/influxdb/influxdb/logger"
 	"github.com/influxdb/influxdb/meta"
 	"github.com/influxdb/influxdb/monitor"
+	"github.com/influxdb/influxdb/schema"
 	"github.com/influxdb/influxdb/services/admin"
+	"github.com/influxdb/influxdb/services/audit"
 	"github.com/influxdb/influxdb/services/collectd"
 	"github.com/influxdb/influxdb/services/continuous_querier"
 	"github.com/influxdb/influxdb/services/copier"
+	"github.com/influxdb/influxdb/services/downsampler"
 	"github.com/influxdb/influxdb/services/graphite"
 	"github.com/influxdb/influxdb/services/hh"
 	"github.com/influxdb/influxdb/services/httpd"
@@ -26,6 +35,7 @@ import (
 	"github.com/influxdb/influxdb/services/precreator"
 	"github.com/influxdb/influxdb/services/retention"
 	"github.com/influxdb/influxdb/services/snapshotter"
+	"github.com/influxdb/influxdb/services/subscriber"
 	"github.com/influxdb/influxdb/services/udp"
 	"github.com/influxdb/influxdb/tcp"
 	"github.com/influxdb/influxdb/tsdb"
@@ -45,6 +55,10 @@ type BuildInfo struct {
 type Server struct {
 	buildInfo BuildInfo
 
+	// The running configuration, updated by Reload.
+	mu     sync.Mutex
+	config *Config
+
 	err     chan error
 	closing chan struct{}
 
@@ -52,16 +66,23 @@ type Server struct {
 	BindAddress string
 	Listener    net.Listener
 
-	MetaStore     *meta.Store
-	TSDBStore     *tsdb.Store
-	QueryExecutor *tsdb.QueryExecutor
-	PointsWriter  *cluster.PointsWriter
-	ShardWriter   *cluster.ShardWriter
-	ShardMapper   *cluster.ShardMapper
-	HintedHandoff *hh.Service
+	MetaStore      *meta.Store
+	TSDBStore      *tsdb.Store
+	QueryExecutor  *tsdb.QueryExecutor
+	PointsWriter   *cluster.PointsWriter
+	WriteCoalescer *cluster.WriteCoalescer
+	ShardWriter    *cluster.ShardWriter
+	ShardMapper    *cluster.ShardMapper
+	HintedHandoff  *hh.Service
+	Subscriber     *subscriber.Service
+	Schema         *schema.Registry
 
 	Services []Service
 
+	// The graphite services, by index of their config. Disabled services
+	// are nil.
+	graphites []*graphite.Service
+
 	// These references are required for the tcp muxer.
 	ClusterService     *cluster.Service
 	SnapshotterService *snapshotter.Service
@@ -69,22 +90,38 @@ type Server struct {
 
 	Monitor *monitor.Monitor
 
+	// Logging is shared by the loggers of all subsystems. Its levels and
+	// format can be changed while the server is running.
+	Logging *logger.Output
+
 	// Server reporting
 	reportingDisabled bool
 
 	// Profiling
 	CPUProfile string
 	MemProfile string
+
+	// Set atomically once the TSDB store is open, and once the whole server
+	// is open. The WALs of the shards may still be replaying in the
+	// background.
+	storeOpened int32
+	opened      int32
 }
 
 // NewServer returns a new instance of Server built from a config.
 func NewServer(c *Config, buildInfo *BuildInfo) (*Server, error) {
+	logging := logger.NewOutput(os.Stderr)
+	if err := logging.Configure(c.Logging); err != nil {
+		return nil, fmt.Errorf("logging: %s", err)
+	}
+
 	// Construct base meta store and data store.
 	tsdbStore := tsdb.NewStore(c.Data.Dir)
 	tsdbStore.EngineOptions.Config = c.Data
 
 	s := &Server{
 		buildInfo: *buildInfo,
+		config:    c,
 		err:       make(chan error),
 		closing:   make(chan struct{}),
 
@@ -96,16 +133,24 @@ func NewServer(c *Config, buildInfo *BuildInfo) (*Server, error) {
 
 		Monitor: monitor.New(c.Monitor),
 
+		Logging: logging,
+
 		reportingDisabled: c.ReportingDisabled,
 	}
 
+	s.MetaStore.Logger = s.Logging.StdLogger("metastore")
+	s.TSDBStore.Logger = s.Logging.Logger("store")
+
 	// Copy TSDB configuration.
 	s.TSDBStore.EngineOptions.MaxWALSize = c.Data.MaxWALSize
 	s.TSDBStore.EngineOptions.WALFlushInterval = time.Duration(c.Data.WALFlushInterval)
 	s.TSDBStore.EngineOptions.WALPartitionFlushDelay = time.Duration(c.Data.WALPartitionFlushDelay)
+	s.TSDBStore.WriteTimeout = time.Duration(c.Cluster.WriteTimeout)
 
 	// Set the shard mapper
 	s.ShardMapper = cluster.NewShardMapper(time.Duration(c.Cluster.ShardMapperTimeout))
+	s.ShardMapper.WindowSize = c.Cluster.ShardMapperWindowSize
+	s.ShardMapper.Compress = c.Cluster.ShardMapperCompression
 	s.ShardMapper.ForceRemoteMapping = c.Cluster.ForceRemoteShardMapping
 	s.ShardMapper.MetaStore = s.MetaStore
 	s.ShardMapper.TSDBStore = s.TSDBStore
@@ -116,6 +161,10 @@ func NewServer(c *Config, buildInfo *BuildInfo) (*Server, error) {
 	s.QueryExecutor.MetaStatementExecutor = &meta.StatementExecutor{Store: s.MetaStore}
 	s.QueryExecutor.MonitorStatementExecutor = &monitor.StatementExecutor{Monitor: s.Monitor}
 	s.QueryExecutor.ShardMapper = s.ShardMapper
+	s.QueryExecutor.SetDatabaseLimits(databaseQueryLimits(c.Cluster.DatabaseLimits))
+	s.QueryExecutor.SetSelectLimits(selectLimits(c.Cluster))
+	s.QueryExecutor.SetMaxConcurrentSelects(c.Cluster.MaxConcurrentSelects)
+	s.QueryExecutor.SetLogger(s.Logging.Logger("query"))
 
 	// Set the shard writer
 	s.ShardWriter = cluster.NewShardWriter(time.Duration(c.Cluster.ShardWriterTimeout))
@@ -123,14 +172,61 @@ func NewServer(c *Config, buildInfo *BuildInfo) (*Server, error) {
 
 	// Create the hinted handoff service
 	s.HintedHandoff = hh.NewService(c.HintedHandoff, s.ShardWriter)
+	s.HintedHandoff.MetaStore = s.MetaStore
+	s.HintedHandoff.SetLogger(s.Logging.StdLogger("handoff"))
+	s.ShardMapper.HintedHandoff = s.HintedHandoff
+	s.QueryExecutor.HintedHandoffStatementExecutor = &hh.StatementExecutor{HintedHandoff: s.HintedHandoff}
+
+	// Create the subscriber service
+	if c.Subscriber.Enabled {
+		s.Subscriber = subscriber.NewService(c.Subscriber)
+		s.Subscriber.MetaStore = s.MetaStore
+		s.Subscriber.Logger = s.Logging.StdLogger("subscriber")
+	}
 
 	// Initialize points writer.
 	s.PointsWriter = cluster.NewPointsWriter()
 	s.PointsWriter.WriteTimeout = time.Duration(c.Cluster.WriteTimeout)
+	s.PointsWriter.MaxFutureTime = time.Duration(c.Cluster.MaxFutureTime)
+	s.PointsWriter.MaxPastTime = time.Duration(c.Cluster.MaxPastTime)
+	s.PointsWriter.RejectBeyondRetention = c.Cluster.RejectBeyondRetention
+	s.PointsWriter.BatchIDTTL = time.Duration(c.Cluster.BatchIDTTL)
+	s.PointsWriter.MaxBatchIDs = c.Cluster.MaxBatchIDs
+	s.PointsWriter.WriteRetries = c.Cluster.WriteRetries
+	s.PointsWriter.RetryInterval = time.Duration(c.Cluster.WriteRetryInterval)
+	s.PointsWriter.BreakerFailures = c.Cluster.BreakerFailures
+	s.PointsWriter.BreakerCooldown = time.Duration(c.Cluster.BreakerCooldown)
+	s.PointsWriter.SetRoutes(c.Cluster.Routes)
+	s.PointsWriter.SetDatabaseLimits(c.Cluster.DatabaseLimits)
+	s.PointsWriter.SetDedupWindows(c.Cluster.DedupWindows)
+	if err := s.PointsWriter.SetTransforms(c.Cluster.Transforms); err != nil {
+		return nil, fmt.Errorf("cluster: %s", err)
+	}
 	s.PointsWriter.MetaStore = s.MetaStore
 	s.PointsWriter.TSDBStore = s.TSDBStore
 	s.PointsWriter.ShardWriter = s.ShardWriter
 	s.PointsWriter.HintedHandoff = s.HintedHandoff
+	s.PointsWriter.Logger = s.Logging.Logger("write")
+	s.QueryExecutor.IntoWriter = s.PointsWriter
+
+	// Merge small HTTP writes before they reach the points writer.
+	if c.Cluster.CoalesceWrites {
+		s.WriteCoalescer = cluster.NewWriteCoalescer(c.Cluster.CoalesceMaxPoints, time.Duration(c.Cluster.CoalesceMaxDelay))
+		s.WriteCoalescer.PointsWriter = s.PointsWriter
+	}
+	if s.Subscriber != nil {
+		s.PointsWriter.Subscriber = s.Subscriber
+	}
+
+	// Create the schema registry enforced by the points writer.
+	if c.Schema.Enabled {
+		registry, err := schema.NewRegistry(c.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("schema: %s", err)
+		}
+		s.Schema = registry
+		s.PointsWriter.Schema = registry
+	}
 
 	// Initialize the monitor
 	s.Monitor.Version = s.buildInfo.Version
@@ -138,6 +234,7 @@ func NewServer(c *Config, buildInfo *BuildInfo) (*Server, error) {
 	s.Monitor.Branch = s.buildInfo.Branch
 	s.Monitor.MetaStore = s.MetaStore
 	s.Monitor.PointsWriter = s.PointsWriter
+	s.Monitor.SetLogger(s.Logging.StdLogger("monitor"))
 
 	// Append services.
 	s.appendClusterService(c.Cluster)
@@ -146,6 +243,8 @@ func NewServer(c *Config, buildInfo *BuildInfo) (*Server, error) {
 	s.appendCopierService()
 	s.appendAdminService(c.Admin)
 	s.appendContinuousQueryService(c.ContinuousQuery)
+	s.appendDownsamplerService(c.Downsampler)
+	s.appendAuditService(c.Audit)
 	s.appendHTTPDService(c.HTTPD)
 	s.appendCollectdService(c.Collectd)
 	if err := s.appendOpenTSDBService(c.OpenTSDB); err != nil {
@@ -168,6 +267,7 @@ func (s *Server) appendClusterService(c cluster.Config) {
 	srv := cluster.NewService(c)
 	srv.TSDBStore = s.TSDBStore
 	srv.MetaStore = s.MetaStore
+	srv.SetLogger(s.Logging.Logger("cluster"))
 	s.Services = append(s.Services, srv)
 	s.ClusterService = srv
 }
@@ -176,13 +276,17 @@ func (s *Server) appendSnapshotterService() {
 	srv := snapshotter.NewService()
 	srv.TSDBStore = s.TSDBStore
 	srv.MetaStore = s.MetaStore
+	srv.SetLogger(s.Logging.StdLogger("snapshot"))
 	s.Services = append(s.Services, srv)
 	s.SnapshotterService = srv
 }
 
 func (s *Server) appendCopierService() {
 	srv := copier.NewService()
+	srv.MetaStore = s.MetaStore
 	srv.TSDBStore = s.TSDBStore
+	srv.SetLogger(s.Logging.StdLogger("copier"))
+	s.QueryExecutor.CopierStatementExecutor = &copier.StatementExecutor{Copier: srv}
 	s.Services = append(s.Services, srv)
 	s.CopierService = srv
 }
@@ -194,6 +298,7 @@ func (s *Server) appendRetentionPolicyService(c retention.Config) {
 	srv := retention.NewService(c)
 	srv.MetaStore = s.MetaStore
 	srv.TSDBStore = s.TSDBStore
+	srv.SetLogger(s.Logging.StdLogger("retention"))
 	s.Services = append(s.Services, srv)
 }
 
@@ -202,6 +307,17 @@ func (s *Server) appendAdminService(c admin.Config) {
 		return
 	}
 	srv := admin.NewService(c)
+	srv.MetaStore = s.MetaStore
+	srv.SetLogger(s.Logging.StdLogger("admin"))
+	s.Services = append(s.Services, srv)
+}
+
+func (s *Server) appendAuditService(c audit.Config) {
+	if !c.Enabled {
+		return
+	}
+	srv := audit.NewService(c)
+	srv.SetLogger(s.Logging.StdLogger("audit"))
 	s.Services = append(s.Services, srv)
 }
 
@@ -213,10 +329,23 @@ func (s *Server) appendHTTPDService(c httpd.Config) {
 	srv.Handler.MetaStore = s.MetaStore
 	srv.Handler.QueryExecutor = s.QueryExecutor
 	srv.Handler.PointsWriter = s.PointsWriter
+	if s.WriteCoalescer != nil {
+		srv.Handler.PointsWriter = s.WriteCoalescer
+	}
 	srv.Handler.Version = s.buildInfo.Version
+	srv.Handler.Diagnostics = s.diagnostics
+	srv.Handler.Health = s.health
+	srv.Handler.Ready = s.ready
+	srv.Handler.Replay = s.TSDBStore.ReplayStatus
+	srv.Handler.WriteThrottler = s.TSDBStore
+	srv.SetLogger(s.Logging.Logger("httpd"))
 
 	// If a ContinuousQuerier service has been started, attach it.
+	// Likewise for the audit log.
 	for _, srvc := range s.Services {
+		if auditsrvc, ok := srvc.(*audit.Service); ok {
+			srv.Handler.AuditLog = auditsrvc
+		}
 		if cqsrvc, ok := srvc.(continuous_querier.ContinuousQuerier); ok {
 			srv.Handler.ContinuousQuerier = cqsrvc
 		}
@@ -232,6 +361,7 @@ func (s *Server) appendCollectdService(c collectd.Config) {
 	srv := collectd.NewService(c)
 	srv.MetaStore = s.MetaStore
 	srv.PointsWriter = s.PointsWriter
+	srv.SetLogger(s.Logging.StdLogger("collectd"))
 	s.Services = append(s.Services, srv)
 }
 
@@ -245,22 +375,26 @@ func (s *Server) appendOpenTSDBService(c opentsdb.Config) error {
 	}
 	srv.PointsWriter = s.PointsWriter
 	srv.MetaStore = s.MetaStore
+	srv.SetLogger(s.Logging.StdLogger("opentsdb"))
 	s.Services = append(s.Services, srv)
 	return nil
 }
 
 func (s *Server) appendGraphiteService(c graphite.Config) error {
 	if !c.Enabled {
+		s.graphites = append(s.graphites, nil)
 		return nil
 	}
 	srv, err := graphite.NewService(c)
 	if err != nil {
 		return err
 	}
+	s.graphites = append(s.graphites, srv)
 
 	srv.PointsWriter = s.PointsWriter
 	srv.MetaStore = s.MetaStore
 	srv.Monitor = s.Monitor
+	srv.SetLogger(s.Logging.StdLogger("graphite"))
 	s.Services = append(s.Services, srv)
 	return nil
 }
@@ -275,6 +409,7 @@ func (s *Server) appendPrecreatorService(c precreator.Config) error {
 	}
 
 	srv.MetaStore = s.MetaStore
+	srv.SetLogger(s.Logging.StdLogger("shard-precreation"))
 	s.Services = append(s.Services, srv)
 	return nil
 }
@@ -285,6 +420,7 @@ func (s *Server) appendUDPService(c udp.Config) {
 	}
 	srv := udp.NewService(c)
 	srv.PointsWriter = s.PointsWriter
+	srv.SetLogger(s.Logging.StdLogger("udp"))
 	s.Services = append(s.Services, srv)
 }
 
@@ -296,6 +432,22 @@ func (s *Server) appendContinuousQueryService(c continuous_querier.Config) {
 	srv.MetaStore = s.MetaStore
 	srv.QueryExecutor = s.QueryExecutor
 	srv.PointsWriter = s.PointsWriter
+	srv.Ready = s.replayed
+	srv.SetLogger(s.Logging.Logger("continuous_querier"))
+	s.QueryExecutor.ContinuousQueryStatementExecutor = &continuous_querier.StatementExecutor{ContinuousQuerier: srv}
+	s.Services = append(s.Services, srv)
+}
+
+func (s *Server) appendDownsamplerService(c downsampler.Config) {
+	if !c.Enabled {
+		return
+	}
+	srv := downsampler.NewService(c)
+	srv.MetaStore = s.MetaStore
+	srv.QueryExecutor = s.QueryExecutor
+	srv.PointsWriter = s.PointsWriter
+	srv.Ready = s.replayed
+	srv.SetLogger(s.Logging.Logger("downsampler"))
 	s.Services = append(s.Services, srv)
 }
 
@@ -361,12 +513,25 @@ func (s *Server) Open() error {
 		if err := s.TSDBStore.Open(); err != nil {
 			return fmt.Errorf("open tsdb store: %s", err)
 		}
+		atomic.StoreInt32(&s.storeOpened, 1)
 
 		// Open the hinted handoff service
 		if err := s.HintedHandoff.Open(); err != nil {
 			return fmt.Errorf("open hinted handoff: %s", err)
 		}
 
+		// Open the subscriber service
+		if s.Subscriber != nil {
+			if err := s.Subscriber.Open(); err != nil {
+				return fmt.Errorf("open subscriber: %s", err)
+			}
+		}
+
+		// Open the points writer service
+		if err := s.PointsWriter.Open(); err != nil {
+			return fmt.Errorf("open points writer: %s", err)
+		}
+
 		for _, service := range s.Services {
 			if err := service.Open(); err != nil {
 				return fmt.Errorf("open service: %s", err)
@@ -378,6 +543,7 @@ func (s *Server) Open() error {
 			go s.startServerReporting()
 		}
 
+		atomic.StoreInt32(&s.opened, 1)
 		return nil
 
 	}(); err != nil {
@@ -391,6 +557,7 @@ func (s *Server) Open() error {
 // Close shuts down the meta and data stores and all services.
 func (s *Server) Close() error {
 	stopProfile()
+	atomic.StoreInt32(&s.opened, 0)
 
 	// Close the listener first to stop any new connections
 	if s.Listener != nil {
@@ -407,12 +574,25 @@ func (s *Server) Close() error {
 		s.Monitor.Close()
 	}
 
+	if s.WriteCoalescer != nil {
+		s.WriteCoalescer.Close()
+	}
+
+	if s.PointsWriter != nil {
+		s.PointsWriter.Close()
+	}
+
 	if s.HintedHandoff != nil {
 		s.HintedHandoff.Close()
 	}
 
+	if s.Subscriber != nil {
+		s.Subscriber.Close()
+	}
+
 	// Close the TSDBStore, no more reads or writes at this point
 	if s.TSDBStore != nil {
+		atomic.StoreInt32(&s.storeOpened, 0)
 		s.TSDBStore.Close()
 	}
 
@@ -425,6 +605,243 @@ func (s *Server) Close() error {
 	return nil
 }
 
+// ReloadReport describes the outcome of reloading the configuration. Settings
+// are named by their section in the config file.
+type ReloadReport struct {
+	// Applied lists the settings changed on the running server.
+	Applied []string
+
+	// RestartRequired lists the changed settings that only take effect
+	// after the server is restarted.
+	RestartRequired []string
+}
+
+// Reload applies the settings of c that can be changed while the server is
+// running: the logging levels and format, the measurement schemas, the
+// continuous query settings, the graphite templates and the collectd types
+// db. The report lists the settings that were applied and the other changed
+// settings, which require a restart. Nothing is applied if c is invalid.
+func (s *Server) Reload(c *Config) (*ReloadReport, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Build the new running configuration from the current one.
+	r := &ReloadReport{}
+	running := *s.config
+	running.Graphites = append([]graphite.Config(nil), s.config.Graphites...)
+	running.Cluster.Routes = append([]cluster.Route(nil), s.config.Cluster.Routes...)
+	running.Cluster.DatabaseLimits = append([]cluster.DatabaseLimit(nil), s.config.Cluster.DatabaseLimits...)
+	running.Cluster.DedupWindows = append([]cluster.DedupWindow(nil), s.config.Cluster.DedupWindows...)
+	running.Cluster.Transforms = append([]cluster.Transform(nil), s.config.Cluster.Transforms...)
+
+	// Apply the collectd types first as reading the file can fail.
+	if srv := s.collectdService(); srv != nil && c.Collectd.TypesDB != running.Collectd.TypesDB {
+		if err := srv.Reload(c.Collectd); err != nil {
+			return nil, fmt.Errorf("collectd: %s", err)
+		}
+		running.Collectd.TypesDB = c.Collectd.TypesDB
+		r.Applied = append(r.Applied, "collectd.typesdb")
+	}
+
+	if !reflect.DeepEqual(c.Logging, running.Logging) {
+		if err := s.Logging.Configure(c.Logging); err != nil {
+			return nil, fmt.Errorf("logging: %s", err)
+		}
+		running.Logging = c.Logging
+		r.Applied = append(r.Applied, "logging")
+	}
+
+	if s.Schema != nil {
+		sc := c.Schema
+		sc.Enabled = running.Schema.Enabled
+		if !reflect.DeepEqual(sc, running.Schema) {
+			if err := s.Schema.Configure(sc); err != nil {
+				return nil, fmt.Errorf("schema: %s", err)
+			}
+			running.Schema = sc
+			r.Applied = append(r.Applied, "schema")
+		}
+	}
+
+	if !reflect.DeepEqual(c.Cluster.Routes, running.Cluster.Routes) {
+		s.PointsWriter.SetRoutes(c.Cluster.Routes)
+		running.Cluster.Routes = c.Cluster.Routes
+		r.Applied = append(r.Applied, "cluster.route")
+	}
+
+	if !reflect.DeepEqual(c.Cluster.DatabaseLimits, running.Cluster.DatabaseLimits) {
+		s.PointsWriter.SetDatabaseLimits(c.Cluster.DatabaseLimits)
+		s.QueryExecutor.SetDatabaseLimits(databaseQueryLimits(c.Cluster.DatabaseLimits))
+		running.Cluster.DatabaseLimits = c.Cluster.DatabaseLimits
+		r.Applied = append(r.Applied, "cluster.database-limit")
+	}
+
+	if !reflect.DeepEqual(c.Cluster.DedupWindows, running.Cluster.DedupWindows) {
+		s.PointsWriter.SetDedupWindows(c.Cluster.DedupWindows)
+		running.Cluster.DedupWindows = c.Cluster.DedupWindows
+		r.Applied = append(r.Applied, "cluster.dedup-window")
+	}
+
+	if !reflect.DeepEqual(c.Cluster.Transforms, running.Cluster.Transforms) {
+		if err := s.PointsWriter.SetTransforms(c.Cluster.Transforms); err != nil {
+			return nil, fmt.Errorf("cluster: %s", err)
+		}
+		running.Cluster.Transforms = c.Cluster.Transforms
+		r.Applied = append(r.Applied, "cluster.transform")
+	}
+
+	if l := selectLimits(c.Cluster); l != selectLimits(running.Cluster) {
+		s.QueryExecutor.SetSelectLimits(l)
+		running.Cluster.MaxSelectPointN, running.Cluster.MaxSelectSeriesN, running.Cluster.TruncateSelect = l.MaxPointN, l.MaxSeriesN, l.Truncate
+		r.Applied = append(r.Applied, "cluster.max-select")
+	}
+
+	if n := c.Cluster.MaxConcurrentSelects; n != running.Cluster.MaxConcurrentSelects {
+		s.QueryExecutor.SetMaxConcurrentSelects(n)
+		running.Cluster.MaxConcurrentSelects = n
+		r.Applied = append(r.Applied, "cluster.max-concurrent-selects")
+	}
+
+	if srv := s.continuousQueryService(); srv != nil {
+		cq := c.ContinuousQuery
+		cq.Enabled = running.ContinuousQuery.Enabled
+		if cq != running.ContinuousQuery {
+			srv.SetConfig(cq)
+			running.ContinuousQuery = cq
+			r.Applied = append(r.Applied, "continuous_queries")
+		}
+	}
+
+	// Graphite services can only be matched to their config if none were
+	// added or removed.
+	if len(c.Graphites) == len(running.Graphites) {
+		for i, srv := range s.graphites {
+			g, cur := c.Graphites[i], running.Graphites[i]
+			if srv == nil || (reflect.DeepEqual(g.Templates, cur.Templates) && reflect.DeepEqual(g.Tags, cur.Tags) && g.Separator == cur.Separator) {
+				continue
+			}
+			if err := srv.Reload(g); err != nil {
+				return nil, fmt.Errorf("graphite: %s", err)
+			}
+			cur.Templates, cur.Tags, cur.Separator = g.Templates, g.Tags, g.Separator
+			running.Graphites[i] = cur
+			r.Applied = append(r.Applied, fmt.Sprintf("graphite[%d].templates", i))
+		}
+	}
+
+	// Any remaining differences require a restart.
+	rv, cv := reflect.ValueOf(running), reflect.ValueOf(*c)
+	for i := 0; i < rv.NumField(); i++ {
+		if !reflect.DeepEqual(rv.Field(i).Interface(), cv.Field(i).Interface()) {
+			name := strings.Split(rv.Type().Field(i).Tag.Get("toml"), ",")[0]
+			r.RestartRequired = append(r.RestartRequired, name)
+		}
+	}
+
+	s.config = &running
+	return r, nil
+}
+
+// databaseQueryLimits returns the query limits of each database in limits.
+func databaseQueryLimits(limits []cluster.DatabaseLimit) map[string]tsdb.DatabaseLimits {
+	m := make(map[string]tsdb.DatabaseLimits)
+	for _, l := range limits {
+		m[l.Database] = tsdb.DatabaseLimits{MaxQueries: l.MaxQueries, MaxQueryMemory: l.MaxQueryMemory}
+	}
+	return m
+}
+
+// health returns the state of the meta store, the shards of the TSDB store
+// and the replay of their WALs for the /health endpoint of the HTTP service.
+func (s *Server) health() []httpd.ComponentHealth {
+	m := httpd.ComponentHealth{Name: "meta", Message: "no leader"}
+	if leader := s.MetaStore.Leader(); leader != "" {
+		m.Healthy, m.Message = true, "leader "+leader
+	}
+
+	shards := httpd.ComponentHealth{Name: "shards", Message: "opening"}
+	wal := httpd.ComponentHealth{Name: "wal", Message: "replaying"}
+	if atomic.LoadInt32(&s.storeOpened) == 1 {
+		st := s.TSDBStore.ReplayStatus()
+		n := s.TSDBStore.QuarantinedShardN()
+		shards.Healthy = st.Done && n == 0
+		shards.Message = fmt.Sprintf("%d open, %d quarantined", s.TSDBStore.ShardN(), n)
+		if st.Err != "" {
+			wal.Message = "replay failed: " + st.Err
+		} else if wal.Healthy = st.Done; wal.Healthy {
+			wal.Message = "replayed"
+		} else {
+			wal.Message = fmt.Sprintf("replaying: %d/%d shards, %d/%d segments, %d points",
+				st.ShardsOpened, st.ShardsTotal, st.SegmentsReplayed, st.SegmentsTotal, st.PointsReplayed)
+		}
+	}
+	return []httpd.ComponentHealth{m, shards, wal}
+}
+
+// ready returns true once the server is open and the WALs of its shards are
+// replayed, for the /ready endpoint of the HTTP service.
+func (s *Server) ready() bool {
+	return atomic.LoadInt32(&s.opened) == 1 && s.TSDBStore.ReplayStatus().Done
+}
+
+// replayed returns true once the WALs of the shards of the TSDB store are
+// replayed, so queries see all of their points.
+func (s *Server) replayed() bool {
+	return atomic.LoadInt32(&s.storeOpened) == 1 && s.TSDBStore.ReplayStatus().Done
+}
+
+// diagnostics returns the running configuration, without secrets, and the
+// diagnostics of the monitor for the diagnostics bundle of the HTTP service.
+func (s *Server) diagnostics() (map[string][]byte, error) {
+	s.mu.Lock()
+	c, err := s.config.Redacted()
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(*c); err != nil {
+		return nil, fmt.Errorf("encode config: %s", err)
+	}
+	files := map[string][]byte{"config.toml": buf.Bytes()}
+
+	d, err := s.Monitor.Diagnostics()
+	if err != nil {
+		return files, fmt.Errorf("monitor diagnostics: %s", err)
+	}
+	b, err := json.MarshalIndent(d, "", "    ")
+	if err != nil {
+		return files, fmt.Errorf("encode monitor diagnostics: %s", err)
+	}
+	files["diagnostics.json"] = b
+	return files, nil
+}
+
+// collectdService returns the collectd service, if enabled.
+func (s *Server) collectdService() *collectd.Service {
+	for _, srv := range s.Services {
+		if srv, ok := srv.(*collectd.Service); ok {
+			return srv
+		}
+	}
+	return nil
+}
+
+// continuousQueryService returns the continuous query service, if enabled.
+func (s *Server) continuousQueryService() *continuous_querier.Service {
+	for _, srv := range s.Services {
+		if srv, ok := srv.(*continuous_querier.Service); ok {
+			return srv
+		}
+	}
+	return nil
+}
+
 // startServerReporting starts periodic server reporting.
 func (s *Server) startServerReporting() {
 	for {
@@ -583,3 +1000,8 @@ type tcpaddr struct{ host string }
 
 func (a *tcpaddr) Network() string { return "tcp" }
 func (a *tcpaddr) String() string  { return a.host }
+
+// selectLimits returns the limits of the results of SELECT statements in c.
+func selectLimits(c cluster.Config) tsdb.SelectLimits {
+	return tsdb.SelectLimits{MaxPointN: c.MaxSelectPointN, MaxSeriesN: c.MaxSelectSeriesN, Truncate: c.TruncateSelect}
+}
@@ -4,10 +4,13 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/influxdb/influxdb/services/graphite"
 )
 
 // Ensure that HTTP responses include the InfluxDB version.
@@ -23,6 +26,73 @@ func TestServer_HTTPResponseVersion(t *testing.T) {
 	}
 }
 
+// Ensure reloading the config applies the settings that can be changed at
+// runtime and reports the others.
+func TestServer_Reload(t *testing.T) {
+	t.Parallel()
+	c := NewConfig()
+	g := graphite.Config{Enabled: true, BindAddress: "127.0.0.1:0"}
+	c.Graphites = []graphite.Config{g}
+	s := OpenServer(c, "")
+	defer s.Close()
+
+	// An invalid config is rejected.
+	other := *c
+	other.Logging.Level = "loud"
+	if _, err := s.Reload(&other); err == nil {
+		t.Fatal("expected error")
+	}
+
+	other = *c
+	other.Logging.Level = "debug"
+	other.ContinuousQuery.RecomputePreviousN = 5
+	other.Graphites = []graphite.Config{g}
+	other.Graphites[0].Templates = []string{"measurement.host"}
+	other.Data.MaxWALSize = 1
+	other.HTTPD.WriteTracing = true
+	r, err := s.Reload(&other)
+	if err != nil {
+		t.Fatal(err)
+	} else if exp := []string{"logging", "continuous_queries", "graphite[0].templates"}; !reflect.DeepEqual(r.Applied, exp) {
+		t.Fatalf("unexpected applied settings: %v", r.Applied)
+	} else if exp := []string{"data", "http"}; !reflect.DeepEqual(r.RestartRequired, exp) {
+		t.Fatalf("unexpected restart required settings: %v", r.RestartRequired)
+	}
+
+	// Reloading again only reports the settings not yet applied.
+	r, err = s.Reload(&other)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(r.Applied) != 0 {
+		t.Fatalf("unexpected applied settings: %v", r.Applied)
+	} else if exp := []string{"data", "http"}; !reflect.DeepEqual(r.RestartRequired, exp) {
+		t.Fatalf("unexpected restart required settings: %v", r.RestartRequired)
+	}
+}
+
+// Ensure an open server is ready and its components are healthy.
+func TestServer_HealthAndReady(t *testing.T) {
+	t.Parallel()
+	s := OpenServer(NewConfig(), "")
+	defer s.Close()
+
+	body, err := s.HTTPGet(s.URL() + "/health")
+	if err != nil {
+		t.Fatal(err)
+	} else if !expectPattern(`^{"healthy":true,"components":\[{"name":"meta","healthy":true,"message":"leader .*"},{"name":"shards","healthy":true,"message":"0 open, 0 quarantined"},{"name":"wal","healthy":true,"message":"replayed"}\]}$`, body) {
+		t.Fatalf("unexpected health: %s", body)
+	}
+
+	resp, err := http.Get(s.URL() + "/ready")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d", resp.StatusCode)
+	}
+}
+
 // Ensure the database commands work.
 func TestServer_DatabaseCommands(t *testing.T) {
 	t.Parallel()
@@ -357,7 +427,7 @@ func TestServer_RetentionPolicyCommands(t *testing.T) {
 			&Query{
 				name:    "show retention policy should succeed",
 				command: `SHOW RETENTION POLICIES ON db0`,
-				exp:     `{"results":[{"series":[{"columns":["name","duration","replicaN","default"],"values":[["rp0","1h0m0s",1,false]]}]}]}`,
+				exp:     `{"results":[{"series":[{"columns":["name","duration","replicaN","default","resolution","shardGroupN","diskBytes"],"values":[["rp0","1h0m0s",1,false,"0s",0,0]]}]}]}`,
 			},
 			&Query{
 				name:    "alter retention policy should succeed",
@@ -367,7 +437,7 @@ func TestServer_RetentionPolicyCommands(t *testing.T) {
 			&Query{
 				name:    "show retention policy should have new altered information",
 				command: `SHOW RETENTION POLICIES ON db0`,
-				exp:     `{"results":[{"series":[{"columns":["name","duration","replicaN","default"],"values":[["rp0","2h0m0s",3,true]]}]}]}`,
+				exp:     `{"results":[{"series":[{"columns":["name","duration","replicaN","default","resolution","shardGroupN","diskBytes"],"values":[["rp0","2h0m0s",3,true,"0s",0,0]]}]}]}`,
 			},
 			&Query{
 				name:    "dropping default retention policy should not succeed",
@@ -377,7 +447,7 @@ func TestServer_RetentionPolicyCommands(t *testing.T) {
 			&Query{
 				name:    "show retention policy should still show policy",
 				command: `SHOW RETENTION POLICIES ON db0`,
-				exp:     `{"results":[{"series":[{"columns":["name","duration","replicaN","default"],"values":[["rp0","2h0m0s",3,true]]}]}]}`,
+				exp:     `{"results":[{"series":[{"columns":["name","duration","replicaN","default","resolution","shardGroupN","diskBytes"],"values":[["rp0","2h0m0s",3,true,"0s",0,0]]}]}]}`,
 			},
 			&Query{
 				name:    "create a second non-default retention policy",
@@ -387,7 +457,7 @@ func TestServer_RetentionPolicyCommands(t *testing.T) {
 			&Query{
 				name:    "show retention policy should show both",
 				command: `SHOW RETENTION POLICIES ON db0`,
-				exp:     `{"results":[{"series":[{"columns":["name","duration","replicaN","default"],"values":[["rp0","2h0m0s",3,true],["rp2","1h0m0s",1,false]]}]}]}`,
+				exp:     `{"results":[{"series":[{"columns":["name","duration","replicaN","default","resolution","shardGroupN","diskBytes"],"values":[["rp0","2h0m0s",3,true,"0s",0,0],["rp2","1h0m0s",1,false,"0s",0,0]]}]}]}`,
 			},
 			&Query{
 				name:    "dropping non-default retention policy succeed",
@@ -397,7 +467,7 @@ func TestServer_RetentionPolicyCommands(t *testing.T) {
 			&Query{
 				name:    "show retention policy should show just default",
 				command: `SHOW RETENTION POLICIES ON db0`,
-				exp:     `{"results":[{"series":[{"columns":["name","duration","replicaN","default"],"values":[["rp0","2h0m0s",3,true]]}]}]}`,
+				exp:     `{"results":[{"series":[{"columns":["name","duration","replicaN","default","resolution","shardGroupN","diskBytes"],"values":[["rp0","2h0m0s",3,true,"0s",0,0]]}]}]}`,
 			},
 			&Query{
 				name:    "Ensure retention policy with unacceptable retention cannot be created",
@@ -441,7 +511,7 @@ func TestServer_DatabaseRetentionPolicyAutoCreate(t *testing.T) {
 			&Query{
 				name:    "show retention policies should return auto-created policy",
 				command: `SHOW RETENTION POLICIES ON db0`,
-				exp:     `{"results":[{"series":[{"columns":["name","duration","replicaN","default"],"values":[["default","0",1,true]]}]}]}`,
+				exp:     `{"results":[{"series":[{"columns":["name","duration","replicaN","default","resolution","shardGroupN","diskBytes"],"values":[["default","0",1,true,"0s",0,0]]}]}]}`,
 			},
 		},
 	}
@@ -475,7 +545,7 @@ func TestServer_UserCommands(t *testing.T) {
 			&Query{
 				name:    "show users, no actual users",
 				command: `SHOW USERS`,
-				exp:     `{"results":[{"series":[{"columns":["user","admin"]}]}]}`,
+				exp:     `{"results":[{"series":[{"columns":["user","admin","write_only"]}]}]}`,
 			},
 			&Query{
 				name:    `create user`,
@@ -485,7 +555,7 @@ func TestServer_UserCommands(t *testing.T) {
 			&Query{
 				name:    "show users, 1 existing user",
 				command: `SHOW USERS`,
-				exp:     `{"results":[{"series":[{"columns":["user","admin"],"values":[["jdoe",false]]}]}]}`,
+				exp:     `{"results":[{"series":[{"columns":["user","admin","write_only"],"values":[["jdoe",false,false]]}]}]}`,
 			},
 			&Query{
 				name:    "grant all priviledges to jdoe",
@@ -495,7 +565,7 @@ func TestServer_UserCommands(t *testing.T) {
 			&Query{
 				name:    "show users, existing user as admin",
 				command: `SHOW USERS`,
-				exp:     `{"results":[{"series":[{"columns":["user","admin"],"values":[["jdoe",true]]}]}]}`,
+				exp:     `{"results":[{"series":[{"columns":["user","admin","write_only"],"values":[["jdoe",true,false]]}]}]}`,
 			},
 			&Query{
 				name:    "grant DB privileges to user",
@@ -530,7 +600,7 @@ func TestServer_UserCommands(t *testing.T) {
 			&Query{
 				name:    "make sure user was dropped",
 				command: `SHOW USERS`,
-				exp:     `{"results":[{"series":[{"columns":["user","admin"]}]}]}`,
+				exp:     `{"results":[{"series":[{"columns":["user","admin","write_only"]}]}]}`,
 			},
 			&Query{
 				name:    "delete non existing user",
@@ -705,7 +775,7 @@ func TestServer_Query_DefaultDBAndRP(t *testing.T) {
 		&Query{
 			name:    "default rp exists",
 			command: `show retention policies ON db0`,
-			exp:     `{"results":[{"series":[{"columns":["name","duration","replicaN","default"],"values":[["default","0",1,false],["rp0","1h0m0s",1,true]]}]}]}`,
+			exp:     `{"results":[{"series":[{"columns":["name","duration","replicaN","default","resolution","shardGroupN","diskBytes"],"values":[["default","0",1,false,"0s",0,0],["rp0","1h0m0s",1,true,"0s",1,32768]]}]}]}`,
 		},
 		&Query{
 			name:    "default rp",
@@ -1964,13 +2034,13 @@ func TestServer_Query_Aggregates(t *testing.T) {
 			name:    "distinct select tag - int",
 			params:  url.Values{"db": []string{"db0"}},
 			command: `SELECT DISTINCT(host) FROM intmany`,
-			exp:     `{"results":[{"error":"statement must have at least one field in select clause"}]}`,
+			exp:     `{"results":[{"series":[{"name":"intmany","columns":["time","distinct"],"values":[["1970-01-01T00:00:00Z",["server01","server02","server03","server04","server05","server06","server07","server08"]]]}]}]}`,
 		},
 		&Query{
 			name:    "distinct alt select tag - int",
 			params:  url.Values{"db": []string{"db0"}},
 			command: `SELECT DISTINCT host FROM intmany`,
-			exp:     `{"results":[{"error":"statement must have at least one field in select clause"}]}`,
+			exp:     `{"results":[{"series":[{"name":"intmany","columns":["time","distinct"],"values":[["1970-01-01T00:00:00Z",["server01","server02","server03","server04","server05","server06","server07","server08"]]]}]}]}`,
 		},
 		&Query{
 			name:    "count distinct - int",
@@ -2093,13 +2163,13 @@ func TestServer_Query_Aggregates(t *testing.T) {
 			name:    "distinct select tag - float",
 			params:  url.Values{"db": []string{"db0"}},
 			command: `SELECT DISTINCT(host) FROM floatmany`,
-			exp:     `{"results":[{"error":"statement must have at least one field in select clause"}]}`,
+			exp:     `{"results":[{"series":[{"name":"floatmany","columns":["time","distinct"],"values":[["1970-01-01T00:00:00Z",["server01","server02","server03","server04","server05","server06","server07","server08"]]]}]}]}`,
 		},
 		&Query{
 			name:    "distinct alt select tag - float",
 			params:  url.Values{"db": []string{"db0"}},
 			command: `SELECT DISTINCT host FROM floatmany`,
-			exp:     `{"results":[{"error":"statement must have at least one field in select clause"}]}`,
+			exp:     `{"results":[{"series":[{"name":"floatmany","columns":["time","distinct"],"values":[["1970-01-01T00:00:00Z",["server01","server02","server03","server04","server05","server06","server07","server08"]]]}]}]}`,
 		},
 		&Query{
 			name:    "count distinct - float",
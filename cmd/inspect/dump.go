@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// cmdDump writes the points of the selected shards to stdout as line protocol.
+func cmdDump(args []string) error {
+	var f shardFilter
+	fs := flag.NewFlagSet("dump", flag.ExitOnError)
+	path := fs.String("p", defaultPath(), "Root storage path. [$HOME/.influxdb]")
+	fs.StringVar(&f.Database, "db", "", "Only dump shards of this database.")
+	fs.StringVar(&f.RetentionPolicy, "rp", "", "Only dump shards of this retention policy.")
+	fs.Uint64Var(&f.ShardID, "shard", 0, "Only dump this shard.")
+	measurement := fs.String("measurement", "", "Only dump this measurement.")
+	fs.Parse(args)
+
+	tstore, err := openStore(*path)
+	if err != nil {
+		return err
+	}
+	defer tstore.Close()
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	for _, si := range f.shards(tstore) {
+		if err := dumpShard(w, tstore.DatabaseIndex(si.Database), si, *measurement, math.MinInt64, math.MaxInt64); err != nil {
+			return fmt.Errorf("dump shard %d: %s", si.ID, err)
+		}
+	}
+	return nil
+}
+
+// dumpShard writes the points in a shard between tmin and tmax, inclusive,
+// as line protocol to w. All measurements are written if measurement is blank.
+func dumpShard(w io.Writer, index *tsdb.DatabaseIndex, si shardInfo, measurement string, tmin, tmax int64) error {
+	if index == nil {
+		return nil
+	}
+
+	tx, err := si.Shard.ReadOnlyTx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	measurements := index.Measurements()
+	sort.Sort(measurements)
+	for _, m := range measurements {
+		if measurement != "" && m.Name != measurement {
+			continue
+		}
+
+		codec := si.Shard.FieldCodec(m.Name)
+		if codec == nil {
+			continue
+		}
+
+		keys := m.SeriesKeys()
+		sort.Strings(keys)
+		for _, key := range keys {
+			series := index.Series(key)
+			if series == nil {
+				continue
+			}
+
+			c := tx.Cursor(key, tsdb.Forward)
+			if c == nil {
+				continue
+			}
+			// Keys are ordered as unsigned so only skip ahead for positive times.
+			seek := []byte{}
+			if tmin > 0 {
+				seek = u64tob(uint64(tmin))
+			}
+			for k, v := c.Seek(seek); k != nil; k, v = c.Next() {
+				timestamp := int64(btou64(k))
+				if timestamp < tmin || timestamp > tmax {
+					continue
+				}
+
+				fields, err := codec.DecodeFieldsWithNames(v)
+				if err != nil {
+					return fmt.Errorf("decode: series=%s, err=%s", key, err)
+				}
+
+				p := tsdb.NewPoint(m.Name, tsdb.Tags(series.Tags), tsdb.Fields(fields), time.Unix(0, timestamp))
+				if _, err := fmt.Fprintln(w, p.String()); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
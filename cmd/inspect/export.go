@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/influxdb/influxdb/influxql"
+)
+
+// cmdExport writes the points of a database in a time range to a file in the
+// format read by "influx -import". The file has a DDL section that creates
+// the database and a DML section of line protocol, with context lines
+// marking the database and retention policy of the points that follow.
+func cmdExport(args []string) error {
+	var f shardFilter
+	var start, end string
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	path := fs.String("p", defaultPath(), "Root storage path. [$HOME/.influxdb]")
+	fs.StringVar(&f.Database, "db", "", "Only export this database.")
+	fs.StringVar(&f.RetentionPolicy, "rp", "", "Only export this retention policy. Requires -db.")
+	fs.StringVar(&start, "start", "", "Only export points at or after this RFC3339 time.")
+	fs.StringVar(&end, "end", "", "Only export points at or before this RFC3339 time.")
+	out := fs.String("out", "", "File to write the export to. Required.")
+	compress := fs.Bool("compress", true, "Compress the export with gzip.")
+	fs.Parse(args)
+
+	if *out == "" {
+		return errors.New("output file required")
+	} else if f.RetentionPolicy != "" && f.Database == "" {
+		return errors.New("database required with retention policy")
+	}
+
+	tmin, tmax := int64(math.MinInt64), int64(math.MaxInt64)
+	if start != "" {
+		t, err := time.Parse(time.RFC3339, start)
+		if err != nil {
+			return fmt.Errorf("invalid start time: %s", err)
+		}
+		tmin = t.UnixNano()
+	}
+	if end != "" {
+		t, err := time.Parse(time.RFC3339, end)
+		if err != nil {
+			return fmt.Errorf("invalid end time: %s", err)
+		}
+		tmax = t.UnixNano()
+	}
+
+	tstore, err := openStore(*path)
+	if err != nil {
+		return err
+	}
+	defer tstore.Close()
+
+	// Group shards by database and retention policy.
+	shards := f.shards(tstore)
+	sort.Sort(shardsByLocation(shards))
+
+	file, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var w io.Writer = file
+	if *compress {
+		gw := gzip.NewWriter(file)
+		defer gw.Close()
+		w = gw
+	}
+	bw := bufio.NewWriter(w)
+
+	// Write the DDL. Retention policies are part of the meta store and
+	// must be created on the target before importing.
+	fmt.Fprintln(bw, "# DDL")
+	for i, si := range shards {
+		if i == 0 || shards[i-1].Database != si.Database {
+			fmt.Fprintf(bw, "CREATE DATABASE IF NOT EXISTS %s\n", influxql.QuoteIdent(si.Database))
+		}
+	}
+
+	// Write the DML.
+	fmt.Fprintln(bw, "# DML")
+	for i, si := range shards {
+		if i == 0 || shards[i-1].Database != si.Database {
+			fmt.Fprintf(bw, "# CONTEXT-DATABASE:%s\n", si.Database)
+		}
+		if i == 0 || shards[i-1].Database != si.Database || shards[i-1].RetentionPolicy != si.RetentionPolicy {
+			fmt.Fprintf(bw, "# CONTEXT-RETENTION-POLICY:%s\n", si.RetentionPolicy)
+		}
+		if err := dumpShard(bw, tstore.DatabaseIndex(si.Database), si, "", tmin, tmax); err != nil {
+			return fmt.Errorf("export shard %d: %s", si.ID, err)
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	if gw, ok := w.(*gzip.Writer); ok {
+		if err := gw.Close(); err != nil {
+			return err
+		}
+	}
+	return file.Sync()
+}
+
+// shardsByLocation sorts shards by database, retention policy and ID.
+type shardsByLocation []shardInfo
+
+func (a shardsByLocation) Len() int      { return len(a) }
+func (a shardsByLocation) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a shardsByLocation) Less(i, j int) bool {
+	if a[i].Database != a[j].Database {
+		return a[i].Database < a[j].Database
+	} else if a[i].RetentionPolicy != a[j].RetentionPolicy {
+		return a[i].RetentionPolicy < a[j].RetentionPolicy
+	}
+	return a[i].ID < a[j].ID
+}
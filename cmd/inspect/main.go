@@ -1,142 +1,140 @@
 package main
 
 import (
-	"encoding/binary"
-	"flag"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
-	"text/tabwriter"
 
+	"github.com/influxdb/influxdb/logger"
 	"github.com/influxdb/influxdb/tsdb"
 	_ "github.com/influxdb/influxdb/tsdb/engine"
 )
 
+const usage = `usage: inspect [command] [flags]
+
+Commands:
+
+    info    summarize the databases, shards and measurements in the store (default)
+    dump    write the points in the store as line protocol to stdout
+    export  write the points of a database and time range to a file for "influx -import"
+    verify  check the integrity of the data blocks in each shard
+    report  report series, field and tag cardinality and disk usage per measurement
+
+Use "inspect [command] -h" for the flags of a command.
+`
+
 func main() {
+	// The command defaults to "info" when only flags are given.
+	name, args := "info", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
 
-	var path string
-	flag.StringVar(&path, "p", os.Getenv("HOME")+"/.influxdb", "Root storage path. [$HOME/.influxdb]")
-	flag.Parse()
+	var err error
+	switch name {
+	case "info":
+		err = cmdInfo(args)
+	case "dump":
+		err = cmdDump(args)
+	case "export":
+		err = cmdExport(args)
+	case "verify":
+		err = cmdVerify(args)
+	case "report":
+		err = cmdReport(args)
+	case "help":
+		fmt.Fprint(os.Stderr, usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n%s", name, usage)
+		os.Exit(1)
+	}
 
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// defaultPath returns the default root storage path.
+func defaultPath() string { return os.Getenv("HOME") + "/.influxdb" }
+
+// openStore opens the tsdb store under the root storage path.
+func openStore(path string) (*tsdb.Store, error) {
 	tstore := tsdb.NewStore(filepath.Join(path, "data"))
-	tstore.Logger = log.New(ioutil.Discard, "", log.LstdFlags)
+	tstore.Logger = logger.New(ioutil.Discard, "store")
 	tstore.EngineOptions.Config.Dir = filepath.Join(path, "data")
 	tstore.EngineOptions.Config.WALLoggingEnabled = false
 	tstore.EngineOptions.Config.WALDir = filepath.Join(path, "wal")
 	if err := tstore.Open(); err != nil {
-		fmt.Printf("Failed to open dir: %v\n", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("Failed to open dir: %v", err)
 	}
+	return tstore, nil
+}
 
-	size, err := tstore.DiskSize()
-	if err != nil {
-		fmt.Printf("Failed to determine disk usage: %v\n", err)
-	}
+// shardInfo identifies a shard in the store.
+type shardInfo struct {
+	ID              uint64
+	Database        string
+	RetentionPolicy string
+	Shard           *tsdb.Shard
+}
 
-	// Summary stats
-	fmt.Printf("Shards: %d, Indexes: %d, Databases: %d, Disk Size: %d, Series: %d\n",
-		tstore.ShardN(), tstore.DatabaseIndexN(), len(tstore.Databases()), size, countSeries(tstore))
-	fmt.Println()
-
-	tw := tabwriter.NewWriter(os.Stdout, 16, 8, 0, '\t', 0)
-
-	fmt.Fprintln(tw, strings.Join([]string{"Shard", "DB", "Measurement", "Tags [#K/#V]", "Fields [Name:Type]", "Series"}, "\t"))
-
-	shardIDs := tstore.ShardIDs()
-
-	databases := tstore.Databases()
-	sort.Strings(databases)
-
-	for _, db := range databases {
-		index := tstore.DatabaseIndex(db)
-		measurements := index.Measurements()
-		sort.Sort(measurements)
-		for _, m := range measurements {
-			tags := m.TagKeys()
-			tagValues := 0
-			for _, tag := range tags {
-				tagValues += len(m.TagValues(tag))
-			}
-			fields := m.FieldNames()
-			sort.Strings(fields)
-			series := m.SeriesKeys()
-			sort.Strings(series)
-			sort.Sort(ShardIDs(shardIDs))
-
-			// Sample a point from each measurement to determine the field types
-			for _, shardID := range shardIDs {
-				shard := tstore.Shard(shardID)
-				tx, err := shard.ReadOnlyTx()
-				if err != nil {
-					fmt.Printf("Failed to get transaction: %v", err)
-				}
-
-				for _, key := range series {
-					fieldSummary := []string{}
-
-					cursor := tx.Cursor(key, tsdb.Forward)
-
-					// Series doesn't exist in this shard
-					if cursor == nil {
-						continue
-					}
-
-					// Seek to the beginning
-					_, value := cursor.Seek([]byte{})
-					codec := shard.FieldCodec(m.Name)
-					if codec != nil {
-						fields, err := codec.DecodeFieldsWithNames(value)
-						if err != nil {
-							fmt.Printf("Failed to decode values: %v", err)
-						}
-
-						for field, value := range fields {
-							fieldSummary = append(fieldSummary, fmt.Sprintf("%s:%T", field, value))
-						}
-						sort.Strings(fieldSummary)
-					}
-					fmt.Fprintf(tw, "%d\t%s\t%s\t%d/%d\t%d [%s]\t%d\n", shardID, db, m.Name, len(tags), tagValues,
-						len(fields), strings.Join(fieldSummary, ","), len(series))
-					break
-				}
-				tx.Rollback()
-			}
-		}
-	}
-	tw.Flush()
+// shardFilter restricts the shards a command operates on.
+type shardFilter struct {
+	Database        string
+	RetentionPolicy string
+	ShardID         uint64
 }
 
-func countSeries(tstore *tsdb.Store) int {
-	var count int
-	for _, shardID := range tstore.ShardIDs() {
-		shard := tstore.Shard(shardID)
-		cnt, err := shard.SeriesCount()
+// shards returns the shards in the store matching the filter, sorted by ID.
+// Shards are stored under <data>/<database>/<retention policy>/<id>.
+func (f *shardFilter) shards(tstore *tsdb.Store) []shardInfo {
+	ids := tstore.ShardIDs()
+	sort.Sort(ShardIDs(ids))
+
+	var a []shardInfo
+	for _, id := range ids {
+		sh := tstore.Shard(id)
+		if sh == nil {
+			continue
+		}
+
+		rel, err := filepath.Rel(tstore.Path(), sh.Path())
 		if err != nil {
-			fmt.Printf("series count failed: %v\n", err)
 			continue
 		}
-		count += cnt
-	}
-	return count
-}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		if len(parts) != 3 || parts[2] != strconv.FormatUint(id, 10) {
+			continue
+		}
 
-func btou64(b []byte) uint64 {
-	return binary.BigEndian.Uint64(b)
+		if f.Database != "" && parts[0] != f.Database {
+			continue
+		} else if f.RetentionPolicy != "" && parts[1] != f.RetentionPolicy {
+			continue
+		} else if f.ShardID != 0 && id != f.ShardID {
+			continue
+		}
+		a = append(a, shardInfo{ID: id, Database: parts[0], RetentionPolicy: parts[1], Shard: sh})
+	}
+	return a
 }
 
-// u64tob converts a uint64 into an 8-byte slice.
-func u64tob(v uint64) []byte {
-	b := make([]byte, 8)
-	binary.BigEndian.PutUint64(b, v)
-	return b
+// measurementsByKey returns a lookup of series keys to their measurement name
+// for a database.
+func measurementsByKey(index *tsdb.DatabaseIndex) map[string]string {
+	m := make(map[string]string)
+	if index == nil {
+		return m
+	}
+	for _, mm := range index.Measurements() {
+		for _, key := range mm.SeriesKeys() {
+			m[key] = mm.Name
+		}
+	}
+	return m
 }
-
-type ShardIDs []uint64
-
-func (a ShardIDs) Len() int           { return len(a) }
-func (a ShardIDs) Less(i, j int) bool { return a[i] < a[j] }
-func (a ShardIDs) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
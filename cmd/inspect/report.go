@@ -0,0 +1,119 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// measurementReport holds the usage of a single measurement.
+type measurementReport struct {
+	Database    string
+	Measurement string
+	Series      int
+	TagKeys     int
+	TagValues   int
+	Fields      int
+	Blocks      int
+	Points      int
+	Size        int64 // stored size of blocks, in bytes
+	RawSize     int64 // uncompressed size of blocks, in bytes
+}
+
+// cmdReport prints the series, tag and field cardinality of each measurement
+// along with the number of points and disk space used by its blocks in the
+// selected shards. Only data flushed from the WAL is counted.
+func cmdReport(args []string) error {
+	var f shardFilter
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	path := fs.String("p", defaultPath(), "Root storage path. [$HOME/.influxdb]")
+	fs.StringVar(&f.Database, "db", "", "Only report on this database.")
+	fs.StringVar(&f.RetentionPolicy, "rp", "", "Only count shards of this retention policy.")
+	fs.Uint64Var(&f.ShardID, "shard", 0, "Only count this shard.")
+	fs.Parse(args)
+
+	tstore, err := openStore(*path)
+	if err != nil {
+		return err
+	}
+	defer tstore.Close()
+
+	// Build the cardinality of each measurement from the index.
+	reports := make(map[string]*measurementReport)
+	lookups := make(map[string]map[string]string)
+	databases := tstore.Databases()
+	sort.Strings(databases)
+	for _, db := range databases {
+		if f.Database != "" && db != f.Database {
+			continue
+		}
+
+		index := tstore.DatabaseIndex(db)
+		if index == nil {
+			continue
+		}
+		lookups[db] = measurementsByKey(index)
+
+		for _, m := range index.Measurements() {
+			r := &measurementReport{
+				Database:    db,
+				Measurement: m.Name,
+				Series:      m.SeriesN(),
+				Fields:      len(m.FieldNames()),
+			}
+			for _, k := range m.TagKeys() {
+				r.TagKeys++
+				r.TagValues += len(m.TagValues(k))
+			}
+			reports[db+"\x00"+m.Name] = r
+		}
+	}
+
+	// Add the block usage of each shard to its measurements.
+	for _, si := range f.shards(tstore) {
+		lookup := lookups[si.Database]
+		err := si.Shard.InspectBlocks(func(b tsdb.BlockInfo) error {
+			r := reports[si.Database+"\x00"+lookup[b.Key]]
+			if r == nil {
+				return nil
+			}
+			r.Blocks++
+			r.Points += b.Points
+			r.Size += int64(b.Size)
+			r.RawSize += int64(b.RawSize)
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "shard %d: %s\n", si.ID, err)
+		}
+	}
+
+	keys := make([]string, 0, len(reports))
+	for k := range reports {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	tw := tabwriter.NewWriter(os.Stdout, 8, 8, 1, '\t', 0)
+	fmt.Fprintln(tw, strings.Join([]string{"DB", "Measurement", "Series", "Tags [#K/#V]", "Fields", "Blocks", "Points", "Disk Size", "Raw Size"}, "\t"))
+
+	var total measurementReport
+	for _, k := range keys {
+		r := reports[k]
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d/%d\t%d\t%d\t%d\t%d\t%d\n",
+			r.Database, r.Measurement, r.Series, r.TagKeys, r.TagValues, r.Fields, r.Blocks, r.Points, r.Size, r.RawSize)
+
+		total.Series += r.Series
+		total.Blocks += r.Blocks
+		total.Points += r.Points
+		total.Size += r.Size
+		total.RawSize += r.RawSize
+	}
+	fmt.Fprintf(tw, "Total\t\t%d\t\t\t%d\t%d\t%d\t%d\n", total.Series, total.Blocks, total.Points, total.Size, total.RawSize)
+	return tw.Flush()
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// cmdVerify checks the data blocks of the selected shards. Blocks are
+// decoded and their entries checked against the block header, and the
+// underlying data file's page structure is checked. Returns an error if any
+// shard fails verification.
+func cmdVerify(args []string) error {
+	var f shardFilter
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	path := fs.String("p", defaultPath(), "Root storage path. [$HOME/.influxdb]")
+	fs.StringVar(&f.Database, "db", "", "Only verify shards of this database.")
+	fs.StringVar(&f.RetentionPolicy, "rp", "", "Only verify shards of this retention policy.")
+	fs.Uint64Var(&f.ShardID, "shard", 0, "Only verify this shard.")
+	fs.Parse(args)
+
+	tstore, err := openStore(*path)
+	if err != nil {
+		return err
+	}
+	defer tstore.Close()
+
+	var failed int
+	for _, si := range f.shards(tstore) {
+		var blocks, corrupt int
+		err := si.Shard.InspectBlocks(func(b tsdb.BlockInfo) error {
+			blocks++
+			if b.Err != nil {
+				corrupt++
+				fmt.Printf("shard %d: series %q, block %d-%d: %s\n", si.ID, b.Key, b.MinTime, b.MaxTime, b.Err)
+			}
+			return nil
+		})
+		if err == tsdb.ErrInspectNotSupported {
+			fmt.Printf("shard %d: skipped: %s\n", si.ID, err)
+			continue
+		} else if err != nil {
+			failed++
+			fmt.Printf("shard %d: %s\n", si.ID, err)
+			continue
+		}
+
+		if corrupt > 0 {
+			failed++
+		}
+		fmt.Printf("shard %d (%s/%s): %d blocks, %d corrupt\n", si.ID, si.Database, si.RetentionPolicy, blocks, corrupt)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("verification failed for %d shard(s)", failed)
+	}
+	return nil
+}
@@ -14,6 +14,14 @@ var (
 
 	// ErrFieldTypeConflict is returned when a new field already exists with a different type.
 	ErrFieldTypeConflict = errors.New("field type conflict")
+
+	// ErrSchemaViolation is returned when a point doesn't conform to the
+	// schema of its measurement.
+	ErrSchemaViolation = errors.New("schema violation")
+
+	// ErrTimeOutOfRange is returned when points are written with a time
+	// outside the range accepted by the server.
+	ErrTimeOutOfRange = errors.New("point time outside the allowed range")
 )
 
 func ErrDatabaseNotFound(name string) error { return fmt.Errorf("database not found: %s", name) }
@@ -47,6 +55,14 @@ func IsClientError(err error) bool {
 		return true
 	}
 
+	if strings.Contains(err.Error(), ErrSchemaViolation.Error()) {
+		return true
+	}
+
+	if strings.Contains(err.Error(), ErrTimeOutOfRange.Error()) {
+		return true
+	}
+
 	return false
 }
 
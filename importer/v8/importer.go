@@ -14,7 +14,20 @@ import (
 	"github.com/influxdb/influxdb/client"
 )
 
-const batchSize = 5000
+const (
+	// DefaultBatchSize is the default number of points written per request.
+	DefaultBatchSize = 5000
+
+	// DefaultRetries is the default number of times a failed batch is retried.
+	DefaultRetries = 3
+
+	// DefaultRetryInterval is the default time to wait before the first retry.
+	// The wait doubles with every retry of the same batch.
+	DefaultRetryInterval = 500 * time.Millisecond
+
+	// progressInterval is the number of lines between progress reports.
+	progressInterval = 100000
+)
 
 // Config is the config used to initialize a Importer importer
 type Config struct {
@@ -27,36 +40,73 @@ type Config struct {
 	Version          string
 	Compressed       bool
 	PPS              int
+
+	// Database and RetentionPolicy are used for points that aren't preceded
+	// by a context line, e.g. when importing a plain line protocol file.
+	Database        string
+	RetentionPolicy string
+
+	BatchSize     int
+	Retries       int
+	RetryInterval time.Duration
+
+	// Failed lines are written to Stdout so they can be captured and
+	// imported again. Defaults to os.Stdout.
+	Stdout io.Writer
 }
 
 // NewConfig returns an initialized *Config
 func NewConfig() *Config {
-	return &Config{}
+	return &Config{
+		BatchSize:     DefaultBatchSize,
+		Retries:       DefaultRetries,
+		RetryInterval: DefaultRetryInterval,
+	}
 }
 
-// Importer is the importer used for importing 0.8 data
+// Summary represents the progress of an import.
+type Summary struct {
+	Commands int // DDL statements executed
+	Inserts  int // points written
+	Failed   int // points that could not be written
+	Retries  int // write requests that were retried
+	Elapsed  time.Duration
+}
+
+// Importer is the importer used for importing 0.8 exports and line protocol files
 type Importer struct {
-	client                *client.Client
-	database              string
-	retentionPolicy       string
-	config                *Config
-	batch                 []string
-	totalInserts          int
-	failedInserts         int
-	totalCommands         int
-	throttlePointsWritten int
-	lastWrite             time.Time
-	throttle              *time.Ticker
+	client          *client.Client
+	database        string
+	retentionPolicy string
+	config          *Config
+	batch           []string
+	summary         Summary
+	start           time.Time
+	throttleStart   time.Time
+	throttlePoints  int
+	sleep           func(time.Duration)
 }
 
 // NewImporter will return an intialized Importer struct
 func NewImporter(config *Config) *Importer {
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultBatchSize
+	}
+	if config.Stdout == nil {
+		config.Stdout = os.Stdout
+	}
 	return &Importer{
-		config: config,
-		batch:  make([]string, 0, batchSize),
+		config:          config,
+		database:        config.Database,
+		retentionPolicy: config.RetentionPolicy,
+		batch:           make([]string, 0, config.BatchSize),
+		sleep:           time.Sleep,
 	}
 }
 
+// Summary returns the progress of the import.
+func (i *Importer) Summary() Summary { return i.summary }
+
 // Import processes the specified file in the Config and writes the data to the databases in chunks specified by batchSize
 func (i *Importer) Import() error {
 	// Create a client and try to connect
@@ -79,12 +129,14 @@ func (i *Importer) Import() error {
 		return fmt.Errorf("file argument required")
 	}
 
+	i.start = time.Now()
 	defer func() {
-		if i.totalInserts > 0 {
-			log.Printf("Processed %d commands\n", i.totalCommands)
-			log.Printf("Processed %d inserts\n", i.totalInserts)
-			log.Printf("Failed %d inserts\n", i.failedInserts)
-		}
+		i.summary.Elapsed = time.Since(i.start)
+		log.Printf("Processed %d commands\n", i.summary.Commands)
+		log.Printf("Processed %d inserts\n", i.summary.Inserts)
+		log.Printf("Failed %d inserts\n", i.summary.Failed)
+		log.Printf("Retried %d writes\n", i.summary.Retries)
+		log.Printf("Time elapsed: %s\n", i.summary.Elapsed)
 	}()
 
 	// Open the file
@@ -97,7 +149,7 @@ func (i *Importer) Import() error {
 	var r io.Reader
 
 	// If gzipped, wrap in a gzip reader
-	if i.config.Compressed {
+	if i.config.Compressed || strings.HasSuffix(i.config.Path, ".gz") {
 		gr, err := gzip.NewReader(f)
 		if err != nil {
 			return err
@@ -113,20 +165,28 @@ func (i *Importer) Import() error {
 	// Get our reader
 	scanner := bufio.NewScanner(r)
 
-	// Process the DDL
-	i.processDDL(scanner)
-
-	// Set up our throttle channel.  Since there is effectively no other activity at this point
-	// the smaller resolution gets us much closer to the requested PPS
-	i.throttle = time.NewTicker(time.Microsecond)
-	defer i.throttle.Stop()
+	// Prime the throttle
+	i.throttleStart = time.Now()
 
-	// Prime the last write
-	i.lastWrite = time.Now()
+	// Files exported from 0.8 start with a DDL section. Anything else is
+	// treated as line protocol.
+	if scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "# DDL") {
+			i.processDDL(scanner)
+		} else {
+			i.processDMLLine(line)
+		}
+	}
 
 	// Process the DML
 	i.processDML(scanner)
 
+	// Write any remaining points
+	if len(i.batch) > 0 {
+		i.flush()
+	}
+
 	// Check if we had any errors scanning the file
 	if err := scanner.Err(); err != nil {
 		return fmt.Errorf("reading standard input: %s", err)
@@ -150,22 +210,36 @@ func (i *Importer) processDDL(scanner *bufio.Scanner) {
 }
 
 func (i *Importer) processDML(scanner *bufio.Scanner) {
-	start := time.Now()
 	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, "# CONTEXT-DATABASE:") {
-			i.database = strings.TrimSpace(strings.Split(line, ":")[1])
-		}
-		if strings.HasPrefix(line, "# CONTEXT-RETENTION-POLICY:") {
-			i.retentionPolicy = strings.TrimSpace(strings.Split(line, ":")[1])
-		}
-		if strings.HasPrefix(line, "#") {
-			continue
-		}
-		i.batchAccumulator(line, start)
+		i.processDMLLine(scanner.Text())
 	}
 }
 
+func (i *Importer) processDMLLine(line string) {
+	if strings.HasPrefix(line, "# CONTEXT-DATABASE:") {
+		i.switchContext(strings.TrimSpace(strings.Split(line, ":")[1]), i.retentionPolicy)
+	}
+	if strings.HasPrefix(line, "# CONTEXT-RETENTION-POLICY:") {
+		i.switchContext(i.database, strings.TrimSpace(strings.Split(line, ":")[1]))
+	}
+	if strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
+		return
+	}
+	i.batchAccumulator(line)
+}
+
+// switchContext sets the database and retention policy for the following
+// points. Points batched for the previous context are written first.
+func (i *Importer) switchContext(database, retentionPolicy string) {
+	if database == i.database && retentionPolicy == i.retentionPolicy {
+		return
+	}
+	if len(i.batch) > 0 {
+		i.flush()
+	}
+	i.database, i.retentionPolicy = database, retentionPolicy
+}
+
 func (i *Importer) execute(command string) {
 	response, err := i.client.Query(client.Query{Command: command, Database: i.database})
 	if err != nil {
@@ -178,59 +252,91 @@ func (i *Importer) execute(command string) {
 }
 
 func (i *Importer) queryExecutor(command string) {
-	i.totalCommands++
+	i.summary.Commands++
 	i.execute(command)
 }
 
-func (i *Importer) batchAccumulator(line string, start time.Time) {
+func (i *Importer) batchAccumulator(line string) {
 	i.batch = append(i.batch, line)
-	if len(i.batch) == batchSize {
-		if e := i.batchWrite(); e != nil {
-			log.Println("error writing batch: ", e)
-			// Output failed lines to STDOUT so users can capture lines that failed to import
-			fmt.Println(strings.Join(i.batch, "\n"))
-			i.failedInserts += len(i.batch)
-		} else {
-			i.totalInserts += len(i.batch)
-		}
-		i.batch = i.batch[:0]
-		// Give some status feedback every 100000 lines processed
-		processed := i.totalInserts + i.failedInserts
-		if processed%100000 == 0 {
-			since := time.Since(start)
-			pps := float64(processed) / since.Seconds()
-			log.Printf("Processed %d lines.  Time elapsed: %s.  Points per second (PPS): %d", processed, since.String(), int64(pps))
-		}
+	if len(i.batch) == i.config.BatchSize {
+		i.flush()
+	}
+}
+
+// flush writes the current batch and reports progress.
+func (i *Importer) flush() {
+	before := i.summary.Inserts + i.summary.Failed
+	i.writeBatch(i.batch, 0)
+	i.batch = i.batch[:0]
+
+	// Give some status feedback every 100000 lines processed
+	processed := i.summary.Inserts + i.summary.Failed
+	if processed/progressInterval > before/progressInterval {
+		since := time.Since(i.start)
+		pps := float64(processed) / since.Seconds()
+		log.Printf("Processed %d lines.  Failed %d lines.  Time elapsed: %s.  Points per second (PPS): %d", processed, i.summary.Failed, since.String(), int64(pps))
 	}
 }
 
-func (i *Importer) batchWrite() error {
-	// Accumulate the batch size to see how many points we have written this second
-	i.throttlePointsWritten += len(i.batch)
+// writeBatch writes lines to the server. Failed writes, including partial
+// writes, are retried with a backoff. If the server rejects points in the
+// batch then it is split in half and each half is written separately so
+// that only the lines at fault fail.
+func (i *Importer) writeBatch(lines []string, attempt int) {
+	err := i.write(lines)
+	if err == nil {
+		i.summary.Inserts += len(lines)
+		return
+	}
+
+	if isRejected(err) {
+		if len(lines) > 1 {
+			mid := len(lines) / 2
+			i.writeBatch(lines[:mid], 0)
+			i.writeBatch(lines[mid:], 0)
+			return
+		}
+	} else if attempt < i.config.Retries {
+		i.summary.Retries++
+		i.sleep(i.config.RetryInterval << uint(attempt))
+		i.writeBatch(lines, attempt+1)
+		return
+	}
 
-	// Find out when we last wrote data
-	since := time.Since(i.lastWrite)
+	log.Println("error writing batch: ", err)
+	// Output failed lines to STDOUT so users can capture lines that failed to import
+	fmt.Fprintln(i.config.Stdout, strings.Join(lines, "\n"))
+	i.summary.Failed += len(lines)
+}
 
-	// Check to see if we've exceeded our points per second for the current timeframe
-	var currentPPS int
-	if since.Seconds() > 0 {
-		currentPPS = int(float64(i.throttlePointsWritten) / since.Seconds())
-	} else {
-		currentPPS = i.throttlePointsWritten
+// isRejected returns true if the server refused to write points in the batch
+// because of their content. Retrying the same points won't succeed.
+func isRejected(err error) bool {
+	if _, ok := err.(*url.Error); ok {
+		return false
 	}
+	msg := err.Error()
+	return strings.Contains(msg, "unable to parse") || strings.Contains(msg, "field type conflict")
+}
 
-	// If our currentPPS is greater than the PPS specified, then we wait and retry
-	if int(currentPPS) > i.config.PPS && i.config.PPS != 0 {
-		// Wait for the next tick
-		<-i.throttle.C
+// write throttles to the configured points per second and sends lines to
+// the server.
+func (i *Importer) write(lines []string) error {
+	if i.config.PPS > 0 {
+		// Wait until writing these points keeps the rate under the limit.
+		i.throttlePoints += len(lines)
+		due := time.Duration(float64(i.throttlePoints) / float64(i.config.PPS) * float64(time.Second))
+		if d := due - time.Since(i.throttleStart); d > 0 {
+			i.sleep(d)
+		}
 
-		// Decrement the batch size back out as it is going to get called again
-		i.throttlePointsWritten -= len(i.batch)
-		return i.batchWrite()
+		// Restart the measurement every second so a slow server doesn't
+		// build up credit for a burst.
+		if time.Since(i.throttleStart) > time.Second {
+			i.throttleStart, i.throttlePoints = time.Now(), 0
+		}
 	}
 
-	_, e := i.client.WriteLineProtocol(strings.Join(i.batch, "\n"), i.database, i.retentionPolicy, i.config.Precision, i.config.WriteConsistency)
-	i.throttlePointsWritten = 0
-	i.lastWrite = time.Now()
-	return e
+	_, err := i.client.WriteLineProtocol(strings.Join(lines, "\n"), i.database, i.retentionPolicy, i.config.Precision, i.config.WriteConsistency)
+	return err
 }
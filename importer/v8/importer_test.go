@@ -0,0 +1,187 @@
+package v8_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/importer/v8"
+)
+
+func init() {
+	log.SetOutput(ioutil.Discard)
+}
+
+// Ensure a plain line protocol file is written in batches and that only the
+// lines rejected by the server fail.
+func TestImporter_Import_LineProtocol(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	path := MustWriteFile(t, "a.txt", "cpu value=1\ncpu value=2\nbad\ncpu value=3\n\ncpu value=4\n")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	var stdout bytes.Buffer
+	c := s.Config(path)
+	c.BatchSize = 2
+	c.Stdout = &stdout
+
+	i := v8.NewImporter(c)
+	if err := i.Import(); err != nil {
+		t.Fatal(err)
+	}
+
+	if sum := i.Summary(); sum.Inserts != 4 || sum.Failed != 1 || sum.Retries != 0 {
+		t.Fatalf("unexpected summary: %+v", sum)
+	} else if exp := []string{"cpu value=1", "cpu value=2", "cpu value=3", "cpu value=4"}; !reflect.DeepEqual(s.Lines("db0"), exp) {
+		t.Fatalf("unexpected lines: %q", s.Lines("db0"))
+	} else if stdout.String() != "bad\n" {
+		t.Fatalf("unexpected failed lines: %q", stdout.String())
+	}
+}
+
+// Ensure failed writes are retried.
+func TestImporter_Import_Retry(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.Failures = 2
+
+	path := MustWriteGzipFile(t, "a.txt.gz", "cpu value=1\ncpu value=2\n")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	i := v8.NewImporter(s.Config(path))
+	if err := i.Import(); err != nil {
+		t.Fatal(err)
+	}
+
+	if sum := i.Summary(); sum.Inserts != 2 || sum.Failed != 0 || sum.Retries != 2 {
+		t.Fatalf("unexpected summary: %+v", sum)
+	} else if exp := []string{"cpu value=1", "cpu value=2"}; !reflect.DeepEqual(s.Lines("db0"), exp) {
+		t.Fatalf("unexpected lines: %q", s.Lines("db0"))
+	}
+}
+
+// Ensure the points in a 0.8 export are written to their context database.
+func TestImporter_Import_Export(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	path := MustWriteFile(t, "a.txt", "# DDL\nCREATE DATABASE db1\n# DML\n# CONTEXT-DATABASE: db1\ncpu value=1\n# CONTEXT-DATABASE: db2\ncpu value=2\n")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	i := v8.NewImporter(s.Config(path))
+	if err := i.Import(); err != nil {
+		t.Fatal(err)
+	}
+
+	if sum := i.Summary(); sum.Commands != 1 || sum.Inserts != 2 {
+		t.Fatalf("unexpected summary: %+v", sum)
+	} else if exp := []string{"cpu value=1"}; !reflect.DeepEqual(s.Lines("db1"), exp) {
+		t.Fatalf("unexpected lines: %q", s.Lines("db1"))
+	} else if exp := []string{"cpu value=2"}; !reflect.DeepEqual(s.Lines("db2"), exp) {
+		t.Fatalf("unexpected lines: %q", s.Lines("db2"))
+	}
+}
+
+// Server is a mock HTTP server that records written lines by database.
+// Batches containing a line starting with "bad" are rejected.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	lines    map[string][]string
+	Failures int // number of writes to fail before succeeding
+}
+
+// NewServer returns a new, running instance of Server.
+func NewServer() *Server {
+	s := &Server{lines: make(map[string][]string)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/ping":
+		w.WriteHeader(http.StatusNoContent)
+	case "/query":
+		w.Write([]byte(`{"results":[{}]}`))
+	case "/write":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.Failures > 0 {
+			s.Failures--
+			http.Error(w, `{"error":"timeout"}`, http.StatusInternalServerError)
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		lines := strings.Split(string(body), "\n")
+		for _, l := range lines {
+			if strings.HasPrefix(l, "bad") {
+				http.Error(w, `{"error":"unable to parse '`+l+`': missing fields"}`, http.StatusBadRequest)
+				return
+			}
+		}
+		db := r.URL.Query().Get("db")
+		s.lines[db] = append(s.lines[db], lines...)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Lines returns the lines written to a database.
+func (s *Server) Lines(db string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lines[db]
+}
+
+// Config returns an importer config for importing path into the server.
+func (s *Server) Config(path string) *v8.Config {
+	u, _ := url.Parse(s.URL)
+	c := v8.NewConfig()
+	c.URL = *u
+	c.Path = path
+	c.Database = "db0"
+	c.Precision = "ns"
+	c.WriteConsistency = "any"
+	c.RetryInterval = time.Millisecond
+	c.Stdout = ioutil.Discard
+	return c
+}
+
+// MustWriteFile writes data to a file in a temporary directory.
+func MustWriteFile(t *testing.T, name, data string) string {
+	dir, err := ioutil.TempDir("", "importer-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(data), 0666); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// MustWriteGzipFile writes gzipped data to a file in a temporary directory.
+func MustWriteGzipFile(t *testing.T, name, data string) string {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	gw.Write([]byte(data))
+	gw.Close()
+	return MustWriteFile(t, name, buf.String())
+}
@@ -31,6 +31,8 @@ const (
 	Time = 5
 	// Duration means the data type is a duration of time.
 	Duration = 6
+	// Unsigned means the data type is an unsigned integer.
+	Unsigned = 7
 )
 
 // InspectDataType returns the data type of a given value.
@@ -40,6 +42,8 @@ func InspectDataType(v interface{}) DataType {
 		return Float
 	case int64, int32, int:
 		return Integer
+	case uint64:
+		return Unsigned
 	case bool:
 		return Boolean
 	case string:
@@ -59,6 +63,8 @@ func (d DataType) String() string {
 		return "float"
 	case Integer:
 		return "integer"
+	case Unsigned:
+		return "unsigned"
 	case Boolean:
 		return "boolean"
 	case String:
@@ -80,39 +86,61 @@ type Node interface {
 func (*Query) node()     {}
 func (Statements) node() {}
 
-func (*AlterRetentionPolicyStatement) node()  {}
-func (*CreateContinuousQueryStatement) node() {}
-func (*CreateDatabaseStatement) node()        {}
-func (*CreateRetentionPolicyStatement) node() {}
-func (*CreateUserStatement) node()            {}
-func (*Distinct) node()                       {}
-func (*DeleteStatement) node()                {}
-func (*DropContinuousQueryStatement) node()   {}
-func (*DropDatabaseStatement) node()          {}
-func (*DropMeasurementStatement) node()       {}
-func (*DropRetentionPolicyStatement) node()   {}
-func (*DropSeriesStatement) node()            {}
-func (*DropUserStatement) node()              {}
-func (*GrantStatement) node()                 {}
-func (*GrantAdminStatement) node()            {}
-func (*RevokeStatement) node()                {}
-func (*RevokeAdminStatement) node()           {}
-func (*SelectStatement) node()                {}
-func (*SetPasswordUserStatement) node()       {}
-func (*ShowContinuousQueriesStatement) node() {}
-func (*ShowGrantsForUserStatement) node()     {}
-func (*ShowServersStatement) node()           {}
-func (*ShowDatabasesStatement) node()         {}
-func (*ShowFieldKeysStatement) node()         {}
-func (*ShowRetentionPoliciesStatement) node() {}
-func (*ShowMeasurementsStatement) node()      {}
-func (*ShowSeriesStatement) node()            {}
-func (*ShowShardsStatement) node()            {}
-func (*ShowStatsStatement) node()             {}
-func (*ShowDiagnosticsStatement) node()       {}
-func (*ShowTagKeysStatement) node()           {}
-func (*ShowTagValuesStatement) node()         {}
-func (*ShowUsersStatement) node()             {}
+func (*AlterRetentionPolicyStatement) node()        {}
+func (*CreateContinuousQueryStatement) node()       {}
+func (*CopyShardStatement) node()                   {}
+func (*CreateDatabaseStatement) node()              {}
+func (*CreateDownsamplePolicyStatement) node()      {}
+func (*CreateRetentionPolicyStatement) node()       {}
+func (*CreateSeriesStatement) node()                {}
+func (*CreateSubscriptionStatement) node()          {}
+func (*CreateTagRuleStatement) node()               {}
+func (*CreateUserStatement) node()                  {}
+func (*Distinct) node()                             {}
+func (*DeleteStatement) node()                      {}
+func (*DropContinuousQueryStatement) node()         {}
+func (*DropDataNodeStatement) node()                {}
+func (*DropDatabaseStatement) node()                {}
+func (*DropDownsamplePolicyStatement) node()        {}
+func (*DropHintedHandoffStatement) node()           {}
+func (*DropMeasurementStatement) node()             {}
+func (*DropRetentionPolicyStatement) node()         {}
+func (*DropSeriesStatement) node()                  {}
+func (*DropSubscriptionStatement) node()            {}
+func (*DropTagRuleStatement) node()                 {}
+func (*DropUserStatement) node()                    {}
+func (*GrantStatement) node()                       {}
+func (*GrantAdminStatement) node()                  {}
+func (*MoveShardStatement) node()                   {}
+func (*RenameDatabaseStatement) node()              {}
+func (*RenameMeasurementStatement) node()           {}
+func (*RenameTagKeyStatement) node()                {}
+func (*RenameTagValueStatement) node()              {}
+func (*RevokeStatement) node()                      {}
+func (*RevokeAdminStatement) node()                 {}
+func (*SelectStatement) node()                      {}
+func (*SetPasswordUserStatement) node()             {}
+func (*SetUserLimitsStatement) node()               {}
+func (*ShowContinuousQueriesStatement) node()       {}
+func (*ShowContinuousQueriesStatusStatement) node() {}
+func (*ShowGrantsForUserStatement) node()           {}
+func (*ShowHintedHandoffStatement) node()           {}
+func (*ShowServersStatement) node()                 {}
+func (*ShowDatabasesStatement) node()               {}
+func (*ShowDownsamplePoliciesStatement) node()      {}
+func (*ShowFieldKeysStatement) node()               {}
+func (*ShowRetentionPoliciesStatement) node()       {}
+func (*ShowMeasurementsStatement) node()            {}
+func (*ShowSeriesStatement) node()                  {}
+func (*ShowShardsStatement) node()                  {}
+func (*ShowStatsStatement) node()                   {}
+func (*ShowSubscriptionsStatement) node()           {}
+func (*ShowDiagnosticsStatement) node()             {}
+func (*ShowTagKeysStatement) node()                 {}
+func (*ShowTagRulesStatement) node()                {}
+func (*ShowTagValuesStatement) node()               {}
+func (*ShowUsersStatement) node()                   {}
+func (*ShowUserLimitsStatement) node()              {}
 
 func (*BinaryExpr) node()      {}
 func (*BooleanLiteral) node()  {}
@@ -140,6 +168,22 @@ func (*Wildcard) node()        {}
 // Query represents a collection of ordered statements.
 type Query struct {
 	Statements Statements
+
+	// FailFast stops the execution at the first statement with an error.
+	// Otherwise each statement runs and has its own result or error.
+	FailFast bool
+
+	// Stats adds the execution statistics of each statement to its result.
+	Stats bool
+
+	// Batch queues the SELECT statements of the query behind those of
+	// interactive queries when the server runs as many as it may at once.
+	Batch bool
+
+	// Rollup reads the points of SELECT statements older than what the
+	// downsample policies of their retention policies have downsampled from
+	// the downsampled retention policies instead.
+	Rollup bool
 }
 
 // String returns a string representation of the query.
@@ -187,38 +231,60 @@ type ExecutionPrivilege struct {
 // ExecutionPrivileges is a list of privileges required to execute a statement.
 type ExecutionPrivileges []ExecutionPrivilege
 
-func (*AlterRetentionPolicyStatement) stmt()  {}
-func (*CreateContinuousQueryStatement) stmt() {}
-func (*CreateDatabaseStatement) stmt()        {}
-func (*CreateRetentionPolicyStatement) stmt() {}
-func (*CreateUserStatement) stmt()            {}
-func (*DeleteStatement) stmt()                {}
-func (*DropContinuousQueryStatement) stmt()   {}
-func (*DropDatabaseStatement) stmt()          {}
-func (*DropMeasurementStatement) stmt()       {}
-func (*DropRetentionPolicyStatement) stmt()   {}
-func (*DropSeriesStatement) stmt()            {}
-func (*DropUserStatement) stmt()              {}
-func (*GrantStatement) stmt()                 {}
-func (*GrantAdminStatement) stmt()            {}
-func (*ShowContinuousQueriesStatement) stmt() {}
-func (*ShowGrantsForUserStatement) stmt()     {}
-func (*ShowServersStatement) stmt()           {}
-func (*ShowDatabasesStatement) stmt()         {}
-func (*ShowFieldKeysStatement) stmt()         {}
-func (*ShowMeasurementsStatement) stmt()      {}
-func (*ShowRetentionPoliciesStatement) stmt() {}
-func (*ShowSeriesStatement) stmt()            {}
-func (*ShowShardsStatement) stmt()            {}
-func (*ShowStatsStatement) stmt()             {}
-func (*ShowDiagnosticsStatement) stmt()       {}
-func (*ShowTagKeysStatement) stmt()           {}
-func (*ShowTagValuesStatement) stmt()         {}
-func (*ShowUsersStatement) stmt()             {}
-func (*RevokeStatement) stmt()                {}
-func (*RevokeAdminStatement) stmt()           {}
-func (*SelectStatement) stmt()                {}
-func (*SetPasswordUserStatement) stmt()       {}
+func (*AlterRetentionPolicyStatement) stmt()        {}
+func (*CreateContinuousQueryStatement) stmt()       {}
+func (*CopyShardStatement) stmt()                   {}
+func (*CreateDatabaseStatement) stmt()              {}
+func (*CreateDownsamplePolicyStatement) stmt()      {}
+func (*CreateRetentionPolicyStatement) stmt()       {}
+func (*CreateSeriesStatement) stmt()                {}
+func (*CreateSubscriptionStatement) stmt()          {}
+func (*CreateTagRuleStatement) stmt()               {}
+func (*CreateUserStatement) stmt()                  {}
+func (*DeleteStatement) stmt()                      {}
+func (*DropContinuousQueryStatement) stmt()         {}
+func (*DropDataNodeStatement) stmt()                {}
+func (*DropDatabaseStatement) stmt()                {}
+func (*DropDownsamplePolicyStatement) stmt()        {}
+func (*DropHintedHandoffStatement) stmt()           {}
+func (*DropMeasurementStatement) stmt()             {}
+func (*DropRetentionPolicyStatement) stmt()         {}
+func (*DropSeriesStatement) stmt()                  {}
+func (*DropSubscriptionStatement) stmt()            {}
+func (*DropTagRuleStatement) stmt()                 {}
+func (*DropUserStatement) stmt()                    {}
+func (*GrantStatement) stmt()                       {}
+func (*GrantAdminStatement) stmt()                  {}
+func (*MoveShardStatement) stmt()                   {}
+func (*RenameDatabaseStatement) stmt()              {}
+func (*RenameMeasurementStatement) stmt()           {}
+func (*RenameTagKeyStatement) stmt()                {}
+func (*RenameTagValueStatement) stmt()              {}
+func (*ShowContinuousQueriesStatement) stmt()       {}
+func (*ShowContinuousQueriesStatusStatement) stmt() {}
+func (*ShowGrantsForUserStatement) stmt()           {}
+func (*ShowHintedHandoffStatement) stmt()           {}
+func (*ShowServersStatement) stmt()                 {}
+func (*ShowDatabasesStatement) stmt()               {}
+func (*ShowDownsamplePoliciesStatement) stmt()      {}
+func (*ShowFieldKeysStatement) stmt()               {}
+func (*ShowMeasurementsStatement) stmt()            {}
+func (*ShowRetentionPoliciesStatement) stmt()       {}
+func (*ShowSeriesStatement) stmt()                  {}
+func (*ShowShardsStatement) stmt()                  {}
+func (*ShowStatsStatement) stmt()                   {}
+func (*ShowSubscriptionsStatement) stmt()           {}
+func (*ShowDiagnosticsStatement) stmt()             {}
+func (*ShowTagKeysStatement) stmt()                 {}
+func (*ShowTagRulesStatement) stmt()                {}
+func (*ShowTagValuesStatement) stmt()               {}
+func (*ShowUsersStatement) stmt()                   {}
+func (*ShowUserLimitsStatement) stmt()              {}
+func (*RevokeStatement) stmt()                      {}
+func (*RevokeAdminStatement) stmt()                 {}
+func (*SelectStatement) stmt()                      {}
+func (*SetPasswordUserStatement) stmt()             {}
+func (*SetUserLimitsStatement) stmt()               {}
 
 // Expr represents an expression that can be evaluated to a value.
 type Expr interface {
@@ -347,6 +413,30 @@ func (s *DropDatabaseStatement) RequiredPrivileges() ExecutionPrivileges {
 	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
 }
 
+// RenameDatabaseStatement represents a command to rename a database.
+type RenameDatabaseStatement struct {
+	// Current name of the database.
+	Name string
+
+	// Name the database is renamed to.
+	NewName string
+}
+
+// String returns a string representation of the rename database statement.
+func (s *RenameDatabaseStatement) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("RENAME DATABASE ")
+	_, _ = buf.WriteString(s.Name)
+	_, _ = buf.WriteString(" TO ")
+	_, _ = buf.WriteString(s.NewName)
+	return buf.String()
+}
+
+// RequiredPrivileges returns the privilege required to execute a RenameDatabaseStatement.
+func (s *RenameDatabaseStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
 // DropRetentionPolicyStatement represents a command to drop a retention policy from a database.
 type DropRetentionPolicyStatement struct {
 	// Name of the policy to drop.
@@ -381,6 +471,9 @@ type CreateUserStatement struct {
 
 	// User's admin privilege.
 	Admin bool
+
+	// Database a write-only user may only write to. Blank for other users.
+	WriteOnlyDatabase string
 }
 
 // String returns a string representation of the create user statement.
@@ -392,6 +485,9 @@ func (s *CreateUserStatement) String() string {
 	_, _ = buf.WriteString("[REDACTED]")
 	if s.Admin {
 		_, _ = buf.WriteString(" WITH ALL PRIVILEGES")
+	} else if s.WriteOnlyDatabase != "" {
+		_, _ = buf.WriteString(" WITH WRITE ONLY ON ")
+		_, _ = buf.WriteString(QuoteIdent(s.WriteOnlyDatabase))
 	}
 	return buf.String()
 }
@@ -524,6 +620,54 @@ func (s *SetPasswordUserStatement) RequiredPrivileges() ExecutionPrivileges {
 	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
 }
 
+// SetUserLimitsStatement represents a command for setting the resources the
+// queries of a user may use. A zero limit is unlimited.
+type SetUserLimitsStatement struct {
+	// Name of the user.
+	Name string
+
+	// Maximum number of queries the user may run at once.
+	MaxQueries int
+
+	// Maximum time range a SELECT of the user may cover.
+	MaxRange time.Duration
+
+	// Maximum number of points a SELECT of the user may read from shards.
+	MaxPoints int64
+
+	// Batch queues the SELECT statements of the user behind those of
+	// interactive queries.
+	Batch bool
+}
+
+// String returns a string representation of the set limits statement.
+func (s *SetUserLimitsStatement) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("SET LIMITS FOR ")
+	_, _ = buf.WriteString(QuoteIdent(s.Name))
+	if s.MaxQueries > 0 {
+		_, _ = buf.WriteString(" MAX QUERIES ")
+		_, _ = buf.WriteString(strconv.Itoa(s.MaxQueries))
+	}
+	if s.MaxRange > 0 {
+		_, _ = buf.WriteString(" MAX RANGE ")
+		_, _ = buf.WriteString(FormatDuration(s.MaxRange))
+	}
+	if s.MaxPoints > 0 {
+		_, _ = buf.WriteString(" MAX POINTS ")
+		_, _ = buf.WriteString(strconv.FormatInt(s.MaxPoints, 10))
+	}
+	if s.Batch {
+		_, _ = buf.WriteString(" PRIORITY BATCH")
+	}
+	return buf.String()
+}
+
+// RequiredPrivileges returns the privilege required to execute a SetUserLimitsStatement.
+func (s *SetUserLimitsStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
 // RevokeStatement represents a command to revoke a privilege from a user.
 type RevokeStatement struct {
 	// The privilege to be revoked.
@@ -586,6 +730,13 @@ type CreateRetentionPolicyStatement struct {
 	// Replication factor for data written to this policy.
 	Replication int
 
+	// Duration spanned by each shard group. Zero uses a duration based on
+	// the policy duration.
+	ShardGroupDuration time.Duration
+
+	// Interval of the points written to this policy. Zero if unknown.
+	Resolution time.Duration
+
 	// Should this policy be set as default for the database?
 	Default bool
 }
@@ -601,6 +752,14 @@ func (s *CreateRetentionPolicyStatement) String() string {
 	_, _ = buf.WriteString(FormatDuration(s.Duration))
 	_, _ = buf.WriteString(" REPLICATION ")
 	_, _ = buf.WriteString(strconv.Itoa(s.Replication))
+	if s.ShardGroupDuration > 0 {
+		_, _ = buf.WriteString(" SHARD DURATION ")
+		_, _ = buf.WriteString(FormatDuration(s.ShardGroupDuration))
+	}
+	if s.Resolution > 0 {
+		_, _ = buf.WriteString(" RESOLUTION ")
+		_, _ = buf.WriteString(FormatDuration(s.Resolution))
+	}
 	if s.Default {
 		_, _ = buf.WriteString(" DEFAULT")
 	}
@@ -626,6 +785,9 @@ type AlterRetentionPolicyStatement struct {
 	// Replication factor for data written to this policy.
 	Replication *int
 
+	// Interval of the points written to this policy.
+	Resolution *time.Duration
+
 	// Should this policy be set as defalut for the database?
 	Default bool
 }
@@ -648,6 +810,11 @@ func (s *AlterRetentionPolicyStatement) String() string {
 		_, _ = buf.WriteString(strconv.Itoa(*s.Replication))
 	}
 
+	if s.Resolution != nil {
+		_, _ = buf.WriteString(" RESOLUTION ")
+		_, _ = buf.WriteString(FormatDuration(*s.Resolution))
+	}
+
 	if s.Default {
 		_, _ = buf.WriteString(" DEFAULT")
 	}
@@ -807,20 +974,35 @@ func cloneSource(s Source) Source {
 }
 
 // RewriteWildcards returns the re-written form of the select statement. Any wildcard query
-// fields are replaced with the supplied fields, and any wildcard GROUP BY fields are replaced
-// with the supplied dimensions.
-func (s *SelectStatement) RewriteWildcards(fields Fields, dimensions Dimensions) *SelectStatement {
+// fields are replaced with the supplied fields and tags, and any wildcard GROUP BY fields are
+// replaced with the supplied dimensions. A "*::field" or "*::tag" wildcard is only replaced
+// with the fields or tags. A plain wildcard doesn't include tags if the tags are also grouped by.
+func (s *SelectStatement) RewriteWildcards(fields, tags Fields, dimensions Dimensions) *SelectStatement {
 	other := s.Clone()
-	selectWildcard, groupWildcard := false, false
+	selectWildcard, groupWildcard := false, s.HasDimensionWildcard()
+
+	// Sort wildcard fields for consistent output
+	all := append(Fields{}, fields...)
+	if !groupWildcard {
+		all = append(all, tags...)
+	}
+	sort.Sort(all)
+	sort.Sort(fields)
+	sort.Sort(tags)
 
 	// Rewrite all wildcard query fields
 	rwFields := make(Fields, 0, len(s.Fields))
 	for _, f := range s.Fields {
-		switch f.Expr.(type) {
+		switch expr := f.Expr.(type) {
 		case *Wildcard:
-			// Sort wildcard fields for consistent output
-			sort.Sort(fields)
-			rwFields = append(rwFields, fields...)
+			switch expr.Type {
+			case FIELD:
+				rwFields = append(rwFields, fields...)
+			case TAG:
+				rwFields = append(rwFields, tags...)
+			default:
+				rwFields = append(rwFields, all...)
+			}
 			selectWildcard = true
 		default:
 			rwFields = append(rwFields, f)
@@ -834,7 +1016,6 @@ func (s *SelectStatement) RewriteWildcards(fields Fields, dimensions Dimensions)
 		switch d.Expr.(type) {
 		case *Wildcard:
 			rwDimensions = append(rwDimensions, dimensions...)
-			groupWildcard = true
 		default:
 			rwDimensions = append(rwDimensions, d)
 		}
@@ -1096,6 +1277,9 @@ func (s *SelectStatement) validateDimensions() error {
 				return errors.New("time() is a function and expects at least one argument")
 			}
 		case *Wildcard:
+			if expr.Type == FIELD {
+				return errors.New("only time and tag dimensions allowed")
+			}
 		default:
 			return errors.New("only time and tag dimensions allowed")
 		}
@@ -1133,7 +1317,7 @@ func (s *SelectStatement) validateAggregates(tr targetRequirement) error {
 				if err := s.validSelectWithAggregate(numAggregates); err != nil {
 					return err
 				}
-				if min, max, got := 1, 2, len(expr.Args); got > max || got < min {
+				if min, max, got := 1, 3, len(expr.Args); got > max || got < min {
 					return fmt.Errorf("invalid number of arguments for %s, expected at least %d but no more than %d, got %d", expr.Name, min, max, got)
 				}
 				// Validate that if they have a time dimension, they need a sub-call like min/max, etc.
@@ -1168,7 +1352,17 @@ func (s *SelectStatement) validateAggregates(tr targetRequirement) error {
 						return fmt.Errorf("limit (%d) in %s function can not be larger than the LIMIT (%d) in the select statement", int64(callLimit.Val), expr.Name, int64(s.Limit))
 					}
 
-					for _, v := range expr.Args[:len(expr.Args)-1] {
+					// The first argument may be an aggregate to select the tags with the
+					// top aggregates rather than the top points e.g. top(mean(value), host, 5).
+					args := expr.Args[:len(expr.Args)-1]
+					if fn, ok := args[0].(*Call); ok {
+						if err := validateTopAggregate(expr, fn); err != nil {
+							return err
+						}
+						args = args[1:]
+					}
+
+					for _, v := range args {
 						if _, ok := v.(*VarRef); !ok {
 							return fmt.Errorf("only fields or tags are allowed in %s(), found %s", expr.Name, v)
 						}
@@ -1341,13 +1535,42 @@ func (s *SelectStatement) validateDerivative() error {
 	}
 
 	// If a duration arg is pased, make sure it's a duration
-	if len(derivativeCall.Args) == 2 {
+	if len(derivativeCall.Args) >= 2 {
 		// Second must be a duration .e.g (1h)
 		if _, ok := derivativeCall.Args[1].(*DurationLiteral); !ok {
 			return fmt.Errorf("derivative requires a duration argument")
 		}
 	}
 
+	// If a max gap is passed, it must be a duration too .e.g (10m)
+	if len(derivativeCall.Args) == 3 {
+		if _, ok := derivativeCall.Args[2].(*DurationLiteral); !ok {
+			return fmt.Errorf("derivative requires a duration as max gap")
+		}
+	}
+
+	return nil
+}
+
+// validateTopAggregate returns an error if fn can't be the aggregate selected by
+// the top or bottom call expr.
+func validateTopAggregate(expr, fn *Call) error {
+	if expr.Name != "top" {
+		return fmt.Errorf("aggregate argument not supported in %s()", expr.Name)
+	} else if len(expr.Args) < 3 {
+		return fmt.Errorf("tag argument required with aggregate argument in %s()", expr.Name)
+	}
+
+	switch fn.Name {
+	case "count", "sum", "mean", "median", "min", "max", "spread", "stddev", "first", "last":
+	default:
+		return fmt.Errorf("invalid aggregate in %s(), found %s", expr.Name, fn)
+	}
+	if len(fn.Args) != 1 {
+		return fmt.Errorf("invalid number of arguments for %s, expected %d, got %d", fn.Name, 1, len(fn.Args))
+	} else if _, ok := fn.Args[0].(*VarRef); !ok {
+		return fmt.Errorf("expected field argument in %s()", fn.Name)
+	}
 	return nil
 }
 
@@ -1659,13 +1882,13 @@ type DeleteStatement struct {
 // String returns a string representation of the delete statement.
 func (s *DeleteStatement) String() string {
 	var buf bytes.Buffer
-	_, _ = buf.WriteString("DELETE ")
+	_, _ = buf.WriteString("DELETE FROM ")
 	_, _ = buf.WriteString(s.Source.String())
 	if s.Condition != nil {
 		_, _ = buf.WriteString(" WHERE ")
 		_, _ = buf.WriteString(s.Condition.String())
 	}
-	return s.String()
+	return buf.String()
 }
 
 // RequiredPrivileges returns the privilege required to execute a DeleteStatement.
@@ -1675,6 +1898,9 @@ func (s *DeleteStatement) RequiredPrivileges() ExecutionPrivileges {
 
 // ShowSeriesStatement represents a command for listing series in the database.
 type ShowSeriesStatement struct {
+	// Returns the number of points stored for each series instead of its tags.
+	ExactCounts bool
+
 	// Measurement(s) the series are listed for.
 	Sources Sources
 
@@ -1697,6 +1923,10 @@ func (s *ShowSeriesStatement) String() string {
 	var buf bytes.Buffer
 	_, _ = buf.WriteString("SHOW SERIES")
 
+	if s.ExactCounts {
+		_, _ = buf.WriteString(" EXACT COUNTS")
+	}
+
 	if s.Sources != nil {
 		_, _ = buf.WriteString(" FROM ")
 		_, _ = buf.WriteString(s.Sources.String())
@@ -1768,6 +1998,110 @@ func (s *ShowContinuousQueriesStatement) RequiredPrivileges() ExecutionPrivilege
 	return ExecutionPrivileges{{Admin: false, Name: "", Privilege: ReadPrivilege}}
 }
 
+// ShowContinuousQueriesStatusStatement represents a command for listing the
+// execution status of continuous queries.
+type ShowContinuousQueriesStatusStatement struct{}
+
+// String returns a string representation of the statement.
+func (s *ShowContinuousQueriesStatusStatement) String() string {
+	return "SHOW CONTINUOUS QUERIES STATUS"
+}
+
+// RequiredPrivileges returns the privilege required to execute a ShowContinuousQueriesStatusStatement.
+func (s *ShowContinuousQueriesStatusStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
+// ShowHintedHandoffStatement represents a command for listing hinted handoff queues.
+type ShowHintedHandoffStatement struct{}
+
+// String returns a string representation of the statement.
+func (s *ShowHintedHandoffStatement) String() string { return "SHOW HINTED HANDOFF" }
+
+// RequiredPrivileges returns the privilege required to execute a ShowHintedHandoffStatement.
+func (s *ShowHintedHandoffStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
+// DropDataNodeStatement represents a command for removing a data node from the cluster.
+type DropDataNodeStatement struct {
+	// ID of the node to remove.
+	NodeID uint64
+
+	// Reassign shards only owned by the node, losing their data.
+	Force bool
+}
+
+// String returns a string representation of the statement.
+func (s *DropDataNodeStatement) String() string {
+	if s.Force {
+		return fmt.Sprintf("DROP DATA NODE %d FORCE", s.NodeID)
+	}
+	return fmt.Sprintf("DROP DATA NODE %d", s.NodeID)
+}
+
+// RequiredPrivileges returns the privilege required to execute a DropDataNodeStatement.
+func (s *DropDataNodeStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
+// DropHintedHandoffStatement represents a command for purging hinted handoff queues.
+type DropHintedHandoffStatement struct {
+	// ID of the node whose queue is purged. Zero purges all queues.
+	NodeID uint64
+}
+
+// String returns a string representation of the statement.
+func (s *DropHintedHandoffStatement) String() string {
+	if s.NodeID == 0 {
+		return "DROP HINTED HANDOFF"
+	}
+	return fmt.Sprintf("DROP HINTED HANDOFF FOR %d", s.NodeID)
+}
+
+// RequiredPrivileges returns the privilege required to execute a DropHintedHandoffStatement.
+func (s *DropHintedHandoffStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
+// CopyShardStatement represents a command for copying a shard to another node.
+type CopyShardStatement struct {
+	// ID of the shard to copy.
+	ShardID uint64
+
+	// IDs of the node holding the shard and the node receiving the copy.
+	From, To uint64
+}
+
+// String returns a string representation of the statement.
+func (s *CopyShardStatement) String() string {
+	return fmt.Sprintf("COPY SHARD %d FROM %d TO %d", s.ShardID, s.From, s.To)
+}
+
+// RequiredPrivileges returns the privilege required to execute a CopyShardStatement.
+func (s *CopyShardStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
+// MoveShardStatement represents a command for moving a shard between nodes.
+type MoveShardStatement struct {
+	// ID of the shard to move.
+	ShardID uint64
+
+	// IDs of the node currently holding the shard and its new owner.
+	From, To uint64
+}
+
+// String returns a string representation of the statement.
+func (s *MoveShardStatement) String() string {
+	return fmt.Sprintf("MOVE SHARD %d FROM %d TO %d", s.ShardID, s.From, s.To)
+}
+
+// RequiredPrivileges returns the privilege required to execute a MoveShardStatement.
+func (s *MoveShardStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
 // ShowGrantsForUserStatement represents a command for listing user privileges.
 type ShowGrantsForUserStatement struct {
 	// Name of the user to display privileges.
@@ -1820,11 +2154,32 @@ type CreateContinuousQueryStatement struct {
 
 	// Source of data (SELECT statement).
 	Source *SelectStatement
+
+	// Interval between executions. Defaults to the GROUP BY interval.
+	ResampleEvery time.Duration
+
+	// Window of recent time recomputed on each execution.
+	// Defaults to the GROUP BY interval.
+	ResampleFor time.Duration
 }
 
 // String returns a string representation of the statement.
 func (s *CreateContinuousQueryStatement) String() string {
-	return fmt.Sprintf("CREATE CONTINUOUS QUERY %s ON %s BEGIN %s END", QuoteIdent(s.Name), QuoteIdent(s.Database), s.Source.String())
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "CREATE CONTINUOUS QUERY %s ON %s ", QuoteIdent(s.Name), QuoteIdent(s.Database))
+
+	if s.ResampleEvery > 0 || s.ResampleFor > 0 {
+		buf.WriteString("RESAMPLE ")
+		if s.ResampleEvery > 0 {
+			fmt.Fprintf(&buf, "EVERY %s ", FormatDuration(s.ResampleEvery))
+		}
+		if s.ResampleFor > 0 {
+			fmt.Fprintf(&buf, "FOR %s ", FormatDuration(s.ResampleFor))
+		}
+	}
+
+	fmt.Fprintf(&buf, "BEGIN %s END", s.Source.String())
+	return buf.String()
 }
 
 // DefaultDatabase returns the default database from the statement.
@@ -1869,6 +2224,264 @@ func (s *DropContinuousQueryStatement) RequiredPrivileges() ExecutionPrivileges
 	return ExecutionPrivileges{{Admin: false, Name: "", Privilege: WritePrivilege}}
 }
 
+// CreateSubscriptionStatement represents a command to add a subscription to the incoming data stream.
+type CreateSubscriptionStatement struct {
+	Name            string
+	Database        string
+	RetentionPolicy string
+	Destinations    []string
+	Mode            string
+}
+
+// String returns a string representation of the CreateSubscriptionStatement.
+func (s *CreateSubscriptionStatement) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("CREATE SUBSCRIPTION ")
+	_, _ = buf.WriteString(QuoteIdent(s.Name))
+	_, _ = buf.WriteString(" ON ")
+	_, _ = buf.WriteString(QuoteIdent(s.Database))
+	_, _ = buf.WriteString(".")
+	_, _ = buf.WriteString(QuoteIdent(s.RetentionPolicy))
+	_, _ = buf.WriteString(" DESTINATIONS ")
+	_, _ = buf.WriteString(s.Mode)
+	_, _ = buf.WriteString(" ")
+	for i, dest := range s.Destinations {
+		if i != 0 {
+			_, _ = buf.WriteString(", ")
+		}
+		_, _ = buf.WriteString(QuoteString(dest))
+	}
+	return buf.String()
+}
+
+// RequiredPrivileges returns the privilege required to execute a CreateSubscriptionStatement
+func (s *CreateSubscriptionStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
+// DropSubscriptionStatement represents a command to drop a subscription to the incoming data stream.
+type DropSubscriptionStatement struct {
+	Name            string
+	Database        string
+	RetentionPolicy string
+}
+
+// String returns a string representation of the DropSubscriptionStatement.
+func (s *DropSubscriptionStatement) String() string {
+	return fmt.Sprintf(`DROP SUBSCRIPTION %s ON %s.%s`, QuoteIdent(s.Name), QuoteIdent(s.Database), QuoteIdent(s.RetentionPolicy))
+}
+
+// RequiredPrivileges returns the privilege required to execute a DropSubscriptionStatement
+func (s *DropSubscriptionStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
+// ShowSubscriptionsStatement represents a command to show a list of subscriptions.
+type ShowSubscriptionsStatement struct {
+}
+
+// String returns a string representation of the ShowSubscriptionsStatement.
+func (s *ShowSubscriptionsStatement) String() string {
+	return "SHOW SUBSCRIPTIONS"
+}
+
+// RequiredPrivileges returns the privilege required to execute a ShowSubscriptionsStatement
+func (s *ShowSubscriptionsStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
+// CreateTagRuleStatement represents a command for creating a rule that
+// normalizes tag values written to a database.
+type CreateTagRuleStatement struct {
+	// Name of the rule.
+	Name string
+
+	// Database the rule applies to.
+	Database string
+
+	// Tag key the rule applies to. The rule applies to all tags if blank.
+	Key string
+
+	// Normalization applied to tag values: "lowercase", "trim" or "map".
+	Action string
+
+	// Values replaced by a "map" rule, keyed by the value they replace.
+	Mappings map[string]string
+}
+
+// String returns a string representation of the create tag rule statement.
+func (s *CreateTagRuleStatement) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("CREATE TAG RULE ")
+	_, _ = buf.WriteString(QuoteIdent(s.Name))
+	_, _ = buf.WriteString(" ON ")
+	_, _ = buf.WriteString(QuoteIdent(s.Database))
+	if s.Key != "" {
+		_, _ = buf.WriteString(" KEY ")
+		_, _ = buf.WriteString(QuoteIdent(s.Key))
+	}
+	_, _ = buf.WriteString(" ")
+	_, _ = buf.WriteString(strings.ToUpper(s.Action))
+
+	// Write mappings in order so the statement is deterministic.
+	from := make([]string, 0, len(s.Mappings))
+	for k := range s.Mappings {
+		from = append(from, k)
+	}
+	sort.Strings(from)
+	for i, k := range from {
+		if i == 0 {
+			_, _ = buf.WriteString(" ")
+		} else {
+			_, _ = buf.WriteString(", ")
+		}
+		_, _ = buf.WriteString(QuoteString(k))
+		_, _ = buf.WriteString(" TO ")
+		_, _ = buf.WriteString(QuoteString(s.Mappings[k]))
+	}
+	return buf.String()
+}
+
+// RequiredPrivileges returns the privilege required to execute a CreateTagRuleStatement.
+func (s *CreateTagRuleStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
+// DropTagRuleStatement represents a command for removing a tag normalization rule.
+type DropTagRuleStatement struct {
+	Name     string
+	Database string
+}
+
+// String returns a string representation of the drop tag rule statement.
+func (s *DropTagRuleStatement) String() string {
+	return fmt.Sprintf(`DROP TAG RULE %s ON %s`, QuoteIdent(s.Name), QuoteIdent(s.Database))
+}
+
+// RequiredPrivileges returns the privilege required to execute a DropTagRuleStatement.
+func (s *DropTagRuleStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
+// ShowTagRulesStatement represents a command for listing tag normalization rules.
+type ShowTagRulesStatement struct {
+	// Only show the rules of this database, if set.
+	Database string
+}
+
+// String returns a string representation of the show tag rules statement.
+func (s *ShowTagRulesStatement) String() string {
+	if s.Database != "" {
+		return fmt.Sprintf("SHOW TAG RULES ON %s", QuoteIdent(s.Database))
+	}
+	return "SHOW TAG RULES"
+}
+
+// RequiredPrivileges returns the privilege required to execute a ShowTagRulesStatement.
+func (s *ShowTagRulesStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
+// CreateDownsamplePolicyStatement represents a command for creating a policy
+// that downsamples the points of one retention policy into another.
+type CreateDownsamplePolicyStatement struct {
+	// Name of the policy.
+	Name string
+
+	// Database the policy applies to.
+	Database string
+
+	// Retention policy the points are read from.
+	Source string
+
+	// Retention policy the downsampled points are written to.
+	Target string
+
+	// Interval of the downsampled points.
+	Interval time.Duration
+
+	// Aggregate applied to fields without their own aggregate. Blank uses
+	// the default.
+	DefaultAggregate string
+
+	// Aggregates applied to specific fields, keyed by field.
+	Aggregates map[string]string
+}
+
+// String returns a string representation of the create downsample policy statement.
+func (s *CreateDownsamplePolicyStatement) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("CREATE DOWNSAMPLE POLICY ")
+	_, _ = buf.WriteString(QuoteIdent(s.Name))
+	_, _ = buf.WriteString(" ON ")
+	_, _ = buf.WriteString(QuoteIdent(s.Database))
+	_, _ = buf.WriteString(" FROM ")
+	_, _ = buf.WriteString(QuoteIdent(s.Source))
+	_, _ = buf.WriteString(" TO ")
+	_, _ = buf.WriteString(QuoteIdent(s.Target))
+	_, _ = buf.WriteString(" EVERY ")
+	_, _ = buf.WriteString(FormatDuration(s.Interval))
+
+	// Write field aggregates in order so the statement is deterministic.
+	var aggregates []string
+	if s.DefaultAggregate != "" {
+		aggregates = append(aggregates, s.DefaultAggregate)
+	}
+	fields := make([]string, 0, len(s.Aggregates))
+	for k := range s.Aggregates {
+		fields = append(fields, k)
+	}
+	sort.Strings(fields)
+	for _, k := range fields {
+		aggregates = append(aggregates, fmt.Sprintf("%s(%s)", s.Aggregates[k], QuoteIdent(k)))
+	}
+	if len(aggregates) > 0 {
+		_, _ = buf.WriteString(" AGGREGATE ")
+		_, _ = buf.WriteString(strings.Join(aggregates, ", "))
+	}
+	return buf.String()
+}
+
+// RequiredPrivileges returns the privilege required to execute a CreateDownsamplePolicyStatement.
+func (s *CreateDownsamplePolicyStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
+// DropDownsamplePolicyStatement represents a command for removing a downsample policy.
+type DropDownsamplePolicyStatement struct {
+	Name     string
+	Database string
+}
+
+// String returns a string representation of the drop downsample policy statement.
+func (s *DropDownsamplePolicyStatement) String() string {
+	return fmt.Sprintf(`DROP DOWNSAMPLE POLICY %s ON %s`, QuoteIdent(s.Name), QuoteIdent(s.Database))
+}
+
+// RequiredPrivileges returns the privilege required to execute a DropDownsamplePolicyStatement.
+func (s *DropDownsamplePolicyStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
+// ShowDownsamplePoliciesStatement represents a command for listing downsample policies.
+type ShowDownsamplePoliciesStatement struct {
+	// Only show the policies of this database, if set.
+	Database string
+}
+
+// String returns a string representation of the show downsample policies statement.
+func (s *ShowDownsamplePoliciesStatement) String() string {
+	if s.Database != "" {
+		return fmt.Sprintf("SHOW DOWNSAMPLE POLICIES ON %s", QuoteIdent(s.Database))
+	}
+	return "SHOW DOWNSAMPLE POLICIES"
+}
+
+// RequiredPrivileges returns the privilege required to execute a ShowDownsamplePoliciesStatement.
+func (s *ShowDownsamplePoliciesStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
 // ShowMeasurementsStatement represents a command for listing measurements.
 type ShowMeasurementsStatement struct {
 	// An expression evaluated on data point.
@@ -1914,10 +2527,54 @@ func (s *ShowMeasurementsStatement) RequiredPrivileges() ExecutionPrivileges {
 	return ExecutionPrivileges{{Admin: false, Name: "", Privilege: ReadPrivilege}}
 }
 
+// CreateSeriesStatement represents a command for registering a series in the
+// shard its points are currently written to, without writing a point.
+type CreateSeriesStatement struct {
+	// Measurement of the series.
+	Measurement *Measurement
+
+	// Tag set of the series.
+	Tags map[string]string
+}
+
+// String returns a string representation of the create series statement.
+func (s *CreateSeriesStatement) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("CREATE SERIES ")
+	_, _ = buf.WriteString(s.Measurement.String())
+
+	// Write tags in order so the statement is deterministic.
+	keys := make([]string, 0, len(s.Tags))
+	for k := range s.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		if i == 0 {
+			_, _ = buf.WriteString(" WITH TAGS ")
+		} else {
+			_, _ = buf.WriteString(", ")
+		}
+		_, _ = buf.WriteString(QuoteIdent(k))
+		_, _ = buf.WriteString(" = ")
+		_, _ = buf.WriteString(QuoteString(s.Tags[k]))
+	}
+	return buf.String()
+}
+
+// RequiredPrivileges returns the privilege required to execute a CreateSeriesStatement.
+func (s *CreateSeriesStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: false, Name: s.Measurement.Database, Privilege: WritePrivilege}}
+}
+
 // DropMeasurementStatement represents a command to drop a measurement.
 type DropMeasurementStatement struct {
 	// Name of the measurement to be dropped.
 	Name string
+
+	// An expression on time limiting the points dropped. The measurement is
+	// only dropped if it's nil.
+	Condition Expr
 }
 
 // String returns a string representation of the drop measurement statement.
@@ -1925,6 +2582,10 @@ func (s *DropMeasurementStatement) String() string {
 	var buf bytes.Buffer
 	_, _ = buf.WriteString("DROP MEASUREMENT ")
 	_, _ = buf.WriteString(s.Name)
+	if s.Condition != nil {
+		_, _ = buf.WriteString(" WHERE ")
+		_, _ = buf.WriteString(s.Condition.String())
+	}
 	return buf.String()
 }
 
@@ -1933,6 +2594,89 @@ func (s *DropMeasurementStatement) RequiredPrivileges() ExecutionPrivileges {
 	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
 }
 
+// RenameMeasurementStatement represents a command to rename a measurement.
+type RenameMeasurementStatement struct {
+	// Current name of the measurement.
+	Name string
+
+	// Name the measurement is renamed to.
+	NewName string
+}
+
+// String returns a string representation of the rename measurement statement.
+func (s *RenameMeasurementStatement) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("ALTER MEASUREMENT ")
+	_, _ = buf.WriteString(s.Name)
+	_, _ = buf.WriteString(" RENAME TO ")
+	_, _ = buf.WriteString(s.NewName)
+	return buf.String()
+}
+
+// RequiredPrivileges returns the privilege(s) required to execute a RenameMeasurementStatement
+func (s *RenameMeasurementStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
+// RenameTagKeyStatement represents a command to rename a tag key of the series
+// of a measurement.
+type RenameTagKeyStatement struct {
+	// Name of the measurement of the series.
+	Measurement string
+
+	// Current and new tag key.
+	Key, NewKey string
+}
+
+// String returns a string representation of the rename tag key statement.
+func (s *RenameTagKeyStatement) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("ALTER MEASUREMENT ")
+	_, _ = buf.WriteString(s.Measurement)
+	_, _ = buf.WriteString(" RENAME TAG ")
+	_, _ = buf.WriteString(s.Key)
+	_, _ = buf.WriteString(" TO ")
+	_, _ = buf.WriteString(s.NewKey)
+	return buf.String()
+}
+
+// RequiredPrivileges returns the privilege(s) required to execute a RenameTagKeyStatement
+func (s *RenameTagKeyStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
+// RenameTagValueStatement represents a command to rewrite a tag value of the
+// series of a measurement.
+type RenameTagValueStatement struct {
+	// Name of the measurement of the series.
+	Measurement string
+
+	// Tag key of the value.
+	Key string
+
+	// Current and new tag value.
+	Value, NewValue string
+}
+
+// String returns a string representation of the rename tag value statement.
+func (s *RenameTagValueStatement) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("ALTER MEASUREMENT ")
+	_, _ = buf.WriteString(s.Measurement)
+	_, _ = buf.WriteString(" RENAME TAG ")
+	_, _ = buf.WriteString(s.Key)
+	_, _ = buf.WriteString(" VALUE ")
+	_, _ = buf.WriteString(QuoteString(s.Value))
+	_, _ = buf.WriteString(" TO ")
+	_, _ = buf.WriteString(QuoteString(s.NewValue))
+	return buf.String()
+}
+
+// RequiredPrivileges returns the privilege(s) required to execute a RenameTagValueStatement
+func (s *RenameTagValueStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
 // ShowRetentionPoliciesStatement represents a command for listing retention policies.
 type ShowRetentionPoliciesStatement struct {
 	// Name of the database to list policies for.
@@ -1954,6 +2698,9 @@ func (s *ShowRetentionPoliciesStatement) RequiredPrivileges() ExecutionPrivilege
 
 // ShowRetentionPoliciesStatement represents a command for displaying stats for a given server.
 type ShowStatsStatement struct {
+	// Database the stats are restricted to. All stats are shown if blank.
+	Database string
+
 	// Hostname or IP of the server for stats.
 	Host string
 }
@@ -1961,9 +2708,14 @@ type ShowStatsStatement struct {
 // String returns a string representation of a ShowStatsStatement.
 func (s *ShowStatsStatement) String() string {
 	var buf bytes.Buffer
-	_, _ = buf.WriteString("SHOW STATS ")
+	_, _ = buf.WriteString("SHOW STATS")
+	if s.Database != "" {
+		_, _ = buf.WriteString(" FOR ")
+		_, _ = buf.WriteString(QuoteString(s.Database))
+	}
 	if s.Host != "" {
-		_, _ = buf.WriteString(s.Host)
+		_, _ = buf.WriteString(" ON ")
+		_, _ = buf.WriteString(QuoteString(s.Host))
 	}
 	return buf.String()
 }
@@ -2115,11 +2867,27 @@ func (s *ShowUsersStatement) RequiredPrivileges() ExecutionPrivileges {
 	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
 }
 
+// ShowUserLimitsStatement represents a command for listing the limits of users.
+type ShowUserLimitsStatement struct{}
+
+// String returns a string representation of the ShowUserLimitsStatement.
+func (s *ShowUserLimitsStatement) String() string {
+	return "SHOW LIMITS"
+}
+
+// RequiredPrivileges returns the privilege(s) required to execute a ShowUserLimitsStatement
+func (s *ShowUserLimitsStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
+}
+
 // ShowFieldKeysStatement represents a command for listing field keys.
 type ShowFieldKeysStatement struct {
 	// Data sources that fields are extracted from.
 	Sources Sources
 
+	// Whether the types and last written times of the fields are returned.
+	Exact bool
+
 	// Fields to sort results by
 	SortFields SortFields
 
@@ -2140,6 +2908,9 @@ func (s *ShowFieldKeysStatement) String() string {
 		_, _ = buf.WriteString(" FROM ")
 		_, _ = buf.WriteString(s.Sources.String())
 	}
+	if s.Exact {
+		_, _ = buf.WriteString(" EXACT")
+	}
 	if len(s.SortFields) > 0 {
 		_, _ = buf.WriteString(" ORDER BY ")
 		_, _ = buf.WriteString(s.SortFields.String())
@@ -2521,10 +3292,21 @@ func CloneRegexLiteral(r *RegexLiteral) *RegexLiteral {
 }
 
 // Wildcard represents a wild card expression.
-type Wildcard struct{}
+type Wildcard struct {
+	// Limits the expansion to fields or tags if set to FIELD or TAG.
+	Type Token
+}
 
 // String returns a string representation of the wildcard.
-func (e *Wildcard) String() string { return "*" }
+func (e *Wildcard) String() string {
+	switch e.Type {
+	case FIELD:
+		return "*::field"
+	case TAG:
+		return "*::tag"
+	}
+	return "*"
+}
 
 // CloneExpr returns a deep copy of the expression.
 func CloneExpr(expr Expr) Expr {
@@ -2559,7 +3341,7 @@ func CloneExpr(expr Expr) Expr {
 	case *VarRef:
 		return &VarRef{Val: expr.Val}
 	case *Wildcard:
-		return &Wildcard{}
+		return &Wildcard{Type: expr.Type}
 	}
 	panic("unreachable")
 }
@@ -2682,6 +3464,20 @@ func Walk(v Visitor, node Node) {
 	case *CreateContinuousQueryStatement:
 		Walk(v, n.Source)
 
+	case *DeleteStatement:
+		Walk(v, n.Source)
+		Walk(v, n.Condition)
+
+	case *CreateSeriesStatement:
+		Walk(v, n.Measurement)
+
+	case *DropMeasurementStatement:
+		Walk(v, n.Condition)
+
+	case *DropSeriesStatement:
+		Walk(v, n.Sources)
+		Walk(v, n.Condition)
+
 	case *Dimension:
 		Walk(v, n.Expr)
 
@@ -2712,6 +3508,10 @@ func Walk(v Visitor, node Node) {
 		Walk(v, n.Condition)
 		Walk(v, n.SortFields)
 
+	case *ShowMeasurementsStatement:
+		Walk(v, n.Condition)
+		Walk(v, n.SortFields)
+
 	case *ShowSeriesStatement:
 		Walk(v, n.Sources)
 		Walk(v, n.Condition)
@@ -2783,7 +3583,48 @@ func Rewrite(r Rewriter, node Node) Node {
 		n.Fields = Rewrite(r, n.Fields).(Fields)
 		n.Dimensions = Rewrite(r, n.Dimensions).(Dimensions)
 		n.Sources = Rewrite(r, n.Sources).(Sources)
-		n.Condition = Rewrite(r, n.Condition).(Expr)
+		n.Condition = rewriteExpr(r, n.Condition)
+
+	case *CreateContinuousQueryStatement:
+		if n.Source != nil {
+			n.Source = Rewrite(r, n.Source).(*SelectStatement)
+		}
+
+	case *DeleteStatement:
+		if n.Source != nil {
+			n.Source = Rewrite(r, n.Source).(Source)
+		}
+		n.Condition = rewriteExpr(r, n.Condition)
+
+	case *DropMeasurementStatement:
+		n.Condition = rewriteExpr(r, n.Condition)
+
+	case *DropSeriesStatement:
+		n.Sources = Rewrite(r, n.Sources).(Sources)
+		n.Condition = rewriteExpr(r, n.Condition)
+
+	case *ShowMeasurementsStatement:
+		n.Condition = rewriteExpr(r, n.Condition)
+
+	case *ShowSeriesStatement:
+		n.Sources = Rewrite(r, n.Sources).(Sources)
+		n.Condition = rewriteExpr(r, n.Condition)
+
+	case *ShowTagKeysStatement:
+		n.Sources = Rewrite(r, n.Sources).(Sources)
+		n.Condition = rewriteExpr(r, n.Condition)
+
+	case *ShowTagValuesStatement:
+		n.Sources = Rewrite(r, n.Sources).(Sources)
+		n.Condition = rewriteExpr(r, n.Condition)
+
+	case *ShowFieldKeysStatement:
+		n.Sources = Rewrite(r, n.Sources).(Sources)
+
+	case Sources:
+		for i, s := range n {
+			n[i] = Rewrite(r, s).(Source)
+		}
 
 	case Fields:
 		for i, f := range n {
@@ -2817,6 +3658,15 @@ func Rewrite(r Rewriter, node Node) Node {
 	return r.Rewrite(node)
 }
 
+// rewriteExpr rewrites an optional expression, such as a condition.
+func rewriteExpr(r Rewriter, expr Expr) Expr {
+	if expr == nil {
+		return nil
+	}
+	e, _ := Rewrite(r, expr).(Expr)
+	return e
+}
+
 // RewriteFunc rewrites a node hierarchy.
 func RewriteFunc(node Node, fn func(Node) Node) Node {
 	return Rewrite(rewriterFunc(fn), node)
@@ -2925,6 +3775,39 @@ func evalBinaryExpr(expr *BinaryExpr, m map[string]interface{}) interface{} {
 			}
 			return lhs / rhs
 		}
+	case uint64:
+		// Negative literals would wrap around as an uint64, so they are
+		// evaluated against the value as a float64 instead.
+		rhsf, _ := rhs.(float64)
+		if rhsf < 0 {
+			return evalBinaryExpr(&BinaryExpr{Op: expr.Op, LHS: &NumberLiteral{Val: float64(lhs)}, RHS: &NumberLiteral{Val: rhsf}}, m)
+		}
+		rhs := uint64(rhsf)
+		switch expr.Op {
+		case EQ:
+			return lhs == rhs
+		case NEQ:
+			return lhs != rhs
+		case LT:
+			return lhs < rhs
+		case LTE:
+			return lhs <= rhs
+		case GT:
+			return lhs > rhs
+		case GTE:
+			return lhs >= rhs
+		case ADD:
+			return lhs + rhs
+		case SUB:
+			return lhs - rhs
+		case MUL:
+			return lhs * rhs
+		case DIV:
+			if rhs == 0 {
+				return uint64(0)
+			}
+			return lhs / rhs
+		}
 	case string:
 		rhs, _ := rhs.(string)
 		switch expr.Op {
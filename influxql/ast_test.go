@@ -408,7 +408,7 @@ func TestSelectStatement_RewriteWildcards(t *testing.T) {
 		}
 
 		// Rewrite statement.
-		rw := stmt.(*influxql.SelectStatement).RewriteWildcards(fields, dimensions)
+		rw := stmt.(*influxql.SelectStatement).RewriteWildcards(fields, nil, dimensions)
 		if rw == nil {
 			t.Errorf("%d. %q: unexpected nil statement", i, tt.stmt)
 			continue
@@ -420,6 +420,58 @@ func TestSelectStatement_RewriteWildcards(t *testing.T) {
 	}
 }
 
+// Ensure that typed wildcards only expand to fields or tags.
+func TestSelectStatement_RewriteWildcards_Type(t *testing.T) {
+	var fields = influxql.Fields{
+		&influxql.Field{Expr: &influxql.VarRef{Val: "value2"}},
+		&influxql.Field{Expr: &influxql.VarRef{Val: "value1"}},
+	}
+	var tags = influxql.Fields{
+		&influxql.Field{Expr: &influxql.VarRef{Val: "host"}},
+	}
+	var dimensions = influxql.Dimensions{
+		&influxql.Dimension{Expr: &influxql.VarRef{Val: "host"}},
+	}
+
+	var tests = []struct {
+		stmt    string
+		rewrite string
+	}{
+		{
+			stmt:    `SELECT * FROM cpu`,
+			rewrite: `SELECT host, value1, value2 FROM cpu GROUP BY host`,
+		},
+		{
+			stmt:    `SELECT *::field FROM cpu`,
+			rewrite: `SELECT value1, value2 FROM cpu GROUP BY host`,
+		},
+		{
+			stmt:    `SELECT value1, *::tag FROM cpu`,
+			rewrite: `SELECT value1, host FROM cpu GROUP BY host`,
+		},
+		{
+			stmt:    `SELECT * FROM cpu GROUP BY *`,
+			rewrite: `SELECT value1, value2 FROM cpu GROUP BY host`,
+		},
+		{
+			stmt:    `SELECT *::tag, *::field FROM cpu GROUP BY *`,
+			rewrite: `SELECT host, value1, value2 FROM cpu GROUP BY host`,
+		},
+	}
+
+	for i, tt := range tests {
+		stmt, err := influxql.NewParser(strings.NewReader(tt.stmt)).ParseStatement()
+		if err != nil {
+			t.Fatalf("invalid statement: %q: %s", tt.stmt, err)
+		}
+
+		rw := stmt.(*influxql.SelectStatement).RewriteWildcards(fields, tags, dimensions)
+		if rw := rw.String(); tt.rewrite != rw {
+			t.Errorf("%d. %q: unexpected rewrite:\n\nexp=%s\n\ngot=%s\n\n", i, tt.stmt, tt.rewrite, rw)
+		}
+	}
+}
+
 // Ensure that the IsRawQuery flag gets set properly
 func TestSelectStatement_IsRawQuerySet(t *testing.T) {
 	var tests = []struct {
@@ -580,6 +632,52 @@ func TestRewrite(t *testing.T) {
 	}
 }
 
+// Ensure the conditions and sources of statements can be rewritten and that
+// statements without a condition can be rewritten.
+func TestRewrite_Statements(t *testing.T) {
+	q, err := influxql.ParseQuery(`SELECT value FROM cpu; SHOW SERIES FROM cpu WHERE host = 'a'; DELETE FROM cpu WHERE host = 'a'; SHOW MEASUREMENTS`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rename the cpu measurement and the host tag.
+	act := influxql.RewriteFunc(q, func(n influxql.Node) influxql.Node {
+		switch n := n.(type) {
+		case *influxql.Measurement:
+			return &influxql.Measurement{Name: "cpu_" + n.Name}
+		case *influxql.VarRef:
+			return &influxql.VarRef{Val: "hostname"}
+		default:
+			return n
+		}
+	})
+
+	if act := act.String(); act != "SELECT hostname FROM cpu_cpu;\nSHOW SERIES FROM cpu_cpu WHERE hostname = 'a';\nDELETE FROM cpu_cpu WHERE hostname = 'a';\nSHOW MEASUREMENTS" {
+		t.Fatalf("unexpected result: %s", act)
+	}
+}
+
+// Ensure the conditions of statements are walked.
+func TestWalk_Statements(t *testing.T) {
+	q, err := influxql.ParseQuery(`DELETE FROM cpu WHERE host = 'a'; DROP SERIES FROM mem WHERE region = 'b'; SHOW MEASUREMENTS WHERE zone = 'c'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	influxql.WalkFunc(q, func(n influxql.Node) {
+		switch n := n.(type) {
+		case *influxql.Measurement:
+			names = append(names, n.Name)
+		case *influxql.VarRef:
+			names = append(names, n.Val)
+		}
+	})
+	if !reflect.DeepEqual(names, []string{"cpu", "host", "mem", "region", "zone"}) {
+		t.Fatalf("unexpected names: %v", names)
+	}
+}
+
 // Ensure that the String() value of a statement is parseable
 func TestParseString(t *testing.T) {
 	var tests = []struct {
@@ -651,6 +749,11 @@ func TestEval(t *testing.T) {
 		{in: `foo = 'bar'`, out: true, data: map[string]interface{}{"foo": "bar"}},
 		{in: `foo = 'bar'`, out: nil, data: map[string]interface{}{"foo": nil}},
 		{in: `foo <> 'bar'`, out: true, data: map[string]interface{}{"foo": "xxx"}},
+
+		// Unsigned integers.
+		{in: `foo > 10`, out: true, data: map[string]interface{}{"foo": uint64(18446744073709551615)}},
+		{in: `foo > -1`, out: true, data: map[string]interface{}{"foo": uint64(0)}},
+		{in: `foo + 2`, out: uint64(12), data: map[string]interface{}{"foo": uint64(10)}},
 	} {
 		// Evaluate expression.
 		out := influxql.Eval(MustParseExpr(tt.in), tt.data)
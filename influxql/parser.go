@@ -72,6 +72,69 @@ func (p *Parser) ParseQuery() (*Query, error) {
 	}
 }
 
+// ParseQueryRecover parses a query string, reporting every statement with a
+// syntax error instead of stopping at the first one.
+func ParseQueryRecover(s string) (*Query, []Pos, ParseErrors) {
+	return NewParser(strings.NewReader(s)).ParseQueryRecover()
+}
+
+// ParseQueryRecover parses an InfluxQL string like ParseQuery but recovers
+// from a syntax error by skipping to the next statement. Returns the
+// statements that parsed, the position of each statement and the errors of
+// the others, in order, with the position of their statements. The errors
+// are nil if the query is valid.
+func (p *Parser) ParseQueryRecover() (*Query, []Pos, ParseErrors) {
+	q := &Query{}
+	var positions []Pos
+	var errs ParseErrors
+	var semi bool
+
+	for {
+		tok, pos, _ := p.scanIgnoreWhitespace()
+		if tok == EOF {
+			return q, positions, errs
+		} else if !semi && tok == SEMICOLON {
+			semi = true
+			continue
+		}
+		p.unscan()
+		semi = false
+
+		s, err := p.ParseStatement()
+		if err == nil {
+			q.Statements = append(q.Statements, s)
+			positions = append(positions, pos)
+			continue
+		}
+
+		// Errors without a position are reported at the start of the statement.
+		perr, ok := err.(*ParseError)
+		if !ok {
+			perr = &ParseError{Message: err.Error(), Pos: pos}
+		}
+		perr.StatementPos = pos
+		errs = append(errs, perr)
+
+		// Skip the rest of the statement unless the error was at its end.
+		if p.s.n == 0 {
+			if tok, _, _ := p.s.curr(); tok == EOF {
+				return q, positions, errs
+			} else if tok == SEMICOLON {
+				semi = true
+				continue
+			}
+		}
+		for {
+			if tok, _, _ := p.scan(); tok == EOF {
+				return q, positions, errs
+			} else if tok == SEMICOLON {
+				semi = true
+				break
+			}
+		}
+	}
+}
+
 // ParseStatement parses an InfluxQL string and returns a Statement AST object.
 func (p *Parser) ParseStatement() (Statement, error) {
 	// Inspect the first token.
@@ -94,9 +157,22 @@ func (p *Parser) ParseStatement() (Statement, error) {
 	case ALTER:
 		return p.parseAlterStatement()
 	case SET:
-		return p.parseSetPasswordUserStatement()
+		tok, pos, lit := p.scanIgnoreWhitespace()
+		if tok == PASSWORD {
+			p.unscan()
+			return p.parseSetPasswordUserStatement()
+		} else if tok == IDENT && strings.ToUpper(lit) == "LIMITS" {
+			return p.parseSetUserLimitsStatement()
+		}
+		return nil, newParseError(tokstr(tok, lit), []string{"PASSWORD", "LIMITS"}, pos)
+	case COPY:
+		return p.parseCopyShardStatement()
+	case MOVE:
+		return p.parseMoveShardStatement()
+	case RENAME:
+		return p.parseRenameDatabaseStatement()
 	default:
-		return nil, newParseError(tokstr(tok, lit), []string{"SELECT", "DELETE", "SHOW", "CREATE", "DROP", "GRANT", "REVOKE", "ALTER", "SET"}, pos)
+		return nil, newParseError(tokstr(tok, lit), []string{"SELECT", "DELETE", "SHOW", "CREATE", "DROP", "GRANT", "REVOKE", "ALTER", "SET", "COPY", "MOVE", "RENAME"}, pos)
 	}
 }
 
@@ -109,6 +185,8 @@ func (p *Parser) parseShowStatement() (Statement, error) {
 		return p.parseShowContinuousQueriesStatement()
 	case GRANTS:
 		return p.parseGrantsForUserStatement()
+	case HINTED:
+		return p.parseShowHintedHandoffStatement()
 	case DATABASES:
 		return p.parseShowDatabasesStatement()
 	case SERVERS:
@@ -133,6 +211,8 @@ func (p *Parser) parseShowStatement() (Statement, error) {
 		return p.parseShowShardsStatement()
 	case STATS:
 		return p.parseShowStatsStatement()
+	case SUBSCRIPTIONS:
+		return p.parseShowSubscriptionsStatement()
 	case DIAGNOSTICS:
 		return p.parseShowDiagnosticsStatement()
 	case TAG:
@@ -141,13 +221,24 @@ func (p *Parser) parseShowStatement() (Statement, error) {
 			return p.parseShowTagKeysStatement()
 		} else if tok == VALUES {
 			return p.parseShowTagValuesStatement()
+		} else if tok == IDENT && strings.ToUpper(lit) == "RULES" {
+			return p.parseShowTagRulesStatement()
 		}
-		return nil, newParseError(tokstr(tok, lit), []string{"KEYS", "VALUES"}, pos)
+		return nil, newParseError(tokstr(tok, lit), []string{"KEYS", "VALUES", "RULES"}, pos)
 	case USERS:
 		return p.parseShowUsersStatement()
+	case IDENT:
+		if strings.ToUpper(lit) == "DOWNSAMPLE" {
+			if tok, pos, lit := p.scanIgnoreWhitespace(); tok != POLICIES {
+				return nil, newParseError(tokstr(tok, lit), []string{"POLICIES"}, pos)
+			}
+			return p.parseShowDownsamplePoliciesStatement()
+		} else if strings.ToUpper(lit) == "LIMITS" {
+			return &ShowUserLimitsStatement{}, nil
+		}
 	}
 
-	return nil, newParseError(tokstr(tok, lit), []string{"CONTINUOUS", "DATABASES", "FIELD", "GRANTS", "MEASUREMENTS", "RETENTION", "SERIES", "SERVERS", "TAG", "USERS"}, pos)
+	return nil, newParseError(tokstr(tok, lit), []string{"CONTINUOUS", "DATABASES", "DOWNSAMPLE", "FIELD", "GRANTS", "HINTED", "LIMITS", "MEASUREMENTS", "RETENTION", "SERIES", "SERVERS", "SUBSCRIPTIONS", "TAG", "USERS"}, pos)
 }
 
 // parseCreateStatement parses a string and returns a create statement.
@@ -166,9 +257,23 @@ func (p *Parser) parseCreateStatement() (Statement, error) {
 			return nil, newParseError(tokstr(tok, lit), []string{"POLICY"}, pos)
 		}
 		return p.parseCreateRetentionPolicyStatement()
+	} else if tok == SERIES {
+		return p.parseCreateSeriesStatement()
+	} else if tok == SUBSCRIPTION {
+		return p.parseCreateSubscriptionStatement()
+	} else if tok == TAG {
+		if tok, pos, lit := p.scanIgnoreWhitespace(); tok != IDENT || strings.ToUpper(lit) != "RULE" {
+			return nil, newParseError(tokstr(tok, lit), []string{"RULE"}, pos)
+		}
+		return p.parseCreateTagRuleStatement()
+	} else if tok == IDENT && strings.ToUpper(lit) == "DOWNSAMPLE" {
+		if tok, pos, lit := p.scanIgnoreWhitespace(); tok != POLICY {
+			return nil, newParseError(tokstr(tok, lit), []string{"POLICY"}, pos)
+		}
+		return p.parseCreateDownsamplePolicyStatement()
 	}
 
-	return nil, newParseError(tokstr(tok, lit), []string{"CONTINUOUS", "DATABASE", "USER", "RETENTION"}, pos)
+	return nil, newParseError(tokstr(tok, lit), []string{"CONTINUOUS", "DATABASE", "USER", "RETENTION", "SERIES", "SUBSCRIPTION", "TAG", "DOWNSAMPLE"}, pos)
 }
 
 // parseDropStatement parses a string and returns a drop statement.
@@ -190,9 +295,25 @@ func (p *Parser) parseDropStatement() (Statement, error) {
 		return p.parseDropRetentionPolicyStatement()
 	} else if tok == USER {
 		return p.parseDropUserStatement()
+	} else if tok == SUBSCRIPTION {
+		return p.parseDropSubscriptionStatement()
+	} else if tok == HINTED {
+		return p.parseDropHintedHandoffStatement()
+	} else if tok == IDENT && strings.ToUpper(lit) == "DATA" {
+		return p.parseDropDataNodeStatement()
+	} else if tok == TAG {
+		if tok, pos, lit := p.scanIgnoreWhitespace(); tok != IDENT || strings.ToUpper(lit) != "RULE" {
+			return nil, newParseError(tokstr(tok, lit), []string{"RULE"}, pos)
+		}
+		return p.parseDropTagRuleStatement()
+	} else if tok == IDENT && strings.ToUpper(lit) == "DOWNSAMPLE" {
+		if tok, pos, lit := p.scanIgnoreWhitespace(); tok != POLICY {
+			return nil, newParseError(tokstr(tok, lit), []string{"POLICY"}, pos)
+		}
+		return p.parseDropDownsamplePolicyStatement()
 	}
 
-	return nil, newParseError(tokstr(tok, lit), []string{"SERIES", "CONTINUOUS", "MEASUREMENT"}, pos)
+	return nil, newParseError(tokstr(tok, lit), []string{"SERIES", "CONTINUOUS", "MEASUREMENT", "SUBSCRIPTION", "HINTED", "DATA", "TAG", "DOWNSAMPLE"}, pos)
 }
 
 // parseAlterStatement parses a string and returns an alter statement.
@@ -204,9 +325,11 @@ func (p *Parser) parseAlterStatement() (Statement, error) {
 			return nil, newParseError(tokstr(tok, lit), []string{"POLICY"}, pos)
 		}
 		return p.parseAlterRetentionPolicyStatement()
+	} else if tok == MEASUREMENT {
+		return p.parseAlterMeasurementStatement()
 	}
 
-	return nil, newParseError(tokstr(tok, lit), []string{"RETENTION"}, pos)
+	return nil, newParseError(tokstr(tok, lit), []string{"RETENTION", "MEASUREMENT"}, pos)
 }
 
 // parseSetPasswordUserStatement parses a string and returns a set statement.
@@ -241,6 +364,70 @@ func (p *Parser) parseSetPasswordUserStatement() (*SetPasswordUserStatement, err
 	return stmt, nil
 }
 
+// parseSetUserLimitsStatement parses a string and returns a set limits statement.
+// This function assumes the "SET LIMITS" tokens have already been consumed.
+func (p *Parser) parseSetUserLimitsStatement() (*SetUserLimitsStatement, error) {
+	stmt := &SetUserLimitsStatement{}
+
+	// Consume the required FOR token.
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != FOR {
+		return nil, newParseError(tokstr(tok, lit), []string{"FOR"}, pos)
+	}
+
+	// Parse username.
+	ident, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Name = ident
+
+	// Parse the optional limits. Omitted limits are unlimited and the
+	// priority is interactive unless set.
+	for {
+		if tok, _, lit := p.scanIgnoreWhitespace(); tok == IDENT && strings.ToUpper(lit) == "PRIORITY" {
+			tok, pos, lit := p.scanIgnoreWhitespace()
+			switch {
+			case tok == IDENT && strings.ToUpper(lit) == "BATCH":
+				stmt.Batch = true
+			case tok == IDENT && strings.ToUpper(lit) == "INTERACTIVE":
+				stmt.Batch = false
+			default:
+				return nil, newParseError(tokstr(tok, lit), []string{"BATCH", "INTERACTIVE"}, pos)
+			}
+			continue
+		} else if tok != IDENT || strings.ToUpper(lit) != "MAX" {
+			p.unscan()
+			return stmt, nil
+		}
+
+		tok, pos, lit := p.scanIgnoreWhitespace()
+		switch {
+		case tok == QUERIES:
+			n, err := p.parseInt(0, math.MaxInt32)
+			if err != nil {
+				return nil, err
+			}
+			stmt.MaxQueries = n
+		case tok == IDENT && strings.ToUpper(lit) == "RANGE":
+			d, err := p.parseDuration()
+			if err != nil {
+				return nil, err
+			}
+			stmt.MaxRange = d
+		case tok == IDENT && strings.ToUpper(lit) == "POINTS":
+			n, err := p.parseUInt64()
+			if err != nil {
+				return nil, err
+			} else if n > math.MaxInt64 {
+				return nil, &ParseError{Message: "max points too large", Pos: pos}
+			}
+			stmt.MaxPoints = int64(n)
+		default:
+			return nil, newParseError(tokstr(tok, lit), []string{"QUERIES", "RANGE", "POINTS"}, pos)
+		}
+	}
+}
+
 // parseCreateRetentionPolicyStatement parses a string and returns a create retention policy statement.
 // This function assumes the CREATE RETENTION POLICY tokens have already been consumed.
 func (p *Parser) parseCreateRetentionPolicyStatement() (*CreateRetentionPolicyStatement, error) {
@@ -290,6 +477,31 @@ func (p *Parser) parseCreateRetentionPolicyStatement() (*CreateRetentionPolicySt
 	}
 	stmt.Replication = n
 
+	// Parse optional SHARD DURATION.
+	if tok, _, _ = p.scanIgnoreWhitespace(); tok == SHARD {
+		if tok, pos, lit = p.scanIgnoreWhitespace(); tok != DURATION {
+			return nil, newParseError(tokstr(tok, lit), []string{"DURATION"}, pos)
+		}
+		d, err := p.parseDuration()
+		if err != nil {
+			return nil, err
+		}
+		stmt.ShardGroupDuration = d
+	} else {
+		p.unscan()
+	}
+
+	// Parse optional RESOLUTION.
+	if tok, _, lit = p.scanIgnoreWhitespace(); tok == IDENT && strings.ToUpper(lit) == "RESOLUTION" {
+		d, err := p.parseDuration()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Resolution = d
+	} else {
+		p.unscan()
+	}
+
 	// Parse optional DEFAULT token.
 	if tok, pos, lit = p.scanIgnoreWhitespace(); tok == DEFAULT {
 		stmt.Default = true
@@ -327,8 +539,8 @@ func (p *Parser) parseAlterRetentionPolicyStatement() (*AlterRetentionPolicyStat
 	}
 	stmt.Database = ident
 
-	// Loop through option tokens (DURATION, REPLICATION, DEFAULT, etc.).
-	maxNumOptions := 3
+	// Loop through option tokens (DURATION, REPLICATION, RESOLUTION, DEFAULT).
+	maxNumOptions := 4
 Loop:
 	for i := 0; i < maxNumOptions; i++ {
 		tok, pos, lit := p.scanIgnoreWhitespace()
@@ -348,8 +560,16 @@ Loop:
 		case DEFAULT:
 			stmt.Default = true
 		default:
+			if tok == IDENT && strings.ToUpper(lit) == "RESOLUTION" {
+				d, err := p.parseDuration()
+				if err != nil {
+					return nil, err
+				}
+				stmt.Resolution = &d
+				continue
+			}
 			if i < 1 {
-				return nil, newParseError(tokstr(tok, lit), []string{"DURATION", "RETENTION", "DEFAULT"}, pos)
+				return nil, newParseError(tokstr(tok, lit), []string{"DURATION", "RETENTION", "RESOLUTION", "DEFAULT"}, pos)
 			}
 			p.unscan()
 			break Loop
@@ -524,6 +744,30 @@ func (p *Parser) parseString() (string, error) {
 	return lit, nil
 }
 
+// parseStringList parses a comma delimited list of strings.
+func (p *Parser) parseStringList() ([]string, error) {
+	// Parse first (required) string.
+	str, err := p.parseString()
+	if err != nil {
+		return nil, err
+	}
+	strs := []string{str}
+
+	// Parse remaining (optional) strings.
+	for {
+		if tok, _, _ := p.scanIgnoreWhitespace(); tok != COMMA {
+			p.unscan()
+			return strs, nil
+		}
+
+		if str, err = p.parseString(); err != nil {
+			return nil, err
+		}
+
+		strs = append(strs, str)
+	}
+}
+
 // parseRevokeStatement parses a string and returns a revoke statement.
 // This function assumes the REVOKE token has already been consumed.
 func (p *Parser) parseRevokeStatement() (Statement, error) {
@@ -863,6 +1107,16 @@ func (p *Parser) parseShowSeriesStatement() (*ShowSeriesStatement, error) {
 	stmt := &ShowSeriesStatement{}
 	var err error
 
+	// Parse optional "EXACT COUNTS".
+	if tok, _, lit := p.scanIgnoreWhitespace(); tok == IDENT && strings.ToUpper(lit) == "EXACT" {
+		if tok, pos, lit := p.scanIgnoreWhitespace(); tok != IDENT || strings.ToUpper(lit) != "COUNTS" {
+			return nil, newParseError(tokstr(tok, lit), []string{"COUNTS"}, pos)
+		}
+		stmt.ExactCounts = true
+	} else {
+		p.unscan()
+	}
+
 	// Parse optional FROM.
 	if tok, _, _ := p.scanIgnoreWhitespace(); tok == FROM {
 		if stmt.Sources, err = p.parseSources(); err != nil {
@@ -1036,128 +1290,732 @@ func (p *Parser) parseTagKeys() ([]string, error) {
 
 	var tagKeys []string
 
-	// Parse required IN or EQ token.
-	if tok, pos, lit := p.scanIgnoreWhitespace(); tok == IN {
-		// Parse required ( token.
-		if tok, pos, lit = p.scanIgnoreWhitespace(); tok != LPAREN {
-			return nil, newParseError(tokstr(tok, lit), []string{"("}, pos)
-		}
+	// Parse required IN or EQ token.
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok == IN {
+		// Parse required ( token.
+		if tok, pos, lit = p.scanIgnoreWhitespace(); tok != LPAREN {
+			return nil, newParseError(tokstr(tok, lit), []string{"("}, pos)
+		}
+
+		// Parse tag key list.
+		if tagKeys, err = p.parseIdentList(); err != nil {
+			return nil, err
+		}
+
+		// Parse required ) token.
+		if tok, pos, lit = p.scanIgnoreWhitespace(); tok != RPAREN {
+			return nil, newParseError(tokstr(tok, lit), []string{"("}, pos)
+		}
+	} else if tok == EQ {
+		// Parse required tag key.
+		ident, err := p.parseIdent()
+		if err != nil {
+			return nil, err
+		}
+		tagKeys = append(tagKeys, ident)
+	} else {
+		return nil, newParseError(tokstr(tok, lit), []string{"IN", "="}, pos)
+	}
+
+	return tagKeys, nil
+}
+
+// parseShowUsersStatement parses a string and returns a ShowUsersStatement.
+// This function assumes the "SHOW USERS" tokens have been consumed.
+func (p *Parser) parseShowUsersStatement() (*ShowUsersStatement, error) {
+	return &ShowUsersStatement{}, nil
+}
+
+// parseShowFieldKeysStatement parses a string and returns a ShowSeriesStatement.
+// This function assumes the "SHOW FIELD KEYS" tokens have already been consumed.
+func (p *Parser) parseShowFieldKeysStatement() (*ShowFieldKeysStatement, error) {
+	stmt := &ShowFieldKeysStatement{}
+	var err error
+
+	// Parse optional source.
+	if tok, _, _ := p.scanIgnoreWhitespace(); tok == FROM {
+		if stmt.Sources, err = p.parseSources(); err != nil {
+			return nil, err
+		}
+	} else {
+		p.unscan()
+	}
+
+	// Parse optional "EXACT".
+	if tok, _, lit := p.scanIgnoreWhitespace(); tok == IDENT && strings.ToUpper(lit) == "EXACT" {
+		stmt.Exact = true
+	} else {
+		p.unscan()
+	}
+
+	// Parse sort: "ORDER BY FIELD+".
+	if stmt.SortFields, err = p.parseOrderBy(); err != nil {
+		return nil, err
+	}
+
+	// Parse limit: "LIMIT <n>".
+	if stmt.Limit, err = p.parseOptionalTokenAndInt(LIMIT); err != nil {
+		return nil, err
+	}
+
+	// Parse offset: "OFFSET <n>".
+	if stmt.Offset, err = p.parseOptionalTokenAndInt(OFFSET); err != nil {
+		return nil, err
+	}
+
+	return stmt, nil
+}
+
+// parseDropMeasurementStatement parses a string and returns a DropMeasurementStatement.
+// This function assumes the "DROP MEASUREMENT" tokens have already been consumed.
+func (p *Parser) parseDropMeasurementStatement() (*DropMeasurementStatement, error) {
+	stmt := &DropMeasurementStatement{}
+
+	// Parse the name of the measurement to be dropped.
+	lit, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Name = lit
+
+	// Parse condition: "WHERE EXPR". Only points in a time range can be dropped.
+	_, pos, _ := p.scanIgnoreWhitespace()
+	p.unscan()
+	if stmt.Condition, err = p.parseCondition(); err != nil {
+		return nil, err
+	} else if stmt.Condition != nil && !isTimeCondition(stmt.Condition) {
+		return nil, &ParseError{Message: "DROP MEASUREMENT only supports conditions on time", Pos: pos}
+	}
+
+	return stmt, nil
+}
+
+// isTimeCondition returns true if expr only compares time to literals, and
+// combines the comparisons with AND.
+func isTimeCondition(expr Expr) bool {
+	switch expr := expr.(type) {
+	case *ParenExpr:
+		return isTimeCondition(expr.Expr)
+	case *BinaryExpr:
+		switch expr.Op {
+		case AND:
+			return isTimeCondition(expr.LHS) && isTimeCondition(expr.RHS)
+		case EQ, LT, LTE, GT, GTE:
+			if isTimeRef(expr.LHS) {
+				return !isTimeRef(expr.RHS)
+			}
+			return isTimeRef(expr.RHS)
+		}
+	}
+	return false
+}
+
+// isTimeRef returns true if expr is a reference to time.
+func isTimeRef(expr Expr) bool {
+	ref, ok := expr.(*VarRef)
+	return ok && strings.ToLower(ref.Val) == "time"
+}
+
+// parseAlterMeasurementStatement parses a string and returns a RenameMeasurementStatement,
+// RenameTagKeyStatement or RenameTagValueStatement.
+// This function assumes the "ALTER MEASUREMENT" tokens have already been consumed.
+func (p *Parser) parseAlterMeasurementStatement() (Statement, error) {
+	// Parse the name of the measurement to be altered.
+	name, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+
+	// Consume the required RENAME token.
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != RENAME {
+		return nil, newParseError(tokstr(tok, lit), []string{"RENAME"}, pos)
+	}
+
+	tok, pos, lit := p.scanIgnoreWhitespace()
+	switch tok {
+	case TO:
+		// Parse the new name of the measurement.
+		newName, err := p.parseIdent()
+		if err != nil {
+			return nil, err
+		}
+		return &RenameMeasurementStatement{Name: name, NewName: newName}, nil
+	case TAG:
+		return p.parseRenameTagStatement(name)
+	}
+	return nil, newParseError(tokstr(tok, lit), []string{"TO", "TAG"}, pos)
+}
+
+// parseRenameTagStatement parses a string and returns a RenameTagKeyStatement
+// or RenameTagValueStatement on measurement.
+// This function assumes the "ALTER MEASUREMENT <name> RENAME TAG" tokens have already been consumed.
+func (p *Parser) parseRenameTagStatement(measurement string) (Statement, error) {
+	// Parse the tag key.
+	key, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+
+	tok, pos, lit := p.scanIgnoreWhitespace()
+	if tok == TO {
+		// Parse the new tag key.
+		newKey, err := p.parseIdent()
+		if err != nil {
+			return nil, err
+		}
+		return &RenameTagKeyStatement{Measurement: measurement, Key: key, NewKey: newKey}, nil
+	} else if tok != IDENT || strings.ToUpper(lit) != "VALUE" {
+		return nil, newParseError(tokstr(tok, lit), []string{"TO", "VALUE"}, pos)
+	}
+
+	stmt := &RenameTagValueStatement{Measurement: measurement, Key: key}
+
+	// Parse the tag value.
+	if stmt.Value, err = p.parseString(); err != nil {
+		return nil, err
+	}
+
+	// Consume the required TO token.
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != TO {
+		return nil, newParseError(tokstr(tok, lit), []string{"TO"}, pos)
+	}
+
+	// Parse the new tag value. Series can't have empty tag values.
+	if tok, pos, lit = p.scanIgnoreWhitespace(); tok != STRING {
+		return nil, newParseError(tokstr(tok, lit), []string{"string"}, pos)
+	} else if lit == "" {
+		return nil, &ParseError{Message: "tag value must not be empty", Pos: pos}
+	}
+	stmt.NewValue = lit
+
+	return stmt, nil
+}
+
+// parseDropSeriesStatement parses a string and returns a DropSeriesStatement.
+// This function assumes the "DROP SERIES" tokens have already been consumed.
+func (p *Parser) parseDropSeriesStatement() (*DropSeriesStatement, error) {
+	stmt := &DropSeriesStatement{}
+	var err error
+
+	tok, pos, lit := p.scanIgnoreWhitespace()
+
+	if tok == FROM {
+		// Parse source.
+		if stmt.Sources, err = p.parseSources(); err != nil {
+			return nil, err
+		}
+	} else {
+		p.unscan()
+	}
+
+	// Parse condition: "WHERE EXPR".
+	if stmt.Condition, err = p.parseCondition(); err != nil {
+		return nil, err
+	}
+
+	// If they didn't provide a FROM or a WHERE, this query is invalid
+	if stmt.Condition == nil && stmt.Sources == nil {
+		return nil, newParseError(tokstr(tok, lit), []string{"FROM", "WHERE"}, pos)
+	}
+
+	return stmt, nil
+}
+
+// parseShowContinuousQueriesStatement parses a string and returns a ShowContinuousQueriesStatement
+// or a ShowContinuousQueriesStatusStatement.
+// This function assumes the "SHOW CONTINUOUS" tokens have already been consumed.
+func (p *Parser) parseShowContinuousQueriesStatement() (Statement, error) {
+	// Expect a "QUERIES" token.
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != QUERIES {
+		return nil, newParseError(tokstr(tok, lit), []string{"QUERIES"}, pos)
+	}
+
+	// Check for the optional "STATUS" token.
+	if tok, _, _ := p.scanIgnoreWhitespace(); tok == STATUS {
+		return &ShowContinuousQueriesStatusStatement{}, nil
+	}
+	p.unscan()
+
+	return &ShowContinuousQueriesStatement{}, nil
+}
+
+// parseShowHintedHandoffStatement parses a string and returns a ShowHintedHandoffStatement.
+// This function assumes the "SHOW HINTED" tokens have already been consumed.
+func (p *Parser) parseShowHintedHandoffStatement() (*ShowHintedHandoffStatement, error) {
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != HANDOFF {
+		return nil, newParseError(tokstr(tok, lit), []string{"HANDOFF"}, pos)
+	}
+	return &ShowHintedHandoffStatement{}, nil
+}
+
+// parseDropHintedHandoffStatement parses a string and returns a DropHintedHandoffStatement.
+// This function assumes the "DROP HINTED" tokens have already been consumed.
+func (p *Parser) parseDropHintedHandoffStatement() (*DropHintedHandoffStatement, error) {
+	stmt := &DropHintedHandoffStatement{}
+
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != HANDOFF {
+		return nil, newParseError(tokstr(tok, lit), []string{"HANDOFF"}, pos)
+	}
+
+	// Parse the optional node the queue belongs to.
+	if tok, _, _ := p.scanIgnoreWhitespace(); tok == FOR {
+		id, err := p.parseUInt64()
+		if err != nil {
+			return nil, err
+		}
+		stmt.NodeID = id
+	} else {
+		p.unscan()
+	}
+
+	return stmt, nil
+}
+
+// parseRenameDatabaseStatement parses a string and returns a RenameDatabaseStatement.
+// This function assumes the RENAME token has already been consumed.
+func (p *Parser) parseRenameDatabaseStatement() (*RenameDatabaseStatement, error) {
+	stmt := &RenameDatabaseStatement{}
+
+	// Consume the required DATABASE token.
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != DATABASE {
+		return nil, newParseError(tokstr(tok, lit), []string{"DATABASE"}, pos)
+	}
+
+	// Parse the name of the database to be renamed.
+	lit, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Name = lit
+
+	// Consume the required TO token.
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != TO {
+		return nil, newParseError(tokstr(tok, lit), []string{"TO"}, pos)
+	}
+
+	// Parse the new name of the database.
+	lit, err = p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	stmt.NewName = lit
+
+	return stmt, nil
+}
+
+// parseCopyShardStatement parses a string and returns a CopyShardStatement.
+// This function assumes the COPY token has already been consumed.
+func (p *Parser) parseCopyShardStatement() (*CopyShardStatement, error) {
+	id, from, to, err := p.parseShardTransfer()
+	if err != nil {
+		return nil, err
+	}
+	return &CopyShardStatement{ShardID: id, From: from, To: to}, nil
+}
+
+// parseMoveShardStatement parses a string and returns a MoveShardStatement.
+// This function assumes the MOVE token has already been consumed.
+func (p *Parser) parseMoveShardStatement() (*MoveShardStatement, error) {
+	id, from, to, err := p.parseShardTransfer()
+	if err != nil {
+		return nil, err
+	}
+	return &MoveShardStatement{ShardID: id, From: from, To: to}, nil
+}
+
+// parseShardTransfer parses "SHARD <id> FROM <node> TO <node>" and returns
+// the shard and node IDs.
+func (p *Parser) parseShardTransfer() (id, from, to uint64, err error) {
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != SHARD {
+		return 0, 0, 0, newParseError(tokstr(tok, lit), []string{"SHARD"}, pos)
+	}
+	if id, err = p.parseUInt64(); err != nil {
+		return 0, 0, 0, err
+	}
+
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != FROM {
+		return 0, 0, 0, newParseError(tokstr(tok, lit), []string{"FROM"}, pos)
+	}
+	if from, err = p.parseUInt64(); err != nil {
+		return 0, 0, 0, err
+	}
+
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != TO {
+		return 0, 0, 0, newParseError(tokstr(tok, lit), []string{"TO"}, pos)
+	}
+	if to, err = p.parseUInt64(); err != nil {
+		return 0, 0, 0, err
+	}
+
+	if from == to {
+		return 0, 0, 0, fmt.Errorf("source and destination nodes must differ")
+	}
+	return id, from, to, nil
+}
+
+// parseDropDataNodeStatement parses a string and returns a DropDataNodeStatement.
+// This function assumes the "DROP DATA" tokens have already been consumed.
+// DATA, NODE and FORCE are matched as identifiers so they aren't reserved.
+func (p *Parser) parseDropDataNodeStatement() (*DropDataNodeStatement, error) {
+	stmt := &DropDataNodeStatement{}
+
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != IDENT || strings.ToUpper(lit) != "NODE" {
+		return nil, newParseError(tokstr(tok, lit), []string{"NODE"}, pos)
+	}
+
+	id, err := p.parseUInt64()
+	if err != nil {
+		return nil, err
+	}
+	stmt.NodeID = id
+
+	// Parse the optional FORCE.
+	if tok, _, lit := p.scanIgnoreWhitespace(); tok == IDENT && strings.ToUpper(lit) == "FORCE" {
+		stmt.Force = true
+	} else {
+		p.unscan()
+	}
+
+	return stmt, nil
+}
+
+// parseCreateTagRuleStatement parses a string and returns a CreateTagRuleStatement.
+// This function assumes the "CREATE TAG RULE" tokens have already been consumed.
+// RULE and the actions are matched as identifiers so they aren't reserved.
+func (p *Parser) parseCreateTagRuleStatement() (*CreateTagRuleStatement, error) {
+	stmt := &CreateTagRuleStatement{}
+
+	// Read the name of the rule.
+	ident, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Name = ident
+
+	// Expect an "ON" keyword.
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != ON {
+		return nil, newParseError(tokstr(tok, lit), []string{"ON"}, pos)
+	}
+
+	// Read the name of the database.
+	if ident, err = p.parseIdent(); err != nil {
+		return nil, err
+	}
+	stmt.Database = ident
+
+	// Parse the optional tag key.
+	if tok, _, _ := p.scanIgnoreWhitespace(); tok == KEY {
+		if ident, err = p.parseIdent(); err != nil {
+			return nil, err
+		}
+		stmt.Key = ident
+	} else {
+		p.unscan()
+	}
+
+	// Parse the action.
+	tok, pos, lit := p.scanIgnoreWhitespace()
+	if tok == IDENT {
+		stmt.Action = strings.ToLower(lit)
+	}
+	switch stmt.Action {
+	case "lowercase", "trim":
+	case "map":
+		if stmt.Mappings, err = p.parseTagValueMappings(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, newParseError(tokstr(tok, lit), []string{"LOWERCASE", "TRIM", "MAP"}, pos)
+	}
+
+	return stmt, nil
+}
+
+// parseCreateSeriesStatement parses a string and returns a CreateSeriesStatement.
+// This function assumes the "CREATE SERIES" tokens have already been consumed.
+func (p *Parser) parseCreateSeriesStatement() (*CreateSeriesStatement, error) {
+	stmt := &CreateSeriesStatement{Tags: make(map[string]string)}
+
+	// Parse the measurement of the series.
+	_, pos, _ := p.scanIgnoreWhitespace()
+	p.unscan()
+	source, err := p.parseSource()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Measurement = source.(*Measurement)
+	if stmt.Measurement.Regex != nil {
+		return nil, &ParseError{Message: "CREATE SERIES requires a measurement name", Pos: pos}
+	}
+
+	// Parse the optional tag set: "WITH TAGS key = 'value', ...".
+	if tok, _, _ := p.scanIgnoreWhitespace(); tok != WITH {
+		p.unscan()
+		return stmt, nil
+	}
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != IDENT || strings.ToUpper(lit) != "TAGS" {
+		return nil, newParseError(tokstr(tok, lit), []string{"TAGS"}, pos)
+	}
+	for {
+		key, err := p.parseIdent()
+		if err != nil {
+			return nil, err
+		}
+
+		if tok, pos, lit := p.scanIgnoreWhitespace(); tok != EQ {
+			return nil, newParseError(tokstr(tok, lit), []string{"="}, pos)
+		}
+
+		value, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := stmt.Tags[key]; ok {
+			return nil, fmt.Errorf("duplicate tag key %s", QuoteIdent(key))
+		}
+		stmt.Tags[key] = value
+
+		if tok, _, _ := p.scanIgnoreWhitespace(); tok != COMMA {
+			p.unscan()
+			return stmt, nil
+		}
+	}
+}
+
+// parseTagValueMappings parses a list of "'from' TO 'to'" pairs.
+func (p *Parser) parseTagValueMappings() (map[string]string, error) {
+	m := make(map[string]string)
+	for {
+		from, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+
+		if tok, pos, lit := p.scanIgnoreWhitespace(); tok != TO {
+			return nil, newParseError(tokstr(tok, lit), []string{"TO"}, pos)
+		}
+
+		to, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := m[from]; ok {
+			return nil, fmt.Errorf("duplicate mapping for %s", QuoteString(from))
+		}
+		m[from] = to
+
+		if tok, _, _ := p.scanIgnoreWhitespace(); tok != COMMA {
+			p.unscan()
+			return m, nil
+		}
+	}
+}
+
+// parseDropTagRuleStatement parses a string and returns a DropTagRuleStatement.
+// This function assumes the "DROP TAG RULE" tokens have already been consumed.
+func (p *Parser) parseDropTagRuleStatement() (*DropTagRuleStatement, error) {
+	stmt := &DropTagRuleStatement{}
+
+	// Read the name of the rule.
+	ident, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Name = ident
+
+	// Expect an "ON" keyword.
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != ON {
+		return nil, newParseError(tokstr(tok, lit), []string{"ON"}, pos)
+	}
+
+	// Read the name of the database.
+	if ident, err = p.parseIdent(); err != nil {
+		return nil, err
+	}
+	stmt.Database = ident
+
+	return stmt, nil
+}
 
-		// Parse tag key list.
-		if tagKeys, err = p.parseIdentList(); err != nil {
-			return nil, err
-		}
+// parseShowTagRulesStatement parses a string and returns a ShowTagRulesStatement.
+// This function assumes the "SHOW TAG RULES" tokens have already been consumed.
+func (p *Parser) parseShowTagRulesStatement() (*ShowTagRulesStatement, error) {
+	stmt := &ShowTagRulesStatement{}
 
-		// Parse required ) token.
-		if tok, pos, lit = p.scanIgnoreWhitespace(); tok != RPAREN {
-			return nil, newParseError(tokstr(tok, lit), []string{"("}, pos)
-		}
-	} else if tok == EQ {
-		// Parse required tag key.
+	// Parse the optional database.
+	if tok, _, _ := p.scanIgnoreWhitespace(); tok == ON {
 		ident, err := p.parseIdent()
 		if err != nil {
 			return nil, err
 		}
-		tagKeys = append(tagKeys, ident)
+		stmt.Database = ident
 	} else {
-		return nil, newParseError(tokstr(tok, lit), []string{"IN", "="}, pos)
+		p.unscan()
 	}
 
-	return tagKeys, nil
-}
-
-// parseShowUsersStatement parses a string and returns a ShowUsersStatement.
-// This function assumes the "SHOW USERS" tokens have been consumed.
-func (p *Parser) parseShowUsersStatement() (*ShowUsersStatement, error) {
-	return &ShowUsersStatement{}, nil
+	return stmt, nil
 }
 
-// parseShowFieldKeysStatement parses a string and returns a ShowSeriesStatement.
-// This function assumes the "SHOW FIELD KEYS" tokens have already been consumed.
-func (p *Parser) parseShowFieldKeysStatement() (*ShowFieldKeysStatement, error) {
-	stmt := &ShowFieldKeysStatement{}
-	var err error
+// DownsampleAggregates lists the aggregates a downsample policy can apply.
+var DownsampleAggregates = []string{"count", "first", "last", "max", "mean", "median", "min", "spread", "stddev", "sum"}
 
-	// Parse optional source.
-	if tok, _, _ := p.scanIgnoreWhitespace(); tok == FROM {
-		if stmt.Sources, err = p.parseSources(); err != nil {
-			return nil, err
+// isDownsampleAggregate returns true if name is a downsample aggregate.
+func isDownsampleAggregate(name string) bool {
+	for _, a := range DownsampleAggregates {
+		if a == name {
+			return true
 		}
-	} else {
-		p.unscan()
 	}
+	return false
+}
 
-	// Parse sort: "ORDER BY FIELD+".
-	if stmt.SortFields, err = p.parseOrderBy(); err != nil {
+// parseCreateDownsamplePolicyStatement parses a string and returns a CreateDownsamplePolicyStatement.
+// This function assumes the "CREATE DOWNSAMPLE POLICY" tokens have already been consumed.
+func (p *Parser) parseCreateDownsamplePolicyStatement() (*CreateDownsamplePolicyStatement, error) {
+	stmt := &CreateDownsamplePolicyStatement{}
+
+	// Read the name of the policy.
+	ident, err := p.parseIdent()
+	if err != nil {
 		return nil, err
 	}
+	stmt.Name = ident
 
-	// Parse limit: "LIMIT <n>".
-	if stmt.Limit, err = p.parseOptionalTokenAndInt(LIMIT); err != nil {
-		return nil, err
+	// Expect an "ON" keyword.
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != ON {
+		return nil, newParseError(tokstr(tok, lit), []string{"ON"}, pos)
 	}
 
-	// Parse offset: "OFFSET <n>".
-	if stmt.Offset, err = p.parseOptionalTokenAndInt(OFFSET); err != nil {
+	// Read the name of the database.
+	if ident, err = p.parseIdent(); err != nil {
 		return nil, err
 	}
+	stmt.Database = ident
 
-	return stmt, nil
-}
+	// Read the source retention policy.
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != FROM {
+		return nil, newParseError(tokstr(tok, lit), []string{"FROM"}, pos)
+	}
+	if ident, err = p.parseIdent(); err != nil {
+		return nil, err
+	}
+	stmt.Source = ident
 
-// parseDropMeasurementStatement parses a string and returns a DropMeasurementStatement.
-// This function assumes the "DROP MEASUREMENT" tokens have already been consumed.
-func (p *Parser) parseDropMeasurementStatement() (*DropMeasurementStatement, error) {
-	stmt := &DropMeasurementStatement{}
+	// Read the target retention policy.
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != TO {
+		return nil, newParseError(tokstr(tok, lit), []string{"TO"}, pos)
+	}
+	if ident, err = p.parseIdent(); err != nil {
+		return nil, err
+	}
+	stmt.Target = ident
 
-	// Parse the name of the measurement to be dropped.
-	lit, err := p.parseIdent()
+	// Read the interval.
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != EVERY {
+		return nil, newParseError(tokstr(tok, lit), []string{"EVERY"}, pos)
+	}
+	d, err := p.parseDuration()
 	if err != nil {
 		return nil, err
+	} else if d == 0 {
+		return nil, errors.New("downsample interval must be greater than zero")
+	}
+	stmt.Interval = d
+
+	// Parse the optional aggregates.
+	if tok, _, lit := p.scanIgnoreWhitespace(); tok == IDENT && strings.ToUpper(lit) == "AGGREGATE" {
+		if err := p.parseDownsampleAggregates(stmt); err != nil {
+			return nil, err
+		}
+	} else {
+		p.unscan()
 	}
-	stmt.Name = lit
 
 	return stmt, nil
 }
 
-// parseDropSeriesStatement parses a string and returns a DropSeriesStatement.
-// This function assumes the "DROP SERIES" tokens have already been consumed.
-func (p *Parser) parseDropSeriesStatement() (*DropSeriesStatement, error) {
-	stmt := &DropSeriesStatement{}
-	var err error
+// parseDownsampleAggregates parses a list of aggregates. A bare aggregate
+// applies to all fields and "aggregate(field)" applies to a single field.
+func (p *Parser) parseDownsampleAggregates(stmt *CreateDownsamplePolicyStatement) error {
+	for {
+		tok, pos, lit := p.scanIgnoreWhitespace()
+		name := strings.ToLower(lit)
+		if tok != IDENT || !isDownsampleAggregate(name) {
+			return newParseError(tokstr(tok, lit), DownsampleAggregates, pos)
+		}
 
-	tok, pos, lit := p.scanIgnoreWhitespace()
+		if tok, _, _ := p.scanIgnoreWhitespace(); tok == LPAREN {
+			field, err := p.parseIdent()
+			if err != nil {
+				return err
+			}
+			if tok, pos, lit := p.scanIgnoreWhitespace(); tok != RPAREN {
+				return newParseError(tokstr(tok, lit), []string{")"}, pos)
+			}
 
-	if tok == FROM {
-		// Parse source.
-		if stmt.Sources, err = p.parseSources(); err != nil {
-			return nil, err
+			if _, ok := stmt.Aggregates[field]; ok {
+				return fmt.Errorf("duplicate aggregate for field %s", QuoteIdent(field))
+			} else if stmt.Aggregates == nil {
+				stmt.Aggregates = make(map[string]string)
+			}
+			stmt.Aggregates[field] = name
+		} else {
+			p.unscan()
+			if stmt.DefaultAggregate != "" {
+				return errors.New("duplicate default aggregate")
+			}
+			stmt.DefaultAggregate = name
+		}
+
+		if tok, _, _ := p.scanIgnoreWhitespace(); tok != COMMA {
+			p.unscan()
+			return nil
 		}
-	} else {
-		p.unscan()
 	}
+}
 
-	// Parse condition: "WHERE EXPR".
-	if stmt.Condition, err = p.parseCondition(); err != nil {
+// parseDropDownsamplePolicyStatement parses a string and returns a DropDownsamplePolicyStatement.
+// This function assumes the "DROP DOWNSAMPLE POLICY" tokens have already been consumed.
+func (p *Parser) parseDropDownsamplePolicyStatement() (*DropDownsamplePolicyStatement, error) {
+	stmt := &DropDownsamplePolicyStatement{}
+
+	// Read the name of the policy.
+	ident, err := p.parseIdent()
+	if err != nil {
 		return nil, err
 	}
+	stmt.Name = ident
 
-	// If they didn't provide a FROM or a WHERE, this query is invalid
-	if stmt.Condition == nil && stmt.Sources == nil {
-		return nil, newParseError(tokstr(tok, lit), []string{"FROM", "WHERE"}, pos)
+	// Expect an "ON" keyword.
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != ON {
+		return nil, newParseError(tokstr(tok, lit), []string{"ON"}, pos)
+	}
+
+	// Read the name of the database.
+	if ident, err = p.parseIdent(); err != nil {
+		return nil, err
 	}
+	stmt.Database = ident
 
 	return stmt, nil
 }
 
-// parseShowContinuousQueriesStatement parses a string and returns a ShowContinuousQueriesStatement.
-// This function assumes the "SHOW CONTINUOUS" tokens have already been consumed.
-func (p *Parser) parseShowContinuousQueriesStatement() (*ShowContinuousQueriesStatement, error) {
-	stmt := &ShowContinuousQueriesStatement{}
+// parseShowDownsamplePoliciesStatement parses a string and returns a ShowDownsamplePoliciesStatement.
+// This function assumes the "SHOW DOWNSAMPLE POLICIES" tokens have already been consumed.
+func (p *Parser) parseShowDownsamplePoliciesStatement() (*ShowDownsamplePoliciesStatement, error) {
+	stmt := &ShowDownsamplePoliciesStatement{}
 
-	// Expect a "QUERIES" token.
-	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != QUERIES {
-		return nil, newParseError(tokstr(tok, lit), []string{"QUERIES"}, pos)
+	// Parse the optional database.
+	if tok, _, _ := p.scanIgnoreWhitespace(); tok == ON {
+		ident, err := p.parseIdent()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Database = ident
+	} else {
+		p.unscan()
 	}
 
 	return stmt, nil
@@ -1225,6 +2083,15 @@ func (p *Parser) parseCreateContinuousQueryStatement() (*CreateContinuousQuerySt
 	}
 	stmt.Database = ident
 
+	// Parse the optional resample clause.
+	if tok, _, _ := p.scanIgnoreWhitespace(); tok == RESAMPLE {
+		if err := p.parseResample(stmt); err != nil {
+			return nil, err
+		}
+	} else {
+		p.unscan()
+	}
+
 	// Expect a "BEGIN SELECT" tokens.
 	if err := p.parseTokens([]Token{BEGIN, SELECT}); err != nil {
 		return nil, err
@@ -1251,6 +2118,11 @@ func (p *Parser) parseCreateContinuousQueryStatement() (*CreateContinuousQuerySt
 			}
 			return nil, newParseError(tokstr(tok, lit), expected, pos)
 		}
+
+		// The resample window must cover at least one full interval.
+		if stmt.ResampleFor != 0 && stmt.ResampleFor < d {
+			return nil, fmt.Errorf("FOR duration must be >= GROUP BY time duration: must be a minimum of %s, got %s", FormatDuration(d), FormatDuration(stmt.ResampleFor))
+		}
 	}
 
 	// Expect a "END" keyword.
@@ -1261,6 +2133,41 @@ func (p *Parser) parseCreateContinuousQueryStatement() (*CreateContinuousQuerySt
 	return stmt, nil
 }
 
+// parseResample parses the EVERY and FOR options of a RESAMPLE clause.
+// This function assumes the RESAMPLE token has already been consumed.
+func (p *Parser) parseResample(stmt *CreateContinuousQueryStatement) error {
+	var found bool
+	for {
+		tok, pos, lit := p.scanIgnoreWhitespace()
+		var d *time.Duration
+		switch {
+		case tok == EVERY && stmt.ResampleEvery == 0:
+			d = &stmt.ResampleEvery
+		case tok == FOR && stmt.ResampleFor == 0:
+			d = &stmt.ResampleFor
+		case found:
+			p.unscan()
+			return nil
+		default:
+			return newParseError(tokstr(tok, lit), []string{"EVERY", "FOR"}, pos)
+		}
+
+		// Read the duration and ensure it is positive.
+		tok, pos, lit = p.scanIgnoreWhitespace()
+		if tok != DURATION_VAL {
+			return newParseError(tokstr(tok, lit), []string{"duration"}, pos)
+		}
+		v, err := ParseDuration(lit)
+		if err != nil {
+			return &ParseError{Message: err.Error(), Pos: pos}
+		} else if v <= 0 {
+			return &ParseError{Message: "resample duration must be greater than zero", Pos: pos}
+		}
+		*d = v
+		found = true
+	}
+}
+
 // parseCreateDatabaseStatement parses a string and returns a CreateDatabaseStatement.
 // This function assumes the "CREATE DATABASE" tokens have already been consumed.
 func (p *Parser) parseCreateDatabaseStatement() (*CreateDatabaseStatement, error) {
@@ -1355,12 +2262,29 @@ func (p *Parser) parseCreateUserStatement() (*CreateUserStatement, error) {
 		return stmt, nil
 	}
 
-	// "WITH ALL PRIVILEGES" grants the new user admin privilege.
-	// Only admin privilege can be set on user creation.
-	if err := p.parseTokens([]Token{ALL, PRIVILEGES}); err != nil {
-		return nil, err
+	// "WITH ALL PRIVILEGES" grants the new user admin privilege, and
+	// "WITH WRITE ONLY ON db" makes it a user which may only write to db.
+	// No other privilege can be set on user creation.
+	tok, pos, lit := p.scanIgnoreWhitespace()
+	switch tok {
+	case ALL:
+		if err := p.parseTokens([]Token{PRIVILEGES}); err != nil {
+			return nil, err
+		}
+		stmt.Admin = true
+	case WRITE:
+		if tok, pos, lit := p.scanIgnoreWhitespace(); tok != IDENT || strings.ToUpper(lit) != "ONLY" {
+			return nil, newParseError(tokstr(tok, lit), []string{"ONLY"}, pos)
+		}
+		if err := p.parseTokens([]Token{ON}); err != nil {
+			return nil, err
+		}
+		if stmt.WriteOnlyDatabase, err = p.parseIdent(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, newParseError(tokstr(tok, lit), []string{"ALL", "WRITE"}, pos)
 	}
-	stmt.Admin = true
 
 	return stmt, nil
 }
@@ -1423,6 +2347,15 @@ func (p *Parser) parseShowStatsStatement() (*ShowStatsStatement, error) {
 	stmt := &ShowStatsStatement{}
 	var err error
 
+	// Parse optional FOR clause.
+	if tok, _, _ := p.scanIgnoreWhitespace(); tok == FOR {
+		if stmt.Database, err = p.parseString(); err != nil {
+			return nil, err
+		}
+	} else {
+		p.unscan()
+	}
+
 	if tok, _, _ := p.scanIgnoreWhitespace(); tok == ON {
 		stmt.Host, err = p.parseString()
 	} else {
@@ -1432,6 +2365,104 @@ func (p *Parser) parseShowStatsStatement() (*ShowStatsStatement, error) {
 	return stmt, err
 }
 
+// parseShowSubscriptionsStatement parses a string and returns a ShowSubscriptionsStatement.
+// This function assumes the "SHOW SUBSCRIPTIONS" tokens have already been consumed.
+func (p *Parser) parseShowSubscriptionsStatement() (*ShowSubscriptionsStatement, error) {
+	stmt := &ShowSubscriptionsStatement{}
+	return stmt, nil
+}
+
+// parseCreateSubscriptionStatement parses a string and returns a CreateSubscriptionStatement.
+// This function assumes the "CREATE SUBSCRIPTION" tokens have already been consumed.
+func (p *Parser) parseCreateSubscriptionStatement() (*CreateSubscriptionStatement, error) {
+	stmt := &CreateSubscriptionStatement{}
+
+	// Read the id of the subscription to create.
+	ident, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Name = ident
+
+	// Expect an "ON" keyword.
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != ON {
+		return nil, newParseError(tokstr(tok, lit), []string{"ON"}, pos)
+	}
+
+	// Read the name of the database.
+	if ident, err = p.parseIdent(); err != nil {
+		return nil, err
+	}
+	stmt.Database = ident
+
+	// Expect a "." between the database and retention policy.
+	if tok, pos, lit := p.scan(); tok != DOT {
+		return nil, newParseError(tokstr(tok, lit), []string{"."}, pos)
+	}
+
+	// Read the name of the retention policy.
+	if ident, err = p.parseIdent(); err != nil {
+		return nil, err
+	}
+	stmt.RetentionPolicy = ident
+
+	// Expect a "DESTINATIONS" keyword.
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != DESTINATIONS {
+		return nil, newParseError(tokstr(tok, lit), []string{"DESTINATIONS"}, pos)
+	}
+
+	// Expect one of the "ANY" or "ALL" keywords.
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok == ALL || tok == ANY {
+		stmt.Mode = tokens[tok]
+	} else {
+		return nil, newParseError(tokstr(tok, lit), []string{"ALL", "ANY"}, pos)
+	}
+
+	// Read the list of destinations.
+	if stmt.Destinations, err = p.parseStringList(); err != nil {
+		return nil, err
+	}
+
+	return stmt, nil
+}
+
+// parseDropSubscriptionStatement parses a string and returns a DropSubscriptionStatement.
+// This function assumes the "DROP SUBSCRIPTION" tokens have already been consumed.
+func (p *Parser) parseDropSubscriptionStatement() (*DropSubscriptionStatement, error) {
+	stmt := &DropSubscriptionStatement{}
+
+	// Read the id of the subscription to drop.
+	ident, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Name = ident
+
+	// Expect an "ON" keyword.
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != ON {
+		return nil, newParseError(tokstr(tok, lit), []string{"ON"}, pos)
+	}
+
+	// Read the name of the database.
+	if ident, err = p.parseIdent(); err != nil {
+		return nil, err
+	}
+	stmt.Database = ident
+
+	// Expect a "." between the database and retention policy.
+	if tok, pos, lit := p.scan(); tok != DOT {
+		return nil, newParseError(tokstr(tok, lit), []string{"."}, pos)
+	}
+
+	// Read the name of the retention policy.
+	if ident, err = p.parseIdent(); err != nil {
+		return nil, err
+	}
+	stmt.RetentionPolicy = ident
+
+	return stmt, nil
+}
+
 // parseShowDiagnostics parses a string and returns a ShowDiagnosticsStatement.
 func (p *Parser) parseShowDiagnosticsStatement() (*ShowDiagnosticsStatement, error) {
 	stmt := &ShowDiagnosticsStatement{}
@@ -2023,7 +3054,19 @@ func (p *Parser) parseUnaryExpr() (Expr, error) {
 		v, _ := ParseDuration(lit)
 		return &DurationLiteral{Val: v}, nil
 	case MUL:
-		return &Wildcard{}, nil
+		wc := &Wildcard{}
+		if ch := p.peekRune(); ch == ':' {
+			if err := p.parseTokens([]Token{COLON, COLON}); err != nil {
+				return nil, err
+			}
+			switch tok, pos, lit := p.scanIgnoreWhitespace(); tok {
+			case FIELD, TAG:
+				wc.Type = tok
+			default:
+				return nil, newParseError(tokstr(tok, lit), []string{"field", "tag"}, pos)
+			}
+		}
+		return wc, nil
 	case REGEX:
 		re, err := regexp.Compile(lit)
 		if err != nil {
@@ -2287,6 +3330,22 @@ type ParseError struct {
 	Found    string
 	Expected []string
 	Pos      Pos
+
+	// Position of the statement the error is in. Only set by
+	// ParseQueryRecover, so every skipped statement has a position.
+	StatementPos Pos
+}
+
+// ParseErrors represents the errors of several statements of a query.
+type ParseErrors []*ParseError
+
+// Error returns the errors of the statements, one per line.
+func (a ParseErrors) Error() string {
+	s := make([]string, len(a))
+	for i, e := range a {
+		s[i] = e.Error()
+	}
+	return strings.Join(s, "\n")
 }
 
 // newParseError returns a new instance of ParseError.
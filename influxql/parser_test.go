@@ -51,6 +51,36 @@ func TestParser_ParseQuery_ParseError(t *testing.T) {
 	}
 }
 
+// Ensure the parser reports the errors of every invalid statement and the
+// position of every valid one.
+func TestParser_ParseQueryRecover(t *testing.T) {
+	s := "SELECT a FROM b; SELECT FROM c;\nDELETE foo; SELECT;SHOW DATABASES"
+	q, positions, errs := influxql.ParseQueryRecover(s)
+	if len(q.Statements) != 2 {
+		t.Fatalf("unexpected statement count: %d", len(q.Statements))
+	} else if q.String() != "SELECT a FROM b;\nSHOW DATABASES" {
+		t.Fatalf("unexpected query: %s", q)
+	} else if !reflect.DeepEqual(positions, []influxql.Pos{{Line: 0, Char: 0}, {Line: 1, Char: 19}}) {
+		t.Fatalf("unexpected positions: %v", positions)
+	} else if errs.Error() != "found FROM, expected identifier, string, number, bool at line 1, char 25\n"+
+		"found foo, expected FROM at line 2, char 8\n"+
+		"found ;, expected identifier, string, number, bool at line 2, char 19" {
+		t.Fatalf("unexpected errors: %s", errs)
+	}
+	for i, exp := range []influxql.Pos{{Line: 0, Char: 17}, {Line: 1, Char: 0}, {Line: 1, Char: 12}} {
+		if errs[i].StatementPos != exp {
+			t.Fatalf("unexpected statement position of error %d: %v", i, errs[i].StatementPos)
+		}
+	}
+
+	// A valid query has no errors.
+	if q, _, errs := influxql.ParseQueryRecover(`SELECT a FROM b;`); errs != nil {
+		t.Fatalf("unexpected errors: %s", errs)
+	} else if len(q.Statements) != 1 {
+		t.Fatalf("unexpected statement count: %d", len(q.Statements))
+	}
+}
+
 // Ensure the parser can parse strings into Statement ASTs.
 func TestParser_ParseStatement(t *testing.T) {
 	// For use in various tests.
@@ -109,6 +139,20 @@ func TestParser_ParseStatement(t *testing.T) {
 			},
 		},
 
+		// SELECT * with a wildcard type
+		{
+			s: `SELECT *::field, *::tag FROM myseries GROUP BY *::tag`,
+			stmt: &influxql.SelectStatement{
+				IsRawQuery: true,
+				Fields: []*influxql.Field{
+					{Expr: &influxql.Wildcard{Type: influxql.FIELD}},
+					{Expr: &influxql.Wildcard{Type: influxql.TAG}},
+				},
+				Sources:    []influxql.Source{&influxql.Measurement{Name: "myseries"}},
+				Dimensions: []*influxql.Dimension{{Expr: &influxql.Wildcard{Type: influxql.TAG}}},
+			},
+		},
+
 		// SELECT statement
 		{
 			s: fmt.Sprintf(`SELECT mean(field1), sum(field2) ,count(field3) AS field_x FROM myseries WHERE host = 'hosta.influxdb.org' and time > '%s' GROUP BY time(10h) ORDER BY DESC LIMIT 20 OFFSET 10;`, now.UTC().Format(time.RFC3339Nano)),
@@ -165,6 +209,17 @@ func TestParser_ParseStatement(t *testing.T) {
 			},
 		},
 
+		{
+			s: `SELECT non_negative_derivative(mean(field1), 1h, 10m) FROM myseries;`,
+			stmt: &influxql.SelectStatement{
+				IsRawQuery: false,
+				Fields: []*influxql.Field{
+					{Expr: &influxql.Call{Name: "non_negative_derivative", Args: []influxql.Expr{&influxql.Call{Name: "mean", Args: []influxql.Expr{&influxql.VarRef{Val: "field1"}}}, &influxql.DurationLiteral{Val: time.Hour}, &influxql.DurationLiteral{Val: 10 * time.Minute}}}},
+				},
+				Sources: []influxql.Source{&influxql.Measurement{Name: "myseries"}},
+			},
+		},
+
 		{
 			s: `SELECT derivative(mean(field1)) FROM myseries;`,
 			stmt: &influxql.SelectStatement{
@@ -307,6 +362,27 @@ func TestParser_ParseStatement(t *testing.T) {
 			},
 		},
 
+		{
+			s: `select top(mean(field1), tag1, 2) from cpu where time > now() - 1h group by time(5m)`,
+			stmt: &influxql.SelectStatement{
+				IsRawQuery: false,
+				Fields: []*influxql.Field{
+					{Expr: &influxql.Call{Name: "top", Args: []influxql.Expr{&influxql.Call{Name: "mean", Args: []influxql.Expr{&influxql.VarRef{Val: "field1"}}}, &influxql.VarRef{Val: "tag1"}, &influxql.NumberLiteral{Val: 2}}}},
+				},
+				Sources: []influxql.Source{&influxql.Measurement{Name: "cpu"}},
+				Condition: &influxql.BinaryExpr{
+					Op:  influxql.GT,
+					LHS: &influxql.VarRef{Val: "time"},
+					RHS: &influxql.BinaryExpr{
+						Op:  influxql.SUB,
+						LHS: &influxql.Call{Name: "now"},
+						RHS: &influxql.DurationLiteral{Val: time.Hour},
+					},
+				},
+				Dimensions: []*influxql.Dimension{{Expr: &influxql.Call{Name: "time", Args: []influxql.Expr{&influxql.DurationLiteral{Val: 5 * time.Minute}}}}},
+			},
+		},
+
 		// select distinct statements
 		{
 			s: `select distinct(field1) from cpu`,
@@ -677,6 +753,29 @@ func TestParser_ParseStatement(t *testing.T) {
 			stmt: &influxql.ShowSeriesStatement{Offset: 0, Limit: 2},
 		},
 
+		// SHOW SERIES EXACT COUNTS
+		{
+			s: `SHOW SERIES EXACT COUNTS FROM cpu WHERE host = 'serverA' AND time >= '2015-10-01T00:00:00Z' LIMIT 10`,
+			stmt: &influxql.ShowSeriesStatement{
+				ExactCounts: true,
+				Sources:     []influxql.Source{&influxql.Measurement{Name: "cpu"}},
+				Condition: &influxql.BinaryExpr{
+					Op: influxql.AND,
+					LHS: &influxql.BinaryExpr{
+						Op:  influxql.EQ,
+						LHS: &influxql.VarRef{Val: "host"},
+						RHS: &influxql.StringLiteral{Val: "serverA"},
+					},
+					RHS: &influxql.BinaryExpr{
+						Op:  influxql.GTE,
+						LHS: &influxql.VarRef{Val: "time"},
+						RHS: &influxql.TimeLiteral{Val: mustParseTime("2015-10-01T00:00:00Z")},
+					},
+				},
+				Limit: 10,
+			},
+		},
+
 		// SHOW SERIES WHERE with ORDER BY and LIMIT
 		{
 			skip: true,
@@ -881,6 +980,18 @@ func TestParser_ParseStatement(t *testing.T) {
 				},
 			},
 		},
+		{
+			s: `SHOW FIELD KEYS FROM cpu EXACT LIMIT 10`,
+			stmt: &influxql.ShowFieldKeysStatement{
+				Sources: []influxql.Source{&influxql.Measurement{Name: "cpu"}},
+				Exact:   true,
+				Limit:   10,
+			},
+		},
+		{
+			s:    `SHOW FIELD KEYS EXACT`,
+			stmt: &influxql.ShowFieldKeysStatement{Exact: true},
+		},
 
 		// DROP SERIES statement
 		{
@@ -915,6 +1026,78 @@ func TestParser_ParseStatement(t *testing.T) {
 			stmt: &influxql.ShowContinuousQueriesStatement{},
 		},
 
+		// SHOW HINTED HANDOFF statement
+		{
+			s:    `SHOW HINTED HANDOFF`,
+			stmt: &influxql.ShowHintedHandoffStatement{},
+		},
+
+		// DROP HINTED HANDOFF statement
+		{
+			s:    `DROP HINTED HANDOFF`,
+			stmt: &influxql.DropHintedHandoffStatement{},
+		},
+
+		// DROP HINTED HANDOFF FOR <node> statement
+		{
+			s:    `DROP HINTED HANDOFF FOR 2`,
+			stmt: &influxql.DropHintedHandoffStatement{NodeID: 2},
+		},
+
+		// DROP DATA NODE statement
+		{
+			s:    `DROP DATA NODE 2`,
+			stmt: &influxql.DropDataNodeStatement{NodeID: 2},
+		},
+
+		// DROP DATA NODE ... FORCE statement
+		{
+			s:    `DROP DATA NODE 2 FORCE`,
+			stmt: &influxql.DropDataNodeStatement{NodeID: 2, Force: true},
+		},
+
+		// COPY SHARD statement
+		{
+			s:    `COPY SHARD 3 FROM 1 TO 2`,
+			stmt: &influxql.CopyShardStatement{ShardID: 3, From: 1, To: 2},
+		},
+
+		// MOVE SHARD statement
+		{
+			s:    `MOVE SHARD 3 FROM 1 TO 2`,
+			stmt: &influxql.MoveShardStatement{ShardID: 3, From: 1, To: 2},
+		},
+
+		// RENAME DATABASE statement
+		{
+			s:    `RENAME DATABASE db0 TO db1`,
+			stmt: &influxql.RenameDatabaseStatement{Name: "db0", NewName: "db1"},
+		},
+
+		// ALTER MEASUREMENT ... RENAME TO statement
+		{
+			s:    `ALTER MEASUREMENT cpu RENAME TO "cpu.total"`,
+			stmt: &influxql.RenameMeasurementStatement{Name: "cpu", NewName: "cpu.total"},
+		},
+
+		// ALTER MEASUREMENT ... RENAME TAG ... TO statement
+		{
+			s:    `ALTER MEASUREMENT cpu RENAME TAG host TO hostname`,
+			stmt: &influxql.RenameTagKeyStatement{Measurement: "cpu", Key: "host", NewKey: "hostname"},
+		},
+
+		// ALTER MEASUREMENT ... RENAME TAG ... VALUE ... TO statement
+		{
+			s:    `ALTER MEASUREMENT cpu RENAME TAG region VALUE 'us-east' TO 'us-east-1'`,
+			stmt: &influxql.RenameTagValueStatement{Measurement: "cpu", Key: "region", Value: "us-east", NewValue: "us-east-1"},
+		},
+
+		// SHOW CONTINUOUS QUERIES STATUS statement
+		{
+			s:    `SHOW CONTINUOUS QUERIES STATUS`,
+			stmt: &influxql.ShowContinuousQueriesStatusStatement{},
+		},
+
 		// CREATE CONTINUOUS QUERY ... INTO <measurement>
 		{
 			s: `CREATE CONTINUOUS QUERY myquery ON testdb BEGIN SELECT count(field1) INTO measure1 FROM myseries GROUP BY time(5m) END`,
@@ -953,6 +1136,48 @@ func TestParser_ParseStatement(t *testing.T) {
 			},
 		},
 
+		// CREATE CONTINUOUS QUERY ... RESAMPLE EVERY <duration> FOR <duration>
+		{
+			s: `CREATE CONTINUOUS QUERY myquery ON testdb RESAMPLE EVERY 1m FOR 1h BEGIN SELECT count(field1) INTO measure1 FROM myseries GROUP BY time(5m) END`,
+			stmt: &influxql.CreateContinuousQueryStatement{
+				Name:          "myquery",
+				Database:      "testdb",
+				ResampleEvery: time.Minute,
+				ResampleFor:   time.Hour,
+				Source: &influxql.SelectStatement{
+					Fields:  []*influxql.Field{{Expr: &influxql.Call{Name: "count", Args: []influxql.Expr{&influxql.VarRef{Val: "field1"}}}}},
+					Target:  &influxql.Target{Measurement: &influxql.Measurement{Name: "measure1", IsTarget: true}},
+					Sources: []influxql.Source{&influxql.Measurement{Name: "myseries"}},
+					Dimensions: []*influxql.Dimension{
+						{
+							Expr: &influxql.Call{
+								Name: "time",
+								Args: []influxql.Expr{
+									&influxql.DurationLiteral{Val: 5 * time.Minute},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+
+		// CREATE CONTINUOUS QUERY ... RESAMPLE FOR <duration>
+		{
+			s: `CREATE CONTINUOUS QUERY myquery ON testdb RESAMPLE FOR 2h BEGIN SELECT value INTO measure1 FROM myseries END`,
+			stmt: &influxql.CreateContinuousQueryStatement{
+				Name:        "myquery",
+				Database:    "testdb",
+				ResampleFor: 2 * time.Hour,
+				Source: &influxql.SelectStatement{
+					IsRawQuery: true,
+					Fields:     []*influxql.Field{{Expr: &influxql.VarRef{Val: "value"}}},
+					Target:     &influxql.Target{Measurement: &influxql.Measurement{Name: "measure1", IsTarget: true}},
+					Sources:    []influxql.Source{&influxql.Measurement{Name: "myseries"}},
+				},
+			},
+		},
+
 		// CREATE CONTINUOUS QUERY ... INTO <retention-policy>.<measurement>
 		{
 			s: `CREATE CONTINUOUS QUERY myquery ON testdb BEGIN SELECT count(field1) INTO "1h.policy1"."cpu.load" FROM myseries GROUP BY time(5m) END`,
@@ -1075,6 +1300,16 @@ func TestParser_ParseStatement(t *testing.T) {
 			},
 		},
 
+		// CREATE USER ... WITH WRITE ONLY ON
+		{
+			s: `CREATE USER agent WITH PASSWORD 'pwd1337' WITH WRITE ONLY ON db0`,
+			stmt: &influxql.CreateUserStatement{
+				Name:              "agent",
+				Password:          "pwd1337",
+				WriteOnlyDatabase: "db0",
+			},
+		},
+
 		// SET PASSWORD FOR USER
 		{
 			s: `SET PASSWORD FOR testuser = 'pwd1337'`,
@@ -1084,6 +1319,35 @@ func TestParser_ParseStatement(t *testing.T) {
 			},
 		},
 
+		// SET LIMITS FOR USER
+		{
+			s: `SET LIMITS FOR testuser MAX QUERIES 2 MAX RANGE 7d MAX POINTS 1000000`,
+			stmt: &influxql.SetUserLimitsStatement{
+				Name:       "testuser",
+				MaxQueries: 2,
+				MaxRange:   7 * 24 * time.Hour,
+				MaxPoints:  1000000,
+			},
+		},
+
+		// SET LIMITS FOR USER with a priority
+		{
+			s:    `SET LIMITS FOR testuser PRIORITY BATCH MAX QUERIES 2`,
+			stmt: &influxql.SetUserLimitsStatement{Name: "testuser", MaxQueries: 2, Batch: true},
+		},
+
+		// SET LIMITS FOR USER without limits
+		{
+			s:    `SET LIMITS FOR testuser`,
+			stmt: &influxql.SetUserLimitsStatement{Name: "testuser"},
+		},
+
+		// SHOW LIMITS
+		{
+			s:    `SHOW LIMITS`,
+			stmt: &influxql.ShowUserLimitsStatement{},
+		},
+
 		// DROP CONTINUOUS QUERY statement
 		{
 			s:    `DROP CONTINUOUS QUERY myquery ON foo`,
@@ -1101,6 +1365,40 @@ func TestParser_ParseStatement(t *testing.T) {
 			s:    `DROP MEASUREMENT cpu`,
 			stmt: &influxql.DropMeasurementStatement{Name: "cpu"},
 		},
+		{
+			s: `DROP MEASUREMENT cpu WHERE time < '2015-10-01T00:00:00Z'`,
+			stmt: &influxql.DropMeasurementStatement{
+				Name: "cpu",
+				Condition: &influxql.BinaryExpr{
+					Op:  influxql.LT,
+					LHS: &influxql.VarRef{Val: "time"},
+					RHS: &influxql.TimeLiteral{Val: mustParseTime("2015-10-01T00:00:00Z")},
+				},
+			},
+		},
+		{
+			s: `DROP MEASUREMENT cpu WHERE now() - 30d > time AND (time >= '2015-10-01T00:00:00Z')`,
+			stmt: &influxql.DropMeasurementStatement{
+				Name: "cpu",
+				Condition: &influxql.BinaryExpr{
+					Op: influxql.AND,
+					LHS: &influxql.BinaryExpr{
+						Op: influxql.GT,
+						LHS: &influxql.BinaryExpr{
+							Op:  influxql.SUB,
+							LHS: &influxql.Call{Name: "now"},
+							RHS: &influxql.DurationLiteral{Val: 30 * 24 * time.Hour},
+						},
+						RHS: &influxql.VarRef{Val: "time"},
+					},
+					RHS: &influxql.ParenExpr{Expr: &influxql.BinaryExpr{
+						Op:  influxql.GTE,
+						LHS: &influxql.VarRef{Val: "time"},
+						RHS: &influxql.TimeLiteral{Val: mustParseTime("2015-10-01T00:00:00Z")},
+					}},
+				},
+			},
+		},
 
 		// DROP RETENTION POLICY
 		{
@@ -1263,6 +1561,32 @@ func TestParser_ParseStatement(t *testing.T) {
 			},
 		},
 
+		// CREATE RETENTION POLICY ... RESOLUTION
+		{
+			s: `CREATE RETENTION POLICY policy1 ON testdb DURATION 52w REPLICATION 1 RESOLUTION 5m DEFAULT`,
+			stmt: &influxql.CreateRetentionPolicyStatement{
+				Name:        "policy1",
+				Database:    "testdb",
+				Duration:    52 * 7 * 24 * time.Hour,
+				Replication: 1,
+				Resolution:  5 * time.Minute,
+				Default:     true,
+			},
+		},
+
+		// CREATE RETENTION POLICY ... SHARD DURATION
+		{
+			s: `CREATE RETENTION POLICY policy1 ON testdb DURATION 1d REPLICATION 1 SHARD DURATION 1h RESOLUTION 5m`,
+			stmt: &influxql.CreateRetentionPolicyStatement{
+				Name:               "policy1",
+				Database:           "testdb",
+				Duration:           24 * time.Hour,
+				Replication:        1,
+				ShardGroupDuration: time.Hour,
+				Resolution:         5 * time.Minute,
+			},
+		},
+
 		// ALTER RETENTION POLICY
 		{
 			s:    `ALTER RETENTION POLICY policy1 ON testdb DURATION 1m REPLICATION 4 DEFAULT`,
@@ -1304,6 +1628,17 @@ func TestParser_ParseStatement(t *testing.T) {
 			stmt: newAlterRetentionPolicyStatement("default", "testdb", -1, 4, false),
 		},
 
+		// ALTER RETENTION POLICY ... RESOLUTION
+		{
+			s: `ALTER RETENTION POLICY policy1 ON testdb RESOLUTION 1h DURATION 52w`,
+			stmt: &influxql.AlterRetentionPolicyStatement{
+				Name:       "policy1",
+				Database:   "testdb",
+				Duration:   durationPtr(52 * 7 * 24 * time.Hour),
+				Resolution: durationPtr(time.Hour),
+			},
+		},
+
 		// SHOW STATS
 		{
 			s: `SHOW STATS`,
@@ -1323,6 +1658,19 @@ func TestParser_ParseStatement(t *testing.T) {
 				Host: "192.167.1.44",
 			},
 		},
+		{
+			s: `SHOW STATS FOR 'db0'`,
+			stmt: &influxql.ShowStatsStatement{
+				Database: "db0",
+			},
+		},
+		{
+			s: `SHOW STATS FOR 'db0' ON 'servera'`,
+			stmt: &influxql.ShowStatsStatement{
+				Database: "db0",
+				Host:     "servera",
+			},
+		},
 
 		// SHOW SHARDS
 		{
@@ -1336,11 +1684,141 @@ func TestParser_ParseStatement(t *testing.T) {
 			stmt: &influxql.ShowDiagnosticsStatement{},
 		},
 
+		// CREATE SUBSCRIPTION
+		{
+			s: `CREATE SUBSCRIPTION "name" ON "db"."rp" DESTINATIONS ANY 'udp://host1:9093', 'udp://host2:9093'`,
+			stmt: &influxql.CreateSubscriptionStatement{
+				Name:            "name",
+				Database:        "db",
+				RetentionPolicy: "rp",
+				Destinations:    []string{"udp://host1:9093", "udp://host2:9093"},
+				Mode:            "ANY",
+			},
+		},
+
+		// DROP SUBSCRIPTION
+		{
+			s: `DROP SUBSCRIPTION "name" ON "db"."rp"`,
+			stmt: &influxql.DropSubscriptionStatement{
+				Name:            "name",
+				Database:        "db",
+				RetentionPolicy: "rp",
+			},
+		},
+
+		// SHOW SUBSCRIPTIONS
+		{
+			s:    `SHOW SUBSCRIPTIONS`,
+			stmt: &influxql.ShowSubscriptionsStatement{},
+		},
+
+		// CREATE TAG RULE
+		{
+			s: `CREATE TAG RULE lower ON db0 LOWERCASE`,
+			stmt: &influxql.CreateTagRuleStatement{
+				Name:     "lower",
+				Database: "db0",
+				Action:   "lowercase",
+			},
+		},
+
+		// CREATE TAG RULE ... KEY ... MAP
+		{
+			s: `CREATE TAG RULE hosts ON db0 KEY host MAP 'WebA' TO 'weba', 'web-a' TO 'weba'`,
+			stmt: &influxql.CreateTagRuleStatement{
+				Name:     "hosts",
+				Database: "db0",
+				Key:      "host",
+				Action:   "map",
+				Mappings: map[string]string{"WebA": "weba", "web-a": "weba"},
+			},
+		},
+
+		// DROP TAG RULE
+		{
+			s:    `DROP TAG RULE lower ON db0`,
+			stmt: &influxql.DropTagRuleStatement{Name: "lower", Database: "db0"},
+		},
+
+		// SHOW TAG RULES
+		{
+			s:    `SHOW TAG RULES`,
+			stmt: &influxql.ShowTagRulesStatement{},
+		},
+
+		// SHOW TAG RULES ON
+		{
+			s:    `SHOW TAG RULES ON db0`,
+			stmt: &influxql.ShowTagRulesStatement{Database: "db0"},
+		},
+
+		// CREATE SERIES
+		{
+			s: `CREATE SERIES cpu`,
+			stmt: &influxql.CreateSeriesStatement{
+				Measurement: &influxql.Measurement{Name: "cpu"},
+				Tags:        map[string]string{},
+			},
+		},
+
+		// CREATE SERIES ... WITH TAGS
+		{
+			s: `CREATE SERIES db0.rp0.cpu WITH TAGS host = 'serverA', region = 'us-west'`,
+			stmt: &influxql.CreateSeriesStatement{
+				Measurement: &influxql.Measurement{Database: "db0", RetentionPolicy: "rp0", Name: "cpu"},
+				Tags:        map[string]string{"host": "serverA", "region": "us-west"},
+			},
+		},
+
+		// CREATE DOWNSAMPLE POLICY
+		{
+			s: `CREATE DOWNSAMPLE POLICY ds0 ON db0 FROM raw TO week EVERY 1h`,
+			stmt: &influxql.CreateDownsamplePolicyStatement{
+				Name:     "ds0",
+				Database: "db0",
+				Source:   "raw",
+				Target:   "week",
+				Interval: time.Hour,
+			},
+		},
+
+		// CREATE DOWNSAMPLE POLICY ... AGGREGATE
+		{
+			s: `CREATE DOWNSAMPLE POLICY ds0 ON db0 FROM raw TO week EVERY 5m AGGREGATE MEAN, max(peak), last(state)`,
+			stmt: &influxql.CreateDownsamplePolicyStatement{
+				Name:             "ds0",
+				Database:         "db0",
+				Source:           "raw",
+				Target:           "week",
+				Interval:         5 * time.Minute,
+				DefaultAggregate: "mean",
+				Aggregates:       map[string]string{"peak": "max", "state": "last"},
+			},
+		},
+
+		// DROP DOWNSAMPLE POLICY
+		{
+			s:    `DROP DOWNSAMPLE POLICY ds0 ON db0`,
+			stmt: &influxql.DropDownsamplePolicyStatement{Name: "ds0", Database: "db0"},
+		},
+
+		// SHOW DOWNSAMPLE POLICIES
+		{
+			s:    `SHOW DOWNSAMPLE POLICIES`,
+			stmt: &influxql.ShowDownsamplePoliciesStatement{},
+		},
+
+		// SHOW DOWNSAMPLE POLICIES ON
+		{
+			s:    `SHOW DOWNSAMPLE POLICIES ON db0`,
+			stmt: &influxql.ShowDownsamplePoliciesStatement{Database: "db0"},
+		},
+
 		// Errors
-		{s: ``, err: `found EOF, expected SELECT, DELETE, SHOW, CREATE, DROP, GRANT, REVOKE, ALTER, SET at line 1, char 1`},
+		{s: ``, err: `found EOF, expected SELECT, DELETE, SHOW, CREATE, DROP, GRANT, REVOKE, ALTER, SET, COPY, MOVE, RENAME at line 1, char 1`},
 		{s: `SELECT`, err: `found EOF, expected identifier, string, number, bool at line 1, char 8`},
 		{s: `SELECT time FROM myseries`, err: `at least 1 non-time field must be queried`},
-		{s: `blah blah`, err: `found blah, expected SELECT, DELETE, SHOW, CREATE, DROP, GRANT, REVOKE, ALTER, SET at line 1, char 1`},
+		{s: `blah blah`, err: `found blah, expected SELECT, DELETE, SHOW, CREATE, DROP, GRANT, REVOKE, ALTER, SET, COPY, MOVE, RENAME at line 1, char 1`},
 		{s: `SELECT field1 X`, err: `found X, expected FROM at line 1, char 15`},
 		{s: `SELECT field1 FROM "series" WHERE X +;`, err: `found ;, expected identifier, string, number, bool at line 1, char 38`},
 		{s: `SELECT field1 FROM myseries GROUP`, err: `found EOF, expected BY at line 1, char 35`},
@@ -1352,6 +1830,10 @@ func TestParser_ParseStatement(t *testing.T) {
 		{s: `SELECT top(field1,host,server,foo) FROM myseries`, err: `expected integer as last argument in top(), found foo`},
 		{s: `SELECT top(field1,5,server,2) FROM myseries`, err: `only fields or tags are allowed in top(), found 5.000`},
 		{s: `SELECT top(field1,max(foo),server,2) FROM myseries`, err: `only fields or tags are allowed in top(), found max(foo)`},
+		{s: `SELECT top(mean(field1),2) FROM myseries`, err: `tag argument required with aggregate argument in top()`},
+		{s: `SELECT top(percentile(field1, 90),host,2) FROM myseries`, err: `invalid aggregate in top(), found percentile(field1, 90.000)`},
+		{s: `SELECT top(mean(max(field1)),host,2) FROM myseries`, err: `expected field argument in mean()`},
+		{s: `SELECT bottom(mean(field1),host,2) FROM myseries`, err: `aggregate argument not supported in bottom()`},
 		{s: `SELECT bottom() FROM myseries`, err: `invalid number of arguments for bottom, expected at least 2, got 0`},
 		{s: `SELECT bottom(field1) FROM myseries`, err: `invalid number of arguments for bottom, expected at least 2, got 1`},
 		{s: `SELECT bottom(field1,foo) FROM myseries`, err: `expected integer as last argument in bottom(), found foo`},
@@ -1376,6 +1858,8 @@ func TestParser_ParseStatement(t *testing.T) {
 		{s: `SELECT count(value) FROM foo group by time(1s) where host = 'hosta.influxdb.org'`, err: `aggregate functions with GROUP BY time require a WHERE time clause`},
 		{s: `SELECT count(value) FROM foo group by time`, err: `time() is a function and expects at least one argument`},
 		{s: `SELECT count(value) FROM foo group by 'time'`, err: `only time and tag dimensions allowed`},
+		{s: `SELECT * FROM foo GROUP BY *::field`, err: `only time and tag dimensions allowed`},
+		{s: `SELECT *::value FROM foo`, err: `found value, expected field, tag at line 1, char 11`},
 		{s: `SELECT count(value) FROM foo where time > now() and time < now() group by time()`, err: `time dimension expected one argument`},
 		{s: `SELECT count(value) FROM foo where time > now() and time < now() group by time(b)`, err: `time dimension must have one duration argument`},
 		{s: `SELECT count(value) FROM foo where time > now() and time < now() group by time(1s), time(2s)`, err: `multiple time dimensions not allowed`},
@@ -1393,12 +1877,13 @@ func TestParser_ParseStatement(t *testing.T) {
 		{s: `select count(distinct(too, many, arguments)) from myseries`, err: `count(distinct <field>) can only have one argument`},
 		{s: `select count() from myseries`, err: `invalid number of arguments for count, expected 1, got 0`},
 		{s: `SELECT derivative(), field1 FROM myseries`, err: `mixing aggregate and non-aggregate queries is not supported`},
-		{s: `select derivative() from myseries`, err: `invalid number of arguments for derivative, expected at least 1 but no more than 2, got 0`},
-		{s: `select derivative(mean(value), 1h, 3) from myseries`, err: `invalid number of arguments for derivative, expected at least 1 but no more than 2, got 3`},
+		{s: `select derivative() from myseries`, err: `invalid number of arguments for derivative, expected at least 1 but no more than 3, got 0`},
+		{s: `select derivative(mean(value), 1h, 10m, 3) from myseries`, err: `invalid number of arguments for derivative, expected at least 1 but no more than 3, got 4`},
+		{s: `select derivative(mean(value), 1h, 3) from myseries`, err: `derivative requires a duration as max gap`},
 		{s: `SELECT derivative(value) FROM myseries where time < now() and time > now() - 1d`, err: `aggregate function required inside the call to derivative`},
 		{s: `SELECT non_negative_derivative(), field1 FROM myseries`, err: `mixing aggregate and non-aggregate queries is not supported`},
-		{s: `select non_negative_derivative() from myseries`, err: `invalid number of arguments for non_negative_derivative, expected at least 1 but no more than 2, got 0`},
-		{s: `select non_negative_derivative(mean(value), 1h, 3) from myseries`, err: `invalid number of arguments for non_negative_derivative, expected at least 1 but no more than 2, got 3`},
+		{s: `select non_negative_derivative() from myseries`, err: `invalid number of arguments for non_negative_derivative, expected at least 1 but no more than 3, got 0`},
+		{s: `select non_negative_derivative(mean(value), 1h, 10m, 3) from myseries`, err: `invalid number of arguments for non_negative_derivative, expected at least 1 but no more than 3, got 4`},
 		{s: `SELECT non_negative_derivative(value) FROM myseries where time < now() and time > now() - 1d`, err: `aggregate function required inside the call to non_negative_derivative`},
 		{s: `SELECT field1 from myseries WHERE host =~ 'asd' LIMIT 1`, err: `found asd, expected regex at line 1, char 42`},
 		{s: `SELECT value > 2 FROM cpu`, err: `invalid operator > in SELECT clause at line 1, char 8; operator is intended for WHERE clause`},
@@ -1408,6 +1893,8 @@ func TestParser_ParseStatement(t *testing.T) {
 		{s: `DELETE FROM`, err: `found EOF, expected identifier at line 1, char 13`},
 		{s: `DELETE FROM myseries WHERE`, err: `found EOF, expected identifier, string, number, bool at line 1, char 28`},
 		{s: `DROP MEASUREMENT`, err: `found EOF, expected identifier at line 1, char 18`},
+		{s: `DROP MEASUREMENT cpu WHERE host = 'serverA'`, err: `DROP MEASUREMENT only supports conditions on time at line 1, char 22`},
+		{s: `DROP MEASUREMENT cpu WHERE time < now() OR time > now()`, err: `DROP MEASUREMENT only supports conditions on time at line 1, char 22`},
 		{s: `DROP SERIES`, err: `found EOF, expected FROM, WHERE at line 1, char 13`},
 		{s: `DROP SERIES FROM`, err: `found EOF, expected identifier at line 1, char 18`},
 		{s: `DROP SERIES FROM src WHERE`, err: `found EOF, expected identifier, string, number, bool at line 1, char 28`},
@@ -1417,8 +1904,10 @@ func TestParser_ParseStatement(t *testing.T) {
 		{s: `SHOW RETENTION POLICIES`, err: `found EOF, expected ON at line 1, char 25`},
 		{s: `SHOW RETENTION POLICIES mydb`, err: `found mydb, expected ON at line 1, char 25`},
 		{s: `SHOW RETENTION POLICIES ON`, err: `found EOF, expected identifier at line 1, char 28`},
-		{s: `SHOW FOO`, err: `found FOO, expected CONTINUOUS, DATABASES, FIELD, GRANTS, MEASUREMENTS, RETENTION, SERIES, SERVERS, TAG, USERS at line 1, char 6`},
+		{s: `SHOW FOO`, err: `found FOO, expected CONTINUOUS, DATABASES, DOWNSAMPLE, FIELD, GRANTS, HINTED, LIMITS, MEASUREMENTS, RETENTION, SERIES, SERVERS, SUBSCRIPTIONS, TAG, USERS at line 1, char 6`},
+		{s: `SHOW SERIES EXACT cpu`, err: `found cpu, expected COUNTS at line 1, char 19`},
 		{s: `SHOW STATS ON`, err: `found EOF, expected string at line 1, char 15`},
+		{s: `SHOW STATS FOR`, err: `found EOF, expected string at line 1, char 16`},
 		{s: `SHOW GRANTS`, err: `found EOF, expected FOR at line 1, char 13`},
 		{s: `SHOW GRANTS FOR`, err: `found EOF, expected identifier at line 1, char 17`},
 		{s: `DROP CONTINUOUS`, err: `found EOF, expected QUERY at line 1, char 17`},
@@ -1427,7 +1916,47 @@ func TestParser_ParseStatement(t *testing.T) {
 		{s: `DROP CONTINUOUS QUERY myquery ON`, err: `found EOF, expected identifier at line 1, char 34`},
 		{s: `CREATE CONTINUOUS`, err: `found EOF, expected QUERY at line 1, char 19`},
 		{s: `CREATE CONTINUOUS QUERY`, err: `found EOF, expected identifier at line 1, char 25`},
-		{s: `DROP FOO`, err: `found FOO, expected SERIES, CONTINUOUS, MEASUREMENT at line 1, char 6`},
+		{s: `CREATE CONTINUOUS QUERY myquery ON testdb RESAMPLE`, err: `found EOF, expected EVERY, FOR at line 1, char 52`},
+		{s: `CREATE CONTINUOUS QUERY myquery ON testdb RESAMPLE EVERY`, err: `found EOF, expected duration at line 1, char 58`},
+		{s: `CREATE CONTINUOUS QUERY myquery ON testdb RESAMPLE EVERY 1m FOR 0s BEGIN`, err: `resample duration must be greater than zero at line 1, char 65`},
+		{s: `CREATE CONTINUOUS QUERY myquery ON testdb RESAMPLE FOR 1m BEGIN SELECT count(value) INTO cpu_count FROM cpu GROUP BY time(5m) END`, err: `FOR duration must be >= GROUP BY time duration: must be a minimum of 5m, got 1m`},
+		{s: `DROP FOO`, err: `found FOO, expected SERIES, CONTINUOUS, MEASUREMENT, SUBSCRIPTION, HINTED, DATA, TAG, DOWNSAMPLE at line 1, char 6`},
+		{s: `SHOW HINTED`, err: `found EOF, expected HANDOFF at line 1, char 13`},
+		{s: `DROP HINTED HANDOFF FOR`, err: `found EOF, expected number at line 1, char 25`},
+		{s: `DROP DATA`, err: `found EOF, expected NODE at line 1, char 11`},
+		{s: `DROP DATA NODE`, err: `found EOF, expected number at line 1, char 16`},
+		{s: `COPY`, err: `found EOF, expected SHARD at line 1, char 6`},
+		{s: `COPY SHARD 3`, err: `found EOF, expected FROM at line 1, char 13`},
+		{s: `COPY SHARD 3 FROM 1`, err: `found EOF, expected TO at line 1, char 20`},
+		{s: `MOVE SHARD 3 FROM 1 TO`, err: `found EOF, expected number at line 1, char 24`},
+		{s: `MOVE SHARD 3 FROM 1 TO 1`, err: `source and destination nodes must differ`},
+		{s: `CREATE SUBSCRIPTION`, err: `found EOF, expected identifier at line 1, char 21`},
+		{s: `CREATE SUBSCRIPTION "name" ON "db"`, err: `found EOF, expected . at line 1, char 35`},
+		{s: `CREATE SUBSCRIPTION "name" ON "db"."rp"`, err: `found EOF, expected DESTINATIONS at line 1, char 40`},
+		{s: `CREATE SUBSCRIPTION "name" ON "db"."rp" DESTINATIONS`, err: `found EOF, expected ALL, ANY at line 1, char 54`},
+		{s: `CREATE SUBSCRIPTION "name" ON "db"."rp" DESTINATIONS ALL`, err: `found EOF, expected string at line 1, char 58`},
+		{s: `DROP SUBSCRIPTION "name" ON "db"`, err: `found EOF, expected . at line 1, char 33`},
+		{s: `CREATE TAG`, err: `found EOF, expected RULE at line 1, char 12`},
+		{s: `CREATE TAG RULE lower ON db0`, err: `found EOF, expected LOWERCASE, TRIM, MAP at line 1, char 30`},
+		{s: `CREATE TAG RULE lower ON db0 KEY host UPPERCASE`, err: `found UPPERCASE, expected LOWERCASE, TRIM, MAP at line 1, char 39`},
+		{s: `CREATE TAG RULE hosts ON db0 MAP 'a'`, err: `found EOF, expected TO at line 1, char 37`},
+		{s: `CREATE TAG RULE hosts ON db0 MAP 'a' TO 'b', 'a' TO 'c'`, err: `duplicate mapping for 'a'`},
+		{s: `DROP TAG RULE lower`, err: `found EOF, expected ON at line 1, char 21`},
+		{s: `SHOW TAG FOO`, err: `found FOO, expected KEYS, VALUES, RULES at line 1, char 10`},
+		{s: `CREATE SERIES db0../cpu/`, err: `CREATE SERIES requires a measurement name at line 1, char 15`},
+		{s: `CREATE SERIES cpu WITH host = 'serverA'`, err: `found host, expected TAGS at line 1, char 24`},
+		{s: `CREATE SERIES cpu WITH TAGS host 'serverA'`, err: `found serverA, expected = at line 1, char 33`},
+		{s: `CREATE SERIES cpu WITH TAGS host = serverA`, err: `found serverA, expected string at line 1, char 36`},
+		{s: `CREATE SERIES cpu WITH TAGS host = 'a', host = 'b'`, err: `duplicate tag key host`},
+		{s: `CREATE DOWNSAMPLE ds0`, err: `found ds0, expected POLICY at line 1, char 19`},
+		{s: `CREATE DOWNSAMPLE POLICY ds0 ON db0 TO week`, err: `found TO, expected FROM at line 1, char 37`},
+		{s: `CREATE DOWNSAMPLE POLICY ds0 ON db0 FROM raw TO week`, err: `found EOF, expected EVERY at line 1, char 54`},
+		{s: `CREATE DOWNSAMPLE POLICY ds0 ON db0 FROM raw TO week EVERY 0s`, err: `downsample interval must be greater than zero`},
+		{s: `CREATE DOWNSAMPLE POLICY ds0 ON db0 FROM raw TO week EVERY 1h AGGREGATE derivative`, err: `found derivative, expected count, first, last, max, mean, median, min, spread, stddev, sum at line 1, char 73`},
+		{s: `CREATE DOWNSAMPLE POLICY ds0 ON db0 FROM raw TO week EVERY 1h AGGREGATE max(a), min(a)`, err: `duplicate aggregate for field a`},
+		{s: `CREATE DOWNSAMPLE POLICY ds0 ON db0 FROM raw TO week EVERY 1h AGGREGATE max, min`, err: `duplicate default aggregate`},
+		{s: `DROP DOWNSAMPLE POLICY ds0`, err: `found EOF, expected ON at line 1, char 28`},
+		{s: `SHOW DOWNSAMPLE`, err: `found EOF, expected POLICIES at line 1, char 17`},
 		{s: `CREATE DATABASE`, err: `found EOF, expected identifier at line 1, char 17`},
 		{s: `CREATE DATABASE IF`, err: `found EOF, expected NOT at line 1, char 20`},
 		{s: `CREATE DATABASE IF NOT`, err: `found EOF, expected EXISTS at line 1, char 24`},
@@ -1441,8 +1970,10 @@ func TestParser_ParseStatement(t *testing.T) {
 		{s: `CREATE USER testuser`, err: `found EOF, expected WITH at line 1, char 22`},
 		{s: `CREATE USER testuser WITH`, err: `found EOF, expected PASSWORD at line 1, char 27`},
 		{s: `CREATE USER testuser WITH PASSWORD`, err: `found EOF, expected string at line 1, char 36`},
-		{s: `CREATE USER testuser WITH PASSWORD 'pwd' WITH`, err: `found EOF, expected ALL at line 1, char 47`},
+		{s: `CREATE USER testuser WITH PASSWORD 'pwd' WITH`, err: `found EOF, expected ALL, WRITE at line 1, char 47`},
 		{s: `CREATE USER testuser WITH PASSWORD 'pwd' WITH ALL`, err: `found EOF, expected PRIVILEGES at line 1, char 51`},
+		{s: `CREATE USER testuser WITH PASSWORD 'pwd' WITH WRITE ON db0`, err: `found ON, expected ONLY at line 1, char 53`},
+		{s: `CREATE USER testuser WITH PASSWORD 'pwd' WITH WRITE ONLY`, err: `found EOF, expected ON at line 1, char 58`},
 		{s: `GRANT`, err: `found EOF, expected READ, WRITE, ALL [PRIVILEGES] at line 1, char 7`},
 		{s: `GRANT BOGUS`, err: `found BOGUS, expected READ, WRITE, ALL [PRIVILEGES] at line 1, char 7`},
 		{s: `GRANT READ`, err: `found EOF, expected ON at line 1, char 12`},
@@ -1519,12 +2050,31 @@ func TestParser_ParseStatement(t *testing.T) {
 		{s: `CREATE RETENTION POLICY policy1 ON testdb DURATION 1h REPLICATION 3.14`, err: `number must be an integer at line 1, char 67`},
 		{s: `CREATE RETENTION POLICY policy1 ON testdb DURATION 1h REPLICATION 0`, err: `invalid value 0: must be 1 <= n <= 2147483647 at line 1, char 67`},
 		{s: `CREATE RETENTION POLICY policy1 ON testdb DURATION 1h REPLICATION bad`, err: `found bad, expected number at line 1, char 67`},
-		{s: `ALTER`, err: `found EOF, expected RETENTION at line 1, char 7`},
+		{s: `ALTER`, err: `found EOF, expected RETENTION, MEASUREMENT at line 1, char 7`},
 		{s: `ALTER RETENTION`, err: `found EOF, expected POLICY at line 1, char 17`},
 		{s: `ALTER RETENTION POLICY`, err: `found EOF, expected identifier at line 1, char 24`},
 		{s: `ALTER RETENTION POLICY policy1`, err: `found EOF, expected ON at line 1, char 32`}, {s: `ALTER RETENTION POLICY policy1 ON`, err: `found EOF, expected identifier at line 1, char 35`},
-		{s: `ALTER RETENTION POLICY policy1 ON testdb`, err: `found EOF, expected DURATION, RETENTION, DEFAULT at line 1, char 42`},
-		{s: `SET`, err: `found EOF, expected PASSWORD at line 1, char 5`},
+		{s: `ALTER RETENTION POLICY policy1 ON testdb`, err: `found EOF, expected DURATION, RETENTION, RESOLUTION, DEFAULT at line 1, char 42`},
+		{s: `ALTER RETENTION POLICY policy1 ON testdb RESOLUTION`, err: `found EOF, expected duration at line 1, char 53`},
+		{s: `CREATE RETENTION POLICY policy1 ON testdb DURATION 1h REPLICATION 1 RESOLUTION bad`, err: `found bad, expected duration at line 1, char 80`},
+		{s: `CREATE RETENTION POLICY policy1 ON testdb DURATION 1h REPLICATION 1 SHARD 1h`, err: `found 1h, expected DURATION at line 1, char 75`},
+		{s: `CREATE RETENTION POLICY policy1 ON testdb DURATION 1h REPLICATION 1 SHARD DURATION bad`, err: `found bad, expected duration at line 1, char 84`},
+		{s: `ALTER MEASUREMENT cpu`, err: `found EOF, expected RENAME at line 1, char 23`},
+		{s: `ALTER MEASUREMENT cpu RENAME`, err: `found EOF, expected TO, TAG at line 1, char 30`},
+		{s: `ALTER MEASUREMENT cpu RENAME TO`, err: `found EOF, expected identifier at line 1, char 33`},
+		{s: `ALTER MEASUREMENT cpu RENAME TAG host`, err: `found EOF, expected TO, VALUE at line 1, char 39`},
+		{s: `ALTER MEASUREMENT cpu RENAME TAG host VALUE a`, err: `found a, expected string at line 1, char 45`},
+		{s: `ALTER MEASUREMENT cpu RENAME TAG host VALUE 'a'`, err: `found EOF, expected TO at line 1, char 48`},
+		{s: `ALTER MEASUREMENT cpu RENAME TAG host VALUE 'a' TO ''`, err: `tag value must not be empty at line 1, char 51`},
+		{s: `RENAME`, err: `found EOF, expected DATABASE at line 1, char 8`},
+		{s: `RENAME DATABASE db0`, err: `found EOF, expected TO at line 1, char 21`},
+		{s: `RENAME DATABASE db0 TO`, err: `found EOF, expected identifier at line 1, char 24`},
+		{s: `SET`, err: `found EOF, expected PASSWORD, LIMITS at line 1, char 5`},
+		{s: `SET LIMITS`, err: `found EOF, expected FOR at line 1, char 12`},
+		{s: `SET LIMITS FOR dejan MAX`, err: `found EOF, expected QUERIES, RANGE, POINTS at line 1, char 26`},
+		{s: `SET LIMITS FOR dejan PRIORITY HIGH`, err: `found HIGH, expected BATCH, INTERACTIVE at line 1, char 31`},
+		{s: `SET LIMITS FOR dejan MAX QUERIES -1`, err: `invalid value -1: must be 0 <= n <= 2147483647 at line 1, char 34`},
+		{s: `SET LIMITS FOR dejan MAX RANGE 10`, err: `found 10, expected duration at line 1, char 32`},
 		{s: `SET PASSWORD`, err: `found EOF, expected FOR at line 1, char 14`},
 		{s: `SET PASSWORD something`, err: `found something, expected FOR at line 1, char 14`},
 		{s: `SET PASSWORD FOR`, err: `found EOF, expected identifier at line 1, char 18`},
@@ -1947,6 +2497,9 @@ func newAlterRetentionPolicyStatement(name string, DB string, d time.Duration, r
 	return stmt
 }
 
+// durationPtr returns a pointer to d.
+func durationPtr(d time.Duration) *time.Duration { return &d }
+
 // mustMarshalJSON encodes a value to JSON.
 func mustMarshalJSON(v interface{}) []byte {
 	b, err := json.Marshal(v)
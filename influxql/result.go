@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"errors"
 	"hash/fnv"
+	"math"
 	"sort"
+	"time"
 )
 
 // TagSet is a fundamental concept within the query system. It represents a composite series,
@@ -83,30 +85,151 @@ type Result struct {
 	StatementID int `json:"-"`
 	Series      Rows
 	Err         error
+
+	// Staleness is how far behind on writes the shard replicas that
+	// served the statement were known to be.
+	Staleness time.Duration
+
+	// Partial is set if the series of the statement were truncated at the
+	// limits of the server.
+	Partial bool
+
+	// Stats are the execution statistics of the statement, if they were
+	// requested. Only the last result of a statement has them.
+	Stats *Stats
+}
+
+// Stats are the execution statistics of a statement.
+type Stats struct {
+	SeriesN   int64         // Series read from the shards.
+	PointN    int64         // Points scanned in the shards.
+	CacheHitN int64         // Points scanned from in-memory caches rather than disk.
+	Duration  time.Duration // Wall time of the statement.
+}
+
+// MarshalJSON encodes the stats into JSON.
+func (s *Stats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&jsonStats{
+		SeriesN:   s.SeriesN,
+		PointN:    s.PointN,
+		CacheHitN: s.CacheHitN,
+		Duration:  s.Duration.String(),
+	})
+}
+
+// UnmarshalJSON decodes the data into the stats.
+func (s *Stats) UnmarshalJSON(b []byte) error {
+	var o jsonStats
+	if err := json.Unmarshal(b, &o); err != nil {
+		return err
+	}
+	d, err := time.ParseDuration(o.Duration)
+	if err != nil {
+		return err
+	}
+	*s = Stats{SeriesN: o.SeriesN, PointN: o.PointN, CacheHitN: o.CacheHitN, Duration: d}
+	return nil
+}
+
+// jsonStats is the JSON encoding of stats.
+type jsonStats struct {
+	SeriesN   int64  `json:"series"`
+	PointN    int64  `json:"points"`
+	CacheHitN int64  `json:"cache_hits"`
+	Duration  string `json:"duration"`
 }
 
 // MarshalJSON encodes the result into JSON.
 func (r *Result) MarshalJSON() ([]byte, error) {
 	// Define a struct that outputs "error" as a string.
 	var o struct {
-		Series []*Row `json:"series,omitempty"`
-		Err    string `json:"error,omitempty"`
+		Series    []*Row `json:"series,omitempty"`
+		Err       string `json:"error,omitempty"`
+		Staleness string `json:"staleness,omitempty"`
+		Partial   bool   `json:"partial,omitempty"`
+		Stats     *Stats `json:"stats,omitempty"`
 	}
 
 	// Copy fields to output struct.
-	o.Series = r.Series
+	o.Series = finiteRows(r.Series)
 	if r.Err != nil {
 		o.Err = r.Err.Error()
 	}
+	if r.Staleness > 0 {
+		o.Staleness = r.Staleness.String()
+	}
+	o.Partial = r.Partial
+	o.Stats = r.Stats
 
 	return json.Marshal(&o)
 }
 
+// finiteRows returns rows with their NaN values replaced by null and their
+// infinite values by the strings "+Inf" and "-Inf" since JSON has no numbers
+// for them. Rows are only copied if they have such values.
+func finiteRows(rows Rows) []*Row {
+	var a []*Row
+	for i, row := range rows {
+		var values [][]interface{}
+		for j, v := range row.Values {
+			var vals []interface{}
+			for k, val := range v {
+				f, ok := val.(float64)
+				if !ok || !(math.IsNaN(f) || math.IsInf(f, 0)) {
+					continue
+				}
+				if vals == nil {
+					vals = make([]interface{}, len(v))
+					copy(vals, v)
+				}
+				if math.IsNaN(f) {
+					vals[k] = nil
+				} else if f > 0 {
+					vals[k] = "+Inf"
+				} else {
+					vals[k] = "-Inf"
+				}
+			}
+			if vals == nil {
+				if values != nil {
+					values[j] = v
+				}
+				continue
+			}
+			if values == nil {
+				values = make([][]interface{}, len(row.Values))
+				copy(values, row.Values[:j])
+			}
+			values[j] = vals
+		}
+		if values == nil {
+			if a != nil {
+				a[i] = row
+			}
+			continue
+		}
+		if a == nil {
+			a = make([]*Row, len(rows))
+			copy(a, rows[:i])
+		}
+		other := *row
+		other.Values = values
+		a[i] = &other
+	}
+	if a == nil {
+		return rows
+	}
+	return a
+}
+
 // UnmarshalJSON decodes the data into the Result struct
 func (r *Result) UnmarshalJSON(b []byte) error {
 	var o struct {
-		Series []*Row `json:"series,omitempty"`
-		Err    string `json:"error,omitempty"`
+		Series    []*Row `json:"series,omitempty"`
+		Err       string `json:"error,omitempty"`
+		Staleness string `json:"staleness,omitempty"`
+		Partial   bool   `json:"partial,omitempty"`
+		Stats     *Stats `json:"stats,omitempty"`
 	}
 
 	err := json.Unmarshal(b, &o)
@@ -114,9 +237,16 @@ func (r *Result) UnmarshalJSON(b []byte) error {
 		return err
 	}
 	r.Series = o.Series
+	r.Partial = o.Partial
+	r.Stats = o.Stats
 	if o.Err != "" {
 		r.Err = errors.New(o.Err)
 	}
+	if o.Staleness != "" {
+		if r.Staleness, err = time.ParseDuration(o.Staleness); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -58,21 +58,25 @@ const (
 	// Keywords
 	ALL
 	ALTER
+	ANY
 	AS
 	ASC
 	BEGIN
 	BY
 	CREATE
 	CONTINUOUS
+	COPY
 	DATABASE
 	DATABASES
 	DEFAULT
 	DELETE
 	DESC
+	DESTINATIONS
 	DISTINCT
 	DROP
 	DURATION
 	END
+	EVERY
 	EXISTS
 	EXPLAIN
 	FIELD
@@ -81,6 +85,8 @@ const (
 	GRANT
 	GRANTS
 	GROUP
+	HANDOFF
+	HINTED
 	IF
 	IN
 	INF
@@ -92,6 +98,7 @@ const (
 	LIMIT
 	MEASUREMENT
 	MEASUREMENTS
+	MOVE
 	NOT
 	OFFSET
 	ON
@@ -103,7 +110,9 @@ const (
 	QUERIES
 	QUERY
 	READ
+	RENAME
 	REPLICATION
+	RESAMPLE
 	RETENTION
 	REVOKE
 	SELECT
@@ -111,11 +120,15 @@ const (
 	SERVERS
 	SET
 	SHOW
+	SHARD
 	SHARDS
 	SLIMIT
 	STATS
+	STATUS
 	DIAGNOSTICS
 	SOFFSET
+	SUBSCRIPTION
+	SUBSCRIPTIONS
 	TAG
 	TO
 	USER
@@ -166,74 +179,87 @@ var tokens = [...]string{
 	SEMICOLON: ";",
 	DOT:       ".",
 
-	ALL:          "ALL",
-	ALTER:        "ALTER",
-	AS:           "AS",
-	ASC:          "ASC",
-	BEGIN:        "BEGIN",
-	BY:           "BY",
-	CREATE:       "CREATE",
-	CONTINUOUS:   "CONTINUOUS",
-	DATABASE:     "DATABASE",
-	DATABASES:    "DATABASES",
-	DEFAULT:      "DEFAULT",
-	DELETE:       "DELETE",
-	DESC:         "DESC",
-	DROP:         "DROP",
-	DISTINCT:     "DISTINCT",
-	DURATION:     "DURATION",
-	END:          "END",
-	EXISTS:       "EXISTS",
-	EXPLAIN:      "EXPLAIN",
-	FIELD:        "FIELD",
-	FOR:          "FOR",
-	FROM:         "FROM",
-	GRANT:        "GRANT",
-	GRANTS:       "GRANTS",
-	GROUP:        "GROUP",
-	IF:           "IF",
-	IN:           "IN",
-	INF:          "INF",
-	INNER:        "INNER",
-	INSERT:       "INSERT",
-	INTO:         "INTO",
-	KEY:          "KEY",
-	KEYS:         "KEYS",
-	LIMIT:        "LIMIT",
-	MEASUREMENT:  "MEASUREMENT",
-	MEASUREMENTS: "MEASUREMENTS",
-	NOT:          "NOT",
-	OFFSET:       "OFFSET",
-	ON:           "ON",
-	ORDER:        "ORDER",
-	PASSWORD:     "PASSWORD",
-	POLICY:       "POLICY",
-	POLICIES:     "POLICIES",
-	PRIVILEGES:   "PRIVILEGES",
-	QUERIES:      "QUERIES",
-	QUERY:        "QUERY",
-	READ:         "READ",
-	REPLICATION:  "REPLICATION",
-	RETENTION:    "RETENTION",
-	REVOKE:       "REVOKE",
-	SELECT:       "SELECT",
-	SERIES:       "SERIES",
-	SERVERS:      "SERVERS",
-	SET:          "SET",
-	SHOW:         "SHOW",
-	SHARDS:       "SHARDS",
-	SLIMIT:       "SLIMIT",
-	SOFFSET:      "SOFFSET",
-	STATS:        "STATS",
-	DIAGNOSTICS:  "DIAGNOSTICS",
-	TAG:          "TAG",
-	TO:           "TO",
-	USER:         "USER",
-	USERS:        "USERS",
-	VALUES:       "VALUES",
-	WHERE:        "WHERE",
-	WITH:         "WITH",
-	WRITE:        "WRITE",
+	ALL:           "ALL",
+	ALTER:         "ALTER",
+	ANY:           "ANY",
+	AS:            "AS",
+	ASC:           "ASC",
+	BEGIN:         "BEGIN",
+	BY:            "BY",
+	CREATE:        "CREATE",
+	CONTINUOUS:    "CONTINUOUS",
+	COPY:          "COPY",
+	DATABASE:      "DATABASE",
+	DATABASES:     "DATABASES",
+	DEFAULT:       "DEFAULT",
+	DELETE:        "DELETE",
+	DESC:          "DESC",
+	DESTINATIONS:  "DESTINATIONS",
+	DROP:          "DROP",
+	DISTINCT:      "DISTINCT",
+	DURATION:      "DURATION",
+	END:           "END",
+	EVERY:         "EVERY",
+	EXISTS:        "EXISTS",
+	EXPLAIN:       "EXPLAIN",
+	FIELD:         "FIELD",
+	FOR:           "FOR",
+	FROM:          "FROM",
+	GRANT:         "GRANT",
+	GRANTS:        "GRANTS",
+	GROUP:         "GROUP",
+	HANDOFF:       "HANDOFF",
+	HINTED:        "HINTED",
+	IF:            "IF",
+	IN:            "IN",
+	INF:           "INF",
+	INNER:         "INNER",
+	INSERT:        "INSERT",
+	INTO:          "INTO",
+	KEY:           "KEY",
+	KEYS:          "KEYS",
+	LIMIT:         "LIMIT",
+	MEASUREMENT:   "MEASUREMENT",
+	MEASUREMENTS:  "MEASUREMENTS",
+	MOVE:          "MOVE",
+	NOT:           "NOT",
+	OFFSET:        "OFFSET",
+	ON:            "ON",
+	ORDER:         "ORDER",
+	PASSWORD:      "PASSWORD",
+	POLICY:        "POLICY",
+	POLICIES:      "POLICIES",
+	PRIVILEGES:    "PRIVILEGES",
+	QUERIES:       "QUERIES",
+	QUERY:         "QUERY",
+	READ:          "READ",
+	RENAME:        "RENAME",
+	REPLICATION:   "REPLICATION",
+	RESAMPLE:      "RESAMPLE",
+	RETENTION:     "RETENTION",
+	REVOKE:        "REVOKE",
+	SELECT:        "SELECT",
+	SERIES:        "SERIES",
+	SERVERS:       "SERVERS",
+	SET:           "SET",
+	SHOW:          "SHOW",
+	SHARD:         "SHARD",
+	SHARDS:        "SHARDS",
+	SLIMIT:        "SLIMIT",
+	SOFFSET:       "SOFFSET",
+	SUBSCRIPTION:  "SUBSCRIPTION",
+	SUBSCRIPTIONS: "SUBSCRIPTIONS",
+	STATS:         "STATS",
+	STATUS:        "STATUS",
+	DIAGNOSTICS:   "DIAGNOSTICS",
+	TAG:           "TAG",
+	TO:            "TO",
+	USER:          "USER",
+	USERS:         "USERS",
+	VALUES:        "VALUES",
+	WHERE:         "WHERE",
+	WITH:          "WITH",
+	WRITE:         "WRITE",
 }
 
 var keywords map[string]Token
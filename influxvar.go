@@ -13,7 +13,35 @@ var expvarMu sync.Mutex
 func NewStatistics(key, name string, tags map[string]string) *expvar.Map {
 	expvarMu.Lock()
 	defer expvarMu.Unlock()
+	return newStatistics(key, name, tags)
+}
+
+// DatabaseStatistics returns the statistics for a database, creating them on
+// first use. The statistics are shared by every subsystem so that the writes
+// and queries of a database are reported together.
+func DatabaseStatistics(database string) *expvar.Map {
+	key := "database:" + database
+
+	expvarMu.Lock()
+	defer expvarMu.Unlock()
+
+	// Return the existing values. Unlike NewStatistics, the values are not reset.
+	if m, ok := expvar.Get(key).(*expvar.Map); ok {
+		var values *expvar.Map
+		m.Do(func(kv expvar.KeyValue) {
+			if kv.Key == "values" {
+				values, _ = kv.Value.(*expvar.Map)
+			}
+		})
+		if values != nil {
+			return values
+		}
+	}
+	return newStatistics(key, "database", map[string]string{"database": database})
+}
 
+// newStatistics creates the statistics for key. expvarMu must be held.
+func newStatistics(key, name string, tags map[string]string) *expvar.Map {
 	// Add expvar for this service.
 	var v expvar.Var
 	if v = expvar.Get(key); v == nil {
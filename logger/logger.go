@@ -0,0 +1,402 @@
+// Package logger provides leveled, structured logging for the subsystems of
+// the server.
+//
+// Each subsystem logs through its own Logger. Loggers created from the same
+// Output share its writer, format and levels, which can be changed while the
+// server is running by calling Configure.
+//
+// Log entries have a message and an optional list of alternating keys and
+// values:
+//
+//     l.Info("wrote points", "database", db, "n", len(points))
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level represents the severity of a log entry.
+type Level int
+
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+// String returns the name of the level.
+func (l Level) String() string {
+	switch l {
+	case DebugLevel:
+		return "debug"
+	case InfoLevel:
+		return "info"
+	case WarnLevel:
+		return "warn"
+	case ErrorLevel:
+		return "error"
+	}
+	return fmt.Sprintf("Level(%d)", int(l))
+}
+
+// ParseLevel returns the level with the given name.
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return DebugLevel, nil
+	case "info":
+		return InfoLevel, nil
+	case "warn", "warning":
+		return WarnLevel, nil
+	case "error":
+		return ErrorLevel, nil
+	}
+	return 0, fmt.Errorf("unknown log level: %q", s)
+}
+
+const (
+	// TextFormat writes entries as lines of text with key=value fields.
+	TextFormat = "text"
+
+	// JSONFormat writes entries as one JSON object per line.
+	JSONFormat = "json"
+)
+
+const (
+	// DefaultLevel is the default minimum level of the entries written.
+	DefaultLevel = "info"
+
+	// DefaultFormat is the default format of the entries written.
+	DefaultFormat = TextFormat
+)
+
+// Config represents the configuration of an Output.
+type Config struct {
+	Level  string `toml:"level"`
+	Format string `toml:"format"`
+
+	// Levels overrides the level per subsystem, by logger name.
+	Levels map[string]string `toml:"levels"`
+}
+
+// NewConfig returns an instance of Config with defaults.
+func NewConfig() Config {
+	return Config{
+		Level:  DefaultLevel,
+		Format: DefaultFormat,
+	}
+}
+
+// Validate returns an error if the config is invalid.
+func (c Config) Validate() error {
+	_, _, err := c.parse()
+	return err
+}
+
+// parse returns the parsed default and subsystem levels.
+func (c Config) parse() (Level, map[string]Level, error) {
+	level := InfoLevel
+	if c.Level != "" {
+		l, err := ParseLevel(c.Level)
+		if err != nil {
+			return 0, nil, err
+		}
+		level = l
+	}
+
+	switch c.Format {
+	case "", TextFormat, JSONFormat:
+	default:
+		return 0, nil, fmt.Errorf("unknown log format: %q", c.Format)
+	}
+
+	levels := make(map[string]Level, len(c.Levels))
+	for name, s := range c.Levels {
+		l, err := ParseLevel(s)
+		if err != nil {
+			return 0, nil, fmt.Errorf("%s: %s", name, err)
+		}
+		levels[name] = l
+	}
+	return level, levels, nil
+}
+
+// Output writes the entries of a set of loggers.
+type Output struct {
+	mu     sync.RWMutex
+	w      io.Writer
+	format string
+	level  Level
+	levels map[string]Level
+
+	// Returns the time of an entry. Used for testing.
+	Now func() time.Time
+}
+
+// NewOutput returns a new instance of Output writing to w with the default
+// level and format.
+func NewOutput(w io.Writer) *Output {
+	return &Output{
+		w:      w,
+		format: DefaultFormat,
+		level:  InfoLevel,
+		levels: make(map[string]Level),
+		Now:    time.Now,
+	}
+}
+
+// Configure sets the format and levels of the output. It is safe to call
+// while loggers are in use. The output is unchanged if the config is invalid.
+func (o *Output) Configure(c Config) error {
+	level, levels, err := c.parse()
+	if err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.level, o.levels = level, levels
+	o.format = c.Format
+	if o.format == "" {
+		o.format = DefaultFormat
+	}
+	return nil
+}
+
+// SetOutput sets the writer entries are written to.
+func (o *Output) SetOutput(w io.Writer) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.w = w
+}
+
+// Logger returns a logger for the named subsystem.
+func (o *Output) Logger(name string) *Logger {
+	return &Logger{name: name, out: o}
+}
+
+// StdLogger returns a standard library logger for the named subsystem. Each
+// line it prints is written as an entry at the info level. It allows
+// subsystems that use a *log.Logger to share the output.
+func (o *Output) StdLogger(name string) *log.Logger {
+	return log.New(stdWriter{o.Logger(name)}, "", 0)
+}
+
+// enabled returns true if entries at level are written for a subsystem.
+func (o *Output) enabled(name string, level Level) bool {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	min, ok := o.levels[name]
+	if !ok {
+		min = o.level
+	}
+	return level >= min
+}
+
+// write formats an entry and writes it to the underlying writer.
+func (o *Output) write(name string, level Level, msg string, fields []interface{}) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	var buf bytes.Buffer
+	if o.format == JSONFormat {
+		writeJSON(&buf, o.Now(), name, level, msg, fields)
+	} else {
+		writeText(&buf, o.Now(), name, level, msg, fields)
+	}
+	o.w.Write(buf.Bytes())
+}
+
+// writeText writes an entry as a line of text.
+func writeText(buf *bytes.Buffer, t time.Time, name string, level Level, msg string, fields []interface{}) {
+	if name != "" {
+		fmt.Fprintf(buf, "[%s] ", name)
+	}
+	buf.WriteString(t.Format("2006/01/02 15:04:05 "))
+	buf.WriteString(strings.ToUpper(level.String()))
+	buf.WriteByte(' ')
+	buf.WriteString(msg)
+	for i := 0; i < len(fields); i += 2 {
+		buf.WriteByte(' ')
+		buf.WriteString(fieldKey(fields, i))
+		buf.WriteByte('=')
+		buf.WriteString(quoteText(formatText(fieldValue(fields, i))))
+	}
+	buf.WriteByte('\n')
+}
+
+// formatText returns the text representation of a field value.
+func formatText(v interface{}) string {
+	if t, ok := v.(time.Time); ok {
+		return t.UTC().Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v)
+}
+
+// quoteText quotes a value if it can't be read back from a line of text.
+func quoteText(s string) string {
+	if s == "" || strings.ContainsAny(s, " =\"\t\r\n") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// writeJSON writes an entry as a JSON object. Keys are written in order.
+func writeJSON(buf *bytes.Buffer, t time.Time, name string, level Level, msg string, fields []interface{}) {
+	buf.WriteString(`{"time":`)
+	writeJSONValue(buf, t.UTC().Format(time.RFC3339Nano))
+	buf.WriteString(`,"level":`)
+	writeJSONValue(buf, level.String())
+	if name != "" {
+		buf.WriteString(`,"subsystem":`)
+		writeJSONValue(buf, name)
+	}
+	buf.WriteString(`,"msg":`)
+	writeJSONValue(buf, msg)
+	for i := 0; i < len(fields); i += 2 {
+		buf.WriteByte(',')
+		writeJSONValue(buf, fieldKey(fields, i))
+		buf.WriteByte(':')
+		writeJSONValue(buf, fieldValue(fields, i))
+	}
+	buf.WriteString("}\n")
+}
+
+// writeJSONValue writes v as JSON. Errors and values that can't be encoded
+// are written as strings.
+func writeJSONValue(buf *bytes.Buffer, v interface{}) {
+	switch v := v.(type) {
+	case time.Time:
+		b, _ := json.Marshal(v.UTC().Format(time.RFC3339Nano))
+		buf.Write(b)
+		return
+	case error:
+		b, _ := json.Marshal(v.Error())
+		buf.Write(b)
+		return
+	case fmt.Stringer:
+		b, _ := json.Marshal(v.String())
+		buf.Write(b)
+		return
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		b, _ = json.Marshal(fmt.Sprint(v))
+	}
+	buf.Write(b)
+}
+
+// fieldKey returns the key of the field at i.
+func fieldKey(fields []interface{}, i int) string {
+	if s, ok := fields[i].(string); ok {
+		return s
+	}
+	return fmt.Sprint(fields[i])
+}
+
+// fieldValue returns the value of the field at i. A key without a value is
+// given an error value so the mistake is visible in the log.
+func fieldValue(fields []interface{}, i int) interface{} {
+	if i+1 >= len(fields) {
+		return errMissingValue
+	}
+	return fields[i+1]
+}
+
+var errMissingValue = errors.New("MISSING")
+
+// Logger writes the entries of a subsystem.
+type Logger struct {
+	name   string
+	fields []interface{}
+	out    *Output
+}
+
+// New returns a logger for the named subsystem writing to w with the default
+// level and format.
+func New(w io.Writer, name string) *Logger {
+	return NewOutput(w).Logger(name)
+}
+
+// Name returns the name of the logger's subsystem.
+func (l *Logger) Name() string { return l.name }
+
+// With returns a logger that adds the given keys and values to every entry.
+func (l *Logger) With(keyvals ...interface{}) *Logger {
+	fields := make([]interface{}, 0, len(l.fields)+len(keyvals))
+	fields = append(fields, l.fields...)
+	fields = append(fields, keyvals...)
+	return &Logger{name: l.name, fields: fields, out: l.out}
+}
+
+// Enabled returns true if entries at level are written. It can be used to
+// avoid building expensive fields for entries that would be dropped.
+func (l *Logger) Enabled(level Level) bool { return l.out.enabled(l.name, level) }
+
+// Log writes an entry at level with the given keys and values.
+func (l *Logger) Log(level Level, msg string, keyvals ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	msg = strings.TrimRight(msg, "\n")
+	fields := keyvals
+	if len(l.fields) > 0 {
+		fields = make([]interface{}, 0, len(l.fields)+len(keyvals))
+		fields = append(fields, l.fields...)
+		fields = append(fields, keyvals...)
+	}
+	l.out.write(l.name, level, msg, fields)
+}
+
+// Debug writes an entry at the debug level.
+func (l *Logger) Debug(msg string, keyvals ...interface{}) { l.Log(DebugLevel, msg, keyvals...) }
+
+// Info writes an entry at the info level.
+func (l *Logger) Info(msg string, keyvals ...interface{}) { l.Log(InfoLevel, msg, keyvals...) }
+
+// Warn writes an entry at the warn level.
+func (l *Logger) Warn(msg string, keyvals ...interface{}) { l.Log(WarnLevel, msg, keyvals...) }
+
+// Error writes an entry at the error level.
+func (l *Logger) Error(msg string, keyvals ...interface{}) { l.Log(ErrorLevel, msg, keyvals...) }
+
+// Print, Printf and Println write an entry at the info level. They allow a
+// Logger to be used in place of a *log.Logger.
+func (l *Logger) Print(v ...interface{}) {
+	if l.Enabled(InfoLevel) {
+		l.Log(InfoLevel, fmt.Sprint(v...))
+	}
+}
+
+func (l *Logger) Printf(format string, v ...interface{}) {
+	if l.Enabled(InfoLevel) {
+		l.Log(InfoLevel, fmt.Sprintf(format, v...))
+	}
+}
+
+func (l *Logger) Println(v ...interface{}) {
+	if l.Enabled(InfoLevel) {
+		l.Log(InfoLevel, fmt.Sprintln(v...))
+	}
+}
+
+// stdWriter writes each line printed by a standard library logger as an entry.
+type stdWriter struct {
+	l *Logger
+}
+
+func (w stdWriter) Write(p []byte) (int, error) {
+	w.l.Info(string(p))
+	return len(p), nil
+}
@@ -0,0 +1,82 @@
+package logger_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/logger"
+)
+
+// Ensure entries are written as text with their fields.
+func TestLogger_Text(t *testing.T) {
+	o, buf := NewOutput()
+	l := o.Logger("httpd").With("database", "db0")
+	l.Info("query failed", "query", "SELECT * FROM cpu", "err", errors.New("boom"), "n", 2)
+	l.Printf("listening on %s\n", ":8086")
+
+	if exp := "[httpd] 2000/01/01 00:00:00 INFO query failed database=db0 query=\"SELECT * FROM cpu\" err=boom n=2\n" +
+		"[httpd] 2000/01/01 00:00:00 INFO listening on :8086 database=db0\n"; buf.String() != exp {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+}
+
+// Ensure entries are written as JSON objects with their fields in order.
+func TestLogger_JSON(t *testing.T) {
+	o, buf := NewOutput()
+	if err := o.Configure(logger.Config{Format: logger.JSONFormat}); err != nil {
+		t.Fatal(err)
+	}
+	o.Logger("cluster").Warn("write failed", "shard", 1, "err", errors.New("timeout"), "missing")
+
+	if exp := `{"time":"2000-01-01T00:00:00Z","level":"warn","subsystem":"cluster","msg":"write failed","shard":1,"err":"timeout","missing":"MISSING"}` + "\n"; buf.String() != exp {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+}
+
+// Ensure entries below the level of a subsystem are dropped and that levels
+// can be changed while loggers are in use.
+func TestLogger_Levels(t *testing.T) {
+	o, buf := NewOutput()
+	httpd, tsdb := o.Logger("httpd"), o.Logger("tsdb")
+
+	httpd.Debug("a")
+	if buf.Len() != 0 {
+		t.Fatalf("unexpected output: %s", buf.String())
+	}
+
+	if err := o.Configure(logger.Config{Level: "error", Levels: map[string]string{"httpd": "debug"}}); err != nil {
+		t.Fatal(err)
+	}
+	httpd.Debug("b")
+	tsdb.Warn("c")
+	tsdb.Error("d")
+	if exp := "[httpd] 2000/01/01 00:00:00 DEBUG b\n[tsdb] 2000/01/01 00:00:00 ERROR d\n"; buf.String() != exp {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+
+	// An invalid config leaves the output unchanged.
+	if err := o.Configure(logger.Config{Levels: map[string]string{"httpd": "loud"}}); err == nil {
+		t.Fatal("expected error")
+	} else if !httpd.Enabled(logger.DebugLevel) || tsdb.Enabled(logger.WarnLevel) {
+		t.Fatal("unexpected levels")
+	}
+}
+
+// Ensure a standard library logger writes info entries.
+func TestOutput_StdLogger(t *testing.T) {
+	o, buf := NewOutput()
+	o.StdLogger("graphite").Println("listening")
+	if exp := "[graphite] 2000/01/01 00:00:00 INFO listening\n"; buf.String() != exp {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+}
+
+// NewOutput returns an output writing to a buffer at a fixed time.
+func NewOutput() (*logger.Output, *bytes.Buffer) {
+	var buf bytes.Buffer
+	o := logger.NewOutput(&buf)
+	o.Now = func() time.Time { return time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC) }
+	return o, &buf
+}
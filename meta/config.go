@@ -1,9 +1,11 @@
 package meta
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/influxdb/influxdb/toml"
+	"golang.org/x/crypto/bcrypt"
 )
 
 const (
@@ -24,6 +26,28 @@ const (
 
 	// DefaultCommitTimeout is the default commit timeout for the store.
 	DefaultCommitTimeout = 50 * time.Millisecond
+
+	// DefaultSnapshotInterval is the default time between checks for whether
+	// a snapshot of the meta data should be taken.
+	DefaultSnapshotInterval = 2 * time.Minute
+
+	// DefaultSnapshotThreshold is the default number of log entries applied
+	// since the last snapshot before a new snapshot is taken.
+	DefaultSnapshotThreshold = 1024
+
+	// DefaultTrailingLogs is the default number of log entries kept after a
+	// snapshot so slightly lagging nodes can catch up without the snapshot.
+	DefaultTrailingLogs = 1024
+
+	// DefaultBcryptCost is the default cost of hashing passwords with bcrypt.
+	DefaultBcryptCost = 10
+
+	// DefaultMaxFailedLogins is the default number of consecutive failed
+	// logins after which a user is locked out.
+	DefaultMaxFailedLogins = 5
+
+	// DefaultLockoutDuration is the default time a user is locked out for.
+	DefaultLockoutDuration = time.Minute
 )
 
 // Config represents the meta configuration.
@@ -38,6 +62,18 @@ type Config struct {
 	LeaderLeaseTimeout  toml.Duration `toml:"leader-lease-timeout"`
 	CommitTimeout       toml.Duration `toml:"commit-timeout"`
 	ClusterTracing      bool          `toml:"cluster-tracing"`
+	SnapshotInterval    toml.Duration `toml:"snapshot-interval"`
+	SnapshotThreshold   uint64        `toml:"snapshot-threshold"`
+	TrailingLogs        uint64        `toml:"trailing-logs"`
+
+	// BcryptCost is the cost passwords are hashed with. Users whose password
+	// was hashed with a lower cost are rehashed when they next log in.
+	BcryptCost int `toml:"bcrypt-cost"`
+
+	// MaxFailedLogins is the number of consecutive failed logins after which
+	// a user is locked out for LockoutDuration. Zero disables the lockout.
+	MaxFailedLogins int           `toml:"max-failed-logins"`
+	LockoutDuration toml.Duration `toml:"lockout-duration"`
 }
 
 func NewConfig() *Config {
@@ -49,5 +85,19 @@ func NewConfig() *Config {
 		HeartbeatTimeout:    toml.Duration(DefaultHeartbeatTimeout),
 		LeaderLeaseTimeout:  toml.Duration(DefaultLeaderLeaseTimeout),
 		CommitTimeout:       toml.Duration(DefaultCommitTimeout),
+		SnapshotInterval:    toml.Duration(DefaultSnapshotInterval),
+		SnapshotThreshold:   DefaultSnapshotThreshold,
+		TrailingLogs:        DefaultTrailingLogs,
+		BcryptCost:          DefaultBcryptCost,
+		MaxFailedLogins:     DefaultMaxFailedLogins,
+		LockoutDuration:     toml.Duration(DefaultLockoutDuration),
+	}
+}
+
+// Validate returns an error if the config is invalid.
+func (c *Config) Validate() error {
+	if c.BcryptCost < bcrypt.MinCost || c.BcryptCost > bcrypt.MaxCost {
+		return fmt.Errorf("bcrypt-cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
 	}
+	return nil
 }
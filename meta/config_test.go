@@ -17,6 +17,12 @@ election-timeout = "10s"
 heartbeat-timeout = "20s"
 leader-lease-timeout = "30h"
 commit-timeout = "40m"
+snapshot-interval = "50s"
+snapshot-threshold = 100
+trailing-logs = 200
+bcrypt-cost = 12
+max-failed-logins = 3
+lockout-duration = "5m"
 `, &c); err != nil {
 		t.Fatal(err)
 	}
@@ -32,5 +38,31 @@ commit-timeout = "40m"
 		t.Fatalf("unexpected leader lease timeout: %v", c.LeaderLeaseTimeout)
 	} else if time.Duration(c.CommitTimeout) != 40*time.Minute {
 		t.Fatalf("unexpected commit timeout: %v", c.CommitTimeout)
+	} else if time.Duration(c.SnapshotInterval) != 50*time.Second {
+		t.Fatalf("unexpected snapshot interval: %v", c.SnapshotInterval)
+	} else if c.SnapshotThreshold != 100 {
+		t.Fatalf("unexpected snapshot threshold: %d", c.SnapshotThreshold)
+	} else if c.TrailingLogs != 200 {
+		t.Fatalf("unexpected trailing logs: %d", c.TrailingLogs)
+	} else if c.BcryptCost != 12 {
+		t.Fatalf("unexpected bcrypt cost: %d", c.BcryptCost)
+	} else if c.MaxFailedLogins != 3 {
+		t.Fatalf("unexpected max failed logins: %d", c.MaxFailedLogins)
+	} else if time.Duration(c.LockoutDuration) != 5*time.Minute {
+		t.Fatalf("unexpected lockout duration: %v", c.LockoutDuration)
+	}
+}
+
+// Ensure the bcrypt cost must be within the range bcrypt supports.
+func TestConfig_Validate_BcryptCost(t *testing.T) {
+	c := meta.NewConfig()
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, cost := range []int{0, 3, 32} {
+		c.BcryptCost = cost
+		if err := c.Validate(); err == nil || err.Error() != "bcrypt-cost must be between 4 and 31" {
+			t.Fatalf("unexpected error for cost %d: %v", cost, err)
+		}
 	}
 }
@@ -2,6 +2,7 @@ package meta
 
 import (
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/gogo/protobuf/proto"
@@ -73,15 +74,54 @@ func (data *Data) CreateNode(host string) error {
 	return nil
 }
 
-// DeleteNode removes a node from the metadata.
-func (data *Data) DeleteNode(id uint64) error {
+// DeleteNode removes a node from the metadata and from the owners of its shards.
+// Deletion is refused while the node is the only owner of a shard unless force
+// is set, in which case those shards are reassigned to the remaining nodes and
+// the data held by the deleted node is lost.
+func (data *Data) DeleteNode(id uint64, force bool) error {
+	idx := -1
 	for i := range data.Nodes {
 		if data.Nodes[i].ID == id {
-			data.Nodes = append(data.Nodes[:i], data.Nodes[i+1:]...)
-			return nil
+			idx = i
+			break
+		}
+	}
+	if idx == -1 {
+		return ErrNodeNotFound
+	}
+
+	// Find the shards which would be left without an owner.
+	var owned, orphaned []*ShardInfo
+	data.visitShards(func(sh *ShardInfo) {
+		if !sh.OwnedBy(id) {
+			return
+		}
+		owned = append(owned, sh)
+		if len(sh.Owners) == 1 {
+			orphaned = append(orphaned, sh)
+		}
+	})
+	if len(orphaned) > 0 && (!force || len(data.Nodes) == 1) {
+		return ErrNodeOwnsShards
+	}
+
+	data.Nodes = append(data.Nodes[:idx], data.Nodes[idx+1:]...)
+
+	// Remove the node from its shards, reassigning orphaned shards round-robin.
+	for _, sh := range owned {
+		owners := make([]ShardOwner, 0, len(sh.Owners))
+		for _, so := range sh.Owners {
+			if so.NodeID != id {
+				owners = append(owners, so)
+			}
 		}
+		sh.Owners = owners
+	}
+	for i, sh := range orphaned {
+		sh.Owners = []ShardOwner{{NodeID: data.Nodes[i%len(data.Nodes)].ID}}
 	}
-	return ErrNodeNotFound
+
+	return nil
 }
 
 // Database returns a database by name.
@@ -120,6 +160,31 @@ func (data *Data) DropDatabase(name string) error {
 	return ErrDatabaseNotFound
 }
 
+// RenameDatabase renames a database and moves the privileges of users on it
+// to the new name. Returns an error if newName is blank or if a database
+// named newName already exists.
+func (data *Data) RenameDatabase(name, newName string) error {
+	di := data.Database(name)
+	if di == nil {
+		return ErrDatabaseNotFound
+	} else if newName == "" {
+		return ErrDatabaseNameRequired
+	} else if data.Database(newName) != nil {
+		return ErrDatabaseExists
+	}
+	di.Name = newName
+
+	for i := range data.Users {
+		ui := &data.Users[i]
+		if p, ok := ui.Privileges[name]; ok {
+			delete(ui.Privileges, name)
+			ui.Privileges[newName] = p
+		}
+	}
+
+	return nil
+}
+
 // RetentionPolicy returns a retention policy for a database by name.
 func (data *Data) RetentionPolicy(database, name string) (*RetentionPolicyInfo, error) {
 	di := data.Database(database)
@@ -143,6 +208,12 @@ func (data *Data) CreateRetentionPolicy(database string, rpi *RetentionPolicyInf
 		return ErrRetentionPolicyNameRequired
 	} else if rpi.ReplicaN < 1 {
 		return ErrReplicationFactorTooLow
+	} else if !validResolution(rpi.Resolution, rpi.Duration) {
+		return ErrRetentionPolicyResolutionInvalid
+	} else if rpi.ShardGroupDuration != 0 && rpi.ShardGroupDuration < RetentionPolicyMinDuration {
+		return ErrShardDurationTooLow
+	} else if rpi.Duration != 0 && rpi.ShardGroupDuration > rpi.Duration {
+		return ErrIncompatibleDurations
 	}
 
 	// Find database.
@@ -153,12 +224,19 @@ func (data *Data) CreateRetentionPolicy(database string, rpi *RetentionPolicyInf
 		return ErrRetentionPolicyExists
 	}
 
+	// Shard groups span a duration based on the policy's unless one is set.
+	sgDuration := rpi.ShardGroupDuration
+	if sgDuration == 0 {
+		sgDuration = shardGroupDuration(rpi.Duration)
+	}
+
 	// Append new policy.
 	di.RetentionPolicies = append(di.RetentionPolicies, RetentionPolicyInfo{
 		Name:               rpi.Name,
 		Duration:           rpi.Duration,
-		ShardGroupDuration: shardGroupDuration(rpi.Duration),
+		ShardGroupDuration: sgDuration,
 		ReplicaN:           rpi.ReplicaN,
+		Resolution:         rpi.Resolution,
 	})
 
 	return nil
@@ -211,6 +289,18 @@ func (data *Data) UpdateRetentionPolicy(database, name string, rpu *RetentionPol
 		return ErrRetentionPolicyDurationTooLow
 	}
 
+	// Ensure the resolution is still within the duration.
+	duration, resolution := rpi.Duration, rpi.Resolution
+	if rpu.Duration != nil {
+		duration = *rpu.Duration
+	}
+	if rpu.Resolution != nil {
+		resolution = *rpu.Resolution
+	}
+	if !validResolution(resolution, duration) {
+		return ErrRetentionPolicyResolutionInvalid
+	}
+
 	// Update fields.
 	if rpu.Name != nil {
 		rpi.Name = *rpu.Name
@@ -222,6 +312,7 @@ func (data *Data) UpdateRetentionPolicy(database, name string, rpu *RetentionPol
 	if rpu.ReplicaN != nil {
 		rpi.ReplicaN = *rpu.ReplicaN
 	}
+	rpi.Resolution = resolution
 
 	return nil
 }
@@ -383,6 +474,72 @@ func (data *Data) DeleteShardGroup(database, policy string, id uint64) error {
 	return ErrShardGroupNotFound
 }
 
+// UpdateShardOwners adds and removes owners of a shard.
+// Adding an existing owner or removing a missing one is ignored.
+func (data *Data) UpdateShardOwners(id uint64, added, removed []uint64) error {
+	sh := data.shard(id)
+	if sh == nil {
+		return ErrShardNotFound
+	}
+
+	for _, nodeID := range added {
+		if data.Node(nodeID) == nil {
+			return ErrNodeNotFound
+		}
+	}
+
+	// Build the new owner list, preserving the order of existing owners.
+	var ids []uint64
+	for _, so := range sh.Owners {
+		if !containsUint64(removed, so.NodeID) {
+			ids = append(ids, so.NodeID)
+		}
+	}
+	for _, nodeID := range added {
+		if !containsUint64(ids, nodeID) {
+			ids = append(ids, nodeID)
+		}
+	}
+	if len(ids) == 0 {
+		return ErrShardOwnerRequired
+	}
+
+	sh.Owners = make([]ShardOwner, len(ids))
+	for i, nodeID := range ids {
+		sh.Owners[i] = ShardOwner{NodeID: nodeID}
+	}
+	return nil
+}
+
+// shard returns a reference to a shard by id, if it exists in a shard group
+// which hasn't been deleted.
+func (data *Data) shard(id uint64) (si *ShardInfo) {
+	data.visitShards(func(sh *ShardInfo) {
+		if sh.ID == id {
+			si = sh
+		}
+	})
+	return
+}
+
+// visitShards calls fn with a reference to every shard in shard groups
+// which haven't been deleted.
+func (data *Data) visitShards(fn func(sh *ShardInfo)) {
+	for i := range data.Databases {
+		for j := range data.Databases[i].RetentionPolicies {
+			rpi := &data.Databases[i].RetentionPolicies[j]
+			for k := range rpi.ShardGroups {
+				if rpi.ShardGroups[k].Deleted() {
+					continue
+				}
+				for l := range rpi.ShardGroups[k].Shards {
+					fn(&rpi.ShardGroups[k].Shards[l])
+				}
+			}
+		}
+	}
+}
+
 // CreateContinuousQuery adds a named continuous query to a database.
 func (data *Data) CreateContinuousQuery(database, name, query string) error {
 	di := data.Database(database)
@@ -422,6 +579,159 @@ func (data *Data) DropContinuousQuery(database, name string) error {
 	return ErrContinuousQueryNotFound
 }
 
+// SetContinuousQueryLastRun records the last time a continuous query was executed.
+func (data *Data) SetContinuousQueryLastRun(database, name string, t time.Time) error {
+	di := data.Database(database)
+	if di == nil {
+		return ErrDatabaseNotFound
+	}
+
+	for i := range di.ContinuousQueries {
+		if di.ContinuousQueries[i].Name == name {
+			di.ContinuousQueries[i].LastRun = t.UTC()
+			return nil
+		}
+	}
+	return ErrContinuousQueryNotFound
+}
+
+// CreateSubscription adds a named subscription to a database and retention policy.
+func (data *Data) CreateSubscription(database, rp, name, mode string, destinations []string) error {
+	rpi, err := data.RetentionPolicy(database, rp)
+	if err != nil {
+		return err
+	}
+	if rpi == nil {
+		return ErrRetentionPolicyNotFound
+	}
+
+	// Ensure the name doesn't already exist.
+	for i := range rpi.Subscriptions {
+		if rpi.Subscriptions[i].Name == name {
+			return ErrSubscriptionExists
+		}
+	}
+
+	// Append new subscription.
+	rpi.Subscriptions = append(rpi.Subscriptions, SubscriptionInfo{
+		Name:         name,
+		Mode:         mode,
+		Destinations: destinations,
+	})
+
+	return nil
+}
+
+// DropSubscription removes a subscription.
+func (data *Data) DropSubscription(database, rp, name string) error {
+	rpi, err := data.RetentionPolicy(database, rp)
+	if err != nil {
+		return err
+	}
+	if rpi == nil {
+		return ErrRetentionPolicyNotFound
+	}
+
+	for i := range rpi.Subscriptions {
+		if rpi.Subscriptions[i].Name == name {
+			rpi.Subscriptions = append(rpi.Subscriptions[:i], rpi.Subscriptions[i+1:]...)
+			return nil
+		}
+	}
+	return ErrSubscriptionNotFound
+}
+
+// CreateTagRule adds a named tag normalization rule to a database.
+func (data *Data) CreateTagRule(database string, rule TagRuleInfo) error {
+	di := data.Database(database)
+	if di == nil {
+		return ErrDatabaseNotFound
+	}
+
+	// Ensure the name doesn't already exist.
+	for i := range di.TagRules {
+		if di.TagRules[i].Name == rule.Name {
+			return ErrTagRuleExists
+		}
+	}
+
+	di.TagRules = append(di.TagRules, rule.clone())
+	return nil
+}
+
+// DropTagRule removes a tag normalization rule from a database.
+func (data *Data) DropTagRule(database, name string) error {
+	di := data.Database(database)
+	if di == nil {
+		return ErrDatabaseNotFound
+	}
+
+	for i := range di.TagRules {
+		if di.TagRules[i].Name == name {
+			di.TagRules = append(di.TagRules[:i], di.TagRules[i+1:]...)
+			return nil
+		}
+	}
+	return ErrTagRuleNotFound
+}
+
+// CreateDownsamplePolicy adds a named downsample policy to a database. Both
+// retention policies of the policy must exist.
+func (data *Data) CreateDownsamplePolicy(database string, dpi DownsamplePolicyInfo) error {
+	di := data.Database(database)
+	if di == nil {
+		return ErrDatabaseNotFound
+	}
+	if di.RetentionPolicy(dpi.SourceRetentionPolicy) == nil || di.RetentionPolicy(dpi.TargetRetentionPolicy) == nil {
+		return ErrRetentionPolicyNotFound
+	} else if dpi.SourceRetentionPolicy == dpi.TargetRetentionPolicy {
+		return ErrDownsamplePolicySameRetentionPolicy
+	}
+
+	// Ensure the name doesn't already exist.
+	for i := range di.DownsamplePolicies {
+		if di.DownsamplePolicies[i].Name == dpi.Name {
+			return ErrDownsamplePolicyExists
+		}
+	}
+
+	di.DownsamplePolicies = append(di.DownsamplePolicies, dpi.clone())
+	return nil
+}
+
+// DropDownsamplePolicy removes a downsample policy from a database.
+func (data *Data) DropDownsamplePolicy(database, name string) error {
+	di := data.Database(database)
+	if di == nil {
+		return ErrDatabaseNotFound
+	}
+
+	for i := range di.DownsamplePolicies {
+		if di.DownsamplePolicies[i].Name == name {
+			di.DownsamplePolicies = append(di.DownsamplePolicies[:i], di.DownsamplePolicies[i+1:]...)
+			return nil
+		}
+	}
+	return ErrDownsamplePolicyNotFound
+}
+
+// SetDownsamplePolicyProgress records the time up to which the points of a
+// downsample policy have been downsampled.
+func (data *Data) SetDownsamplePolicyProgress(database, name string, t time.Time) error {
+	di := data.Database(database)
+	if di == nil {
+		return ErrDatabaseNotFound
+	}
+
+	for i := range di.DownsamplePolicies {
+		if di.DownsamplePolicies[i].Name == name {
+			di.DownsamplePolicies[i].DownsampledUntil = t
+			return nil
+		}
+	}
+	return ErrDownsamplePolicyNotFound
+}
+
 // User returns a user by username.
 func (data *Data) User(username string) *UserInfo {
 	for i := range data.Users {
@@ -451,6 +761,20 @@ func (data *Data) CreateUser(name, hash string, admin bool) error {
 	return nil
 }
 
+// CreateWriteOnlyUser creates a new user which may only write to database.
+func (data *Data) CreateWriteOnlyUser(name, hash, database string) error {
+	if database == "" {
+		return ErrDatabaseNameRequired
+	} else if err := data.CreateUser(name, hash, false); err != nil {
+		return err
+	}
+
+	ui := data.User(name)
+	ui.Privileges = map[string]influxql.Privilege{database: influxql.WritePrivilege}
+	ui.WriteOnly = true
+	return nil
+}
+
 // DropUser removes an existing user by name.
 func (data *Data) DropUser(name string) error {
 	for i := range data.Users {
@@ -478,6 +802,8 @@ func (data *Data) SetPrivilege(name, database string, p influxql.Privilege) erro
 	ui := data.User(name)
 	if ui == nil {
 		return ErrUserNotFound
+	} else if ui.WriteOnly {
+		return ErrUserWriteOnly
 	}
 
 	if ui.Privileges == nil {
@@ -493,6 +819,8 @@ func (data *Data) SetAdminPrivilege(name string, admin bool) error {
 	ui := data.User(name)
 	if ui == nil {
 		return ErrUserNotFound
+	} else if ui.WriteOnly {
+		return ErrUserWriteOnly
 	}
 
 	ui.Admin = admin
@@ -500,6 +828,18 @@ func (data *Data) SetAdminPrivilege(name string, admin bool) error {
 	return nil
 }
 
+// SetUserLimits sets the resource limits for a user.
+func (data *Data) SetUserLimits(name string, limits UserLimits) error {
+	ui := data.User(name)
+	if ui == nil {
+		return ErrUserNotFound
+	}
+
+	ui.Limits = limits
+
+	return nil
+}
+
 // UserPrivileges gets the privileges for a user.
 func (data *Data) UserPrivileges(name string) (map[string]influxql.Privilege, error) {
 	ui := data.User(name)
@@ -510,6 +850,35 @@ func (data *Data) UserPrivileges(name string) (map[string]influxql.Privilege, er
 	return ui.Privileges, nil
 }
 
+// EffectivePrivileges returns what a user may do on each database, and on the
+// databases it was granted privileges on, sorted by database.
+func (data *Data) EffectivePrivileges(name string) ([]EffectivePrivilege, error) {
+	ui := data.User(name)
+	if ui == nil {
+		return nil, ErrUserNotFound
+	}
+
+	databases := make(map[string]struct{})
+	for i := range data.Databases {
+		databases[data.Databases[i].Name] = struct{}{}
+	}
+	for db := range ui.Privileges {
+		databases[db] = struct{}{}
+	}
+
+	a := make([]EffectivePrivilege, 0, len(databases))
+	for db := range databases {
+		a = append(a, EffectivePrivilege{
+			Database:  db,
+			Privilege: ui.Privileges[db],
+			Read:      ui.Authorize(influxql.ReadPrivilege, db),
+			Write:     ui.Authorize(influxql.WritePrivilege, db),
+		})
+	}
+	sort.Sort(EffectivePrivileges(a))
+	return a, nil
+}
+
 // UserPrivilege gets the privilege for a user on a database.
 func (data *Data) UserPrivilege(name, database string) (*influxql.Privilege, error) {
 	ui := data.User(name)
@@ -657,6 +1026,8 @@ type DatabaseInfo struct {
 	DefaultRetentionPolicy string
 	RetentionPolicies      []RetentionPolicyInfo
 	ContinuousQueries      []ContinuousQueryInfo
+	TagRules               []TagRuleInfo
+	DownsamplePolicies     []DownsamplePolicyInfo
 }
 
 // RetentionPolicy returns a retention policy by name.
@@ -713,6 +1084,22 @@ func (di DatabaseInfo) clone() DatabaseInfo {
 		}
 	}
 
+	// Copy tag rules.
+	if di.TagRules != nil {
+		other.TagRules = make([]TagRuleInfo, len(di.TagRules))
+		for i := range di.TagRules {
+			other.TagRules[i] = di.TagRules[i].clone()
+		}
+	}
+
+	// Copy downsample policies.
+	if di.DownsamplePolicies != nil {
+		other.DownsamplePolicies = make([]DownsamplePolicyInfo, len(di.DownsamplePolicies))
+		for i := range di.DownsamplePolicies {
+			other.DownsamplePolicies[i] = di.DownsamplePolicies[i].clone()
+		}
+	}
+
 	return other
 }
 
@@ -731,6 +1118,16 @@ func (di DatabaseInfo) marshal() *internal.DatabaseInfo {
 	for i := range di.ContinuousQueries {
 		pb.ContinuousQueries[i] = di.ContinuousQueries[i].marshal()
 	}
+
+	pb.TagRules = make([]*internal.TagRuleInfo, len(di.TagRules))
+	for i := range di.TagRules {
+		pb.TagRules[i] = di.TagRules[i].marshal()
+	}
+
+	pb.DownsamplePolicies = make([]*internal.DownsamplePolicyInfo, len(di.DownsamplePolicies))
+	for i := range di.DownsamplePolicies {
+		pb.DownsamplePolicies[i] = di.DownsamplePolicies[i].marshal()
+	}
 	return pb
 }
 
@@ -752,6 +1149,50 @@ func (di *DatabaseInfo) unmarshal(pb *internal.DatabaseInfo) {
 			di.ContinuousQueries[i].unmarshal(x)
 		}
 	}
+
+	if len(pb.GetTagRules()) > 0 {
+		di.TagRules = make([]TagRuleInfo, len(pb.GetTagRules()))
+		for i, x := range pb.GetTagRules() {
+			di.TagRules[i].unmarshal(x)
+		}
+	}
+
+	if len(pb.GetDownsamplePolicies()) > 0 {
+		di.DownsamplePolicies = make([]DownsamplePolicyInfo, len(pb.GetDownsamplePolicies()))
+		for i, x := range pb.GetDownsamplePolicies() {
+			di.DownsamplePolicies[i].unmarshal(x)
+		}
+	}
+}
+
+// NormalizeTags returns tags with the tag rules of the database applied.
+// Rules are applied in the order they were created. The original tags are
+// returned if no value changes.
+func (di DatabaseInfo) NormalizeTags(tags map[string]string) (map[string]string, bool) {
+	var other map[string]string
+	for k, v := range tags {
+		nv := v
+		for i := range di.TagRules {
+			nv = di.TagRules[i].Apply(k, nv)
+		}
+		if nv == v {
+			continue
+		}
+
+		// Copy the tags on the first change.
+		if other == nil {
+			other = make(map[string]string, len(tags))
+			for k, v := range tags {
+				other[k] = v
+			}
+		}
+		other[k] = nv
+	}
+
+	if other == nil {
+		return tags, false
+	}
+	return other, true
 }
 
 // RetentionPolicyInfo represents metadata about a retention policy.
@@ -761,6 +1202,12 @@ type RetentionPolicyInfo struct {
 	Duration           time.Duration
 	ShardGroupDuration time.Duration
 	ShardGroups        []ShardGroupInfo
+	Subscriptions      []SubscriptionInfo
+
+	// Resolution is the interval of the points written to the policy, such
+	// as the interval of a downsample policy writing to it. Queries grouping
+	// by finer intervals are rejected. Zero if unknown.
+	Resolution time.Duration
 }
 
 // NewRetentionPolicyInfo returns a new instance of RetentionPolicyInfo with defaults set.
@@ -815,12 +1262,20 @@ func (rpi *RetentionPolicyInfo) marshal() *internal.RetentionPolicyInfo {
 		Duration:           proto.Int64(int64(rpi.Duration)),
 		ShardGroupDuration: proto.Int64(int64(rpi.ShardGroupDuration)),
 	}
+	if rpi.Resolution > 0 {
+		pb.Resolution = proto.Int64(int64(rpi.Resolution))
+	}
 
 	pb.ShardGroups = make([]*internal.ShardGroupInfo, len(rpi.ShardGroups))
 	for i, sgi := range rpi.ShardGroups {
 		pb.ShardGroups[i] = sgi.marshal()
 	}
 
+	pb.Subscriptions = make([]*internal.SubscriptionInfo, len(rpi.Subscriptions))
+	for i, sub := range rpi.Subscriptions {
+		pb.Subscriptions[i] = sub.marshal()
+	}
+
 	return pb
 }
 
@@ -830,6 +1285,7 @@ func (rpi *RetentionPolicyInfo) unmarshal(pb *internal.RetentionPolicyInfo) {
 	rpi.ReplicaN = int(pb.GetReplicaN())
 	rpi.Duration = time.Duration(pb.GetDuration())
 	rpi.ShardGroupDuration = time.Duration(pb.GetShardGroupDuration())
+	rpi.Resolution = time.Duration(pb.GetResolution())
 
 	if len(pb.GetShardGroups()) > 0 {
 		rpi.ShardGroups = make([]ShardGroupInfo, len(pb.GetShardGroups()))
@@ -837,6 +1293,13 @@ func (rpi *RetentionPolicyInfo) unmarshal(pb *internal.RetentionPolicyInfo) {
 			rpi.ShardGroups[i].unmarshal(x)
 		}
 	}
+
+	if len(pb.GetSubscriptions()) > 0 {
+		rpi.Subscriptions = make([]SubscriptionInfo, len(pb.GetSubscriptions()))
+		for i, x := range pb.GetSubscriptions() {
+			rpi.Subscriptions[i].unmarshal(x)
+		}
+	}
 }
 
 // clone returns a deep copy of rpi.
@@ -850,9 +1313,22 @@ func (rpi RetentionPolicyInfo) clone() RetentionPolicyInfo {
 		}
 	}
 
+	if rpi.Subscriptions != nil {
+		other.Subscriptions = make([]SubscriptionInfo, len(rpi.Subscriptions))
+		for i := range rpi.Subscriptions {
+			other.Subscriptions[i] = rpi.Subscriptions[i].clone()
+		}
+	}
+
 	return other
 }
 
+// validResolution returns true if a resolution fits a policy duration. Zero
+// durations are infinite.
+func validResolution(resolution, duration time.Duration) bool {
+	return resolution >= 0 && (duration == 0 || resolution <= duration)
+}
+
 // shardGroupDuration returns the duration for a shard group based on a policy duration.
 func shardGroupDuration(d time.Duration) time.Duration {
 	if d >= 180*24*time.Hour || d == 0 { // 6 months or 0
@@ -863,6 +1339,16 @@ func shardGroupDuration(d time.Duration) time.Duration {
 	return 1 * time.Hour
 }
 
+// containsUint64 returns true if a contains v.
+func containsUint64(a []uint64, v uint64) bool {
+	for _, x := range a {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
 // ShardGroupInfo represents metadata about a shard group. The DeletedAt field is important
 // because it makes it clear that a ShardGroup has been marked as deleted, and allow the system
 // to be sure that a ShardGroup is not simply missing. If the DeletedAt is set, the system can
@@ -1047,6 +1533,9 @@ func (so *ShardOwner) unmarshal(pb *internal.ShardOwner) {
 type ContinuousQueryInfo struct {
 	Name  string
 	Query string
+
+	// LastRun is the last time the query was executed by the leader.
+	LastRun time.Time
 }
 
 // clone returns a deep copy of cqi.
@@ -1054,16 +1543,262 @@ func (cqi ContinuousQueryInfo) clone() ContinuousQueryInfo { return cqi }
 
 // marshal serializes to a protobuf representation.
 func (cqi ContinuousQueryInfo) marshal() *internal.ContinuousQueryInfo {
-	return &internal.ContinuousQueryInfo{
+	pb := &internal.ContinuousQueryInfo{
 		Name:  proto.String(cqi.Name),
 		Query: proto.String(cqi.Query),
 	}
+	if !cqi.LastRun.IsZero() {
+		pb.LastRun = proto.Int64(cqi.LastRun.UnixNano())
+	}
+	return pb
 }
 
 // unmarshal deserializes from a protobuf representation.
 func (cqi *ContinuousQueryInfo) unmarshal(pb *internal.ContinuousQueryInfo) {
 	cqi.Name = pb.GetName()
 	cqi.Query = pb.GetQuery()
+	if pb.LastRun != nil {
+		cqi.LastRun = time.Unix(0, pb.GetLastRun()).UTC()
+	}
+}
+
+// SubscriptionInfo represents metadata about a subscription.
+type SubscriptionInfo struct {
+	Name         string
+	Mode         string
+	Destinations []string
+}
+
+// clone returns a deep copy of si.
+func (si SubscriptionInfo) clone() SubscriptionInfo {
+	other := si
+
+	if si.Destinations != nil {
+		other.Destinations = make([]string, len(si.Destinations))
+		copy(other.Destinations, si.Destinations)
+	}
+
+	return other
+}
+
+// marshal serializes to a protobuf representation.
+func (si SubscriptionInfo) marshal() *internal.SubscriptionInfo {
+	pb := &internal.SubscriptionInfo{
+		Name: proto.String(si.Name),
+		Mode: proto.String(si.Mode),
+	}
+
+	pb.Destinations = make([]string, len(si.Destinations))
+	copy(pb.Destinations, si.Destinations)
+
+	return pb
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (si *SubscriptionInfo) unmarshal(pb *internal.SubscriptionInfo) {
+	si.Name = pb.GetName()
+	si.Mode = pb.GetMode()
+
+	if len(pb.GetDestinations()) > 0 {
+		si.Destinations = make([]string, len(pb.GetDestinations()))
+		copy(si.Destinations, pb.GetDestinations())
+	}
+}
+
+// Tag rule actions.
+const (
+	// TagRuleLowercase converts tag values to lower case.
+	TagRuleLowercase = "lowercase"
+
+	// TagRuleTrim strips leading and trailing whitespace from tag values.
+	TagRuleTrim = "trim"
+
+	// TagRuleMap replaces tag values by the value they're mapped to.
+	TagRuleMap = "map"
+)
+
+// TagRuleInfo represents metadata about a tag normalization rule.
+type TagRuleInfo struct {
+	Name string
+
+	// Key restricts the rule to the values of one tag key. The rule applies
+	// to all tag keys if empty.
+	Key string
+
+	Action   string
+	Mappings map[string]string
+}
+
+// Apply returns the value of a tag with key k after applying the rule.
+func (ri TagRuleInfo) Apply(k, v string) string {
+	if ri.Key != "" && ri.Key != k {
+		return v
+	}
+
+	switch ri.Action {
+	case TagRuleLowercase:
+		return strings.ToLower(v)
+	case TagRuleTrim:
+		return strings.TrimSpace(v)
+	case TagRuleMap:
+		if to, ok := ri.Mappings[v]; ok {
+			return to
+		}
+	}
+	return v
+}
+
+// clone returns a deep copy of ri.
+func (ri TagRuleInfo) clone() TagRuleInfo {
+	other := ri
+
+	if ri.Mappings != nil {
+		other.Mappings = make(map[string]string, len(ri.Mappings))
+		for k, v := range ri.Mappings {
+			other.Mappings[k] = v
+		}
+	}
+
+	return other
+}
+
+// marshal serializes to a protobuf representation.
+func (ri TagRuleInfo) marshal() *internal.TagRuleInfo {
+	pb := &internal.TagRuleInfo{
+		Name:   proto.String(ri.Name),
+		Action: proto.String(ri.Action),
+	}
+	if ri.Key != "" {
+		pb.Key = proto.String(ri.Key)
+	}
+
+	// Mappings are stored in order so the encoding is deterministic.
+	from := make([]string, 0, len(ri.Mappings))
+	for k := range ri.Mappings {
+		from = append(from, k)
+	}
+	sort.Strings(from)
+
+	pb.From = from
+	pb.To = make([]string, len(from))
+	for i, k := range from {
+		pb.To[i] = ri.Mappings[k]
+	}
+
+	return pb
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (ri *TagRuleInfo) unmarshal(pb *internal.TagRuleInfo) {
+	ri.Name = pb.GetName()
+	ri.Key = pb.GetKey()
+	ri.Action = pb.GetAction()
+
+	if len(pb.GetFrom()) > 0 {
+		ri.Mappings = make(map[string]string, len(pb.GetFrom()))
+		for i, k := range pb.GetFrom() {
+			if i < len(pb.GetTo()) {
+				ri.Mappings[k] = pb.GetTo()[i]
+			}
+		}
+	}
+}
+
+// DefaultDownsampleAggregate is the aggregate applied to fields that a
+// downsample policy has no aggregate for.
+const DefaultDownsampleAggregate = "mean"
+
+// DownsamplePolicyInfo represents metadata about a downsample policy.
+type DownsamplePolicyInfo struct {
+	Name                  string
+	SourceRetentionPolicy string
+	TargetRetentionPolicy string
+	Interval              time.Duration
+
+	// Aggregate applied to fields without their own aggregate.
+	DefaultAggregate string
+
+	// Aggregates applied to specific fields, keyed by field.
+	Aggregates map[string]string
+
+	// Points before this time have been downsampled.
+	DownsampledUntil time.Time
+}
+
+// Aggregate returns the aggregate applied to a field.
+func (dpi DownsamplePolicyInfo) Aggregate(field string) string {
+	if a, ok := dpi.Aggregates[field]; ok {
+		return a
+	} else if dpi.DefaultAggregate != "" {
+		return dpi.DefaultAggregate
+	}
+	return DefaultDownsampleAggregate
+}
+
+// clone returns a deep copy of dpi.
+func (dpi DownsamplePolicyInfo) clone() DownsamplePolicyInfo {
+	other := dpi
+
+	if dpi.Aggregates != nil {
+		other.Aggregates = make(map[string]string, len(dpi.Aggregates))
+		for k, v := range dpi.Aggregates {
+			other.Aggregates[k] = v
+		}
+	}
+
+	return other
+}
+
+// marshal serializes to a protobuf representation.
+func (dpi DownsamplePolicyInfo) marshal() *internal.DownsamplePolicyInfo {
+	pb := &internal.DownsamplePolicyInfo{
+		Name:                  proto.String(dpi.Name),
+		SourceRetentionPolicy: proto.String(dpi.SourceRetentionPolicy),
+		TargetRetentionPolicy: proto.String(dpi.TargetRetentionPolicy),
+		Interval:              proto.Int64(int64(dpi.Interval)),
+	}
+	if dpi.DefaultAggregate != "" {
+		pb.DefaultAggregate = proto.String(dpi.DefaultAggregate)
+	}
+	if !dpi.DownsampledUntil.IsZero() {
+		pb.DownsampledUntil = proto.Int64(dpi.DownsampledUntil.UnixNano())
+	}
+
+	// Aggregates are stored in order so the encoding is deterministic.
+	fields := make([]string, 0, len(dpi.Aggregates))
+	for k := range dpi.Aggregates {
+		fields = append(fields, k)
+	}
+	sort.Strings(fields)
+
+	pb.Fields = fields
+	pb.FieldAggregates = make([]string, len(fields))
+	for i, k := range fields {
+		pb.FieldAggregates[i] = dpi.Aggregates[k]
+	}
+
+	return pb
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (dpi *DownsamplePolicyInfo) unmarshal(pb *internal.DownsamplePolicyInfo) {
+	dpi.Name = pb.GetName()
+	dpi.SourceRetentionPolicy = pb.GetSourceRetentionPolicy()
+	dpi.TargetRetentionPolicy = pb.GetTargetRetentionPolicy()
+	dpi.Interval = time.Duration(pb.GetInterval())
+	dpi.DefaultAggregate = pb.GetDefaultAggregate()
+	if pb.DownsampledUntil != nil {
+		dpi.DownsampledUntil = time.Unix(0, pb.GetDownsampledUntil()).UTC()
+	}
+
+	if len(pb.GetFields()) > 0 {
+		dpi.Aggregates = make(map[string]string, len(pb.GetFields()))
+		for i, k := range pb.GetFields() {
+			if i < len(pb.GetFieldAggregates()) {
+				dpi.Aggregates[k] = pb.GetFieldAggregates()[i]
+			}
+		}
+	}
 }
 
 // UserInfo represents metadata about a user in the system.
@@ -1072,8 +1807,49 @@ type UserInfo struct {
 	Hash       string
 	Admin      bool
 	Privileges map[string]influxql.Privilege
+	Limits     UserLimits
+
+	// WriteOnly users may only write to the database they have the write
+	// privilege on. They can't query or be granted other privileges.
+	WriteOnly bool
 }
 
+// UserLimits represents the resources the queries of a user may use.
+// A zero limit is unlimited.
+type UserLimits struct {
+	// Maximum number of queries the user may run at once.
+	MaxQueries int
+
+	// Maximum time range a SELECT of the user may cover.
+	MaxRange time.Duration
+
+	// Maximum number of points a SELECT of the user may read from shards.
+	MaxPoints int64
+
+	// Batch queues the SELECT statements of the user behind those of
+	// interactive queries when the server runs as many as it may.
+	Batch bool
+}
+
+// EffectivePrivilege is what a user may do on a database, granted to it or
+// implied by it being an admin.
+type EffectivePrivilege struct {
+	Database string
+
+	// Privilege granted on the database. NoPrivileges if not granted any.
+	Privilege influxql.Privilege
+
+	Read  bool
+	Write bool
+}
+
+// EffectivePrivileges is a list of effective privileges sortable by database.
+type EffectivePrivileges []EffectivePrivilege
+
+func (a EffectivePrivileges) Len() int           { return len(a) }
+func (a EffectivePrivileges) Less(i, j int) bool { return a[i].Database < a[j].Database }
+func (a EffectivePrivileges) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
 // Authorize returns true if the user is authorized and false if not.
 func (ui *UserInfo) Authorize(privilege influxql.Privilege, database string) bool {
 	if ui.Admin {
@@ -1104,6 +1880,21 @@ func (ui UserInfo) marshal() *internal.UserInfo {
 		Hash:  proto.String(ui.Hash),
 		Admin: proto.Bool(ui.Admin),
 	}
+	if ui.Limits.MaxQueries > 0 {
+		pb.MaxQueries = proto.Int64(int64(ui.Limits.MaxQueries))
+	}
+	if ui.Limits.MaxRange > 0 {
+		pb.MaxRange = proto.Int64(int64(ui.Limits.MaxRange))
+	}
+	if ui.Limits.MaxPoints > 0 {
+		pb.MaxPoints = proto.Int64(ui.Limits.MaxPoints)
+	}
+	if ui.Limits.Batch {
+		pb.Batch = proto.Bool(true)
+	}
+	if ui.WriteOnly {
+		pb.WriteOnly = proto.Bool(true)
+	}
 
 	for database, privilege := range ui.Privileges {
 		pb.Privileges = append(pb.Privileges, &internal.UserPrivilege{
@@ -1120,6 +1911,13 @@ func (ui *UserInfo) unmarshal(pb *internal.UserInfo) {
 	ui.Name = pb.GetName()
 	ui.Hash = pb.GetHash()
 	ui.Admin = pb.GetAdmin()
+	ui.Limits = UserLimits{
+		MaxQueries: int(pb.GetMaxQueries()),
+		MaxRange:   time.Duration(pb.GetMaxRange()),
+		MaxPoints:  pb.GetMaxPoints(),
+		Batch:      pb.GetBatch(),
+	}
+	ui.WriteOnly = pb.GetWriteOnly()
 
 	ui.Privileges = make(map[string]influxql.Privilege)
 	for _, p := range pb.GetPrivileges() {
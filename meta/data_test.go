@@ -36,7 +36,7 @@ func TestData_DeleteNode(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if err := data.DeleteNode(1); err != nil {
+	if err := data.DeleteNode(1, false); err != nil {
 		t.Fatal(err)
 	} else if len(data.Nodes) != 2 {
 		t.Fatalf("unexpected node count: %d", len(data.Nodes))
@@ -47,6 +47,54 @@ func TestData_DeleteNode(t *testing.T) {
 	}
 }
 
+// Ensure a node which is the only owner of a shard is only removed when forced.
+func TestData_DeleteNode_OwnsShards(t *testing.T) {
+	var data meta.Data
+	if err := data.CreateNode("host0"); err != nil {
+		t.Fatal(err)
+	} else if err = data.CreateNode("host1"); err != nil {
+		t.Fatal(err)
+	} else if err = data.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if err = data.CreateRetentionPolicy("db0", &meta.RetentionPolicyInfo{Name: "rp0", ReplicaN: 1, Duration: 1 * time.Hour}); err != nil {
+		t.Fatal(err)
+	} else if err = data.CreateShardGroup("db0", "rp0", time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	sh := &data.Databases[0].RetentionPolicies[0].ShardGroups[0].Shards[0]
+	owner := sh.Owners[0].NodeID
+
+	// Deleting the only owner should fail.
+	if err := data.DeleteNode(owner, false); err != meta.ErrNodeOwnsShards {
+		t.Fatalf("unexpected error: %v", err)
+	} else if len(data.Nodes) != 2 {
+		t.Fatalf("unexpected node count: %d", len(data.Nodes))
+	}
+
+	// Once the shard has another owner the node can be removed.
+	if err := data.UpdateShardOwners(sh.ID, []uint64{3 - owner}, nil); err != nil {
+		t.Fatal(err)
+	} else if err := data.DeleteNode(owner, false); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(sh.Owners, []meta.ShardOwner{{NodeID: 3 - owner}}) {
+		t.Fatalf("unexpected owners: %#v", sh.Owners)
+	}
+
+	// The last node can't be removed, even when forced.
+	if err := data.DeleteNode(3-owner, true); err != meta.ErrNodeOwnsShards {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Forcing reassigns the shard to a remaining node.
+	if err := data.CreateNode("host2"); err != nil {
+		t.Fatal(err)
+	} else if err := data.DeleteNode(3-owner, true); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(sh.Owners, []meta.ShardOwner{{NodeID: 3}}) {
+		t.Fatalf("unexpected owners: %#v", sh.Owners)
+	}
+}
+
 // Ensure a database can be created.
 func TestData_CreateDatabase(t *testing.T) {
 	var data meta.Data
@@ -92,6 +140,35 @@ func TestData_DropDatabase(t *testing.T) {
 	}
 }
 
+// Ensure a database can be renamed along with the privileges on it.
+func TestData_RenameDatabase(t *testing.T) {
+	var data meta.Data
+	for i := 0; i < 2; i++ {
+		if err := data.CreateDatabase(fmt.Sprintf("db%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := data.CreateUser("susy", "ABC123", false); err != nil {
+		t.Fatal(err)
+	} else if err := data.SetPrivilege("susy", "db0", influxql.ReadPrivilege); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := data.RenameDatabase("db0", "db1"); err != meta.ErrDatabaseExists {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.RenameDatabase("db2", "db3"); err != meta.ErrDatabaseNotFound {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := data.RenameDatabase("db0", "tenant0"); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(data.Databases, []meta.DatabaseInfo{{Name: "tenant0"}, {Name: "db1"}}) {
+		t.Fatalf("unexpected databases: %#v", data.Databases)
+	} else if !reflect.DeepEqual(data.Users[0].Privileges, map[string]influxql.Privilege{"tenant0": influxql.ReadPrivilege}) {
+		t.Fatalf("unexpected privileges: %#v", data.Users[0].Privileges)
+	}
+}
+
 // Ensure a retention policy can be created.
 func TestData_CreateRetentionPolicy(t *testing.T) {
 	data := meta.Data{Nodes: []meta.NodeInfo{{ID: 1}, {ID: 2}}}
@@ -121,6 +198,34 @@ func TestData_CreateRetentionPolicy(t *testing.T) {
 	}
 }
 
+// Ensure a retention policy can be created with a shard group duration.
+func TestData_CreateRetentionPolicy_ShardGroupDuration(t *testing.T) {
+	data := meta.Data{Nodes: []meta.NodeInfo{{ID: 1}}}
+	if err := data.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := data.CreateRetentionPolicy("db0", &meta.RetentionPolicyInfo{Name: "rp0", ReplicaN: 1, Duration: 365 * 24 * time.Hour, ShardGroupDuration: 30 * 24 * time.Hour}); err != nil {
+		t.Fatal(err)
+	} else if d := data.Databases[0].RetentionPolicies[0].ShardGroupDuration; d != 30*24*time.Hour {
+		t.Fatalf("unexpected shard group duration: %s", d)
+	}
+
+	// The shard group duration can't be longer than the policy's.
+	if err := data.CreateRetentionPolicy("db0", &meta.RetentionPolicyInfo{Name: "rp1", ReplicaN: 1, Duration: time.Hour, ShardGroupDuration: 2 * time.Hour}); err != meta.ErrIncompatibleDurations {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.CreateRetentionPolicy("db0", &meta.RetentionPolicyInfo{Name: "rp1", ReplicaN: 1, ShardGroupDuration: -time.Hour}); err != meta.ErrShardDurationTooLow {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.CreateRetentionPolicy("db0", &meta.RetentionPolicyInfo{Name: "rp1", ReplicaN: 1, ShardGroupDuration: 30 * time.Minute}); err != meta.ErrShardDurationTooLow {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The shard group duration can be the policy's.
+	if err := data.CreateRetentionPolicy("db0", &meta.RetentionPolicyInfo{Name: "rp1", ReplicaN: 1, Duration: time.Hour, ShardGroupDuration: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+}
+
 // Ensure that creating a policy without a name returns an error.
 func TestData_CreateRetentionPolicy_ErrNameRequired(t *testing.T) {
 	data := meta.Data{Nodes: []meta.NodeInfo{{ID: 1}}}
@@ -187,6 +292,36 @@ func TestData_UpdateRetentionPolicy(t *testing.T) {
 	}
 }
 
+// Ensure the resolution of a retention policy must be within its duration.
+func TestData_UpdateRetentionPolicy_Resolution(t *testing.T) {
+	var data meta.Data
+	if err := data.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if err = data.CreateRetentionPolicy("db0", &meta.RetentionPolicyInfo{Name: "rp0", ReplicaN: 1, Duration: 2 * time.Hour, Resolution: 2 * time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Shortening the duration below the resolution is rejected.
+	var rpu meta.RetentionPolicyUpdate
+	rpu.SetDuration(time.Hour)
+	if err := data.UpdateRetentionPolicy("db0", "rp0", &rpu); err != meta.ErrRetentionPolicyResolutionInvalid {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	rpu.SetResolution(5 * time.Minute)
+	if err := data.UpdateRetentionPolicy("db0", "rp0", &rpu); err != nil {
+		t.Fatal(err)
+	} else if rpi, _ := data.RetentionPolicy("db0", "rp0"); rpi.Resolution != 5*time.Minute || rpi.Duration != time.Hour {
+		t.Fatalf("unexpected policy: %#v", rpi)
+	}
+
+	rpu = meta.RetentionPolicyUpdate{}
+	rpu.SetResolution(-time.Minute)
+	if err := data.UpdateRetentionPolicy("db0", "rp0", &rpu); err != meta.ErrRetentionPolicyResolutionInvalid {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
 // Ensure a retention policy can be removed.
 func TestData_DropRetentionPolicy(t *testing.T) {
 	var data meta.Data
@@ -317,6 +452,51 @@ func TestData_CreateShardGroup(t *testing.T) {
 	}
 }
 
+// Ensure the owners of a shard can be updated.
+func TestData_UpdateShardOwners(t *testing.T) {
+	var data meta.Data
+	if err := data.CreateNode("node0"); err != nil {
+		t.Fatal(err)
+	} else if err = data.CreateNode("node1"); err != nil {
+		t.Fatal(err)
+	} else if err = data.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if err = data.CreateRetentionPolicy("db0", &meta.RetentionPolicyInfo{Name: "rp0", ReplicaN: 1, Duration: 1 * time.Hour}); err != nil {
+		t.Fatal(err)
+	} else if err = data.CreateShardGroup("db0", "rp0", time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	owners := func() []meta.ShardOwner {
+		return data.Databases[0].RetentionPolicies[0].ShardGroups[0].Shards[0].Owners
+	}
+	id := data.Databases[0].RetentionPolicies[0].ShardGroups[0].Shards[0].ID
+	from := owners()[0].NodeID
+	to := 3 - from
+
+	// Move the shard to the other node.
+	if err := data.UpdateShardOwners(id, []uint64{to}, []uint64{from}); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(owners(), []meta.ShardOwner{{NodeID: to}}) {
+		t.Fatalf("unexpected owners: %#v", owners())
+	}
+
+	// Adding an existing owner is ignored.
+	if err := data.UpdateShardOwners(id, []uint64{to}, nil); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(owners(), []meta.ShardOwner{{NodeID: to}}) {
+		t.Fatalf("unexpected owners: %#v", owners())
+	}
+
+	// Removing the last owner, adding unknown nodes and updating missing shards fail.
+	if err := data.UpdateShardOwners(id, nil, []uint64{to}); err != meta.ErrShardOwnerRequired {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.UpdateShardOwners(id, []uint64{100}, nil); err != meta.ErrNodeNotFound {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.UpdateShardOwners(id+100, []uint64{from}, nil); err != meta.ErrShardNotFound {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
 // Ensure that a shard group is correctly detected as expired.
 func TestData_ShardGroupExpiredDeleted(t *testing.T) {
 	var data meta.Data
@@ -470,6 +650,221 @@ func TestData_DropContinuousQuery(t *testing.T) {
 	}
 }
 
+// Ensure the last run time of a continuous query can be set.
+func TestData_SetContinuousQueryLastRun(t *testing.T) {
+	var data meta.Data
+	if err := data.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateContinuousQuery("db0", "cq0", "SELECT count() FROM foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if err := data.SetContinuousQueryLastRun("db0", "cq0", now); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(data.Databases[0].ContinuousQueries, []meta.ContinuousQueryInfo{
+		{Name: "cq0", Query: "SELECT count() FROM foo", LastRun: now},
+	}) {
+		t.Fatalf("unexpected queries: %#v", data.Databases[0].ContinuousQueries)
+	}
+
+	// Setting the last run of a missing query should fail.
+	if err := data.SetContinuousQueryLastRun("db0", "cq1", now); err != meta.ErrContinuousQueryNotFound {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+// Ensure a subscription can be created.
+func TestData_CreateSubscription(t *testing.T) {
+	var data meta.Data
+	rpi := &meta.RetentionPolicyInfo{
+		Name:     "rp0",
+		ReplicaN: 3,
+	}
+	if err := data.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateRetentionPolicy("db0", rpi); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateSubscription("db0", "rp0", "s0", "ANY", []string{"udp://h0:1234", "udp://h1:1234"}); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(data.Databases[0].RetentionPolicies[0].Subscriptions, []meta.SubscriptionInfo{
+		{Name: "s0", Mode: "ANY", Destinations: []string{"udp://h0:1234", "udp://h1:1234"}},
+	}) {
+		t.Fatalf("unexpected subscriptions: %#v", data.Databases[0].RetentionPolicies[0].Subscriptions)
+	}
+
+	// Creating the same subscription again should fail.
+	if err := data.CreateSubscription("db0", "rp0", "s0", "ANY", []string{"udp://h0:1234"}); err != meta.ErrSubscriptionExists {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+// Ensure a subscription can be removed.
+func TestData_DropSubscription(t *testing.T) {
+	var data meta.Data
+	rpi := &meta.RetentionPolicyInfo{
+		Name:     "rp0",
+		ReplicaN: 3,
+	}
+	if err := data.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateRetentionPolicy("db0", rpi); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateSubscription("db0", "rp0", "s0", "ANY", []string{"udp://h0:1234", "udp://h1:1234"}); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateSubscription("db0", "rp0", "s1", "ALL", []string{"udp://h0:1234", "udp://h1:1234"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := data.DropSubscription("db0", "rp0", "s0"); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(data.Databases[0].RetentionPolicies[0].Subscriptions, []meta.SubscriptionInfo{
+		{Name: "s1", Mode: "ALL", Destinations: []string{"udp://h0:1234", "udp://h1:1234"}},
+	}) {
+		t.Fatalf("unexpected subscriptions: %#v", data.Databases[0].RetentionPolicies[0].Subscriptions)
+	}
+
+	if err := data.DropSubscription("db0", "rp0", "s0"); err != meta.ErrSubscriptionNotFound {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+// Ensure a tag rule can be created.
+func TestData_CreateTagRule(t *testing.T) {
+	var data meta.Data
+	if err := data.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateTagRule("db0", meta.TagRuleInfo{Name: "lower", Action: meta.TagRuleLowercase}); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(data.Databases[0].TagRules, []meta.TagRuleInfo{
+		{Name: "lower", Action: meta.TagRuleLowercase},
+	}) {
+		t.Fatalf("unexpected tag rules: %#v", data.Databases[0].TagRules)
+	}
+
+	// Creating the same rule again should fail.
+	if err := data.CreateTagRule("db0", meta.TagRuleInfo{Name: "lower", Action: meta.TagRuleTrim}); err != meta.ErrTagRuleExists {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Creating a rule on a missing database should fail.
+	if err := data.CreateTagRule("no_such_db", meta.TagRuleInfo{Name: "lower", Action: meta.TagRuleLowercase}); err != meta.ErrDatabaseNotFound {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+// Ensure a tag rule can be removed.
+func TestData_DropTagRule(t *testing.T) {
+	var data meta.Data
+	if err := data.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateTagRule("db0", meta.TagRuleInfo{Name: "lower", Action: meta.TagRuleLowercase}); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateTagRule("db0", meta.TagRuleInfo{Name: "trim", Action: meta.TagRuleTrim}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := data.DropTagRule("db0", "lower"); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(data.Databases[0].TagRules, []meta.TagRuleInfo{
+		{Name: "trim", Action: meta.TagRuleTrim},
+	}) {
+		t.Fatalf("unexpected tag rules: %#v", data.Databases[0].TagRules)
+	}
+
+	if err := data.DropTagRule("db0", "lower"); err != meta.ErrTagRuleNotFound {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+// Ensure the tag rules of a database are applied in order.
+func TestDatabaseInfo_NormalizeTags(t *testing.T) {
+	di := meta.DatabaseInfo{
+		TagRules: []meta.TagRuleInfo{
+			{Name: "trim", Action: meta.TagRuleTrim},
+			{Name: "lower", Key: "host", Action: meta.TagRuleLowercase},
+			{Name: "hosts", Key: "host", Action: meta.TagRuleMap, Mappings: map[string]string{"web-a": "weba"}},
+		},
+	}
+
+	tags := map[string]string{"host": " Web-A ", "region": " US-West"}
+	if other, changed := di.NormalizeTags(tags); !changed {
+		t.Fatal("expected tags to change")
+	} else if !reflect.DeepEqual(other, map[string]string{"host": "weba", "region": "US-West"}) {
+		t.Fatalf("unexpected tags: %#v", other)
+	} else if tags["host"] != " Web-A " {
+		t.Fatalf("original tags modified: %#v", tags)
+	}
+
+	if _, changed := di.NormalizeTags(map[string]string{"host": "weba"}); changed {
+		t.Fatal("expected tags to be unchanged")
+	}
+}
+
+// Ensure a downsample policy can be created.
+func TestData_CreateDownsamplePolicy(t *testing.T) {
+	var data meta.Data
+	if err := data.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateRetentionPolicy("db0", &meta.RetentionPolicyInfo{Name: "raw", ReplicaN: 1}); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateRetentionPolicy("db0", &meta.RetentionPolicyInfo{Name: "week", ReplicaN: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	dpi := meta.DownsamplePolicyInfo{Name: "ds0", SourceRetentionPolicy: "raw", TargetRetentionPolicy: "week", Interval: time.Hour}
+	if err := data.CreateDownsamplePolicy("db0", dpi); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(data.Databases[0].DownsamplePolicies, []meta.DownsamplePolicyInfo{dpi}) {
+		t.Fatalf("unexpected downsample policies: %#v", data.Databases[0].DownsamplePolicies)
+	}
+
+	// Creating the same policy again should fail.
+	if err := data.CreateDownsamplePolicy("db0", dpi); err != meta.ErrDownsamplePolicyExists {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Both retention policies must exist and differ.
+	if err := data.CreateDownsamplePolicy("db0", meta.DownsamplePolicyInfo{Name: "ds1", SourceRetentionPolicy: "raw", TargetRetentionPolicy: "year"}); err != meta.ErrRetentionPolicyNotFound {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.CreateDownsamplePolicy("db0", meta.DownsamplePolicyInfo{Name: "ds1", SourceRetentionPolicy: "raw", TargetRetentionPolicy: "raw"}); err != meta.ErrDownsamplePolicySameRetentionPolicy {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+// Ensure a downsample policy can be removed and its progress recorded.
+func TestData_DropDownsamplePolicy(t *testing.T) {
+	var data meta.Data
+	if err := data.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateRetentionPolicy("db0", &meta.RetentionPolicyInfo{Name: "raw", ReplicaN: 1}); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateRetentionPolicy("db0", &meta.RetentionPolicyInfo{Name: "week", ReplicaN: 1}); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateDownsamplePolicy("db0", meta.DownsamplePolicyInfo{Name: "ds0", SourceRetentionPolicy: "raw", TargetRetentionPolicy: "week", Interval: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+
+	until := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := data.SetDownsamplePolicyProgress("db0", "ds0", until); err != nil {
+		t.Fatal(err)
+	} else if !data.Databases[0].DownsamplePolicies[0].DownsampledUntil.Equal(until) {
+		t.Fatalf("unexpected progress: %s", data.Databases[0].DownsamplePolicies[0].DownsampledUntil)
+	}
+
+	if err := data.DropDownsamplePolicy("db0", "ds0"); err != nil {
+		t.Fatal(err)
+	} else if len(data.Databases[0].DownsamplePolicies) != 0 {
+		t.Fatalf("unexpected downsample policies: %#v", data.Databases[0].DownsamplePolicies)
+	}
+
+	if err := data.DropDownsamplePolicy("db0", "ds0"); err != meta.ErrDownsamplePolicyNotFound {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.SetDownsamplePolicyProgress("db0", "ds0", until); err != meta.ErrDownsamplePolicyNotFound {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
 // Ensure a user can be created.
 func TestData_CreateUser(t *testing.T) {
 	var data meta.Data
@@ -482,6 +877,26 @@ func TestData_CreateUser(t *testing.T) {
 	}
 }
 
+// Ensure a write-only user can be created and its privileges can't be changed.
+func TestData_CreateWriteOnlyUser(t *testing.T) {
+	var data meta.Data
+	if err := data.CreateWriteOnlyUser("agent", "ABC123", "db0"); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(data.Users, []meta.UserInfo{
+		{Name: "agent", Hash: "ABC123", Privileges: map[string]influxql.Privilege{"db0": influxql.WritePrivilege}, WriteOnly: true},
+	}) {
+		t.Fatalf("unexpected users: %#v", data.Users)
+	}
+
+	if err := data.SetPrivilege("agent", "db0", influxql.ReadPrivilege); err != meta.ErrUserWriteOnly {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.SetAdminPrivilege("agent", true); err != meta.ErrUserWriteOnly {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.CreateWriteOnlyUser("edge", "", ""); err != meta.ErrDatabaseNameRequired {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
 // Ensure that creating a user with no username returns an error.
 func TestData_CreateUser_ErrUsernameRequired(t *testing.T) {
 	var data meta.Data
@@ -552,6 +967,68 @@ func TestData_UpdateUser_ErrUserNotFound(t *testing.T) {
 	}
 }
 
+// Ensure the effective privileges of users include those implied by admin and
+// ALL privileges.
+func TestData_EffectivePrivileges(t *testing.T) {
+	var data meta.Data
+	for _, name := range []string{"db0", "db1"} {
+		if err := data.CreateDatabase(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := data.CreateUser("susy", "", false); err != nil {
+		t.Fatal(err)
+	} else if err := data.SetPrivilege("susy", "db1", influxql.AllPrivileges); err != nil {
+		t.Fatal(err)
+	} else if err := data.SetPrivilege("susy", "dropped", influxql.ReadPrivilege); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateUser("bob", "", true); err != nil {
+		t.Fatal(err)
+	}
+
+	if a, err := data.EffectivePrivileges("susy"); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(a, []meta.EffectivePrivilege{
+		{Database: "db0", Privilege: influxql.NoPrivileges},
+		{Database: "db1", Privilege: influxql.AllPrivileges, Read: true, Write: true},
+		{Database: "dropped", Privilege: influxql.ReadPrivilege, Read: true},
+	}) {
+		t.Fatalf("unexpected privileges: %#v", a)
+	}
+
+	if a, err := data.EffectivePrivileges("bob"); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(a, []meta.EffectivePrivilege{
+		{Database: "db0", Privilege: influxql.NoPrivileges, Read: true, Write: true},
+		{Database: "db1", Privilege: influxql.NoPrivileges, Read: true, Write: true},
+	}) {
+		t.Fatalf("unexpected privileges: %#v", a)
+	}
+
+	if _, err := data.EffectivePrivileges("jdoe"); err != meta.ErrUserNotFound {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+// Ensure a user's limits can be set.
+func TestData_SetUserLimits(t *testing.T) {
+	var data meta.Data
+	if err := data.CreateUser("susy", "", false); err != nil {
+		t.Fatal(err)
+	}
+
+	limits := meta.UserLimits{MaxQueries: 2, MaxRange: time.Hour, MaxPoints: 1000}
+	if err := data.SetUserLimits("susy", limits); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(data.User("susy"), &meta.UserInfo{Name: "susy", Limits: limits}) {
+		t.Fatalf("unexpected user: %#v", data.User("susy"))
+	}
+
+	if err := data.SetUserLimits("bob", limits); err != meta.ErrUserNotFound {
+		t.Fatal(err)
+	}
+}
+
 // Ensure the data can be deeply copied.
 func TestData_Clone(t *testing.T) {
 	data := meta.Data{
@@ -588,6 +1065,9 @@ func TestData_Clone(t *testing.T) {
 								},
 							},
 						},
+						Subscriptions: []meta.SubscriptionInfo{
+							{Name: "s0", Mode: "ANY", Destinations: []string{"udp://h0:1234"}},
+						},
 					},
 				},
 				ContinuousQueries: []meta.ContinuousQueryInfo{
@@ -642,6 +1122,7 @@ func TestData_MarshalBinary(t *testing.T) {
 						ReplicaN:           3,
 						Duration:           10 * time.Second,
 						ShardGroupDuration: 3 * time.Millisecond,
+						Resolution:         time.Second,
 						ShardGroups: []meta.ShardGroupInfo{
 							{
 								ID:        100,
@@ -659,10 +1140,29 @@ func TestData_MarshalBinary(t *testing.T) {
 								},
 							},
 						},
+						Subscriptions: []meta.SubscriptionInfo{
+							{Name: "s0", Mode: "ANY", Destinations: []string{"udp://h0:1234"}},
+						},
 					},
 				},
 				ContinuousQueries: []meta.ContinuousQueryInfo{
-					{Query: "SELECT count() FROM foo"},
+					{Query: "SELECT count() FROM foo", LastRun: time.Unix(0, 1000).UTC()},
+				},
+				TagRules: []meta.TagRuleInfo{
+					{Name: "lower", Action: meta.TagRuleLowercase},
+					{Name: "hosts", Key: "host", Action: meta.TagRuleMap, Mappings: map[string]string{"WebA": "weba", "web-a": "weba"}},
+				},
+				DownsamplePolicies: []meta.DownsamplePolicyInfo{
+					{Name: "ds0", SourceRetentionPolicy: "rpx", TargetRetentionPolicy: "rpy", Interval: time.Hour},
+					{
+						Name:                  "ds1",
+						SourceRetentionPolicy: "rpy",
+						TargetRetentionPolicy: "rpz",
+						Interval:              24 * time.Hour,
+						DefaultAggregate:      "max",
+						Aggregates:            map[string]string{"n": "sum", "state": "last"},
+						DownsampledUntil:      time.Unix(0, 2000).UTC(),
+					},
 				},
 			},
 		},
@@ -672,6 +1172,13 @@ func TestData_MarshalBinary(t *testing.T) {
 				Hash:       "ABC123",
 				Admin:      true,
 				Privileges: map[string]influxql.Privilege{"db0": influxql.AllPrivileges},
+				Limits:     meta.UserLimits{MaxQueries: 2, MaxRange: time.Hour, MaxPoints: 1000, Batch: true},
+			},
+			{
+				Name:       "agent",
+				Hash:       "XYZ",
+				Privileges: map[string]influxql.Privilege{"db0": influxql.WritePrivilege},
+				WriteOnly:  true,
 			},
 		},
 	}
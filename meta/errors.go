@@ -23,6 +23,10 @@ var (
 	// ErrNodeNotFound is returned when mutating a node that doesn't exist.
 	ErrNodeNotFound = errors.New("node not found")
 
+	// ErrNodeOwnsShards is returned when deleting a node which is the only
+	// owner of one or more shards.
+	ErrNodeOwnsShards = errors.New("node is the only owner of one or more shards")
+
 	// ErrNodesRequired is returned when at least one node is required for an operation.
 	// This occurs when creating a shard group.
 	ErrNodesRequired = errors.New("at least one node required")
@@ -65,6 +69,19 @@ var (
 	// ErrReplicationFactorTooLow is returned when the replication factor is not in an
 	// acceptable range.
 	ErrReplicationFactorTooLow = errors.New("replication factor must be greater than 0")
+
+	// ErrRetentionPolicyResolutionInvalid is returned when the resolution of a
+	// policy is negative or longer than its duration.
+	ErrRetentionPolicyResolutionInvalid = errors.New("retention policy resolution must be between 0 and the policy duration")
+
+	// ErrIncompatibleDurations is returned when creating a policy with a shard
+	// group duration longer than the policy duration.
+	ErrIncompatibleDurations = errors.New("retention policy duration must be at least the shard duration")
+
+	// ErrShardDurationTooLow is returned when creating a policy with a shard
+	// group duration lower than the allowed minimum.
+	ErrShardDurationTooLow = errors.New(fmt.Sprintf("shard duration must be at least %s",
+		RetentionPolicyMinDuration))
 )
 
 var (
@@ -73,6 +90,12 @@ var (
 
 	// ErrShardGroupNotFound is returned when mutating a shard group that doesn't exist.
 	ErrShardGroupNotFound = errors.New("shard group not found")
+
+	// ErrShardNotFound is returned when mutating a shard that doesn't exist.
+	ErrShardNotFound = errors.New("shard not found")
+
+	// ErrShardOwnerRequired is returned when removing the last owner of a shard.
+	ErrShardOwnerRequired = errors.New("shard must have at least one owner")
 )
 
 var (
@@ -83,6 +106,34 @@ var (
 	ErrContinuousQueryNotFound = errors.New("continuous query not found")
 )
 
+var (
+	// ErrSubscriptionExists is returned when creating an already existing subscription.
+	ErrSubscriptionExists = errors.New("subscription already exists")
+
+	// ErrSubscriptionNotFound is returned when removing a subscription that doesn't exist.
+	ErrSubscriptionNotFound = errors.New("subscription not found")
+)
+
+var (
+	// ErrTagRuleExists is returned when creating an already existing tag rule.
+	ErrTagRuleExists = errors.New("tag rule already exists")
+
+	// ErrTagRuleNotFound is returned when removing a tag rule that doesn't exist.
+	ErrTagRuleNotFound = errors.New("tag rule not found")
+)
+
+var (
+	// ErrDownsamplePolicyExists is returned when creating an already existing downsample policy.
+	ErrDownsamplePolicyExists = errors.New("downsample policy already exists")
+
+	// ErrDownsamplePolicyNotFound is returned when mutating a downsample policy that doesn't exist.
+	ErrDownsamplePolicyNotFound = errors.New("downsample policy not found")
+
+	// ErrDownsamplePolicySameRetentionPolicy is returned when creating a
+	// downsample policy that writes to the retention policy it reads from.
+	ErrDownsamplePolicySameRetentionPolicy = errors.New("downsample policy source and target retention policies must differ")
+)
+
 var (
 	// ErrUserExists is returned when creating an already existing user.
 	ErrUserExists = errors.New("user already exists")
@@ -92,11 +143,15 @@ var (
 
 	// ErrUsernameRequired is returned when creating a user without a username.
 	ErrUsernameRequired = errors.New("username required")
+
+	// ErrUserWriteOnly is returned when changing the privileges of a
+	// write-only user.
+	ErrUserWriteOnly = errors.New("privileges of write-only users can't be changed")
 )
 
 var errs = [...]error{
 	ErrStoreOpen, ErrStoreClosed,
-	ErrNodeExists, ErrNodeNotFound,
+	ErrNodeExists, ErrNodeNotFound, ErrNodeOwnsShards,
 	ErrDatabaseExists, ErrDatabaseNotFound, ErrDatabaseNameRequired,
 }
 
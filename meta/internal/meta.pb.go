@@ -12,11 +12,14 @@ It has these top-level messages:
 	Data
 	NodeInfo
 	DatabaseInfo
+	TagRuleInfo
+	DownsamplePolicyInfo
 	RetentionPolicyInfo
 	ShardGroupInfo
 	ShardInfo
 	ShardOwner
 	ContinuousQueryInfo
+	SubscriptionInfo
 	UserInfo
 	UserPrivilege
 	Command
@@ -39,6 +42,18 @@ It has these top-level messages:
 	SetDataCommand
 	SetAdminPrivilegeCommand
 	UpdateNodeCommand
+	CreateSubscriptionCommand
+	DropSubscriptionCommand
+	SetContinuousQueryLastRunCommand
+	UpdateShardOwnersCommand
+	CreateTagRuleCommand
+	DropTagRuleCommand
+	CreateDownsamplePolicyCommand
+	DropDownsamplePolicyCommand
+	SetDownsamplePolicyProgressCommand
+	SetUserLimitsCommand
+	BatchCommand
+	RenameDatabaseCommand
 	Response
 	ResponseHeader
 	ErrorResponse
@@ -95,25 +110,37 @@ func (x *RPCType) UnmarshalJSON(data []byte) error {
 type Command_Type int32
 
 const (
-	Command_CreateNodeCommand                Command_Type = 1
-	Command_DeleteNodeCommand                Command_Type = 2
-	Command_CreateDatabaseCommand            Command_Type = 3
-	Command_DropDatabaseCommand              Command_Type = 4
-	Command_CreateRetentionPolicyCommand     Command_Type = 5
-	Command_DropRetentionPolicyCommand       Command_Type = 6
-	Command_SetDefaultRetentionPolicyCommand Command_Type = 7
-	Command_UpdateRetentionPolicyCommand     Command_Type = 8
-	Command_CreateShardGroupCommand          Command_Type = 9
-	Command_DeleteShardGroupCommand          Command_Type = 10
-	Command_CreateContinuousQueryCommand     Command_Type = 11
-	Command_DropContinuousQueryCommand       Command_Type = 12
-	Command_CreateUserCommand                Command_Type = 13
-	Command_DropUserCommand                  Command_Type = 14
-	Command_UpdateUserCommand                Command_Type = 15
-	Command_SetPrivilegeCommand              Command_Type = 16
-	Command_SetDataCommand                   Command_Type = 17
-	Command_SetAdminPrivilegeCommand         Command_Type = 18
-	Command_UpdateNodeCommand                Command_Type = 19
+	Command_CreateNodeCommand                  Command_Type = 1
+	Command_DeleteNodeCommand                  Command_Type = 2
+	Command_CreateDatabaseCommand              Command_Type = 3
+	Command_DropDatabaseCommand                Command_Type = 4
+	Command_CreateRetentionPolicyCommand       Command_Type = 5
+	Command_DropRetentionPolicyCommand         Command_Type = 6
+	Command_SetDefaultRetentionPolicyCommand   Command_Type = 7
+	Command_UpdateRetentionPolicyCommand       Command_Type = 8
+	Command_CreateShardGroupCommand            Command_Type = 9
+	Command_DeleteShardGroupCommand            Command_Type = 10
+	Command_CreateContinuousQueryCommand       Command_Type = 11
+	Command_DropContinuousQueryCommand         Command_Type = 12
+	Command_CreateUserCommand                  Command_Type = 13
+	Command_DropUserCommand                    Command_Type = 14
+	Command_UpdateUserCommand                  Command_Type = 15
+	Command_SetPrivilegeCommand                Command_Type = 16
+	Command_SetDataCommand                     Command_Type = 17
+	Command_SetAdminPrivilegeCommand           Command_Type = 18
+	Command_UpdateNodeCommand                  Command_Type = 19
+	Command_CreateSubscriptionCommand          Command_Type = 20
+	Command_DropSubscriptionCommand            Command_Type = 21
+	Command_SetContinuousQueryLastRunCommand   Command_Type = 22
+	Command_UpdateShardOwnersCommand           Command_Type = 23
+	Command_CreateTagRuleCommand               Command_Type = 24
+	Command_DropTagRuleCommand                 Command_Type = 25
+	Command_CreateDownsamplePolicyCommand      Command_Type = 26
+	Command_DropDownsamplePolicyCommand        Command_Type = 27
+	Command_SetDownsamplePolicyProgressCommand Command_Type = 28
+	Command_SetUserLimitsCommand               Command_Type = 29
+	Command_BatchCommand                       Command_Type = 30
+	Command_RenameDatabaseCommand              Command_Type = 31
 )
 
 var Command_Type_name = map[int32]string{
@@ -136,27 +163,51 @@ var Command_Type_name = map[int32]string{
 	17: "SetDataCommand",
 	18: "SetAdminPrivilegeCommand",
 	19: "UpdateNodeCommand",
+	20: "CreateSubscriptionCommand",
+	21: "DropSubscriptionCommand",
+	22: "SetContinuousQueryLastRunCommand",
+	23: "UpdateShardOwnersCommand",
+	24: "CreateTagRuleCommand",
+	25: "DropTagRuleCommand",
+	26: "CreateDownsamplePolicyCommand",
+	27: "DropDownsamplePolicyCommand",
+	28: "SetDownsamplePolicyProgressCommand",
+	29: "SetUserLimitsCommand",
+	30: "BatchCommand",
+	31: "RenameDatabaseCommand",
 }
 var Command_Type_value = map[string]int32{
-	"CreateNodeCommand":                1,
-	"DeleteNodeCommand":                2,
-	"CreateDatabaseCommand":            3,
-	"DropDatabaseCommand":              4,
-	"CreateRetentionPolicyCommand":     5,
-	"DropRetentionPolicyCommand":       6,
-	"SetDefaultRetentionPolicyCommand": 7,
-	"UpdateRetentionPolicyCommand":     8,
-	"CreateShardGroupCommand":          9,
-	"DeleteShardGroupCommand":          10,
-	"CreateContinuousQueryCommand":     11,
-	"DropContinuousQueryCommand":       12,
-	"CreateUserCommand":                13,
-	"DropUserCommand":                  14,
-	"UpdateUserCommand":                15,
-	"SetPrivilegeCommand":              16,
-	"SetDataCommand":                   17,
-	"SetAdminPrivilegeCommand":         18,
-	"UpdateNodeCommand":                19,
+	"CreateNodeCommand":                  1,
+	"DeleteNodeCommand":                  2,
+	"CreateDatabaseCommand":              3,
+	"DropDatabaseCommand":                4,
+	"CreateRetentionPolicyCommand":       5,
+	"DropRetentionPolicyCommand":         6,
+	"SetDefaultRetentionPolicyCommand":   7,
+	"UpdateRetentionPolicyCommand":       8,
+	"CreateShardGroupCommand":            9,
+	"DeleteShardGroupCommand":            10,
+	"CreateContinuousQueryCommand":       11,
+	"DropContinuousQueryCommand":         12,
+	"CreateUserCommand":                  13,
+	"DropUserCommand":                    14,
+	"UpdateUserCommand":                  15,
+	"SetPrivilegeCommand":                16,
+	"SetDataCommand":                     17,
+	"SetAdminPrivilegeCommand":           18,
+	"UpdateNodeCommand":                  19,
+	"CreateSubscriptionCommand":          20,
+	"DropSubscriptionCommand":            21,
+	"SetContinuousQueryLastRunCommand":   22,
+	"UpdateShardOwnersCommand":           23,
+	"CreateTagRuleCommand":               24,
+	"DropTagRuleCommand":                 25,
+	"CreateDownsamplePolicyCommand":      26,
+	"DropDownsamplePolicyCommand":        27,
+	"SetDownsamplePolicyProgressCommand": 28,
+	"SetUserLimitsCommand":               29,
+	"BatchCommand":                       30,
+	"RenameDatabaseCommand":              31,
 }
 
 func (x Command_Type) Enum() *Command_Type {
@@ -281,11 +332,13 @@ func (m *NodeInfo) GetHost() string {
 }
 
 type DatabaseInfo struct {
-	Name                   *string                `protobuf:"bytes,1,req" json:"Name,omitempty"`
-	DefaultRetentionPolicy *string                `protobuf:"bytes,2,req" json:"DefaultRetentionPolicy,omitempty"`
-	RetentionPolicies      []*RetentionPolicyInfo `protobuf:"bytes,3,rep" json:"RetentionPolicies,omitempty"`
-	ContinuousQueries      []*ContinuousQueryInfo `protobuf:"bytes,4,rep" json:"ContinuousQueries,omitempty"`
-	XXX_unrecognized       []byte                 `json:"-"`
+	Name                   *string                 `protobuf:"bytes,1,req" json:"Name,omitempty"`
+	DefaultRetentionPolicy *string                 `protobuf:"bytes,2,req" json:"DefaultRetentionPolicy,omitempty"`
+	RetentionPolicies      []*RetentionPolicyInfo  `protobuf:"bytes,3,rep" json:"RetentionPolicies,omitempty"`
+	ContinuousQueries      []*ContinuousQueryInfo  `protobuf:"bytes,4,rep" json:"ContinuousQueries,omitempty"`
+	TagRules               []*TagRuleInfo          `protobuf:"bytes,5,rep" json:"TagRules,omitempty"`
+	DownsamplePolicies     []*DownsamplePolicyInfo `protobuf:"bytes,6,rep" json:"DownsamplePolicies,omitempty"`
+	XXX_unrecognized       []byte                  `json:"-"`
 }
 
 func (m *DatabaseInfo) Reset()         { *m = DatabaseInfo{} }
@@ -320,13 +373,149 @@ func (m *DatabaseInfo) GetContinuousQueries() []*ContinuousQueryInfo {
 	return nil
 }
 
+func (m *DatabaseInfo) GetTagRules() []*TagRuleInfo {
+	if m != nil {
+		return m.TagRules
+	}
+	return nil
+}
+
+func (m *DatabaseInfo) GetDownsamplePolicies() []*DownsamplePolicyInfo {
+	if m != nil {
+		return m.DownsamplePolicies
+	}
+	return nil
+}
+
+type TagRuleInfo struct {
+	Name             *string  `protobuf:"bytes,1,req" json:"Name,omitempty"`
+	Key              *string  `protobuf:"bytes,2,opt" json:"Key,omitempty"`
+	Action           *string  `protobuf:"bytes,3,req" json:"Action,omitempty"`
+	From             []string `protobuf:"bytes,4,rep" json:"From,omitempty"`
+	To               []string `protobuf:"bytes,5,rep" json:"To,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *TagRuleInfo) Reset()         { *m = TagRuleInfo{} }
+func (m *TagRuleInfo) String() string { return proto.CompactTextString(m) }
+func (*TagRuleInfo) ProtoMessage()    {}
+
+func (m *TagRuleInfo) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *TagRuleInfo) GetKey() string {
+	if m != nil && m.Key != nil {
+		return *m.Key
+	}
+	return ""
+}
+
+func (m *TagRuleInfo) GetAction() string {
+	if m != nil && m.Action != nil {
+		return *m.Action
+	}
+	return ""
+}
+
+func (m *TagRuleInfo) GetFrom() []string {
+	if m != nil {
+		return m.From
+	}
+	return nil
+}
+
+func (m *TagRuleInfo) GetTo() []string {
+	if m != nil {
+		return m.To
+	}
+	return nil
+}
+
+type DownsamplePolicyInfo struct {
+	Name                  *string  `protobuf:"bytes,1,req" json:"Name,omitempty"`
+	SourceRetentionPolicy *string  `protobuf:"bytes,2,req" json:"SourceRetentionPolicy,omitempty"`
+	TargetRetentionPolicy *string  `protobuf:"bytes,3,req" json:"TargetRetentionPolicy,omitempty"`
+	Interval              *int64   `protobuf:"varint,4,req" json:"Interval,omitempty"`
+	DefaultAggregate      *string  `protobuf:"bytes,5,opt" json:"DefaultAggregate,omitempty"`
+	Fields                []string `protobuf:"bytes,6,rep" json:"Fields,omitempty"`
+	FieldAggregates       []string `protobuf:"bytes,7,rep" json:"FieldAggregates,omitempty"`
+	DownsampledUntil      *int64   `protobuf:"varint,8,opt" json:"DownsampledUntil,omitempty"`
+	XXX_unrecognized      []byte   `json:"-"`
+}
+
+func (m *DownsamplePolicyInfo) Reset()         { *m = DownsamplePolicyInfo{} }
+func (m *DownsamplePolicyInfo) String() string { return proto.CompactTextString(m) }
+func (*DownsamplePolicyInfo) ProtoMessage()    {}
+
+func (m *DownsamplePolicyInfo) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *DownsamplePolicyInfo) GetSourceRetentionPolicy() string {
+	if m != nil && m.SourceRetentionPolicy != nil {
+		return *m.SourceRetentionPolicy
+	}
+	return ""
+}
+
+func (m *DownsamplePolicyInfo) GetTargetRetentionPolicy() string {
+	if m != nil && m.TargetRetentionPolicy != nil {
+		return *m.TargetRetentionPolicy
+	}
+	return ""
+}
+
+func (m *DownsamplePolicyInfo) GetInterval() int64 {
+	if m != nil && m.Interval != nil {
+		return *m.Interval
+	}
+	return 0
+}
+
+func (m *DownsamplePolicyInfo) GetDefaultAggregate() string {
+	if m != nil && m.DefaultAggregate != nil {
+		return *m.DefaultAggregate
+	}
+	return ""
+}
+
+func (m *DownsamplePolicyInfo) GetFields() []string {
+	if m != nil {
+		return m.Fields
+	}
+	return nil
+}
+
+func (m *DownsamplePolicyInfo) GetFieldAggregates() []string {
+	if m != nil {
+		return m.FieldAggregates
+	}
+	return nil
+}
+
+func (m *DownsamplePolicyInfo) GetDownsampledUntil() int64 {
+	if m != nil && m.DownsampledUntil != nil {
+		return *m.DownsampledUntil
+	}
+	return 0
+}
+
 type RetentionPolicyInfo struct {
-	Name               *string           `protobuf:"bytes,1,req" json:"Name,omitempty"`
-	Duration           *int64            `protobuf:"varint,2,req" json:"Duration,omitempty"`
-	ShardGroupDuration *int64            `protobuf:"varint,3,req" json:"ShardGroupDuration,omitempty"`
-	ReplicaN           *uint32           `protobuf:"varint,4,req" json:"ReplicaN,omitempty"`
-	ShardGroups        []*ShardGroupInfo `protobuf:"bytes,5,rep" json:"ShardGroups,omitempty"`
-	XXX_unrecognized   []byte            `json:"-"`
+	Name               *string             `protobuf:"bytes,1,req" json:"Name,omitempty"`
+	Duration           *int64              `protobuf:"varint,2,req" json:"Duration,omitempty"`
+	ShardGroupDuration *int64              `protobuf:"varint,3,req" json:"ShardGroupDuration,omitempty"`
+	ReplicaN           *uint32             `protobuf:"varint,4,req" json:"ReplicaN,omitempty"`
+	ShardGroups        []*ShardGroupInfo   `protobuf:"bytes,5,rep" json:"ShardGroups,omitempty"`
+	Subscriptions      []*SubscriptionInfo `protobuf:"bytes,6,rep" json:"Subscriptions,omitempty"`
+	Resolution         *int64              `protobuf:"varint,7,opt" json:"Resolution,omitempty"`
+	XXX_unrecognized   []byte              `json:"-"`
 }
 
 func (m *RetentionPolicyInfo) Reset()         { *m = RetentionPolicyInfo{} }
@@ -368,6 +557,20 @@ func (m *RetentionPolicyInfo) GetShardGroups() []*ShardGroupInfo {
 	return nil
 }
 
+func (m *RetentionPolicyInfo) GetSubscriptions() []*SubscriptionInfo {
+	if m != nil {
+		return m.Subscriptions
+	}
+	return nil
+}
+
+func (m *RetentionPolicyInfo) GetResolution() int64 {
+	if m != nil && m.Resolution != nil {
+		return *m.Resolution
+	}
+	return 0
+}
+
 type ShardGroupInfo struct {
 	ID               *uint64      `protobuf:"varint,1,req" json:"ID,omitempty"`
 	StartTime        *int64       `protobuf:"varint,2,req" json:"StartTime,omitempty"`
@@ -467,6 +670,7 @@ func (m *ShardOwner) GetNodeID() uint64 {
 type ContinuousQueryInfo struct {
 	Name             *string `protobuf:"bytes,1,req" json:"Name,omitempty"`
 	Query            *string `protobuf:"bytes,2,req" json:"Query,omitempty"`
+	LastRun          *int64  `protobuf:"varint,3,opt" json:"LastRun,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
@@ -488,11 +692,55 @@ func (m *ContinuousQueryInfo) GetQuery() string {
 	return ""
 }
 
+func (m *ContinuousQueryInfo) GetLastRun() int64 {
+	if m != nil && m.LastRun != nil {
+		return *m.LastRun
+	}
+	return 0
+}
+
+type SubscriptionInfo struct {
+	Name             *string  `protobuf:"bytes,1,req" json:"Name,omitempty"`
+	Mode             *string  `protobuf:"bytes,2,req" json:"Mode,omitempty"`
+	Destinations     []string `protobuf:"bytes,3,rep" json:"Destinations,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *SubscriptionInfo) Reset()         { *m = SubscriptionInfo{} }
+func (m *SubscriptionInfo) String() string { return proto.CompactTextString(m) }
+func (*SubscriptionInfo) ProtoMessage()    {}
+
+func (m *SubscriptionInfo) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *SubscriptionInfo) GetMode() string {
+	if m != nil && m.Mode != nil {
+		return *m.Mode
+	}
+	return ""
+}
+
+func (m *SubscriptionInfo) GetDestinations() []string {
+	if m != nil {
+		return m.Destinations
+	}
+	return nil
+}
+
 type UserInfo struct {
 	Name             *string          `protobuf:"bytes,1,req" json:"Name,omitempty"`
 	Hash             *string          `protobuf:"bytes,2,req" json:"Hash,omitempty"`
 	Admin            *bool            `protobuf:"varint,3,req" json:"Admin,omitempty"`
 	Privileges       []*UserPrivilege `protobuf:"bytes,4,rep" json:"Privileges,omitempty"`
+	MaxQueries       *int64           `protobuf:"varint,5,opt" json:"MaxQueries,omitempty"`
+	MaxRange         *int64           `protobuf:"varint,6,opt" json:"MaxRange,omitempty"`
+	MaxPoints        *int64           `protobuf:"varint,7,opt" json:"MaxPoints,omitempty"`
+	Batch            *bool            `protobuf:"varint,8,opt" json:"Batch,omitempty"`
+	WriteOnly        *bool            `protobuf:"varint,9,opt" json:"WriteOnly,omitempty"`
 	XXX_unrecognized []byte           `json:"-"`
 }
 
@@ -528,6 +776,41 @@ func (m *UserInfo) GetPrivileges() []*UserPrivilege {
 	return nil
 }
 
+func (m *UserInfo) GetMaxQueries() int64 {
+	if m != nil && m.MaxQueries != nil {
+		return *m.MaxQueries
+	}
+	return 0
+}
+
+func (m *UserInfo) GetMaxRange() int64 {
+	if m != nil && m.MaxRange != nil {
+		return *m.MaxRange
+	}
+	return 0
+}
+
+func (m *UserInfo) GetMaxPoints() int64 {
+	if m != nil && m.MaxPoints != nil {
+		return *m.MaxPoints
+	}
+	return 0
+}
+
+func (m *UserInfo) GetBatch() bool {
+	if m != nil && m.Batch != nil {
+		return *m.Batch
+	}
+	return false
+}
+
+func (m *UserInfo) GetWriteOnly() bool {
+	if m != nil && m.WriteOnly != nil {
+		return *m.WriteOnly
+	}
+	return false
+}
+
 type UserPrivilege struct {
 	Database         *string `protobuf:"bytes,1,req" json:"Database,omitempty"`
 	Privilege        *int32  `protobuf:"varint,2,req" json:"Privilege,omitempty"`
@@ -617,6 +900,7 @@ var E_CreateNodeCommand_Command = &proto.ExtensionDesc{
 
 type DeleteNodeCommand struct {
 	ID               *uint64 `protobuf:"varint,1,req" json:"ID,omitempty"`
+	Force            *bool   `protobuf:"varint,2,opt" json:"Force,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
@@ -631,6 +915,13 @@ func (m *DeleteNodeCommand) GetID() uint64 {
 	return 0
 }
 
+func (m *DeleteNodeCommand) GetForce() bool {
+	if m != nil && m.Force != nil {
+		return *m.Force
+	}
+	return false
+}
+
 var E_DeleteNodeCommand_Command = &proto.ExtensionDesc{
 	ExtendedType:  (*Command)(nil),
 	ExtensionType: (*DeleteNodeCommand)(nil),
@@ -789,6 +1080,7 @@ type UpdateRetentionPolicyCommand struct {
 	NewName          *string `protobuf:"bytes,3,opt" json:"NewName,omitempty"`
 	Duration         *int64  `protobuf:"varint,4,opt" json:"Duration,omitempty"`
 	ReplicaN         *uint32 `protobuf:"varint,5,opt" json:"ReplicaN,omitempty"`
+	Resolution       *int64  `protobuf:"varint,6,opt" json:"Resolution,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
@@ -831,6 +1123,13 @@ func (m *UpdateRetentionPolicyCommand) GetReplicaN() uint32 {
 	return 0
 }
 
+func (m *UpdateRetentionPolicyCommand) GetResolution() int64 {
+	if m != nil && m.Resolution != nil {
+		return *m.Resolution
+	}
+	return 0
+}
+
 var E_UpdateRetentionPolicyCommand_Command = &proto.ExtensionDesc{
 	ExtendedType:  (*Command)(nil),
 	ExtensionType: (*UpdateRetentionPolicyCommand)(nil),
@@ -992,10 +1291,11 @@ var E_DropContinuousQueryCommand_Command = &proto.ExtensionDesc{
 }
 
 type CreateUserCommand struct {
-	Name             *string `protobuf:"bytes,1,req" json:"Name,omitempty"`
-	Hash             *string `protobuf:"bytes,2,req" json:"Hash,omitempty"`
-	Admin            *bool   `protobuf:"varint,3,req" json:"Admin,omitempty"`
-	XXX_unrecognized []byte  `json:"-"`
+	Name              *string `protobuf:"bytes,1,req" json:"Name,omitempty"`
+	Hash              *string `protobuf:"bytes,2,req" json:"Hash,omitempty"`
+	Admin             *bool   `protobuf:"varint,3,req" json:"Admin,omitempty"`
+	WriteOnlyDatabase *string `protobuf:"bytes,4,opt" json:"WriteOnlyDatabase,omitempty"`
+	XXX_unrecognized  []byte  `json:"-"`
 }
 
 func (m *CreateUserCommand) Reset()         { *m = CreateUserCommand{} }
@@ -1023,6 +1323,13 @@ func (m *CreateUserCommand) GetAdmin() bool {
 	return false
 }
 
+func (m *CreateUserCommand) GetWriteOnlyDatabase() string {
+	if m != nil && m.WriteOnlyDatabase != nil {
+		return *m.WriteOnlyDatabase
+	}
+	return ""
+}
+
 var E_CreateUserCommand_Command = &proto.ExtensionDesc{
 	ExtendedType:  (*Command)(nil),
 	ExtensionType: (*CreateUserCommand)(nil),
@@ -1215,6 +1522,462 @@ var E_UpdateNodeCommand_Command = &proto.ExtensionDesc{
 	Tag:           "bytes,119,opt,name=command",
 }
 
+type CreateSubscriptionCommand struct {
+	Name             *string  `protobuf:"bytes,1,req" json:"Name,omitempty"`
+	Database         *string  `protobuf:"bytes,2,req" json:"Database,omitempty"`
+	RetentionPolicy  *string  `protobuf:"bytes,3,req" json:"RetentionPolicy,omitempty"`
+	Mode             *string  `protobuf:"bytes,4,req" json:"Mode,omitempty"`
+	Destinations     []string `protobuf:"bytes,5,rep" json:"Destinations,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *CreateSubscriptionCommand) Reset()         { *m = CreateSubscriptionCommand{} }
+func (m *CreateSubscriptionCommand) String() string { return proto.CompactTextString(m) }
+func (*CreateSubscriptionCommand) ProtoMessage()    {}
+
+func (m *CreateSubscriptionCommand) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *CreateSubscriptionCommand) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *CreateSubscriptionCommand) GetRetentionPolicy() string {
+	if m != nil && m.RetentionPolicy != nil {
+		return *m.RetentionPolicy
+	}
+	return ""
+}
+
+func (m *CreateSubscriptionCommand) GetMode() string {
+	if m != nil && m.Mode != nil {
+		return *m.Mode
+	}
+	return ""
+}
+
+func (m *CreateSubscriptionCommand) GetDestinations() []string {
+	if m != nil {
+		return m.Destinations
+	}
+	return nil
+}
+
+var E_CreateSubscriptionCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*CreateSubscriptionCommand)(nil),
+	Field:         120,
+	Name:          "internal.CreateSubscriptionCommand.command",
+	Tag:           "bytes,120,opt,name=command",
+}
+
+type DropSubscriptionCommand struct {
+	Name             *string `protobuf:"bytes,1,req" json:"Name,omitempty"`
+	Database         *string `protobuf:"bytes,2,req" json:"Database,omitempty"`
+	RetentionPolicy  *string `protobuf:"bytes,3,req" json:"RetentionPolicy,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *DropSubscriptionCommand) Reset()         { *m = DropSubscriptionCommand{} }
+func (m *DropSubscriptionCommand) String() string { return proto.CompactTextString(m) }
+func (*DropSubscriptionCommand) ProtoMessage()    {}
+
+func (m *DropSubscriptionCommand) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *DropSubscriptionCommand) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *DropSubscriptionCommand) GetRetentionPolicy() string {
+	if m != nil && m.RetentionPolicy != nil {
+		return *m.RetentionPolicy
+	}
+	return ""
+}
+
+var E_DropSubscriptionCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*DropSubscriptionCommand)(nil),
+	Field:         121,
+	Name:          "internal.DropSubscriptionCommand.command",
+	Tag:           "bytes,121,opt,name=command",
+}
+
+type SetContinuousQueryLastRunCommand struct {
+	Database         *string `protobuf:"bytes,1,req" json:"Database,omitempty"`
+	Name             *string `protobuf:"bytes,2,req" json:"Name,omitempty"`
+	LastRun          *int64  `protobuf:"varint,3,req" json:"LastRun,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *SetContinuousQueryLastRunCommand) Reset()         { *m = SetContinuousQueryLastRunCommand{} }
+func (m *SetContinuousQueryLastRunCommand) String() string { return proto.CompactTextString(m) }
+func (*SetContinuousQueryLastRunCommand) ProtoMessage()    {}
+
+func (m *SetContinuousQueryLastRunCommand) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *SetContinuousQueryLastRunCommand) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *SetContinuousQueryLastRunCommand) GetLastRun() int64 {
+	if m != nil && m.LastRun != nil {
+		return *m.LastRun
+	}
+	return 0
+}
+
+var E_SetContinuousQueryLastRunCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*SetContinuousQueryLastRunCommand)(nil),
+	Field:         122,
+	Name:          "internal.SetContinuousQueryLastRunCommand.command",
+	Tag:           "bytes,122,opt,name=command",
+}
+
+type UpdateShardOwnersCommand struct {
+	ID               *uint64  `protobuf:"varint,1,req" json:"ID,omitempty"`
+	AddedOwners      []uint64 `protobuf:"varint,2,rep" json:"AddedOwners,omitempty"`
+	RemovedOwners    []uint64 `protobuf:"varint,3,rep" json:"RemovedOwners,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *UpdateShardOwnersCommand) Reset()         { *m = UpdateShardOwnersCommand{} }
+func (m *UpdateShardOwnersCommand) String() string { return proto.CompactTextString(m) }
+func (*UpdateShardOwnersCommand) ProtoMessage()    {}
+
+func (m *UpdateShardOwnersCommand) GetID() uint64 {
+	if m != nil && m.ID != nil {
+		return *m.ID
+	}
+	return 0
+}
+
+func (m *UpdateShardOwnersCommand) GetAddedOwners() []uint64 {
+	if m != nil {
+		return m.AddedOwners
+	}
+	return nil
+}
+
+func (m *UpdateShardOwnersCommand) GetRemovedOwners() []uint64 {
+	if m != nil {
+		return m.RemovedOwners
+	}
+	return nil
+}
+
+var E_UpdateShardOwnersCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*UpdateShardOwnersCommand)(nil),
+	Field:         123,
+	Name:          "internal.UpdateShardOwnersCommand.command",
+	Tag:           "bytes,123,opt,name=command",
+}
+
+type CreateTagRuleCommand struct {
+	Database         *string      `protobuf:"bytes,1,req" json:"Database,omitempty"`
+	Rule             *TagRuleInfo `protobuf:"bytes,2,req" json:"Rule,omitempty"`
+	XXX_unrecognized []byte       `json:"-"`
+}
+
+func (m *CreateTagRuleCommand) Reset()         { *m = CreateTagRuleCommand{} }
+func (m *CreateTagRuleCommand) String() string { return proto.CompactTextString(m) }
+func (*CreateTagRuleCommand) ProtoMessage()    {}
+
+func (m *CreateTagRuleCommand) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *CreateTagRuleCommand) GetRule() *TagRuleInfo {
+	if m != nil {
+		return m.Rule
+	}
+	return nil
+}
+
+var E_CreateTagRuleCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*CreateTagRuleCommand)(nil),
+	Field:         124,
+	Name:          "internal.CreateTagRuleCommand.command",
+	Tag:           "bytes,124,opt,name=command",
+}
+
+type DropTagRuleCommand struct {
+	Database         *string `protobuf:"bytes,1,req" json:"Database,omitempty"`
+	Name             *string `protobuf:"bytes,2,req" json:"Name,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *DropTagRuleCommand) Reset()         { *m = DropTagRuleCommand{} }
+func (m *DropTagRuleCommand) String() string { return proto.CompactTextString(m) }
+func (*DropTagRuleCommand) ProtoMessage()    {}
+
+func (m *DropTagRuleCommand) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *DropTagRuleCommand) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+var E_DropTagRuleCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*DropTagRuleCommand)(nil),
+	Field:         125,
+	Name:          "internal.DropTagRuleCommand.command",
+	Tag:           "bytes,125,opt,name=command",
+}
+
+type CreateDownsamplePolicyCommand struct {
+	Database         *string               `protobuf:"bytes,1,req" json:"Database,omitempty"`
+	Policy           *DownsamplePolicyInfo `protobuf:"bytes,2,req" json:"Policy,omitempty"`
+	XXX_unrecognized []byte                `json:"-"`
+}
+
+func (m *CreateDownsamplePolicyCommand) Reset()         { *m = CreateDownsamplePolicyCommand{} }
+func (m *CreateDownsamplePolicyCommand) String() string { return proto.CompactTextString(m) }
+func (*CreateDownsamplePolicyCommand) ProtoMessage()    {}
+
+func (m *CreateDownsamplePolicyCommand) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *CreateDownsamplePolicyCommand) GetPolicy() *DownsamplePolicyInfo {
+	if m != nil {
+		return m.Policy
+	}
+	return nil
+}
+
+var E_CreateDownsamplePolicyCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*CreateDownsamplePolicyCommand)(nil),
+	Field:         126,
+	Name:          "internal.CreateDownsamplePolicyCommand.command",
+	Tag:           "bytes,126,opt,name=command",
+}
+
+type DropDownsamplePolicyCommand struct {
+	Database         *string `protobuf:"bytes,1,req" json:"Database,omitempty"`
+	Name             *string `protobuf:"bytes,2,req" json:"Name,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *DropDownsamplePolicyCommand) Reset()         { *m = DropDownsamplePolicyCommand{} }
+func (m *DropDownsamplePolicyCommand) String() string { return proto.CompactTextString(m) }
+func (*DropDownsamplePolicyCommand) ProtoMessage()    {}
+
+func (m *DropDownsamplePolicyCommand) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *DropDownsamplePolicyCommand) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+var E_DropDownsamplePolicyCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*DropDownsamplePolicyCommand)(nil),
+	Field:         127,
+	Name:          "internal.DropDownsamplePolicyCommand.command",
+	Tag:           "bytes,127,opt,name=command",
+}
+
+type SetDownsamplePolicyProgressCommand struct {
+	Database         *string `protobuf:"bytes,1,req" json:"Database,omitempty"`
+	Name             *string `protobuf:"bytes,2,req" json:"Name,omitempty"`
+	DownsampledUntil *int64  `protobuf:"varint,3,req" json:"DownsampledUntil,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *SetDownsamplePolicyProgressCommand) Reset()         { *m = SetDownsamplePolicyProgressCommand{} }
+func (m *SetDownsamplePolicyProgressCommand) String() string { return proto.CompactTextString(m) }
+func (*SetDownsamplePolicyProgressCommand) ProtoMessage()    {}
+
+func (m *SetDownsamplePolicyProgressCommand) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *SetDownsamplePolicyProgressCommand) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *SetDownsamplePolicyProgressCommand) GetDownsampledUntil() int64 {
+	if m != nil && m.DownsampledUntil != nil {
+		return *m.DownsampledUntil
+	}
+	return 0
+}
+
+var E_SetDownsamplePolicyProgressCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*SetDownsamplePolicyProgressCommand)(nil),
+	Field:         128,
+	Name:          "internal.SetDownsamplePolicyProgressCommand.command",
+	Tag:           "bytes,128,opt,name=command",
+}
+
+type SetUserLimitsCommand struct {
+	Username         *string `protobuf:"bytes,1,req" json:"Username,omitempty"`
+	MaxQueries       *int64  `protobuf:"varint,2,opt" json:"MaxQueries,omitempty"`
+	MaxRange         *int64  `protobuf:"varint,3,opt" json:"MaxRange,omitempty"`
+	MaxPoints        *int64  `protobuf:"varint,4,opt" json:"MaxPoints,omitempty"`
+	Batch            *bool   `protobuf:"varint,5,opt" json:"Batch,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *SetUserLimitsCommand) Reset()         { *m = SetUserLimitsCommand{} }
+func (m *SetUserLimitsCommand) String() string { return proto.CompactTextString(m) }
+func (*SetUserLimitsCommand) ProtoMessage()    {}
+
+func (m *SetUserLimitsCommand) GetUsername() string {
+	if m != nil && m.Username != nil {
+		return *m.Username
+	}
+	return ""
+}
+
+func (m *SetUserLimitsCommand) GetMaxQueries() int64 {
+	if m != nil && m.MaxQueries != nil {
+		return *m.MaxQueries
+	}
+	return 0
+}
+
+func (m *SetUserLimitsCommand) GetMaxRange() int64 {
+	if m != nil && m.MaxRange != nil {
+		return *m.MaxRange
+	}
+	return 0
+}
+
+func (m *SetUserLimitsCommand) GetMaxPoints() int64 {
+	if m != nil && m.MaxPoints != nil {
+		return *m.MaxPoints
+	}
+	return 0
+}
+
+func (m *SetUserLimitsCommand) GetBatch() bool {
+	if m != nil && m.Batch != nil {
+		return *m.Batch
+	}
+	return false
+}
+
+var E_SetUserLimitsCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*SetUserLimitsCommand)(nil),
+	Field:         129,
+	Name:          "internal.SetUserLimitsCommand.command",
+	Tag:           "bytes,129,opt,name=command",
+}
+
+type BatchCommand struct {
+	Commands         []*Command `protobuf:"bytes,1,rep" json:"Commands,omitempty"`
+	XXX_unrecognized []byte     `json:"-"`
+}
+
+func (m *BatchCommand) Reset()         { *m = BatchCommand{} }
+func (m *BatchCommand) String() string { return proto.CompactTextString(m) }
+func (*BatchCommand) ProtoMessage()    {}
+
+func (m *BatchCommand) GetCommands() []*Command {
+	if m != nil {
+		return m.Commands
+	}
+	return nil
+}
+
+var E_BatchCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*BatchCommand)(nil),
+	Field:         130,
+	Name:          "internal.BatchCommand.command",
+	Tag:           "bytes,130,opt,name=command",
+}
+
+type RenameDatabaseCommand struct {
+	Name             *string `protobuf:"bytes,1,req" json:"Name,omitempty"`
+	NewName          *string `protobuf:"bytes,2,req" json:"NewName,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *RenameDatabaseCommand) Reset()         { *m = RenameDatabaseCommand{} }
+func (m *RenameDatabaseCommand) String() string { return proto.CompactTextString(m) }
+func (*RenameDatabaseCommand) ProtoMessage()    {}
+
+func (m *RenameDatabaseCommand) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *RenameDatabaseCommand) GetNewName() string {
+	if m != nil && m.NewName != nil {
+		return *m.NewName
+	}
+	return ""
+}
+
+var E_RenameDatabaseCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*RenameDatabaseCommand)(nil),
+	Field:         131,
+	Name:          "internal.RenameDatabaseCommand.command",
+	Tag:           "bytes,131,opt,name=command",
+}
+
 type Response struct {
 	OK               *bool   `protobuf:"varint,1,req" json:"OK,omitempty"`
 	Error            *string `protobuf:"bytes,2,opt" json:"Error,omitempty"`
@@ -1443,4 +2206,16 @@ func init() {
 	proto.RegisterExtension(E_SetDataCommand_Command)
 	proto.RegisterExtension(E_SetAdminPrivilegeCommand_Command)
 	proto.RegisterExtension(E_UpdateNodeCommand_Command)
+	proto.RegisterExtension(E_CreateSubscriptionCommand_Command)
+	proto.RegisterExtension(E_DropSubscriptionCommand_Command)
+	proto.RegisterExtension(E_SetContinuousQueryLastRunCommand_Command)
+	proto.RegisterExtension(E_UpdateShardOwnersCommand_Command)
+	proto.RegisterExtension(E_CreateTagRuleCommand_Command)
+	proto.RegisterExtension(E_DropTagRuleCommand_Command)
+	proto.RegisterExtension(E_CreateDownsamplePolicyCommand_Command)
+	proto.RegisterExtension(E_DropDownsamplePolicyCommand_Command)
+	proto.RegisterExtension(E_SetDownsamplePolicyProgressCommand_Command)
+	proto.RegisterExtension(E_SetUserLimitsCommand_Command)
+	proto.RegisterExtension(E_BatchCommand_Command)
+	proto.RegisterExtension(E_RenameDatabaseCommand_Command)
 }
@@ -111,6 +111,15 @@ func (r *localRaft) open() error {
 	config.ElectionTimeout = s.ElectionTimeout
 	config.LeaderLeaseTimeout = s.LeaderLeaseTimeout
 	config.CommitTimeout = s.CommitTimeout
+	if s.SnapshotInterval > 0 {
+		config.SnapshotInterval = s.SnapshotInterval
+	}
+	if s.SnapshotThreshold > 0 {
+		config.SnapshotThreshold = s.SnapshotThreshold
+	}
+	if s.TrailingLogs > 0 {
+		config.TrailingLogs = s.TrailingLogs
+	}
 
 	// If no peers are set in the config or there is one and we are it, then start as a single server.
 	if len(s.peers) <= 1 {
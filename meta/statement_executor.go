@@ -3,6 +3,7 @@ package meta
 import (
 	"bytes"
 	"fmt"
+	"sort"
 	"strconv"
 	"time"
 
@@ -14,11 +15,13 @@ type StatementExecutor struct {
 	Store interface {
 		Nodes() ([]NodeInfo, error)
 		Peers() ([]string, error)
+		DeleteNode(id uint64, force bool) error
 
 		Database(name string) (*DatabaseInfo, error)
 		Databases() ([]DatabaseInfo, error)
 		CreateDatabase(name string) (*DatabaseInfo, error)
 		DropDatabase(name string) error
+		RenameDatabase(name, newName string) error
 
 		DefaultRetentionPolicy(database string) (*RetentionPolicyInfo, error)
 		CreateRetentionPolicy(database string, rpi *RetentionPolicyInfo) (*RetentionPolicyInfo, error)
@@ -28,15 +31,26 @@ type StatementExecutor struct {
 
 		Users() ([]UserInfo, error)
 		CreateUser(name, password string, admin bool) (*UserInfo, error)
+		CreateWriteOnlyUser(name, password, database string) (*UserInfo, error)
 		UpdateUser(name, password string) error
 		DropUser(name string) error
 		SetPrivilege(username, database string, p influxql.Privilege) error
 		SetAdminPrivilege(username string, admin bool) error
-		UserPrivileges(username string) (map[string]influxql.Privilege, error)
+		SetUserLimits(username string, limits UserLimits) error
+		EffectivePrivileges(username string) ([]EffectivePrivilege, error)
 		UserPrivilege(username, database string) (*influxql.Privilege, error)
 
 		CreateContinuousQuery(database, name, query string) error
 		DropContinuousQuery(database, name string) error
+
+		CreateSubscription(database, rp, name, mode string, destinations []string) error
+		DropSubscription(database, rp, name string) error
+
+		CreateTagRule(database string, rule TagRuleInfo) error
+		DropTagRule(database, name string) error
+
+		CreateDownsamplePolicy(database string, dpi DownsamplePolicyInfo) error
+		DropDownsamplePolicy(database, name string) error
 	}
 }
 
@@ -47,12 +61,16 @@ func (e *StatementExecutor) ExecuteStatement(stmt influxql.Statement) *influxql.
 		return e.executeCreateDatabaseStatement(stmt)
 	case *influxql.DropDatabaseStatement:
 		return e.executeDropDatabaseStatement(stmt)
+	case *influxql.RenameDatabaseStatement:
+		return e.executeRenameDatabaseStatement(stmt)
 	case *influxql.ShowDatabasesStatement:
 		return e.executeShowDatabasesStatement(stmt)
 	case *influxql.ShowGrantsForUserStatement:
 		return e.executeShowGrantsForUserStatement(stmt)
 	case *influxql.ShowServersStatement:
 		return e.executeShowServersStatement(stmt)
+	case *influxql.DropDataNodeStatement:
+		return e.executeDropDataNodeStatement(stmt)
 	case *influxql.CreateUserStatement:
 		return e.executeCreateUserStatement(stmt)
 	case *influxql.SetPasswordUserStatement:
@@ -61,6 +79,10 @@ func (e *StatementExecutor) ExecuteStatement(stmt influxql.Statement) *influxql.
 		return e.executeDropUserStatement(stmt)
 	case *influxql.ShowUsersStatement:
 		return e.executeShowUsersStatement(stmt)
+	case *influxql.SetUserLimitsStatement:
+		return e.executeSetUserLimitsStatement(stmt)
+	case *influxql.ShowUserLimitsStatement:
+		return e.executeShowUserLimitsStatement(stmt)
 	case *influxql.GrantStatement:
 		return e.executeGrantStatement(stmt)
 	case *influxql.GrantAdminStatement:
@@ -83,6 +105,24 @@ func (e *StatementExecutor) ExecuteStatement(stmt influxql.Statement) *influxql.
 		return e.executeDropContinuousQueryStatement(stmt)
 	case *influxql.ShowContinuousQueriesStatement:
 		return e.executeShowContinuousQueriesStatement(stmt)
+	case *influxql.CreateSubscriptionStatement:
+		return e.executeCreateSubscriptionStatement(stmt)
+	case *influxql.DropSubscriptionStatement:
+		return e.executeDropSubscriptionStatement(stmt)
+	case *influxql.ShowSubscriptionsStatement:
+		return e.executeShowSubscriptionsStatement(stmt)
+	case *influxql.CreateTagRuleStatement:
+		return e.executeCreateTagRuleStatement(stmt)
+	case *influxql.DropTagRuleStatement:
+		return e.executeDropTagRuleStatement(stmt)
+	case *influxql.ShowTagRulesStatement:
+		return e.executeShowTagRulesStatement(stmt)
+	case *influxql.CreateDownsamplePolicyStatement:
+		return e.executeCreateDownsamplePolicyStatement(stmt)
+	case *influxql.DropDownsamplePolicyStatement:
+		return e.executeDropDownsamplePolicyStatement(stmt)
+	case *influxql.ShowDownsamplePoliciesStatement:
+		return e.executeShowDownsamplePoliciesStatement(stmt)
 	case *influxql.ShowShardsStatement:
 		return e.executeShowShardsStatement(stmt)
 	case *influxql.ShowStatsStatement:
@@ -104,6 +144,10 @@ func (e *StatementExecutor) executeDropDatabaseStatement(q *influxql.DropDatabas
 	return &influxql.Result{Err: e.Store.DropDatabase(q.Name)}
 }
 
+func (e *StatementExecutor) executeRenameDatabaseStatement(q *influxql.RenameDatabaseStatement) *influxql.Result {
+	return &influxql.Result{Err: e.Store.RenameDatabase(q.Name, q.NewName)}
+}
+
 func (e *StatementExecutor) executeShowDatabasesStatement(q *influxql.ShowDatabasesStatement) *influxql.Result {
 	dis, err := e.Store.Databases()
 	if err != nil {
@@ -118,14 +162,14 @@ func (e *StatementExecutor) executeShowDatabasesStatement(q *influxql.ShowDataba
 }
 
 func (e *StatementExecutor) executeShowGrantsForUserStatement(q *influxql.ShowGrantsForUserStatement) *influxql.Result {
-	priv, err := e.Store.UserPrivileges(q.Name)
+	a, err := e.Store.EffectivePrivileges(q.Name)
 	if err != nil {
 		return &influxql.Result{Err: err}
 	}
 
-	row := &influxql.Row{Columns: []string{"database", "privilege"}}
-	for d, p := range priv {
-		row.Values = append(row.Values, []interface{}{d, p.String()})
+	row := &influxql.Row{Columns: []string{"database", "privilege", "read", "write"}}
+	for _, p := range a {
+		row.Values = append(row.Values, []interface{}{p.Database, p.Privilege.String(), p.Read, p.Write})
 	}
 	return &influxql.Result{Series: []*influxql.Row{row}}
 }
@@ -148,8 +192,17 @@ func (e *StatementExecutor) executeShowServersStatement(q *influxql.ShowServersS
 	return &influxql.Result{Series: []*influxql.Row{row}}
 }
 
+func (e *StatementExecutor) executeDropDataNodeStatement(q *influxql.DropDataNodeStatement) *influxql.Result {
+	return &influxql.Result{Err: e.Store.DeleteNode(q.NodeID, q.Force)}
+}
+
 func (e *StatementExecutor) executeCreateUserStatement(q *influxql.CreateUserStatement) *influxql.Result {
-	_, err := e.Store.CreateUser(q.Name, q.Password, q.Admin)
+	var err error
+	if q.WriteOnlyDatabase != "" {
+		_, err = e.Store.CreateWriteOnlyUser(q.Name, q.Password, q.WriteOnlyDatabase)
+	} else {
+		_, err = e.Store.CreateUser(q.Name, q.Password, q.Admin)
+	}
 	return &influxql.Result{Err: err}
 }
 
@@ -167,9 +220,41 @@ func (e *StatementExecutor) executeShowUsersStatement(q *influxql.ShowUsersState
 		return &influxql.Result{Err: err}
 	}
 
-	row := &influxql.Row{Columns: []string{"user", "admin"}}
+	row := &influxql.Row{Columns: []string{"user", "admin", "write_only"}}
+	for _, ui := range uis {
+		row.Values = append(row.Values, []interface{}{ui.Name, ui.Admin, ui.WriteOnly})
+	}
+	return &influxql.Result{Series: []*influxql.Row{row}}
+}
+
+func (e *StatementExecutor) executeSetUserLimitsStatement(q *influxql.SetUserLimitsStatement) *influxql.Result {
+	return &influxql.Result{
+		Err: e.Store.SetUserLimits(q.Name, UserLimits{
+			MaxQueries: q.MaxQueries,
+			MaxRange:   q.MaxRange,
+			MaxPoints:  q.MaxPoints,
+			Batch:      q.Batch,
+		}),
+	}
+}
+
+func (e *StatementExecutor) executeShowUserLimitsStatement(q *influxql.ShowUserLimitsStatement) *influxql.Result {
+	uis, err := e.Store.Users()
+	if err != nil {
+		return &influxql.Result{Err: err}
+	}
+
+	row := &influxql.Row{Columns: []string{"user", "max_queries", "max_range", "max_points", "priority"}}
 	for _, ui := range uis {
-		row.Values = append(row.Values, []interface{}{ui.Name, ui.Admin})
+		var maxRange string
+		if ui.Limits.MaxRange > 0 {
+			maxRange = influxql.FormatDuration(ui.Limits.MaxRange)
+		}
+		priority := "interactive"
+		if ui.Limits.Batch {
+			priority = "batch"
+		}
+		row.Values = append(row.Values, []interface{}{ui.Name, ui.Limits.MaxQueries, maxRange, ui.Limits.MaxPoints, priority})
 	}
 	return &influxql.Result{Series: []*influxql.Row{row}}
 }
@@ -206,6 +291,8 @@ func (e *StatementExecutor) executeCreateRetentionPolicyStatement(stmt *influxql
 	rpi := NewRetentionPolicyInfo(stmt.Name)
 	rpi.Duration = stmt.Duration
 	rpi.ReplicaN = stmt.Replication
+	rpi.ShardGroupDuration = stmt.ShardGroupDuration
+	rpi.Resolution = stmt.Resolution
 
 	// Create new retention policy.
 	_, err := e.Store.CreateRetentionPolicy(stmt.Database, rpi)
@@ -223,8 +310,9 @@ func (e *StatementExecutor) executeCreateRetentionPolicyStatement(stmt *influxql
 
 func (e *StatementExecutor) executeAlterRetentionPolicyStatement(stmt *influxql.AlterRetentionPolicyStatement) *influxql.Result {
 	rpu := &RetentionPolicyUpdate{
-		Duration: stmt.Duration,
-		ReplicaN: stmt.Replication,
+		Duration:   stmt.Duration,
+		ReplicaN:   stmt.Replication,
+		Resolution: stmt.Resolution,
 	}
 
 	// Update the retention policy.
@@ -253,9 +341,9 @@ func (e *StatementExecutor) executeShowRetentionPoliciesStatement(q *influxql.Sh
 		return &influxql.Result{Err: ErrDatabaseNotFound}
 	}
 
-	row := &influxql.Row{Columns: []string{"name", "duration", "replicaN", "default"}}
+	row := &influxql.Row{Columns: []string{"name", "duration", "replicaN", "default", "resolution"}}
 	for _, rpi := range di.RetentionPolicies {
-		row.Values = append(row.Values, []interface{}{rpi.Name, rpi.Duration.String(), rpi.ReplicaN, di.DefaultRetentionPolicy == rpi.Name})
+		row.Values = append(row.Values, []interface{}{rpi.Name, rpi.Duration.String(), rpi.ReplicaN, di.DefaultRetentionPolicy == rpi.Name, rpi.Resolution.String()})
 	}
 	return &influxql.Result{Series: []*influxql.Row{row}}
 }
@@ -289,6 +377,151 @@ func (e *StatementExecutor) executeShowContinuousQueriesStatement(stmt *influxql
 	return &influxql.Result{Series: rows}
 }
 
+func (e *StatementExecutor) executeCreateSubscriptionStatement(q *influxql.CreateSubscriptionStatement) *influxql.Result {
+	return &influxql.Result{
+		Err: e.Store.CreateSubscription(q.Database, q.RetentionPolicy, q.Name, q.Mode, q.Destinations),
+	}
+}
+
+func (e *StatementExecutor) executeDropSubscriptionStatement(q *influxql.DropSubscriptionStatement) *influxql.Result {
+	return &influxql.Result{
+		Err: e.Store.DropSubscription(q.Database, q.RetentionPolicy, q.Name),
+	}
+}
+
+func (e *StatementExecutor) executeShowSubscriptionsStatement(stmt *influxql.ShowSubscriptionsStatement) *influxql.Result {
+	dis, err := e.Store.Databases()
+	if err != nil {
+		return &influxql.Result{Err: err}
+	}
+
+	rows := []*influxql.Row{}
+	for _, di := range dis {
+		row := &influxql.Row{Columns: []string{"retention_policy", "name", "mode", "destinations"}, Name: di.Name}
+		for _, rpi := range di.RetentionPolicies {
+			for _, si := range rpi.Subscriptions {
+				row.Values = append(row.Values, []interface{}{rpi.Name, si.Name, si.Mode, si.Destinations})
+			}
+		}
+		if len(row.Values) > 0 {
+			rows = append(rows, row)
+		}
+	}
+	return &influxql.Result{Series: rows}
+}
+
+func (e *StatementExecutor) executeCreateTagRuleStatement(q *influxql.CreateTagRuleStatement) *influxql.Result {
+	return &influxql.Result{
+		Err: e.Store.CreateTagRule(q.Database, TagRuleInfo{
+			Name:     q.Name,
+			Key:      q.Key,
+			Action:   q.Action,
+			Mappings: q.Mappings,
+		}),
+	}
+}
+
+func (e *StatementExecutor) executeDropTagRuleStatement(q *influxql.DropTagRuleStatement) *influxql.Result {
+	return &influxql.Result{
+		Err: e.Store.DropTagRule(q.Database, q.Name),
+	}
+}
+
+func (e *StatementExecutor) executeShowTagRulesStatement(stmt *influxql.ShowTagRulesStatement) *influxql.Result {
+	dis, err := e.Store.Databases()
+	if err != nil {
+		return &influxql.Result{Err: err}
+	}
+
+	rows := []*influxql.Row{}
+	for _, di := range dis {
+		if stmt.Database != "" && di.Name != stmt.Database {
+			continue
+		}
+
+		row := &influxql.Row{Columns: []string{"name", "key", "action", "mappings"}, Name: di.Name}
+		for _, ri := range di.TagRules {
+			mappings := make([]string, 0, len(ri.Mappings))
+			for from, to := range ri.Mappings {
+				mappings = append(mappings, from+"="+to)
+			}
+			sort.Strings(mappings)
+
+			row.Values = append(row.Values, []interface{}{ri.Name, ri.Key, ri.Action, mappings})
+		}
+		if len(row.Values) > 0 {
+			rows = append(rows, row)
+		}
+	}
+	return &influxql.Result{Series: rows}
+}
+
+func (e *StatementExecutor) executeCreateDownsamplePolicyStatement(q *influxql.CreateDownsamplePolicyStatement) *influxql.Result {
+	return &influxql.Result{
+		Err: e.Store.CreateDownsamplePolicy(q.Database, DownsamplePolicyInfo{
+			Name:                  q.Name,
+			SourceRetentionPolicy: q.Source,
+			TargetRetentionPolicy: q.Target,
+			Interval:              q.Interval,
+			DefaultAggregate:      q.DefaultAggregate,
+			Aggregates:            q.Aggregates,
+		}),
+	}
+}
+
+func (e *StatementExecutor) executeDropDownsamplePolicyStatement(q *influxql.DropDownsamplePolicyStatement) *influxql.Result {
+	return &influxql.Result{
+		Err: e.Store.DropDownsamplePolicy(q.Database, q.Name),
+	}
+}
+
+func (e *StatementExecutor) executeShowDownsamplePoliciesStatement(stmt *influxql.ShowDownsamplePoliciesStatement) *influxql.Result {
+	dis, err := e.Store.Databases()
+	if err != nil {
+		return &influxql.Result{Err: err}
+	}
+
+	rows := []*influxql.Row{}
+	for _, di := range dis {
+		if stmt.Database != "" && di.Name != stmt.Database {
+			continue
+		}
+
+		row := &influxql.Row{Columns: []string{"name", "source", "target", "interval", "default_aggregate", "aggregates", "downsampled_until"}, Name: di.Name}
+		for _, dpi := range di.DownsamplePolicies {
+			aggregates := make([]string, 0, len(dpi.Aggregates))
+			for field, a := range dpi.Aggregates {
+				aggregates = append(aggregates, fmt.Sprintf("%s(%s)", a, field))
+			}
+			sort.Strings(aggregates)
+
+			defaultAggregate := dpi.DefaultAggregate
+			if defaultAggregate == "" {
+				defaultAggregate = DefaultDownsampleAggregate
+			}
+
+			var until string
+			if !dpi.DownsampledUntil.IsZero() {
+				until = dpi.DownsampledUntil.UTC().Format(time.RFC3339)
+			}
+
+			row.Values = append(row.Values, []interface{}{
+				dpi.Name,
+				dpi.SourceRetentionPolicy,
+				dpi.TargetRetentionPolicy,
+				influxql.FormatDuration(dpi.Interval),
+				defaultAggregate,
+				aggregates,
+				until,
+			})
+		}
+		if len(row.Values) > 0 {
+			rows = append(rows, row)
+		}
+	}
+	return &influxql.Result{Series: rows}
+}
+
 func (e *StatementExecutor) executeShowShardsStatement(stmt *influxql.ShowShardsStatement) *influxql.Result {
 	dis, err := e.Store.Databases()
 	if err != nil {
@@ -45,6 +45,23 @@ func TestStatementExecutor_ExecuteStatement_DropDatabase(t *testing.T) {
 	}
 }
 
+// Ensure a RENAME DATABASE statement can be executed.
+func TestStatementExecutor_ExecuteStatement_RenameDatabase(t *testing.T) {
+	e := NewStatementExecutor()
+	e.Store.RenameDatabaseFn = func(name, newName string) error {
+		if name != "foo" || newName != "bar" {
+			t.Fatalf("unexpected names: %s, %s", name, newName)
+		}
+		return nil
+	}
+
+	if res := e.ExecuteStatement(influxql.MustParseStatement(`RENAME DATABASE foo TO bar`)); res.Err != nil {
+		t.Fatal(res.Err)
+	} else if res.Series != nil {
+		t.Fatalf("unexpected rows: %#v", res.Series)
+	}
+}
+
 // Ensure a SHOW DATABASES statement can be executed.
 func TestStatementExecutor_ExecuteStatement_ShowDatabases(t *testing.T) {
 	e := NewStatementExecutor()
@@ -85,15 +102,15 @@ func TestStatementExecutor_ExecuteStatement_ShowDatabases_Err(t *testing.T) {
 
 // Ensure a SHOW GRANTS FOR statement can be executed.
 func TestStatementExecutor_ExecuteStatement_ShowGrantsFor(t *testing.T) {
-	t.Skip("Intermittent test failure: issue 3028")
 	e := NewStatementExecutor()
-	e.Store.UserPrivilegesFn = func(username string) (map[string]influxql.Privilege, error) {
+	e.Store.EffectivePrivilegesFn = func(username string) ([]meta.EffectivePrivilege, error) {
 		if username != "dejan" {
 			t.Fatalf("unexpected username: %s", username)
 		}
-		return map[string]influxql.Privilege{
-			"dejan": influxql.ReadPrivilege,
-			"golja": influxql.WritePrivilege,
+		return []meta.EffectivePrivilege{
+			{Database: "dejan", Privilege: influxql.ReadPrivilege, Read: true},
+			{Database: "golja", Privilege: influxql.WritePrivilege, Write: true},
+			{Database: "other", Privilege: influxql.NoPrivileges},
 		}, nil
 	}
 
@@ -101,10 +118,11 @@ func TestStatementExecutor_ExecuteStatement_ShowGrantsFor(t *testing.T) {
 		t.Fatal(res.Err)
 	} else if !reflect.DeepEqual(res.Series, influxql.Rows{
 		{
-			Columns: []string{"database", "privilege"},
+			Columns: []string{"database", "privilege", "read", "write"},
 			Values: [][]interface{}{
-				{"dejan", "READ"},
-				{"golja", "WRITE"},
+				{"dejan", "READ", true, false},
+				{"golja", "WRITE", false, true},
+				{"other", "NO PRIVILEGES", false, false},
 			},
 		},
 	}) {
@@ -152,6 +170,25 @@ func TestStatementExecutor_ExecuteStatement_ShowServers_Err(t *testing.T) {
 	}
 }
 
+// Ensure a DROP DATA NODE statement can be executed.
+func TestStatementExecutor_ExecuteStatement_DropDataNode(t *testing.T) {
+	e := NewStatementExecutor()
+	e.Store.DeleteNodeFn = func(id uint64, force bool) error {
+		if id != 2 {
+			t.Fatalf("unexpected id: %d", id)
+		} else if !force {
+			t.Fatal("expected force")
+		}
+		return nil
+	}
+
+	if res := e.ExecuteStatement(influxql.MustParseStatement(`DROP DATA NODE 2 FORCE`)); res.Err != nil {
+		t.Fatal(res.Err)
+	} else if res.Series != nil {
+		t.Fatalf("unexpected rows: %#v", res.Series)
+	}
+}
+
 // Ensure a CREATE USER statement can be executed.
 func TestStatementExecutor_ExecuteStatement_CreateUser(t *testing.T) {
 	e := NewStatementExecutor()
@@ -173,6 +210,27 @@ func TestStatementExecutor_ExecuteStatement_CreateUser(t *testing.T) {
 	}
 }
 
+// Ensure a CREATE USER ... WITH WRITE ONLY statement creates a write-only user.
+func TestStatementExecutor_ExecuteStatement_CreateUser_WriteOnly(t *testing.T) {
+	e := NewStatementExecutor()
+	e.Store.CreateWriteOnlyUserFn = func(name, password, database string) (*meta.UserInfo, error) {
+		if name != "agent" {
+			t.Fatalf("unexpected name: %s", name)
+		} else if password != "pass" {
+			t.Fatalf("unexpected password: %s", password)
+		} else if database != "db0" {
+			t.Fatalf("unexpected database: %s", database)
+		}
+		return &meta.UserInfo{Name: name, WriteOnly: true}, nil
+	}
+
+	if res := e.ExecuteStatement(influxql.MustParseStatement(`CREATE USER agent WITH PASSWORD 'pass' WITH WRITE ONLY ON db0`)); res.Err != nil {
+		t.Fatal(res.Err)
+	} else if res.Series != nil {
+		t.Fatalf("unexpected rows: %#v", res.Series)
+	}
+}
+
 // Ensure a CREATE USER statement returns errors from the store.
 func TestStatementExecutor_ExecuteStatement_CreateUser_Err(t *testing.T) {
 	e := NewStatementExecutor()
@@ -252,6 +310,7 @@ func TestStatementExecutor_ExecuteStatement_ShowUsers(t *testing.T) {
 		return []meta.UserInfo{
 			{Name: "susy", Admin: true},
 			{Name: "bob", Admin: false},
+			{Name: "agent", WriteOnly: true},
 		}, nil
 	}
 
@@ -259,10 +318,11 @@ func TestStatementExecutor_ExecuteStatement_ShowUsers(t *testing.T) {
 		t.Fatal(res.Err)
 	} else if !reflect.DeepEqual(res.Series, influxql.Rows{
 		{
-			Columns: []string{"user", "admin"},
+			Columns: []string{"user", "admin", "write_only"},
 			Values: [][]interface{}{
-				{"susy", true},
-				{"bob", false},
+				{"susy", true, false},
+				{"bob", false, false},
+				{"agent", false, true},
 			},
 		},
 	}) {
@@ -346,6 +406,50 @@ func TestStatementExecutor_ExecuteStatement_GrantAdmin_Err(t *testing.T) {
 	}
 }
 
+// Ensure a SET LIMITS statement can be executed.
+func TestStatementExecutor_ExecuteStatement_SetUserLimits(t *testing.T) {
+	e := NewStatementExecutor()
+	e.Store.SetUserLimitsFn = func(username string, limits meta.UserLimits) error {
+		if username != "susy" {
+			t.Fatalf("unexpected username: %s", username)
+		} else if !reflect.DeepEqual(limits, meta.UserLimits{MaxQueries: 2, MaxRange: time.Hour, MaxPoints: 1000, Batch: true}) {
+			t.Fatalf("unexpected limits: %#v", limits)
+		}
+		return nil
+	}
+
+	if res := e.ExecuteStatement(influxql.MustParseStatement(`SET LIMITS FOR susy MAX QUERIES 2 MAX RANGE 1h MAX POINTS 1000 PRIORITY BATCH`)); res.Err != nil {
+		t.Fatal(res.Err)
+	} else if res.Series != nil {
+		t.Fatalf("unexpected rows: %#v", res.Series)
+	}
+}
+
+// Ensure a SHOW LIMITS statement returns the limits of every user.
+func TestStatementExecutor_ExecuteStatement_ShowUserLimits(t *testing.T) {
+	e := NewStatementExecutor()
+	e.Store.UsersFn = func() ([]meta.UserInfo, error) {
+		return []meta.UserInfo{
+			{Name: "susy", Limits: meta.UserLimits{MaxQueries: 2, MaxRange: time.Hour, MaxPoints: 1000, Batch: true}},
+			{Name: "bob"},
+		}, nil
+	}
+
+	if res := e.ExecuteStatement(influxql.MustParseStatement(`SHOW LIMITS`)); res.Err != nil {
+		t.Fatal(res.Err)
+	} else if !reflect.DeepEqual(res.Series, influxql.Rows{
+		{
+			Columns: []string{"user", "max_queries", "max_range", "max_points", "priority"},
+			Values: [][]interface{}{
+				{"susy", 2, "1h", int64(1000), "batch"},
+				{"bob", 0, "", int64(0), "interactive"},
+			},
+		},
+	}) {
+		t.Fatalf("unexpected rows: %s", spew.Sdump(res.Series))
+	}
+}
+
 // Ensure a REVOKE statement can be executed.
 func TestStatementExecutor_ExecuteStatement_Revoke(t *testing.T) {
 	e := NewStatementExecutor()
@@ -422,6 +526,10 @@ func TestStatementExecutor_ExecuteStatement_CreateRetentionPolicy(t *testing.T)
 			t.Fatalf("unexpected duration: %v", rpi.Duration)
 		} else if rpi.ReplicaN != 3 {
 			t.Fatalf("unexpected replication factor: %v", rpi.ReplicaN)
+		} else if rpi.ShardGroupDuration != time.Hour {
+			t.Fatalf("unexpected shard group duration: %v", rpi.ShardGroupDuration)
+		} else if rpi.Resolution != 10*time.Minute {
+			t.Fatalf("unexpected resolution: %v", rpi.Resolution)
 		}
 		return nil, nil
 	}
@@ -434,7 +542,7 @@ func TestStatementExecutor_ExecuteStatement_CreateRetentionPolicy(t *testing.T)
 		return nil
 	}
 
-	if res := e.ExecuteStatement(influxql.MustParseStatement(`CREATE RETENTION POLICY rp0 ON foo DURATION 2h REPLICATION 3 DEFAULT`)); res.Err != nil {
+	if res := e.ExecuteStatement(influxql.MustParseStatement(`CREATE RETENTION POLICY rp0 ON foo DURATION 2h REPLICATION 3 SHARD DURATION 1h RESOLUTION 10m DEFAULT`)); res.Err != nil {
 		t.Fatal(res.Err)
 	} else if res.Series != nil {
 		t.Fatalf("unexpected rows: %#v", res.Series)
@@ -465,6 +573,8 @@ func TestStatementExecutor_ExecuteStatement_AlterRetentionPolicy(t *testing.T) {
 			t.Fatalf("unexpected duration: %v", *rpu.Duration)
 		} else if rpu.ReplicaN != nil && *rpu.ReplicaN != 2 {
 			t.Fatalf("unexpected replication factor: %v", *rpu.ReplicaN)
+		} else if rpu.Resolution != nil && *rpu.Resolution != time.Hour {
+			t.Fatalf("unexpected resolution: %v", *rpu.Resolution)
 		}
 		return nil
 	}
@@ -491,6 +601,11 @@ func TestStatementExecutor_ExecuteStatement_AlterRetentionPolicy(t *testing.T) {
 	if res := e.ExecuteStatement(stmt); res.Err != nil {
 		t.Fatalf("unexpected error: %s", res.Err)
 	}
+
+	stmt = influxql.MustParseStatement(`ALTER RETENTION POLICY rp0 ON foo RESOLUTION 1h`)
+	if res := e.ExecuteStatement(stmt); res.Err != nil {
+		t.Fatalf("unexpected error: %s", res.Err)
+	}
 }
 
 // Ensure a ALTER RETENTION POLICY statement returns errors from the store.
@@ -570,9 +685,10 @@ func TestStatementExecutor_ExecuteStatement_ShowRetentionPolicies(t *testing.T)
 					ReplicaN: 3,
 				},
 				{
-					Name:     "rp1",
-					Duration: 24 * time.Hour,
-					ReplicaN: 1,
+					Name:       "rp1",
+					Duration:   24 * time.Hour,
+					ReplicaN:   1,
+					Resolution: 5 * time.Minute,
 				},
 			},
 		}, nil
@@ -582,10 +698,10 @@ func TestStatementExecutor_ExecuteStatement_ShowRetentionPolicies(t *testing.T)
 		t.Fatal(res.Err)
 	} else if !reflect.DeepEqual(res.Series, influxql.Rows{
 		{
-			Columns: []string{"name", "duration", "replicaN", "default"},
+			Columns: []string{"name", "duration", "replicaN", "default", "resolution"},
 			Values: [][]interface{}{
-				{"rp0", "2h0m0s", 3, false},
-				{"rp1", "24h0m0s", 1, true},
+				{"rp0", "2h0m0s", 3, false, "0s"},
+				{"rp1", "24h0m0s", 1, true, "5m0s"},
 			},
 		},
 	}) {
@@ -743,6 +859,129 @@ func TestStatementExecutor_ExecuteStatement_ShowContinuousQueries_Err(t *testing
 	}
 }
 
+// Ensure a CREATE SUBSCRIPTION statement can be executed.
+func TestStatementExecutor_ExecuteStatement_CreateSubscription(t *testing.T) {
+	e := NewStatementExecutor()
+	e.Store.CreateSubscriptionFn = func(database, rp, name, mode string, destinations []string) error {
+		if database != "db0" {
+			t.Fatalf("unexpected database: %s", database)
+		} else if rp != "rp0" {
+			t.Fatalf("unexpected rp: %s", rp)
+		} else if name != "s0" {
+			t.Fatalf("unexpected name: %s", name)
+		} else if mode != "ANY" {
+			t.Fatalf("unexpected mode: %s", mode)
+		} else if !reflect.DeepEqual(destinations, []string{"udp://h0:1234", "udp://h1:1234"}) {
+			t.Fatalf("unexpected destinations: %s", destinations)
+		}
+		return nil
+	}
+
+	stmt := influxql.MustParseStatement(`CREATE SUBSCRIPTION s0 ON db0.rp0 DESTINATIONS ANY 'udp://h0:1234', 'udp://h1:1234'`)
+	if res := e.ExecuteStatement(stmt); res.Err != nil {
+		t.Fatal(res.Err)
+	} else if res.Series != nil {
+		t.Fatalf("unexpected rows: %#v", res.Series)
+	}
+}
+
+// Ensure a CREATE SUBSCRIPTION statement can return an error from the store.
+func TestStatementExecutor_ExecuteStatement_CreateSubscription_Err(t *testing.T) {
+	e := NewStatementExecutor()
+	e.Store.CreateSubscriptionFn = func(database, rp, name, mode string, destinations []string) error {
+		return errors.New("marker")
+	}
+
+	stmt := influxql.MustParseStatement(`CREATE SUBSCRIPTION s0 ON db0.rp0 DESTINATIONS ANY 'udp://h0:1234', 'udp://h1:1234'`)
+	if res := e.ExecuteStatement(stmt); res.Err == nil || res.Err.Error() != "marker" {
+		t.Fatalf("unexpected error: %s", res.Err)
+	}
+}
+
+// Ensure a DROP SUBSCRIPTION statement can be executed.
+func TestStatementExecutor_ExecuteStatement_DropSubscription(t *testing.T) {
+	e := NewStatementExecutor()
+	e.Store.DropSubscriptionFn = func(database, rp, name string) error {
+		if database != "db0" {
+			t.Fatalf("unexpected database: %s", database)
+		} else if rp != "rp0" {
+			t.Fatalf("unexpected rp: %s", rp)
+		} else if name != "s0" {
+			t.Fatalf("unexpected name: %s", name)
+		}
+		return nil
+	}
+
+	stmt := influxql.MustParseStatement(`DROP SUBSCRIPTION s0 ON db0.rp0`)
+	if res := e.ExecuteStatement(stmt); res.Err != nil {
+		t.Fatal(res.Err)
+	} else if res.Series != nil {
+		t.Fatalf("unexpected rows: %#v", res.Series)
+	}
+}
+
+// Ensure a SHOW SUBSCRIPTIONS statement can be executed.
+func TestStatementExecutor_ExecuteStatement_ShowSubscriptions(t *testing.T) {
+	e := NewStatementExecutor()
+	e.Store.DatabasesFn = func() ([]meta.DatabaseInfo, error) {
+		return []meta.DatabaseInfo{
+			{
+				Name: "db0",
+				RetentionPolicies: []meta.RetentionPolicyInfo{
+					{
+						Name: "rp0",
+						Subscriptions: []meta.SubscriptionInfo{
+							{Name: "s0", Mode: "ALL", Destinations: []string{"udp://h0:1234", "udp://h1:1234"}},
+							{Name: "s1", Mode: "ANY", Destinations: []string{"udp://h2:1234", "udp://h3:1234"}},
+						},
+					},
+					{
+						Name: "rp1",
+						Subscriptions: []meta.SubscriptionInfo{
+							{Name: "s2", Mode: "ALL", Destinations: []string{"udp://h4:1234", "udp://h5:1234"}},
+						},
+					},
+				},
+			},
+			{
+				Name: "db1",
+				RetentionPolicies: []meta.RetentionPolicyInfo{
+					{
+						Name: "rp2",
+						Subscriptions: []meta.SubscriptionInfo{
+							{Name: "s3", Mode: "ANY", Destinations: []string{"udp://h6:1234", "udp://h7:1234"}},
+						},
+					},
+				},
+			},
+		}, nil
+	}
+
+	stmt := influxql.MustParseStatement(`SHOW SUBSCRIPTIONS`)
+	if res := e.ExecuteStatement(stmt); res.Err != nil {
+		t.Fatal(res.Err)
+	} else if !reflect.DeepEqual(res.Series, influxql.Rows{
+		{
+			Name:    "db0",
+			Columns: []string{"retention_policy", "name", "mode", "destinations"},
+			Values: [][]interface{}{
+				{"rp0", "s0", "ALL", []string{"udp://h0:1234", "udp://h1:1234"}},
+				{"rp0", "s1", "ANY", []string{"udp://h2:1234", "udp://h3:1234"}},
+				{"rp1", "s2", "ALL", []string{"udp://h4:1234", "udp://h5:1234"}},
+			},
+		},
+		{
+			Name:    "db1",
+			Columns: []string{"retention_policy", "name", "mode", "destinations"},
+			Values: [][]interface{}{
+				{"rp2", "s3", "ANY", []string{"udp://h6:1234", "udp://h7:1234"}},
+			},
+		},
+	}) {
+		t.Fatalf("unexpected rows: %s", spew.Sdump(res.Series))
+	}
+}
+
 // Ensure that executing an unsupported statement will panic.
 func TestStatementExecutor_ExecuteStatement_Unsupported(t *testing.T) {
 	var panicked bool
@@ -765,6 +1004,169 @@ func TestStatementExecutor_ExecuteStatement_Unsupported(t *testing.T) {
 	}
 }
 
+// Ensure a CREATE TAG RULE statement can be executed.
+func TestStatementExecutor_ExecuteStatement_CreateTagRule(t *testing.T) {
+	e := NewStatementExecutor()
+	e.Store.CreateTagRuleFn = func(database string, rule meta.TagRuleInfo) error {
+		if database != "db0" {
+			t.Fatalf("unexpected database: %s", database)
+		} else if !reflect.DeepEqual(rule, meta.TagRuleInfo{Name: "hosts", Key: "host", Action: "map", Mappings: map[string]string{"WebA": "weba"}}) {
+			t.Fatalf("unexpected rule: %#v", rule)
+		}
+		return nil
+	}
+
+	stmt := influxql.MustParseStatement(`CREATE TAG RULE hosts ON db0 KEY host MAP 'WebA' TO 'weba'`)
+	if res := e.ExecuteStatement(stmt); res.Err != nil {
+		t.Fatal(res.Err)
+	} else if res.Series != nil {
+		t.Fatalf("unexpected rows: %#v", res.Series)
+	}
+}
+
+// Ensure a DROP TAG RULE statement can be executed.
+func TestStatementExecutor_ExecuteStatement_DropTagRule(t *testing.T) {
+	e := NewStatementExecutor()
+	e.Store.DropTagRuleFn = func(database, name string) error {
+		if database != "db0" {
+			t.Fatalf("unexpected database: %s", database)
+		} else if name != "hosts" {
+			t.Fatalf("unexpected name: %s", name)
+		}
+		return nil
+	}
+
+	stmt := influxql.MustParseStatement(`DROP TAG RULE hosts ON db0`)
+	if res := e.ExecuteStatement(stmt); res.Err != nil {
+		t.Fatal(res.Err)
+	} else if res.Series != nil {
+		t.Fatalf("unexpected rows: %#v", res.Series)
+	}
+}
+
+// Ensure a SHOW TAG RULES statement can be executed.
+func TestStatementExecutor_ExecuteStatement_ShowTagRules(t *testing.T) {
+	e := NewStatementExecutor()
+	e.Store.DatabasesFn = func() ([]meta.DatabaseInfo, error) {
+		return []meta.DatabaseInfo{
+			{
+				Name: "db0",
+				TagRules: []meta.TagRuleInfo{
+					{Name: "lower", Action: "lowercase"},
+					{Name: "hosts", Key: "host", Action: "map", Mappings: map[string]string{"web-a": "weba", "WebA": "weba"}},
+				},
+			},
+			{
+				Name:     "db1",
+				TagRules: []meta.TagRuleInfo{{Name: "trim", Action: "trim"}},
+			},
+		}, nil
+	}
+
+	stmt := influxql.MustParseStatement(`SHOW TAG RULES ON db0`)
+	if res := e.ExecuteStatement(stmt); res.Err != nil {
+		t.Fatal(res.Err)
+	} else if !reflect.DeepEqual(res.Series, influxql.Rows{
+		{
+			Name:    "db0",
+			Columns: []string{"name", "key", "action", "mappings"},
+			Values: [][]interface{}{
+				{"lower", "", "lowercase", []string{}},
+				{"hosts", "host", "map", []string{"WebA=weba", "web-a=weba"}},
+			},
+		},
+	}) {
+		t.Fatalf("unexpected rows: %s", spew.Sdump(res.Series))
+	}
+}
+
+// Ensure a CREATE DOWNSAMPLE POLICY statement can be executed.
+func TestStatementExecutor_ExecuteStatement_CreateDownsamplePolicy(t *testing.T) {
+	e := NewStatementExecutor()
+	e.Store.CreateDownsamplePolicyFn = func(database string, dpi meta.DownsamplePolicyInfo) error {
+		if database != "db0" {
+			t.Fatalf("unexpected database: %s", database)
+		} else if !reflect.DeepEqual(dpi, meta.DownsamplePolicyInfo{
+			Name:                  "ds0",
+			SourceRetentionPolicy: "raw",
+			TargetRetentionPolicy: "week",
+			Interval:              time.Hour,
+			DefaultAggregate:      "max",
+			Aggregates:            map[string]string{"state": "last"},
+		}) {
+			t.Fatalf("unexpected policy: %#v", dpi)
+		}
+		return nil
+	}
+
+	stmt := influxql.MustParseStatement(`CREATE DOWNSAMPLE POLICY ds0 ON db0 FROM raw TO week EVERY 1h AGGREGATE max, last(state)`)
+	if res := e.ExecuteStatement(stmt); res.Err != nil {
+		t.Fatal(res.Err)
+	} else if res.Series != nil {
+		t.Fatalf("unexpected rows: %#v", res.Series)
+	}
+}
+
+// Ensure a DROP DOWNSAMPLE POLICY statement can be executed.
+func TestStatementExecutor_ExecuteStatement_DropDownsamplePolicy(t *testing.T) {
+	e := NewStatementExecutor()
+	e.Store.DropDownsamplePolicyFn = func(database, name string) error {
+		if database != "db0" {
+			t.Fatalf("unexpected database: %s", database)
+		} else if name != "ds0" {
+			t.Fatalf("unexpected name: %s", name)
+		}
+		return nil
+	}
+
+	stmt := influxql.MustParseStatement(`DROP DOWNSAMPLE POLICY ds0 ON db0`)
+	if res := e.ExecuteStatement(stmt); res.Err != nil {
+		t.Fatal(res.Err)
+	} else if res.Series != nil {
+		t.Fatalf("unexpected rows: %#v", res.Series)
+	}
+}
+
+// Ensure a SHOW DOWNSAMPLE POLICIES statement can be executed.
+func TestStatementExecutor_ExecuteStatement_ShowDownsamplePolicies(t *testing.T) {
+	e := NewStatementExecutor()
+	e.Store.DatabasesFn = func() ([]meta.DatabaseInfo, error) {
+		return []meta.DatabaseInfo{
+			{
+				Name: "db0",
+				DownsamplePolicies: []meta.DownsamplePolicyInfo{
+					{Name: "ds0", SourceRetentionPolicy: "raw", TargetRetentionPolicy: "week", Interval: 5 * time.Minute},
+					{
+						Name:                  "ds1",
+						SourceRetentionPolicy: "week",
+						TargetRetentionPolicy: "year",
+						Interval:              time.Hour,
+						DefaultAggregate:      "max",
+						Aggregates:            map[string]string{"state": "last", "n": "sum"},
+						DownsampledUntil:      time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+					},
+				},
+			},
+		}, nil
+	}
+
+	stmt := influxql.MustParseStatement(`SHOW DOWNSAMPLE POLICIES`)
+	if res := e.ExecuteStatement(stmt); res.Err != nil {
+		t.Fatal(res.Err)
+	} else if !reflect.DeepEqual(res.Series, influxql.Rows{
+		{
+			Name:    "db0",
+			Columns: []string{"name", "source", "target", "interval", "default_aggregate", "aggregates", "downsampled_until"},
+			Values: [][]interface{}{
+				{"ds0", "raw", "week", "5m", "mean", []string{}, ""},
+				{"ds1", "week", "year", "1h", "max", []string{"last(state)", "sum(n)"}, "2000-01-01T00:00:00Z"},
+			},
+		},
+	}) {
+		t.Fatalf("unexpected rows: %s", spew.Sdump(res.Series))
+	}
+}
+
 // Ensure a SHOW SHARDS statement can be executed.
 func TestStatementExecutor_ExecuteStatement_ShowShards(t *testing.T) {
 	e := NewStatementExecutor()
@@ -833,10 +1235,12 @@ func NewStatementExecutor() *StatementExecutor {
 type StatementExecutorStore struct {
 	NodesFn                     func() ([]meta.NodeInfo, error)
 	PeersFn                     func() ([]string, error)
+	DeleteNodeFn                func(id uint64, force bool) error
 	DatabaseFn                  func(name string) (*meta.DatabaseInfo, error)
 	DatabasesFn                 func() ([]meta.DatabaseInfo, error)
 	CreateDatabaseFn            func(name string) (*meta.DatabaseInfo, error)
 	DropDatabaseFn              func(name string) error
+	RenameDatabaseFn            func(name, newName string) error
 	DefaultRetentionPolicyFn    func(database string) (*meta.RetentionPolicyInfo, error)
 	CreateRetentionPolicyFn     func(database string, rpi *meta.RetentionPolicyInfo) (*meta.RetentionPolicyInfo, error)
 	UpdateRetentionPolicyFn     func(database, name string, rpu *meta.RetentionPolicyUpdate) error
@@ -844,15 +1248,23 @@ type StatementExecutorStore struct {
 	DropRetentionPolicyFn       func(database, name string) error
 	UsersFn                     func() ([]meta.UserInfo, error)
 	CreateUserFn                func(name, password string, admin bool) (*meta.UserInfo, error)
+	CreateWriteOnlyUserFn       func(name, password, database string) (*meta.UserInfo, error)
 	UpdateUserFn                func(name, password string) error
 	DropUserFn                  func(name string) error
 	SetPrivilegeFn              func(username, database string, p influxql.Privilege) error
 	SetAdminPrivilegeFn         func(username string, admin bool) error
-	UserPrivilegesFn            func(username string) (map[string]influxql.Privilege, error)
+	SetUserLimitsFn             func(username string, limits meta.UserLimits) error
+	EffectivePrivilegesFn       func(username string) ([]meta.EffectivePrivilege, error)
 	UserPrivilegeFn             func(username, database string) (*influxql.Privilege, error)
 	ContinuousQueriesFn         func() ([]meta.ContinuousQueryInfo, error)
 	CreateContinuousQueryFn     func(database, name, query string) error
 	DropContinuousQueryFn       func(database, name string) error
+	CreateSubscriptionFn        func(database, rp, name, mode string, destinations []string) error
+	DropSubscriptionFn          func(database, rp, name string) error
+	CreateTagRuleFn             func(database string, rule meta.TagRuleInfo) error
+	DropTagRuleFn               func(database, name string) error
+	CreateDownsamplePolicyFn    func(database string, dpi meta.DownsamplePolicyInfo) error
+	DropDownsamplePolicyFn      func(database, name string) error
 }
 
 func (s *StatementExecutorStore) Nodes() ([]meta.NodeInfo, error) {
@@ -863,6 +1275,10 @@ func (s *StatementExecutorStore) Peers() ([]string, error) {
 	return s.PeersFn()
 }
 
+func (s *StatementExecutorStore) DeleteNode(id uint64, force bool) error {
+	return s.DeleteNodeFn(id, force)
+}
+
 func (s *StatementExecutorStore) Database(name string) (*meta.DatabaseInfo, error) {
 	return s.DatabaseFn(name)
 }
@@ -879,6 +1295,10 @@ func (s *StatementExecutorStore) DropDatabase(name string) error {
 	return s.DropDatabaseFn(name)
 }
 
+func (s *StatementExecutorStore) RenameDatabase(name, newName string) error {
+	return s.RenameDatabaseFn(name, newName)
+}
+
 func (s *StatementExecutorStore) DefaultRetentionPolicy(database string) (*meta.RetentionPolicyInfo, error) {
 	return s.DefaultRetentionPolicyFn(database)
 }
@@ -907,6 +1327,10 @@ func (s *StatementExecutorStore) CreateUser(name, password string, admin bool) (
 	return s.CreateUserFn(name, password, admin)
 }
 
+func (s *StatementExecutorStore) CreateWriteOnlyUser(name, password, database string) (*meta.UserInfo, error) {
+	return s.CreateWriteOnlyUserFn(name, password, database)
+}
+
 func (s *StatementExecutorStore) UpdateUser(name, password string) error {
 	return s.UpdateUserFn(name, password)
 }
@@ -923,8 +1347,12 @@ func (s *StatementExecutorStore) SetAdminPrivilege(username string, admin bool)
 	return s.SetAdminPrivilegeFn(username, admin)
 }
 
-func (s *StatementExecutorStore) UserPrivileges(username string) (map[string]influxql.Privilege, error) {
-	return s.UserPrivilegesFn(username)
+func (s *StatementExecutorStore) SetUserLimits(username string, limits meta.UserLimits) error {
+	return s.SetUserLimitsFn(username, limits)
+}
+
+func (s *StatementExecutorStore) EffectivePrivileges(username string) ([]meta.EffectivePrivilege, error) {
+	return s.EffectivePrivilegesFn(username)
 }
 
 func (s *StatementExecutorStore) UserPrivilege(username, database string) (*influxql.Privilege, error) {
@@ -942,3 +1370,27 @@ func (s *StatementExecutorStore) CreateContinuousQuery(database, name, query str
 func (s *StatementExecutorStore) DropContinuousQuery(database, name string) error {
 	return s.DropContinuousQueryFn(database, name)
 }
+
+func (s *StatementExecutorStore) CreateSubscription(database, rp, name, mode string, destinations []string) error {
+	return s.CreateSubscriptionFn(database, rp, name, mode, destinations)
+}
+
+func (s *StatementExecutorStore) DropSubscription(database, rp, name string) error {
+	return s.DropSubscriptionFn(database, rp, name)
+}
+
+func (s *StatementExecutorStore) CreateTagRule(database string, rule meta.TagRuleInfo) error {
+	return s.CreateTagRuleFn(database, rule)
+}
+
+func (s *StatementExecutorStore) DropTagRule(database, name string) error {
+	return s.DropTagRuleFn(database, name)
+}
+
+func (s *StatementExecutorStore) CreateDownsamplePolicy(database string, dpi meta.DownsamplePolicyInfo) error {
+	return s.CreateDownsamplePolicyFn(database, dpi)
+}
+
+func (s *StatementExecutorStore) DropDownsamplePolicy(database, name string) error {
+	return s.DropDownsamplePolicyFn(database, name)
+}
@@ -117,9 +117,27 @@ type Store struct {
 	// The amount of time without an apply before sending a heartbeat.
 	CommitTimeout time.Duration
 
+	// The interval between checks for taking a snapshot, the number of new
+	// log entries which triggers one, and the number of entries retained
+	// after the log is truncated. Zero values use the raft defaults.
+	SnapshotInterval  time.Duration
+	SnapshotThreshold uint64
+	TrailingLogs      uint64
+
+	// The cost passwords are hashed with by the default hash function.
+	BcryptCost int
+
+	// The number of consecutive failed logins after which a user is locked
+	// out, and for how long. Zero disables the lockout.
+	MaxFailedLogins int
+	LockoutDuration time.Duration
+
 	// Authentication cache.
 	authCache map[string]authUser
 
+	// Failed logins by user name. They are tracked by each node separately.
+	failedLogins map[string]*failedLogin
+
 	// hashPassword generates a cryptographically secure hash for password.
 	// Returns an error if the password is invalid or a hash cannot be generated.
 	hashPassword HashPasswordFn
@@ -132,6 +150,11 @@ type authUser struct {
 	hash []byte
 }
 
+type failedLogin struct {
+	n           int
+	lockedUntil time.Time
+}
+
 // NewStore returns a new instance of Store.
 func NewStore(c *Config) *Store {
 	s := &Store{
@@ -151,11 +174,18 @@ func NewStore(c *Config) *Store {
 		ElectionTimeout:    time.Duration(c.ElectionTimeout),
 		LeaderLeaseTimeout: time.Duration(c.LeaderLeaseTimeout),
 		CommitTimeout:      time.Duration(c.CommitTimeout),
+		SnapshotInterval:   time.Duration(c.SnapshotInterval),
+		SnapshotThreshold:  c.SnapshotThreshold,
+		TrailingLogs:       c.TrailingLogs,
+		BcryptCost:         c.BcryptCost,
+		MaxFailedLogins:    c.MaxFailedLogins,
+		LockoutDuration:    time.Duration(c.LockoutDuration),
 		authCache:          make(map[string]authUser, 0),
-		hashPassword: func(password string) ([]byte, error) {
-			return bcrypt.GenerateFromPassword([]byte(password), BcryptCost)
-		},
-		Logger: log.New(os.Stderr, "[metastore] ", log.LstdFlags),
+		failedLogins:       make(map[string]*failedLogin),
+		Logger:             log.New(os.Stderr, "[metastore] ", log.LstdFlags),
+	}
+	s.hashPassword = func(password string) ([]byte, error) {
+		return bcrypt.GenerateFromPassword([]byte(password), s.BcryptCost)
 	}
 
 	s.raftState = &localRaft{store: s}
@@ -191,6 +221,9 @@ func (s *Store) Open() error {
 		panic("Store.RPCListener not set")
 	}
 
+	// The logger may have been replaced since the store was created.
+	s.rpc.logger = s.Logger
+
 	s.Logger.Printf("Using data dir: %v", s.Path())
 
 	if err := func() error {
@@ -823,10 +856,12 @@ func (s *Store) UpdateNode(id uint64, host string) (*NodeInfo, error) {
 }
 
 // DeleteNode removes a node from the metastore by id.
-func (s *Store) DeleteNode(id uint64) error {
+// If force is set then shards only owned by the node are reassigned.
+func (s *Store) DeleteNode(id uint64, force bool) error {
 	return s.exec(internal.Command_DeleteNodeCommand, internal.E_DeleteNodeCommand_Command,
 		&internal.DeleteNodeCommand{
-			ID: proto.Uint64(id),
+			ID:    proto.Uint64(id),
+			Force: proto.Bool(force),
 		},
 	)
 }
@@ -854,14 +889,10 @@ func (s *Store) Databases() (dis []DatabaseInfo, err error) {
 
 // CreateDatabase creates a new database in the store.
 func (s *Store) CreateDatabase(name string) (*DatabaseInfo, error) {
-	if err := s.exec(internal.Command_CreateDatabaseCommand, internal.E_CreateDatabaseCommand_Command,
-		&internal.CreateDatabaseCommand{
-			Name: proto.String(name),
-		},
-	); err != nil {
-		return nil, err
-	}
-	s.Logger.Printf("database '%s' created", name)
+	// The database and its default retention policy are created in a batch,
+	// so the database is never left without the policy.
+	b := s.NewBatch()
+	b.CreateDatabase(name)
 
 	if s.retentionAutoCreate {
 		// Read node count.
@@ -878,19 +909,18 @@ func (s *Store) CreateDatabase(name string) (*DatabaseInfo, error) {
 			nodeN = MaxAutoCreatedRetentionPolicyReplicaN
 		}
 
-		// Create a retention policy.
+		// Create a retention policy and set it as the default.
 		rpi := NewRetentionPolicyInfo(AutoCreateRetentionPolicyName)
 		rpi.ReplicaN = nodeN
 		rpi.Duration = AutoCreateRetentionPolicyPeriod
-		if _, err := s.CreateRetentionPolicy(name, rpi); err != nil {
-			return nil, err
-		}
+		b.CreateRetentionPolicy(name, rpi)
+		b.SetDefaultRetentionPolicy(name, AutoCreateRetentionPolicyName)
+	}
 
-		// Set it as the default retention policy.
-		if err := s.SetDefaultRetentionPolicy(name, AutoCreateRetentionPolicyName); err != nil {
-			return nil, err
-		}
+	if err := s.ApplyBatch(b); err != nil {
+		return nil, err
 	}
+	s.Logger.Printf("database '%s' created", name)
 
 	return s.Database(name)
 }
@@ -921,6 +951,16 @@ func (s *Store) DropDatabase(name string) error {
 	)
 }
 
+// RenameDatabase renames a database in the metastore.
+func (s *Store) RenameDatabase(name, newName string) error {
+	return s.exec(internal.Command_RenameDatabaseCommand, internal.E_RenameDatabaseCommand_Command,
+		&internal.RenameDatabaseCommand{
+			Name:    proto.String(name),
+			NewName: proto.String(newName),
+		},
+	)
+}
+
 // RetentionPolicy returns a retention policy for a database by name.
 func (s *Store) RetentionPolicy(database, name string) (rpi *RetentionPolicyInfo, err error) {
 	err = s.read(func(data *Data) error {
@@ -1031,13 +1071,20 @@ func (s *Store) UpdateRetentionPolicy(database, name string, rpu *RetentionPolic
 		replicaN = &value
 	}
 
+	var resolution *int64
+	if rpu.Resolution != nil {
+		value := int64(*rpu.Resolution)
+		resolution = &value
+	}
+
 	return s.exec(internal.Command_UpdateRetentionPolicyCommand, internal.E_UpdateRetentionPolicyCommand_Command,
 		&internal.UpdateRetentionPolicyCommand{
-			Database: proto.String(database),
-			Name:     proto.String(name),
-			NewName:  newName,
-			Duration: duration,
-			ReplicaN: replicaN,
+			Database:   proto.String(database),
+			Name:       proto.String(name),
+			NewName:    newName,
+			Duration:   duration,
+			ReplicaN:   replicaN,
+			Resolution: resolution,
 		},
 	)
 }
@@ -1176,6 +1223,17 @@ func (s *Store) ShardOwner(shardID uint64) (database, policy string, sgi *ShardG
 	return
 }
 
+// UpdateShardOwners adds and removes owners of a shard.
+func (s *Store) UpdateShardOwners(id uint64, added, removed []uint64) error {
+	return s.exec(internal.Command_UpdateShardOwnersCommand, internal.E_UpdateShardOwnersCommand_Command,
+		&internal.UpdateShardOwnersCommand{
+			ID:            proto.Uint64(id),
+			AddedOwners:   added,
+			RemovedOwners: removed,
+		},
+	)
+}
+
 // CreateContinuousQuery creates a new continuous query on the store.
 func (s *Store) CreateContinuousQuery(database, name, query string) error {
 	return s.exec(internal.Command_CreateContinuousQueryCommand, internal.E_CreateContinuousQueryCommand_Command,
@@ -1197,6 +1255,93 @@ func (s *Store) DropContinuousQuery(database, name string) error {
 	)
 }
 
+// SetContinuousQueryLastRun records the last execution time of a continuous query.
+func (s *Store) SetContinuousQueryLastRun(database, name string, t time.Time) error {
+	return s.exec(internal.Command_SetContinuousQueryLastRunCommand, internal.E_SetContinuousQueryLastRunCommand_Command,
+		&internal.SetContinuousQueryLastRunCommand{
+			Database: proto.String(database),
+			Name:     proto.String(name),
+			LastRun:  proto.Int64(t.UnixNano()),
+		},
+	)
+}
+
+// CreateSubscription creates a new subscription on the store.
+func (s *Store) CreateSubscription(database, rp, name, mode string, destinations []string) error {
+	return s.exec(internal.Command_CreateSubscriptionCommand, internal.E_CreateSubscriptionCommand_Command,
+		&internal.CreateSubscriptionCommand{
+			Database:        proto.String(database),
+			RetentionPolicy: proto.String(rp),
+			Name:            proto.String(name),
+			Mode:            proto.String(mode),
+			Destinations:    destinations,
+		},
+	)
+}
+
+// DropSubscription removes a subscription from the store.
+func (s *Store) DropSubscription(database, rp, name string) error {
+	return s.exec(internal.Command_DropSubscriptionCommand, internal.E_DropSubscriptionCommand_Command,
+		&internal.DropSubscriptionCommand{
+			Database:        proto.String(database),
+			RetentionPolicy: proto.String(rp),
+			Name:            proto.String(name),
+		},
+	)
+}
+
+// CreateTagRule adds a tag normalization rule to a database.
+func (s *Store) CreateTagRule(database string, rule TagRuleInfo) error {
+	return s.exec(internal.Command_CreateTagRuleCommand, internal.E_CreateTagRuleCommand_Command,
+		&internal.CreateTagRuleCommand{
+			Database: proto.String(database),
+			Rule:     rule.marshal(),
+		},
+	)
+}
+
+// DropTagRule removes a tag normalization rule from a database.
+func (s *Store) DropTagRule(database, name string) error {
+	return s.exec(internal.Command_DropTagRuleCommand, internal.E_DropTagRuleCommand_Command,
+		&internal.DropTagRuleCommand{
+			Database: proto.String(database),
+			Name:     proto.String(name),
+		},
+	)
+}
+
+// CreateDownsamplePolicy adds a downsample policy to a database.
+func (s *Store) CreateDownsamplePolicy(database string, dpi DownsamplePolicyInfo) error {
+	return s.exec(internal.Command_CreateDownsamplePolicyCommand, internal.E_CreateDownsamplePolicyCommand_Command,
+		&internal.CreateDownsamplePolicyCommand{
+			Database: proto.String(database),
+			Policy:   dpi.marshal(),
+		},
+	)
+}
+
+// DropDownsamplePolicy removes a downsample policy from a database.
+func (s *Store) DropDownsamplePolicy(database, name string) error {
+	return s.exec(internal.Command_DropDownsamplePolicyCommand, internal.E_DropDownsamplePolicyCommand_Command,
+		&internal.DropDownsamplePolicyCommand{
+			Database: proto.String(database),
+			Name:     proto.String(name),
+		},
+	)
+}
+
+// SetDownsamplePolicyProgress records the time up to which the points of a
+// downsample policy have been downsampled.
+func (s *Store) SetDownsamplePolicyProgress(database, name string, t time.Time) error {
+	return s.exec(internal.Command_SetDownsamplePolicyProgressCommand, internal.E_SetDownsamplePolicyProgressCommand_Command,
+		&internal.SetDownsamplePolicyProgressCommand{
+			Database:         proto.String(database),
+			Name:             proto.String(name),
+			DownsampledUntil: proto.Int64(t.UnixNano()),
+		},
+	)
+}
+
 // User returns a user by name.
 func (s *Store) User(name string) (ui *UserInfo, err error) {
 	err = s.read(func(data *Data) error {
@@ -1235,8 +1380,15 @@ func (s *Store) AdminUserExists() (exists bool, err error) {
 // ErrAuthenticate is returned when authentication fails.
 var ErrAuthenticate = errors.New("authentication failed")
 
+// ErrUserLocked is returned when authenticating a user locked out after too
+// many failed logins.
+var ErrUserLocked = errors.New("user locked out after too many failed logins")
+
 // Authenticate retrieves a user with a matching username and password.
+// A user is locked out for LockoutDuration after MaxFailedLogins consecutive
+// failures. A password hashed with a lower cost than BcryptCost is rehashed.
 func (s *Store) Authenticate(username, password string) (ui *UserInfo, err error) {
+	var rehash bool
 	err = s.read(func(data *Data) error {
 		s.mu.Lock()
 		defer s.mu.Unlock()
@@ -1247,6 +1399,11 @@ func (s *Store) Authenticate(username, password string) (ui *UserInfo, err error
 			return ErrUserNotFound
 		}
 
+		// Reject locked out users before checking the password.
+		if fl := s.failedLogins[username]; fl != nil && time.Now().Before(fl.lockedUntil) {
+			return ErrUserLocked
+		}
+
 		// Check the local auth cache first.
 		if au, ok := s.authCache[username]; ok {
 			// verify the password using the cached salt and hash
@@ -1256,6 +1413,7 @@ func (s *Store) Authenticate(username, password string) (ui *UserInfo, err error
 			}
 
 			if bytes.Equal(hashed, au.hash) {
+				delete(s.failedLogins, username)
 				ui = u
 				return nil
 			}
@@ -1266,6 +1424,7 @@ func (s *Store) Authenticate(username, password string) (ui *UserInfo, err error
 		if err := bcrypt.CompareHashAndPassword([]byte(u.Hash), []byte(password)); err != nil {
 			return ErrAuthenticate
 		}
+		delete(s.failedLogins, username)
 
 		// generate a salt and hash of the password for the cache
 		salt, hashed, err := s.saltedHash(password)
@@ -1274,12 +1433,51 @@ func (s *Store) Authenticate(username, password string) (ui *UserInfo, err error
 		}
 		s.authCache[username] = authUser{salt: salt, hash: hashed}
 
+		if cost, err := bcrypt.Cost([]byte(u.Hash)); err == nil && cost < s.BcryptCost {
+			rehash = true
+		}
+
 		ui = u
 		return nil
 	})
+
+	// Count the failure once read is done, since it retries failed reads.
+	if err == ErrAuthenticate {
+		s.mu.Lock()
+		s.addFailedLogin(username)
+		s.mu.Unlock()
+	}
+
+	// Upgrade legacy hashes now that the password is known. The login
+	// succeeds even if the hash cannot be updated.
+	if err == nil && rehash {
+		if err := s.UpdateUser(username, password); err != nil {
+			s.Logger.Printf("rehash password of user %s: %s", username, err)
+		}
+	}
 	return
 }
 
+// addFailedLogin counts a failed login of a user and locks the user out once
+// MaxFailedLogins is reached. The caller must hold s.mu.
+func (s *Store) addFailedLogin(username string) {
+	if s.MaxFailedLogins <= 0 {
+		return
+	}
+
+	fl := s.failedLogins[username]
+	if fl == nil || !fl.lockedUntil.IsZero() {
+		fl = &failedLogin{}
+		s.failedLogins[username] = fl
+	}
+
+	fl.n++
+	if fl.n >= s.MaxFailedLogins {
+		fl.lockedUntil = time.Now().Add(s.LockoutDuration)
+		s.Logger.Printf("user %s locked out for %s after %d failed logins", username, s.LockoutDuration, fl.n)
+	}
+}
+
 // hashWithSalt returns a salted hash of password using salt
 func (s *Store) hashWithSalt(salt []byte, password string) ([]byte, error) {
 	hasher := sha256.New()
@@ -1320,6 +1518,28 @@ func (s *Store) CreateUser(name, password string, admin bool) (*UserInfo, error)
 	return s.User(name)
 }
 
+// CreateWriteOnlyUser creates a new user which may only write to database.
+func (s *Store) CreateWriteOnlyUser(name, password, database string) (*UserInfo, error) {
+	// Hash the password before serializing it.
+	hash, err := s.hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	// Serialize command and send it to the leader.
+	if err := s.exec(internal.Command_CreateUserCommand, internal.E_CreateUserCommand_Command,
+		&internal.CreateUserCommand{
+			Name:              proto.String(name),
+			Hash:              proto.String(string(hash)),
+			Admin:             proto.Bool(false),
+			WriteOnlyDatabase: proto.String(database),
+		},
+	); err != nil {
+		return nil, err
+	}
+	return s.User(name)
+}
+
 // DropUser removes a user from the metastore by name.
 func (s *Store) DropUser(name string) error {
 	return s.exec(internal.Command_DropUserCommand, internal.E_DropUserCommand_Command,
@@ -1367,6 +1587,19 @@ func (s *Store) SetAdminPrivilege(username string, admin bool) error {
 	)
 }
 
+// SetUserLimits sets the resource limits for a user.
+func (s *Store) SetUserLimits(username string, limits UserLimits) error {
+	return s.exec(internal.Command_SetUserLimitsCommand, internal.E_SetUserLimitsCommand_Command,
+		&internal.SetUserLimitsCommand{
+			Username:   proto.String(username),
+			MaxQueries: proto.Int64(int64(limits.MaxQueries)),
+			MaxRange:   proto.Int64(int64(limits.MaxRange)),
+			MaxPoints:  proto.Int64(limits.MaxPoints),
+			Batch:      proto.Bool(limits.Batch),
+		},
+	)
+}
+
 // UserPrivileges returns a list of all databases.
 func (s *Store) UserPrivileges(username string) (p map[string]influxql.Privilege, err error) {
 	err = s.read(func(data *Data) error {
@@ -1376,6 +1609,15 @@ func (s *Store) UserPrivileges(username string) (p map[string]influxql.Privilege
 	return
 }
 
+// EffectivePrivileges returns what a user may do on each database.
+func (s *Store) EffectivePrivileges(username string) (a []EffectivePrivilege, err error) {
+	err = s.read(func(data *Data) error {
+		a, err = data.EffectivePrivileges(username)
+		return err
+	})
+	return
+}
+
 // UserPrivilege returns the privilege for a database.
 func (s *Store) UserPrivilege(username, database string) (p *influxql.Privilege, err error) {
 	err = s.read(func(data *Data) error {
@@ -1483,11 +1725,128 @@ func (s *Store) invalidate() error {
 	return s.raftState.invalidate()
 }
 
-func (s *Store) exec(typ internal.Command_Type, desc *proto.ExtensionDesc, value interface{}) error {
-	// Create command.
+// Batch is a set of changes to the meta store which Store.ApplyBatch applies
+// atomically, in the order they were added: if one change fails, none are
+// applied. Changes are validated when they are applied, so a batch can
+// create a database and then its retention policies and users.
+type Batch struct {
+	store *Store
+	cmds  []*internal.Command
+	err   error // first error preparing a change
+}
+
+// NewBatch returns an empty batch of changes to the store.
+func (s *Store) NewBatch() *Batch {
+	return &Batch{store: s}
+}
+
+func (b *Batch) add(typ internal.Command_Type, desc *proto.ExtensionDesc, value interface{}) {
+	b.cmds = append(b.cmds, newCommand(typ, desc, value))
+}
+
+// CreateDatabase adds the creation of a database. Unlike Store.CreateDatabase,
+// no retention policy is created automatically.
+func (b *Batch) CreateDatabase(name string) {
+	b.add(internal.Command_CreateDatabaseCommand, internal.E_CreateDatabaseCommand_Command,
+		&internal.CreateDatabaseCommand{
+			Name: proto.String(name),
+		},
+	)
+}
+
+// CreateRetentionPolicy adds the creation of a retention policy.
+func (b *Batch) CreateRetentionPolicy(database string, rpi *RetentionPolicyInfo) {
+	if rpi.Duration < RetentionPolicyMinDuration && rpi.Duration != 0 {
+		if b.err == nil {
+			b.err = ErrRetentionPolicyDurationTooLow
+		}
+		return
+	}
+	b.add(internal.Command_CreateRetentionPolicyCommand, internal.E_CreateRetentionPolicyCommand_Command,
+		&internal.CreateRetentionPolicyCommand{
+			Database:        proto.String(database),
+			RetentionPolicy: rpi.marshal(),
+		},
+	)
+}
+
+// SetDefaultRetentionPolicy adds setting the default retention policy of a database.
+func (b *Batch) SetDefaultRetentionPolicy(database, name string) {
+	b.add(internal.Command_SetDefaultRetentionPolicyCommand, internal.E_SetDefaultRetentionPolicyCommand_Command,
+		&internal.SetDefaultRetentionPolicyCommand{
+			Database: proto.String(database),
+			Name:     proto.String(name),
+		},
+	)
+}
+
+// CreateUser adds the creation of a user.
+func (b *Batch) CreateUser(name, password string, admin bool) {
+	hash, err := b.store.hashPassword(password)
+	if err != nil {
+		if b.err == nil {
+			b.err = err
+		}
+		return
+	}
+	b.add(internal.Command_CreateUserCommand, internal.E_CreateUserCommand_Command,
+		&internal.CreateUserCommand{
+			Name:  proto.String(name),
+			Hash:  proto.String(string(hash)),
+			Admin: proto.Bool(admin),
+		},
+	)
+}
+
+// SetPrivilege adds setting the privilege of a user on a database.
+func (b *Batch) SetPrivilege(username, database string, p influxql.Privilege) {
+	b.add(internal.Command_SetPrivilegeCommand, internal.E_SetPrivilegeCommand_Command,
+		&internal.SetPrivilegeCommand{
+			Username:  proto.String(username),
+			Database:  proto.String(database),
+			Privilege: proto.Int32(int32(p)),
+		},
+	)
+}
+
+// SetUserLimits adds setting the resource limits of a user.
+func (b *Batch) SetUserLimits(username string, limits UserLimits) {
+	b.add(internal.Command_SetUserLimitsCommand, internal.E_SetUserLimitsCommand_Command,
+		&internal.SetUserLimitsCommand{
+			Username:   proto.String(username),
+			MaxQueries: proto.Int64(int64(limits.MaxQueries)),
+			MaxRange:   proto.Int64(int64(limits.MaxRange)),
+			MaxPoints:  proto.Int64(limits.MaxPoints),
+			Batch:      proto.Bool(limits.Batch),
+		},
+	)
+}
+
+// ApplyBatch applies all the changes of b, or none of them if one fails.
+func (s *Store) ApplyBatch(b *Batch) error {
+	if b.err != nil {
+		return b.err
+	} else if len(b.cmds) == 0 {
+		return nil
+	}
+	return s.exec(internal.Command_BatchCommand, internal.E_BatchCommand_Command,
+		&internal.BatchCommand{
+			Commands: b.cmds,
+		},
+	)
+}
+
+// newCommand returns a command of typ with value as its extension.
+func newCommand(typ internal.Command_Type, desc *proto.ExtensionDesc, value interface{}) *internal.Command {
 	cmd := &internal.Command{Type: &typ}
 	err := proto.SetExtension(cmd, desc, value)
 	assert(err == nil, "proto.SetExtension: %s", err)
+	return cmd
+}
+
+func (s *Store) exec(typ internal.Command_Type, desc *proto.ExtensionDesc, value interface{}) error {
+	// Create command.
+	cmd := newCommand(typ, desc, value)
 
 	// Marshal to a byte slice.
 	b, err := proto.Marshal(cmd)
@@ -1577,10 +1936,6 @@ func (s *Store) cachedData() *Data {
 	return s.data.Clone()
 }
 
-// BcryptCost is the cost associated with generating password with Bcrypt.
-// This setting is lowered during testing to improve test suite performance.
-var BcryptCost = 10
-
 // HashPasswordFn represnets a password hashing function.
 type HashPasswordFn func(password string) ([]byte, error)
 
@@ -1612,50 +1967,7 @@ func (fsm *storeFSM) Apply(l *raft.Log) interface{} {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	err := func() interface{} {
-		switch cmd.GetType() {
-		case internal.Command_CreateNodeCommand:
-			return fsm.applyCreateNodeCommand(&cmd)
-		case internal.Command_DeleteNodeCommand:
-			return fsm.applyDeleteNodeCommand(&cmd)
-		case internal.Command_CreateDatabaseCommand:
-			return fsm.applyCreateDatabaseCommand(&cmd)
-		case internal.Command_DropDatabaseCommand:
-			return fsm.applyDropDatabaseCommand(&cmd)
-		case internal.Command_CreateRetentionPolicyCommand:
-			return fsm.applyCreateRetentionPolicyCommand(&cmd)
-		case internal.Command_DropRetentionPolicyCommand:
-			return fsm.applyDropRetentionPolicyCommand(&cmd)
-		case internal.Command_SetDefaultRetentionPolicyCommand:
-			return fsm.applySetDefaultRetentionPolicyCommand(&cmd)
-		case internal.Command_UpdateRetentionPolicyCommand:
-			return fsm.applyUpdateRetentionPolicyCommand(&cmd)
-		case internal.Command_CreateShardGroupCommand:
-			return fsm.applyCreateShardGroupCommand(&cmd)
-		case internal.Command_DeleteShardGroupCommand:
-			return fsm.applyDeleteShardGroupCommand(&cmd)
-		case internal.Command_CreateContinuousQueryCommand:
-			return fsm.applyCreateContinuousQueryCommand(&cmd)
-		case internal.Command_DropContinuousQueryCommand:
-			return fsm.applyDropContinuousQueryCommand(&cmd)
-		case internal.Command_CreateUserCommand:
-			return fsm.applyCreateUserCommand(&cmd)
-		case internal.Command_DropUserCommand:
-			return fsm.applyDropUserCommand(&cmd)
-		case internal.Command_UpdateUserCommand:
-			return fsm.applyUpdateUserCommand(&cmd)
-		case internal.Command_SetPrivilegeCommand:
-			return fsm.applySetPrivilegeCommand(&cmd)
-		case internal.Command_SetAdminPrivilegeCommand:
-			return fsm.applySetAdminPrivilegeCommand(&cmd)
-		case internal.Command_SetDataCommand:
-			return fsm.applySetDataCommand(&cmd)
-		case internal.Command_UpdateNodeCommand:
-			return fsm.applyUpdateNodeCommand(&cmd)
-		default:
-			panic(fmt.Errorf("cannot apply command: %x", l.Data))
-		}
-	}()
+	err := fsm.applyCommand(&cmd)
 
 	// Copy term and index to new metadata.
 	fsm.data.Term = l.Term
@@ -1666,6 +1978,76 @@ func (fsm *storeFSM) Apply(l *raft.Log) interface{} {
 	return err
 }
 
+// applyCommand applies a single command to the data.
+func (fsm *storeFSM) applyCommand(cmd *internal.Command) interface{} {
+	switch cmd.GetType() {
+	case internal.Command_CreateNodeCommand:
+		return fsm.applyCreateNodeCommand(cmd)
+	case internal.Command_DeleteNodeCommand:
+		return fsm.applyDeleteNodeCommand(cmd)
+	case internal.Command_CreateDatabaseCommand:
+		return fsm.applyCreateDatabaseCommand(cmd)
+	case internal.Command_DropDatabaseCommand:
+		return fsm.applyDropDatabaseCommand(cmd)
+	case internal.Command_CreateRetentionPolicyCommand:
+		return fsm.applyCreateRetentionPolicyCommand(cmd)
+	case internal.Command_DropRetentionPolicyCommand:
+		return fsm.applyDropRetentionPolicyCommand(cmd)
+	case internal.Command_SetDefaultRetentionPolicyCommand:
+		return fsm.applySetDefaultRetentionPolicyCommand(cmd)
+	case internal.Command_UpdateRetentionPolicyCommand:
+		return fsm.applyUpdateRetentionPolicyCommand(cmd)
+	case internal.Command_CreateShardGroupCommand:
+		return fsm.applyCreateShardGroupCommand(cmd)
+	case internal.Command_DeleteShardGroupCommand:
+		return fsm.applyDeleteShardGroupCommand(cmd)
+	case internal.Command_UpdateShardOwnersCommand:
+		return fsm.applyUpdateShardOwnersCommand(cmd)
+	case internal.Command_CreateContinuousQueryCommand:
+		return fsm.applyCreateContinuousQueryCommand(cmd)
+	case internal.Command_DropContinuousQueryCommand:
+		return fsm.applyDropContinuousQueryCommand(cmd)
+	case internal.Command_SetContinuousQueryLastRunCommand:
+		return fsm.applySetContinuousQueryLastRunCommand(cmd)
+	case internal.Command_CreateSubscriptionCommand:
+		return fsm.applyCreateSubscriptionCommand(cmd)
+	case internal.Command_DropSubscriptionCommand:
+		return fsm.applyDropSubscriptionCommand(cmd)
+	case internal.Command_CreateTagRuleCommand:
+		return fsm.applyCreateTagRuleCommand(cmd)
+	case internal.Command_DropTagRuleCommand:
+		return fsm.applyDropTagRuleCommand(cmd)
+	case internal.Command_CreateDownsamplePolicyCommand:
+		return fsm.applyCreateDownsamplePolicyCommand(cmd)
+	case internal.Command_DropDownsamplePolicyCommand:
+		return fsm.applyDropDownsamplePolicyCommand(cmd)
+	case internal.Command_SetDownsamplePolicyProgressCommand:
+		return fsm.applySetDownsamplePolicyProgressCommand(cmd)
+	case internal.Command_CreateUserCommand:
+		return fsm.applyCreateUserCommand(cmd)
+	case internal.Command_DropUserCommand:
+		return fsm.applyDropUserCommand(cmd)
+	case internal.Command_UpdateUserCommand:
+		return fsm.applyUpdateUserCommand(cmd)
+	case internal.Command_SetPrivilegeCommand:
+		return fsm.applySetPrivilegeCommand(cmd)
+	case internal.Command_SetAdminPrivilegeCommand:
+		return fsm.applySetAdminPrivilegeCommand(cmd)
+	case internal.Command_SetUserLimitsCommand:
+		return fsm.applySetUserLimitsCommand(cmd)
+	case internal.Command_SetDataCommand:
+		return fsm.applySetDataCommand(cmd)
+	case internal.Command_UpdateNodeCommand:
+		return fsm.applyUpdateNodeCommand(cmd)
+	case internal.Command_BatchCommand:
+		return fsm.applyBatchCommand(cmd)
+	case internal.Command_RenameDatabaseCommand:
+		return fsm.applyRenameDatabaseCommand(cmd)
+	default:
+		panic(fmt.Errorf("cannot apply command: %s", cmd))
+	}
+}
+
 func (fsm *storeFSM) applyCreateNodeCommand(cmd *internal.Command) interface{} {
 	ext, _ := proto.GetExtension(cmd, internal.E_CreateNodeCommand_Command)
 	v := ext.(*internal.CreateNodeCommand)
@@ -1708,7 +2090,7 @@ func (fsm *storeFSM) applyDeleteNodeCommand(cmd *internal.Command) interface{} {
 
 	// Copy data and update.
 	other := fsm.data.Clone()
-	if err := other.DeleteNode(v.GetID()); err != nil {
+	if err := other.DeleteNode(v.GetID(), v.GetForce()); err != nil {
 		return err
 	}
 	fsm.data = other
@@ -1744,6 +2126,20 @@ func (fsm *storeFSM) applyDropDatabaseCommand(cmd *internal.Command) interface{}
 	return nil
 }
 
+func (fsm *storeFSM) applyRenameDatabaseCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_RenameDatabaseCommand_Command)
+	v := ext.(*internal.RenameDatabaseCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.RenameDatabase(v.GetName(), v.GetNewName()); err != nil {
+		return err
+	}
+	fsm.data = other
+
+	return nil
+}
+
 func (fsm *storeFSM) applyCreateRetentionPolicyCommand(cmd *internal.Command) interface{} {
 	ext, _ := proto.GetExtension(cmd, internal.E_CreateRetentionPolicyCommand_Command)
 	v := ext.(*internal.CreateRetentionPolicyCommand)
@@ -1757,6 +2153,7 @@ func (fsm *storeFSM) applyCreateRetentionPolicyCommand(cmd *internal.Command) in
 			ReplicaN:           int(pb.GetReplicaN()),
 			Duration:           time.Duration(pb.GetDuration()),
 			ShardGroupDuration: time.Duration(pb.GetShardGroupDuration()),
+			Resolution:         time.Duration(pb.GetResolution()),
 		}); err != nil {
 		return err
 	}
@@ -1807,6 +2204,10 @@ func (fsm *storeFSM) applyUpdateRetentionPolicyCommand(cmd *internal.Command) in
 		value := int(v.GetReplicaN())
 		rpu.ReplicaN = &value
 	}
+	if v.Resolution != nil {
+		value := time.Duration(v.GetResolution())
+		rpu.Resolution = &value
+	}
 
 	// Copy data and update.
 	other := fsm.data.Clone()
@@ -1846,6 +2247,20 @@ func (fsm *storeFSM) applyDeleteShardGroupCommand(cmd *internal.Command) interfa
 	return nil
 }
 
+func (fsm *storeFSM) applyUpdateShardOwnersCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_UpdateShardOwnersCommand_Command)
+	v := ext.(*internal.UpdateShardOwnersCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.UpdateShardOwners(v.GetID(), v.GetAddedOwners(), v.GetRemovedOwners()); err != nil {
+		return err
+	}
+	fsm.data = other
+
+	return nil
+}
+
 func (fsm *storeFSM) applyCreateContinuousQueryCommand(cmd *internal.Command) interface{} {
 	ext, _ := proto.GetExtension(cmd, internal.E_CreateContinuousQueryCommand_Command)
 	v := ext.(*internal.CreateContinuousQueryCommand)
@@ -1874,13 +2289,135 @@ func (fsm *storeFSM) applyDropContinuousQueryCommand(cmd *internal.Command) inte
 	return nil
 }
 
+func (fsm *storeFSM) applySetContinuousQueryLastRunCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_SetContinuousQueryLastRunCommand_Command)
+	v := ext.(*internal.SetContinuousQueryLastRunCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.SetContinuousQueryLastRun(v.GetDatabase(), v.GetName(), time.Unix(0, v.GetLastRun())); err != nil {
+		return err
+	}
+	fsm.data = other
+
+	return nil
+}
+
+func (fsm *storeFSM) applyCreateSubscriptionCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_CreateSubscriptionCommand_Command)
+	v := ext.(*internal.CreateSubscriptionCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.CreateSubscription(v.GetDatabase(), v.GetRetentionPolicy(), v.GetName(), v.GetMode(), v.GetDestinations()); err != nil {
+		return err
+	}
+	fsm.data = other
+
+	return nil
+}
+
+func (fsm *storeFSM) applyDropSubscriptionCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_DropSubscriptionCommand_Command)
+	v := ext.(*internal.DropSubscriptionCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.DropSubscription(v.GetDatabase(), v.GetRetentionPolicy(), v.GetName()); err != nil {
+		return err
+	}
+	fsm.data = other
+
+	return nil
+}
+
+func (fsm *storeFSM) applyCreateTagRuleCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_CreateTagRuleCommand_Command)
+	v := ext.(*internal.CreateTagRuleCommand)
+
+	var rule TagRuleInfo
+	rule.unmarshal(v.GetRule())
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.CreateTagRule(v.GetDatabase(), rule); err != nil {
+		return err
+	}
+	fsm.data = other
+
+	return nil
+}
+
+func (fsm *storeFSM) applyDropTagRuleCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_DropTagRuleCommand_Command)
+	v := ext.(*internal.DropTagRuleCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.DropTagRule(v.GetDatabase(), v.GetName()); err != nil {
+		return err
+	}
+	fsm.data = other
+
+	return nil
+}
+
+func (fsm *storeFSM) applyCreateDownsamplePolicyCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_CreateDownsamplePolicyCommand_Command)
+	v := ext.(*internal.CreateDownsamplePolicyCommand)
+
+	var dpi DownsamplePolicyInfo
+	dpi.unmarshal(v.GetPolicy())
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.CreateDownsamplePolicy(v.GetDatabase(), dpi); err != nil {
+		return err
+	}
+	fsm.data = other
+
+	return nil
+}
+
+func (fsm *storeFSM) applyDropDownsamplePolicyCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_DropDownsamplePolicyCommand_Command)
+	v := ext.(*internal.DropDownsamplePolicyCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.DropDownsamplePolicy(v.GetDatabase(), v.GetName()); err != nil {
+		return err
+	}
+	fsm.data = other
+
+	return nil
+}
+
+func (fsm *storeFSM) applySetDownsamplePolicyProgressCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_SetDownsamplePolicyProgressCommand_Command)
+	v := ext.(*internal.SetDownsamplePolicyProgressCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.SetDownsamplePolicyProgress(v.GetDatabase(), v.GetName(), time.Unix(0, v.GetDownsampledUntil()).UTC()); err != nil {
+		return err
+	}
+	fsm.data = other
+
+	return nil
+}
+
 func (fsm *storeFSM) applyCreateUserCommand(cmd *internal.Command) interface{} {
 	ext, _ := proto.GetExtension(cmd, internal.E_CreateUserCommand_Command)
 	v := ext.(*internal.CreateUserCommand)
 
 	// Copy data and update.
 	other := fsm.data.Clone()
-	if err := other.CreateUser(v.GetName(), v.GetHash(), v.GetAdmin()); err != nil {
+	if db := v.GetWriteOnlyDatabase(); db != "" {
+		if err := other.CreateWriteOnlyUser(v.GetName(), v.GetHash(), db); err != nil {
+			return err
+		}
+	} else if err := other.CreateUser(v.GetName(), v.GetHash(), v.GetAdmin()); err != nil {
 		return err
 	}
 	fsm.data = other
@@ -1899,6 +2436,7 @@ func (fsm *storeFSM) applyDropUserCommand(cmd *internal.Command) interface{} {
 	}
 	fsm.data = other
 	delete(fsm.authCache, v.GetName())
+	delete(fsm.failedLogins, v.GetName())
 	return nil
 }
 
@@ -1913,6 +2451,7 @@ func (fsm *storeFSM) applyUpdateUserCommand(cmd *internal.Command) interface{} {
 	}
 	fsm.data = other
 	delete(fsm.authCache, v.GetName())
+	delete(fsm.failedLogins, v.GetName())
 	return nil
 }
 
@@ -1942,6 +2481,40 @@ func (fsm *storeFSM) applySetAdminPrivilegeCommand(cmd *internal.Command) interf
 	return nil
 }
 
+func (fsm *storeFSM) applySetUserLimitsCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_SetUserLimitsCommand_Command)
+	v := ext.(*internal.SetUserLimitsCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.SetUserLimits(v.GetUsername(), UserLimits{
+		MaxQueries: int(v.GetMaxQueries()),
+		MaxRange:   time.Duration(v.GetMaxRange()),
+		MaxPoints:  v.GetMaxPoints(),
+		Batch:      v.GetBatch(),
+	}); err != nil {
+		return err
+	}
+	fsm.data = other
+	return nil
+}
+
+func (fsm *storeFSM) applyBatchCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_BatchCommand_Command)
+	v := ext.(*internal.BatchCommand)
+
+	// Apply the commands in order. Every command copies the data before
+	// updating it, so restoring the original data undoes the whole batch.
+	data := fsm.data
+	for _, c := range v.GetCommands() {
+		if err := fsm.applyCommand(c); err != nil {
+			fsm.data = data
+			return err
+		}
+	}
+	return nil
+}
+
 func (fsm *storeFSM) applySetDataCommand(cmd *internal.Command) interface{} {
 	ext, _ := proto.GetExtension(cmd, internal.E_SetDataCommand_Command)
 	v := ext.(*internal.SetDataCommand)
@@ -1979,6 +2552,17 @@ func (fsm *storeFSM) Restore(r io.ReadCloser) error {
 	// with any other function.
 	fsm.data = data
 
+	// Notify waiters of the new data. This is done asynchronously as the
+	// store lock is held while restoring on open.
+	s := (*Store)(fsm)
+	go func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		close(s.changed)
+		s.changed = make(chan struct{})
+	}()
+
+	s.Logger.Printf("restored meta data from snapshot: term=%d index=%d", data.Term, data.Index)
 	return nil
 }
 
@@ -2063,14 +2647,16 @@ func (l *raftLayer) Close() error { return l.ln.Close() }
 
 // RetentionPolicyUpdate represents retention policy fields to be updated.
 type RetentionPolicyUpdate struct {
-	Name     *string
-	Duration *time.Duration
-	ReplicaN *int
+	Name       *string
+	Duration   *time.Duration
+	ReplicaN   *int
+	Resolution *time.Duration
 }
 
-func (rpu *RetentionPolicyUpdate) SetName(v string)            { rpu.Name = &v }
-func (rpu *RetentionPolicyUpdate) SetDuration(v time.Duration) { rpu.Duration = &v }
-func (rpu *RetentionPolicyUpdate) SetReplicaN(v int)           { rpu.ReplicaN = &v }
+func (rpu *RetentionPolicyUpdate) SetName(v string)              { rpu.Name = &v }
+func (rpu *RetentionPolicyUpdate) SetDuration(v time.Duration)   { rpu.Duration = &v }
+func (rpu *RetentionPolicyUpdate) SetReplicaN(v int)             { rpu.ReplicaN = &v }
+func (rpu *RetentionPolicyUpdate) SetResolution(v time.Duration) { rpu.Resolution = &v }
 
 // assert will panic with a given formatted message if the given condition is false.
 func assert(condition bool, msg string, v ...interface{}) {
@@ -14,6 +14,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/influxdb/influxdb/influxql"
 	"github.com/influxdb/influxdb/meta"
 	"github.com/influxdb/influxdb/tcp"
 	"github.com/influxdb/influxdb/toml"
@@ -151,7 +152,7 @@ func TestStore_DeleteNode(t *testing.T) {
 	}
 
 	// Remove second node.
-	if err := s.DeleteNode(3); err != nil {
+	if err := s.DeleteNode(3, false); err != nil {
 		t.Fatal(err)
 	}
 
@@ -173,7 +174,7 @@ func TestStore_DeleteNode_ErrNodeNotFound(t *testing.T) {
 	s := MustOpenStore()
 	defer s.Close()
 
-	if err := s.DeleteNode(2); err != meta.ErrNodeNotFound {
+	if err := s.DeleteNode(2, false); err != meta.ErrNodeNotFound {
 		t.Fatalf("unexpected error: %s", err)
 	}
 }
@@ -259,9 +260,10 @@ func TestStore_CreateRetentionPolicy(t *testing.T) {
 
 	// Create policy on database.
 	if rpi, err := s.CreateRetentionPolicy("db0", &meta.RetentionPolicyInfo{
-		Name:     "rp0",
-		ReplicaN: 2,
-		Duration: 48 * time.Hour,
+		Name:       "rp0",
+		ReplicaN:   2,
+		Duration:   48 * time.Hour,
+		Resolution: time.Minute,
 	}); err != nil {
 		t.Fatal(err)
 	} else if !reflect.DeepEqual(rpi, &meta.RetentionPolicyInfo{
@@ -269,6 +271,7 @@ func TestStore_CreateRetentionPolicy(t *testing.T) {
 		ReplicaN:           2,
 		Duration:           48 * time.Hour,
 		ShardGroupDuration: 24 * time.Hour,
+		Resolution:         time.Minute,
 	}) {
 		t.Fatalf("unexpected policy: %#v", rpi)
 	}
@@ -608,6 +611,123 @@ func TestStore_DropContinuousQuery(t *testing.T) {
 	}
 }
 
+// Ensure the store can record the last run time of a continuous query.
+func TestStore_SetContinuousQueryLastRun(t *testing.T) {
+	t.Parallel()
+	s := MustOpenStore()
+	defer s.Close()
+
+	now := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := s.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if err := s.CreateContinuousQuery("db0", "cq0", "SELECT count() FROM foo"); err != nil {
+		t.Fatal(err)
+	} else if err := s.SetContinuousQueryLastRun("db0", "cq0", now); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ensure the last run time is visible.
+	if di, err := s.Database("db0"); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(di.ContinuousQueries, []meta.ContinuousQueryInfo{
+		{Name: "cq0", Query: "SELECT count() FROM foo", LastRun: now},
+	}) {
+		t.Fatalf("unexpected queries: %#v", di.ContinuousQueries)
+	}
+}
+
+// Ensure the store can update the owners of a shard.
+func TestStore_UpdateShardOwners(t *testing.T) {
+	t.Parallel()
+	s := MustOpenStore()
+	defer s.Close()
+
+	if _, err := s.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if _, err := s.CreateRetentionPolicy("db0", &meta.RetentionPolicyInfo{Name: "rp0", ReplicaN: 1}); err != nil {
+		t.Fatal(err)
+	}
+	sgi, err := s.CreateShardGroup("db0", "rp0", time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := sgi.Shards[0].ID
+
+	// Removing the only owner should fail.
+	if err := s.UpdateShardOwners(id, nil, []uint64{s.NodeID()}); err == nil || err.Error() != meta.ErrShardOwnerRequired.Error() {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Re-adding the current owner leaves the shard unchanged.
+	if err := s.UpdateShardOwners(id, []uint64{s.NodeID()}, nil); err != nil {
+		t.Fatal(err)
+	} else if _, _, sgi := s.ShardOwner(id); sgi == nil || !reflect.DeepEqual(sgi.Shards[0].Owners, []meta.ShardOwner{{NodeID: s.NodeID()}}) {
+		t.Fatalf("unexpected shard group: %#v", sgi)
+	}
+}
+
+// Ensure the store can create a new subscription.
+func TestStore_CreateSubscription(t *testing.T) {
+	t.Parallel()
+	s := MustOpenStore()
+	defer s.Close()
+
+	// Create subscription.
+	if _, err := s.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if _, err := s.CreateRetentionPolicy("db0", &meta.RetentionPolicyInfo{Name: "rp0", ReplicaN: 1}); err != nil {
+		t.Fatal(err)
+	} else if err := s.CreateSubscription("db0", "rp0", "s0", "ANY", []string{"udp://localhost:9000"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ensure the subscription is visible.
+	if rpi, err := s.RetentionPolicy("db0", "rp0"); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(rpi.Subscriptions, []meta.SubscriptionInfo{
+		{Name: "s0", Mode: "ANY", Destinations: []string{"udp://localhost:9000"}},
+	}) {
+		t.Fatalf("unexpected subscriptions: %#v", rpi.Subscriptions)
+	}
+
+	// Create it again.
+	if err := s.CreateSubscription("db0", "rp0", "s0", "ANY", []string{"udp://localhost:9000"}); err != meta.ErrSubscriptionExists {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+// Ensure the store can delete a subscription.
+func TestStore_DropSubscription(t *testing.T) {
+	t.Parallel()
+	s := MustOpenStore()
+	defer s.Close()
+
+	// Create subscriptions.
+	if _, err := s.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if _, err := s.CreateRetentionPolicy("db0", &meta.RetentionPolicyInfo{Name: "rp0", ReplicaN: 1}); err != nil {
+		t.Fatal(err)
+	} else if err := s.CreateSubscription("db0", "rp0", "s0", "ANY", []string{"udp://localhost:9000"}); err != nil {
+		t.Fatal(err)
+	} else if err := s.CreateSubscription("db0", "rp0", "s1", "ALL", []string{"udp://localhost:9001"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Remove one of the subscriptions.
+	if err := s.DropSubscription("db0", "rp0", "s0"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ensure the resulting subscriptions are correct.
+	if rpi, err := s.RetentionPolicy("db0", "rp0"); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(rpi.Subscriptions, []meta.SubscriptionInfo{
+		{Name: "s1", Mode: "ALL", Destinations: []string{"udp://localhost:9001"}},
+	}) {
+		t.Fatalf("unexpected subscriptions: %#v", rpi.Subscriptions)
+	}
+}
+
 // Ensure the store can create a user.
 func TestStore_CreateUser(t *testing.T) {
 	t.Parallel()
@@ -622,6 +742,65 @@ func TestStore_CreateUser(t *testing.T) {
 	}
 }
 
+// Ensure the store applies all the changes of a batch.
+func TestStore_ApplyBatch(t *testing.T) {
+	t.Parallel()
+	s := MustOpenStore()
+	defer s.Close()
+
+	b := s.NewBatch()
+	b.CreateDatabase("tenant0")
+	b.CreateRetentionPolicy("tenant0", &meta.RetentionPolicyInfo{Name: "rp0", ReplicaN: 1, Duration: 24 * time.Hour})
+	b.SetDefaultRetentionPolicy("tenant0", "rp0")
+	b.CreateUser("susy", "pass", false)
+	b.SetPrivilege("susy", "tenant0", influxql.AllPrivileges)
+	if err := s.ApplyBatch(b); err != nil {
+		t.Fatal(err)
+	}
+
+	if di, err := s.Database("tenant0"); err != nil {
+		t.Fatal(err)
+	} else if di == nil || di.DefaultRetentionPolicy != "rp0" || di.RetentionPolicy("rp0") == nil {
+		t.Fatalf("unexpected database: %#v", di)
+	}
+	if p, err := s.UserPrivilege("susy", "tenant0"); err != nil {
+		t.Fatal(err)
+	} else if *p != influxql.AllPrivileges {
+		t.Fatalf("unexpected privilege: %s", p)
+	}
+}
+
+// Ensure the store applies none of the changes of a batch if one fails.
+func TestStore_ApplyBatch_Rollback(t *testing.T) {
+	t.Parallel()
+	s := MustOpenStore()
+	defer s.Close()
+
+	if _, err := s.CreateUser("susy", "pass", false); err != nil {
+		t.Fatal(err)
+	}
+
+	// Creating an existing user fails the batch.
+	b := s.NewBatch()
+	b.CreateDatabase("tenant0")
+	b.CreateUser("bob", "pass", false)
+	b.CreateUser("susy", "pass", false)
+	if err := s.ApplyBatch(b); err != meta.ErrUserExists {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if di, err := s.Database("tenant0"); err != nil {
+		t.Fatal(err)
+	} else if di != nil {
+		t.Fatalf("unexpected database: %#v", di)
+	}
+	if ui, err := s.User("bob"); err != nil {
+		t.Fatal(err)
+	} else if ui != nil {
+		t.Fatalf("unexpected user: %#v", ui)
+	}
+}
+
 // Ensure the store can remove a user.
 func TestStore_DropUser(t *testing.T) {
 	t.Parallel()
@@ -734,6 +913,90 @@ func TestStore_Authentication(t *testing.T) {
 	}
 }
 
+// Ensure a user is locked out after too many failed logins.
+func TestStore_Authentication_Lockout(t *testing.T) {
+	t.Parallel()
+
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	s := MustOpenStore()
+	defer s.Close()
+	s.MaxFailedLogins = 2
+	s.LockoutDuration = 100 * time.Millisecond
+	s.SetHashPasswordFn(func(password string) ([]byte, error) {
+		return bcrypt.GenerateFromPassword([]byte(password), 4)
+	})
+
+	if _, err := s.CreateUser("susy", "pass", true); err != nil {
+		t.Fatal(err)
+	}
+
+	// A successful login resets the count of failures.
+	if _, err := s.Authenticate("susy", "bad"); err != meta.ErrAuthenticate {
+		t.Fatalf("unexpected error: %v", err)
+	} else if _, err := s.Authenticate("susy", "pass"); err != nil {
+		t.Fatal(err)
+	} else if _, err := s.Authenticate("susy", "bad"); err != meta.ErrAuthenticate {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The second consecutive failure locks the user out, even with the right password.
+	if _, err := s.Authenticate("susy", "bad"); err != meta.ErrAuthenticate {
+		t.Fatalf("unexpected error: %v", err)
+	} else if _, err := s.Authenticate("susy", "pass"); err != meta.ErrUserLocked {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The lockout expires.
+	time.Sleep(150 * time.Millisecond)
+	if _, err := s.Authenticate("susy", "pass"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Ensure passwords hashed with a lower cost are rehashed on login.
+func TestStore_Authentication_Rehash(t *testing.T) {
+	t.Parallel()
+
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	s := MustOpenStore()
+	defer s.Close()
+
+	// Create the user with a legacy hash.
+	s.SetHashPasswordFn(func(password string) ([]byte, error) {
+		return bcrypt.GenerateFromPassword([]byte(password), 4)
+	})
+	if _, err := s.CreateUser("susy", "pass", true); err != nil {
+		t.Fatal(err)
+	}
+
+	s.BcryptCost = 5
+	s.SetHashPasswordFn(func(password string) ([]byte, error) {
+		return bcrypt.GenerateFromPassword([]byte(password), s.BcryptCost)
+	})
+	if _, err := s.Authenticate("susy", "pass"); err != nil {
+		t.Fatal(err)
+	}
+
+	if u, err := s.User("susy"); err != nil {
+		t.Fatal(err)
+	} else if cost, err := bcrypt.Cost([]byte(u.Hash)); err != nil {
+		t.Fatal(err)
+	} else if cost != 5 {
+		t.Fatalf("unexpected cost: %d", cost)
+	}
+
+	// The new hash still authenticates.
+	if _, err := s.Authenticate("susy", "pass"); err != nil {
+		t.Fatal(err)
+	}
+}
+
 // Ensure the store can return the count of users in it.
 func TestStore_UserCount(t *testing.T) {
 	t.Parallel()
@@ -805,6 +1068,54 @@ func TestStore_Snapshot_And_Restore(t *testing.T) {
 	}
 }
 
+// Ensure the store periodically snapshots its data and can restore from it.
+func TestStore_Snapshot_Periodic(t *testing.T) {
+	t.Parallel()
+
+	c := NewConfig(MustTempFile())
+	c.SnapshotInterval = toml.Duration(10 * time.Millisecond)
+	c.SnapshotThreshold = 1
+	c.TrailingLogs = 1
+	s := NewStore(c)
+	s.LeaveFiles = true
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	<-s.Ready()
+	addr := s.RemoteAddr.String()
+
+	for n := 0; n < 5; n++ {
+		if _, err := s.CreateDatabase(fmt.Sprintf("db%d", n)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Wait for a snapshot to be written.
+	timeout := time.Now().Add(5 * time.Second)
+	for {
+		if fis, _ := ioutil.ReadDir(filepath.Join(s.Path(), "snapshots")); len(fis) > 0 {
+			break
+		} else if time.Now().After(timeout) {
+			t.Fatal("timed out waiting for snapshot")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	s.Close()
+
+	// Allow the kernel to free up the port so we can re-use it again
+	time.Sleep(100 * time.Millisecond)
+
+	s = MustOpenStoreWithPath(addr, s.Path())
+	defer s.Close()
+	for n := 0; n < 5; n++ {
+		if dbi, err := s.Database(fmt.Sprintf("db%d", n)); err != nil {
+			t.Fatal(err)
+		} else if dbi == nil {
+			t.Fatalf("database not found: db%d", n)
+		}
+	}
+}
+
 // Ensure a multi-node cluster can start, join the cluster, and replicate commands.
 func TestCluster_Open(t *testing.T) {
 	c := MustOpenCluster(3)
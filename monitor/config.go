@@ -14,22 +14,39 @@ const (
 	// DefaultStoreDatabase is the name of the database where gathered information is written
 	DefaultStoreDatabase = "_internal"
 
+	// DefaultStoreRetentionPolicy is the name of the retention policy gathered
+	// information is written to.
+	DefaultStoreRetentionPolicy = "monitor"
+
+	// DefaultStoreRetentionDuration is the period gathered information is kept.
+	DefaultStoreRetentionDuration = 7 * 24 * time.Hour
+
+	// DefaultStoreReplicationFactor is the replication factor of the retention
+	// policy gathered information is written to.
+	DefaultStoreReplicationFactor = 1
+
 	// DefaultStoreInterval is the period between storing gathered information.
 	DefaultStoreInterval = 10 * time.Second
 )
 
 // Config represents the configuration for the monitor service.
 type Config struct {
-	StoreEnabled  bool          `toml:"store-enabled"`
-	StoreDatabase string        `toml:"store-database"`
-	StoreInterval toml.Duration `toml:"store-interval"`
+	StoreEnabled           bool          `toml:"store-enabled"`
+	StoreDatabase          string        `toml:"store-database"`
+	StoreRetentionPolicy   string        `toml:"store-retention-policy"`
+	StoreRetentionDuration toml.Duration `toml:"store-retention-duration"`
+	StoreReplicationFactor int           `toml:"store-replication-factor"`
+	StoreInterval          toml.Duration `toml:"store-interval"`
 }
 
 // NewConfig returns an instance of Config with defaults.
 func NewConfig() Config {
 	return Config{
-		StoreEnabled:  true,
-		StoreDatabase: DefaultStoreDatabase,
-		StoreInterval: toml.Duration(DefaultStoreInterval),
+		StoreEnabled:           DefaultStoreEnabled,
+		StoreDatabase:          DefaultStoreDatabase,
+		StoreRetentionPolicy:   DefaultStoreRetentionPolicy,
+		StoreRetentionDuration: toml.Duration(DefaultStoreRetentionDuration),
+		StoreReplicationFactor: DefaultStoreReplicationFactor,
+		StoreInterval:          toml.Duration(DefaultStoreInterval),
 	}
 }
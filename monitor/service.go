@@ -18,11 +18,6 @@ import (
 
 const leaderWaitTimeout = 30 * time.Second
 
-const (
-	MonitorRetentionPolicy         = "monitor"
-	MonitorRetentionPolicyDuration = 7 * 24 * time.Hour
-)
-
 // DiagsClient is the interface modules implement if they register diags with monitor.
 type DiagsClient interface {
 	Diagnostics() (*Diagnostic, error)
@@ -102,12 +97,15 @@ type Monitor struct {
 // New returns a new instance of the monitor system.
 func New(c Config) *Monitor {
 	return &Monitor{
-		done:              make(chan struct{}),
-		diagRegistrations: make(map[string]DiagsClient),
-		storeEnabled:      c.StoreEnabled,
-		storeDatabase:     c.StoreDatabase,
-		storeInterval:     time.Duration(c.StoreInterval),
-		Logger:            log.New(os.Stderr, "[monitor] ", log.LstdFlags),
+		done:                   make(chan struct{}),
+		diagRegistrations:      make(map[string]DiagsClient),
+		storeEnabled:           c.StoreEnabled,
+		storeDatabase:          c.StoreDatabase,
+		storeRetentionPolicy:   c.StoreRetentionPolicy,
+		storeRetentionDuration: time.Duration(c.StoreRetentionDuration),
+		storeReplicationFactor: c.StoreReplicationFactor,
+		storeInterval:          time.Duration(c.StoreInterval),
+		Logger:                 log.New(os.Stderr, "[monitor] ", log.LstdFlags),
 	}
 }
 
@@ -180,8 +178,12 @@ func (m *Monitor) Statistics(tags map[string]string) ([]*statistic, error) {
 			statistic.Tags[k] = v
 		}
 
-		// Every other top-level expvar value is a map.
-		m := kv.Value.(*expvar.Map)
+		// Every other top-level expvar value registered with
+		// influxdb.NewStatistics is a map. Skip anything else.
+		m, ok := kv.Value.(*expvar.Map)
+		if !ok {
+			return
+		}
 
 		m.Do(func(subKV expvar.KeyValue) {
 			switch subKV.Key {
@@ -219,6 +221,14 @@ func (m *Monitor) Statistics(tags map[string]string) ([]*statistic, error) {
 						if err != nil {
 							return
 						}
+					case expvar.Func:
+						// Gauges computed on read.
+						switch x := v().(type) {
+						case int64, float64:
+							f = x
+						default:
+							return
+						}
 					default:
 						return
 					}
@@ -307,9 +317,9 @@ func (m *Monitor) storeStatistics() {
 		return
 	}
 
-	rpi := meta.NewRetentionPolicyInfo(MonitorRetentionPolicy)
-	rpi.Duration = MonitorRetentionPolicyDuration
-	rpi.ReplicaN = 1
+	rpi := meta.NewRetentionPolicyInfo(m.storeRetentionPolicy)
+	rpi.Duration = m.storeRetentionDuration
+	rpi.ReplicaN = m.storeReplicationFactor
 	if _, err := m.MetaStore.CreateRetentionPolicyIfNotExists(m.storeDatabase, rpi); err != nil {
 		m.Logger.Printf("failed to create retention policy '%s', terminating storage: %s",
 			rpi.Name, err.Error())
@@ -322,25 +332,31 @@ func (m *Monitor) storeStatistics() {
 		return
 	}
 
-	if err := m.MetaStore.DropRetentionPolicy(m.storeDatabase, "default"); err != nil && err != meta.ErrRetentionPolicyNotFound {
-		m.Logger.Printf("failed to delete retention policy 'default', terminating storage: %s", err.Error())
-		return
+	// The database is created with a "default" retention policy that keeps
+	// data forever. Drop it so statistics aren't kept longer than configured.
+	if m.storeRetentionPolicy != "default" {
+		if err := m.MetaStore.DropRetentionPolicy(m.storeDatabase, "default"); err != nil && err != meta.ErrRetentionPolicyNotFound {
+			m.Logger.Printf("failed to delete retention policy 'default', terminating storage: %s", err.Error())
+			return
+		}
 	}
 
 	tick := time.NewTicker(m.storeInterval)
 	defer tick.Stop()
 	for {
 		select {
-		case <-tick.C:
+		case now := <-tick.C:
 			stats, err := m.Statistics(clusterTags)
 			if err != nil {
 				m.Logger.Printf("failed to retrieve registered statistics: %s", err)
 				continue
 			}
 
+			// Every statistic in a snapshot shares a timestamp so the
+			// subsystems can be compared point for point.
 			points := make(tsdb.Points, 0, len(stats))
 			for _, s := range stats {
-				points = append(points, tsdb.NewPoint(s.Name, s.Tags, s.Values, time.Now()))
+				points = append(points, tsdb.NewPoint(s.Name, s.Tags, s.Values, now))
 			}
 
 			err = m.PointsWriter.WritePoints(&cluster.WritePointsRequest{
@@ -1,13 +1,18 @@
 package monitor
 
 import (
+	"expvar"
+	"io/ioutil"
+	"log"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/influxdb/influxdb"
+	"github.com/influxdb/influxdb/cluster"
 	"github.com/influxdb/influxdb/influxql"
 	"github.com/influxdb/influxdb/meta"
+	"github.com/influxdb/influxdb/toml"
 )
 
 // Test that a registered stats client results in the correct SHOW STATS output.
@@ -37,7 +42,94 @@ func Test_RegisterStats(t *testing.T) {
 	}
 }
 
-type mockMetastore struct{}
+// Test that gauges computed on read are included in SHOW STATS.
+func Test_RegisterStats_Func(t *testing.T) {
+	monitor := openMonitor(t)
+	executor := &StatementExecutor{Monitor: monitor}
+
+	statMap := influxdb.NewStatistics("gauge", "gauge", nil)
+	statMap.Set("n", expvar.Func(func() interface{} { return int64(5) }))
+	statMap.Set("ignored", expvar.Func(func() interface{} { return "foo" }))
+	json := executeShowStatsJSON(t, executor)
+	if !strings.Contains(json, `"name":"gauge","columns":["n"],"values":[[5]]`) {
+		t.Fatalf("SHOW STATS response incorrect, got: %s\n", json)
+	}
+}
+
+// Test that SHOW STATS FOR only returns the statistics of a database.
+func Test_ShowStatsForDatabase(t *testing.T) {
+	monitor := openMonitor(t)
+	executor := &StatementExecutor{Monitor: monitor}
+
+	influxdb.DatabaseStatistics("db0").Add("query_req", 1)
+	influxdb.DatabaseStatistics("db1").Add("query_req", 2)
+
+	r := executor.ExecuteStatement(&influxql.ShowStatsStatement{Database: "db0"})
+	if r.Err != nil {
+		t.Fatal(r.Err)
+	} else if len(r.Series) != 1 {
+		t.Fatalf("unexpected series count: %d", len(r.Series))
+	} else if row := r.Series[0]; row.Name != "database" || row.Tags["database"] != "db0" {
+		t.Fatalf("unexpected row: %s %v", row.Name, row.Tags)
+	}
+}
+
+// Test that statistics are written to the configured database and retention policy.
+func Test_StoreStatistics(t *testing.T) {
+	c := NewConfig()
+	c.StoreDatabase = "db0"
+	c.StoreRetentionPolicy = "rp0"
+	c.StoreRetentionDuration = toml.Duration(time.Hour)
+	c.StoreInterval = toml.Duration(10 * time.Millisecond)
+
+	metaStore := &mockMetastore{}
+	pointsWriter := &mockPointsWriter{ch: make(chan *cluster.WritePointsRequest, 1)}
+	monitor := New(c)
+	monitor.MetaStore = metaStore
+	monitor.PointsWriter = pointsWriter
+	monitor.SetLogger(log.New(ioutil.Discard, "", 0))
+	if err := monitor.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer monitor.Close()
+
+	influxdb.NewStatistics("store_test", "store_test", map[string]string{"proto": "tcp"}).Add("n", 1)
+
+	var req *cluster.WritePointsRequest
+	select {
+	case req = <-pointsWriter.ch:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for statistics")
+	}
+
+	if rpi := metaStore.rpi; rpi == nil || rpi.Name != "rp0" || rpi.Duration != time.Hour || rpi.ReplicaN != 1 {
+		t.Fatalf("unexpected retention policy: %+v", rpi)
+	} else if req.Database != "db0" || req.RetentionPolicy != "rp0" {
+		t.Fatalf("unexpected write destination: %s.%s", req.Database, req.RetentionPolicy)
+	}
+
+	var found bool
+	for _, p := range req.Points {
+		if !p.Time().Equal(req.Points[0].Time()) {
+			t.Fatalf("unexpected point time: %s", p.Time())
+		}
+		if p.Name() == "store_test" {
+			found = true
+			if p.Tags()["proto"] != "tcp" || p.Tags()["nodeID"] != "2" {
+				t.Fatalf("unexpected tags: %v", p.Tags())
+			} else if p.Fields()["n"] != int64(1) {
+				t.Fatalf("unexpected fields: %v", p.Fields())
+			}
+		}
+	}
+	if !found {
+		t.Fatal("statistic not written")
+	}
+}
+
+type mockMetastore struct {
+	rpi *meta.RetentionPolicyInfo
+}
 
 func (m *mockMetastore) ClusterID() (uint64, error)                            { return 1, nil }
 func (m *mockMetastore) NodeID() uint64                                        { return 2 }
@@ -48,7 +140,20 @@ func (m *mockMetastore) CreateDatabaseIfNotExists(name string) (*meta.DatabaseIn
 	return nil, nil
 }
 func (m *mockMetastore) CreateRetentionPolicyIfNotExists(database string, rpi *meta.RetentionPolicyInfo) (*meta.RetentionPolicyInfo, error) {
-	return nil, nil
+	m.rpi = rpi
+	return rpi, nil
+}
+
+type mockPointsWriter struct {
+	ch chan *cluster.WritePointsRequest
+}
+
+func (w *mockPointsWriter) WritePoints(p *cluster.WritePointsRequest) error {
+	select {
+	case w.ch <- p:
+	default:
+	}
+	return nil
 }
 
 func openMonitor(t *testing.T) *Monitor {
@@ -18,7 +18,7 @@ type StatementExecutor struct {
 func (s *StatementExecutor) ExecuteStatement(stmt influxql.Statement) *influxql.Result {
 	switch stmt := stmt.(type) {
 	case *influxql.ShowStatsStatement:
-		return s.executeShowStatistics()
+		return s.executeShowStatistics(stmt.Database)
 	case *influxql.ShowDiagnosticsStatement:
 		return s.executeShowDiagnostics()
 	default:
@@ -26,14 +26,19 @@ func (s *StatementExecutor) ExecuteStatement(stmt influxql.Statement) *influxql.
 	}
 }
 
-func (s *StatementExecutor) executeShowStatistics() *influxql.Result {
+// executeShowStatistics returns the statistics of all subsystems. If database
+// is not blank then only the statistics tagged with that database are returned.
+func (s *StatementExecutor) executeShowStatistics(database string) *influxql.Result {
 	stats, err := s.Monitor.Statistics(nil)
 	if err != nil {
 		return &influxql.Result{Err: err}
 	}
-	rows := make([]*influxql.Row, len(stats))
+	rows := make([]*influxql.Row, 0, len(stats))
 
-	for n, stat := range stats {
+	for _, stat := range stats {
+		if database != "" && stat.Tags["database"] != database {
+			continue
+		}
 		row := &influxql.Row{Name: stat.Name, Tags: stat.Tags}
 
 		values := make([]interface{}, 0, len(stat.Values))
@@ -42,7 +47,7 @@ func (s *StatementExecutor) executeShowStatistics() *influxql.Result {
 			values = append(values, stat.Values[k])
 		}
 		row.Values = [][]interface{}{values}
-		rows[n] = row
+		rows = append(rows, row)
 	}
 	return &influxql.Result{Series: rows}
 }
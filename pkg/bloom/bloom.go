@@ -0,0 +1,113 @@
+// Package bloom implements bloom filters over byte slice keys.
+//
+// A filter answers whether a key may have been inserted. It never returns a
+// false negative and returns false positives at about the rate it was created
+// with. Filters grow as keys are inserted: once a filter holds as many keys as
+// it was sized for, a layer twice as large with half the false positive rate
+// is added, so the overall rate stays bounded however many keys are inserted.
+package bloom
+
+import (
+	"hash/fnv"
+	"math"
+)
+
+// Filter represents a growable bloom filter. A nil filter contains no keys.
+type Filter struct {
+	layers []*layer
+	p      float64 // false positive rate of the last layer
+	n      int     // number of inserted keys
+}
+
+// NewFilter returns a filter sized for n keys with a false positive rate of p.
+func NewFilter(n int, p float64) *Filter {
+	if n < 1 {
+		n = 1
+	}
+	p = p / 2 // the layers' rates sum up to twice the first layer's.
+	return &Filter{layers: []*layer{newLayer(n, p)}, p: p}
+}
+
+// Len returns the number of keys inserted into f.
+func (f *Filter) Len() int {
+	if f == nil {
+		return 0
+	}
+	return f.n
+}
+
+// Insert adds key to f.
+func (f *Filter) Insert(key []byte) {
+	l := f.layers[len(f.layers)-1]
+	if l.n >= l.cap {
+		f.p /= 2
+		l = newLayer(l.cap*2, f.p)
+		f.layers = append(f.layers, l)
+	}
+
+	h1, h2 := hash(key)
+	for i := uint64(0); i < l.k; i++ {
+		l.set((h1 + i*h2) % l.m)
+	}
+	l.n++
+	f.n++
+}
+
+// Contains returns true if key may have been inserted into f.
+func (f *Filter) Contains(key []byte) bool {
+	if f == nil {
+		return false
+	}
+
+	h1, h2 := hash(key)
+	for _, l := range f.layers {
+		if l.contains(h1, h2) {
+			return true
+		}
+	}
+	return false
+}
+
+// layer is a fixed size bloom filter.
+type layer struct {
+	bits []uint64
+	m    uint64 // number of bits
+	k    uint64 // number of hash functions
+	n    int    // number of inserted keys
+	cap  int    // keys the layer is sized for
+}
+
+// newLayer returns a layer sized for n keys with a false positive rate of p.
+func newLayer(n int, p float64) *layer {
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := uint64(math.Ceil(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return &layer{bits: make([]uint64, (m+63)/64), m: m, k: k, cap: n}
+}
+
+func (l *layer) set(i uint64) { l.bits[i/64] |= 1 << (i % 64) }
+
+func (l *layer) contains(h1, h2 uint64) bool {
+	for i := uint64(0); i < l.k; i++ {
+		j := (h1 + i*h2) % l.m
+		if l.bits[j/64]&(1<<(j%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// hash returns the two hashes of a key that the positions of its bits are
+// derived from, as h1 + i*h2 for the i-th hash function.
+func hash(key []byte) (h1, h2 uint64) {
+	h := fnv.New64a()
+	h.Write(key)
+	h1 = h.Sum64()
+	h2 = h1>>33 | h1<<31 | 1 // odd, so the positions don't repeat early.
+	return h1, h2
+}
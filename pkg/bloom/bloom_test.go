@@ -0,0 +1,63 @@
+package bloom_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/influxdb/influxdb/pkg/bloom"
+)
+
+// Ensure inserted keys are always found and others are rarely found.
+func TestFilter(t *testing.T) {
+	f := bloom.NewFilter(1000, 0.01)
+	for i := 0; i < 1000; i++ {
+		f.Insert([]byte("cpu,host=server" + strconv.Itoa(i)))
+	}
+
+	for i := 0; i < 1000; i++ {
+		if !f.Contains([]byte("cpu,host=server" + strconv.Itoa(i))) {
+			t.Fatalf("expected key %d to be found", i)
+		}
+	}
+	if n := falsePositives(f); n > 200 {
+		t.Fatalf("unexpected false positives: %d", n)
+	} else if f.Len() != 1000 {
+		t.Fatalf("unexpected len: %d", f.Len())
+	}
+}
+
+// Ensure filters keep their false positive rate as they grow past their size.
+func TestFilter_Grow(t *testing.T) {
+	f := bloom.NewFilter(10, 0.01)
+	for i := 0; i < 5000; i++ {
+		f.Insert([]byte("cpu,host=server" + strconv.Itoa(i)))
+	}
+
+	for i := 0; i < 5000; i++ {
+		if !f.Contains([]byte("cpu,host=server" + strconv.Itoa(i))) {
+			t.Fatalf("expected key %d to be found", i)
+		}
+	}
+	if n := falsePositives(f); n > 200 {
+		t.Fatalf("unexpected false positives: %d", n)
+	}
+}
+
+// Ensure a nil filter contains no keys.
+func TestFilter_Nil(t *testing.T) {
+	var f *bloom.Filter
+	if f.Contains([]byte("cpu")) || f.Len() != 0 {
+		t.Fatal("expected empty filter")
+	}
+}
+
+// falsePositives returns how many of 10000 keys that weren't inserted are
+// found in f.
+func falsePositives(f *bloom.Filter) (n int) {
+	for i := 0; i < 10000; i++ {
+		if f.Contains([]byte("mem,host=server" + strconv.Itoa(i))) {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,45 @@
+package hll_test
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/influxdb/influxdb/pkg/hll"
+)
+
+// Ensure sketches estimate the number of distinct keys within their error.
+func TestSketch_Count(t *testing.T) {
+	for _, n := range []int{0, 1, 10, 100, 1000, 10000, 100000} {
+		s := hll.NewSketch(12)
+		for i := 0; i < n; i++ {
+			s.Add([]byte("cpu,host=server" + strconv.Itoa(i)))
+		}
+
+		// Allow for four times the standard error of 1.6%.
+		if got := float64(s.Count()); math.Abs(got-float64(n)) > 0.065*float64(n)+0.5 {
+			t.Errorf("%d. unexpected count: %v", n, got)
+		}
+	}
+}
+
+// Ensure keys added more than once are counted once.
+func TestSketch_Count_Duplicates(t *testing.T) {
+	s := hll.NewSketch(10)
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 100; j++ {
+			s.Add([]byte("cpu,host=server" + strconv.Itoa(j)))
+		}
+	}
+	if n := s.Count(); n < 90 || n > 110 {
+		t.Fatalf("unexpected count: %d", n)
+	}
+}
+
+// Ensure a nil sketch holds no keys.
+func TestSketch_Count_Nil(t *testing.T) {
+	var s *hll.Sketch
+	if n := s.Count(); n != 0 {
+		t.Fatalf("unexpected count: %d", n)
+	}
+}
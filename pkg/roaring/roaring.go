@@ -0,0 +1,455 @@
+// Package roaring implements compressed bitmaps of uint64 values.
+//
+// A bitmap splits its values into containers by their upper 48 bits. Each
+// container holds the lower 16 bits of up to 65536 values, either as a
+// sorted array while it holds at most 4096 values, or as a bitmap of 65536
+// bits once it holds more. Dense sets of values, such as the series IDs of
+// a measurement, are stored in a fraction of the space of a slice and set
+// operations work on whole containers at a time.
+package roaring
+
+import "sort"
+
+const (
+	// arrayMaxSize is the most values an array container holds before it
+	// is converted to a bitmap container. Above it a bitmap is smaller.
+	arrayMaxSize = 4096
+
+	// bitmapN is the number of words in a bitmap container.
+	bitmapN = (1 << 16) / 64
+)
+
+// Bitmap represents a set of uint64 values. The zero value is an empty set
+// and a nil bitmap can be read as an empty set.
+type Bitmap struct {
+	keys       []uint64 // sorted upper 48 bits of the values of each container
+	containers []*container
+	n          int // number of values
+}
+
+// NewBitmap returns a bitmap holding a.
+func NewBitmap(a ...uint64) *Bitmap {
+	b := &Bitmap{}
+	for _, v := range a {
+		b.Add(v)
+	}
+	return b
+}
+
+// Count returns the number of values in b.
+func (b *Bitmap) Count() int {
+	if b == nil {
+		return 0
+	}
+	return b.n
+}
+
+// Contains returns true if v is in b.
+func (b *Bitmap) Contains(v uint64) bool {
+	if b == nil {
+		return false
+	}
+	i, ok := b.search(v >> 16)
+	return ok && b.containers[i].contains(uint16(v))
+}
+
+// Add adds v to b. Returns true if v wasn't already in b.
+func (b *Bitmap) Add(v uint64) bool {
+	key := v >> 16
+	i, ok := b.search(key)
+	if !ok {
+		b.keys = append(b.keys, 0)
+		copy(b.keys[i+1:], b.keys[i:])
+		b.keys[i] = key
+
+		b.containers = append(b.containers, nil)
+		copy(b.containers[i+1:], b.containers[i:])
+		b.containers[i] = &container{}
+	}
+
+	if !b.containers[i].add(uint16(v)) {
+		return false
+	}
+	b.n++
+	return true
+}
+
+// Remove removes v from b. Returns true if v was in b.
+func (b *Bitmap) Remove(v uint64) bool {
+	i, ok := b.search(v >> 16)
+	if !ok {
+		return false
+	}
+
+	c := b.containers[i]
+	if !c.remove(uint16(v)) {
+		return false
+	}
+	b.n--
+
+	// Drop the container once it's empty.
+	if c.n == 0 {
+		b.keys = append(b.keys[:i], b.keys[i+1:]...)
+		b.containers = append(b.containers[:i], b.containers[i+1:]...)
+	}
+	return true
+}
+
+// Clone returns a copy of b.
+func (b *Bitmap) Clone() *Bitmap {
+	other := &Bitmap{}
+	if b == nil {
+		return other
+	}
+	other.keys = make([]uint64, len(b.keys))
+	copy(other.keys, b.keys)
+	other.containers = make([]*container, len(b.containers))
+	for i, c := range b.containers {
+		other.containers[i] = c.clone()
+	}
+	other.n = b.n
+	return other
+}
+
+// Slice returns the values of b in ascending order.
+func (b *Bitmap) Slice() []uint64 {
+	a := make([]uint64, 0, b.Count())
+	b.ForEach(func(v uint64) { a = append(a, v) })
+	return a
+}
+
+// ForEach calls fn with each value of b in ascending order.
+func (b *Bitmap) ForEach(fn func(v uint64)) {
+	if b == nil {
+		return
+	}
+	for i, c := range b.containers {
+		high := b.keys[i] << 16
+		c.forEach(func(v uint16) { fn(high | uint64(v)) })
+	}
+}
+
+// Union returns a new bitmap with the values in either b or other.
+func (b *Bitmap) Union(other *Bitmap) *Bitmap {
+	if b.Count() == 0 {
+		return other.Clone()
+	} else if other.Count() == 0 {
+		return b.Clone()
+	}
+
+	result := &Bitmap{}
+	i, j := 0, 0
+	for i < len(b.keys) || j < len(other.keys) {
+		switch {
+		case j == len(other.keys) || (i < len(b.keys) && b.keys[i] < other.keys[j]):
+			result.append(b.keys[i], b.containers[i].clone())
+			i++
+		case i == len(b.keys) || other.keys[j] < b.keys[i]:
+			result.append(other.keys[j], other.containers[j].clone())
+			j++
+		default:
+			result.append(b.keys[i], union(b.containers[i], other.containers[j]))
+			i, j = i+1, j+1
+		}
+	}
+	return result
+}
+
+// Intersect returns a new bitmap with the values in both b and other.
+func (b *Bitmap) Intersect(other *Bitmap) *Bitmap {
+	result := &Bitmap{}
+	if b.Count() == 0 || other.Count() == 0 {
+		return result
+	}
+
+	i, j := 0, 0
+	for i < len(b.keys) && j < len(other.keys) {
+		switch {
+		case b.keys[i] < other.keys[j]:
+			i++
+		case other.keys[j] < b.keys[i]:
+			j++
+		default:
+			result.append(b.keys[i], intersect(b.containers[i], other.containers[j]))
+			i, j = i+1, j+1
+		}
+	}
+	return result
+}
+
+// Difference returns a new bitmap with the values in b that aren't in other.
+func (b *Bitmap) Difference(other *Bitmap) *Bitmap {
+	if b.Count() == 0 || other.Count() == 0 {
+		return b.Clone()
+	}
+
+	result := &Bitmap{}
+	i, j := 0, 0
+	for i < len(b.keys) {
+		switch {
+		case j == len(other.keys) || b.keys[i] < other.keys[j]:
+			result.append(b.keys[i], b.containers[i].clone())
+			i++
+		case other.keys[j] < b.keys[i]:
+			j++
+		default:
+			result.append(b.keys[i], difference(b.containers[i], other.containers[j]))
+			i, j = i+1, j+1
+		}
+	}
+	return result
+}
+
+// search returns the index of the container of key, or the index to insert
+// it at and false if it doesn't exist.
+func (b *Bitmap) search(key uint64) (int, bool) {
+	i := sort.Search(len(b.keys), func(i int) bool { return b.keys[i] >= key })
+	return i, i < len(b.keys) && b.keys[i] == key
+}
+
+// append adds c, the container of key, to the end of b. Empty containers
+// are ignored. Key must be greater than the keys already in b.
+func (b *Bitmap) append(key uint64, c *container) {
+	if c.n == 0 {
+		return
+	}
+	b.keys = append(b.keys, key)
+	b.containers = append(b.containers, c)
+	b.n += c.n
+}
+
+// container holds the lower 16 bits of the values of a bitmap that share
+// their upper bits, either as a sorted array or as a bitmap.
+type container struct {
+	n      int      // number of values
+	array  []uint16 // sorted values, if bitmap is nil
+	bitmap []uint64 // bitmapN words, if the container has more than arrayMaxSize values
+}
+
+func (c *container) contains(v uint16) bool {
+	if c.bitmap != nil {
+		return c.bitmap[v/64]&(1<<(v%64)) != 0
+	}
+	i := c.searchArray(v)
+	return i < len(c.array) && c.array[i] == v
+}
+
+func (c *container) add(v uint16) bool {
+	if c.bitmap != nil {
+		if c.bitmap[v/64]&(1<<(v%64)) != 0 {
+			return false
+		}
+		c.bitmap[v/64] |= 1 << (v % 64)
+		c.n++
+		return true
+	}
+
+	i := c.searchArray(v)
+	if i < len(c.array) && c.array[i] == v {
+		return false
+	}
+	c.array = append(c.array, 0)
+	copy(c.array[i+1:], c.array[i:])
+	c.array[i] = v
+	c.n++
+
+	if c.n > arrayMaxSize {
+		c.toBitmap()
+	}
+	return true
+}
+
+func (c *container) remove(v uint16) bool {
+	if c.bitmap != nil {
+		if c.bitmap[v/64]&(1<<(v%64)) == 0 {
+			return false
+		}
+		c.bitmap[v/64] &^= 1 << (v % 64)
+		c.n--
+
+		if c.n <= arrayMaxSize {
+			c.toArray()
+		}
+		return true
+	}
+
+	i := c.searchArray(v)
+	if i == len(c.array) || c.array[i] != v {
+		return false
+	}
+	c.array = append(c.array[:i], c.array[i+1:]...)
+	c.n--
+	return true
+}
+
+// searchArray returns the index of v in the array, or the index to insert it at.
+func (c *container) searchArray(v uint16) int {
+	return sort.Search(len(c.array), func(i int) bool { return c.array[i] >= v })
+}
+
+func (c *container) forEach(fn func(v uint16)) {
+	if c.bitmap == nil {
+		for _, v := range c.array {
+			fn(v)
+		}
+		return
+	}
+	for i, w := range c.bitmap {
+		for w != 0 {
+			t := w & -w
+			fn(uint16(i*64 + popcount(t-1)))
+			w ^= t
+		}
+	}
+}
+
+func (c *container) clone() *container {
+	other := &container{n: c.n}
+	if c.bitmap != nil {
+		other.bitmap = make([]uint64, bitmapN)
+		copy(other.bitmap, c.bitmap)
+	} else {
+		other.array = make([]uint16, len(c.array))
+		copy(other.array, c.array)
+	}
+	return other
+}
+
+// toBitmap converts an array container to a bitmap container.
+func (c *container) toBitmap() {
+	c.bitmap = make([]uint64, bitmapN)
+	for _, v := range c.array {
+		c.bitmap[v/64] |= 1 << (v % 64)
+	}
+	c.array = nil
+}
+
+// toArray converts a bitmap container to an array container.
+func (c *container) toArray() {
+	a := make([]uint16, 0, c.n)
+	c.forEach(func(v uint16) { a = append(a, v) })
+	c.array, c.bitmap = a, nil
+}
+
+// optimize converts c to the smaller of the container types for its number
+// of values.
+func (c *container) optimize() *container {
+	if c.bitmap != nil && c.n <= arrayMaxSize {
+		c.toArray()
+	} else if c.bitmap == nil && c.n > arrayMaxSize {
+		c.toBitmap()
+	}
+	return c
+}
+
+// union returns a new container with the values of either a or b.
+func union(a, b *container) *container {
+	if a.bitmap == nil && b.bitmap == nil {
+		c := &container{array: make([]uint16, 0, len(a.array)+len(b.array))}
+		i, j := 0, 0
+		for i < len(a.array) || j < len(b.array) {
+			switch {
+			case j == len(b.array) || (i < len(a.array) && a.array[i] < b.array[j]):
+				c.array = append(c.array, a.array[i])
+				i++
+			case i == len(a.array) || b.array[j] < a.array[i]:
+				c.array = append(c.array, b.array[j])
+				j++
+			default:
+				c.array = append(c.array, a.array[i])
+				i, j = i+1, j+1
+			}
+		}
+		c.n = len(c.array)
+		return c.optimize()
+	}
+
+	// The union is at least as large as a bitmap operand, so it's a bitmap.
+	if a.bitmap == nil {
+		a, b = b, a
+	}
+	c := a.clone()
+	if b.bitmap != nil {
+		for i, w := range b.bitmap {
+			c.bitmap[i] |= w
+		}
+		c.n = countBitmap(c.bitmap)
+		return c
+	}
+	for _, v := range b.array {
+		c.add(v)
+	}
+	return c
+}
+
+// intersect returns a new container with the values of both a and b.
+func intersect(a, b *container) *container {
+	if a.bitmap != nil && b.bitmap != nil {
+		c := &container{bitmap: make([]uint64, bitmapN)}
+		for i := range c.bitmap {
+			c.bitmap[i] = a.bitmap[i] & b.bitmap[i]
+		}
+		c.n = countBitmap(c.bitmap)
+		return c.optimize()
+	}
+
+	// The intersection is no larger than an array operand, so it's an array.
+	if a.bitmap != nil {
+		a, b = b, a
+	}
+	c := &container{}
+	for _, v := range a.array {
+		if b.contains(v) {
+			c.array = append(c.array, v)
+		}
+	}
+	c.n = len(c.array)
+	return c
+}
+
+// difference returns a new container with the values of a that aren't in b.
+func difference(a, b *container) *container {
+	if a.bitmap == nil {
+		c := &container{}
+		for _, v := range a.array {
+			if !b.contains(v) {
+				c.array = append(c.array, v)
+			}
+		}
+		c.n = len(c.array)
+		return c
+	}
+
+	c := a.clone()
+	if b.bitmap != nil {
+		for i, w := range b.bitmap {
+			c.bitmap[i] &^= w
+		}
+		c.n = countBitmap(c.bitmap)
+		return c.optimize()
+	}
+	for _, v := range b.array {
+		if c.bitmap[v/64]&(1<<(v%64)) != 0 {
+			c.bitmap[v/64] &^= 1 << (v % 64)
+			c.n--
+		}
+	}
+	return c.optimize()
+}
+
+// countBitmap returns the number of bits set in a bitmap container.
+func countBitmap(bitmap []uint64) int {
+	n := 0
+	for _, w := range bitmap {
+		n += popcount(w)
+	}
+	return n
+}
+
+// popcount returns the number of bits set in w.
+func popcount(w uint64) int {
+	w -= (w >> 1) & 0x5555555555555555
+	w = (w & 0x3333333333333333) + ((w >> 2) & 0x3333333333333333)
+	w = (w + (w >> 4)) & 0x0f0f0f0f0f0f0f0f
+	return int((w * 0x0101010101010101) >> 56)
+}
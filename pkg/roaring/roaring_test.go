@@ -0,0 +1,128 @@
+package roaring_test
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/influxdb/influxdb/pkg/roaring"
+)
+
+// Ensure values can be added to, found in and removed from a bitmap.
+func TestBitmap_AddRemove(t *testing.T) {
+	b := roaring.NewBitmap(3, 1, 1<<40, 2)
+	if !b.Contains(1<<40) || b.Contains(4) {
+		t.Fatal("unexpected contains")
+	} else if b.Add(3) {
+		t.Fatal("expected existing value not to be added")
+	} else if b.Count() != 4 {
+		t.Fatalf("unexpected count: %d", b.Count())
+	} else if exp := []uint64{1, 2, 3, 1 << 40}; !reflect.DeepEqual(b.Slice(), exp) {
+		t.Fatalf("unexpected values: %v", b.Slice())
+	}
+
+	if !b.Remove(1<<40) || b.Remove(1<<40) {
+		t.Fatal("unexpected remove")
+	} else if exp := []uint64{1, 2, 3}; !reflect.DeepEqual(b.Slice(), exp) {
+		t.Fatalf("unexpected values: %v", b.Slice())
+	}
+}
+
+// Ensure a nil bitmap reads as an empty set.
+func TestBitmap_Nil(t *testing.T) {
+	var b *roaring.Bitmap
+	if b.Count() != 0 || b.Contains(1) || len(b.Slice()) != 0 {
+		t.Fatal("expected empty bitmap")
+	} else if got := b.Union(roaring.NewBitmap(1)).Slice(); !reflect.DeepEqual(got, []uint64{1}) {
+		t.Fatalf("unexpected union: %v", got)
+	} else if got := roaring.NewBitmap(1).Difference(b).Slice(); !reflect.DeepEqual(got, []uint64{1}) {
+		t.Fatalf("unexpected difference: %v", got)
+	}
+}
+
+// Ensure set operations match those of maps, for both sparse and dense
+// containers.
+func TestBitmap_SetOperations(t *testing.T) {
+	rand := rand.New(rand.NewSource(0))
+	for i, n := range []int{10, 5000, 60000} {
+		a, am := randomBitmap(rand, n)
+		b, bm := randomBitmap(rand, n)
+
+		union := make(map[uint64]bool)
+		intersection := make(map[uint64]bool)
+		difference := make(map[uint64]bool)
+		for v := range am {
+			union[v] = true
+			if bm[v] {
+				intersection[v] = true
+			} else {
+				difference[v] = true
+			}
+		}
+		for v := range bm {
+			union[v] = true
+		}
+
+		if got := a.Union(b); !equal(got, union) {
+			t.Fatalf("%d: unexpected union: %d values", i, got.Count())
+		} else if got := a.Intersect(b); !equal(got, intersection) {
+			t.Fatalf("%d: unexpected intersection: %d values", i, got.Count())
+		} else if got := a.Difference(b); !equal(got, difference) {
+			t.Fatalf("%d: unexpected difference: %d values", i, got.Count())
+		} else if !equal(a, am) {
+			t.Fatalf("%d: operand modified", i)
+		}
+
+		// Removing values converts dense containers back to arrays.
+		for v := range am {
+			a.Remove(v)
+		}
+		if a.Count() != 0 {
+			t.Fatalf("%d: unexpected count: %d", i, a.Count())
+		}
+	}
+}
+
+// randomBitmap returns a bitmap, and a map of its values, of n values in
+// [0, 2^17) so they span two containers.
+func randomBitmap(rand *rand.Rand, n int) (*roaring.Bitmap, map[uint64]bool) {
+	b, m := roaring.NewBitmap(), make(map[uint64]bool)
+	for i := 0; i < n; i++ {
+		v := uint64(rand.Intn(1 << 17))
+		b.Add(v)
+		m[v] = true
+	}
+	return b, m
+}
+
+// equal returns true if b holds the values of m.
+func equal(b *roaring.Bitmap, m map[uint64]bool) bool {
+	exp := make([]uint64, 0, len(m))
+	for v := range m {
+		exp = append(exp, v)
+	}
+	sort.Sort(uint64Slice(exp))
+	return b.Count() == len(m) && reflect.DeepEqual(b.Slice(), exp)
+}
+
+type uint64Slice []uint64
+
+func (a uint64Slice) Len() int           { return len(a) }
+func (a uint64Slice) Less(i, j int) bool { return a[i] < a[j] }
+func (a uint64Slice) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
+func BenchmarkBitmap_Intersect_1M(b *testing.B) {
+	x, y := roaring.NewBitmap(), roaring.NewBitmap()
+	for i := uint64(0); i < 1000000; i++ {
+		x.Add(i)
+		if i%3 == 0 {
+			y.Add(i)
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		x.Intersect(y)
+	}
+}
@@ -0,0 +1,82 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// ModeReject rejects a write if any of its points don't conform to the
+	// schema of their measurement.
+	ModeReject = "reject"
+
+	// ModeDrop drops the points that don't conform to the schema of their
+	// measurement and writes the others.
+	ModeDrop = "drop"
+
+	// DefaultMode is the default handling of nonconforming points.
+	DefaultMode = ModeReject
+)
+
+// Config represents the configuration of the schema registry.
+type Config struct {
+	Enabled bool   `toml:"enabled"`
+	Mode    string `toml:"mode"`
+
+	// Strict rejects measurements without a schema in the databases that
+	// have a schema for at least one measurement.
+	Strict bool `toml:"strict"`
+
+	Measurements []Measurement `toml:"measurement"`
+}
+
+// Measurement represents the schema of a measurement in a database.
+type Measurement struct {
+	Database string `toml:"database"`
+	Name     string `toml:"name"`
+
+	// Tags lists the allowed tag keys. Points don't need to have every tag.
+	Tags []string `toml:"tags"`
+
+	// Fields maps the allowed field keys to their type: "float", "integer",
+	// "unsigned", "string" or "boolean".
+	Fields map[string]string `toml:"fields"`
+}
+
+// NewConfig returns an instance of Config with defaults.
+func NewConfig() Config {
+	return Config{
+		Mode: DefaultMode,
+	}
+}
+
+// Validate returns an error if the config is invalid.
+func (c Config) Validate() error {
+	switch c.Mode {
+	case "", ModeReject, ModeDrop:
+	default:
+		return fmt.Errorf("unknown mode: %q", c.Mode)
+	}
+
+	seen := make(map[string]bool)
+	for _, m := range c.Measurements {
+		if m.Database == "" {
+			return errors.New("measurement database required")
+		} else if m.Name == "" {
+			return fmt.Errorf("measurement name required in database %q", m.Database)
+		}
+
+		key := m.Database + "." + m.Name
+		if seen[key] {
+			return fmt.Errorf("duplicate measurement %q in database %q", m.Name, m.Database)
+		}
+		seen[key] = true
+
+		for name, typ := range m.Fields {
+			if _, err := parseFieldType(typ); err != nil {
+				return fmt.Errorf("measurement %q: field %q: %s", m.Name, name, err)
+			}
+		}
+	}
+	return nil
+}
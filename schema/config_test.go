@@ -0,0 +1,59 @@
+package schema_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/influxdb/influxdb/schema"
+)
+
+func TestConfig_Parse(t *testing.T) {
+	// Parse configuration.
+	var c schema.Config
+	if _, err := toml.Decode(`
+enabled = true
+mode = "drop"
+strict = true
+
+[[measurement]]
+database = "db0"
+name = "cpu"
+tags = ["host", "region"]
+[measurement.fields]
+value = "float"
+`, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate configuration.
+	if !c.Enabled {
+		t.Fatalf("unexpected enabled: %v", c.Enabled)
+	} else if c.Mode != schema.ModeDrop {
+		t.Fatalf("unexpected mode: %s", c.Mode)
+	} else if !c.Strict {
+		t.Fatalf("unexpected strict: %v", c.Strict)
+	} else if exp := []schema.Measurement{{Database: "db0", Name: "cpu", Tags: []string{"host", "region"}, Fields: map[string]string{"value": "float"}}}; !reflect.DeepEqual(c.Measurements, exp) {
+		t.Fatalf("unexpected measurements: %+v", c.Measurements)
+	} else if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Ensure invalid configs return an error.
+func TestConfig_Validate(t *testing.T) {
+	for i, tt := range []struct {
+		c   schema.Config
+		err string
+	}{
+		{c: schema.Config{Mode: "ignore"}, err: `unknown mode: "ignore"`},
+		{c: schema.Config{Measurements: []schema.Measurement{{Name: "cpu"}}}, err: `measurement database required`},
+		{c: schema.Config{Measurements: []schema.Measurement{{Database: "db0"}}}, err: `measurement name required in database "db0"`},
+		{c: schema.Config{Measurements: []schema.Measurement{{Database: "db0", Name: "cpu"}, {Database: "db0", Name: "cpu"}}}, err: `duplicate measurement "cpu" in database "db0"`},
+		{c: schema.Config{Measurements: []schema.Measurement{{Database: "db0", Name: "cpu", Fields: map[string]string{"value": "double"}}}}, err: `measurement "cpu": field "value": unknown field type: "double"`},
+	} {
+		if err := tt.c.Validate(); err == nil || err.Error() != tt.err {
+			t.Errorf("%d. unexpected error: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// Package schema enforces per-database measurement schemas at write time.
+//
+// A schema lists the tag keys and the field keys and types allowed for a
+// measurement. Points that don't conform are either rejected with the rest of
+// their write or dropped, depending on the mode of the registry.
+package schema
+
+import (
+	"expvar"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/influxdb/influxdb"
+	"github.com/influxdb/influxdb/influxql"
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// Statistics maintained by the registry.
+const (
+	statPointsRejected = "points_rejected" // Points in writes rejected for a nonconforming point
+	statPointsDropped  = "points_dropped"  // Nonconforming points dropped from writes
+)
+
+// statDatabaseSchemaViolations is the number of nonconforming points written
+// to a database. It is tracked in the statistics of each database.
+const statDatabaseSchemaViolations = "schema_violations"
+
+// Registry holds the measurement schemas of each database.
+type Registry struct {
+	mu        sync.RWMutex
+	mode      string
+	strict    bool
+	databases map[string]map[string]*measurement
+
+	statMap *expvar.Map
+}
+
+// measurement is the parsed schema of a measurement.
+type measurement struct {
+	tags   map[string]struct{}
+	fields map[string]influxql.DataType
+}
+
+// NewRegistry returns a new instance of Registry for a config.
+func NewRegistry(c Config) (*Registry, error) {
+	r := &Registry{
+		statMap: influxdb.NewStatistics("schema", "schema", nil),
+	}
+	if err := r.Configure(c); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Configure replaces the mode and schemas of the registry. It is safe to call
+// while points are being validated. The registry is unchanged if the config
+// is invalid.
+func (r *Registry) Configure(c Config) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	databases := make(map[string]map[string]*measurement)
+	for _, m := range c.Measurements {
+		mm := &measurement{
+			tags:   make(map[string]struct{}, len(m.Tags)),
+			fields: make(map[string]influxql.DataType, len(m.Fields)),
+		}
+		for _, k := range m.Tags {
+			mm.tags[k] = struct{}{}
+		}
+		for k, typ := range m.Fields {
+			mm.fields[k], _ = parseFieldType(typ)
+		}
+
+		if databases[m.Database] == nil {
+			databases[m.Database] = make(map[string]*measurement)
+		}
+		databases[m.Database][m.Name] = mm
+	}
+
+	mode := c.Mode
+	if mode == "" {
+		mode = DefaultMode
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mode, r.strict, r.databases = mode, c.Strict, databases
+	return nil
+}
+
+// Validate returns an error if p doesn't conform to the schema of its
+// measurement in database. Points of measurements without a schema conform
+// unless the registry is strict.
+func (r *Registry) Validate(database string, p tsdb.Point) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.validate(database, p)
+}
+
+func (r *Registry) validate(database string, p tsdb.Point) error {
+	measurements := r.databases[database]
+	if measurements == nil {
+		return nil
+	}
+
+	m := measurements[p.Name()]
+	if m == nil {
+		if r.strict {
+			return fmt.Errorf("%s: measurement %q not allowed in database %q", influxdb.ErrSchemaViolation, p.Name(), database)
+		}
+		return nil
+	}
+
+	// Keys are checked in order so the reported violation is deterministic.
+	tags := p.Tags()
+	tagKeys := make([]string, 0, len(tags))
+	for k := range tags {
+		tagKeys = append(tagKeys, k)
+	}
+	sort.Strings(tagKeys)
+	for _, k := range tagKeys {
+		if _, ok := m.tags[k]; !ok {
+			return fmt.Errorf("%s: measurement %q: tag %q not allowed", influxdb.ErrSchemaViolation, p.Name(), k)
+		}
+	}
+
+	fields := p.Fields()
+	fieldKeys := make([]string, 0, len(fields))
+	for k := range fields {
+		fieldKeys = append(fieldKeys, k)
+	}
+	sort.Strings(fieldKeys)
+	for _, k := range fieldKeys {
+		typ, ok := m.fields[k]
+		if !ok {
+			return fmt.Errorf("%s: measurement %q: field %q not allowed", influxdb.ErrSchemaViolation, p.Name(), k)
+		} else if actual := influxql.InspectDataType(fields[k]); actual != typ {
+			return fmt.Errorf("%s: measurement %q: field %q is %s, expected %s", influxdb.ErrSchemaViolation, p.Name(), k, actual, typ)
+		}
+	}
+	return nil
+}
+
+// Filter validates points written to database. In reject mode, an error is
+// returned if any point doesn't conform. In drop mode, the conforming points
+// are returned.
+func (r *Registry) Filter(database string, points []tsdb.Point) ([]tsdb.Point, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.databases[database] == nil {
+		return points, nil
+	}
+
+	var a []tsdb.Point
+	var dropped int
+	for i, p := range points {
+		err := r.validate(database, p)
+		if err == nil {
+			if a != nil {
+				a = append(a, p)
+			}
+			continue
+		}
+
+		influxdb.DatabaseStatistics(database).Add(statDatabaseSchemaViolations, 1)
+		if r.mode == ModeReject {
+			r.statMap.Add(statPointsRejected, int64(len(points)))
+			return nil, err
+		}
+
+		// Copy the points that conform so far on the first violation.
+		if a == nil {
+			a = make([]tsdb.Point, i, len(points))
+			copy(a, points[:i])
+		}
+		dropped++
+	}
+
+	if dropped == 0 {
+		return points, nil
+	}
+	r.statMap.Add(statPointsDropped, int64(dropped))
+	return a, nil
+}
+
+// parseFieldType returns the data type of a field type in a schema.
+func parseFieldType(s string) (influxql.DataType, error) {
+	switch strings.ToLower(s) {
+	case "float":
+		return influxql.Float, nil
+	case "integer":
+		return influxql.Integer, nil
+	case "unsigned":
+		return influxql.Unsigned, nil
+	case "string":
+		return influxql.String, nil
+	case "boolean":
+		return influxql.Boolean, nil
+	}
+	return influxql.Unknown, fmt.Errorf("unknown field type: %q", s)
+}
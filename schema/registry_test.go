@@ -0,0 +1,121 @@
+package schema_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb"
+	"github.com/influxdb/influxdb/schema"
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// Ensure points are validated against the schema of their measurement.
+func TestRegistry_Validate(t *testing.T) {
+	r := MustNewRegistry(NewConfig())
+
+	for i, tt := range []struct {
+		database string
+		point    tsdb.Point
+		err      string
+	}{
+		{database: "db0", point: NewPoint("cpu", tsdb.Tags{"host": "a"}, tsdb.Fields{"value": 1.0, "count": int64(1)})},
+		{database: "db0", point: NewPoint("cpu", nil, tsdb.Fields{"value": 1.0})},
+		{database: "db0", point: NewPoint("mem", tsdb.Tags{"any": "a"}, tsdb.Fields{"any": "x"})},
+		{database: "db1", point: NewPoint("cpu", tsdb.Tags{"any": "a"}, tsdb.Fields{"value": "x"})},
+		{database: "db0", point: NewPoint("cpu", tsdb.Tags{"host": "a", "zone": "b"}, tsdb.Fields{"value": 1.0}), err: `schema violation: measurement "cpu": tag "zone" not allowed`},
+		{database: "db0", point: NewPoint("cpu", nil, tsdb.Fields{"value": 1.0, "idle": 1.0}), err: `schema violation: measurement "cpu": field "idle" not allowed`},
+		{database: "db0", point: NewPoint("cpu", nil, tsdb.Fields{"value": int64(1)}), err: `schema violation: measurement "cpu": field "value" is integer, expected float`},
+	} {
+		err := r.Validate(tt.database, tt.point)
+		if errstr(err) != tt.err {
+			t.Errorf("%d. unexpected error: %v", i, err)
+		} else if err != nil && !influxdb.IsClientError(err) {
+			t.Errorf("%d. expected client error: %v", i, err)
+		}
+	}
+
+	// Measurements without a schema are rejected when strict.
+	c := NewConfig()
+	c.Strict = true
+	if err := r.Configure(c); err != nil {
+		t.Fatal(err)
+	} else if err := r.Validate("db0", NewPoint("mem", nil, tsdb.Fields{"value": 1.0})); errstr(err) != `schema violation: measurement "mem" not allowed in database "db0"` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure a write with a nonconforming point is rejected in reject mode.
+func TestRegistry_Filter_Reject(t *testing.T) {
+	r := MustNewRegistry(NewConfig())
+
+	points := []tsdb.Point{
+		NewPoint("cpu", nil, tsdb.Fields{"value": 1.0}),
+		NewPoint("cpu", nil, tsdb.Fields{"idle": 1.0}),
+	}
+	if a, err := r.Filter("db0", points); err == nil {
+		t.Fatal("expected error")
+	} else if a != nil {
+		t.Fatalf("unexpected points: %v", a)
+	}
+
+	if a, err := r.Filter("db0", points[:1]); err != nil {
+		t.Fatal(err)
+	} else if len(a) != 1 {
+		t.Fatalf("unexpected points: %v", a)
+	}
+}
+
+// Ensure nonconforming points are dropped in drop mode.
+func TestRegistry_Filter_Drop(t *testing.T) {
+	c := NewConfig()
+	c.Mode = schema.ModeDrop
+	r := MustNewRegistry(c)
+
+	points := []tsdb.Point{
+		NewPoint("cpu", nil, tsdb.Fields{"idle": 1.0}),
+		NewPoint("cpu", nil, tsdb.Fields{"value": 1.0}),
+		NewPoint("cpu", tsdb.Tags{"zone": "a"}, tsdb.Fields{"value": 1.0}),
+		NewPoint("mem", nil, tsdb.Fields{"value": 1.0}),
+	}
+	a, err := r.Filter("db0", points)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(a) != 2 || a[0] != points[1] || a[1] != points[3] {
+		t.Fatalf("unexpected points: %v", a)
+	}
+}
+
+// NewConfig returns a config with a schema for the "cpu" measurement of "db0".
+func NewConfig() schema.Config {
+	c := schema.NewConfig()
+	c.Enabled = true
+	c.Measurements = []schema.Measurement{{
+		Database: "db0",
+		Name:     "cpu",
+		Tags:     []string{"host"},
+		Fields:   map[string]string{"value": "float", "count": "integer"},
+	}}
+	return c
+}
+
+// MustNewRegistry returns a registry for c. Panic on error.
+func MustNewRegistry(c schema.Config) *schema.Registry {
+	r, err := schema.NewRegistry(c)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// NewPoint returns a point at a fixed time.
+func NewPoint(name string, tags tsdb.Tags, fields tsdb.Fields) tsdb.Point {
+	return tsdb.NewPoint(name, tags, fields, time.Unix(0, 0))
+}
+
+// errstr returns the string representation of err, or blank if nil.
+func errstr(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return ""
+}
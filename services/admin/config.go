@@ -1,5 +1,9 @@
 package admin
 
+import (
+	"github.com/influxdb/influxdb/services/httpd"
+)
+
 const (
 	// DefaultBindAddress is the default bind address for the HTTP server.
 	DefaultBindAddress = ":8083"
@@ -10,6 +14,21 @@ type Config struct {
 	BindAddress      string `toml:"bind-address"`
 	HttpsEnabled     bool   `toml:"https-enabled"`
 	HttpsCertificate string `toml:"https-certificate"`
+
+	// HttpsPrivateKey is the key of the certificate, when it isn't in the
+	// certificate file. HttpsClientCA, if set, requires clients to present a
+	// certificate signed by one of the CAs in the file.
+	HttpsPrivateKey string `toml:"https-private-key"`
+	HttpsClientCA   string `toml:"https-client-ca"`
+
+	// AuthEnabled requires an admin user to authenticate, independently of
+	// the [http] service. The providers are the ones of the [http] service:
+	// "meta", "ldap" or "file".
+	AuthEnabled  bool                `toml:"auth-enabled"`
+	AuthProvider string              `toml:"auth-provider"`
+	AuthFile     string              `toml:"auth-file"`
+	LDAP         httpd.LDAPConfig    `toml:"ldap"`
+	Groups       []httpd.GroupConfig `toml:"group"`
 }
 
 func NewConfig() Config {
@@ -17,5 +36,7 @@ func NewConfig() Config {
 		BindAddress:      DefaultBindAddress,
 		HttpsEnabled:     false,
 		HttpsCertificate: "/etc/ssl/influxdb.pem",
+		AuthProvider:     httpd.AuthProviderMeta,
+		LDAP:             httpd.NewLDAPConfig(),
 	}
 }
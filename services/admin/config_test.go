@@ -15,6 +15,11 @@ enabled = true
 bind-address = ":8083"
 https-enabled = true
 https-certificate = "/dev/null"
+https-private-key = "/dev/zero"
+https-client-ca = "/dev/random"
+auth-enabled = true
+auth-provider = "file"
+auth-file = "/dev/urandom"
 `, &c); err != nil {
 		t.Fatal(err)
 	}
@@ -28,5 +33,15 @@ https-certificate = "/dev/null"
 		t.Fatalf("unexpected https enabled: %v", c.HttpsEnabled)
 	} else if c.HttpsCertificate != "/dev/null" {
 		t.Fatalf("unexpected https certificate: %v", c.HttpsCertificate)
+	} else if c.HttpsPrivateKey != "/dev/zero" {
+		t.Fatalf("unexpected https private key: %v", c.HttpsPrivateKey)
+	} else if c.HttpsClientCA != "/dev/random" {
+		t.Fatalf("unexpected https client ca: %v", c.HttpsClientCA)
+	} else if c.AuthEnabled != true {
+		t.Fatalf("unexpected auth enabled: %v", c.AuthEnabled)
+	} else if c.AuthProvider != "file" {
+		t.Fatalf("unexpected auth provider: %v", c.AuthProvider)
+	} else if c.AuthFile != "/dev/urandom" {
+		t.Fatalf("unexpected auth file: %v", c.AuthFile)
 	}
 }
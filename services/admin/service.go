@@ -2,13 +2,18 @@ package admin
 
 import (
 	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"net"
 	"net/http"
 	"os"
 	"strings"
 
+	"github.com/influxdb/influxdb/meta"
+	"github.com/influxdb/influxdb/services/httpd"
 	// Register static assets via statik.
 	_ "github.com/influxdb/influxdb/statik"
 	"github.com/rakyll/statik/fs"
@@ -20,19 +25,34 @@ type Service struct {
 	addr     string
 	https    bool
 	cert     string
+	key      string
+	clientCA string
+	auth     Config
 	err      chan error
 
+	// MetaStore authenticates the users of the "meta" auth provider.
+	MetaStore interface {
+		Users() ([]meta.UserInfo, error)
+		Authenticate(username, password string) (*meta.UserInfo, error)
+	}
+
+	// Authenticator, if set, authenticates users instead of the meta store.
+	Authenticator httpd.Authenticator
+
 	logger *log.Logger
 }
 
 // NewService returns a new instance of Service.
 func NewService(c Config) *Service {
 	return &Service{
-		addr:   c.BindAddress,
-		https:  c.HttpsEnabled,
-		cert:   c.HttpsCertificate,
-		err:    make(chan error),
-		logger: log.New(os.Stderr, "[admin] ", log.LstdFlags),
+		addr:     c.BindAddress,
+		https:    c.HttpsEnabled,
+		cert:     c.HttpsCertificate,
+		key:      c.HttpsPrivateKey,
+		clientCA: c.HttpsClientCA,
+		auth:     c,
+		err:      make(chan error),
+		logger:   log.New(os.Stderr, "[admin] ", log.LstdFlags),
 	}
 }
 
@@ -40,16 +60,30 @@ func NewService(c Config) *Service {
 func (s *Service) Open() error {
 	s.logger.Printf("Starting admin service")
 
+	// Authenticate users with the configured provider unless one is set.
+	if s.auth.AuthEnabled && s.Authenticator == nil {
+		a, err := httpd.NewAuthenticator(httpd.Config{
+			AuthProvider: s.auth.AuthProvider,
+			AuthFile:     s.auth.AuthFile,
+			LDAP:         s.auth.LDAP,
+			Groups:       s.auth.Groups,
+		})
+		if err != nil {
+			return fmt.Errorf("auth provider: %s", err)
+		} else if a == nil && s.MetaStore == nil {
+			return errors.New("auth provider: meta store required")
+		}
+		s.Authenticator = a
+	}
+
 	// Open listener.
 	if s.https {
-		cert, err := tls.LoadX509KeyPair(s.cert, s.cert)
+		config, err := s.tlsConfig()
 		if err != nil {
 			return err
 		}
 
-		listener, err := tls.Listen("tcp", s.addr, &tls.Config{
-			Certificates: []tls.Certificate{cert},
-		})
+		listener, err := tls.Listen("tcp", s.addr, config)
 		if err != nil {
 			return err
 		}
@@ -71,6 +105,35 @@ func (s *Service) Open() error {
 	return nil
 }
 
+// tlsConfig returns the TLS configuration of the listener. The private key
+// is read from the certificate file unless a key file is set.
+func (s *Service) tlsConfig() (*tls.Config, error) {
+	key := s.key
+	if key == "" {
+		key = s.cert
+	}
+	cert, err := tls.LoadX509KeyPair(s.cert, key)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	// Only accept clients with a certificate signed by the client CAs.
+	if s.clientCA != "" {
+		buf, err := ioutil.ReadFile(s.clientCA)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(buf) {
+			return nil, fmt.Errorf("no certificates in client CA file: %s", s.clientCA)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
 // Close closes the underlying listener.
 func (s *Service) Close() error {
 	if s.listener != nil {
@@ -104,8 +167,58 @@ func (s *Service) serve() {
 	}
 
 	// Run file system handler on listener.
-	err = http.Serve(s.listener, http.FileServer(statikFS))
+	var h http.Handler = http.FileServer(statikFS)
+	if s.auth.AuthEnabled {
+		h = s.authenticate(h)
+	}
+	err = http.Serve(s.listener, h)
 	if err != nil && !strings.Contains(err.Error(), "closed") {
 		s.err <- fmt.Errorf("listener error: addr=%s, err=%s", s.Addr(), err)
 	}
 }
+
+// authenticate requires the requests of inner to authenticate an admin user
+// with HTTP basic auth. Like the [http] service, the users of the meta store
+// don't authenticate while there are none, to bootstrap the server.
+func (s *Service) authenticate(inner http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.Authenticator == nil {
+			uis, err := s.MetaStore.Users()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			} else if len(uis) == 0 {
+				inner.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		username, password, ok := r.BasicAuth()
+		if !ok || username == "" {
+			unauthorized(w, "username required")
+			return
+		}
+
+		var u *meta.UserInfo
+		var err error
+		if s.Authenticator != nil {
+			u, err = s.Authenticator.Authenticate(username, password)
+		} else {
+			u, err = s.MetaStore.Authenticate(username, password)
+		}
+		if err != nil {
+			unauthorized(w, err.Error())
+			return
+		} else if !u.Admin {
+			http.Error(w, fmt.Sprintf("%q user is not an admin", username), http.StatusForbidden)
+			return
+		}
+		inner.ServeHTTP(w, r)
+	})
+}
+
+// unauthorized asks the client for basic auth credentials.
+func unauthorized(w http.ResponseWriter, msg string) {
+	w.Header().Set("WWW-Authenticate", `Basic realm="InfluxDB admin"`)
+	http.Error(w, msg, http.StatusUnauthorized)
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"testing"
 
+	"github.com/influxdb/influxdb/meta"
 	"github.com/influxdb/influxdb/services/admin"
 )
 
@@ -31,3 +32,60 @@ func TestService_Index(t *testing.T) {
 		t.Fatalf("unable to read body: %s", err)
 	}
 }
+
+// Ensure the service requires an admin user when auth is enabled.
+func TestService_Auth(t *testing.T) {
+	s := admin.NewService(admin.Config{BindAddress: "127.0.0.1:0", AuthEnabled: true})
+	s.MetaStore = &MetaStore{users: []meta.UserInfo{
+		{Name: "admin", Hash: "pw", Admin: true},
+		{Name: "susy", Hash: "pw"},
+	}}
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for i, tt := range []struct {
+		username string
+		password string
+		code     int
+	}{
+		{code: http.StatusUnauthorized},
+		{username: "admin", password: "bad", code: http.StatusUnauthorized},
+		{username: "susy", password: "pw", code: http.StatusForbidden},
+		{username: "admin", password: "pw", code: http.StatusOK},
+	} {
+		req, err := http.NewRequest("GET", "http://"+s.Addr().String(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.username != "" {
+			req.SetBasicAuth(tt.username, tt.password)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.code {
+			t.Errorf("%d. unexpected status: %d", i, resp.StatusCode)
+		}
+	}
+}
+
+// MetaStore is a mock of the users of the meta store.
+type MetaStore struct {
+	users []meta.UserInfo
+}
+
+func (m *MetaStore) Users() ([]meta.UserInfo, error) { return m.users, nil }
+
+// Authenticate compares the password to the plain text hash of the user.
+func (m *MetaStore) Authenticate(username, password string) (*meta.UserInfo, error) {
+	for i := range m.users {
+		if u := &m.users[i]; u.Name == username && u.Hash == password {
+			return u, nil
+		}
+	}
+	return nil, meta.ErrAuthenticate
+}
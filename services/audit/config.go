@@ -0,0 +1,24 @@
+package audit
+
+import "errors"
+
+// Config represents the configuration for the audit log.
+type Config struct {
+	Enabled bool `toml:"enabled"`
+
+	// Path is the file audit entries are appended to.
+	Path string `toml:"path"`
+}
+
+// NewConfig returns a new instance of Config with defaults.
+func NewConfig() Config {
+	return Config{Enabled: false}
+}
+
+// Validate returns an error if the config is invalid.
+func (c Config) Validate() error {
+	if c.Enabled && c.Path == "" {
+		return errors.New("path must be specified")
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package audit_test
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/influxdb/influxdb/services/audit"
+)
+
+func TestConfig_Parse(t *testing.T) {
+	// Parse configuration.
+	var c audit.Config
+	if _, err := toml.Decode(`
+enabled = true
+path = "/tmp/audit.log"
+`, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate configuration.
+	if c.Enabled != true {
+		t.Fatalf("unexpected enabled state: %v", c.Enabled)
+	} else if c.Path != "/tmp/audit.log" {
+		t.Fatalf("unexpected path: %s", c.Path)
+	}
+}
+
+// Ensure an enabled audit log requires a path.
+func TestConfig_Validate(t *testing.T) {
+	c := audit.NewConfig()
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	c.Enabled = true
+	if err := c.Validate(); err == nil || err.Error() != "path must be specified" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/influxdb/influxdb/influxql"
+)
+
+// Entry is a single administrative statement recorded in the audit log.
+type Entry struct {
+	Time      time.Time `json:"time"`
+	User      string    `json:"user,omitempty"`
+	Addr      string    `json:"addr,omitempty"`
+	Database  string    `json:"database,omitempty"`
+	Statement string    `json:"statement"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// Service records administrative statements to a file as newline-delimited JSON.
+type Service struct {
+	mu   sync.Mutex
+	path string
+	w    io.WriteCloser
+	enc  *json.Encoder
+
+	Logger *log.Logger
+}
+
+// NewService returns a new instance of Service.
+func NewService(c Config) *Service {
+	return &Service{
+		path:   c.Path,
+		Logger: log.New(os.Stderr, "[audit] ", log.LstdFlags),
+	}
+}
+
+// Open opens the audit log file.
+func (s *Service) Open() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Logger.Println("Starting audit log at", s.path)
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("open audit log: %s", err)
+	}
+	s.w = f
+	s.enc = json.NewEncoder(f)
+	return nil
+}
+
+// Close closes the audit log file.
+func (s *Service) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.w == nil {
+		return nil
+	}
+	err := s.w.Close()
+	s.w, s.enc = nil, nil
+	return err
+}
+
+// SetLogger sets the internal logger to the logger passed in.
+func (s *Service) SetLogger(l *log.Logger) {
+	s.Logger = l
+}
+
+// Record appends e to the audit log. Entries without a time are stamped
+// with the current time. Entries recorded while the log is closed are dropped.
+func (s *Service) Record(e Entry) {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.enc == nil {
+		return
+	}
+	if err := s.enc.Encode(e); err != nil {
+		s.Logger.Printf("failed to write audit entry: %s", err)
+	}
+}
+
+// Audited returns true if stmt changes databases, users, privileges, or
+// deletes data, and should therefore be recorded in the audit log.
+func Audited(stmt influxql.Statement) bool {
+	switch stmt.(type) {
+	case *influxql.CreateDatabaseStatement,
+		*influxql.DropDatabaseStatement,
+		*influxql.CreateUserStatement,
+		*influxql.DropUserStatement,
+		*influxql.SetPasswordUserStatement,
+		*influxql.SetUserLimitsStatement,
+		*influxql.GrantStatement,
+		*influxql.GrantAdminStatement,
+		*influxql.RevokeStatement,
+		*influxql.RevokeAdminStatement,
+		*influxql.DeleteStatement,
+		*influxql.DropSeriesStatement,
+		*influxql.DropMeasurementStatement:
+		return true
+	}
+	return false
+}
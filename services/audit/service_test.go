@@ -0,0 +1,89 @@
+package audit_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/influxql"
+	"github.com/influxdb/influxdb/services/audit"
+)
+
+// Ensure entries are appended to the audit log file as JSON lines.
+func TestService_Record(t *testing.T) {
+	dir, err := ioutil.TempDir("", "influxdb-audit-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	s := audit.NewService(audit.Config{Enabled: true, Path: path})
+	s.SetLogger(log.New(ioutil.Discard, "", 0))
+
+	// Entries are dropped until the log is opened.
+	s.Record(audit.Entry{Statement: "DROP DATABASE db0"})
+
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.Record(audit.Entry{Time: now, User: "susy", Addr: "10.0.0.1", Statement: "DROP DATABASE db1"})
+	s.Record(audit.Entry{Statement: "CREATE DATABASE db2", Error: "marker"})
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("unexpected log: %s", b)
+	}
+
+	var e audit.Entry
+	if err := json.Unmarshal([]byte(lines[0]), &e); err != nil {
+		t.Fatal(err)
+	} else if !e.Time.Equal(now) || e.User != "susy" || e.Addr != "10.0.0.1" || e.Statement != "DROP DATABASE db1" || e.Error != "" {
+		t.Fatalf("unexpected entry: %#v", e)
+	}
+	e = audit.Entry{}
+	if err := json.Unmarshal([]byte(lines[1]), &e); err != nil {
+		t.Fatal(err)
+	} else if e.Time.IsZero() || e.Statement != "CREATE DATABASE db2" || e.Error != "marker" {
+		t.Fatalf("unexpected entry: %#v", e)
+	}
+}
+
+// Ensure only administrative statements are audited.
+func TestAudited(t *testing.T) {
+	for _, tt := range []struct {
+		s       string
+		audited bool
+	}{
+		{s: `CREATE DATABASE db0`, audited: true},
+		{s: `DROP DATABASE db0`, audited: true},
+		{s: `CREATE USER susy WITH PASSWORD 'pass'`, audited: true},
+		{s: `SET PASSWORD FOR susy = 'pass'`, audited: true},
+		{s: `GRANT READ ON db0 TO susy`, audited: true},
+		{s: `REVOKE ALL PRIVILEGES FROM susy`, audited: true},
+		{s: `DROP SERIES FROM cpu`, audited: true},
+		{s: `SELECT value FROM cpu`, audited: false},
+		{s: `SHOW DATABASES`, audited: false},
+	} {
+		stmt, err := influxql.ParseStatement(tt.s)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.s, err)
+		}
+		if got := audit.Audited(stmt); got != tt.audited {
+			t.Errorf("%s: audited=%v, expected %v", tt.s, got, tt.audited)
+		}
+	}
+}
@@ -54,9 +54,11 @@ type Service struct {
 	stop    chan struct{}
 	ln      *net.UDPConn
 	batcher *tsdb.PointBatcher
-	typesdb gollectd.Types
 	addr    net.Addr
 
+	mu      sync.RWMutex
+	typesdb gollectd.Types
+
 	// expvar-based stats.
 	statMap *expvar.Map
 }
@@ -100,13 +102,13 @@ func (s *Service) Open() error {
 		return err
 	}
 
-	if s.typesdb == nil {
+	if s.types() == nil {
 		// Open collectd types.
 		typesdb, err := gollectd.TypesDBFile(s.Config.TypesDB)
 		if err != nil {
 			return fmt.Errorf("Open(): %s", err)
 		}
-		s.typesdb = typesdb
+		s.setTypes(typesdb)
 	}
 
 	// Resolve our address.
@@ -168,9 +170,40 @@ func (s *Service) SetLogger(l *log.Logger) {
 }
 
 // SetTypes sets collectd types db.
-func (s *Service) SetTypes(types string) (err error) {
-	s.typesdb, err = gollectd.TypesDB([]byte(types))
-	return
+func (s *Service) SetTypes(types string) error {
+	typesdb, err := gollectd.TypesDB([]byte(types))
+	if err != nil {
+		return err
+	}
+	s.setTypes(typesdb)
+	return nil
+}
+
+// Reload reads the types db file of c and replaces the types used to parse
+// packets. The current types are kept if the file can't be read. Other
+// settings of c require a restart and are ignored.
+func (s *Service) Reload(c Config) error {
+	typesdb, err := gollectd.TypesDBFile(c.TypesDB)
+	if err != nil {
+		return fmt.Errorf("read types db: %s", err)
+	}
+	s.setTypes(typesdb)
+	s.Logger.Printf("reloaded collectd types from %s", c.TypesDB)
+	return nil
+}
+
+// types returns the current collectd types db.
+func (s *Service) types() gollectd.Types {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.typesdb
+}
+
+// setTypes replaces the current collectd types db.
+func (s *Service) setTypes(typesdb gollectd.Types) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.typesdb = typesdb
 }
 
 // Err returns a channel for fatal errors that occur on go routines.
@@ -217,7 +250,7 @@ func (s *Service) serve() {
 }
 
 func (s *Service) handleMessage(buffer []byte) {
-	packets, err := gollectd.Packets(buffer, s.typesdb)
+	packets, err := gollectd.Packets(buffer, s.types())
 	if err != nil {
 		s.statMap.Add(statPointsParseFail, 1)
 		s.Logger.Printf("Collectd parse error: %s", err)
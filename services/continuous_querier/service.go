@@ -4,8 +4,8 @@ import (
 	"errors"
 	"expvar"
 	"fmt"
-	"log"
 	"os"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -13,6 +13,7 @@ import (
 	"github.com/influxdb/influxdb"
 	"github.com/influxdb/influxdb/cluster"
 	"github.com/influxdb/influxdb/influxql"
+	"github.com/influxdb/influxdb/logger"
 	"github.com/influxdb/influxdb/meta"
 	"github.com/influxdb/influxdb/tsdb"
 )
@@ -29,6 +30,13 @@ const (
 	statPointsWritten = "points_written"
 )
 
+// Statistics for individual continuous queries.
+const (
+	statLastRunTime  = "last_run_time"
+	statLastDuration = "last_duration_ns"
+	statLastError    = "last_error"
+)
+
 // ContinuousQuerier represents a service that executes continuous queries.
 type ContinuousQuerier interface {
 	// Run executes the named query in the named database.  Blank database or name matches all.
@@ -37,7 +45,7 @@ type ContinuousQuerier interface {
 
 // queryExecutor is an internal interface to make testing easier.
 type queryExecutor interface {
-	ExecuteQuery(query *influxql.Query, database string, chunkSize int) (<-chan *influxql.Result, error)
+	ExecuteQuery(query *influxql.Query, database string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error)
 }
 
 // metaStore is an internal interface to make testing easier.
@@ -45,6 +53,7 @@ type metaStore interface {
 	IsLeader() bool
 	Databases() ([]meta.DatabaseInfo, error)
 	Database(name string) (*meta.DatabaseInfo, error)
+	SetContinuousQueryLastRun(database, name string, t time.Time) error
 }
 
 // pointsWriter is an internal interface to make testing easier.
@@ -81,9 +90,13 @@ type Service struct {
 	PointsWriter  pointsWriter
 	Config        *Config
 	RunInterval   time.Duration
+	// Ready, if set, returns false while the points of the shards are still
+	// being loaded. CQs aren't run until then, so they don't write results
+	// computed from missing points.
+	Ready func() bool
 	// RunCh can be used by clients to signal service to run CQs.
 	RunCh          chan *RunRequest
-	Logger         *log.Logger
+	Logger         *logger.Logger
 	loggingEnabled bool
 	statMap        *expvar.Map
 	// lastRuns maps CQ name to last time it was run.
@@ -91,6 +104,32 @@ type Service struct {
 	lastRuns map[string]time.Time
 	stop     chan struct{}
 	wg       *sync.WaitGroup
+
+	// statuses maps each executed CQ to the outcome of its last execution.
+	statusMu sync.RWMutex
+	statuses map[cqKey]*cqStatus
+}
+
+// Status represents the outcome of the last execution of a continuous query.
+type Status struct {
+	Database      string
+	Name          string
+	LastRun       time.Time
+	Duration      time.Duration
+	PointsWritten int64
+	LastError     string
+}
+
+// cqKey uniquely identifies a continuous query.
+type cqKey struct {
+	database string
+	name     string
+}
+
+// cqStatus holds the status of a single CQ and its statistics.
+type cqStatus struct {
+	Status
+	statMap *expvar.Map
 }
 
 // NewService returns a new instance of Service.
@@ -101,8 +140,9 @@ func NewService(c Config) *Service {
 		RunCh:          make(chan *RunRequest),
 		loggingEnabled: c.LogEnabled,
 		statMap:        influxdb.NewStatistics("cq", "cq", nil),
-		Logger:         log.New(os.Stderr, "[continuous_querier] ", log.LstdFlags),
+		Logger:         logger.New(os.Stderr, "continuous_querier"),
 		lastRuns:       map[string]time.Time{},
+		statuses:       make(map[cqKey]*cqStatus),
 	}
 
 	return s
@@ -110,7 +150,7 @@ func NewService(c Config) *Service {
 
 // Open starts the service.
 func (s *Service) Open() error {
-	s.Logger.Println("Starting continuous query service")
+	s.Logger.Info("Starting continuous query service")
 
 	if s.stop != nil {
 		return nil
@@ -140,10 +180,21 @@ func (s *Service) Close() error {
 }
 
 // SetLogger sets the internal logger to the logger passed in.
-func (s *Service) SetLogger(l *log.Logger) {
+func (s *Service) SetLogger(l *logger.Logger) {
 	s.Logger = l
 }
 
+// SetConfig replaces the settings used to schedule and log continuous query
+// executions. It takes effect on the next execution. Changes to Enabled
+// require a restart and are ignored.
+func (s *Service) SetConfig(c Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c.Enabled = s.Config.Enabled
+	s.Config = &c
+	s.loggingEnabled = c.LogEnabled
+}
+
 // Run runs the specified continuous query, or all CQs if none is specified.
 func (s *Service) Run(database, name string, t time.Time) error {
 	var dbs []meta.DatabaseInfo
@@ -191,27 +242,34 @@ func (s *Service) backgroundLoop() {
 	for {
 		select {
 		case <-s.stop:
-			s.Logger.Println("continuous query service terminating")
+			s.Logger.Info("continuous query service terminating")
 			return
 		case req := <-s.RunCh:
-			if s.MetaStore.IsLeader() {
-				s.Logger.Printf("running continuous queries by request for time: %v", req.Now.UnixNano())
+			if !s.ready() {
+				s.Logger.Info("not running continuous queries by request, shards still loading", "time", req.Now.UnixNano())
+			} else if s.MetaStore.IsLeader() {
+				s.Logger.Info("running continuous queries by request", "time", req.Now.UnixNano())
 				s.runContinuousQueries(req)
 			}
 		case <-time.After(s.RunInterval):
-			if s.MetaStore.IsLeader() {
+			if s.ready() && s.MetaStore.IsLeader() {
 				s.runContinuousQueries(&RunRequest{Now: time.Now()})
 			}
 		}
 	}
 }
 
+// ready returns true if the CQs can be run.
+func (s *Service) ready() bool {
+	return s.Ready == nil || s.Ready()
+}
+
 // runContinuousQueries gets CQs from the meta store and runs them.
 func (s *Service) runContinuousQueries(req *RunRequest) {
 	// Get list of all databases.
 	dbs, err := s.MetaStore.Databases()
 	if err != nil {
-		s.Logger.Println("error getting databases")
+		s.Logger.Error("error getting databases", "err", err)
 		return
 	}
 	// Loop through all databases executing CQs.
@@ -222,7 +280,7 @@ func (s *Service) runContinuousQueries(req *RunRequest) {
 				continue
 			}
 			if err := s.ExecuteContinuousQuery(&db, &cq, req.Now); err != nil {
-				s.Logger.Printf("error executing query: %s: err = %s", cq.Query, err)
+				s.Logger.Error("error executing continuous query", "database", db.Name, "name", cq.Name, "query", cq.Query, "err", err)
 				s.statMap.Add(statQueryFail, 1)
 			} else {
 				s.statMap.Add(statQueryOK, 1)
@@ -231,8 +289,60 @@ func (s *Service) runContinuousQueries(req *RunRequest) {
 	}
 }
 
+// Statuses returns the status of the last execution of each continuous query,
+// sorted by database and name. Only queries executed by this node are included.
+func (s *Service) Statuses() []Status {
+	s.statusMu.RLock()
+	defer s.statusMu.RUnlock()
+
+	a := make([]Status, 0, len(s.statuses))
+	for _, st := range s.statuses {
+		a = append(a, st.Status)
+	}
+	sort.Sort(statuses(a))
+	return a
+}
+
+// updateStatus records the outcome of a CQ execution.
+func (s *Service) updateStatus(database, name string, lastRun time.Time, d time.Duration, n int, err error) {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+
+	key := cqKey{database: database, name: name}
+	st := s.statuses[key]
+	if st == nil {
+		st = &cqStatus{
+			Status:  Status{Database: database, Name: name},
+			statMap: influxdb.NewStatistics("cq:"+database+":"+name, "cq_query", map[string]string{"database": database, "name": name}),
+		}
+		s.statuses[key] = st
+	}
+
+	st.LastRun = lastRun
+	st.Duration = d
+	st.PointsWritten = int64(n)
+	st.LastError = ""
+	if err != nil {
+		st.LastError = err.Error()
+		st.statMap.Add(statQueryFail, 1)
+	} else {
+		st.statMap.Add(statQueryOK, 1)
+	}
+
+	lastErr := &expvar.String{}
+	lastErr.Set(st.LastError)
+	st.statMap.Set(statLastError, lastErr)
+	st.statMap.Add(statPointsWritten, int64(n))
+
+	runTime, duration := &expvar.Int{}, &expvar.Int{}
+	runTime.Set(lastRun.UnixNano())
+	duration.Set(int64(d))
+	st.statMap.Set(statLastRunTime, runTime)
+	st.statMap.Set(statLastDuration, duration)
+}
+
 // ExecuteContinuousQuery executes a single CQ.
-func (s *Service) ExecuteContinuousQuery(dbi *meta.DatabaseInfo, cqi *meta.ContinuousQueryInfo, now time.Time) error {
+func (s *Service) ExecuteContinuousQuery(dbi *meta.DatabaseInfo, cqi *meta.ContinuousQueryInfo, now time.Time) (err error) {
 	// TODO: re-enable stats
 	//s.stats.Inc("continuousQueryExecuted")
 
@@ -242,14 +352,28 @@ func (s *Service) ExecuteContinuousQuery(dbi *meta.DatabaseInfo, cqi *meta.Conti
 		return err
 	}
 
-	// Get the last time this CQ was run from the service's cache.
+	// Get the last time this CQ was run from the service's cache, falling
+	// back to the time recorded in the meta store, e.g. by a previous leader.
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	cq.LastRun = s.lastRuns[cqi.Name]
+	if t, ok := s.lastRuns[cqi.Name]; ok {
+		cq.LastRun = t
+	} else {
+		cq.LastRun = cqi.LastRun
+	}
 
-	// Set the retention policy to default if it wasn't specified in the query.
+	// Set the retention policy to the default of the target database if it
+	// wasn't specified in the query.
 	if cq.intoRP() == "" {
-		cq.setIntoRP(dbi.DefaultRetentionPolicy)
+		intoDBI := dbi
+		if cq.intoDB() != dbi.Name {
+			if intoDBI, err = s.MetaStore.Database(cq.intoDB()); err != nil {
+				return err
+			} else if intoDBI == nil {
+				return fmt.Errorf("database not found: %s", cq.intoDB())
+			}
+		}
+		cq.setIntoRP(intoDBI.DefaultRetentionPolicy)
 	}
 
 	// See if this query needs to be run.
@@ -265,6 +389,15 @@ func (s *Service) ExecuteContinuousQuery(dbi *meta.DatabaseInfo, cqi *meta.Conti
 	lastRun := time.Now()
 	cq.LastRun = lastRun
 	s.lastRuns[cqi.Name] = lastRun
+	if err := s.MetaStore.SetContinuousQueryLastRun(dbi.Name, cqi.Name, lastRun); err != nil {
+		s.Logger.Warn("error recording last run", "database", dbi.Name, "name", cqi.Name, "err", err)
+	}
+
+	// Record the outcome of this execution once it completes.
+	var pointsWritten int
+	defer func() {
+		s.updateStatus(dbi.Name, cqi.Name, lastRun, time.Since(lastRun), pointsWritten, err)
+	}()
 
 	// Get the group by interval.
 	interval, err := cq.q.GroupByInterval()
@@ -280,20 +413,34 @@ func (s *Service) ExecuteContinuousQuery(dbi *meta.DatabaseInfo, cqi *meta.Conti
 		startTime = startTime.Add(-interval)
 	}
 
-	if err := cq.q.SetTimeRange(startTime, startTime.Add(interval)); err != nil {
-		s.Logger.Printf("error setting time range: %s\n", err)
+	// Widen the range to cover the resample window so that recent intervals
+	// are recomputed to pick up late-arriving data.
+	endTime := startTime.Add(interval)
+	if cq.resampleFor > interval {
+		startTime = endTime.Add(-(cq.resampleFor / interval) * interval)
+	}
+
+	if err := cq.q.SetTimeRange(startTime, endTime); err != nil {
+		s.Logger.Error("error setting time range", "database", dbi.Name, "name", cqi.Name, "err", err)
 	}
 
 	if s.loggingEnabled {
-		s.Logger.Printf("executing continuous query %s", cq.Info.Name)
+		s.Logger.Info("executing continuous query", "database", dbi.Name, "name", cqi.Name, "start", startTime, "end", endTime)
 	}
 
 	// Do the actual processing of the query & writing of results.
-	if err := s.runContinuousQueryAndWriteResult(cq); err != nil {
-		s.Logger.Printf("error: %s. running: %s\n", err, cq.q.String())
+	n, err := s.runContinuousQueryAndWriteResult(cq)
+	pointsWritten += n
+	if err != nil {
+		s.Logger.Error("error running continuous query", "database", dbi.Name, "name", cqi.Name, "query", cq.q.String(), "err", err)
 		return err
 	}
 
+	// The resample window replaces the configured recompute settings.
+	if cq.resampleFor > 0 {
+		return nil
+	}
+
 	recomputeNoOlderThan := time.Duration(s.Config.RecomputeNoOlderThan)
 
 	for i := 0; i < s.Config.RecomputePreviousN; i++ {
@@ -304,12 +451,14 @@ func (s *Service) ExecuteContinuousQuery(dbi *meta.DatabaseInfo, cqi *meta.Conti
 		newStartTime := startTime.Add(-interval)
 
 		if err := cq.q.SetTimeRange(newStartTime, startTime); err != nil {
-			s.Logger.Printf("error setting time range: %s\n", err)
+			s.Logger.Error("error setting time range", "database", dbi.Name, "name", cqi.Name, "err", err)
 			return err
 		}
 
-		if err := s.runContinuousQueryAndWriteResult(cq); err != nil {
-			s.Logger.Printf("error during recompute previous: %s. running: %s\n", err, cq.q.String())
+		n, err := s.runContinuousQueryAndWriteResult(cq)
+		pointsWritten += n
+		if err != nil {
+			s.Logger.Error("error during recompute previous", "database", dbi.Name, "name", cqi.Name, "query", cq.q.String(), "err", err)
 			return err
 		}
 
@@ -318,24 +467,29 @@ func (s *Service) ExecuteContinuousQuery(dbi *meta.DatabaseInfo, cqi *meta.Conti
 	return nil
 }
 
-// runContinuousQueryAndWriteResult will run the query against the cluster and write the results back in
-func (s *Service) runContinuousQueryAndWriteResult(cq *ContinuousQuery) error {
+// runContinuousQueryAndWriteResult will run the query against the cluster and write the results back in.
+// Returns the number of points written.
+func (s *Service) runContinuousQueryAndWriteResult(cq *ContinuousQuery) (int, error) {
 	// Wrap the CQ's inner SELECT statement in a Query for the QueryExecutor.
+	// The results are written here, so the statement is run without its
+	// INTO clause.
+	stmt := cq.q.Clone()
+	stmt.Target = nil
 	q := &influxql.Query{
-		Statements: influxql.Statements{cq.q},
+		Statements: influxql.Statements{stmt},
 	}
 
 	// Execute the SELECT.
-	ch, err := s.QueryExecutor.ExecuteQuery(q, cq.Database, NoChunkingSize)
+	ch, err := s.QueryExecutor.ExecuteQuery(q, cq.Database, NoChunkingSize, tsdb.ReadPreferenceNearest, nil)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	// Read all rows from the result channel.
 	points := make([]tsdb.Point, 0, 100)
 	for result := range ch {
 		if result.Err != nil {
-			return result.Err
+			return 0, result.Err
 		}
 
 		for _, row := range result.Series {
@@ -347,7 +501,7 @@ func (s *Service) runContinuousQueryAndWriteResult(cq *ContinuousQuery) error {
 			// Convert the result row to points.
 			part, err := s.convertRowToPoints(measurement, row)
 			if err != nil {
-				log.Println(err)
+				s.Logger.Warn("error converting row to points", "name", cq.Info.Name, "err", err)
 				continue
 			}
 
@@ -361,7 +515,7 @@ func (s *Service) runContinuousQueryAndWriteResult(cq *ContinuousQuery) error {
 				fields := p.Fields()
 				for _, v := range fields {
 					if v == nil {
-						return nil
+						return 0, nil
 					}
 				}
 			}
@@ -370,7 +524,7 @@ func (s *Service) runContinuousQueryAndWriteResult(cq *ContinuousQuery) error {
 	}
 
 	if len(points) == 0 {
-		return nil
+		return 0, nil
 	}
 
 	// Create a write request for the points.
@@ -383,16 +537,16 @@ func (s *Service) runContinuousQueryAndWriteResult(cq *ContinuousQuery) error {
 
 	// Write the request.
 	if err := s.PointsWriter.WritePoints(req); err != nil {
-		s.Logger.Println(err)
-		return err
+		s.Logger.Error("error writing points", "database", cq.intoDB(), "retention_policy", cq.intoRP(), "err", err)
+		return 0, err
 	}
 
 	s.statMap.Add(statPointsWritten, int64(len(points)))
 	if s.loggingEnabled {
-		s.Logger.Printf("wrote %d point(s) to %s.%s", len(points), cq.intoDB(), cq.intoRP())
+		s.Logger.Info("wrote points", "database", cq.intoDB(), "retention_policy", cq.intoRP(), "n", len(points))
 	}
 
-	return nil
+	return len(points), nil
 }
 
 // convertRowToPoints will convert a query result Row into Points that can be written back in.
@@ -434,6 +588,10 @@ type ContinuousQuery struct {
 	Info     *meta.ContinuousQueryInfo
 	LastRun  time.Time
 	q        *influxql.SelectStatement
+
+	// Resample options from the CQ definition. Zero uses the config defaults.
+	resampleEvery time.Duration
+	resampleFor   time.Duration
 }
 
 func (cq *ContinuousQuery) intoDB() string {
@@ -463,6 +621,9 @@ func NewContinuousQuery(database string, cqi *meta.ContinuousQueryInfo) (*Contin
 		Database: database,
 		Info:     cqi,
 		q:        q.Source,
+
+		resampleEvery: q.ResampleEvery,
+		resampleFor:   q.ResampleFor,
 	}
 
 	return cquery, nil
@@ -490,6 +651,10 @@ func (cq *ContinuousQuery) shouldRunContinuousQuery(runsPerInterval int, noMoreT
 	if computeEvery < noMoreThan {
 		computeEvery = noMoreThan
 	}
+	// an explicit RESAMPLE EVERY overrides the config
+	if cq.resampleEvery > 0 {
+		computeEvery = cq.resampleEvery
+	}
 
 	// if we've passed the amount of time since the last run, do it up
 	if cq.LastRun.Add(computeEvery).UnixNano() <= time.Now().UnixNano() {
@@ -505,3 +670,15 @@ func assert(condition bool, msg string, v ...interface{}) {
 		panic(fmt.Sprintf("assert failed: "+msg, v...))
 	}
 }
+
+// statuses represents a list of statuses sortable by database and name.
+type statuses []Status
+
+func (a statuses) Len() int      { return len(a) }
+func (a statuses) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a statuses) Less(i, j int) bool {
+	if a[i].Database != a[j].Database {
+		return a[i].Database < a[j].Database
+	}
+	return a[i].Name < a[j].Name
+}
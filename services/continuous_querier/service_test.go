@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"strings"
 	"sync"
 	"testing"
@@ -12,7 +11,9 @@ import (
 
 	"github.com/influxdb/influxdb/cluster"
 	"github.com/influxdb/influxdb/influxql"
+	"github.com/influxdb/influxdb/logger"
 	"github.com/influxdb/influxdb/meta"
+	"github.com/influxdb/influxdb/tsdb"
 )
 
 var (
@@ -101,6 +102,44 @@ func TestExecuteContinuousQuery_ReferenceSource(t *testing.T) {
 	}
 }
 
+// Test ExecuteContinuousQuery writes into the default retention policy of another database.
+func TestExecuteContinuousQuery_IntoDatabase(t *testing.T) {
+	s := NewTestService(t)
+	ms := s.MetaStore.(*MetaStore)
+	ms.CreateDatabase("analytics", "longterm")
+	ms.CreateContinuousQuery("db", "cq_analytics", `CREATE CONTINUOUS QUERY cq_analytics ON db BEGIN SELECT count(cpu) INTO analytics..cpu_count FROM cpu GROUP BY time(1s) END`)
+
+	dbi, _ := ms.Database("db")
+	cqi := dbi.ContinuousQueries[1]
+
+	qe := s.QueryExecutor.(*QueryExecutor)
+	qe.Results = []*influxql.Result{genResult(1, 10)}
+	qe.ExecuteQueryFn = func(query *influxql.Query, database string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error) {
+		// The results are written by the service, not the query executor.
+		if stmt := query.Statements[0].(*influxql.SelectStatement); stmt.Target != nil {
+			t.Fatalf("unexpected target: %s", stmt.Target)
+		} else if database != "db" {
+			t.Fatalf("unexpected database: %s", database)
+		}
+		return nil, nil
+	}
+
+	var req *cluster.WritePointsRequest
+	pw := s.PointsWriter.(*PointsWriter)
+	pw.WritePointsFn = func(p *cluster.WritePointsRequest) error {
+		req = p
+		return nil
+	}
+
+	if err := s.ExecuteContinuousQuery(dbi, &cqi, time.Now()); err != nil {
+		t.Fatal(err)
+	} else if req == nil {
+		t.Fatal("expected points to be written")
+	} else if req.Database != "analytics" || req.RetentionPolicy != "longterm" {
+		t.Fatalf("unexpected target: %s.%s", req.Database, req.RetentionPolicy)
+	}
+}
+
 // Test the service happy path.
 func TestContinuousQueryService(t *testing.T) {
 	s := NewTestService(t)
@@ -142,7 +181,7 @@ func TestContinuousQueryService_Run(t *testing.T) {
 
 	// Set a callback for ExecuteQuery.
 	qe := s.QueryExecutor.(*QueryExecutor)
-	qe.ExecuteQueryFn = func(query *influxql.Query, database string, chunkSize int) (<-chan *influxql.Result, error) {
+	qe.ExecuteQueryFn = func(query *influxql.Query, database string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error) {
 		callCnt++
 		if callCnt >= expectCallCnt {
 			done <- struct{}{}
@@ -189,7 +228,32 @@ func TestContinuousQueryService_NotLeader(t *testing.T) {
 	done := make(chan struct{})
 	qe := s.QueryExecutor.(*QueryExecutor)
 	// Set a callback for ExecuteQuery. Shouldn't get called because we're not the leader.
-	qe.ExecuteQueryFn = func(query *influxql.Query, database string, chunkSize int) (<-chan *influxql.Result, error) {
+	qe.ExecuteQueryFn = func(query *influxql.Query, database string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error) {
+		done <- struct{}{}
+		return nil, unexpectedErr
+	}
+
+	s.Open()
+	// Trigger service to run CQs.
+	s.RunCh <- &RunRequest{Now: time.Now()}
+	// Expect timeout error because ExecuteQuery callback wasn't called.
+	if err := wait(done, 100*time.Millisecond); err == nil {
+		t.Error(err)
+	}
+	s.Close()
+}
+
+// Test service behavior when the shards are still loading.
+func TestContinuousQueryService_NotReady(t *testing.T) {
+	s := NewTestService(t)
+	// Set RunInterval high so we can test triggering with the RunCh below.
+	s.RunInterval = 10 * time.Second
+	s.Ready = func() bool { return false }
+
+	done := make(chan struct{})
+	qe := s.QueryExecutor.(*QueryExecutor)
+	// Set a callback for ExecuteQuery. Shouldn't get called because the shards aren't loaded.
+	qe.ExecuteQueryFn = func(query *influxql.Query, database string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error) {
 		done <- struct{}{}
 		return nil, unexpectedErr
 	}
@@ -214,7 +278,7 @@ func TestContinuousQueryService_MetaStoreFailsToGetDatabases(t *testing.T) {
 	done := make(chan struct{})
 	qe := s.QueryExecutor.(*QueryExecutor)
 	// Set ExecuteQuery callback, which shouldn't get called because of meta store failure.
-	qe.ExecuteQueryFn = func(query *influxql.Query, database string, chunkSize int) (<-chan *influxql.Result, error) {
+	qe.ExecuteQueryFn = func(query *influxql.Query, database string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error) {
 		done <- struct{}{}
 		return nil, unexpectedErr
 	}
@@ -229,6 +293,116 @@ func TestContinuousQueryService_MetaStoreFailsToGetDatabases(t *testing.T) {
 	s.Close()
 }
 
+// Test ExecuteContinuousQuery recomputes the RESAMPLE FOR window and records the last run.
+func TestExecuteContinuousQuery_ResampleFor(t *testing.T) {
+	s := NewTestService(t)
+	ms := s.MetaStore.(*MetaStore)
+	ms.CreateDatabase("db4", "default")
+	ms.CreateContinuousQuery("db4", "cq4", `CREATE CONTINUOUS QUERY cq4 ON db4 RESAMPLE FOR 10m BEGIN SELECT count(value) INTO cpu_count FROM cpu GROUP BY time(1m) END`)
+	dbis, _ := ms.Databases()
+	dbi := dbis[3]
+	cqi := dbi.ContinuousQueries[0]
+
+	now := time.Date(2000, time.January, 1, 0, 30, 30, 0, time.UTC)
+	callCnt := 0
+	qe := s.QueryExecutor.(*QueryExecutor)
+	qe.ExecuteQueryFn = func(query *influxql.Query, database string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error) {
+		callCnt++
+		min, max := influxql.TimeRange(query.Statements[0].(*influxql.SelectStatement).Condition)
+		if exp := time.Date(2000, time.January, 1, 0, 21, 0, 0, time.UTC); !min.Equal(exp) {
+			t.Errorf("unexpected min time: exp = %s, got = %s", exp, min)
+		}
+		if exp := time.Date(2000, time.January, 1, 0, 30, 59, 999999999, time.UTC); !max.Equal(exp) {
+			t.Errorf("unexpected max time: exp = %s, got = %s", exp, max)
+		}
+		return nil, nil
+	}
+
+	if err := s.ExecuteContinuousQuery(&dbi, &cqi, now); err != nil {
+		t.Fatal(err)
+	} else if callCnt != 1 {
+		t.Fatalf("exp = 1 query, got = %d", callCnt)
+	}
+
+	// The last run should be recorded in the meta store.
+	if di, _ := ms.Database("db4"); di.ContinuousQueries[0].LastRun.IsZero() {
+		t.Fatal("expected last run to be recorded")
+	}
+}
+
+// Test ExecuteContinuousQuery honours RESAMPLE EVERY and the last run from the meta store.
+func TestExecuteContinuousQuery_ResampleEvery(t *testing.T) {
+	s := NewTestService(t)
+	ms := s.MetaStore.(*MetaStore)
+	ms.CreateDatabase("db4", "default")
+	ms.CreateContinuousQuery("db4", "cq4", `CREATE CONTINUOUS QUERY cq4 ON db4 RESAMPLE EVERY 1h BEGIN SELECT count(value) INTO cpu_count FROM cpu GROUP BY time(1m) END`)
+	dbis, _ := ms.Databases()
+	dbi := dbis[3]
+	cqi := dbi.ContinuousQueries[0]
+
+	// A recent run recorded by a previous leader should prevent execution.
+	cqi.LastRun = time.Now().Add(-time.Minute)
+
+	qe := s.QueryExecutor.(*QueryExecutor)
+	qe.ExecuteQueryFn = func(query *influxql.Query, database string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error) {
+		t.Error("unexpected query execution")
+		return nil, nil
+	}
+
+	if err := s.ExecuteContinuousQuery(&dbi, &cqi, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Test ExecuteContinuousQuery records the status of each execution.
+func TestExecuteContinuousQuery_Status(t *testing.T) {
+	s := NewTestService(t)
+	dbis, _ := s.MetaStore.Databases()
+	dbi := dbis[0]
+	cqi := dbi.ContinuousQueries[0]
+
+	// Only want one call to ExecuteQuery per execution.
+	s.Config.RecomputePreviousN = 0
+
+	qe := s.QueryExecutor.(*QueryExecutor)
+	qe.Results = []*influxql.Result{genResult(1, 10)}
+	if err := s.ExecuteContinuousQuery(&dbi, &cqi, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	a := s.Statuses()
+	if len(a) != 1 {
+		t.Fatalf("exp = 1 status, got = %d", len(a))
+	} else if a[0].Database != "db" || a[0].Name != "cq" || a[0].PointsWritten != 10 || a[0].LastError != "" || a[0].LastRun.IsZero() {
+		t.Fatalf("unexpected status: %#v", a[0])
+	}
+
+	// A failed execution should record the error.
+	s.lastRuns[cqi.Name] = time.Time{}
+	qe.Err = expectedErr
+	if err := s.ExecuteContinuousQuery(&dbi, &cqi, time.Now()); err != expectedErr {
+		t.Fatalf("exp = %s, got = %v", expectedErr, err)
+	}
+
+	a = s.Statuses()
+	if len(a) != 1 {
+		t.Fatalf("exp = 1 status, got = %d", len(a))
+	} else if a[0].PointsWritten != 0 || a[0].LastError != expectedErr.Error() {
+		t.Fatalf("unexpected status: %#v", a[0])
+	}
+
+	// The status should be exposed through SHOW CONTINUOUS QUERIES STATUS.
+	e := &StatementExecutor{ContinuousQuerier: s}
+	res := e.ExecuteStatement(&influxql.ShowContinuousQueriesStatusStatement{})
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	} else if len(res.Series) != 1 || res.Series[0].Name != "db" || len(res.Series[0].Values) != 1 {
+		t.Fatalf("unexpected series: %#v", res.Series)
+	} else if row := res.Series[0].Values[0]; row[0] != "cq" || row[4] != expectedErr.Error() {
+		t.Fatalf("unexpected row: %#v", row)
+	}
+}
+
 // Test ExecuteContinuousQuery with invalid queries.
 func TestExecuteContinuousQuery_InvalidQueries(t *testing.T) {
 	s := NewTestService(t)
@@ -284,7 +458,7 @@ func NewTestService(t *testing.T) *Service {
 
 	// Set Logger to write to dev/null so stdout isn't polluted.
 	if !testing.Verbose() {
-		s.Logger = log.New(ioutil.Discard, "", log.LstdFlags)
+		s.Logger = logger.New(ioutil.Discard, "continuous_querier")
 	}
 
 	// Add a couple test databases and CQs.
@@ -403,9 +577,31 @@ func (ms *MetaStore) CreateContinuousQuery(database, name, query string) error {
 	return nil
 }
 
+// SetContinuousQueryLastRun records the last run time of a CQ.
+func (ms *MetaStore) SetContinuousQueryLastRun(database, name string, t time.Time) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if ms.Err != nil {
+		return ms.Err
+	}
+
+	dbi, err := ms.database(database)
+	if err != nil {
+		return err
+	}
+
+	for i := range dbi.ContinuousQueries {
+		if dbi.ContinuousQueries[i].Name == name {
+			dbi.ContinuousQueries[i].LastRun = t
+			return nil
+		}
+	}
+	return fmt.Errorf("continuous query not found: %s", name)
+}
+
 // QueryExecutor is a mock query executor.
 type QueryExecutor struct {
-	ExecuteQueryFn      func(query *influxql.Query, database string, chunkSize int) (<-chan *influxql.Result, error)
+	ExecuteQueryFn      func(query *influxql.Query, database string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error)
 	Results             []*influxql.Result
 	ResultInterval      time.Duration
 	Err                 error
@@ -424,11 +620,11 @@ func NewQueryExecutor(t *testing.T) *QueryExecutor {
 }
 
 // ExecuteQuery returns a channel that the caller can read query results from.
-func (qe *QueryExecutor) ExecuteQuery(query *influxql.Query, database string, chunkSize int) (<-chan *influxql.Result, error) {
+func (qe *QueryExecutor) ExecuteQuery(query *influxql.Query, database string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error) {
 
 	// If the test set a callback, call it.
 	if qe.ExecuteQueryFn != nil {
-		if _, err := qe.ExecuteQueryFn(query, database, chunkSize); err != nil {
+		if _, err := qe.ExecuteQueryFn(query, database, chunkSize, readPref, u); err != nil {
 			return nil, err
 		}
 	}
@@ -0,0 +1,42 @@
+package continuous_querier
+
+import (
+	"fmt"
+
+	"github.com/influxdb/influxdb/influxql"
+)
+
+// StatementExecutor translates InfluxQL queries to continuous query service methods.
+type StatementExecutor struct {
+	ContinuousQuerier interface {
+		Statuses() []Status
+	}
+}
+
+// ExecuteStatement executes continuous query related statements.
+func (e *StatementExecutor) ExecuteStatement(stmt influxql.Statement) *influxql.Result {
+	switch stmt := stmt.(type) {
+	case *influxql.ShowContinuousQueriesStatusStatement:
+		return e.executeShowContinuousQueriesStatusStatement()
+	default:
+		panic(fmt.Sprintf("unsupported statement type: %T", stmt))
+	}
+}
+
+// executeShowContinuousQueriesStatusStatement returns one row per database
+// listing the last execution of each of its continuous queries.
+func (e *StatementExecutor) executeShowContinuousQueriesStatusStatement() *influxql.Result {
+	rows := []*influxql.Row{}
+	var row *influxql.Row
+	for _, st := range e.ContinuousQuerier.Statuses() {
+		if row == nil || row.Name != st.Database {
+			row = &influxql.Row{
+				Name:    st.Database,
+				Columns: []string{"name", "last_run", "duration", "points_written", "last_error"},
+			}
+			rows = append(rows, row)
+		}
+		row.Values = append(row.Values, []interface{}{st.Name, st.LastRun.UTC(), st.Duration.String(), st.PointsWritten, st.LastError})
+	}
+	return &influxql.Result{Series: rows}
+}
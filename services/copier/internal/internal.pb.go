@@ -21,9 +21,49 @@ import math "math"
 var _ = proto.Marshal
 var _ = math.Inf
 
+type Request_Type int32
+
+const (
+	Request_ReadShard   Request_Type = 1
+	Request_CopyShard   Request_Type = 2
+	Request_DeleteShard Request_Type = 3
+)
+
+var Request_Type_name = map[int32]string{
+	1: "ReadShard",
+	2: "CopyShard",
+	3: "DeleteShard",
+}
+var Request_Type_value = map[string]int32{
+	"ReadShard":   1,
+	"CopyShard":   2,
+	"DeleteShard": 3,
+}
+
+func (x Request_Type) Enum() *Request_Type {
+	p := new(Request_Type)
+	*p = x
+	return p
+}
+func (x Request_Type) String() string {
+	return proto.EnumName(Request_Type_name, int32(x))
+}
+func (x *Request_Type) UnmarshalJSON(data []byte) error {
+	value, err := proto.UnmarshalJSONEnum(Request_Type_value, data, "Request_Type")
+	if err != nil {
+		return err
+	}
+	*x = Request_Type(value)
+	return nil
+}
+
 type Request struct {
-	ShardID          *uint64 `protobuf:"varint,1,req" json:"ShardID,omitempty"`
-	XXX_unrecognized []byte  `json:"-"`
+	ShardID          *uint64       `protobuf:"varint,1,req" json:"ShardID,omitempty"`
+	Type             *Request_Type `protobuf:"varint,2,opt,enum=internal.Request_Type" json:"Type,omitempty"`
+	Source           *string       `protobuf:"bytes,3,opt" json:"Source,omitempty"`
+	Database         *string       `protobuf:"bytes,4,opt" json:"Database,omitempty"`
+	RetentionPolicy  *string       `protobuf:"bytes,5,opt" json:"RetentionPolicy,omitempty"`
+	XXX_unrecognized []byte        `json:"-"`
 }
 
 func (m *Request) Reset()         { *m = Request{} }
@@ -37,8 +77,37 @@ func (m *Request) GetShardID() uint64 {
 	return 0
 }
 
+func (m *Request) GetType() Request_Type {
+	if m != nil && m.Type != nil {
+		return *m.Type
+	}
+	return Request_ReadShard
+}
+
+func (m *Request) GetSource() string {
+	if m != nil && m.Source != nil {
+		return *m.Source
+	}
+	return ""
+}
+
+func (m *Request) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *Request) GetRetentionPolicy() string {
+	if m != nil && m.RetentionPolicy != nil {
+		return *m.RetentionPolicy
+	}
+	return ""
+}
+
 type Response struct {
 	Error            *string `protobuf:"bytes,1,opt" json:"Error,omitempty"`
+	SeriesN          *uint64 `protobuf:"varint,2,opt" json:"SeriesN,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
@@ -53,5 +122,13 @@ func (m *Response) GetError() string {
 	return ""
 }
 
+func (m *Response) GetSeriesN() uint64 {
+	if m != nil && m.SeriesN != nil {
+		return *m.SeriesN
+	}
+	return 0
+}
+
 func init() {
+	proto.RegisterEnum("internal.Request_Type", Request_Type_name, Request_Type_value)
 }
@@ -12,6 +12,7 @@ import (
 	"sync"
 
 	"github.com/gogo/protobuf/proto"
+	"github.com/influxdb/influxdb/meta"
 	"github.com/influxdb/influxdb/services/copier/internal"
 	"github.com/influxdb/influxdb/tcp"
 	"github.com/influxdb/influxdb/tsdb"
@@ -24,11 +25,28 @@ const MuxHeader = 6
 
 // Service manages the listener for the endpoint.
 type Service struct {
-	wg  sync.WaitGroup
-	err chan error
+	wg      sync.WaitGroup
+	err     chan error
+	closing chan struct{}
+
+	// Retention policies queued to have their shards replicated.
+	mu      sync.Mutex
+	pending []retentionPolicy
+	queued  map[retentionPolicy]bool
+	signal  chan struct{}
+
+	MetaStore interface {
+		Node(id uint64) (ni *meta.NodeInfo, err error)
+		Nodes() ([]meta.NodeInfo, error)
+		RetentionPolicy(database, name string) (rpi *meta.RetentionPolicyInfo, err error)
+		ShardOwner(shardID uint64) (database, policy string, sgi *meta.ShardGroupInfo)
+		UpdateShardOwners(id uint64, added, removed []uint64) error
+	}
 
 	TSDBStore interface {
 		Shard(id uint64) *tsdb.Shard
+		RestoreShard(database, retentionPolicy string, shardID uint64, r io.Reader) error
+		DeleteShard(shardID uint64) error
 	}
 
 	Listener net.Listener
@@ -39,6 +57,8 @@ type Service struct {
 func NewService() *Service {
 	return &Service{
 		err:    make(chan error),
+		queued: make(map[retentionPolicy]bool),
+		signal: make(chan struct{}, 1),
 		Logger: log.New(os.Stderr, "[copier] ", log.LstdFlags),
 	}
 }
@@ -47,8 +67,10 @@ func NewService() *Service {
 func (s *Service) Open() error {
 	s.Logger.Println("Starting copier service")
 
-	s.wg.Add(1)
+	s.closing = make(chan struct{})
+	s.wg.Add(2)
 	go s.serve()
+	go s.replicateQueued()
 	return nil
 }
 
@@ -57,6 +79,9 @@ func (s *Service) Close() error {
 	if s.Listener != nil {
 		s.Listener.Close()
 	}
+	if s.closing != nil {
+		close(s.closing)
+	}
 	s.wg.Wait()
 	return nil
 }
@@ -104,6 +129,30 @@ func (s *Service) handleConn(conn net.Conn) error {
 		return fmt.Errorf("read request: %s", err)
 	}
 
+	switch req.GetType() {
+	case internal.Request_ReadShard:
+		return s.handleReadShard(conn, req)
+	case internal.Request_CopyShard:
+		err = s.restoreShard(req.GetSource(), req.GetDatabase(), req.GetRetentionPolicy(), req.GetShardID())
+	case internal.Request_DeleteShard:
+		err = s.TSDBStore.DeleteShard(req.GetShardID())
+	default:
+		err = fmt.Errorf("unknown request type: %d", req.GetType())
+	}
+
+	// Write the result of the request.
+	resp := &internal.Response{}
+	if err != nil {
+		resp.Error = proto.String(err.Error())
+	}
+	if err := s.writeResponse(conn, resp); err != nil {
+		return fmt.Errorf("write response: %s", err)
+	}
+	return nil
+}
+
+// handleReadShard streams a local shard to conn.
+func (s *Service) handleReadShard(conn net.Conn, req *internal.Request) error {
 	// Retrieve shard.
 	sh := s.TSDBStore.Shard(req.GetShardID())
 
@@ -117,8 +166,17 @@ func (s *Service) handleConn(conn net.Conn) error {
 		return nil
 	}
 
+	// Count series so the receiver can verify its copy.
+	n, err := sh.SeriesCount()
+	if err != nil {
+		if err := s.writeResponse(conn, &internal.Response{Error: proto.String(err.Error())}); err != nil {
+			return fmt.Errorf("write error response: %s", err)
+		}
+		return nil
+	}
+
 	// Write successful response.
-	if err := s.writeResponse(conn, &internal.Response{}); err != nil {
+	if err := s.writeResponse(conn, &internal.Response{SeriesN: proto.Uint64(uint64(n))}); err != nil {
 		return fmt.Errorf("write response: %s", err)
 	}
 
@@ -130,6 +188,241 @@ func (s *Service) handleConn(conn net.Conn) error {
 	return nil
 }
 
+// restoreShard copies a shard from the copier service at host into the
+// local store and verifies the copy.
+func (s *Service) restoreShard(host, database, policy string, id uint64) error {
+	r, err := NewClient(host).ShardReader(id)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if err := s.TSDBStore.RestoreShard(database, policy, id, r); err != nil {
+		return err
+	}
+
+	// The copy must hold at least the series the source had when it started.
+	n, err := s.TSDBStore.Shard(id).SeriesCount()
+	if err == nil && uint64(n) < r.SeriesN {
+		err = fmt.Errorf("expected at least %d series, got %d", r.SeriesN, n)
+	}
+	if err != nil {
+		s.TSDBStore.DeleteShard(id)
+		return fmt.Errorf("verify shard: %s", err)
+	}
+
+	s.Logger.Printf("copied shard %d from %s", id, host)
+	return nil
+}
+
+// CopyShard copies a shard from one node to another and adds the
+// destination node to the shard's owners.
+func (s *Service) CopyShard(id, from, to uint64) error {
+	return s.transferShard(id, from, to, false)
+}
+
+// MoveShard copies a shard from one node to another, transfers ownership
+// to the destination node and removes the original.
+func (s *Service) MoveShard(id, from, to uint64) error {
+	return s.transferShard(id, from, to, true)
+}
+
+// retentionPolicy identifies a retention policy of a database.
+type retentionPolicy struct {
+	database, name string
+}
+
+// QueueReplicateRetentionPolicy queues the replication of the shards of a
+// retention policy, which ReplicateRetentionPolicy does in the background.
+// A policy already queued isn't queued again.
+func (s *Service) QueueReplicateRetentionPolicy(database, policy string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rp := retentionPolicy{database: database, name: policy}
+	if s.queued[rp] {
+		return nil
+	}
+	s.queued[rp] = true
+	s.pending = append(s.pending, rp)
+
+	select {
+	case s.signal <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// replicateQueued replicates the queued retention policies, in order, until
+// the service is closed.
+func (s *Service) replicateQueued() {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case <-s.closing:
+			return
+		case <-s.signal:
+		}
+
+		for {
+			s.mu.Lock()
+			if len(s.pending) == 0 {
+				s.mu.Unlock()
+				break
+			}
+			rp := s.pending[0]
+			s.pending = s.pending[1:]
+			delete(s.queued, rp)
+			s.mu.Unlock()
+
+			if err := s.ReplicateRetentionPolicy(rp.database, rp.name); err != nil {
+				s.Logger.Printf("error replicating retention policy %s.%s: %s", rp.database, rp.name, err)
+			}
+
+			select {
+			case <-s.closing:
+				return
+			default:
+			}
+		}
+	}
+}
+
+// ReplicateRetentionPolicy copies the shards of the existing shard groups of
+// a retention policy to more nodes until each shard has as many owners as the
+// policy's replication factor, or the cluster has nodes. Shards are copied
+// from their first owner to the nodes owning the fewest of the policy's
+// shards. Every shard is attempted and the first error is returned.
+func (s *Service) ReplicateRetentionPolicy(database, policy string) error {
+	rpi, err := s.MetaStore.RetentionPolicy(database, policy)
+	if err != nil {
+		return err
+	} else if rpi == nil {
+		return meta.ErrRetentionPolicyNotFound
+	}
+
+	nodes, err := s.MetaStore.Nodes()
+	if err != nil {
+		return err
+	}
+
+	// Count the shards each node owns to spread the new replicas.
+	owned := make(map[uint64]int, len(nodes))
+	for _, sgi := range rpi.ShardGroups {
+		if sgi.Deleted() {
+			continue
+		}
+		for _, si := range sgi.Shards {
+			for _, o := range si.Owners {
+				owned[o.NodeID]++
+			}
+		}
+	}
+
+	var first error
+	for _, sgi := range rpi.ShardGroups {
+		if sgi.Deleted() {
+			continue
+		}
+
+		for _, si := range sgi.Shards {
+			if len(si.Owners) == 0 {
+				continue
+			}
+			from := si.Owners[0].NodeID
+			si.Owners = append([]meta.ShardOwner(nil), si.Owners...)
+
+			for n := len(si.Owners); n < rpi.ReplicaN; n++ {
+				// Pick the node owning the fewest shards which doesn't own this one.
+				var to *meta.NodeInfo
+				for i := range nodes {
+					if si.OwnedBy(nodes[i].ID) {
+						continue
+					} else if to == nil || owned[nodes[i].ID] < owned[to.ID] {
+						to = &nodes[i]
+					}
+				}
+				if to == nil {
+					break
+				}
+
+				if err := s.CopyShard(si.ID, from, to.ID); err != nil {
+					s.Logger.Printf("error replicating shard %d to node %d: %s", si.ID, to.ID, err)
+					if first == nil {
+						first = err
+					}
+					break
+				}
+				si.Owners = append(si.Owners, meta.ShardOwner{NodeID: to.ID})
+				owned[to.ID]++
+			}
+		}
+	}
+	return first
+}
+
+func (s *Service) transferShard(id, from, to uint64, move bool) error {
+	database, policy, sgi := s.MetaStore.ShardOwner(id)
+	if sgi == nil {
+		return fmt.Errorf("shard not found: id=%d", id)
+	}
+	for _, si := range sgi.Shards {
+		if si.ID != id {
+			continue
+		} else if !si.OwnedBy(from) {
+			return fmt.Errorf("shard %d is not owned by node %d", id, from)
+		} else if si.OwnedBy(to) {
+			return fmt.Errorf("shard %d is already owned by node %d", id, to)
+		}
+	}
+
+	src, err := s.node(from)
+	if err != nil {
+		return err
+	}
+	dst, err := s.node(to)
+	if err != nil {
+		return err
+	}
+
+	// Have the destination node pull and verify the shard.
+	if err := NewClient(dst.Host).CopyShard(id, src.Host, database, policy); err != nil {
+		return fmt.Errorf("copy shard %d to node %d: %s", id, to, err)
+	}
+
+	// Update ownership so writes and queries are routed to the new copy.
+	var removed []uint64
+	if move {
+		removed = []uint64{from}
+	}
+	if err := s.MetaStore.UpdateShardOwners(id, []uint64{to}, removed); err != nil {
+		return fmt.Errorf("update shard owners: %s", err)
+	}
+
+	if !move {
+		return nil
+	}
+
+	// Remove the original now that the source node no longer owns it.
+	if err := NewClient(src.Host).DeleteShard(id); err != nil {
+		return fmt.Errorf("shard %d moved but not removed from node %d: %s", id, from, err)
+	}
+	s.Logger.Printf("moved shard %d from node %d to node %d", id, from, to)
+	return nil
+}
+
+// node returns a node by id or an error if it doesn't exist.
+func (s *Service) node(id uint64) (*meta.NodeInfo, error) {
+	ni, err := s.MetaStore.Node(id)
+	if err != nil {
+		return nil, err
+	} else if ni == nil {
+		return nil, fmt.Errorf("node not found: id=%d", id)
+	}
+	return ni, nil
+}
+
 // readRequest reads and unmarshals a Request from r.
 func (s *Service) readRequest(r io.Reader) (*internal.Request, error) {
 	// Read request length.
@@ -186,9 +479,17 @@ func NewClient(host string) *Client {
 	}
 }
 
+// ShardReader streams the data of a shard from a copier service.
+type ShardReader struct {
+	io.ReadCloser
+
+	// Number of series in the shard when the stream started.
+	SeriesN uint64
+}
+
 // ShardReader returns a reader for streaming shard data.
-// Returned ReadCloser must be closed by the caller.
-func (c *Client) ShardReader(id uint64) (io.ReadCloser, error) {
+// Returned ShardReader must be closed by the caller.
+func (c *Client) ShardReader(id uint64) (*ShardReader, error) {
 	// Connect to remote server.
 	conn, err := tcp.Dial("tcp", c.host, MuxHeader)
 	if err != nil {
@@ -197,12 +498,14 @@ func (c *Client) ShardReader(id uint64) (io.ReadCloser, error) {
 
 	// Send request to server.
 	if err := c.writeRequest(conn, &internal.Request{ShardID: proto.Uint64(id)}); err != nil {
+		conn.Close()
 		return nil, fmt.Errorf("write request: %s", err)
 	}
 
 	// Read response from the server.
 	resp, err := c.readResponse(conn)
 	if err != nil {
+		conn.Close()
 		return nil, fmt.Errorf("read response: %s", err)
 	}
 
@@ -213,7 +516,48 @@ func (c *Client) ShardReader(id uint64) (io.ReadCloser, error) {
 	}
 
 	// Returning remaining stream for caller to consume.
-	return conn, nil
+	return &ShardReader{ReadCloser: conn, SeriesN: resp.GetSeriesN()}, nil
+}
+
+// CopyShard requests that the remote server copies a shard from the
+// copier service at source into its own store.
+func (c *Client) CopyShard(id uint64, source, database, policy string) error {
+	return c.exec(&internal.Request{
+		Type:            internal.Request_CopyShard.Enum(),
+		ShardID:         proto.Uint64(id),
+		Source:          proto.String(source),
+		Database:        proto.String(database),
+		RetentionPolicy: proto.String(policy),
+	})
+}
+
+// DeleteShard requests that the remote server deletes its copy of a shard.
+func (c *Client) DeleteShard(id uint64) error {
+	return c.exec(&internal.Request{
+		Type:    internal.Request_DeleteShard.Enum(),
+		ShardID: proto.Uint64(id),
+	})
+}
+
+// exec sends req to the remote server and waits for its response.
+func (c *Client) exec(req *internal.Request) error {
+	conn, err := tcp.Dial("tcp", c.host, MuxHeader)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := c.writeRequest(conn, req); err != nil {
+		return fmt.Errorf("write request: %s", err)
+	}
+
+	resp, err := c.readResponse(conn)
+	if err != nil {
+		return fmt.Errorf("read response: %s", err)
+	} else if resp.GetError() != "" {
+		return errors.New(resp.GetError())
+	}
+	return nil
 }
 
 // writeRequest marshals and writes req to w.
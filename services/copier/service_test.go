@@ -9,8 +9,12 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/influxdb/influxdb/meta"
 	"github.com/influxdb/influxdb/services/copier"
 	"github.com/influxdb/influxdb/tcp"
 	"github.com/influxdb/influxdb/tsdb"
@@ -85,6 +89,183 @@ func TestService_handleConn_Error(t *testing.T) {
 	}
 }
 
+// Ensure a shard can be moved between nodes.
+func TestService_MoveShard(t *testing.T) {
+	src := MustOpenService()
+	defer src.Close()
+	dst := MustOpenService()
+	defer dst.Close()
+
+	// Write a point to the shard on the source node.
+	sh := MustOpenShard(123)
+	defer sh.Close()
+	pts, _ := tsdb.ParsePoints([]byte("cpu,host=a value=1"))
+	if err := sh.WritePoints(pts); err != nil {
+		t.Fatal(err)
+	}
+	src.TSDBStore.ShardFn = func(id uint64) *tsdb.Shard { return sh.Shard }
+	var deleted uint64
+	src.TSDBStore.DeleteShardFn = func(id uint64) error {
+		deleted = id
+		return nil
+	}
+
+	// Restore into a real store on the destination node.
+	store := MustOpenStore()
+	defer store.Close()
+	dst.Service.TSDBStore = store
+
+	var ms ServiceMetaStore
+	ms.NodeFn = func(id uint64) (*meta.NodeInfo, error) {
+		switch id {
+		case 1:
+			return &meta.NodeInfo{ID: 1, Host: src.Addr().String()}, nil
+		case 2:
+			return &meta.NodeInfo{ID: 2, Host: dst.Addr().String()}, nil
+		}
+		return nil, nil
+	}
+	ms.ShardOwnerFn = func(shardID uint64) (string, string, *meta.ShardGroupInfo) {
+		return "db0", "rp0", &meta.ShardGroupInfo{
+			Shards: []meta.ShardInfo{{ID: 123, Owners: []meta.ShardOwner{{NodeID: 1}}}},
+		}
+	}
+	var added, removed []uint64
+	ms.UpdateShardOwnersFn = func(id uint64, a, r []uint64) error {
+		added, removed = a, r
+		return nil
+	}
+	src.Service.MetaStore = &ms
+
+	if err := src.MoveShard(123, 1, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	// Verify the shard was copied, ownership transferred and the original removed.
+	if sh := store.Shard(123); sh == nil {
+		t.Fatal("expected shard to be copied")
+	} else if n, err := sh.SeriesCount(); err != nil || n != 1 {
+		t.Fatalf("unexpected series count: %d (%v)", n, err)
+	}
+	if !reflect.DeepEqual(added, []uint64{2}) || !reflect.DeepEqual(removed, []uint64{1}) {
+		t.Fatalf("unexpected owner update: added=%v removed=%v", added, removed)
+	} else if deleted != 123 {
+		t.Fatalf("unexpected deleted shard: %d", deleted)
+	}
+
+	// Moving to a node which doesn't own the shard should fail.
+	if err := src.MoveShard(123, 2, 1); err == nil || err.Error() != `shard 123 is not owned by node 2` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure the shards of a retention policy are copied to match its replication factor.
+func TestService_ReplicateRetentionPolicy(t *testing.T) {
+	src := MustOpenService()
+	defer src.Close()
+	dst := MustOpenService()
+	defer dst.Close()
+
+	sh := MustOpenShard(123)
+	defer sh.Close()
+	pts, _ := tsdb.ParsePoints([]byte("cpu,host=a value=1"))
+	if err := sh.WritePoints(pts); err != nil {
+		t.Fatal(err)
+	}
+	src.TSDBStore.ShardFn = func(id uint64) *tsdb.Shard { return sh.Shard }
+
+	store := MustOpenStore()
+	defer store.Close()
+	dst.Service.TSDBStore = store
+
+	nodes := []meta.NodeInfo{
+		{ID: 1, Host: src.Addr().String()},
+		{ID: 2, Host: dst.Addr().String()},
+	}
+	sgi := meta.ShardGroupInfo{
+		ID:     1,
+		Shards: []meta.ShardInfo{{ID: 123, Owners: []meta.ShardOwner{{NodeID: 1}}}},
+	}
+
+	var ms ServiceMetaStore
+	ms.NodeFn = func(id uint64) (*meta.NodeInfo, error) { return &nodes[id-1], nil }
+	ms.NodesFn = func() ([]meta.NodeInfo, error) { return nodes, nil }
+	ms.RetentionPolicyFn = func(database, name string) (*meta.RetentionPolicyInfo, error) {
+		if database != "db0" || name != "rp0" {
+			t.Fatalf("unexpected policy: %s.%s", database, name)
+		}
+		// The replication factor is over the number of nodes.
+		return &meta.RetentionPolicyInfo{Name: "rp0", ReplicaN: 3, ShardGroups: []meta.ShardGroupInfo{sgi}}, nil
+	}
+	ms.ShardOwnerFn = func(shardID uint64) (string, string, *meta.ShardGroupInfo) {
+		return "db0", "rp0", &sgi
+	}
+	var added [][]uint64
+	ms.UpdateShardOwnersFn = func(id uint64, a, r []uint64) error {
+		added = append(added, a)
+		return nil
+	}
+	src.Service.MetaStore = &ms
+
+	if err := src.ReplicateRetentionPolicy("db0", "rp0"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Verify the shard was copied once, to the only node not owning it.
+	if sh := store.Shard(123); sh == nil {
+		t.Fatal("expected shard to be copied")
+	} else if !reflect.DeepEqual(added, [][]uint64{{2}}) {
+		t.Fatalf("unexpected owner updates: %v", added)
+	}
+}
+
+// Ensure queued retention policies are replicated in the background, once
+// however often they're queued.
+func TestService_QueueReplicateRetentionPolicy(t *testing.T) {
+	s := MustOpenService()
+	defer s.Close()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	var n int32
+	var ms ServiceMetaStore
+	ms.NodesFn = func() ([]meta.NodeInfo, error) { return nil, nil }
+	ms.RetentionPolicyFn = func(database, name string) (*meta.RetentionPolicyInfo, error) {
+		if atomic.AddInt32(&n, 1) == 1 {
+			close(started)
+		}
+		<-release
+		return &meta.RetentionPolicyInfo{Name: name, ReplicaN: 2}, nil
+	}
+	s.Service.MetaStore = &ms
+
+	// Queueing returns while the replication is still running.
+	if err := s.QueueReplicateRetentionPolicy("db0", "rp0"); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	// The policy is queued again once, however often.
+	for i := 0; i < 3; i++ {
+		if err := s.QueueReplicateRetentionPolicy("db0", "rp0"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(release)
+
+	timeout := time.After(5 * time.Second)
+	for atomic.LoadInt32(&n) < 2 {
+		select {
+		case <-timeout:
+			t.Fatal("timed out waiting for replication")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt32(&n); n != 2 {
+		t.Fatalf("unexpected replication count: %d", n)
+	}
+}
+
 // Service represents a test wrapper for copier.Service.
 type Service struct {
 	*copier.Service
@@ -140,13 +321,73 @@ func (s *Service) Close() error {
 // Addr returns the address of the service.
 func (s *Service) Addr() net.Addr { return s.ln.Addr() }
 
+// ServiceMetaStore is a mock that implements copier.Service.MetaStore.
+type ServiceMetaStore struct {
+	NodeFn              func(id uint64) (*meta.NodeInfo, error)
+	NodesFn             func() ([]meta.NodeInfo, error)
+	RetentionPolicyFn   func(database, name string) (*meta.RetentionPolicyInfo, error)
+	ShardOwnerFn        func(shardID uint64) (string, string, *meta.ShardGroupInfo)
+	UpdateShardOwnersFn func(id uint64, added, removed []uint64) error
+}
+
+func (ms *ServiceMetaStore) Node(id uint64) (*meta.NodeInfo, error) { return ms.NodeFn(id) }
+
+func (ms *ServiceMetaStore) Nodes() ([]meta.NodeInfo, error) { return ms.NodesFn() }
+
+func (ms *ServiceMetaStore) RetentionPolicy(database, name string) (*meta.RetentionPolicyInfo, error) {
+	return ms.RetentionPolicyFn(database, name)
+}
+
+func (ms *ServiceMetaStore) ShardOwner(shardID uint64) (string, string, *meta.ShardGroupInfo) {
+	return ms.ShardOwnerFn(shardID)
+}
+
+func (ms *ServiceMetaStore) UpdateShardOwners(id uint64, added, removed []uint64) error {
+	return ms.UpdateShardOwnersFn(id, added, removed)
+}
+
 // ServiceTSDBStore is a mock that implements copier.Service.TSDBStore.
 type ServiceTSDBStore struct {
-	ShardFn func(id uint64) *tsdb.Shard
+	ShardFn        func(id uint64) *tsdb.Shard
+	RestoreShardFn func(database, retentionPolicy string, shardID uint64, r io.Reader) error
+	DeleteShardFn  func(shardID uint64) error
 }
 
 func (ss *ServiceTSDBStore) Shard(id uint64) *tsdb.Shard { return ss.ShardFn(id) }
 
+func (ss *ServiceTSDBStore) RestoreShard(database, retentionPolicy string, shardID uint64, r io.Reader) error {
+	return ss.RestoreShardFn(database, retentionPolicy, shardID, r)
+}
+
+func (ss *ServiceTSDBStore) DeleteShard(shardID uint64) error { return ss.DeleteShardFn(shardID) }
+
+// Store is a test wrapper for tsdb.Store.
+type Store struct {
+	*tsdb.Store
+	path string
+}
+
+// MustOpenStore returns a temporary, opened store.
+func MustOpenStore() *Store {
+	path, err := ioutil.TempDir("", "copier-")
+	if err != nil {
+		panic(err)
+	}
+
+	s := &Store{Store: tsdb.NewStore(filepath.Join(path, "data")), path: path}
+	s.EngineOptions.Config.WALDir = filepath.Join(path, "wal")
+	if err := s.Open(); err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func (s *Store) Close() error {
+	err := s.Store.Close()
+	os.RemoveAll(s.path)
+	return err
+}
+
 // Shard is a test wrapper for tsdb.Shard.
 type Shard struct {
 	*tsdb.Shard
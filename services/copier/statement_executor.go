@@ -0,0 +1,32 @@
+package copier
+
+import (
+	"fmt"
+
+	"github.com/influxdb/influxdb/influxql"
+)
+
+// StatementExecutor translates InfluxQL queries to copier service methods.
+type StatementExecutor struct {
+	Copier interface {
+		CopyShard(id, from, to uint64) error
+		MoveShard(id, from, to uint64) error
+		QueueReplicateRetentionPolicy(database, policy string) error
+	}
+}
+
+// ExecuteStatement executes shard transfer statements. An altered retention
+// policy has its existing shards copied in the background to match its
+// replication factor.
+func (e *StatementExecutor) ExecuteStatement(stmt influxql.Statement) *influxql.Result {
+	switch stmt := stmt.(type) {
+	case *influxql.CopyShardStatement:
+		return &influxql.Result{Err: e.Copier.CopyShard(stmt.ShardID, stmt.From, stmt.To)}
+	case *influxql.MoveShardStatement:
+		return &influxql.Result{Err: e.Copier.MoveShard(stmt.ShardID, stmt.From, stmt.To)}
+	case *influxql.AlterRetentionPolicyStatement:
+		return &influxql.Result{Err: e.Copier.QueueReplicateRetentionPolicy(stmt.Database, stmt.Name)}
+	default:
+		panic(fmt.Sprintf("unsupported statement type: %T", stmt))
+	}
+}
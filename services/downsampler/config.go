@@ -0,0 +1,40 @@
+package downsampler
+
+import (
+	"time"
+
+	"github.com/influxdb/influxdb/toml"
+)
+
+const (
+	// DefaultCheckInterval is the default time between runs of the downsample policies.
+	DefaultCheckInterval = 10 * time.Second
+
+	// DefaultMaxIntervals is the default number of intervals a policy may
+	// catch up on in a single run.
+	DefaultMaxIntervals = 24
+)
+
+// Config represents the configuration for the downsampler service.
+type Config struct {
+	Enabled    bool `toml:"enabled"`
+	LogEnabled bool `toml:"log-enabled"`
+
+	// CheckInterval is the time between runs of the downsample policies.
+	CheckInterval toml.Duration `toml:"check-interval"`
+
+	// MaxIntervals limits the number of intervals a policy catches up on in
+	// a single run, e.g. after the service was stopped. Older intervals are
+	// skipped.
+	MaxIntervals int `toml:"max-intervals-per-run"`
+}
+
+// NewConfig returns a new instance of Config with defaults.
+func NewConfig() Config {
+	return Config{
+		Enabled:       true,
+		LogEnabled:    true,
+		CheckInterval: toml.Duration(DefaultCheckInterval),
+		MaxIntervals:  DefaultMaxIntervals,
+	}
+}
@@ -0,0 +1,33 @@
+package downsampler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/influxdb/influxdb/services/downsampler"
+)
+
+func TestConfig_Parse(t *testing.T) {
+	// Parse configuration.
+	var c downsampler.Config
+	if _, err := toml.Decode(`
+enabled = true
+log-enabled = false
+check-interval = "30s"
+max-intervals-per-run = 5
+`, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate configuration.
+	if c.Enabled != true {
+		t.Fatalf("unexpected enabled: %v", c.Enabled)
+	} else if c.LogEnabled != false {
+		t.Fatalf("unexpected log enabled: %v", c.LogEnabled)
+	} else if time.Duration(c.CheckInterval) != 30*time.Second {
+		t.Fatalf("unexpected check interval: %v", c.CheckInterval)
+	} else if c.MaxIntervals != 5 {
+		t.Fatalf("unexpected max intervals per run: %d", c.MaxIntervals)
+	}
+}
@@ -0,0 +1,371 @@
+// Package downsampler executes the downsample policies of the meta store.
+//
+// A downsample policy aggregates the points of every measurement in a source
+// retention policy into points at a coarser interval in a target retention
+// policy. The measurements and fields are looked up on each run, so new
+// measurements are downsampled without changing the policy.
+package downsampler
+
+import (
+	"expvar"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdb/influxdb"
+	"github.com/influxdb/influxdb/cluster"
+	"github.com/influxdb/influxdb/influxql"
+	"github.com/influxdb/influxdb/logger"
+	"github.com/influxdb/influxdb/meta"
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// Statistics for the downsampler service.
+const (
+	statRunOK         = "run_ok"
+	statRunFail       = "run_fail"
+	statPointsWritten = "points_written"
+)
+
+// queryExecutor is an internal interface to make testing easier.
+type queryExecutor interface {
+	ExecuteQuery(query *influxql.Query, database string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error)
+}
+
+// metaStore is an internal interface to make testing easier.
+type metaStore interface {
+	IsLeader() bool
+	Databases() ([]meta.DatabaseInfo, error)
+	SetDownsamplePolicyProgress(database, name string, t time.Time) error
+}
+
+// pointsWriter is an internal interface to make testing easier.
+type pointsWriter interface {
+	WritePoints(p *cluster.WritePointsRequest) error
+}
+
+// Service executes downsample policies.
+type Service struct {
+	MetaStore     metaStore
+	QueryExecutor queryExecutor
+	PointsWriter  pointsWriter
+	Config        Config
+	Logger        *logger.Logger
+
+	// Ready, if set, returns false while the points of the shards are still
+	// being loaded. The policies aren't run until then, so intervals aren't
+	// downsampled from missing points.
+	Ready func() bool
+
+	statMap *expvar.Map
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewService returns a new instance of Service.
+func NewService(c Config) *Service {
+	return &Service{
+		Config:  c,
+		Logger:  logger.New(os.Stderr, "downsampler"),
+		statMap: influxdb.NewStatistics("downsample", "downsample", nil),
+	}
+}
+
+// Open starts the service.
+func (s *Service) Open() error {
+	if s.stop != nil {
+		return nil
+	}
+	s.Logger.Info("Starting downsampler service", "check_interval", time.Duration(s.Config.CheckInterval))
+
+	s.stop = make(chan struct{})
+	s.wg.Add(1)
+	go s.backgroundLoop(s.stop)
+	return nil
+}
+
+// Close stops the service.
+func (s *Service) Close() error {
+	if s.stop == nil {
+		return nil
+	}
+	close(s.stop)
+	s.wg.Wait()
+	s.stop = nil
+	return nil
+}
+
+// SetLogger sets the internal logger to the logger passed in.
+func (s *Service) SetLogger(l *logger.Logger) {
+	s.Logger = l
+}
+
+// backgroundLoop periodically runs the downsample policies while this node is
+// the leader.
+func (s *Service) backgroundLoop(stop chan struct{}) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(time.Duration(s.Config.CheckInterval))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if s.ready() && s.MetaStore.IsLeader() {
+				s.Run(time.Now())
+			}
+		}
+	}
+}
+
+// ready returns true if the downsample policies can be run.
+func (s *Service) ready() bool {
+	return s.Ready == nil || s.Ready()
+}
+
+// Run executes every downsample policy up to now.
+func (s *Service) Run(now time.Time) {
+	dbs, err := s.MetaStore.Databases()
+	if err != nil {
+		s.Logger.Error("error getting databases", "err", err)
+		return
+	}
+
+	for i := range dbs {
+		for j := range dbs[i].DownsamplePolicies {
+			dpi := &dbs[i].DownsamplePolicies[j]
+			if err := s.ExecuteDownsamplePolicy(dbs[i].Name, dpi, now); err != nil {
+				s.Logger.Error("error executing downsample policy", "database", dbs[i].Name, "name", dpi.Name, "err", err)
+				s.statMap.Add(statRunFail, 1)
+			} else {
+				s.statMap.Add(statRunOK, 1)
+			}
+		}
+	}
+}
+
+// ExecuteDownsamplePolicy downsamples the intervals of a policy completed
+// since its last run. The progress of the policy is only recorded once all
+// of its measurements have been written, so failed intervals are retried.
+func (s *Service) ExecuteDownsamplePolicy(database string, dpi *meta.DownsamplePolicyInfo, now time.Time) error {
+	if dpi.Interval <= 0 {
+		return fmt.Errorf("invalid interval: %s", dpi.Interval)
+	}
+
+	// Only complete intervals are downsampled.
+	end := now.Truncate(dpi.Interval)
+	start := dpi.DownsampledUntil
+	if start.IsZero() {
+		start = end.Add(-dpi.Interval)
+	}
+	if !start.Before(end) {
+		return nil
+	}
+
+	// Skip the intervals beyond what a single run may catch up on.
+	if max := s.Config.MaxIntervals; max > 0 && end.Sub(start) > time.Duration(max)*dpi.Interval {
+		skipped := end.Add(-time.Duration(max) * dpi.Interval)
+		s.Logger.Warn("skipping intervals", "database", database, "name", dpi.Name, "start", start, "end", skipped)
+		start = skipped
+	}
+
+	fields, err := s.fieldKeys(database)
+	if err != nil {
+		return err
+	}
+
+	// Measurements are downsampled in order so partial failures are repeatable.
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var n int
+	for _, name := range names {
+		written, err := s.downsampleMeasurement(database, dpi, name, fields[name], start, end)
+		n += written
+		if err != nil {
+			return fmt.Errorf("measurement %s: %s", name, err)
+		}
+	}
+
+	if s.Config.LogEnabled {
+		s.Logger.Info("downsampled points", "database", database, "name", dpi.Name, "start", start, "end", end, "n", n)
+	}
+	return s.MetaStore.SetDownsamplePolicyProgress(database, dpi.Name, end)
+}
+
+// Field is a field of a measurement to downsample.
+type Field struct {
+	Name string
+
+	// Set for fields which aren't floats or integers. Aggregates that only
+	// apply to numbers are replaced with last() for these fields.
+	NonNumeric bool
+}
+
+// fieldKeys returns the fields of each measurement in a database.
+func (s *Service) fieldKeys(database string) (map[string][]Field, error) {
+	results, err := s.execute(&influxql.ShowFieldKeysStatement{Exact: true}, database)
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string][]Field)
+	for _, result := range results {
+		for _, row := range result.Series {
+			typeIndex := -1
+			for i, c := range row.Columns {
+				if c == "fieldType" {
+					typeIndex = i
+				}
+			}
+
+			for _, v := range row.Values {
+				if len(v) == 0 {
+					continue
+				}
+				k, ok := v[0].(string)
+				if !ok {
+					continue
+				}
+				f := Field{Name: k}
+				if typeIndex >= 0 && typeIndex < len(v) {
+					switch v[typeIndex] {
+					case "string", "boolean":
+						f.NonNumeric = true
+					}
+				}
+				m[row.Name] = append(m[row.Name], f)
+			}
+		}
+	}
+	return m, nil
+}
+
+// downsampleMeasurement aggregates the fields of a measurement between start
+// and end and writes the result to the target retention policy. Returns the
+// number of points written.
+func (s *Service) downsampleMeasurement(database string, dpi *meta.DownsamplePolicyInfo, name string, fields []Field, start, end time.Time) (int, error) {
+	stmt, err := NewSelectStatement(database, dpi, name, fields, start, end)
+	if err != nil {
+		return 0, err
+	}
+
+	results, err := s.execute(stmt, database)
+	if err != nil {
+		return 0, err
+	}
+
+	var points []tsdb.Point
+	for _, result := range results {
+		for _, row := range result.Series {
+			points = append(points, convertRowToPoints(name, row)...)
+		}
+	}
+	if len(points) == 0 {
+		return 0, nil
+	}
+
+	if err := s.PointsWriter.WritePoints(&cluster.WritePointsRequest{
+		Database:         database,
+		RetentionPolicy:  dpi.TargetRetentionPolicy,
+		ConsistencyLevel: cluster.ConsistencyLevelAny,
+		Points:           points,
+	}); err != nil {
+		return 0, err
+	}
+
+	s.statMap.Add(statPointsWritten, int64(len(points)))
+	return len(points), nil
+}
+
+// execute runs a statement and returns its results.
+func (s *Service) execute(stmt influxql.Statement, database string) ([]*influxql.Result, error) {
+	ch, err := s.QueryExecutor.ExecuteQuery(&influxql.Query{Statements: influxql.Statements{stmt}}, database, 0, tsdb.ReadPreferenceNearest, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*influxql.Result
+	for result := range ch {
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// anyTypeAggregates are the aggregates which apply to fields of any type.
+var anyTypeAggregates = map[string]bool{
+	"count": true,
+	"first": true,
+	"last":  true,
+}
+
+// NewSelectStatement returns the statement that downsamples the fields of a
+// measurement between start and end. Non-numeric fields are downsampled with
+// last() unless their aggregate applies to any type.
+func NewSelectStatement(database string, dpi *meta.DownsamplePolicyInfo, name string, fields []Field, start, end time.Time) (*influxql.SelectStatement, error) {
+	exprs := make([]string, len(fields))
+	for i, f := range fields {
+		agg := dpi.Aggregate(f.Name)
+		if f.NonNumeric && !anyTypeAggregates[agg] {
+			agg = "last"
+		}
+		exprs[i] = fmt.Sprintf("%s(%s) AS %s", agg, influxql.QuoteIdent(f.Name), influxql.QuoteIdent(f.Name))
+	}
+
+	q := fmt.Sprintf("SELECT %s FROM %s WHERE time >= '%s' AND time < '%s' GROUP BY time(%s), * fill(none)",
+		strings.Join(exprs, ", "),
+		influxql.QuoteIdent(database, dpi.SourceRetentionPolicy, name),
+		start.UTC().Format(time.RFC3339Nano),
+		end.UTC().Format(time.RFC3339Nano),
+		influxql.FormatDuration(dpi.Interval),
+	)
+	stmt, err := influxql.ParseStatement(q)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.(*influxql.SelectStatement), nil
+}
+
+// convertRowToPoints converts a result row to points. Fields without a value
+// in an interval are left out of its point.
+func convertRowToPoints(name string, row *influxql.Row) []tsdb.Point {
+	timeIndex := -1
+	for i, c := range row.Columns {
+		if c == "time" {
+			timeIndex = i
+		}
+	}
+	if timeIndex == -1 {
+		return nil
+	}
+
+	points := make([]tsdb.Point, 0, len(row.Values))
+	for _, v := range row.Values {
+		t, ok := v[timeIndex].(time.Time)
+		if !ok {
+			continue
+		}
+
+		fields := make(map[string]interface{})
+		for i, c := range row.Columns {
+			if i != timeIndex && v[i] != nil {
+				fields[c] = v[i]
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		points = append(points, tsdb.NewPoint(name, row.Tags, fields, t))
+	}
+	return points
+}
@@ -0,0 +1,259 @@
+package downsampler
+
+import (
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/cluster"
+	"github.com/influxdb/influxdb/influxql"
+	"github.com/influxdb/influxdb/logger"
+	"github.com/influxdb/influxdb/meta"
+	"github.com/influxdb/influxdb/toml"
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// Ensure the downsample query aggregates each field with its aggregate.
+func TestNewSelectStatement(t *testing.T) {
+	dpi := &meta.DownsamplePolicyInfo{
+		SourceRetentionPolicy: "raw",
+		Interval:              time.Hour,
+		Aggregates:            map[string]string{"peak": "max"},
+	}
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	stmt, err := NewSelectStatement("db0", dpi, "cpu", []Field{{Name: "value"}, {Name: "peak"}}, start, start.Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	} else if exp := `SELECT mean(value) AS "value", max(peak) AS "peak" FROM "db0"."raw".cpu WHERE time >= '2000-01-01T00:00:00Z' AND time < '2000-01-01T02:00:00Z' GROUP BY time(1h), * fill(none)`; stmt.String() != exp {
+		t.Fatalf("unexpected statement:\n exp=%s\n got=%s", exp, stmt.String())
+	}
+}
+
+// Ensure non-numeric fields are downsampled with last() unless their
+// aggregate applies to any type.
+func TestNewSelectStatement_NonNumeric(t *testing.T) {
+	dpi := &meta.DownsamplePolicyInfo{
+		SourceRetentionPolicy: "raw",
+		Interval:              time.Hour,
+		Aggregates:            map[string]string{"events": "count"},
+	}
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	fields := []Field{{Name: "value"}, {Name: "status", NonNumeric: true}, {Name: "events", NonNumeric: true}}
+	stmt, err := NewSelectStatement("db0", dpi, "cpu", fields, start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	} else if exp := `SELECT mean(value) AS "value", last("status") AS "status", count(events) AS "events" FROM "db0"."raw".cpu WHERE time >= '2000-01-01T00:00:00Z' AND time < '2000-01-01T01:00:00Z' GROUP BY time(1h), * fill(none)`; stmt.String() != exp {
+		t.Fatalf("unexpected statement:\n exp=%s\n got=%s", exp, stmt.String())
+	}
+}
+
+// Ensure a policy downsamples the intervals completed since its last run and
+// records its progress.
+func TestService_ExecuteDownsamplePolicy(t *testing.T) {
+	s := NewTestService()
+	now := time.Date(2000, 1, 1, 3, 30, 0, 0, time.UTC)
+	dpi := &meta.DownsamplePolicyInfo{
+		Name:                  "ds0",
+		SourceRetentionPolicy: "raw",
+		TargetRetentionPolicy: "week",
+		Interval:              time.Hour,
+		DownsampledUntil:      time.Date(2000, 1, 1, 1, 0, 0, 0, time.UTC),
+	}
+
+	var queries []string
+	s.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query) []*influxql.Result {
+		queries = append(queries, q.String())
+		if _, ok := q.Statements[0].(*influxql.ShowFieldKeysStatement); ok {
+			return []*influxql.Result{{Series: influxql.Rows{
+				{Name: "cpu", Columns: []string{"fieldKey"}, Values: [][]interface{}{{"value"}}},
+			}}}
+		}
+		return []*influxql.Result{{Series: influxql.Rows{
+			{
+				Name:    "cpu",
+				Tags:    map[string]string{"host": "a"},
+				Columns: []string{"time", "value"},
+				Values: [][]interface{}{
+					{time.Date(2000, 1, 1, 1, 0, 0, 0, time.UTC), 1.5},
+					{time.Date(2000, 1, 1, 2, 0, 0, 0, time.UTC), nil},
+				},
+			},
+		}}}
+	}
+
+	var written []string
+	s.PointsWriter.WritePointsFn = func(p *cluster.WritePointsRequest) error {
+		if p.Database != "db0" || p.RetentionPolicy != "week" {
+			t.Fatalf("unexpected destination: %s.%s", p.Database, p.RetentionPolicy)
+		}
+		for _, pt := range p.Points {
+			written = append(written, pt.String())
+		}
+		return nil
+	}
+
+	var progress time.Time
+	s.MetaStore.SetDownsamplePolicyProgressFn = func(database, name string, t time.Time) error {
+		progress = t
+		return nil
+	}
+
+	if err := s.ExecuteDownsamplePolicy("db0", dpi, now); err != nil {
+		t.Fatal(err)
+	} else if len(queries) != 2 || queries[1] != `SELECT mean(value) AS "value" FROM "db0"."raw".cpu WHERE time >= '2000-01-01T01:00:00Z' AND time < '2000-01-01T03:00:00Z' GROUP BY time(1h), * fill(none)` {
+		t.Fatalf("unexpected queries: %v", queries)
+	} else if !reflect.DeepEqual(written, []string{"cpu,host=a value=1.5 946688400000000000"}) {
+		t.Fatalf("unexpected points: %v", written)
+	} else if !progress.Equal(time.Date(2000, 1, 1, 3, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected progress: %s", progress)
+	}
+}
+
+// Ensure the progress of a policy isn't recorded if a write fails and that
+// old intervals are skipped.
+func TestService_ExecuteDownsamplePolicy_Err(t *testing.T) {
+	s := NewTestService()
+	s.Config.MaxIntervals = 2
+	now := time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC)
+	dpi := &meta.DownsamplePolicyInfo{
+		Name:                  "ds0",
+		SourceRetentionPolicy: "raw",
+		TargetRetentionPolicy: "week",
+		Interval:              time.Hour,
+		DownsampledUntil:      time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	var queries []string
+	s.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query) []*influxql.Result {
+		queries = append(queries, q.String())
+		if _, ok := q.Statements[0].(*influxql.ShowFieldKeysStatement); ok {
+			return []*influxql.Result{{Series: influxql.Rows{
+				{Name: "cpu", Columns: []string{"fieldKey"}, Values: [][]interface{}{{"value"}}},
+			}}}
+		}
+		return []*influxql.Result{{Series: influxql.Rows{
+			{Name: "cpu", Columns: []string{"time", "value"}, Values: [][]interface{}{{time.Date(2000, 1, 1, 22, 0, 0, 0, time.UTC), 1.0}}},
+		}}}
+	}
+	s.PointsWriter.WritePointsFn = func(p *cluster.WritePointsRequest) error { return errors.New("marker") }
+	s.MetaStore.SetDownsamplePolicyProgressFn = func(database, name string, until time.Time) error {
+		t.Fatal("unexpected progress")
+		return nil
+	}
+
+	if err := s.ExecuteDownsamplePolicy("db0", dpi, now); err == nil || err.Error() != "measurement cpu: marker" {
+		t.Fatalf("unexpected error: %v", err)
+	} else if len(queries) != 2 || queries[1] != `SELECT mean(value) AS "value" FROM "db0"."raw".cpu WHERE time >= '2000-01-01T22:00:00Z' AND time < '2000-01-02T00:00:00Z' GROUP BY time(1h), * fill(none)` {
+		t.Fatalf("unexpected queries: %v", queries)
+	}
+}
+
+// Ensure a policy that is up to date isn't executed.
+func TestService_ExecuteDownsamplePolicy_UpToDate(t *testing.T) {
+	s := NewTestService()
+	s.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query) []*influxql.Result {
+		t.Fatalf("unexpected query: %s", q)
+		return nil
+	}
+
+	dpi := &meta.DownsamplePolicyInfo{Name: "ds0", Interval: time.Hour, DownsampledUntil: time.Date(2000, 1, 1, 1, 0, 0, 0, time.UTC)}
+	if err := s.ExecuteDownsamplePolicy("db0", dpi, time.Date(2000, 1, 1, 1, 59, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Ensure the policies aren't run until the service is ready.
+func TestService_Ready(t *testing.T) {
+	s := NewTestService()
+	s.Config.CheckInterval = toml.Duration(time.Millisecond)
+	var ready int32
+	s.Ready = func() bool { return atomic.LoadInt32(&ready) == 1 }
+	ran := make(chan struct{}, 1)
+	s.MetaStore.DatabasesFn = func() ([]meta.DatabaseInfo, error) {
+		select {
+		case ran <- struct{}{}:
+		default:
+		}
+		return nil, nil
+	}
+
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	select {
+	case <-ran:
+		t.Fatal("unexpected run before the service is ready")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	atomic.StoreInt32(&ready, 1)
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("expected run once the service is ready")
+	}
+}
+
+// TestService is a service with fake dependencies.
+type TestService struct {
+	*Service
+	MetaStore     *MetaStore
+	QueryExecutor *QueryExecutor
+	PointsWriter  *PointsWriter
+}
+
+// NewTestService returns a new instance of TestService.
+func NewTestService() *TestService {
+	s := &TestService{
+		Service:       NewService(NewConfig()),
+		MetaStore:     &MetaStore{},
+		QueryExecutor: &QueryExecutor{},
+		PointsWriter:  &PointsWriter{},
+	}
+	s.Service.MetaStore = s.MetaStore
+	s.Service.QueryExecutor = s.QueryExecutor
+	s.Service.PointsWriter = s.PointsWriter
+	s.Service.Logger = logger.New(ioutil.Discard, "downsampler")
+	return s
+}
+
+// MetaStore is a mockable meta store.
+type MetaStore struct {
+	DatabasesFn                   func() ([]meta.DatabaseInfo, error)
+	SetDownsamplePolicyProgressFn func(database, name string, t time.Time) error
+}
+
+func (ms *MetaStore) IsLeader() bool                          { return true }
+func (ms *MetaStore) Databases() ([]meta.DatabaseInfo, error) { return ms.DatabasesFn() }
+func (ms *MetaStore) SetDownsamplePolicyProgress(database, name string, t time.Time) error {
+	return ms.SetDownsamplePolicyProgressFn(database, name, t)
+}
+
+// QueryExecutor is a mockable query executor.
+type QueryExecutor struct {
+	ExecuteQueryFn func(q *influxql.Query) []*influxql.Result
+}
+
+func (qe *QueryExecutor) ExecuteQuery(query *influxql.Query, database string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error) {
+	results := qe.ExecuteQueryFn(query)
+	ch := make(chan *influxql.Result, len(results))
+	for _, r := range results {
+		ch <- r
+	}
+	close(ch)
+	return ch, nil
+}
+
+// PointsWriter is a mockable points writer.
+type PointsWriter struct {
+	WritePointsFn func(p *cluster.WritePointsRequest) error
+}
+
+func (pw *PointsWriter) WritePoints(p *cluster.WritePointsRequest) error {
+	return pw.WritePointsFn(p)
+}
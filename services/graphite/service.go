@@ -92,7 +92,9 @@ type Service struct {
 	consistencyLevel cluster.ConsistencyLevel
 
 	batcher *tsdb.PointBatcher
-	parser  *Parser
+
+	mu     sync.RWMutex
+	parser *Parser
 
 	logger  *log.Logger
 	statMap *expvar.Map
@@ -151,6 +153,31 @@ func NewService(c Config) (*Service, error) {
 	return &s, nil
 }
 
+// Reload replaces the templates, default tags and separator used to parse
+// metrics with those of c. The current parser is kept if c is invalid. Other
+// settings of c require a restart and are ignored.
+func (s *Service) Reload(c Config) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	d := c.WithDefaults()
+	parser, err := NewParserWithOptions(Options{
+		Templates:   d.Templates,
+		DefaultTags: d.DefaultTags(),
+		Separator:   d.Separator})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.parser = parser
+	s.mu.Unlock()
+
+	s.logger.Printf("reloaded graphite templates, %d templates", len(d.Templates))
+	return nil
+}
+
 // Open starts the Graphite input processing data.
 func (s *Service) Open() error {
 	s.logger.Printf("Starting graphite service, batch size %d, batch timeout %s", s.batchSize, s.batchTimeout)
@@ -326,7 +353,10 @@ func (s *Service) handleLine(line string) {
 	}
 
 	// Parse it.
-	point, err := s.parser.Parse(line)
+	s.mu.RLock()
+	parser := s.parser
+	s.mu.RUnlock()
+	point, err := parser.Parse(line)
 	if err != nil {
 		s.logger.Printf("unable to parse line: %s", err)
 		s.statMap.Add(statPointsParseFail, 1)
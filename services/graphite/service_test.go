@@ -82,6 +82,66 @@ func Test_ServerGraphiteTCP(t *testing.T) {
 	wg.Wait()
 }
 
+// Ensure templates can be replaced while the service is running.
+func Test_ServerGraphiteTCP_Reload(t *testing.T) {
+	t.Parallel()
+
+	config := graphite.Config{}
+	config.Database = "graphitedb"
+	config.BatchSize = 0 // No batching.
+	config.BatchTimeout = toml.Duration(time.Second)
+	config.BindAddress = ":0"
+
+	service, err := graphite.NewService(config)
+	if err != nil {
+		t.Fatalf("failed to create Graphite service: %s", err.Error())
+	}
+
+	points := make(chan tsdb.Point, 1)
+	service.PointsWriter = &PointsWriter{
+		WritePointsFn: func(req *cluster.WritePointsRequest) error {
+			points <- req.Points[0]
+			return nil
+		},
+	}
+	service.MetaStore = &DatabaseCreator{}
+	if err := service.Open(); err != nil {
+		t.Fatalf("failed to open Graphite service: %s", err.Error())
+	}
+	defer service.Close()
+
+	// An invalid template leaves the parser unchanged.
+	config.Templates = []string{"measurement.host", "measurement.host"}
+	if err := service.Reload(config); err == nil {
+		t.Fatal("expected error")
+	}
+
+	config.Templates = []string{"measurement.host"}
+	if err := service.Reload(config); err != nil {
+		t.Fatal(err)
+	}
+
+	_, port, _ := net.SplitHostPort(service.Addr().String())
+	conn, err := net.Dial("tcp", "127.0.0.1:"+port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = conn.Write([]byte("cpu.server01 23.456 1000000000\n"))
+	conn.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case p := <-points:
+		if p.Name() != "cpu" || p.Tags()["host"] != "server01" {
+			t.Fatalf("unexpected point: %s", p.String())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for point")
+	}
+}
+
 func Test_ServerGraphiteUDP(t *testing.T) {
 	t.Parallel()
 
@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
@@ -27,6 +28,12 @@ type Processor struct {
 	Logger *log.Logger
 }
 
+// QueueStatus describes the hinted handoff queue for a single node.
+type QueueStatus struct {
+	NodeID uint64
+	Size   int64
+}
+
 type ProcessorOptions struct {
 	MaxSize        int64
 	RetryRateLimit int64
@@ -87,6 +94,11 @@ func (p *Processor) addQueue(nodeID uint64) (*queue, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	// The queue may have been added since the caller checked.
+	if q, ok := p.queues[nodeID]; ok {
+		return q, nil
+	}
+
 	path := filepath.Join(p.dir, strconv.FormatUint(nodeID, 10))
 	if err := os.MkdirAll(path, 0700); err != nil {
 		return nil, err
@@ -104,7 +116,9 @@ func (p *Processor) addQueue(nodeID uint64) (*queue, error) {
 }
 
 func (p *Processor) WriteShard(shardID, ownerID uint64, points []tsdb.Point) error {
+	p.mu.RLock()
 	queue, ok := p.queues[ownerID]
+	p.mu.RUnlock()
 	if !ok {
 		var err error
 		if queue, err = p.addQueue(ownerID); err != nil {
@@ -216,3 +230,56 @@ func (p *Processor) PurgeOlderThan(when time.Duration) error {
 	}
 	return nil
 }
+
+// Queues returns the status of the queue for each node, sorted by node ID.
+func (p *Processor) Queues() []QueueStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	a := make([]QueueStatus, 0, len(p.queues))
+	for nodeID, q := range p.queues {
+		a = append(a, QueueStatus{NodeID: nodeID, Size: q.DiskUsage()})
+	}
+	sort.Sort(queueStatuses(a))
+	return a
+}
+
+// Lag returns how long the oldest write queued for a node has been waiting.
+func (p *Processor) Lag(nodeID uint64) time.Duration {
+	p.mu.RLock()
+	q, ok := p.queues[nodeID]
+	p.mu.RUnlock()
+	if !ok {
+		return 0
+	}
+
+	since := q.PendingSince()
+	if since.IsZero() {
+		return 0
+	}
+	return time.Since(since)
+}
+
+// PurgeNode removes all queued writes for a node.
+func (p *Processor) PurgeNode(nodeID uint64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	q, ok := p.queues[nodeID]
+	if !ok {
+		return nil
+	}
+	delete(p.queues, nodeID)
+
+	if err := q.Close(); err != nil {
+		return err
+	}
+	return os.RemoveAll(q.dir)
+}
+
+// queueStatuses represents a list of queue statuses sortable by node ID.
+type queueStatuses []QueueStatus
+
+func (a queueStatuses) Len() int           { return len(a) }
+func (a queueStatuses) Less(i, j int) bool { return a[i].NodeID < a[j].NodeID }
+func (a queueStatuses) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
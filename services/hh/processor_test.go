@@ -2,6 +2,8 @@ package hh
 
 import (
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -78,3 +80,58 @@ func TestProcessorProcess(t *testing.T) {
 	}
 
 }
+
+func TestProcessorPurgeNode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "processor_test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var count int
+	sh := &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []tsdb.Point) error {
+			count += 1
+			return nil
+		},
+	}
+
+	p, err := NewProcessor(dir, sh, ProcessorOptions{MaxSize: 1024})
+	if err != nil {
+		t.Fatalf("PurgeNode() failed to create processor: %v", err)
+	}
+
+	// Queue writes for two nodes.
+	pt := tsdb.NewPoint("cpu", tsdb.Tags{"foo": "bar"}, tsdb.Fields{"value": 1.0}, time.Unix(0, 0))
+	for _, nodeID := range []uint64{2, 1} {
+		if err := p.WriteShard(100, nodeID, []tsdb.Point{pt}); err != nil {
+			t.Fatalf("PurgeNode() failed to write points: %v", err)
+		}
+	}
+
+	queues := p.Queues()
+	if len(queues) != 2 || queues[0].NodeID != 1 || queues[1].NodeID != 2 {
+		t.Fatalf("PurgeNode() queues mismatch: got %v", queues)
+	} else if queues[0].Size == 0 {
+		t.Fatalf("PurgeNode() queue size mismatch: got %v", queues[0].Size)
+	}
+
+	// Purging a node should drop its queued writes from disk.
+	if err := p.PurgeNode(1); err != nil {
+		t.Fatalf("PurgeNode() failed to purge node: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "1")); !os.IsNotExist(err) {
+		t.Fatalf("PurgeNode() queue directory still exists: %v", err)
+	}
+	if queues := p.Queues(); len(queues) != 1 || queues[0].NodeID != 2 {
+		t.Fatalf("PurgeNode() queues mismatch: got %v", queues)
+	}
+
+	// Only the remaining node's write should be sent.
+	if err := p.Process(); err != nil {
+		t.Fatalf("PurgeNode() failed to process: %v", err)
+	}
+	if exp := 1; count != exp {
+		t.Fatalf("PurgeNode() write count mismatch: got %v, exp %v", count, exp)
+	}
+}
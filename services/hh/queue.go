@@ -71,6 +71,10 @@ type queue struct {
 
 	// The segments that exist on disk
 	segments segments
+
+	// When the oldest entry not yet advanced past was appended. Zero when
+	// the queue is empty.
+	pendingSince time.Time
 }
 
 type segments []*segment
@@ -112,7 +116,17 @@ func (l *queue) Open() error {
 	// This advances the segment if the current head is already at the end.
 	_, err = l.head.current()
 	if err == io.EOF {
-		return l.trimHead()
+		if err := l.trimHead(); err != nil {
+			return err
+		}
+	}
+
+	// The append time of existing entries isn't stored so use the time the
+	// head segment was last written to.
+	if !l.empty() {
+		if l.pendingSince, err = l.head.lastModified(); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -173,12 +187,24 @@ func (l *queue) PurgeOlderThan(when time.Time) error {
 		}
 
 		if mod.After(cutoff) || mod.Equal(cutoff) {
-			return nil
+			break
 		}
 		if err := l.trimHead(); err != nil {
 			return err
 		}
 	}
+
+	if l.empty() {
+		l.pendingSince = time.Time{}
+	}
+	return nil
+}
+
+// DiskUsage returns the total size on disk used by the queue
+func (l *queue) DiskUsage() int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.diskUsage()
 }
 
 // diskUsage returns the total size on disk used by the queue
@@ -286,11 +312,32 @@ func (l *queue) Append(b []byte) error {
 			return err
 		}
 		l.tail = segment
-		return l.tail.append(b)
+		if err := l.tail.append(b); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	if l.pendingSince.IsZero() {
+		l.pendingSince = time.Now()
 	}
 	return nil
 }
 
+// PendingSince returns when the oldest entry in the queue was appended or
+// the zero time if the queue is empty.
+func (l *queue) PendingSince() time.Time {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.pendingSince
+}
+
+// empty returns true if every entry in the queue has been advanced past.
+func (l *queue) empty() bool {
+	return l.head == l.tail && l.head.empty()
+}
+
 // Current returns the current byte slice at the head of the queue
 func (l *queue) Current() ([]byte, error) {
 	if l.head == nil {
@@ -315,6 +362,9 @@ func (l *queue) Advance() error {
 		}
 	}
 
+	if l.empty() {
+		l.pendingSince = time.Time{}
+	}
 	return nil
 }
 
@@ -565,6 +615,13 @@ func (l *segment) close() error {
 	return nil
 }
 
+// empty returns true if the current value pointer is at the end of the segment.
+func (l *segment) empty() bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return int64(l.pos) == l.size-footerSize
+}
+
 func (l *segment) lastModified() (time.Time, error) {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
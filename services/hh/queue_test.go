@@ -325,3 +325,50 @@ func TestPurgeQueue(t *testing.T) {
 	}
 
 }
+
+// Ensure the queue tracks when its oldest pending entry was appended.
+func TestQueuePendingSince(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hh_queue")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	q, err := newQueue(dir, 1024)
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+	if err := q.Open(); err != nil {
+		t.Fatalf("failed to open queue: %v", err)
+	}
+	defer q.Close()
+
+	if since := q.PendingSince(); !since.IsZero() {
+		t.Fatalf("unexpected pending time for empty queue: %v", since)
+	}
+
+	before := time.Now()
+	for _, b := range []string{"one", "two"} {
+		if err := q.Append([]byte(b)); err != nil {
+			t.Fatalf("Queue.Append failed: %v", err)
+		}
+	}
+	since := q.PendingSince()
+	if since.Before(before) {
+		t.Fatalf("unexpected pending time: %v", since)
+	}
+
+	// Advancing past the first entry leaves the second pending.
+	if err := q.Advance(); err != nil {
+		t.Fatalf("Queue.Advance failed: %v", err)
+	} else if !q.PendingSince().Equal(since) {
+		t.Fatalf("unexpected pending time: got %v, exp %v", q.PendingSince(), since)
+	}
+
+	// An empty queue has nothing pending.
+	if err := q.Advance(); err != nil {
+		t.Fatalf("Queue.Advance failed: %v", err)
+	} else if since := q.PendingSince(); !since.IsZero() {
+		t.Fatalf("unexpected pending time for drained queue: %v", since)
+	}
+}
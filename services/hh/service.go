@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/influxdb/influxdb/meta"
 	"github.com/influxdb/influxdb/tsdb"
 )
 
@@ -23,10 +24,18 @@ type Service struct {
 
 	ShardWriter shardWriter
 
+	// MetaStore is used to find queues for nodes that have left the cluster.
+	MetaStore interface {
+		Node(id uint64) (ni *meta.NodeInfo, err error)
+	}
+
 	HintedHandoff interface {
 		WriteShard(shardID, ownerID uint64, points []tsdb.Point) error
 		Process() error
 		PurgeOlderThan(when time.Duration) error
+		PurgeNode(nodeID uint64) error
+		Queues() []QueueStatus
+		Lag(nodeID uint64) time.Duration
 	}
 }
 
@@ -125,12 +134,56 @@ func (s *Service) expireWrites() {
 			if err := s.HintedHandoff.PurgeOlderThan(time.Duration(s.cfg.MaxAge)); err != nil {
 				s.Logger.Printf("purge write failed: %v", err)
 			}
+			if err := s.purgeWrites(); err != nil {
+				s.Logger.Printf("purge inactive nodes failed: %v", err)
+			}
 		}
 	}
 }
 
 // purgeWrites will cause the handoff queues to remove writes that are no longer
 // valid.  e.g. queued writes for a node that has been removed
-func (s *Service) purgeWrites() {
-	panic("not implemented")
+func (s *Service) purgeWrites() error {
+	if s.MetaStore == nil {
+		return nil
+	}
+
+	for _, q := range s.HintedHandoff.Queues() {
+		ni, err := s.MetaStore.Node(q.NodeID)
+		if err != nil {
+			return err
+		} else if ni != nil {
+			continue
+		}
+
+		if err := s.HintedHandoff.PurgeNode(q.NodeID); err != nil {
+			return err
+		}
+		s.Logger.Printf("purged %d bytes of queued writes for removed node %d", q.Size, q.NodeID)
+	}
+	return nil
+}
+
+// Queues returns the status of the queue for each node.
+func (s *Service) Queues() []QueueStatus {
+	return s.HintedHandoff.Queues()
+}
+
+// Lag returns how long writes to a node have been waiting in its queue.
+func (s *Service) Lag(nodeID uint64) time.Duration {
+	return s.HintedHandoff.Lag(nodeID)
+}
+
+// Purge removes all queued writes for a node. A node ID of zero purges all queues.
+func (s *Service) Purge(nodeID uint64) error {
+	if nodeID != 0 {
+		return s.HintedHandoff.PurgeNode(nodeID)
+	}
+
+	for _, q := range s.HintedHandoff.Queues() {
+		if err := s.HintedHandoff.PurgeNode(q.NodeID); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,35 @@
+package hh
+
+import (
+	"fmt"
+
+	"github.com/influxdb/influxdb/influxql"
+)
+
+// StatementExecutor translates InfluxQL queries to hinted handoff service methods.
+type StatementExecutor struct {
+	HintedHandoff interface {
+		Queues() []QueueStatus
+		Purge(nodeID uint64) error
+	}
+}
+
+// ExecuteStatement executes hinted handoff related statements.
+func (e *StatementExecutor) ExecuteStatement(stmt influxql.Statement) *influxql.Result {
+	switch stmt := stmt.(type) {
+	case *influxql.ShowHintedHandoffStatement:
+		return e.executeShowHintedHandoffStatement()
+	case *influxql.DropHintedHandoffStatement:
+		return &influxql.Result{Err: e.HintedHandoff.Purge(stmt.NodeID)}
+	default:
+		panic(fmt.Sprintf("unsupported statement type: %T", stmt))
+	}
+}
+
+func (e *StatementExecutor) executeShowHintedHandoffStatement() *influxql.Result {
+	row := &influxql.Row{Columns: []string{"node_id", "size"}}
+	for _, q := range e.HintedHandoff.Queues() {
+		row.Values = append(row.Values, []interface{}{q.NodeID, q.Size})
+	}
+	return &influxql.Result{Series: []*influxql.Row{row}}
+}
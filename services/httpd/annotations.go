@@ -0,0 +1,264 @@
+package httpd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdb/influxdb/cluster"
+	"github.com/influxdb/influxdb/influxql"
+	"github.com/influxdb/influxdb/meta"
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// AnnotationsMeasurement is the measurement annotations are stored in. The
+// title and text of an annotation are fields and its tags are tags, so they
+// can also be queried with InfluxQL.
+const AnnotationsMeasurement = "annotations"
+
+// Annotation is an event, such as a deployment or an incident, to overlay on
+// graphs.
+type Annotation struct {
+	Time  time.Time         `json:"time"`
+	Title string            `json:"title"`
+	Text  string            `json:"text,omitempty"`
+	Tags  map[string]string `json:"tags,omitempty"`
+}
+
+// annotations sorts annotations by time.
+type annotations []Annotation
+
+func (a annotations) Len() int           { return len(a) }
+func (a annotations) Less(i, j int) bool { return a[i].Time.Before(a[j].Time) }
+func (a annotations) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
+// serveAnnotations writes the annotations POSTed as a JSON array to the
+// database in "db", or returns the annotations of the database which are
+// between "start" and "end" and have the tags in "tag", as "key:value".
+func (h *Handler) serveAnnotations(w http.ResponseWriter, r *http.Request, user *meta.UserInfo) {
+	q := r.URL.Query()
+	pretty := q.Get("pretty") == "true"
+
+	db := q.Get("db")
+	if db == "" {
+		httpError(w, "database is required", pretty, http.StatusBadRequest)
+		return
+	}
+	if di, err := h.MetaStore.Database(db); err != nil {
+		httpError(w, fmt.Sprintf("metastore database error: %s", err), pretty, http.StatusInternalServerError)
+		return
+	} else if di == nil {
+		httpError(w, fmt.Sprintf("database not found: %q", db), pretty, http.StatusNotFound)
+		return
+	}
+
+	if r.Method == "POST" {
+		h.writeAnnotations(w, r, user, db, pretty)
+		return
+	}
+	h.queryAnnotations(w, r, user, db, pretty)
+}
+
+// writeAnnotations writes the annotations in the body of r to db.
+func (h *Handler) writeAnnotations(w http.ResponseWriter, r *http.Request, user *meta.UserInfo, db string, pretty bool) {
+	if h.requireAuthentication && user == nil {
+		httpError(w, fmt.Sprintf("user is required to write to database %q", db), pretty, http.StatusUnauthorized)
+		return
+	}
+	if h.requireAuthentication && !user.Authorize(influxql.WritePrivilege, db) {
+		httpError(w, fmt.Sprintf("%q user is not authorized to write to database %q", user.Name, db), pretty, http.StatusUnauthorized)
+		return
+	}
+
+	var a []Annotation
+	if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+		httpError(w, "error parsing annotations: "+err.Error(), pretty, http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now().UTC()
+	points := make([]tsdb.Point, 0, len(a))
+	for _, e := range a {
+		if e.Title == "" {
+			httpError(w, "annotation title is required", pretty, http.StatusBadRequest)
+			return
+		}
+		if e.Time.IsZero() {
+			e.Time = now
+		}
+
+		fields := tsdb.Fields{"title": e.Title}
+		if e.Text != "" {
+			fields["text"] = e.Text
+		}
+		points = append(points, tsdb.NewPoint(AnnotationsMeasurement, tsdb.Tags(e.Tags), fields, e.Time))
+	}
+
+	consistency, err := parseConsistencyLevel(r)
+	if err != nil {
+		httpError(w, err.Error(), pretty, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.PointsWriter.WritePoints(&cluster.WritePointsRequest{
+		Database:         db,
+		RetentionPolicy:  r.URL.Query().Get("rp"),
+		ConsistencyLevel: consistency,
+		Points:           points,
+	}); err != nil {
+		h.statMap.Add(statPointsWrittenFail, int64(len(points)))
+		httpError(w, err.Error(), pretty, http.StatusInternalServerError)
+		return
+	}
+	h.statMap.Add(statPointsWrittenOK, int64(len(points)))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// queryAnnotations returns the annotations of db matching the query of r,
+// sorted by time.
+func (h *Handler) queryAnnotations(w http.ResponseWriter, r *http.Request, user *meta.UserInfo, db string, pretty bool) {
+	q := r.URL.Query()
+
+	query, err := annotationsQuery(q.Get("rp"), q.Get("start"), q.Get("end"), q["tag"])
+	if err != nil {
+		httpError(w, err.Error(), pretty, http.StatusBadRequest)
+		return
+	}
+
+	if h.requireAuthentication {
+		if err := h.QueryExecutor.Authorize(user, query, db); err != nil {
+			httpError(w, "error authorizing query: "+err.Error(), pretty, http.StatusUnauthorized)
+			return
+		}
+	}
+
+	results, err := h.QueryExecutor.ExecuteQuery(query, db, DefaultChunkSize, tsdb.ReadPreferenceNearest, user)
+	if err != nil {
+		httpError(w, err.Error(), pretty, http.StatusInternalServerError)
+		return
+	}
+
+	a := make(annotations, 0)
+	for res := range results {
+		if res == nil {
+			continue
+		} else if res.Err != nil {
+			// Read the rest of the results so the query can finish.
+			for _ = range results {
+			}
+			httpError(w, res.Err.Error(), pretty, http.StatusInternalServerError)
+			return
+		}
+
+		for _, row := range res.Series {
+			a = append(a, rowAnnotations(row)...)
+		}
+	}
+	sort.Stable(a)
+
+	w.Header().Add("content-type", "application/json")
+	w.Write(MarshalJSON(a, pretty))
+}
+
+// annotationsQuery returns the query of the annotations between start and
+// end, if set, as RFC3339 times, which have the tags, as "key:value".
+func annotationsQuery(rp, start, end string, tags []string) (*influxql.Query, error) {
+	var cond influxql.Expr
+	and := func(expr influxql.Expr) {
+		if cond == nil {
+			cond = expr
+			return
+		}
+		cond = &influxql.BinaryExpr{Op: influxql.AND, LHS: cond, RHS: expr}
+	}
+
+	for _, b := range []struct {
+		name, value string
+		op          influxql.Token
+	}{
+		{name: "start", value: start, op: influxql.GTE},
+		{name: "end", value: end, op: influxql.LT},
+	} {
+		if b.value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, b.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %q", b.name, b.value)
+		}
+		and(&influxql.BinaryExpr{
+			Op:  b.op,
+			LHS: &influxql.VarRef{Val: "time"},
+			RHS: &influxql.TimeLiteral{Val: t},
+		})
+	}
+
+	for _, tag := range tags {
+		i := strings.Index(tag, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid tag: %q", tag)
+		}
+		and(&influxql.BinaryExpr{
+			Op:  influxql.EQ,
+			LHS: &influxql.VarRef{Val: tag[:i]},
+			RHS: &influxql.StringLiteral{Val: tag[i+1:]},
+		})
+	}
+
+	return &influxql.Query{Statements: influxql.Statements{
+		&influxql.SelectStatement{
+			Fields: influxql.Fields{
+				{Expr: &influxql.VarRef{Val: "title"}},
+				{Expr: &influxql.VarRef{Val: "text"}},
+			},
+			Sources:    influxql.Sources{&influxql.Measurement{RetentionPolicy: rp, Name: AnnotationsMeasurement}},
+			Condition:  cond,
+			Dimensions: influxql.Dimensions{{Expr: &influxql.Wildcard{}}},
+		},
+	}}, nil
+}
+
+// rowAnnotations returns the annotations of a row of the annotations query.
+func rowAnnotations(row *influxql.Row) []Annotation {
+	// Series without a tag have an empty value for it.
+	var tags map[string]string
+	for k, v := range row.Tags {
+		if v == "" {
+			continue
+		}
+		if tags == nil {
+			tags = make(map[string]string)
+		}
+		tags[k] = v
+	}
+
+	a := make([]Annotation, 0, len(row.Values))
+	for _, values := range row.Values {
+		var e Annotation
+		for i, col := range row.Columns {
+			if i >= len(values) {
+				break
+			}
+			switch v := values[i].(type) {
+			case time.Time:
+				if col == "time" {
+					e.Time = v
+				}
+			case string:
+				switch col {
+				case "title":
+					e.Title = v
+				case "text":
+					e.Text = v
+				}
+			}
+		}
+		e.Tags = tags
+		a = append(a, e)
+	}
+	return a
+}
@@ -0,0 +1,104 @@
+package httpd_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/cluster"
+	"github.com/influxdb/influxdb/influxql"
+	"github.com/influxdb/influxdb/meta"
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// Ensure the handler writes annotations as points of the annotations measurement.
+func TestHandler_Annotations_Write(t *testing.T) {
+	h := NewHandler(false)
+	h.MetaStore.DatabaseFn = func(name string) (*meta.DatabaseInfo, error) {
+		return &meta.DatabaseInfo{Name: name}, nil
+	}
+
+	var req *cluster.WritePointsRequest
+	h.PointsWriter.WritePointsFn = func(p *cluster.WritePointsRequest) error {
+		req = p
+		return nil
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("POST", "/annotations?db=foo&rp=bar", strings.NewReader(`[{"time":"2015-10-01T00:00:00Z","title":"Deploy","text":"v1.2","tags":{"service":"api"}}]`)))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	} else if req.Database != "foo" || req.RetentionPolicy != "bar" || len(req.Points) != 1 {
+		t.Fatalf("unexpected request: %#v", req)
+	}
+
+	p := req.Points[0]
+	if p.Name() != "annotations" {
+		t.Fatalf("unexpected name: %s", p.Name())
+	} else if !reflect.DeepEqual(p.Tags(), tsdb.Tags{"service": "api"}) {
+		t.Fatalf("unexpected tags: %v", p.Tags())
+	} else if !reflect.DeepEqual(p.Fields(), tsdb.Fields{"title": "Deploy", "text": "v1.2"}) {
+		t.Fatalf("unexpected fields: %v", p.Fields())
+	} else if !p.Time().Equal(time.Date(2015, 10, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected time: %s", p.Time())
+	}
+
+	// Annotations require a title.
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("POST", "/annotations?db=foo", strings.NewReader(`[{"text":"v1.2"}]`)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+}
+
+// Ensure the handler queries annotations by time range and tags.
+func TestHandler_Annotations_Query(t *testing.T) {
+	h := NewHandler(false)
+	h.MetaStore.DatabaseFn = func(name string) (*meta.DatabaseInfo, error) {
+		return &meta.DatabaseInfo{Name: name}, nil
+	}
+
+	t0 := time.Date(2015, 10, 1, 0, 0, 0, 0, time.UTC)
+	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error) {
+		if db != "foo" {
+			t.Fatalf("unexpected db: %s", db)
+		} else if q.String() != `SELECT title, text FROM annotations WHERE time >= '2015-10-01T00:00:00Z' AND service = 'api' GROUP BY *` {
+			t.Fatalf("unexpected query: %s", q.String())
+		}
+		return NewResultChan(&influxql.Result{StatementID: 0, Series: influxql.Rows{
+			{Name: "annotations", Tags: map[string]string{"host": "", "service": "api"}, Columns: []string{"time", "title", "text"}, Values: [][]interface{}{
+				{t0.Add(time.Hour), "Rollback", nil},
+			}},
+			{Name: "annotations", Tags: map[string]string{"host": "a", "service": "api"}, Columns: []string{"time", "title", "text"}, Values: [][]interface{}{
+				{t0, "Deploy", "v1.2"},
+			}},
+		}}), nil
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/annotations?db=foo&start=2015-10-01T00:00:00Z&tag=service:api", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	} else if w.Body.String() != `[{"time":"2015-10-01T00:00:00Z","title":"Deploy","text":"v1.2","tags":{"host":"a","service":"api"}},{"time":"2015-10-01T01:00:00Z","title":"Rollback","tags":{"service":"api"}}]` {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+}
+
+// Ensure the handler rejects an invalid time range of annotations.
+func TestHandler_Annotations_ErrInvalidTime(t *testing.T) {
+	h := NewHandler(false)
+	h.MetaStore.DatabaseFn = func(name string) (*meta.DatabaseInfo, error) {
+		return &meta.DatabaseInfo{Name: name}, nil
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/annotations?db=foo&end=yesterday", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if w.Body.String() != `{"error":"invalid end: \"yesterday\""}` {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+}
@@ -0,0 +1,185 @@
+package httpd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/influxdb/influxdb/influxql"
+	"github.com/influxdb/influxdb/meta"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Authentication providers.
+const (
+	// AuthProviderMeta authenticates users stored in the meta store.
+	AuthProviderMeta = "meta"
+
+	// AuthProviderLDAP authenticates users against an LDAP directory.
+	AuthProviderLDAP = "ldap"
+
+	// AuthProviderFile authenticates users listed in a static file.
+	AuthProviderFile = "file"
+)
+
+// Authenticator authenticates users by name and password. The privileges of
+// the returned user are the ones its queries are authorized against.
+type Authenticator interface {
+	Authenticate(username, password string) (*meta.UserInfo, error)
+}
+
+// GroupConfig maps a group of an external authentication provider to
+// InfluxDB privileges. Privileges maps a database to "read", "write" or "all".
+type GroupConfig struct {
+	Name       string            `toml:"name"`
+	Admin      bool              `toml:"admin"`
+	Privileges map[string]string `toml:"privileges"`
+}
+
+// NewAuthenticator returns the authenticator of the provider configured by c.
+// Returns nil for the meta store, which the handler uses by default.
+func NewAuthenticator(c Config) (Authenticator, error) {
+	switch c.AuthProvider {
+	case "", AuthProviderMeta:
+		return nil, nil
+	case AuthProviderLDAP:
+		return NewLDAPAuthenticator(c.LDAP, c.Groups)
+	case AuthProviderFile:
+		return NewFileAuthenticator(c.AuthFile, c.Groups)
+	default:
+		return nil, fmt.Errorf("unknown auth provider: %q", c.AuthProvider)
+	}
+}
+
+// groupMapping maps group names to the privileges of their members.
+type groupMapping map[string]GroupConfig
+
+// newGroupMapping returns the mapping of groups. Returns an error if a
+// privilege is invalid.
+func newGroupMapping(groups []GroupConfig) (groupMapping, error) {
+	m := make(groupMapping, len(groups))
+	for _, g := range groups {
+		if g.Name == "" {
+			return nil, fmt.Errorf("group name required")
+		}
+		for db, p := range g.Privileges {
+			if _, err := parsePrivilege(p); err != nil {
+				return nil, fmt.Errorf("group %s: database %s: %s", g.Name, db, err)
+			}
+		}
+		m[g.Name] = g
+	}
+	return m, nil
+}
+
+// userInfo returns a user with the combined privileges of groups.
+// Groups which are not mapped are ignored.
+func (m groupMapping) userInfo(name string, groups []string) *meta.UserInfo {
+	u := &meta.UserInfo{Name: name, Privileges: make(map[string]influxql.Privilege)}
+	for _, name := range groups {
+		g, ok := m[name]
+		if !ok {
+			continue
+		}
+		if g.Admin {
+			u.Admin = true
+		}
+		for db, s := range g.Privileges {
+			p, _ := parsePrivilege(s)
+			u.Privileges[db] = mergePrivileges(u.Privileges[db], p)
+		}
+	}
+	return u
+}
+
+// parsePrivilege returns the privilege named by s.
+func parsePrivilege(s string) (influxql.Privilege, error) {
+	switch strings.ToLower(s) {
+	case "read":
+		return influxql.ReadPrivilege, nil
+	case "write":
+		return influxql.WritePrivilege, nil
+	case "all":
+		return influxql.AllPrivileges, nil
+	}
+	return influxql.NoPrivileges, fmt.Errorf("invalid privilege: %q", s)
+}
+
+// mergePrivileges returns the privilege granting both a and b.
+func mergePrivileges(a, b influxql.Privilege) influxql.Privilege {
+	switch {
+	case a == b || b == influxql.NoPrivileges:
+		return a
+	case a == influxql.NoPrivileges:
+		return b
+	}
+	return influxql.AllPrivileges
+}
+
+// FileAuthenticator authenticates users listed in a static file. Each line of
+// the file holds a user name, a bcrypt hash of its password, and an optional
+// comma-separated list of groups, separated by colons:
+//
+//	susy:$2a$10$...:admins,telegraf-writers
+//
+// Blank lines and lines starting with # are ignored.
+type FileAuthenticator struct {
+	users  map[string]fileUser
+	groups groupMapping
+}
+
+type fileUser struct {
+	hash   []byte
+	groups []string
+}
+
+// NewFileAuthenticator returns an authenticator of the users in the file at
+// path, whose groups are mapped to privileges by groups.
+func NewFileAuthenticator(path string, groups []GroupConfig) (*FileAuthenticator, error) {
+	m, err := newGroupMapping(groups)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	a := &FileAuthenticator{users: make(map[string]fileUser), groups: m}
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.SplitN(line, ":", 3)
+		if len(fields) < 2 || fields[0] == "" || fields[1] == "" {
+			return nil, fmt.Errorf("%s:%d: expected name:hash[:groups]", path, n)
+		}
+		u := fileUser{hash: []byte(fields[1])}
+		if len(fields) == 3 && fields[2] != "" {
+			u.groups = strings.Split(fields[2], ",")
+		}
+		a.users[fields[0]] = u
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Authenticate returns the user with a matching name and password.
+func (a *FileAuthenticator) Authenticate(username, password string) (*meta.UserInfo, error) {
+	u, ok := a.users[username]
+	if !ok {
+		return nil, meta.ErrAuthenticate
+	}
+	if err := bcrypt.CompareHashAndPassword(u.hash, []byte(password)); err != nil {
+		return nil, meta.ErrAuthenticate
+	}
+	return a.groups.userInfo(username, u.groups), nil
+}
@@ -0,0 +1,115 @@
+package httpd_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/influxdb/influxdb/influxql"
+	"github.com/influxdb/influxdb/meta"
+	"github.com/influxdb/influxdb/services/httpd"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Ensure the file authenticator maps the groups of its users to privileges.
+func TestFileAuthenticator_Authenticate(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("pass"), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := MustWriteTempFile(fmt.Sprintf(`
+# users
+susy:%s:readers,writers,unknown
+bob:%s
+`, hash, hash))
+	defer os.Remove(path)
+
+	a, err := httpd.NewFileAuthenticator(path, []httpd.GroupConfig{
+		{Name: "admins", Admin: true},
+		{Name: "readers", Privileges: map[string]string{"db0": "read", "db1": "read"}},
+		{Name: "writers", Privileges: map[string]string{"db0": "write"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if u, err := a.Authenticate("susy", "pass"); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(u, &meta.UserInfo{
+		Name: "susy",
+		Privileges: map[string]influxql.Privilege{
+			"db0": influxql.AllPrivileges,
+			"db1": influxql.ReadPrivilege,
+		},
+	}) {
+		t.Fatalf("unexpected user: %#v", u)
+	}
+
+	// Users without groups have no privileges.
+	if u, err := a.Authenticate("bob", "pass"); err != nil {
+		t.Fatal(err)
+	} else if u.Admin || len(u.Privileges) != 0 {
+		t.Fatalf("unexpected user: %#v", u)
+	}
+
+	if _, err := a.Authenticate("susy", "bad"); err != meta.ErrAuthenticate {
+		t.Fatalf("unexpected error: %v", err)
+	} else if _, err := a.Authenticate("jim", "pass"); err != meta.ErrAuthenticate {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure invalid user files and group privileges are rejected.
+func TestFileAuthenticator_Err(t *testing.T) {
+	path := MustWriteTempFile("susy\n")
+	defer os.Remove(path)
+	if _, err := httpd.NewFileAuthenticator(path, nil); err == nil || err.Error() != path+":1: expected name:hash[:groups]" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	groups := []httpd.GroupConfig{{Name: "readers", Privileges: map[string]string{"db0": "reed"}}}
+	if _, err := httpd.NewFileAuthenticator(path, groups); err == nil || err.Error() != `group readers: database db0: invalid privilege: "reed"` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure the configured auth provider is returned.
+func TestNewAuthenticator(t *testing.T) {
+	c := httpd.NewConfig()
+	if a, err := httpd.NewAuthenticator(c); err != nil || a != nil {
+		t.Fatalf("unexpected authenticator: %v, %v", a, err)
+	}
+
+	c.AuthProvider = httpd.AuthProviderLDAP
+	c.LDAP.URL = "ldap://ldap.example.com"
+	if a, err := httpd.NewAuthenticator(c); err != nil {
+		t.Fatal(err)
+	} else if _, ok := a.(*httpd.LDAPAuthenticator); !ok {
+		t.Fatalf("unexpected authenticator: %T", a)
+	}
+
+	c.LDAP.URL = "http://ldap.example.com"
+	if _, err := httpd.NewAuthenticator(c); err == nil || err.Error() != `ldap url: unknown scheme: "http"` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c.AuthProvider = "kerberos"
+	if _, err := httpd.NewAuthenticator(c); err == nil || err.Error() != `unknown auth provider: "kerberos"` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// MustWriteTempFile writes s to a temporary file and returns its path.
+func MustWriteTempFile(s string) string {
+	f, err := ioutil.TempFile("", "influxdb-httpd-")
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(s); err != nil {
+		panic(err)
+	}
+	return f.Name()
+}
@@ -9,6 +9,20 @@ type Config struct {
 	PprofEnabled     bool   `toml:"pprof-enabled"`
 	HttpsEnabled     bool   `toml:"https-enabled"`
 	HttpsCertificate string `toml:"https-certificate"`
+
+	// FloatPolicy handles the NaN and infinite values of written points:
+	// "reject" rejects the write, "drop" drops their fields and "store"
+	// stores them. Writes can request another with "float_policy".
+	FloatPolicy string `toml:"float-policy"`
+
+	// AuthProvider authenticates users when auth is enabled: "meta" for the
+	// users of the meta store, "ldap" for an LDAP directory, or "file" for the
+	// users listed in AuthFile. Groups map the groups of users authenticated
+	// by LDAP or a file to privileges.
+	AuthProvider string        `toml:"auth-provider"`
+	AuthFile     string        `toml:"auth-file"`
+	LDAP         LDAPConfig    `toml:"ldap"`
+	Groups       []GroupConfig `toml:"group"`
 }
 
 func NewConfig() Config {
@@ -18,5 +32,8 @@ func NewConfig() Config {
 		LogEnabled:       true,
 		HttpsEnabled:     false,
 		HttpsCertificate: "/etc/ssl/influxdb.pem",
+		FloatPolicy:      "reject",
+		AuthProvider:     AuthProviderMeta,
+		LDAP:             NewLDAPConfig(),
 	}
 }
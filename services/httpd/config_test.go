@@ -19,6 +19,21 @@ write-tracing = true
 pprof-enabled = true
 https-enabled = true
 https-certificate = "/dev/null"
+float-policy = "drop"
+auth-provider = "ldap"
+
+[ldap]
+url = "ldaps://ldap.example.com"
+base-dn = "dc=example,dc=com"
+
+[[group]]
+name = "admins"
+admin = true
+
+[[group]]
+name = "writers"
+  [group.privileges]
+  telegraf = "write"
 `, &c); err != nil {
 		t.Fatal(err)
 	}
@@ -40,6 +55,14 @@ https-certificate = "/dev/null"
 		t.Fatalf("unexpected https enabled: %v", c.HttpsEnabled)
 	} else if c.HttpsCertificate != "/dev/null" {
 		t.Fatalf("unexpected https certificate: %v", c.HttpsCertificate)
+	} else if c.FloatPolicy != "drop" {
+		t.Fatalf("unexpected float policy: %s", c.FloatPolicy)
+	} else if c.AuthProvider != "ldap" {
+		t.Fatalf("unexpected auth provider: %s", c.AuthProvider)
+	} else if c.LDAP.URL != "ldaps://ldap.example.com" || c.LDAP.BaseDN != "dc=example,dc=com" {
+		t.Fatalf("unexpected ldap config: %#v", c.LDAP)
+	} else if len(c.Groups) != 2 || c.Groups[0].Name != "admins" || !c.Groups[0].Admin || c.Groups[1].Privileges["telegraf"] != "write" {
+		t.Fatalf("unexpected groups: %#v", c.Groups)
 	}
 }
 
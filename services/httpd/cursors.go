@@ -0,0 +1,125 @@
+package httpd
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/influxdb/influxdb/influxql"
+)
+
+// DefaultCursorTimeout is how long the rest of the results of a query
+// limited by "series_limit" are kept for a follow-up request.
+const DefaultCursorTimeout = time.Minute
+
+// queryCursor holds the results of a query that haven't been returned yet.
+type queryCursor struct {
+	user    string                  // name of the user who ran the query
+	pending []*influxql.Result      // results already read from the channel
+	results <-chan *influxql.Result // results still being executed
+	timer   *time.Timer
+}
+
+// next returns the next result of the cursor, or false once all the results
+// have been read.
+func (c *queryCursor) next() (*influxql.Result, bool) {
+	if len(c.pending) > 0 {
+		r := c.pending[0]
+		c.pending = c.pending[1:]
+		return r, true
+	}
+	r, ok := <-c.results
+	return r, ok
+}
+
+// drain discards the rest of the results so the query can finish.
+func (c *queryCursor) drain() {
+	for _ = range c.results {
+	}
+}
+
+// cursorStore keeps the cursors of queries until they're resumed or expire.
+type cursorStore struct {
+	mu      sync.Mutex
+	cursors map[string]*queryCursor
+	timeout time.Duration
+}
+
+// newCursorStore returns a store expiring cursors after timeout.
+func newCursorStore(timeout time.Duration) *cursorStore {
+	return &cursorStore{
+		cursors: make(map[string]*queryCursor),
+		timeout: timeout,
+	}
+}
+
+// add stores c and returns the token to resume it with. The rest of the
+// results of c are discarded if it isn't resumed before the timeout.
+func (s *cursorStore) add(c *queryCursor) (string, error) {
+	b := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cursors[token] = c
+	c.timer = time.AfterFunc(s.timeout, func() {
+		s.mu.Lock()
+		expired := s.cursors[token] == c
+		delete(s.cursors, token)
+		s.mu.Unlock()
+
+		if expired {
+			c.drain()
+		}
+	})
+	return token, nil
+}
+
+// take removes and returns the cursor of token if it belongs to user.
+// Returns nil if the cursor doesn't exist, has expired or belongs to
+// another user.
+func (s *cursorStore) take(token, user string) *queryCursor {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.cursors[token]
+	if c == nil || c.user != user {
+		return nil
+	}
+	delete(s.cursors, token)
+	c.timer.Stop()
+	return c
+}
+
+// splitSeries trims results to their first n series and returns the rest of
+// the series as a result of the same statement, or nil if results have no
+// more than n series. Only the last result may go over n.
+func splitSeries(results []*influxql.Result, n int) ([]*influxql.Result, *influxql.Result) {
+	for i, r := range results {
+		if len(r.Series) <= n {
+			n -= len(r.Series)
+			continue
+		}
+
+		// The error of the statement, whether it's partial and its stats are
+		// returned after all its series.
+		rest := &influxql.Result{
+			StatementID: r.StatementID,
+			Series:      r.Series[n:],
+			Err:         r.Err,
+			Partial:     r.Partial,
+			Stats:       r.Stats,
+		}
+		if n == 0 {
+			return results[:i], rest
+		}
+		r.Series, r.Err, r.Partial, r.Stats = r.Series[:n], nil, false, nil
+		return results[:i+1], rest
+	}
+	return results, nil
+}
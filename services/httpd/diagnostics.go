@@ -0,0 +1,123 @@
+package httpd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultDiagnosticsCPUProfile is the default duration of the CPU profile of
+// the diagnostics bundle.
+const DefaultDiagnosticsCPUProfile = 10 * time.Second
+
+// serveDiagnostics writes a gzipped tarball of the profiles and statistics of
+// the process and the files returned by h.Diagnostics. The CPU profile is
+// taken for the number of seconds given by the "seconds" parameter; zero
+// skips it. Files which cannot be collected are listed in errors.txt.
+func (h *Handler) serveDiagnostics(w http.ResponseWriter, r *http.Request) {
+	cpu := DefaultDiagnosticsCPUProfile
+	if s := r.URL.Query().Get("seconds"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			httpError(w, fmt.Sprintf("invalid seconds: %q", s), false, http.StatusBadRequest)
+			return
+		}
+		cpu = time.Duration(n) * time.Second
+	}
+
+	b := &diagnosticsBundle{}
+	if cpu > 0 {
+		b.add("cpu.pprof", func(w io.Writer) error {
+			if err := pprof.StartCPUProfile(w); err != nil {
+				return err
+			}
+			time.Sleep(cpu)
+			pprof.StopCPUProfile()
+			return nil
+		})
+	}
+	b.add("goroutine.txt", func(w io.Writer) error { return pprof.Lookup("goroutine").WriteTo(w, 2) })
+	b.add("heap.pprof", func(w io.Writer) error { return pprof.Lookup("heap").WriteTo(w, 0) })
+	b.add("block.pprof", func(w io.Writer) error { return pprof.Lookup("block").WriteTo(w, 0) })
+	b.add("vars.json", func(w io.Writer) error { writeExpvar(w); return nil })
+
+	if h.Diagnostics != nil {
+		files, err := h.Diagnostics()
+		if err != nil {
+			b.errs = append(b.errs, fmt.Sprintf("diagnostics: %s", err))
+		}
+
+		names := make([]string, 0, len(files))
+		for name := range files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			b.files = append(b.files, diagnosticsFile{name: name, data: files[name]})
+		}
+	}
+
+	if len(b.errs) > 0 {
+		b.files = append(b.files, diagnosticsFile{name: "errors.txt", data: []byte(strings.Join(b.errs, "\n") + "\n")})
+	}
+
+	now := time.Now().UTC()
+	dir := "influxdb-diagnostics-" + now.Format("20060102T150405Z")
+	w.Header().Set("Content-Type", "application/x-gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", dir+".tar.gz"))
+	if err := b.writeTo(w, dir, now); err != nil {
+		h.Logger.Error("failed to write diagnostics bundle", "err", err)
+	}
+}
+
+// diagnosticsBundle holds the files of a diagnostics bundle.
+type diagnosticsBundle struct {
+	files []diagnosticsFile
+	errs  []string
+}
+
+type diagnosticsFile struct {
+	name string
+	data []byte
+}
+
+// add adds the file written by fn to the bundle, or records the error of fn.
+func (b *diagnosticsBundle) add(name string, fn func(w io.Writer) error) {
+	var buf bytes.Buffer
+	if err := fn(&buf); err != nil {
+		b.errs = append(b.errs, fmt.Sprintf("%s: %s", name, err))
+		return
+	}
+	b.files = append(b.files, diagnosticsFile{name: name, data: buf.Bytes()})
+}
+
+// writeTo writes the bundle to w as a gzipped tarball of the directory dir.
+func (b *diagnosticsBundle) writeTo(w io.Writer, dir string, modTime time.Time) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, f := range b.files {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    dir + "/" + f.name,
+			Mode:    0644,
+			Size:    int64(len(f.data)),
+			ModTime: modTime,
+		}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
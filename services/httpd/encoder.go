@@ -0,0 +1,370 @@
+package httpd
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/influxdb/influxdb/influxql"
+)
+
+const (
+	// encoderFlushSize is the size at which the encoder's buffer is written out.
+	encoderFlushSize = 32 * 1024
+
+	// encoderMaxPresize is the most the encoder grows its buffer by up front
+	// for the values of a row.
+	encoderMaxPresize = 1024 * 1024
+)
+
+// encoderPool holds encoders so their buffers are reused across responses.
+var encoderPool = sync.Pool{
+	New: func() interface{} {
+		return &encoder{buf: make([]byte, 0, 2*encoderFlushSize)}
+	},
+}
+
+// WriteResponse writes resp to w as JSON and returns the number of bytes
+// written. The JSON is the same as MarshalJSON's, but the results are encoded
+// value by value into a pooled buffer that is written out as it fills, rather
+// than marshaled as a whole. Pretty printed responses are marshaled.
+func WriteResponse(w io.Writer, resp Response, pretty bool) (int, error) {
+	if pretty {
+		return w.Write(MarshalJSON(resp, pretty))
+	}
+
+	e := encoderPool.Get().(*encoder)
+	defer func() {
+		e.w, e.n, e.err = nil, 0, nil
+		e.buf = e.buf[:0]
+		encoderPool.Put(e)
+	}()
+
+	e.w = w
+	e.encodeResponse(resp)
+	if e.err == nil {
+		e.flush()
+	}
+
+	// Nothing was written if the response can't be encoded, so write what
+	// MarshalJSON does.
+	if e.err != nil && e.n == 0 {
+		return w.Write(MarshalJSON(resp, pretty))
+	}
+	return e.n, e.err
+}
+
+// encoder encodes responses as JSON into a buffer written out to w.
+type encoder struct {
+	w   io.Writer
+	buf []byte
+	n   int // bytes written to w
+	err error
+}
+
+// flush writes out the buffer.
+func (e *encoder) flush() {
+	if e.err != nil || len(e.buf) == 0 {
+		return
+	}
+	n, err := e.w.Write(e.buf)
+	e.n += n
+	e.err = err
+	e.buf = e.buf[:0]
+}
+
+// presize grows the buffer so n more bytes fit without it being reallocated.
+func (e *encoder) presize(n int) {
+	if n > encoderMaxPresize {
+		n = encoderMaxPresize
+	}
+	if cap(e.buf)-len(e.buf) < n {
+		buf := make([]byte, len(e.buf), len(e.buf)+n)
+		copy(buf, e.buf)
+		e.buf = buf
+	}
+}
+
+func (e *encoder) encodeResponse(resp Response) {
+	e.buf = append(e.buf, '{')
+	comma := false
+	if len(resp.Results) > 0 {
+		e.buf = append(e.buf, `"results":[`...)
+		for i, r := range resp.Results {
+			if i > 0 {
+				e.buf = append(e.buf, ',')
+			}
+			e.encodeResult(r)
+		}
+		e.buf = append(e.buf, ']')
+		comma = true
+	}
+	if resp.Err != nil && resp.Err.Error() != "" {
+		comma = e.key("error", comma)
+		e.buf = appendString(e.buf, resp.Err.Error())
+	}
+	if resp.Cursor != "" {
+		comma = e.key("cursor", comma)
+		e.buf = appendString(e.buf, resp.Cursor)
+	}
+	e.buf = append(e.buf, '}')
+}
+
+func (e *encoder) encodeResult(r *influxql.Result) {
+	if r == nil {
+		e.buf = append(e.buf, "null"...)
+		return
+	}
+
+	e.buf = append(e.buf, '{')
+	comma := false
+	if len(r.Series) > 0 {
+		e.buf = append(e.buf, `"series":[`...)
+		for i, row := range r.Series {
+			if i > 0 {
+				e.buf = append(e.buf, ',')
+			}
+			e.encodeRow(row)
+		}
+		e.buf = append(e.buf, ']')
+		comma = true
+	}
+	if r.Err != nil && r.Err.Error() != "" {
+		comma = e.key("error", comma)
+		e.buf = appendString(e.buf, r.Err.Error())
+	}
+	if r.Staleness > 0 {
+		comma = e.key("staleness", comma)
+		e.buf = appendString(e.buf, r.Staleness.String())
+	}
+	if r.Partial {
+		comma = e.key("partial", comma)
+		e.buf = append(e.buf, "true"...)
+	}
+	if r.Stats != nil {
+		comma = e.key("stats", comma)
+		e.marshal(r.Stats)
+	}
+	e.buf = append(e.buf, '}')
+}
+
+func (e *encoder) encodeRow(row *influxql.Row) {
+	if row == nil {
+		e.buf = append(e.buf, "null"...)
+		return
+	}
+
+	e.buf = append(e.buf, '{')
+	comma := false
+	if row.Name != "" {
+		comma = e.key("name", comma)
+		e.buf = appendString(e.buf, row.Name)
+	}
+	if len(row.Tags) > 0 {
+		comma = e.key("tags", comma)
+		keys := make([]string, 0, len(row.Tags))
+		for k := range row.Tags {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		e.buf = append(e.buf, '{')
+		for i, k := range keys {
+			if i > 0 {
+				e.buf = append(e.buf, ',')
+			}
+			e.buf = appendString(e.buf, k)
+			e.buf = append(e.buf, ':')
+			e.buf = appendString(e.buf, row.Tags[k])
+		}
+		e.buf = append(e.buf, '}')
+	}
+	if len(row.Columns) > 0 {
+		comma = e.key("columns", comma)
+		e.buf = append(e.buf, '[')
+		for i, c := range row.Columns {
+			if i > 0 {
+				e.buf = append(e.buf, ',')
+			}
+			e.buf = appendString(e.buf, c)
+		}
+		e.buf = append(e.buf, ']')
+	}
+	if len(row.Values) > 0 {
+		comma = e.key("values", comma)
+
+		// Make room for the values of the row, estimating the size of each from
+		// the first one.
+		if len(row.Values[0]) > 0 {
+			e.presize(len(row.Values) * (12*len(row.Values[0]) + 3))
+		}
+
+		e.buf = append(e.buf, '[')
+		for i, values := range row.Values {
+			if i > 0 {
+				e.buf = append(e.buf, ',')
+			}
+			e.encodeValues(values)
+
+			if len(e.buf) >= encoderFlushSize {
+				e.flush()
+			}
+		}
+		e.buf = append(e.buf, ']')
+	}
+	if row.Err != nil {
+		comma = e.key("err", comma)
+		e.marshal(row.Err)
+	}
+	e.buf = append(e.buf, '}')
+}
+
+func (e *encoder) encodeValues(values []interface{}) {
+	if values == nil {
+		e.buf = append(e.buf, "null"...)
+		return
+	}
+
+	e.buf = append(e.buf, '[')
+	for i, v := range values {
+		if i > 0 {
+			e.buf = append(e.buf, ',')
+		}
+
+		switch v := v.(type) {
+		case nil:
+			e.buf = append(e.buf, "null"...)
+		case float64:
+			// JSON has no numbers for NaN and infinite values.
+			if math.IsNaN(v) {
+				e.buf = append(e.buf, "null"...)
+			} else if math.IsInf(v, 1) {
+				e.buf = append(e.buf, `"+Inf"`...)
+			} else if math.IsInf(v, -1) {
+				e.buf = append(e.buf, `"-Inf"`...)
+			} else {
+				e.buf = appendFloat(e.buf, v)
+			}
+		case int64:
+			e.buf = strconv.AppendInt(e.buf, v, 10)
+		case int:
+			e.buf = strconv.AppendInt(e.buf, int64(v), 10)
+		case uint64:
+			e.buf = strconv.AppendUint(e.buf, v, 10)
+		case bool:
+			e.buf = strconv.AppendBool(e.buf, v)
+		case string:
+			e.buf = appendString(e.buf, v)
+		case time.Time:
+			if y := v.Year(); y < 0 || y > 9999 {
+				e.marshal(v)
+				break
+			}
+			e.buf = append(e.buf, '"')
+			e.buf = v.AppendFormat(e.buf, time.RFC3339Nano)
+			e.buf = append(e.buf, '"')
+		default:
+			e.marshal(v)
+		}
+	}
+	e.buf = append(e.buf, ']')
+}
+
+// key appends the key of an object member, preceded by a comma if comma is
+// true. It returns true, as the next member needs a comma.
+func (e *encoder) key(name string, comma bool) bool {
+	if comma {
+		e.buf = append(e.buf, ',')
+	}
+	e.buf = append(e.buf, '"')
+	e.buf = append(e.buf, name...)
+	e.buf = append(e.buf, '"', ':')
+	return true
+}
+
+// marshal appends v marshaled by encoding/json, for values of other types.
+func (e *encoder) marshal(v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		if e.err == nil {
+			e.err = err
+		}
+		return
+	}
+	e.buf = append(e.buf, b...)
+}
+
+// appendFloat appends f formatted like encoding/json formats floats.
+func appendFloat(b []byte, f float64) []byte {
+	abs := math.Abs(f)
+	format := byte('f')
+	if abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	b = strconv.AppendFloat(b, f, format, -1, 64)
+	if format == 'e' {
+		// Clean up e-09 to e-9.
+		if n := len(b); n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	return b
+}
+
+// appendString appends s as a JSON string, escaped like encoding/json escapes
+// strings.
+func appendString(b []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = append(b, "\ufffd"...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', hex[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
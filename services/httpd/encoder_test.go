@@ -0,0 +1,153 @@
+package httpd_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/influxql"
+	"github.com/influxdb/influxdb/services/httpd"
+)
+
+// Ensure responses are written as the same JSON they're marshaled to.
+func TestWriteResponse(t *testing.T) {
+	for i, tt := range []struct {
+		resp httpd.Response
+	}{
+		{resp: httpd.Response{}},
+		{resp: httpd.Response{Results: []*influxql.Result{}}},
+		{resp: httpd.Response{Err: errors.New("marker")}},
+		{resp: httpd.Response{Results: []*influxql.Result{{}}, Cursor: "abc"}},
+		{resp: httpd.Response{Results: []*influxql.Result{nil, {Err: errors.New("<bad> & \"quoted\"")}}}},
+		{resp: httpd.Response{Results: []*influxql.Result{{
+			Staleness: 3 * time.Second,
+			Partial:   true,
+			Stats:     &influxql.Stats{SeriesN: 1, PointN: 2, CacheHitN: 1, Duration: time.Millisecond},
+		}}}},
+		{resp: httpd.Response{Results: []*influxql.Result{{
+			Series: []*influxql.Row{
+				{Name: "cpu"},
+				{
+					Name:    "cpu\tload",
+					Tags:    map[string]string{"region": "us\nwest", "host": "serverA", "dc": "\u2028\u2029\x01\xff"},
+					Columns: []string{"time", "value", "count", "up", "host", "other"},
+					Values: [][]interface{}{
+						{time.Unix(0, 0).UTC(), 1.5, int64(-10), true, "serverA", nil},
+						{time.Unix(1, 123456789).UTC(), 1e21, 10, false, "", []string{"a"}},
+						{int64(1444238400000000000), 1e-7, uint64(math.MaxUint64), nil, "x", map[string]int{"a": 1}},
+						{time.Unix(2, 0).In(time.FixedZone("", 3600)), -0.000001, 0.0, math.MaxFloat64, -1e-300, 100.0},
+						{math.NaN(), math.Inf(1), math.Inf(-1), int32(5), float32(0.1), "\u65e5\u672c"},
+						nil,
+						{},
+					},
+				},
+				{Columns: []string{"value"}, Err: errors.New("row error")},
+			},
+		}}}},
+	} {
+		exp := httpd.MarshalJSON(tt.resp, false)
+
+		var buf bytes.Buffer
+		if n, err := httpd.WriteResponse(&buf, tt.resp, false); err != nil {
+			t.Errorf("%d. unexpected error: %s", i, err)
+		} else if n != buf.Len() {
+			t.Errorf("%d. unexpected byte count: %d != %d", i, n, buf.Len())
+		} else if !bytes.Equal(buf.Bytes(), exp) {
+			t.Errorf("%d. unexpected JSON:\n\nexp=%s\n\ngot=%s\n\n", i, exp, buf.Bytes())
+		}
+	}
+}
+
+// Ensure large responses are written out in pieces.
+func TestWriteResponse_Large(t *testing.T) {
+	resp := NewRawResponse(100000, 5)
+
+	var w countWriter
+	if n, err := httpd.WriteResponse(&w, resp, false); err != nil {
+		t.Fatal(err)
+	} else if n != w.buf.Len() {
+		t.Fatalf("unexpected byte count: %d != %d", n, w.buf.Len())
+	} else if w.n < 2 {
+		t.Fatalf("unexpected write count: %d", w.n)
+	} else if exp := httpd.MarshalJSON(resp, false); !bytes.Equal(w.buf.Bytes(), exp) {
+		t.Fatal("unexpected JSON")
+	}
+}
+
+// Ensure pretty printed responses are indented.
+func TestWriteResponse_Pretty(t *testing.T) {
+	resp := httpd.Response{Results: []*influxql.Result{{Series: []*influxql.Row{{Name: "cpu"}}}}}
+
+	var buf bytes.Buffer
+	if _, err := httpd.WriteResponse(&buf, resp, true); err != nil {
+		t.Fatal(err)
+	} else if exp := httpd.MarshalJSON(resp, true); !bytes.Equal(buf.Bytes(), exp) {
+		t.Fatalf("unexpected JSON: %s", buf.Bytes())
+	}
+}
+
+// Ensure the error is written if a response can't be encoded.
+func TestWriteResponse_Error(t *testing.T) {
+	resp := httpd.Response{Results: []*influxql.Result{{Series: []*influxql.Row{{
+		Columns: []string{"value"},
+		Values:  [][]interface{}{{&invalidJSON{}}},
+	}}}}}
+
+	var buf bytes.Buffer
+	if _, err := httpd.WriteResponse(&buf, resp, false); err != nil {
+		t.Fatal(err)
+	} else if exp := httpd.MarshalJSON(resp, false); !bytes.Equal(buf.Bytes(), exp) {
+		t.Fatalf("unexpected bytes: %q", buf.String())
+	}
+}
+
+func BenchmarkMarshalJSON_Raw(b *testing.B)  { benchmarkEncodeRaw(b, false) }
+func BenchmarkWriteResponse_Raw(b *testing.B) { benchmarkEncodeRaw(b, true) }
+
+func benchmarkEncodeRaw(b *testing.B, stream bool) {
+	resp := NewRawResponse(100000, 4)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if stream {
+			httpd.WriteResponse(ioutil.Discard, resp, false)
+		} else {
+			ioutil.Discard.Write(httpd.MarshalJSON(resp, false))
+		}
+	}
+}
+
+// NewRawResponse returns a response to a raw query, with n points in each
+// of seriesN series.
+func NewRawResponse(n, seriesN int) httpd.Response {
+	result := &influxql.Result{}
+	for i := 0; i < seriesN; i++ {
+		row := &influxql.Row{
+			Name:    "cpu",
+			Tags:    map[string]string{"host": fmt.Sprintf("server%d", i), "region": "uswest"},
+			Columns: []string{"time", "value", "count", "status"},
+		}
+		for j := 0; j < n; j++ {
+			row.Values = append(row.Values, []interface{}{
+				time.Unix(int64(j), 0).UTC(), float64(j) / 3, int64(j), "ok",
+			})
+		}
+		result.Series = append(result.Series, row)
+	}
+	return httpd.Response{Results: []*influxql.Result{result}}
+}
+
+// countWriter buffers what's written to it and counts the writes.
+type countWriter struct {
+	buf bytes.Buffer
+	n   int
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	w.n++
+	return w.buf.Write(p)
+}
@@ -3,13 +3,15 @@ package httpd
 import (
 	"bytes"
 	"compress/gzip"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"expvar"
 	"fmt"
+	"hash/fnv"
 	"io"
 	"io/ioutil"
-	"log"
+	"net"
 	"net/http"
 	"net/http/pprof"
 	"os"
@@ -22,7 +24,9 @@ import (
 	"github.com/influxdb/influxdb/client"
 	"github.com/influxdb/influxdb/cluster"
 	"github.com/influxdb/influxdb/influxql"
+	"github.com/influxdb/influxdb/logger"
 	"github.com/influxdb/influxdb/meta"
+	"github.com/influxdb/influxdb/services/audit"
 	"github.com/influxdb/influxdb/services/continuous_querier"
 	"github.com/influxdb/influxdb/tsdb"
 	"github.com/influxdb/influxdb/uuid"
@@ -51,6 +55,7 @@ type route struct {
 // Handler represents an HTTP handler for the InfluxDB server.
 type Handler struct {
 	mux                   *pat.PatternServeMux
+	debug                 http.Handler
 	requireAuthentication bool
 	Version               string
 
@@ -62,7 +67,8 @@ type Handler struct {
 
 	QueryExecutor interface {
 		Authorize(u *meta.UserInfo, q *influxql.Query, db string) error
-		ExecuteQuery(q *influxql.Query, db string, chunkSize int) (<-chan *influxql.Result, error)
+		ExecuteQuery(q *influxql.Query, db string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error)
+		QueryLastModified(q *influxql.Query, db string) (time.Time, []uint64, bool)
 	}
 
 	PointsWriter interface {
@@ -71,10 +77,46 @@ type Handler struct {
 
 	ContinuousQuerier continuous_querier.ContinuousQuerier
 
-	Logger         *log.Logger
+	// PprofEnabled serves the profiles and diagnostics bundle under /debug.
+	PprofEnabled bool
+
+	// Diagnostics, if set, returns additional files of the diagnostics
+	// bundle by name, such as the configuration of the server.
+	Diagnostics func() (map[string][]byte, error)
+
+	// Health, if set, returns the state of the components of the server
+	// for /health. Ready, if set, returns true once the server has started,
+	// for /ready. Replay, if set, returns the progress of the replay of the
+	// WALs of the shards, which /ready waits for.
+	Health func() []ComponentHealth
+	Ready  func() bool
+	Replay func() tsdb.ReplayStatus
+
+	// WriteThrottler, if set, serves and replaces the write throttles of
+	// the data store at /throttle.
+	WriteThrottler interface {
+		WriteThrottles() tsdb.WriteThrottles
+		SetWriteThrottles(t tsdb.WriteThrottles)
+	}
+
+	// Authenticator, if set, authenticates users instead of the meta store.
+	Authenticator Authenticator
+
+	// AuditLog, if set, records administrative statements.
+	AuditLog interface {
+		Record(e audit.Entry)
+	}
+
+	// FloatPolicy determines how NaN and infinite values of written points
+	// are handled unless a write requests another with "float_policy".
+	FloatPolicy tsdb.FloatPolicy
+
+	Logger         *logger.Logger
 	loggingEnabled bool // Log every HTTP access.
 	WriteTrace     bool // Detailed logging of write path
 	statMap        *expvar.Map
+
+	cursors *cursorStore // Results of queries limited by "series_limit".
 }
 
 // NewHandler returns a new instance of handler with routes.
@@ -82,10 +124,11 @@ func NewHandler(requireAuthentication, loggingEnabled, writeTrace bool, statMap
 	h := &Handler{
 		mux: pat.New(),
 		requireAuthentication: requireAuthentication,
-		Logger:                log.New(os.Stderr, "[http] ", log.LstdFlags),
+		Logger:                logger.New(os.Stderr, "httpd"),
 		loggingEnabled:        loggingEnabled,
 		WriteTrace:            writeTrace,
 		statMap:               statMap,
+		cursors:               newCursorStore(DefaultCursorTimeout),
 	}
 
 	h.SetRoutes([]route{
@@ -97,6 +140,10 @@ func NewHandler(requireAuthentication, loggingEnabled, writeTrace bool, statMap
 			"query", // Query serving route.
 			"GET", "/query", true, true, h.serveQuery,
 		},
+		route{
+			"query", // Query serving route for long batches of statements.
+			"POST", "/query", true, true, h.serveQuery,
+		},
 		route{
 			"write", // Satisfy CORS checks.
 			"OPTIONS", "/write", true, true, h.serveOptions,
@@ -105,6 +152,18 @@ func NewHandler(requireAuthentication, loggingEnabled, writeTrace bool, statMap
 			"write", // Data-ingest route.
 			"POST", "/write", true, true, h.serveWrite,
 		},
+		route{
+			"annotations", // Satisfy CORS checks.
+			"OPTIONS", "/annotations", true, true, h.serveOptions,
+		},
+		route{
+			"annotations", // Annotations query route.
+			"GET", "/annotations", true, true, h.serveAnnotations,
+		},
+		route{
+			"annotations", // Annotations write route.
+			"POST", "/annotations", true, true, h.serveAnnotations,
+		},
 		route{ // Ping
 			"ping",
 			"GET", "/ping", true, true, h.servePing,
@@ -113,11 +172,36 @@ func NewHandler(requireAuthentication, loggingEnabled, writeTrace bool, statMap
 			"ping-head",
 			"HEAD", "/ping", true, true, h.servePing,
 		},
+		route{ // State of the components of the server
+			"health",
+			"GET", "/health", true, true, h.serveHealth,
+		},
+		route{
+			"health-head",
+			"HEAD", "/health", true, true, h.serveHealth,
+		},
+		route{ // Startup completion
+			"ready",
+			"GET", "/ready", true, true, h.serveReady,
+		},
+		route{
+			"ready-head",
+			"HEAD", "/ready", true, true, h.serveReady,
+		},
+		route{
+			"throttle", // Write throttles of the data store.
+			"GET", "/throttle", true, true, h.serveThrottle,
+		},
+		route{
+			"throttle", // Replace the write throttles of the data store.
+			"POST", "/throttle", true, true, h.serveThrottle,
+		},
 		route{ // Tell data node to run CQs that should be run
 			"process_continuous_queries",
 			"POST", "/data/process_continuous_queries", false, false, h.serveProcessContinuousQueries,
 		},
 	})
+	h.debug = recovery(authenticate(h.serveDebug, h, requireAuthentication), "debug", h.Logger)
 
 	return h
 }
@@ -154,25 +238,97 @@ func (h *Handler) SetRoutes(routes []route) {
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	h.statMap.Add(statRequest, 1)
 
-	// FIXME(benbjohnson): Add pprof enabled flag.
-	if strings.HasPrefix(r.URL.Path, "/debug/pprof") {
-		switch r.URL.Path {
-		case "/debug/pprof/cmdline":
-			pprof.Cmdline(w, r)
-		case "/debug/pprof/profile":
-			pprof.Profile(w, r)
-		case "/debug/pprof/symbol":
-			pprof.Symbol(w, r)
-		default:
-			pprof.Index(w, r)
-		}
-	} else if strings.HasPrefix(r.URL.Path, "/debug/vars") {
-		serveExpvar(w, r)
+	if strings.HasPrefix(r.URL.Path, "/debug/") {
+		h.debug.ServeHTTP(w, r)
 	} else {
 		h.mux.ServeHTTP(w, r)
 	}
 }
 
+// serveDebug serves the statistics, profiles and diagnostics bundle under
+// /debug. Only admin users may access them when authentication is enabled.
+// Profiles and the bundle are only served if pprof is enabled.
+func (h *Handler) serveDebug(w http.ResponseWriter, r *http.Request, user *meta.UserInfo) {
+	if user != nil && !user.Admin {
+		httpError(w, "admin privilege required", false, http.StatusForbidden)
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/debug/vars"):
+		serveExpvar(w, r)
+	case !h.PprofEnabled:
+		http.NotFound(w, r)
+	case r.URL.Path == "/debug/diagnostics":
+		h.serveDiagnostics(w, r)
+	case r.URL.Path == "/debug/pprof/cmdline":
+		pprof.Cmdline(w, r)
+	case r.URL.Path == "/debug/pprof/profile":
+		pprof.Profile(w, r)
+	case r.URL.Path == "/debug/pprof/symbol":
+		pprof.Symbol(w, r)
+	case strings.HasPrefix(r.URL.Path, "/debug/pprof"):
+		pprof.Index(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveThrottle returns the write throttles of the data store, replacing
+// them with the throttles in the body of POST requests first.
+func (h *Handler) serveThrottle(w http.ResponseWriter, r *http.Request, user *meta.UserInfo) {
+	pretty := r.URL.Query().Get("pretty") == "true"
+
+	if user != nil && !user.Admin {
+		httpError(w, "admin privilege required", pretty, http.StatusForbidden)
+		return
+	}
+
+	// If there's nothing to throttle, return 501.
+	if h.WriteThrottler == nil {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+
+	if r.Method == "POST" {
+		var t tsdb.WriteThrottles
+		if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+			httpError(w, "invalid throttles: "+err.Error(), pretty, http.StatusBadRequest)
+			return
+		}
+		if err := validateWriteThrottles(t); err != nil {
+			httpError(w, err.Error(), pretty, http.StatusBadRequest)
+			return
+		}
+		h.WriteThrottler.SetWriteThrottles(t)
+	}
+
+	w.Header().Add("content-type", "application/json")
+	w.Write(MarshalJSON(h.WriteThrottler.WriteThrottles(), pretty))
+}
+
+// validateWriteThrottles returns an error if any of the rates are negative.
+func validateWriteThrottles(t tsdb.WriteThrottles) error {
+	validate := func(name string, wt tsdb.WriteThrottle) error {
+		if wt.PointsPerSecond < 0 || wt.BytesPerSecond < 0 {
+			return fmt.Errorf("invalid %s throttle: rates can't be negative", name)
+		}
+		return nil
+	}
+
+	if err := validate("global", t.Global); err != nil {
+		return err
+	} else if err := validate("shard", t.Shard); err != nil {
+		return err
+	}
+	for id, wt := range t.Shards {
+		if err := validate(fmt.Sprintf("shard %d", id), wt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (h *Handler) serveProcessContinuousQueries(w http.ResponseWriter, r *http.Request, user *meta.UserInfo) {
 	h.statMap.Add(statCQRequest, 1)
 
@@ -217,20 +373,52 @@ func (h *Handler) serveProcessContinuousQueries(w http.ResponseWriter, r *http.R
 // serveQuery parses an incoming query and, if valid, executes the query.
 func (h *Handler) serveQuery(w http.ResponseWriter, r *http.Request, user *meta.UserInfo) {
 	h.statMap.Add(statQueryRequest, 1)
+	defer func(start time.Time) {
+		h.statMap.Add(statQueryRequestDuration, time.Since(start).Nanoseconds())
+	}(time.Now())
 
-	q := r.URL.Query()
-	pretty := q.Get("pretty") == "true"
+	// Parameters can be passed in the URL or, with POST, in a form body.
+	pretty := r.FormValue("pretty") == "true"
 
-	qp := strings.TrimSpace(q.Get("q"))
+	// Parse the number of series to return before handing out a cursor.
+	seriesLimit, err := parseSeriesLimit(r.FormValue("series_limit"))
+	if err != nil {
+		httpError(w, err.Error(), pretty, http.StatusBadRequest)
+		return
+	}
+
+	// Parse the unit of integer timestamps, if requested instead of RFC3339.
+	epoch, err := parseEpoch(strings.TrimSpace(r.FormValue("epoch")))
+	if err != nil {
+		httpError(w, err.Error(), pretty, http.StatusBadRequest)
+		return
+	}
+
+	// Resume the results of an earlier query at its cursor.
+	if token := r.FormValue("cursor"); token != "" {
+		var name string
+		if user != nil {
+			name = user.Name
+		}
+		c := h.cursors.take(token, name)
+		if c == nil {
+			httpError(w, "cursor not found", pretty, http.StatusNotFound)
+			return
+		}
+		w.Header().Add("content-type", "application/json")
+		h.serveResults(w, c, pretty, epoch, r.FormValue("chunked") == "true", seriesLimit, nil)
+		return
+	}
+
+	// The statements can also be POSTed in a form body.
+	qp := strings.TrimSpace(r.FormValue("q"))
 	if qp == "" {
 		httpError(w, `missing required parameter "q"`, pretty, http.StatusBadRequest)
 		return
 	}
 
-	epoch := strings.TrimSpace(q.Get("epoch"))
-
 	p := influxql.NewParser(strings.NewReader(qp))
-	db := q.Get("db")
+	db := r.FormValue("db")
 
 	// Parse query from query string.
 	query, err := p.ParseQuery()
@@ -238,6 +426,9 @@ func (h *Handler) serveQuery(w http.ResponseWriter, r *http.Request, user *meta.
 		httpError(w, "error parsing query: "+err.Error(), pretty, http.StatusBadRequest)
 		return
 	}
+	query.FailFast = r.FormValue("fail_fast") == "true"
+	query.Stats = r.FormValue("stats") == "true"
+	query.Rollup = r.FormValue("rollup") == "true"
 
 	// Sanitize statements with passwords.
 	for _, s := range query.Statements {
@@ -253,96 +444,262 @@ func (h *Handler) serveQuery(w http.ResponseWriter, r *http.Request, user *meta.
 	if h.requireAuthentication {
 		err = h.QueryExecutor.Authorize(user, query, db)
 		if err != nil {
+			h.audit(r, user, db, query, func(int) error { return err })
 			httpError(w, "error authorizing query: "+err.Error(), pretty, http.StatusUnauthorized)
 			return
 		}
 	}
 
 	// Parse chunk size. Use default if not provided or unparsable.
-	chunked := (q.Get("chunked") == "true")
+	chunked := (r.FormValue("chunked") == "true")
 	chunkSize := DefaultChunkSize
 	if chunked {
-		if n, err := strconv.ParseInt(q.Get("chunk_size"), 10, 64); err == nil {
+		if n, err := strconv.ParseInt(r.FormValue("chunk_size"), 10, 64); err == nil {
 			chunkSize = int(n)
 		}
 	}
 
+	// Parse which shard replicas should serve the query.
+	readPref, err := tsdb.ParseReadPreference(r.FormValue("read_preference"))
+	if err != nil {
+		httpError(w, err.Error(), pretty, http.StatusBadRequest)
+		return
+	}
+
+	// Parse the priority of the query. Batch queries wait behind interactive ones.
+	switch p := r.Header.Get("X-InfluxDB-Query-Priority"); strings.ToLower(p) {
+	case "", "interactive":
+	case "batch":
+		query.Batch = true
+	default:
+		httpError(w, fmt.Sprintf("invalid query priority: %q", p), pretty, http.StatusBadRequest)
+		return
+	}
+
+	// Results of time ranges which can't change are validated by the last
+	// modification of the shards read, so clients can revalidate cheaply.
+	// Chunked results and results split by a cursor can't be validated, since
+	// they're written before it's known whether they're complete.
+	if r.Method == "GET" && !chunked && seriesLimit == 0 {
+		if lastModified, shards, ok := h.QueryExecutor.QueryLastModified(query, db); ok {
+			etag := queryETag(r, lastModified, shards)
+			w.Header().Set("ETag", etag)
+			w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+			if notModified(r, etag, lastModified) {
+				h.audit(r, user, db, query, func(int) error { return nil })
+				h.statMap.Add(statQueryNotModified, 1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+	}
+
 	// Execute query.
 	w.Header().Add("content-type", "application/json")
-	results, err := h.QueryExecutor.ExecuteQuery(query, db, chunkSize)
+	results, err := h.QueryExecutor.ExecuteQuery(query, db, chunkSize, readPref, user)
 
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
+	// Keep the errors of the statements for the audit log.
+	errs := make(map[int]error)
+	defer func(req *http.Request) {
+		h.audit(req, user, db, query, func(i int) error { return errs[i] })
+	}(r)
+
+	c := &queryCursor{results: results}
+	if user != nil {
+		c.user = user.Name
+	}
+	h.serveResults(w, c, pretty, epoch, chunked, seriesLimit, errs)
+}
+
+// serveResults writes the results of c. Unless chunked, the results stop at
+// seriesLimit series, if set, and the rest are kept for a cursor passed in
+// the response. The errors of the statements are recorded in errs, if set.
+func (h *Handler) serveResults(w http.ResponseWriter, c *queryCursor, pretty bool, epoch time.Duration, chunked bool, seriesLimit int, errs map[int]error) {
 	// if we're not chunking, this will be the in memory buffer for all results before sending to client
 	resp := Response{Results: make([]*influxql.Result, 0)}
 
-	// Status header is OK once this point is reached.
-	w.WriteHeader(http.StatusOK)
+	// Chunked results are written as they come, so the status is OK from the
+	// start.
+	if chunked {
+		w.WriteHeader(http.StatusOK)
+	}
 
 	// pull all results from the channel
-	for r := range results {
+	for r, ok := c.next(); ok; r, ok = c.next() {
 		// Ignore nil results.
 		if r == nil {
 			continue
 		}
 
+		if r.Err != nil && errs != nil {
+			errs[r.StatementID] = r.Err
+		}
+
 		// if requested, convert result timestamps to epoch
-		if epoch != "" {
+		if epoch != 0 {
 			convertToEpoch(r, epoch)
 		}
 
 		// Write out result immediately if chunked.
 		if chunked {
-			n, _ := w.Write(MarshalJSON(Response{
+			n, _ := WriteResponse(w, Response{
 				Results: []*influxql.Result{r},
-			}, pretty))
+			}, pretty)
 			h.statMap.Add(statQueryRequestBytesTransmitted, int64(n))
 			w.(http.Flusher).Flush()
 			continue
 		}
 
 		// It's not chunked so buffer results in memory.
-		// Results for statements need to be combined together.
-		// We need to check if this new result is for the same statement as
-		// the last result, or for the next statement
-		l := len(resp.Results)
-		if l == 0 {
-			resp.Results = append(resp.Results, r)
-		} else if resp.Results[l-1].StatementID == r.StatementID {
-			cr := resp.Results[l-1]
-			lastSeries := cr.Series[len(cr.Series)-1]
-			rowsMerged := 0
-
-			for _, row := range r.Series {
-				if !lastSeries.SameSeries(row) {
-					// Next row is for a different series than last.
-					break
+		resp.Results = appendResult(resp.Results, r)
+
+		// Keep the series over the limit and the results still to come for
+		// a follow-up request.
+		if seriesLimit > 0 {
+			var rest *influxql.Result
+			if resp.Results, rest = splitSeries(resp.Results, seriesLimit); rest != nil {
+				c.pending = append([]*influxql.Result{rest}, c.pending...)
+				token, err := h.cursors.add(c)
+				if err != nil {
+					resp.Err = err
+					go c.drain()
 				}
-				// Values are for the same series, so append them.
-				lastSeries.Values = append(lastSeries.Values, row.Values...)
-				rowsMerged++
+				resp.Cursor = token
+				break
 			}
-
-			// Append remaining rows as new rows.
-			r.Series = r.Series[rowsMerged:]
-			cr.Series = append(cr.Series, r.Series...)
-		} else {
-			resp.Results = append(resp.Results, r)
 		}
 	}
 
 	// If it's not chunked we buffered everything in memory, so write it out
 	if !chunked {
-		n, _ := w.Write(MarshalJSON(resp, pretty))
+		// Results with errors, or partial ones, may change when the query is
+		// run again, so they mustn't be revalidated from a cache.
+		if w.Header().Get("ETag") != "" && !cacheable(resp) {
+			w.Header().Del("ETag")
+			w.Header().Del("Last-Modified")
+			w.Header().Set("Cache-Control", "no-store")
+		}
+		w.WriteHeader(http.StatusOK)
+		n, _ := WriteResponse(w, resp, pretty)
 		h.statMap.Add(statQueryRequestBytesTransmitted, int64(n))
 	}
 }
 
+// cacheable returns true if a response has every result of its query, none
+// of them failed or partial.
+func cacheable(resp Response) bool {
+	if resp.Err != nil || resp.Cursor != "" {
+		return false
+	}
+	for _, r := range resp.Results {
+		if r.Err != nil || r.Partial {
+			return false
+		}
+	}
+	return true
+}
+
+// appendResult appends r to results. Results for statements need to be
+// combined together, so r is merged into the last result if it's for the
+// same statement, and its first series into the last series if they're the
+// same series.
+func appendResult(results []*influxql.Result, r *influxql.Result) []*influxql.Result {
+	l := len(results)
+	if l == 0 || results[l-1].StatementID != r.StatementID {
+		return append(results, r)
+	}
+
+	cr := results[l-1]
+	if r.Err != nil {
+		cr.Err = r.Err
+	}
+	if r.Partial {
+		cr.Partial = true
+	}
+	if r.Stats != nil {
+		cr.Stats = r.Stats
+	}
+	if len(cr.Series) == 0 {
+		cr.Series = r.Series
+		return results
+	}
+	lastSeries := cr.Series[len(cr.Series)-1]
+	rowsMerged := 0
+
+	for _, row := range r.Series {
+		if !lastSeries.SameSeries(row) {
+			// Next row is for a different series than last.
+			break
+		}
+		// Values are for the same series, so append them.
+		lastSeries.Values = append(lastSeries.Values, row.Values...)
+		rowsMerged++
+	}
+
+	// Append remaining rows as new rows.
+	r.Series = r.Series[rowsMerged:]
+	cr.Series = append(cr.Series, r.Series...)
+	return results
+}
+
+// parseSeriesLimit parses the "series_limit" parameter of a query. Returns
+// zero, no limit, if s is blank.
+func parseSeriesLimit(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid series_limit: %q", s)
+	}
+	return n, nil
+}
+
+// audit records the audited statements of query to the audit log, along with
+// the user and address the query came from. errFn returns the error of the
+// statement at position i of the query, or nil if it succeeded.
+func (h *Handler) audit(r *http.Request, user *meta.UserInfo, db string, query *influxql.Query, errFn func(i int) error) {
+	if h.AuditLog == nil {
+		return
+	}
+
+	addr := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	var name string
+	if user != nil {
+		name = user.Name
+	}
+
+	for i, stmt := range query.Statements {
+		if !audit.Audited(stmt) {
+			continue
+		}
+		e := audit.Entry{
+			Time:      time.Now().UTC(),
+			User:      name,
+			Addr:      addr,
+			Database:  db,
+			Statement: stmt.String(),
+		}
+		if err := errFn(i); err != nil {
+			e.Error = err.Error()
+		}
+		h.AuditLog.Record(e)
+	}
+}
+
 func (h *Handler) serveWrite(w http.ResponseWriter, r *http.Request, user *meta.UserInfo) {
 	h.statMap.Add(statWriteRequest, 1)
+	defer func(start time.Time) {
+		h.statMap.Add(statWriteRequestDuration, time.Since(start).Nanoseconds())
+	}(time.Now())
 
 	// Handle gzip decoding of the body
 	body := r.Body
@@ -359,14 +716,14 @@ func (h *Handler) serveWrite(w http.ResponseWriter, r *http.Request, user *meta.
 	b, err := ioutil.ReadAll(body)
 	if err != nil {
 		if h.WriteTrace {
-			h.Logger.Print("write handler unable to read bytes from request body")
+			h.Logger.Info("write handler unable to read bytes from request body", "err", err)
 		}
 		h.writeError(w, influxql.Result{Err: err}, http.StatusBadRequest)
 		return
 	}
 	h.statMap.Add(statWriteRequestBytesReceived, int64(len(b)))
 	if h.WriteTrace {
-		h.Logger.Printf("write body received by handler: %s", string(b))
+		h.Logger.Info("write body received by handler", "db", r.URL.Query().Get("db"), "body", string(b))
 	}
 
 	if r.Header.Get("Content-Type") == "application/json" {
@@ -416,6 +773,14 @@ func (h *Handler) serveWriteJSON(w http.ResponseWriter, r *http.Request, body []
 	}
 
 	points, err := NormalizeBatchPoints(bp)
+	if err != nil {
+		h.addParseDropped(bp.Database, len(bp.Points))
+		resultError(w, influxql.Result{Err: err}, http.StatusBadRequest)
+		return
+	}
+
+	// Determine required consistency level.
+	consistency, err := parseConsistencyLevel(r)
 	if err != nil {
 		resultError(w, influxql.Result{Err: err}, http.StatusBadRequest)
 		return
@@ -425,12 +790,15 @@ func (h *Handler) serveWriteJSON(w http.ResponseWriter, r *http.Request, body []
 	if err := h.PointsWriter.WritePoints(&cluster.WritePointsRequest{
 		Database:         bp.Database,
 		RetentionPolicy:  bp.RetentionPolicy,
-		ConsistencyLevel: cluster.ConsistencyLevelOne,
+		ConsistencyLevel: consistency,
 		Points:           points,
+		BatchID:          r.FormValue("batch_id"),
 	}); err != nil {
 		h.statMap.Add(statPointsWrittenFail, int64(len(points)))
 		if influxdb.IsClientError(err) {
 			h.writeError(w, influxql.Result{Err: err}, http.StatusBadRequest)
+		} else if err == cluster.ErrWriteLimitExceeded {
+			h.writeError(w, influxql.Result{Err: err}, http.StatusTooManyRequests)
 		} else {
 			h.writeError(w, influxql.Result{Err: err}, http.StatusInternalServerError)
 		}
@@ -447,6 +815,53 @@ func (h *Handler) writeError(w http.ResponseWriter, result influxql.Result, stat
 	w.Write([]byte("\n"))
 }
 
+// addParseDropped records n points of a write to database that were dropped
+// because the write could not be parsed. Writes to unknown databases are not
+// recorded so that requests can't create statistics for arbitrary names.
+func (h *Handler) addParseDropped(database string, n int) {
+	if database == "" || n == 0 {
+		return
+	}
+	if di, err := h.MetaStore.Database(database); err != nil || di == nil {
+		return
+	}
+	dbStats := influxdb.DatabaseStatistics(database)
+	dbStats.Add(statDatabasePointsDropped, int64(n))
+	dbStats.Add(statDatabasePointsDroppedParse, int64(n))
+}
+
+// countLines returns the number of points in a line protocol body, skipping
+// blank lines and comments.
+func countLines(body []byte) int {
+	var n int
+	for _, line := range bytes.Split(body, []byte("\n")) {
+		if line = bytes.TrimSpace(line); len(line) > 0 && line[0] != '#' {
+			n++
+		}
+	}
+	return n
+}
+
+// parseConsistencyLevel returns the write consistency level requested by the
+// "consistency" query parameter. Defaults to ConsistencyLevelOne.
+func parseConsistencyLevel(r *http.Request) (cluster.ConsistencyLevel, error) {
+	level := r.FormValue("consistency")
+	if level == "" {
+		return cluster.ConsistencyLevelOne, nil
+	}
+	return cluster.ParseConsistencyLevel(level)
+}
+
+// parseFloatPolicy returns the float policy requested by the "float_policy"
+// query parameter. Defaults to the policy of the handler.
+func (h *Handler) parseFloatPolicy(r *http.Request) (tsdb.FloatPolicy, error) {
+	policy := r.FormValue("float_policy")
+	if policy == "" {
+		return h.FloatPolicy, nil
+	}
+	return tsdb.ParseFloatPolicy(policy)
+}
+
 // serveWriteLine receives incoming series data in line protocol format and writes it to the database.
 func (h *Handler) serveWriteLine(w http.ResponseWriter, r *http.Request, body []byte, user *meta.UserInfo) {
 	// Some clients may not set the content-type header appropriately and send JSON with a non-json
@@ -479,6 +894,7 @@ func (h *Handler) serveWriteLine(w http.ResponseWriter, r *http.Request, body []
 			w.WriteHeader(http.StatusOK)
 			return
 		}
+		h.addParseDropped(r.FormValue("db"), countLines(body))
 		h.writeError(w, influxql.Result{Err: err}, http.StatusBadRequest)
 		return
 	}
@@ -507,17 +923,26 @@ func (h *Handler) serveWriteLine(w http.ResponseWriter, r *http.Request, body []
 		return
 	}
 
+	// Handle NaN and infinite values with the requested policy.
+	policy, err := h.parseFloatPolicy(r)
+	if err != nil {
+		h.writeError(w, influxql.Result{Err: err}, http.StatusBadRequest)
+		return
+	}
+	points, err = tsdb.ApplyFloatPolicy(points, policy)
+	if err != nil {
+		h.writeError(w, influxql.Result{Err: err}, http.StatusBadRequest)
+		return
+	} else if len(points) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
 	// Determine required consistency level.
-	consistency := cluster.ConsistencyLevelOne
-	switch r.Form.Get("consistency") {
-	case "all":
-		consistency = cluster.ConsistencyLevelAll
-	case "any":
-		consistency = cluster.ConsistencyLevelAny
-	case "one":
-		consistency = cluster.ConsistencyLevelOne
-	case "quorum":
-		consistency = cluster.ConsistencyLevelQuorum
+	consistency, err := parseConsistencyLevel(r)
+	if err != nil {
+		h.writeError(w, influxql.Result{Err: err}, http.StatusBadRequest)
+		return
 	}
 
 	// Write points.
@@ -526,10 +951,15 @@ func (h *Handler) serveWriteLine(w http.ResponseWriter, r *http.Request, body []
 		RetentionPolicy:  r.FormValue("rp"),
 		ConsistencyLevel: consistency,
 		Points:           points,
+		BatchID:          r.FormValue("batch_id"),
 	}); influxdb.IsClientError(err) {
 		h.statMap.Add(statPointsWrittenFail, int64(len(points)))
 		h.writeError(w, influxql.Result{Err: err}, http.StatusBadRequest)
 		return
+	} else if err == cluster.ErrWriteLimitExceeded {
+		h.statMap.Add(statPointsWrittenFail, int64(len(points)))
+		h.writeError(w, influxql.Result{Err: err}, http.StatusTooManyRequests)
+		return
 	} else if err != nil {
 		h.statMap.Add(statPointsWrittenFail, int64(len(points)))
 		h.writeError(w, influxql.Result{Err: err}, http.StatusInternalServerError)
@@ -551,23 +981,73 @@ func (h *Handler) servePing(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// convertToEpoch converts result timestamps from time.Time to the specified epoch.
-func convertToEpoch(r *influxql.Result, epoch string) {
-	divisor := int64(1)
+// queryETag returns the entity tag of the results of a query request, from
+// the parameters shaping the results and the shards they're read from.
+func queryETag(r *http.Request, lastModified time.Time, shards []uint64) string {
+	q := r.URL.Query()
+	h := fnv.New64a()
+	for _, v := range []string{
+		r.FormValue("q"), q.Get("db"), q.Get("epoch"), q.Get("pretty"),
+		q.Get("chunked"), q.Get("chunk_size"), q.Get("series_limit"),
+		r.FormValue("fail_fast"),
+	} {
+		io.WriteString(h, v)
+		h.Write([]byte{0})
+	}
+
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(lastModified.UnixNano()))
+	h.Write(buf[:])
+	for _, id := range shards {
+		binary.BigEndian.PutUint64(buf[:], id)
+		h.Write(buf[:])
+	}
+	return fmt.Sprintf(`"%016x"`, h.Sum64())
+}
+
+// notModified returns true if the conditional headers of a request match
+// the results of its query. If-None-Match takes precedence over
+// If-Modified-Since, as in RFC 7232.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		t, err := http.ParseTime(ims)
+		if err != nil {
+			return false
+		}
+		// HTTP dates have a resolution of a second.
+		return !lastModified.Truncate(time.Second).After(t)
+	}
+	return false
+}
 
-	switch epoch {
-	case "u":
-		divisor = int64(time.Microsecond)
-	case "ms":
-		divisor = int64(time.Millisecond)
-	case "s":
-		divisor = int64(time.Second)
-	case "m":
-		divisor = int64(time.Minute)
-	case "h":
-		divisor = int64(time.Hour)
+// parseEpoch returns the unit of the "epoch" parameter of a query: "n" or
+// "ns", "u" or "us", "ms", "s", "m", or "h". Returns zero, RFC3339
+// timestamps, if epoch is blank.
+func parseEpoch(epoch string) (time.Duration, error) {
+	if epoch == "" {
+		return 0, nil
+	}
+	unit, err := tsdb.ParsePrecision(epoch)
+	if err != nil {
+		return 0, fmt.Errorf("invalid epoch: %q", epoch)
 	}
+	return unit, nil
+}
 
+// convertToEpoch converts result timestamps from time.Time to integers in
+// the specified unit.
+func convertToEpoch(r *influxql.Result, unit time.Duration) {
+	divisor := int64(unit)
 	for _, s := range r.Series {
 		for _, v := range s.Values {
 			if ts, ok := v[0].(time.Time); ok {
@@ -609,6 +1089,11 @@ type Batch struct {
 // serveExpvar serves registered expvar information over HTTP.
 func serveExpvar(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	writeExpvar(w)
+}
+
+// writeExpvar writes registered expvar information to w as JSON.
+func writeExpvar(w io.Writer) {
 	fmt.Fprintf(w, "{\n")
 	first := true
 	expvar.Do(func(kv expvar.KeyValue) {
@@ -683,7 +1168,8 @@ func authenticate(inner func(http.ResponseWriter, *http.Request, *meta.UserInfo)
 		}
 
 		// TODO corylanou: never allow this in the future without users
-		if requireAuthentication && len(uis) > 0 {
+		// Users of an external authenticator are not in the meta store.
+		if requireAuthentication && (len(uis) > 0 || h.Authenticator != nil) {
 			username, password, err := parseCredentials(r)
 			if err != nil {
 				h.statMap.Add(statAuthFail, 1)
@@ -696,7 +1182,11 @@ func authenticate(inner func(http.ResponseWriter, *http.Request, *meta.UserInfo)
 				return
 			}
 
-			user, err = h.MetaStore.Authenticate(username, password)
+			if h.Authenticator != nil {
+				user, err = h.Authenticator.Authenticate(username, password)
+			} else {
+				user, err = h.MetaStore.Authenticate(username, password)
+			}
 			if err != nil {
 				h.statMap.Add(statAuthFail, 1)
 				httpError(w, err.Error(), false, http.StatusUnauthorized)
@@ -766,6 +1256,7 @@ func cors(inner http.Handler) http.Handler {
 				`Content-Type`,
 				`X-CSRF-Token`,
 				`X-HTTP-Method-Override`,
+				`X-InfluxDB-Query-Priority`,
 			}, ", "))
 		}
 
@@ -787,25 +1278,22 @@ func requestID(inner http.Handler) http.Handler {
 	})
 }
 
-func logging(inner http.Handler, name string, weblog *log.Logger) http.Handler {
+func logging(inner http.Handler, name string, weblog *logger.Logger) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 		l := &responseLogger{w: w}
 		inner.ServeHTTP(l, r)
-		logLine := buildLogLine(l, r, start)
-		weblog.Println(logLine)
+		weblog.Info(buildLogLine(l, r, start))
 	})
 }
 
-func recovery(inner http.Handler, name string, weblog *log.Logger) http.Handler {
+func recovery(inner http.Handler, name string, weblog *logger.Logger) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 		l := &responseLogger{w: w}
 		inner.ServeHTTP(l, r)
 		if err := recover(); err != nil {
-			logLine := buildLogLine(l, r, start)
-			logLine = fmt.Sprintf(`%s [err:%s]`, logLine, err)
-			weblog.Println(logLine)
+			weblog.Error(buildLogLine(l, r, start), "err", err)
 		}
 	})
 }
@@ -814,6 +1302,9 @@ func recovery(inner http.Handler, name string, weblog *log.Logger) http.Handler
 type Response struct {
 	Results []*influxql.Result
 	Err     error
+
+	// Cursor, if set, resumes the results at the next series.
+	Cursor string
 }
 
 // MarshalJSON encodes a Response struct into JSON.
@@ -822,10 +1313,12 @@ func (r Response) MarshalJSON() ([]byte, error) {
 	var o struct {
 		Results []*influxql.Result `json:"results,omitempty"`
 		Err     string             `json:"error,omitempty"`
+		Cursor  string             `json:"cursor,omitempty"`
 	}
 
 	// Copy fields to output struct.
 	o.Results = r.Results
+	o.Cursor = r.Cursor
 	if r.Err != nil {
 		o.Err = r.Err.Error()
 	}
@@ -838,6 +1331,7 @@ func (r *Response) UnmarshalJSON(b []byte) error {
 	var o struct {
 		Results []*influxql.Result `json:"results,omitempty"`
 		Err     string             `json:"error,omitempty"`
+		Cursor  string             `json:"cursor,omitempty"`
 	}
 
 	err := json.Unmarshal(b, &o)
@@ -845,6 +1339,7 @@ func (r *Response) UnmarshalJSON(b []byte) error {
 		return err
 	}
 	r.Results = o.Results
+	r.Cursor = o.Cursor
 	if o.Err != "" {
 		r.Err = errors.New(o.Err)
 	}
@@ -1,21 +1,30 @@
 package httpd_test
 
 import (
+	"archive/tar"
+	"compress/gzip"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"math"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"path"
 	"reflect"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/influxdb/influxdb"
 	"github.com/influxdb/influxdb/client"
+	"github.com/influxdb/influxdb/cluster"
 	"github.com/influxdb/influxdb/influxql"
 	"github.com/influxdb/influxdb/meta"
+	"github.com/influxdb/influxdb/services/audit"
 	"github.com/influxdb/influxdb/services/httpd"
 	"github.com/influxdb/influxdb/tsdb"
 )
@@ -134,7 +143,7 @@ func TestBatchWrite_UnmarshalRFC(t *testing.T) {
 // Ensure the handler returns results from a query (including nil results).
 func TestHandler_Query(t *testing.T) {
 	h := NewHandler(false)
-	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int) (<-chan *influxql.Result, error) {
+	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error) {
 		if q.String() != `SELECT * FROM bar` {
 			t.Fatalf("unexpected query: %s", q.String())
 		} else if db != `foo` {
@@ -156,10 +165,334 @@ func TestHandler_Query(t *testing.T) {
 	}
 }
 
+// Ensure the handler serves the health of the components of the server.
+func TestHandler_Health(t *testing.T) {
+	h := NewHandler(false)
+	components := []httpd.ComponentHealth{{Name: "meta", Healthy: true}, {Name: "wal", Healthy: false, Message: "replaying"}}
+	h.Health = func() []httpd.ComponentHealth { return components }
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("GET", "/health", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if w.Body.String() != `{"healthy":false,"components":[{"name":"meta","healthy":true},{"name":"wal","healthy":false,"message":"replaying"}]}` {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+
+	components[1].Healthy = true
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("HEAD", "/health", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if w.Body.Len() != 0 {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+}
+
+// Ensure the handler is ready once the server has started.
+func TestHandler_Ready(t *testing.T) {
+	var ready bool
+	h := NewHandler(false)
+	h.Ready = func() bool { return ready }
+
+	for _, exp := range []int{http.StatusServiceUnavailable, http.StatusNoContent} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, MustNewRequest("GET", "/ready", nil))
+		if w.Code != exp {
+			t.Fatalf("unexpected status: %d", w.Code)
+		}
+		ready = true
+	}
+
+	// The progress of the replay is returned until it's done.
+	h.Replay = func() tsdb.ReplayStatus {
+		return tsdb.ReplayStatus{ShardsOpened: 1, ShardsTotal: 2, SegmentsReplayed: 3, SegmentsTotal: 4, PointsReplayed: 5, ETA: time.Second}
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("GET", "/ready", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if w.Body.String() != `{"replay":{"done":false,"shardsOpened":1,"shardsTotal":2,"segmentsReplayed":3,"segmentsTotal":4,"pointsReplayed":5,"eta":1000000000}}` {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+}
+
+// Ensure the handler answers conditional queries of unchanged results with 304.
+func TestHandler_Query_NotModified(t *testing.T) {
+	lastModified := time.Date(2000, 1, 1, 0, 0, 0, 500, time.UTC)
+	var executed int
+	h := NewHandler(false)
+	h.QueryExecutor.QueryLastModifiedFn = func(q *influxql.Query, db string) (time.Time, []uint64, bool) {
+		return lastModified, []uint64{1, 2}, true
+	}
+	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error) {
+		executed++
+		return NewResultChan(&influxql.Result{StatementID: 1, Series: influxql.Rows{{Name: "series0"}}}), nil
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar", nil))
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if etag == "" {
+		t.Fatal("expected etag")
+	} else if lm := w.Header().Get("Last-Modified"); lm != "Sat, 01 Jan 2000 00:00:00 GMT" {
+		t.Fatalf("unexpected last modified: %s", lm)
+	}
+
+	for i, tt := range []struct {
+		url    string
+		header string
+		value  string
+		code   int
+	}{
+		{url: "/query?db=foo&q=SELECT+*+FROM+bar", header: "If-None-Match", value: etag, code: http.StatusNotModified},
+		{url: "/query?db=foo&q=SELECT+*+FROM+bar", header: "If-None-Match", value: `"other", W/` + etag, code: http.StatusNotModified},
+		{url: "/query?db=foo&q=SELECT+*+FROM+bar", header: "If-None-Match", value: `"other"`, code: http.StatusOK},
+		{url: "/query?db=foo&q=SELECT+*+FROM+bar&epoch=s", header: "If-None-Match", value: etag, code: http.StatusOK},
+		{url: "/query?db=foo&q=SELECT+*+FROM+bar", header: "If-Modified-Since", value: "Sat, 01 Jan 2000 00:00:00 GMT", code: http.StatusNotModified},
+		{url: "/query?db=foo&q=SELECT+*+FROM+bar", header: "If-Modified-Since", value: "Fri, 31 Dec 1999 23:59:59 GMT", code: http.StatusOK},
+	} {
+		r := MustNewJSONRequest("GET", tt.url, nil)
+		r.Header.Set(tt.header, tt.value)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tt.code {
+			t.Errorf("%d. unexpected status: %d", i, w.Code)
+		}
+	}
+	if executed != 4 {
+		t.Fatalf("unexpected executions: %d", executed)
+	}
+}
+
+// Ensure results which may change aren't validated for caching, and answers
+// with 304 are audited.
+func TestHandler_Query_NotModified_Uncacheable(t *testing.T) {
+	var entries []audit.Entry
+	var result *influxql.Result
+	h := NewHandler(false)
+	h.AuditLog = AuditLogFunc(func(e audit.Entry) { entries = append(entries, e) })
+	h.QueryExecutor.QueryLastModifiedFn = func(q *influxql.Query, db string) (time.Time, []uint64, bool) {
+		return time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), []uint64{1}, true
+	}
+	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error) {
+		return NewResultChan(result), nil
+	}
+
+	for i, tt := range []struct {
+		url          string
+		result       *influxql.Result
+		cacheControl string
+	}{
+		{url: "/query?db=foo&q=SELECT+*+FROM+bar", result: &influxql.Result{StatementID: 0, Err: errors.New("marker")}, cacheControl: "no-store"},
+		{url: "/query?db=foo&q=SELECT+*+FROM+bar", result: &influxql.Result{StatementID: 0, Partial: true}, cacheControl: "no-store"},
+		{url: "/query?db=foo&q=SELECT+*+FROM+bar&chunked=true", result: &influxql.Result{StatementID: 0}},
+		{url: "/query?db=foo&q=SELECT+*+FROM+bar&series_limit=1", result: &influxql.Result{StatementID: 0}},
+	} {
+		result = tt.result
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, MustNewJSONRequest("GET", tt.url, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("%d. unexpected status: %d", i, w.Code)
+		} else if etag := w.Header().Get("ETag"); etag != "" {
+			t.Errorf("%d. unexpected etag: %s", i, etag)
+		} else if lm := w.Header().Get("Last-Modified"); lm != "" {
+			t.Errorf("%d. unexpected last modified: %s", i, lm)
+		} else if cc := w.Header().Get("Cache-Control"); cc != tt.cacheControl {
+			t.Errorf("%d. unexpected cache control: %s", i, cc)
+		}
+	}
+
+	result = &influxql.Result{StatementID: 0}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=DROP+SERIES+FROM+cpu", nil))
+	r := MustNewJSONRequest("GET", "/query?db=foo&q=DROP+SERIES+FROM+cpu", nil)
+	r.Header.Set("If-None-Match", w.Header().Get("ETag"))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if len(entries) != 2 || entries[1].Statement != "DROP SERIES FROM cpu" {
+		t.Fatalf("unexpected entries: %#v", entries)
+	}
+}
+
+// Ensure the handler executes statements POSTed in a form body and can fail fast.
+func TestHandler_Query_Post(t *testing.T) {
+	h := NewHandler(false)
+	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error) {
+		if q.String() != "CREATE DATABASE foo;\nCREATE DATABASE bar" {
+			t.Fatalf("unexpected query: %s", q.String())
+		} else if !q.FailFast {
+			t.Fatal("expected fail fast")
+		}
+		return NewResultChan(
+			&influxql.Result{StatementID: 0, Err: errors.New("database already exists")},
+			&influxql.Result{StatementID: 1, Err: errors.New("not executed")},
+		), nil
+	}
+
+	r := MustNewRequest("POST", "/query?fail_fast=true", strings.NewReader(url.Values{"q": {"CREATE DATABASE foo; CREATE DATABASE bar"}}.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if w.Body.String() != `{"results":[{"error":"database already exists"},{"error":"not executed"}]}` {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+}
+
+// Ensure the handler reads the parameters of a query from a POSTed form body.
+func TestHandler_Query_PostParams(t *testing.T) {
+	h := NewHandler(false)
+	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error) {
+		if db != "foo" {
+			t.Fatalf("unexpected db: %s", db)
+		} else if chunkSize != 1 {
+			t.Fatalf("unexpected chunk size: %d", chunkSize)
+		}
+		return NewResultChan(&influxql.Result{StatementID: 0, Series: influxql.Rows{{Name: "cpu", Columns: []string{"time"}, Values: [][]interface{}{{time.Unix(0, 1000).UTC()}}}}}), nil
+	}
+
+	r := MustNewRequest("POST", "/query", strings.NewReader(url.Values{
+		"q":          {"SELECT * FROM cpu"},
+		"db":         {"foo"},
+		"epoch":      {"u"},
+		"chunked":    {"true"},
+		"chunk_size": {"1"},
+	}.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if w.Body.String() != `{"results":[{"series":[{"name":"cpu","columns":["time"],"values":[[1]]}]}]}` {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+}
+
+// Ensure the handler passes the rollup flag to the query executor.
+func TestHandler_Query_Rollup(t *testing.T) {
+	h := NewHandler(false)
+	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error) {
+		if !q.Rollup {
+			t.Fatal("expected rollup")
+		}
+		return NewResultChan(&influxql.Result{StatementID: 0}), nil
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+mean(value)+FROM+cpu+WHERE+time+%3E+now()+-+1d+GROUP+BY+time(1h)&rollup=true", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+}
+
+// Ensure the handler returns the series over the limit with a cursor.
+func TestHandler_Query_SeriesLimit(t *testing.T) {
+	h := NewHandler(false)
+	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error) {
+		return NewResultChan(
+			&influxql.Result{StatementID: 0, Series: influxql.Rows{{Name: "series0"}, {Name: "series1"}}},
+			&influxql.Result{StatementID: 0, Series: influxql.Rows{{Name: "series2"}}},
+			&influxql.Result{StatementID: 1, Series: influxql.Rows{{Name: "series3"}}},
+		), nil
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar%3BSELECT+*+FROM+baz&series_limit=2", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+	var resp httpd.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	} else if resp.Cursor == "" {
+		t.Fatalf("expected cursor: %s", w.Body.String())
+	} else if len(resp.Results) != 1 || len(resp.Results[0].Series) != 2 || resp.Results[0].Series[1].Name != "series1" {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+
+	// Resume the results at the cursor.
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?series_limit=2&cursor="+resp.Cursor, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if w.Body.String() != `{"results":[{"series":[{"name":"series2"}]},{"series":[{"name":"series3"}]}]}` {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+
+	// The cursor can only be resumed once.
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?cursor="+resp.Cursor, nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+}
+
+// Ensure the handler rejects an invalid series limit.
+func TestHandler_Query_ErrInvalidSeriesLimit(t *testing.T) {
+	h := NewHandler(false)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar&series_limit=-1", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if w.Body.String() != `{"error":"invalid series_limit: \"-1\""}` {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+}
+
+// Ensure the handler returns integer timestamps in the requested epoch.
+func TestHandler_Query_Epoch(t *testing.T) {
+	now := time.Unix(0, 1444000000123456789)
+	for _, tt := range []struct {
+		epoch string
+		time  int64
+	}{
+		{epoch: "ns", time: 1444000000123456789},
+		{epoch: "us", time: 1444000000123456},
+		{epoch: "u", time: 1444000000123456},
+		{epoch: "ms", time: 1444000000123},
+		{epoch: "s", time: 1444000000},
+	} {
+		h := NewHandler(false)
+		h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error) {
+			return NewResultChan(
+				&influxql.Result{StatementID: 0, Series: influxql.Rows{{Name: "cpu", Columns: []string{"time", "value"}, Values: [][]interface{}{{now, 1}}}}},
+			), nil
+		}
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+cpu&epoch="+tt.epoch, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: unexpected status: %d", tt.epoch, w.Code)
+		} else if exp := fmt.Sprintf(`{"results":[{"series":[{"name":"cpu","columns":["time","value"],"values":[[%d,1]]}]}]}`, tt.time); w.Body.String() != exp {
+			t.Fatalf("%s: unexpected body: %s", tt.epoch, w.Body.String())
+		}
+	}
+}
+
+// Ensure the handler rejects an unknown epoch.
+func TestHandler_Query_ErrInvalidEpoch(t *testing.T) {
+	h := NewHandler(false)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+cpu&epoch=d", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if w.Body.String() != `{"error":"invalid epoch: \"d\""}` {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+}
+
 // Ensure the handler merges results from the same statement.
 func TestHandler_Query_MergeResults(t *testing.T) {
 	h := NewHandler(false)
-	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int) (<-chan *influxql.Result, error) {
+	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error) {
 		return NewResultChan(
 			&influxql.Result{StatementID: 1, Series: influxql.Rows{{Name: "series0"}}},
 			&influxql.Result{StatementID: 1, Series: influxql.Rows{{Name: "series1"}}},
@@ -175,10 +508,73 @@ func TestHandler_Query_MergeResults(t *testing.T) {
 	}
 }
 
+// Ensure the handler keeps a statement partial when merging its results.
+func TestHandler_Query_MergePartialResults(t *testing.T) {
+	h := NewHandler(false)
+	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error) {
+		return NewResultChan(
+			&influxql.Result{StatementID: 1, Series: influxql.Rows{{Name: "series0"}}},
+			&influxql.Result{StatementID: 1, Partial: true},
+		), nil
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if w.Body.String() != `{"results":[{"series":[{"name":"series0"}],"partial":true}]}` {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+}
+
+// Ensure the handler requests statistics and returns them with the merged
+// results of a statement.
+func TestHandler_Query_Stats(t *testing.T) {
+	h := NewHandler(false)
+	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error) {
+		if !q.Stats {
+			t.Fatal("expected stats")
+		}
+		return NewResultChan(
+			&influxql.Result{StatementID: 1, Series: influxql.Rows{{Name: "series0"}}},
+			&influxql.Result{StatementID: 1, Series: influxql.Rows{{Name: "series1"}}, Stats: &influxql.Stats{SeriesN: 2, PointN: 10, CacheHitN: 4, Duration: 1500 * time.Microsecond}},
+		), nil
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar&stats=true", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if w.Body.String() != `{"results":[{"series":[{"name":"series0"},{"name":"series1"}],"stats":{"series":2,"points":10,"cache_hits":4,"duration":"1.5ms"}}]}` {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+}
+
+// Ensure the handler returns NaN as null and infinite values as strings.
+func TestHandler_Query_NonFiniteFloats(t *testing.T) {
+	h := NewHandler(false)
+	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error) {
+		return NewResultChan(
+			&influxql.Result{StatementID: 1, Series: influxql.Rows{{Name: "cpu", Columns: []string{"a", "b", "c"}, Values: [][]interface{}{
+				{1.5, math.NaN(), math.Inf(1)},
+				{math.Inf(-1), 2.0, "x"},
+			}}}},
+		), nil
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if w.Body.String() != `{"results":[{"series":[{"name":"cpu","columns":["a","b","c"],"values":[[1.5,null,"+Inf"],["-Inf",2,"x"]]}]}]}` {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+}
+
 // Ensure the handler can parse chunked and chunk size query parameters.
 func TestHandler_Query_Chunked(t *testing.T) {
 	h := NewHandler(false)
-	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int) (<-chan *influxql.Result, error) {
+	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error) {
 		if chunkSize != 2 {
 			t.Fatalf("unexpected chunk size: %d", chunkSize)
 		}
@@ -238,7 +634,7 @@ func TestHandler_Query_ErrInvalidQuery(t *testing.T) {
 // Ensure the handler returns a status 500 if an error is returned from the query executor.
 func TestHandler_Query_ErrExecuteQuery(t *testing.T) {
 	h := NewHandler(false)
-	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int) (<-chan *influxql.Result, error) {
+	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error) {
 		return nil, errors.New("marker")
 	}
 
@@ -252,7 +648,7 @@ func TestHandler_Query_ErrExecuteQuery(t *testing.T) {
 // Ensure the handler returns a status 200 if an error is returned in the result.
 func TestHandler_Query_ErrResult(t *testing.T) {
 	h := NewHandler(false)
-	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int) (<-chan *influxql.Result, error) {
+	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error) {
 		return NewResultChan(&influxql.Result{Err: errors.New("measurement not found")}), nil
 	}
 
@@ -265,6 +661,492 @@ func TestHandler_Query_ErrResult(t *testing.T) {
 	}
 }
 
+// Ensure the handler passes the read preference to the query executor and returns staleness.
+func TestHandler_Query_ReadPreference(t *testing.T) {
+	h := NewHandler(false)
+	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error) {
+		if readPref != tsdb.ReadPreferenceRandom {
+			t.Fatalf("unexpected read preference: %s", readPref)
+		}
+		return NewResultChan(&influxql.Result{Series: influxql.Rows{{Name: "series0"}}, Staleness: 2 * time.Second}), nil
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar&read_preference=random", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if w.Body.String() != `{"results":[{"series":[{"name":"series0"}],"staleness":"2s"}]}` {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+
+	// Unknown read preferences are rejected.
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar&read_preference=closest", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+}
+
+// Ensure the handler runs queries as batch queries if the priority header asks to.
+func TestHandler_Query_Priority(t *testing.T) {
+	h := NewHandler(false)
+	var batch bool
+	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error) {
+		batch = q.Batch
+		return NewResultChan(&influxql.Result{}), nil
+	}
+
+	for _, tt := range []struct {
+		priority string
+		code     int
+		batch    bool
+	}{
+		{priority: "", code: http.StatusOK},
+		{priority: "interactive", code: http.StatusOK},
+		{priority: "Batch", code: http.StatusOK, batch: true},
+		{priority: "low", code: http.StatusBadRequest},
+	} {
+		batch = false
+		r := MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar", nil)
+		if tt.priority != "" {
+			r.Header.Set("X-InfluxDB-Query-Priority", tt.priority)
+		}
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tt.code {
+			t.Fatalf("%q: unexpected status: %d", tt.priority, w.Code)
+		} else if batch != tt.batch {
+			t.Fatalf("%q: unexpected batch: %v", tt.priority, batch)
+		}
+	}
+}
+
+// Ensure the handler authenticates users with the authenticator, if set.
+func TestHandler_Query_Authenticator(t *testing.T) {
+	h := NewHandler(true)
+	h.MetaStore.UsersFn = func() ([]meta.UserInfo, error) { return nil, nil }
+	h.Authenticator = AuthenticatorFunc(func(username, password string) (*meta.UserInfo, error) {
+		if username != "susy" || password != "pass" {
+			return nil, meta.ErrAuthenticate
+		}
+		return &meta.UserInfo{Name: "susy", Admin: true}, nil
+	})
+	h.QueryExecutor.AuthorizeFn = func(u *meta.UserInfo, q *influxql.Query, db string) error {
+		if u == nil || u.Name != "susy" {
+			t.Fatalf("unexpected user: %#v", u)
+		}
+		return nil
+	}
+	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error) {
+		return NewResultChan(&influxql.Result{}), nil
+	}
+
+	// Credentials are required even though the meta store has no users.
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SHOW+DATABASES", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SHOW+DATABASES&u=susy&p=bad", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SHOW+DATABASES&u=susy&p=pass", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// Ensure the handler records administrative statements to the audit log.
+func TestHandler_Query_Audit(t *testing.T) {
+	var entries []audit.Entry
+	h := NewHandler(false)
+	h.AuditLog = AuditLogFunc(func(e audit.Entry) { entries = append(entries, e) })
+	h.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, db string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error) {
+		return NewResultChan(
+			&influxql.Result{StatementID: 0},
+			&influxql.Result{StatementID: 1},
+			&influxql.Result{StatementID: 2, Err: errors.New("marker")},
+		), nil
+	}
+
+	req := MustNewJSONRequest("GET", "/query?db=foo&q="+url.QueryEscape("CREATE DATABASE bar; SHOW DATABASES; DROP SERIES FROM cpu"), nil)
+	req.RemoteAddr = "10.0.0.1:4321"
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("unexpected entries: %#v", entries)
+	}
+	if e := entries[0]; e.Statement != "CREATE DATABASE bar" || e.Addr != "10.0.0.1" || e.Database != "foo" || e.Error != "" || e.Time.IsZero() {
+		t.Fatalf("unexpected entry: %#v", e)
+	}
+	if e := entries[1]; e.Statement != "DROP SERIES FROM cpu" || e.Error != "marker" {
+		t.Fatalf("unexpected entry: %#v", e)
+	}
+}
+
+// Ensure profiles are only served if pprof is enabled.
+func TestHandler_Debug_Pprof(t *testing.T) {
+	h := NewHandler(false)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("GET", "/debug/vars", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("GET", "/debug/pprof/", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+
+	h.PprofEnabled = true
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("GET", "/debug/pprof/", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+}
+
+// Ensure only admin users may access the debug endpoints when authentication is enabled.
+func TestHandler_Debug_RequireAdmin(t *testing.T) {
+	h := NewHandler(true)
+	h.PprofEnabled = true
+	h.MetaStore.UsersFn = func() ([]meta.UserInfo, error) {
+		return []meta.UserInfo{{Name: "susy", Admin: true}, {Name: "bob"}}, nil
+	}
+	h.MetaStore.AuthenticateFn = func(username, password string) (*meta.UserInfo, error) {
+		return &meta.UserInfo{Name: username, Admin: username == "susy"}, nil
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("GET", "/debug/vars", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("GET", "/debug/vars?u=bob&p=pass", nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("GET", "/debug/pprof/?u=susy&p=pass", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+}
+
+// Ensure the diagnostics bundle holds the profiles, statistics and additional files.
+func TestHandler_Debug_Diagnostics(t *testing.T) {
+	h := NewHandler(false)
+	h.PprofEnabled = true
+	h.Diagnostics = func() (map[string][]byte, error) {
+		return map[string][]byte{"config.toml": []byte("[meta]\n")}, errors.New("marker")
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("GET", "/debug/diagnostics?seconds=0", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[path.Base(hdr.Name)] = string(b)
+	}
+
+	for _, name := range []string{"goroutine.txt", "heap.pprof", "block.pprof", "vars.json"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("missing %s", name)
+		}
+	}
+	if _, ok := files["cpu.pprof"]; ok {
+		t.Errorf("unexpected cpu profile")
+	}
+	if files["config.toml"] != "[meta]\n" {
+		t.Errorf("unexpected config: %q", files["config.toml"])
+	}
+	if files["errors.txt"] != "diagnostics: marker\n" {
+		t.Errorf("unexpected errors: %q", files["errors.txt"])
+	}
+
+	// Invalid durations are rejected.
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("GET", "/debug/diagnostics?seconds=-1", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+}
+
+// Ensure the write throttles can be read and replaced.
+func TestHandler_Throttle(t *testing.T) {
+	h := NewHandler(false)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("GET", "/throttle", nil))
+	if w.Code != http.StatusNotImplemented {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+
+	var throttler HandlerWriteThrottler
+	h.WriteThrottler = &throttler
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/throttle", strings.NewReader(`{"global":{"pointsPerSecond":1000},"shards":{"2":{"bytesPerSecond":500}}}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if exp := (tsdb.WriteThrottles{
+		Global: tsdb.WriteThrottle{PointsPerSecond: 1000},
+		Shards: map[uint64]tsdb.WriteThrottle{2: {BytesPerSecond: 500}},
+	}); !reflect.DeepEqual(throttler.Throttles, exp) {
+		t.Fatalf("unexpected throttles: %+v", throttler.Throttles)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("GET", "/throttle", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if body := w.Body.String(); body != `{"global":{"pointsPerSecond":1000,"bytesPerSecond":0},"shard":{"pointsPerSecond":0,"bytesPerSecond":0},"shards":{"2":{"pointsPerSecond":0,"bytesPerSecond":500}}}` {
+		t.Fatalf("unexpected body: %s", body)
+	}
+
+	// Negative rates are rejected.
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/throttle", strings.NewReader(`{"shards":{"3":{"pointsPerSecond":-1}}}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if body := w.Body.String(); body != `{"error":"invalid shard 3 throttle: rates can't be negative"}` {
+		t.Fatalf("unexpected body: %s", body)
+	}
+}
+
+// Ensure only admin users may change the write throttles when authentication is enabled.
+func TestHandler_Throttle_RequireAdmin(t *testing.T) {
+	h := NewHandler(true)
+	h.WriteThrottler = &HandlerWriteThrottler{}
+	h.MetaStore.UsersFn = func() ([]meta.UserInfo, error) {
+		return []meta.UserInfo{{Name: "susy", Admin: true}, {Name: "bob"}}, nil
+	}
+	h.MetaStore.AuthenticateFn = func(username, password string) (*meta.UserInfo, error) {
+		return &meta.UserInfo{Name: username, Admin: username == "susy"}, nil
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/throttle?u=bob&p=pass", strings.NewReader(`{}`)))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/throttle?u=susy&p=pass", strings.NewReader(`{}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+}
+
+// Ensure the handler passes the requested consistency level to the points writer.
+func TestHandler_Write_Consistency(t *testing.T) {
+	h := NewHandler(false)
+	h.MetaStore.DatabaseFn = func(name string) (*meta.DatabaseInfo, error) {
+		return &meta.DatabaseInfo{Name: name}, nil
+	}
+
+	var level cluster.ConsistencyLevel
+	h.PointsWriter.WritePointsFn = func(p *cluster.WritePointsRequest) error {
+		level = p.ConsistencyLevel
+		return nil
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/write?db=foo&consistency=quorum", strings.NewReader("cpu value=1")))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	} else if level != cluster.ConsistencyLevelQuorum {
+		t.Fatalf("unexpected consistency level: %v", level)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("POST", "/write?consistency=all", strings.NewReader(`{"database":"foo","points":[{"measurement":"cpu","fields":{"value":1}}]}`)))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	} else if level != cluster.ConsistencyLevelAll {
+		t.Fatalf("unexpected consistency level: %v", level)
+	}
+}
+
+// Ensure the handler returns an error for an invalid consistency level.
+func TestHandler_Write_ErrInvalidConsistency(t *testing.T) {
+	h := NewHandler(false)
+	h.MetaStore.DatabaseFn = func(name string) (*meta.DatabaseInfo, error) {
+		return &meta.DatabaseInfo{Name: name}, nil
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/write?db=foo&consistency=some", strings.NewReader("cpu value=1")))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if body := strings.TrimSpace(w.Body.String()); body != "invalid consistency level" {
+		t.Fatalf("unexpected body: %s", body)
+	}
+}
+
+// Ensure the handler scales timestamps by the precision and rejects unknown precisions.
+func TestHandler_Write_Precision(t *testing.T) {
+	h := NewHandler(false)
+	h.MetaStore.DatabaseFn = func(name string) (*meta.DatabaseInfo, error) {
+		return &meta.DatabaseInfo{Name: name}, nil
+	}
+
+	var points []tsdb.Point
+	h.PointsWriter.WritePointsFn = func(p *cluster.WritePointsRequest) error {
+		points = p.Points
+		return nil
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/write?db=foo&precision=ms", strings.NewReader("cpu value=1 1500")))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	} else if len(points) != 1 || points[0].UnixNano() != 1500*int64(time.Millisecond) {
+		t.Fatalf("unexpected points: %v", points)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/write?db=foo&precision=d", strings.NewReader("cpu value=1 1500")))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if body := strings.TrimSpace(w.Body.String()); body != `invalid precision: "d"` {
+		t.Fatalf("unexpected body: %s", body)
+	}
+}
+
+// Ensure the handler returns 429 for writes over the rate limit of a database.
+func TestHandler_Write_ErrWriteLimitExceeded(t *testing.T) {
+	h := NewHandler(false)
+	h.MetaStore.DatabaseFn = func(name string) (*meta.DatabaseInfo, error) {
+		return &meta.DatabaseInfo{Name: name}, nil
+	}
+	h.PointsWriter.WritePointsFn = func(p *cluster.WritePointsRequest) error {
+		return cluster.ErrWriteLimitExceeded
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/write?db=foo", strings.NewReader("cpu value=1")))
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("POST", "/write", strings.NewReader(`{"database":"foo","points":[{"measurement":"cpu","fields":{"value":1}}]}`)))
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+}
+
+// Ensure the handler rejects, drops or stores NaN and infinite values by the float policy.
+func TestHandler_Write_FloatPolicy(t *testing.T) {
+	h := NewHandler(false)
+	h.MetaStore.DatabaseFn = func(name string) (*meta.DatabaseInfo, error) {
+		return &meta.DatabaseInfo{Name: name}, nil
+	}
+
+	var points []tsdb.Point
+	h.PointsWriter.WritePointsFn = func(p *cluster.WritePointsRequest) error {
+		points = p.Points
+		return nil
+	}
+
+	for i, tt := range []struct {
+		policy tsdb.FloatPolicy
+		url    string
+		code   int
+		n      int
+	}{
+		{tsdb.FloatPolicyReject, "/write?db=foo", http.StatusBadRequest, 0},
+		{tsdb.FloatPolicyReject, "/write?db=foo&float_policy=drop", http.StatusNoContent, 1},
+		{tsdb.FloatPolicyStore, "/write?db=foo", http.StatusNoContent, 2},
+		{tsdb.FloatPolicyStore, "/write?db=foo&float_policy=keep", http.StatusBadRequest, 0},
+	} {
+		points = nil
+		h.FloatPolicy = tt.policy
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, MustNewRequest("POST", tt.url, strings.NewReader("cpu value=1\ncpu value=NaN")))
+		if w.Code != tt.code {
+			t.Fatalf("%d: unexpected status: %d: %s", i, w.Code, w.Body.String())
+		} else if len(points) != tt.n {
+			t.Fatalf("%d: unexpected points: %v", i, points)
+		}
+	}
+}
+
+// Ensure the handler records the points of writes that fail to parse as dropped.
+func TestHandler_Write_ParseDroppedStatistics(t *testing.T) {
+	h := NewHandler(false)
+	h.MetaStore.DatabaseFn = func(name string) (*meta.DatabaseInfo, error) {
+		if name != "parsedb" {
+			return nil, nil
+		}
+		return &meta.DatabaseInfo{Name: name}, nil
+	}
+
+	stats := func() map[string]int64 {
+		m := make(map[string]int64)
+		if err := json.Unmarshal([]byte(influxdb.DatabaseStatistics("parsedb").String()), &m); err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	before := stats()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/write?db=parsedb", strings.NewReader("# comment\ncpu value=1\ncpu value=\n")))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+
+	// Writes to unknown databases are not recorded.
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/write?db=nodb", strings.NewReader("cpu value=")))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+
+	after := stats()
+	for k, exp := range map[string]int64{"points_dropped": 2, "points_dropped_parse": 2} {
+		if n := after[k] - before[k]; n != exp {
+			t.Errorf("unexpected %s: got %d, exp %d", k, n, exp)
+		}
+	}
+}
+
 func TestMarshalJSON_NoPretty(t *testing.T) {
 	if b := httpd.MarshalJSON(struct {
 		Name string `json:"name"`
@@ -362,6 +1244,7 @@ type Handler struct {
 	MetaStore     HandlerMetaStore
 	QueryExecutor HandlerQueryExecutor
 	TSDBStore     HandlerTSDBStore
+	PointsWriter  HandlerPointsWriter
 }
 
 // NewHandler returns a new instance of Handler.
@@ -372,10 +1255,22 @@ func NewHandler(requireAuthentication bool) *Handler {
 	}
 	h.Handler.MetaStore = &h.MetaStore
 	h.Handler.QueryExecutor = &h.QueryExecutor
+	h.Handler.PointsWriter = &h.PointsWriter
 	h.Handler.Version = "0.0.0"
 	return h
 }
 
+// HandlerWriteThrottler is a mock implementation of Handler.WriteThrottler.
+type HandlerWriteThrottler struct {
+	Throttles tsdb.WriteThrottles
+}
+
+func (t *HandlerWriteThrottler) WriteThrottles() tsdb.WriteThrottles { return t.Throttles }
+
+func (t *HandlerWriteThrottler) SetWriteThrottles(throttles tsdb.WriteThrottles) {
+	t.Throttles = throttles
+}
+
 // HandlerMetaStore is a mock implementation of Handler.MetaStore.
 type HandlerMetaStore struct {
 	DatabaseFn     func(name string) (*meta.DatabaseInfo, error)
@@ -398,17 +1293,38 @@ func (s *HandlerMetaStore) Users() ([]meta.UserInfo, error) {
 // HandlerQueryExecutor is a mock implementation of Handler.QueryExecutor.
 type HandlerQueryExecutor struct {
 	AuthorizeFn    func(u *meta.UserInfo, q *influxql.Query, db string) error
-	ExecuteQueryFn func(q *influxql.Query, db string, chunkSize int) (<-chan *influxql.Result, error)
+	ExecuteQueryFn func(q *influxql.Query, db string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error)
+
+	QueryLastModifiedFn func(q *influxql.Query, db string) (time.Time, []uint64, bool)
 }
 
 func (e *HandlerQueryExecutor) Authorize(u *meta.UserInfo, q *influxql.Query, db string) error {
 	return e.AuthorizeFn(u, q, db)
 }
 
-func (e *HandlerQueryExecutor) ExecuteQuery(q *influxql.Query, db string, chunkSize int) (<-chan *influxql.Result, error) {
-	return e.ExecuteQueryFn(q, db, chunkSize)
+func (e *HandlerQueryExecutor) ExecuteQuery(q *influxql.Query, db string, chunkSize int, readPref tsdb.ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error) {
+	return e.ExecuteQueryFn(q, db, chunkSize, readPref, u)
+}
+
+func (e *HandlerQueryExecutor) QueryLastModified(q *influxql.Query, db string) (time.Time, []uint64, bool) {
+	if e.QueryLastModifiedFn == nil {
+		return time.Time{}, nil, false
+	}
+	return e.QueryLastModifiedFn(q, db)
+}
+
+// AuthenticatorFunc is a function that implements httpd.Authenticator.
+type AuthenticatorFunc func(username, password string) (*meta.UserInfo, error)
+
+func (fn AuthenticatorFunc) Authenticate(username, password string) (*meta.UserInfo, error) {
+	return fn(username, password)
 }
 
+// AuditLogFunc is a function that implements Handler.AuditLog.
+type AuditLogFunc func(e audit.Entry)
+
+func (fn AuditLogFunc) Record(e audit.Entry) { fn(e) }
+
 // HandlerTSDBStore is a mock implementation of Handler.TSDBStore
 type HandlerTSDBStore struct {
 	CreateMapperFn func(shardID uint64, query string, chunkSize int) (tsdb.Mapper, error)
@@ -418,6 +1334,15 @@ func (h *HandlerTSDBStore) CreateMapper(shardID uint64, query string, chunkSize
 	return h.CreateMapperFn(shardID, query, chunkSize)
 }
 
+// HandlerPointsWriter is a mock implementation of Handler.PointsWriter.
+type HandlerPointsWriter struct {
+	WritePointsFn func(p *cluster.WritePointsRequest) error
+}
+
+func (w *HandlerPointsWriter) WritePoints(p *cluster.WritePointsRequest) error {
+	return w.WritePointsFn(p)
+}
+
 // MustNewRequest returns a new HTTP request. Panic on error.
 func MustNewRequest(method, urlStr string, body io.Reader) *http.Request {
 	r, err := http.NewRequest(method, urlStr, body)
@@ -0,0 +1,67 @@
+package httpd
+
+import (
+	"net/http"
+
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// ComponentHealth is the state of a component of the server, such as the
+// meta store or the shards of the data store.
+type ComponentHealth struct {
+	Name    string `json:"name"`
+	Healthy bool   `json:"healthy"`
+	Message string `json:"message,omitempty"`
+}
+
+// healthResponse is the body of /health.
+type healthResponse struct {
+	Healthy    bool              `json:"healthy"`
+	Components []ComponentHealth `json:"components"`
+}
+
+// serveHealth serves the state of the components returned by h.Health. It
+// responds 200 if every component is healthy and 503 otherwise.
+func (h *Handler) serveHealth(w http.ResponseWriter, r *http.Request) {
+	resp := healthResponse{Healthy: true, Components: []ComponentHealth{}}
+	if h.Health != nil {
+		resp.Components = h.Health()
+	}
+	for _, c := range resp.Components {
+		resp.Healthy = resp.Healthy && c.Healthy
+	}
+
+	w.Header().Add("content-type", "application/json")
+	if !resp.Healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if r.Method != "HEAD" {
+		w.Write(MarshalJSON(resp, r.URL.Query().Get("pretty") == "true"))
+	}
+}
+
+// serveReady responds 204 once h.Ready reports the server has started, and
+// 503 before then. Load balancers can hold traffic back until it's ready.
+// While the WALs of the shards replay, the progress of the replay is returned.
+func (h *Handler) serveReady(w http.ResponseWriter, r *http.Request) {
+	if h.Replay != nil {
+		if st := h.Replay(); !st.Done {
+			w.Header().Add("content-type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			if r.Method != "HEAD" {
+				w.Write(MarshalJSON(readyResponse{Replay: &st}, r.URL.Query().Get("pretty") == "true"))
+			}
+			return
+		}
+	}
+	if h.Ready != nil && !h.Ready() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// readyResponse is the body of /ready while the server isn't ready.
+type readyResponse struct {
+	Replay *tsdb.ReplayStatus `json:"replay,omitempty"`
+}
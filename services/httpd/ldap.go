@@ -0,0 +1,189 @@
+package httpd
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/influxdb/influxdb/meta"
+	"gopkg.in/ldap.v2"
+)
+
+const (
+	// DefaultLDAPUserFilter is the default filter finding the entry of a user.
+	DefaultLDAPUserFilter = "(uid=%s)"
+
+	// DefaultLDAPGroupAttribute is the default attribute listing the groups of a user.
+	DefaultLDAPGroupAttribute = "memberOf"
+)
+
+// LDAPConfig represents the configuration of the LDAP authentication provider.
+type LDAPConfig struct {
+	// URL is the address of the server: ldap://host:389 or ldaps://host:636.
+	URL string `toml:"url"`
+
+	// StartTLS upgrades ldap:// connections to TLS.
+	StartTLS           bool `toml:"start-tls"`
+	InsecureSkipVerify bool `toml:"insecure-skip-verify"`
+
+	// BindDN and BindPassword are the credentials the entries of users are
+	// searched with. Empty searches anonymously.
+	BindDN       string `toml:"bind-dn"`
+	BindPassword string `toml:"bind-password"`
+
+	// BaseDN is searched for the entry matching UserFilter, in which %s is
+	// replaced by the user name. GroupAttribute of the entry lists the groups
+	// of the user.
+	BaseDN         string `toml:"base-dn"`
+	UserFilter     string `toml:"user-filter"`
+	GroupAttribute string `toml:"group-attribute"`
+}
+
+// NewLDAPConfig returns a new instance of LDAPConfig with defaults.
+func NewLDAPConfig() LDAPConfig {
+	return LDAPConfig{
+		UserFilter:     DefaultLDAPUserFilter,
+		GroupAttribute: DefaultLDAPGroupAttribute,
+	}
+}
+
+// LDAPAuthenticator authenticates users by binding to an LDAP server as the
+// entry of the user. Every authentication connects to the server.
+type LDAPAuthenticator struct {
+	network   string
+	addr      string
+	tls       bool
+	startTLS  bool
+	tlsConfig *tls.Config
+	config    LDAPConfig
+	groups    groupMapping
+}
+
+// NewLDAPAuthenticator returns an authenticator for the server configured by
+// c, whose groups are mapped to privileges by groups.
+func NewLDAPAuthenticator(c LDAPConfig, groups []GroupConfig) (*LDAPAuthenticator, error) {
+	m, err := newGroupMapping(groups)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return nil, fmt.Errorf("ldap url: %s", err)
+	}
+
+	a := &LDAPAuthenticator{
+		network:  "tcp",
+		addr:     u.Host,
+		startTLS: c.StartTLS,
+		config:   c,
+		groups:   m,
+	}
+	switch u.Scheme {
+	case "ldap":
+		if _, _, err := net.SplitHostPort(a.addr); err != nil {
+			a.addr = net.JoinHostPort(a.addr, "389")
+		}
+	case "ldaps":
+		a.tls = true
+		if _, _, err := net.SplitHostPort(a.addr); err != nil {
+			a.addr = net.JoinHostPort(a.addr, "636")
+		}
+	default:
+		return nil, fmt.Errorf("ldap url: unknown scheme: %q", u.Scheme)
+	}
+
+	host, _, _ := net.SplitHostPort(a.addr)
+	a.tlsConfig = &tls.Config{ServerName: host, InsecureSkipVerify: c.InsecureSkipVerify}
+
+	if a.config.UserFilter == "" {
+		a.config.UserFilter = DefaultLDAPUserFilter
+	} else if !strings.Contains(a.config.UserFilter, "%s") {
+		return nil, errors.New("ldap user filter must contain %s")
+	}
+	if a.config.GroupAttribute == "" {
+		a.config.GroupAttribute = DefaultLDAPGroupAttribute
+	}
+	return a, nil
+}
+
+// Authenticate returns the user with a matching name and password.
+func (a *LDAPAuthenticator) Authenticate(username, password string) (*meta.UserInfo, error) {
+	// An LDAP bind without a password succeeds anonymously.
+	if password == "" {
+		return nil, meta.ErrAuthenticate
+	}
+
+	conn, err := a.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// Find the entry of the user.
+	if a.config.BindDN != "" {
+		if err := conn.Bind(a.config.BindDN, a.config.BindPassword); err != nil {
+			return nil, fmt.Errorf("ldap bind: %s", err)
+		}
+	}
+	res, err := conn.Search(ldap.NewSearchRequest(
+		a.config.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 0, false,
+		fmt.Sprintf(a.config.UserFilter, escapeLDAPFilter(username)),
+		[]string{a.config.GroupAttribute},
+		nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("ldap search: %s", err)
+	} else if len(res.Entries) != 1 {
+		return nil, meta.ErrAuthenticate
+	}
+	entry := res.Entries[0]
+
+	// Verify the password by binding as the user.
+	if err := conn.Bind(entry.DN, password); err != nil {
+		return nil, meta.ErrAuthenticate
+	}
+
+	return a.groups.userInfo(username, entry.GetAttributeValues(a.config.GroupAttribute)), nil
+}
+
+// dial connects to the LDAP server.
+func (a *LDAPAuthenticator) dial() (*ldap.Conn, error) {
+	if a.tls {
+		conn, err := ldap.DialTLS(a.network, a.addr, a.tlsConfig)
+		if err != nil {
+			return nil, fmt.Errorf("ldap dial: %s", err)
+		}
+		return conn, nil
+	}
+
+	conn, err := ldap.Dial(a.network, a.addr)
+	if err != nil {
+		return nil, fmt.Errorf("ldap dial: %s", err)
+	}
+	if a.startTLS {
+		if err := conn.StartTLS(a.tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ldap start tls: %s", err)
+		}
+	}
+	return conn, nil
+}
+
+// escapeLDAPFilter escapes the characters of s with a special meaning in an
+// LDAP search filter, as described in RFC 4515.
+func escapeLDAPFilter(s string) string {
+	var buf []byte
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\', '*', '(', ')', 0:
+			buf = append(buf, fmt.Sprintf(`\%02x`, c)...)
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return string(buf)
+}
@@ -4,13 +4,14 @@ import (
 	"crypto/tls"
 	"expvar"
 	"fmt"
-	"log"
 	"net"
 	"net/http"
 	"os"
 	"strings"
 
 	"github.com/influxdb/influxdb"
+	"github.com/influxdb/influxdb/logger"
+	"github.com/influxdb/influxdb/tsdb"
 )
 
 // statistics gathered by the httpd package.
@@ -25,6 +26,15 @@ const (
 	statPointsWrittenOK              = "points_written_ok"   // Number of points written OK
 	statPointsWrittenFail            = "points_written_fail" // Number of points that failed to be written
 	statAuthFail                     = "auth_fail"           // Number of authentication failures
+	statQueryRequestDuration         = "query_req_dur"       // Sum of time spent serving query requests, in ns
+	statQueryNotModified             = "query_not_modified"  // Number of query requests answered with 304 Not Modified
+	statWriteRequestDuration         = "write_req_dur"       // Sum of time spent serving write requests, in ns
+)
+
+// statistics gathered by the httpd package for each database.
+const (
+	statDatabasePointsDropped      = "points_dropped"       // Number of points that could not be written
+	statDatabasePointsDroppedParse = "points_dropped_parse" // Points dropped because the write could not be parsed
 )
 
 // Service manages the listener and handler for an HTTP endpoint.
@@ -33,11 +43,12 @@ type Service struct {
 	addr  string
 	https bool
 	cert  string
+	auth  Config
 	err   chan error
 
 	Handler *Handler
 
-	Logger  *log.Logger
+	Logger  *logger.Logger
 	statMap *expvar.Map
 }
 
@@ -53,6 +64,7 @@ func NewService(c Config) *Service {
 		addr:  c.BindAddress,
 		https: c.HttpsEnabled,
 		cert:  c.HttpsCertificate,
+		auth:  c,
 		err:   make(chan error),
 		Handler: NewHandler(
 			c.AuthEnabled,
@@ -60,16 +72,34 @@ func NewService(c Config) *Service {
 			c.WriteTracing,
 			statMap,
 		),
-		Logger: log.New(os.Stderr, "[httpd] ", log.LstdFlags),
+		Logger: logger.New(os.Stderr, "httpd"),
 	}
 	s.Handler.Logger = s.Logger
+	s.Handler.PprofEnabled = c.PprofEnabled
 	return s
 }
 
 // Open starts the service
 func (s *Service) Open() error {
-	s.Logger.Println("Starting HTTP service")
-	s.Logger.Println("Authentication enabled:", s.Handler.requireAuthentication)
+	s.Logger.Info("Starting HTTP service", "auth_enabled", s.Handler.requireAuthentication)
+
+	// Authenticate users with the configured provider unless one is set.
+	if s.Handler.requireAuthentication && s.Handler.Authenticator == nil {
+		a, err := NewAuthenticator(s.auth)
+		if err != nil {
+			return fmt.Errorf("auth provider: %s", err)
+		}
+		s.Handler.Authenticator = a
+	}
+
+	// Handle NaN and infinite values with the configured policy.
+	if s.auth.FloatPolicy != "" {
+		policy, err := tsdb.ParseFloatPolicy(s.auth.FloatPolicy)
+		if err != nil {
+			return fmt.Errorf("float policy: %s", err)
+		}
+		s.Handler.FloatPolicy = policy
+	}
 
 	// Open listener.
 	if s.https {
@@ -85,7 +115,7 @@ func (s *Service) Open() error {
 			return err
 		}
 
-		s.Logger.Println("Listening on HTTPS:", listener.Addr().String())
+		s.Logger.Info("Listening on HTTPS", "addr", listener.Addr().String())
 		s.ln = listener
 	} else {
 		listener, err := net.Listen("tcp", s.addr)
@@ -93,7 +123,7 @@ func (s *Service) Open() error {
 			return err
 		}
 
-		s.Logger.Println("Listening on HTTP:", listener.Addr().String())
+		s.Logger.Info("Listening on HTTP", "addr", listener.Addr().String())
 		s.ln = listener
 	}
 
@@ -111,8 +141,9 @@ func (s *Service) Close() error {
 }
 
 // SetLogger sets the internal logger to the logger passed in.
-func (s *Service) SetLogger(l *log.Logger) {
+func (s *Service) SetLogger(l *logger.Logger) {
 	s.Logger = l
+	s.Handler.Logger = l
 }
 
 // Err returns a channel for fatal errors that occur on the listener.
@@ -0,0 +1,48 @@
+package retention
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// auditRecord is a single entry in the audit log.
+type auditRecord struct {
+	Time            time.Time  `json:"time"`
+	Action          string     `json:"action"`
+	Database        string     `json:"database"`
+	RetentionPolicy string     `json:"retention_policy"`
+	ShardGroupID    uint64     `json:"shard_group_id,omitempty"`
+	ShardID         uint64     `json:"shard_id,omitempty"`
+	StartTime       *time.Time `json:"start_time,omitempty"`
+	EndTime         *time.Time `json:"end_time,omitempty"`
+	DryRun          bool       `json:"dry_run"`
+	Error           string     `json:"error,omitempty"`
+}
+
+// auditLog writes audit records as newline-delimited JSON.
+type auditLog struct {
+	mu  sync.Mutex
+	w   io.WriteCloser
+	enc *json.Encoder
+}
+
+// newAuditLog returns an audit log writing to w.
+func newAuditLog(w io.WriteCloser) *auditLog {
+	return &auditLog{w: w, enc: json.NewEncoder(w)}
+}
+
+// Write appends rec to the log.
+func (l *auditLog) Write(rec auditRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.enc.Encode(rec)
+}
+
+// Close closes the underlying writer.
+func (l *auditLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Close()
+}
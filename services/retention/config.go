@@ -9,6 +9,16 @@ import (
 type Config struct {
 	Enabled       bool          `toml:"enabled"`
 	CheckInterval toml.Duration `toml:"check-interval"`
+
+	// DryRun reports expired shard groups and shards without deleting them.
+	DryRun bool `toml:"dry-run"`
+
+	// MinimumAge is an additional safety margin a shard group must be past
+	// its retention policy's duration before it is deleted.
+	MinimumAge toml.Duration `toml:"minimum-age"`
+
+	// AuditLogPath is the file deletions are recorded to. Empty disables the audit log.
+	AuditLogPath string `toml:"audit-log-path"`
 }
 
 func NewConfig() Config {
@@ -14,6 +14,9 @@ func TestConfig_Parse(t *testing.T) {
 	if _, err := toml.Decode(`
 enabled = true
 check-interval = "1s"
+dry-run = true
+minimum-age = "1h"
+audit-log-path = "/tmp/retention.log"
 `, &c); err != nil {
 		t.Fatal(err)
 	}
@@ -23,5 +26,11 @@ check-interval = "1s"
 		t.Fatalf("unexpected enabled state: %v", c.Enabled)
 	} else if time.Duration(c.CheckInterval) != time.Second {
 		t.Fatalf("unexpected check interval: %v", c.CheckInterval)
+	} else if c.DryRun != true {
+		t.Fatalf("unexpected dry run state: %v", c.DryRun)
+	} else if time.Duration(c.MinimumAge) != time.Hour {
+		t.Fatalf("unexpected minimum age: %v", c.MinimumAge)
+	} else if c.AuditLogPath != "/tmp/retention.log" {
+		t.Fatalf("unexpected audit log path: %s", c.AuditLogPath)
 	}
 }
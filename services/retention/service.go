@@ -1,6 +1,7 @@
 package retention
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"sync"
@@ -23,9 +24,13 @@ type Service struct {
 
 	enabled       bool
 	checkInterval time.Duration
+	dryRun        bool
+	minimumAge    time.Duration
+	auditLogPath  string
 	wg            sync.WaitGroup
 	done          chan struct{}
 
+	audit  *auditLog
 	logger *log.Logger
 }
 
@@ -33,6 +38,9 @@ type Service struct {
 func NewService(c Config) *Service {
 	return &Service{
 		checkInterval: time.Duration(c.CheckInterval),
+		dryRun:        c.DryRun,
+		minimumAge:    time.Duration(c.MinimumAge),
+		auditLogPath:  c.AuditLogPath,
 		done:          make(chan struct{}),
 		logger:        log.New(os.Stderr, "[retention] ", log.LstdFlags),
 	}
@@ -41,6 +49,18 @@ func NewService(c Config) *Service {
 // Open starts retention policy enforcement.
 func (s *Service) Open() error {
 	s.logger.Println("Starting retention policy enforcement service with check interval of", s.checkInterval)
+	if s.dryRun {
+		s.logger.Println("dry-run enabled, expired data will be reported but not deleted")
+	}
+
+	if s.auditLogPath != "" {
+		f, err := os.OpenFile(s.auditLogPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("open audit log: %s", err)
+		}
+		s.audit = newAuditLog(f)
+	}
+
 	s.wg.Add(2)
 	go s.deleteShardGroups()
 	go s.deleteShards()
@@ -52,6 +72,9 @@ func (s *Service) Close() error {
 	s.logger.Println("retention policy enforcement terminating")
 	close(s.done)
 	s.wg.Wait()
+	if s.audit != nil {
+		return s.audit.Close()
+	}
 	return nil
 }
 
@@ -77,22 +100,42 @@ func (s *Service) deleteShardGroups() {
 				continue
 			}
 			s.logger.Println("retention policy enforcement check commencing")
-
-			s.MetaStore.VisitRetentionPolicies(func(d meta.DatabaseInfo, r meta.RetentionPolicyInfo) {
-				for _, g := range r.ExpiredShardGroups(time.Now().UTC()) {
-					if err := s.MetaStore.DeleteShardGroup(d.Name, r.Name, g.ID); err != nil {
-						s.logger.Printf("failed to delete shard group %d from database %s, retention policy %s: %s",
-							g.ID, d.Name, r.Name, err.Error())
-					} else {
-						s.logger.Printf("deleted shard group %d from database %s, retention policy %s",
-							g.ID, d.Name, r.Name)
-					}
-				}
-			})
+			s.deleteExpiredShardGroups(time.Now().UTC())
 		}
 	}
 }
 
+// deleteExpiredShardGroups deletes shard groups which expired at least
+// the minimum age before now.
+func (s *Service) deleteExpiredShardGroups(now time.Time) {
+	s.MetaStore.VisitRetentionPolicies(func(d meta.DatabaseInfo, r meta.RetentionPolicyInfo) {
+		for _, g := range r.ExpiredShardGroups(now.Add(-s.minimumAge)) {
+			rec := auditRecord{
+				Action:          "delete_shard_group",
+				Database:        d.Name,
+				RetentionPolicy: r.Name,
+				ShardGroupID:    g.ID,
+				StartTime:       &g.StartTime,
+				EndTime:         &g.EndTime,
+				DryRun:          s.dryRun,
+			}
+
+			if s.dryRun {
+				s.logger.Printf("dry-run: would delete shard group %d from database %s, retention policy %s",
+					g.ID, d.Name, r.Name)
+			} else if err := s.MetaStore.DeleteShardGroup(d.Name, r.Name, g.ID); err != nil {
+				s.logger.Printf("failed to delete shard group %d from database %s, retention policy %s: %s",
+					g.ID, d.Name, r.Name, err.Error())
+				rec.Error = err.Error()
+			} else {
+				s.logger.Printf("deleted shard group %d from database %s, retention policy %s",
+					g.ID, d.Name, r.Name)
+			}
+			s.record(now, rec)
+		}
+	})
+}
+
 func (s *Service) deleteShards() {
 	defer s.wg.Done()
 
@@ -105,25 +148,58 @@ func (s *Service) deleteShards() {
 
 		case <-ticker.C:
 			s.logger.Println("retention policy shard deletion check commencing")
+			s.deleteLocalShards(time.Now().UTC())
+		}
+	}
+}
 
-			deletedShardIDs := make(map[uint64]struct{}, 0)
-			s.MetaStore.VisitRetentionPolicies(func(d meta.DatabaseInfo, r meta.RetentionPolicyInfo) {
-				for _, g := range r.DeletedShardGroups() {
-					for _, sh := range g.Shards {
-						deletedShardIDs[sh.ID] = struct{}{}
-					}
-				}
-			})
-
-			for _, id := range s.TSDBStore.ShardIDs() {
-				if _, ok := deletedShardIDs[id]; ok {
-					if err := s.TSDBStore.DeleteShard(id); err != nil {
-						s.logger.Printf("failed to delete shard ID %d: %s", id, err.Error())
-						continue
-					}
-					s.logger.Printf("shard ID %d deleted", id)
-				}
+// deleteLocalShards deletes local shards belonging to deleted shard groups.
+func (s *Service) deleteLocalShards(now time.Time) {
+	type shardOwner struct {
+		database, policy string
+	}
+	deletedShardIDs := make(map[uint64]shardOwner, 0)
+	s.MetaStore.VisitRetentionPolicies(func(d meta.DatabaseInfo, r meta.RetentionPolicyInfo) {
+		for _, g := range r.DeletedShardGroups() {
+			for _, sh := range g.Shards {
+				deletedShardIDs[sh.ID] = shardOwner{database: d.Name, policy: r.Name}
 			}
 		}
+	})
+
+	for _, id := range s.TSDBStore.ShardIDs() {
+		owner, ok := deletedShardIDs[id]
+		if !ok {
+			continue
+		}
+
+		rec := auditRecord{
+			Action:          "delete_shard",
+			Database:        owner.database,
+			RetentionPolicy: owner.policy,
+			ShardID:         id,
+			DryRun:          s.dryRun,
+		}
+
+		if s.dryRun {
+			s.logger.Printf("dry-run: would delete shard ID %d", id)
+		} else if err := s.TSDBStore.DeleteShard(id); err != nil {
+			s.logger.Printf("failed to delete shard ID %d: %s", id, err.Error())
+			rec.Error = err.Error()
+		} else {
+			s.logger.Printf("shard ID %d deleted", id)
+		}
+		s.record(now, rec)
+	}
+}
+
+// record writes rec to the audit log, if enabled.
+func (s *Service) record(now time.Time, rec auditRecord) {
+	if s.audit == nil {
+		return
+	}
+	rec.Time = now
+	if err := s.audit.Write(rec); err != nil {
+		s.logger.Printf("failed to write audit log: %s", err)
 	}
 }
@@ -0,0 +1,146 @@
+package retention
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/meta"
+	"github.com/influxdb/influxdb/toml"
+)
+
+// Ensure expired shard groups are only deleted once past the minimum age.
+func TestService_DeleteExpiredShardGroups_MinimumAge(t *testing.T) {
+	now := time.Date(2000, time.January, 10, 0, 0, 0, 0, time.UTC)
+	ms := NewMetaStore(now)
+
+	c := NewConfig()
+	c.MinimumAge = toml.Duration(36 * time.Hour)
+	s := NewTestService(c, ms)
+	s.deleteExpiredShardGroups(now)
+
+	// Only the oldest group has been expired for longer than the margin.
+	if !reflect.DeepEqual(ms.deleted, []uint64{1}) {
+		t.Fatalf("unexpected deleted shard groups: %v", ms.deleted)
+	}
+}
+
+// Ensure dry-run reports expired shard groups and shards without deleting them.
+func TestService_DryRun(t *testing.T) {
+	now := time.Date(2000, time.January, 10, 0, 0, 0, 0, time.UTC)
+	ms := NewMetaStore(now)
+	ms.rpi.ShardGroups = append(ms.rpi.ShardGroups, meta.ShardGroupInfo{
+		ID:        3,
+		DeletedAt: now,
+		Shards:    []meta.ShardInfo{{ID: 30}},
+	})
+	ts := &TSDBStore{ids: []uint64{30}}
+
+	c := NewConfig()
+	c.DryRun = true
+	s := NewTestService(c, ms)
+	s.TSDBStore = ts
+
+	var buf bytes.Buffer
+	s.audit = newAuditLog(nopCloser{&buf})
+
+	s.deleteExpiredShardGroups(now)
+	s.deleteLocalShards(now)
+
+	if len(ms.deleted) != 0 {
+		t.Fatalf("unexpected deleted shard groups: %v", ms.deleted)
+	} else if len(ts.deleted) != 0 {
+		t.Fatalf("unexpected deleted shards: %v", ts.deleted)
+	}
+
+	// Ensure the would-be deletions are recorded in the audit log.
+	dec := json.NewDecoder(&buf)
+	var recs []auditRecord
+	for {
+		var rec auditRecord
+		if err := dec.Decode(&rec); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		recs = append(recs, rec)
+	}
+	if len(recs) != 3 {
+		t.Fatalf("unexpected audit record count: %d", len(recs))
+	}
+	for i, exp := range []struct {
+		action string
+		id     uint64
+	}{{"delete_shard_group", 1}, {"delete_shard_group", 2}, {"delete_shard", 30}} {
+		rec := recs[i]
+		if rec.Action != exp.action || !rec.DryRun || rec.Database != "db0" || rec.RetentionPolicy != "rp0" {
+			t.Fatalf("unexpected audit record %d: %#v", i, rec)
+		} else if id := rec.ShardGroupID + rec.ShardID; id != exp.id {
+			t.Fatalf("unexpected audit record %d id: %d", i, id)
+		}
+	}
+}
+
+// NewTestService returns a service with a discarded logger.
+func NewTestService(c Config, ms *MetaStore) *Service {
+	s := NewService(c)
+	s.MetaStore = ms
+	s.logger = log.New(ioutil.Discard, "", 0)
+	return s
+}
+
+// MetaStore is a mock meta store with a single retention policy.
+type MetaStore struct {
+	rpi     meta.RetentionPolicyInfo
+	deleted []uint64
+}
+
+// NewMetaStore returns a meta store with shard groups that expired two days
+// and one day before now, and one that has not expired.
+func NewMetaStore(now time.Time) *MetaStore {
+	return &MetaStore{
+		rpi: meta.RetentionPolicyInfo{
+			Name:     "rp0",
+			Duration: 24 * time.Hour,
+			ShardGroups: []meta.ShardGroupInfo{
+				{ID: 1, EndTime: now.Add(-72 * time.Hour)},
+				{ID: 2, EndTime: now.Add(-48 * time.Hour)},
+				{ID: 4, EndTime: now},
+			},
+		},
+	}
+}
+
+func (ms *MetaStore) IsLeader() bool { return true }
+
+func (ms *MetaStore) VisitRetentionPolicies(f func(d meta.DatabaseInfo, r meta.RetentionPolicyInfo)) {
+	f(meta.DatabaseInfo{Name: "db0"}, ms.rpi)
+}
+
+func (ms *MetaStore) DeleteShardGroup(database, policy string, id uint64) error {
+	ms.deleted = append(ms.deleted, id)
+	return nil
+}
+
+// TSDBStore is a mock store of local shards.
+type TSDBStore struct {
+	ids     []uint64
+	deleted []uint64
+}
+
+func (ts *TSDBStore) ShardIDs() []uint64 { return ts.ids }
+
+func (ts *TSDBStore) DeleteShard(id uint64) error {
+	ts.deleted = append(ts.deleted, id)
+	return nil
+}
+
+// nopCloser wraps a buffer as an io.WriteCloser.
+type nopCloser struct{ *bytes.Buffer }
+
+func (nopCloser) Close() error { return nil }
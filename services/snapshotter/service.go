@@ -2,15 +2,19 @@ package snapshotter
 
 import (
 	"encoding"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"os"
 	"strings"
 	"sync"
+	"time"
 
-	"github.com/influxdb/influxdb/snapshot"
+	"github.com/influxdb/influxdb/tcp"
 	"github.com/influxdb/influxdb/tsdb"
 )
 
@@ -95,51 +99,206 @@ func (s *Service) serve() {
 
 // handleConn processes conn. This is run in a separate goroutine.
 func (s *Service) handleConn(conn net.Conn) error {
-	// Read manifest from connection.
-	m, err := s.readManifest(conn)
-	if err != nil {
-		return fmt.Errorf("read manifest: %s", err)
+	// Read request from connection.
+	var r Request
+	if err := json.NewDecoder(conn).Decode(&r); err != nil {
+		return fmt.Errorf("read request: %s", err)
 	}
 
-	// Write snapshot to connection.
-	if err := s.writeSnapshot(conn, m); err != nil {
-		return fmt.Errorf("write snapshot: %s", err)
+	switch r.Type {
+	case RequestMetastoreBackup:
+		if err := s.writeMetastoreBackup(conn); err != nil {
+			return fmt.Errorf("write metastore backup: %s", err)
+		}
+	case RequestShardBackup:
+		if err := s.writeShardBackup(conn, r.ShardID, r.Since); err != nil {
+			return fmt.Errorf("write shard backup: id=%d, err=%s", r.ShardID, err)
+		}
+	default:
+		err := fmt.Errorf("request type unknown: %d", r.Type)
+		writeResponse(conn, &Response{Err: err.Error()})
+		return err
 	}
 
 	return nil
 }
 
-// readManifest reads the manifest size and contents from conn.
-// Unmarshals the bytes and returns a manifest object.
-func (s *Service) readManifest(conn net.Conn) (snapshot.Manifest, error) {
-	var m snapshot.Manifest
-	if err := json.NewDecoder(conn).Decode(&m); err != nil {
-		return m, err
-	}
-	return m, nil
-}
-
-// writeSnapshot creates a snapshot writer, trims the manifest, and writes to conn.
-func (s *Service) writeSnapshot(conn net.Conn, prev snapshot.Manifest) error {
+// writeMetastoreBackup writes a snapshot of the meta store to w.
+func (s *Service) writeMetastoreBackup(w io.Writer) error {
 	// Retrieve and serialize the current meta data.
 	buf, err := s.MetaStore.MarshalBinary()
 	if err != nil {
+		writeResponse(w, &Response{Err: err.Error()})
 		return fmt.Errorf("marshal meta: %s", err)
 	}
 
-	// Build a snapshot writer.
-	sw, err := tsdb.NewSnapshotWriter(buf, s.TSDBStore)
+	if err := writeResponse(w, &Response{ModTime: time.Now().UTC()}); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, uint64(len(buf))); err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+// writeShardBackup writes the data of a shard to w if it was modified after since.
+func (s *Service) writeShardBackup(w io.Writer, shardID uint64, since time.Time) error {
+	sh := s.TSDBStore.Shard(shardID)
+	if sh == nil {
+		writeResponse(w, &Response{Err: tsdb.ErrShardNotFound.Error()})
+		return tsdb.ErrShardNotFound
+	}
+
+	// Skip the data if the shard hasn't changed since the last backup.
+	modTime := sh.LastModified()
+	if !since.IsZero() && !modTime.After(since) {
+		return writeResponse(w, &Response{ModTime: modTime, NotModified: true})
+	}
+
+	if err := writeResponse(w, &Response{ModTime: modTime}); err != nil {
+		return err
+	}
+
+	// Hide the connection's ReadFrom so that bolt's direct I/O file isn't
+	// handed to sendfile.
+	_, err := sh.WriteTo(struct{ io.Writer }{w})
+	return err
+}
+
+// RequestType indicates the type of backup requested.
+type RequestType uint8
+
+const (
+	// RequestMetastoreBackup requests a snapshot of the meta store.
+	RequestMetastoreBackup RequestType = iota + 1
+
+	// RequestShardBackup requests the data of a single shard.
+	RequestShardBackup
+)
+
+// Request represents a request for a backup from the snapshotter service.
+type Request struct {
+	Type    RequestType `json:"type"`
+	ShardID uint64      `json:"shardID,omitempty"`
+
+	// Shard data is only returned if it was modified after Since.
+	Since time.Time `json:"since,omitempty"`
+}
+
+// Response is written ahead of the requested data. Unless there is an error
+// or the shard was not modified, it is followed by the size of the data as
+// a big-endian uint64 and then the data itself.
+type Response struct {
+	ModTime     time.Time `json:"modTime"`
+	NotModified bool      `json:"notModified,omitempty"`
+	Err         string    `json:"error,omitempty"`
+}
+
+// writeResponse writes a length-prefixed response header to w.
+func writeResponse(w io.Writer, resp *Response) error {
+	b, err := json.Marshal(resp)
 	if err != nil {
-		return fmt.Errorf("create snapshot writer: %s", err)
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, uint32(len(b))); err != nil {
+		return err
 	}
+	_, err = w.Write(b)
+	return err
+}
 
-	// Trim old files from snapshot.
-	sw.Manifest = sw.Manifest.Diff(&prev)
+// readResponse reads a length-prefixed response header from r.
+func readResponse(r io.Reader) (*Response, error) {
+	var n uint32
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return nil, err
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
 
-	// Write snapshot out to connection.
-	if _, err := sw.WriteTo(conn); err != nil {
-		return fmt.Errorf("write to: %s", err)
+	var resp Response
+	if err := json.Unmarshal(b, &resp); err != nil {
+		return nil, err
 	}
+	return &resp, nil
+}
 
-	return nil
+// Client provides an API for the snapshotter service.
+type Client struct {
+	host string
+}
+
+// NewClient returns a new instance of Client.
+func NewClient(host string) *Client {
+	return &Client{host: host}
+}
+
+// Backup streams backup data from a snapshotter service.
+type Backup struct {
+	io.ReadCloser
+
+	// Size of the data in bytes.
+	Size int64
+
+	// Time the data was last modified.
+	ModTime time.Time
+}
+
+// MetastoreBackup returns a snapshot of the meta store.
+// Returned Backup must be closed by the caller.
+func (c *Client) MetastoreBackup() (*Backup, error) {
+	return c.backup(&Request{Type: RequestMetastoreBackup})
+}
+
+// ShardBackup returns the data of a shard. Returns nil if since is set and
+// the shard has not been modified after it.
+// Returned Backup must be closed by the caller.
+func (c *Client) ShardBackup(id uint64, since time.Time) (*Backup, error) {
+	return c.backup(&Request{Type: RequestShardBackup, ShardID: id, Since: since})
+}
+
+func (c *Client) backup(r *Request) (*Backup, error) {
+	// Connect to remote server.
+	conn, err := tcp.Dial("tcp", c.host, MuxHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send request to server.
+	if err := json.NewEncoder(conn).Encode(r); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("write request: %s", err)
+	}
+
+	// Read response from the server.
+	resp, err := readResponse(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("read response: %s", err)
+	} else if resp.Err != "" {
+		conn.Close()
+		return nil, errors.New(resp.Err)
+	} else if resp.NotModified {
+		conn.Close()
+		return nil, nil
+	}
+
+	// Read the size of the data that follows.
+	var n uint64
+	if err := binary.Read(conn, binary.BigEndian, &n); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("read size: %s", err)
+	}
+
+	return &Backup{
+		ReadCloser: struct {
+			io.Reader
+			io.Closer
+		}{io.LimitReader(conn, int64(n)), conn},
+		Size:    int64(n),
+		ModTime: resp.ModTime,
+	}, nil
 }
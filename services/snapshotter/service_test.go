@@ -1 +1,145 @@
 package snapshotter_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/services/snapshotter"
+	"github.com/influxdb/influxdb/tcp"
+	"github.com/influxdb/influxdb/tsdb"
+	_ "github.com/influxdb/influxdb/tsdb/engine"
+)
+
+// Ensure the service returns the meta store through the client.
+func TestService_MetastoreBackup(t *testing.T) {
+	s := MustOpenService(t)
+	defer s.Close()
+
+	b, err := snapshotter.NewClient(s.Addr()).MetastoreBackup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	if buf, err := ioutil.ReadAll(b); err != nil {
+		t.Fatal(err)
+	} else if string(buf) != "META" {
+		t.Fatalf("unexpected meta: %q", buf)
+	} else if b.Size != 4 {
+		t.Fatalf("unexpected size: %d", b.Size)
+	}
+}
+
+// Ensure the service streams shard data and honors the since time.
+func TestService_ShardBackup(t *testing.T) {
+	s := MustOpenService(t)
+	defer s.Close()
+
+	if err := s.TSDBStore.CreateShard("db0", "rp0", 1); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := tsdb.ParsePoints([]byte("cpu value=1"))
+	if err := s.TSDBStore.WriteToShard(1, p); err != nil {
+		t.Fatal(err)
+	}
+
+	c := snapshotter.NewClient(s.Addr())
+	b, err := c.ShardBackup(1, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	} else if b == nil {
+		t.Fatal("expected backup")
+	}
+	buf, err := ioutil.ReadAll(b)
+	b.Close()
+	if err != nil {
+		t.Fatal(err)
+	} else if int64(len(buf)) != b.Size {
+		t.Fatalf("unexpected size: %d, exp %d", len(buf), b.Size)
+	}
+
+	// The data should restore into another store.
+	if err := s.TSDBStore.RestoreShard("db0", "rp0", 2, bytes.NewReader(buf)); err != nil {
+		t.Fatal(err)
+	} else if n, err := s.TSDBStore.Shard(2).SeriesCount(); err != nil {
+		t.Fatal(err)
+	} else if n != 1 {
+		t.Fatalf("unexpected series count: %d", n)
+	}
+
+	// The shard hasn't been modified since the previous backup.
+	if b, err := c.ShardBackup(1, b.ModTime); err != nil {
+		t.Fatal(err)
+	} else if b != nil {
+		b.Close()
+		t.Fatal("expected shard to be unmodified")
+	}
+
+	// Unknown shards return an error.
+	if _, err := c.ShardBackup(100, time.Time{}); err == nil || err.Error() != tsdb.ErrShardNotFound.Error() {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Service is a test wrapper for snapshotter.Service.
+type Service struct {
+	*snapshotter.Service
+	ln  net.Listener
+	dir string
+}
+
+// MustOpenService returns a new, open service listening on a random port
+// with an open tsdb store and a meta store that returns "META".
+func MustOpenService(t *testing.T) *Service {
+	dir, err := ioutil.TempDir("", "snapshotter_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := tsdb.NewStore(filepath.Join(dir, "data"))
+	store.EngineOptions.Config.WALDir = filepath.Join(dir, "wal")
+	if err := store.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := tcp.NewMux()
+	go mux.Serve(ln)
+
+	s := &Service{Service: snapshotter.NewService(), ln: ln, dir: dir}
+	s.MetaStore = MetaStore("META")
+	s.TSDBStore = store
+	s.Listener = mux.Listen(snapshotter.MuxHeader)
+	if !testing.Verbose() {
+		s.SetLogger(log.New(ioutil.Discard, "", 0))
+	}
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// Addr returns the address of the service.
+func (s *Service) Addr() string { return s.ln.Addr().String() }
+
+// Close closes the service and removes its data.
+func (s *Service) Close() error {
+	s.ln.Close()
+	s.Service.Close()
+	s.TSDBStore.Close()
+	return os.RemoveAll(s.dir)
+}
+
+// MetaStore is a mock meta store that serializes to a fixed value.
+type MetaStore []byte
+
+func (m MetaStore) MarshalBinary() ([]byte, error) { return []byte(m), nil }
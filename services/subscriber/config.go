@@ -0,0 +1,25 @@
+package subscriber
+
+const (
+	// DefaultWriteBufferSize is the default number of write requests buffered
+	// for subscribers before new requests are dropped.
+	DefaultWriteBufferSize = 1000
+)
+
+// Config represents a configuration of the subscriber service.
+type Config struct {
+	// Whether to enable to Subscriber service
+	Enabled bool `toml:"enabled"`
+
+	// The number of write requests that can be buffered before
+	// requests to subscribers are dropped.
+	WriteBufferSize int `toml:"write-buffer-size"`
+}
+
+// NewConfig returns a new instance of a subscriber config.
+func NewConfig() Config {
+	return Config{
+		Enabled:         true,
+		WriteBufferSize: DefaultWriteBufferSize,
+	}
+}
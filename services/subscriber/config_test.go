@@ -0,0 +1,26 @@
+package subscriber_test
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/influxdb/influxdb/services/subscriber"
+)
+
+func TestConfig_Parse(t *testing.T) {
+	// Parse configuration.
+	var c subscriber.Config
+	if _, err := toml.Decode(`
+enabled = false
+write-buffer-size = 100
+`, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate configuration.
+	if c.Enabled {
+		t.Fatalf("unexpected enabled state: %v", c.Enabled)
+	} else if c.WriteBufferSize != 100 {
+		t.Fatalf("unexpected write buffer size: %d", c.WriteBufferSize)
+	}
+}
@@ -0,0 +1,43 @@
+package subscriber
+
+import (
+	"bytes"
+	"net/url"
+
+	"github.com/influxdb/influxdb/client"
+	"github.com/influxdb/influxdb/cluster"
+)
+
+// HTTP writes points over HTTP using the line protocol to the /write endpoint
+// of the destination.
+type HTTP struct {
+	c *client.Client
+}
+
+// NewHTTP returns a new HTTP points writer for the destination URL.
+func NewHTTP(u url.URL) (*HTTP, error) {
+	conf := client.NewConfig()
+	conf.URL = u
+	if u.User != nil {
+		conf.Username = u.User.Username()
+		conf.Password, _ = u.User.Password()
+	}
+
+	c, err := client.NewClient(conf)
+	if err != nil {
+		return nil, err
+	}
+	return &HTTP{c: c}, nil
+}
+
+// WritePoints writes points over HTTP transport.
+func (h *HTTP) WritePoints(p *cluster.WritePointsRequest) error {
+	var buf bytes.Buffer
+	for _, pt := range p.Points {
+		buf.WriteString(pt.String())
+		buf.WriteByte('\n')
+	}
+
+	_, err := h.c.WriteLineProtocol(buf.String(), p.Database, p.RetentionPolicy, "n", "")
+	return err
+}
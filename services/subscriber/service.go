@@ -0,0 +1,370 @@
+package subscriber
+
+import (
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/influxdb/influxdb"
+	"github.com/influxdb/influxdb/cluster"
+	"github.com/influxdb/influxdb/meta"
+)
+
+// Statistics for the Subscriber service.
+const (
+	statPointsWritten = "points_written"
+	statWriteFailures = "write_failures"
+	statWriteDropped  = "write_dropped"
+)
+
+// PointsWriter is an interface for writing points to a subscription destination.
+// Only WritePoints() needs to be satisfied.
+type PointsWriter interface {
+	WritePoints(p *cluster.WritePointsRequest) error
+}
+
+// subEntry is the unique set of fields that identify a subscription.
+type subEntry struct {
+	db   string
+	rp   string
+	name string
+}
+
+// Service manages forking the incoming data from InfluxDB
+// to defined third party destinations.
+// Subscriptions are defined per database and retention policy.
+type Service struct {
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+	closing chan struct{}
+	config  Config
+	subs    map[subEntry]*chanWriter
+	points  chan *cluster.WritePointsRequest
+
+	MetaStore interface {
+		Databases() ([]meta.DatabaseInfo, error)
+		WaitForDataChanged() error
+	}
+
+	// NewPointsWriter returns a writer for a single destination URL.
+	NewPointsWriter func(u url.URL) (PointsWriter, error)
+
+	Logger *log.Logger
+}
+
+// NewService returns a subscriber service with a given settings.
+func NewService(c Config) *Service {
+	if c.WriteBufferSize <= 0 {
+		c.WriteBufferSize = DefaultWriteBufferSize
+	}
+	return &Service{
+		config:          c,
+		subs:            make(map[subEntry]*chanWriter),
+		points:          make(chan *cluster.WritePointsRequest, c.WriteBufferSize),
+		NewPointsWriter: newPointsWriter,
+		Logger:          log.New(os.Stderr, "[subscriber] ", log.LstdFlags),
+	}
+}
+
+// Open starts the subscription service.
+func (s *Service) Open() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.MetaStore == nil {
+		return errors.New("no meta store")
+	}
+
+	s.closing = make(chan struct{})
+
+	// Perform the initial update so existing subscriptions receive points.
+	if err := s.update(); err != nil {
+		return err
+	}
+
+	s.wg.Add(1)
+	go s.writePoints(s.closing)
+
+	// Meta changes are awaited outside of the wait group as the meta store
+	// is only closed after all services have been closed.
+	go s.waitForMetaUpdates(s.closing)
+
+	s.Logger.Println("opened service")
+	return nil
+}
+
+// Close stops the subscription service.
+func (s *Service) Close() error {
+	s.mu.Lock()
+	if s.closing == nil {
+		s.mu.Unlock()
+		return nil
+	}
+	close(s.closing)
+	s.closing = nil
+	s.mu.Unlock()
+
+	// Wait for the dispatcher to finish before stopping the subscriptions.
+	s.wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for se, cw := range s.subs {
+		cw.close()
+		delete(s.subs, se)
+	}
+
+	s.Logger.Println("closed service")
+	return nil
+}
+
+// Points returns a channel into which write point requests can be sent.
+// Requests are mirrored to all subscriptions on the request's database
+// and retention policy.
+func (s *Service) Points() chan<- *cluster.WritePointsRequest {
+	return s.points
+}
+
+// Update starts new and stops deleted subscriptions.
+func (s *Service) Update() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.update()
+}
+
+func (s *Service) update() error {
+	dis, err := s.MetaStore.Databases()
+	if err != nil {
+		return err
+	}
+
+	entries := make(map[subEntry]bool)
+
+	// Start any subscriptions that don't exist yet.
+	for _, di := range dis {
+		for _, rpi := range di.RetentionPolicies {
+			for _, si := range rpi.Subscriptions {
+				se := subEntry{db: di.Name, rp: rpi.Name, name: si.Name}
+				entries[se] = true
+				if _, ok := s.subs[se]; ok {
+					continue
+				}
+
+				cw, err := s.createSubscription(se, si.Mode, si.Destinations)
+				if err != nil {
+					s.Logger.Printf("failed to create subscription %s on %s.%s: %s", se.name, se.db, se.rp, err)
+					continue
+				}
+				s.subs[se] = cw
+				s.Logger.Printf("added new subscription for %s %s", se.db, se.rp)
+			}
+		}
+	}
+
+	// Stop subscriptions which have been removed.
+	for se, cw := range s.subs {
+		if !entries[se] {
+			cw.close()
+			delete(s.subs, se)
+			s.Logger.Printf("deleted old subscription for %s %s", se.db, se.rp)
+		}
+	}
+
+	return nil
+}
+
+// createSubscription returns a writer for all of a subscription's destinations.
+func (s *Service) createSubscription(se subEntry, mode string, destinations []string) (*chanWriter, error) {
+	var bm BalanceMode
+	switch mode {
+	case "ALL":
+		bm = ALL
+	case "ANY":
+		bm = ANY
+	default:
+		return nil, fmt.Errorf("unknown balance mode %q", mode)
+	}
+
+	writers := make([]PointsWriter, len(destinations))
+	for i, dest := range destinations {
+		u, err := url.Parse(dest)
+		if err != nil {
+			closeWriters(writers[:i])
+			return nil, err
+		}
+		w, err := s.NewPointsWriter(*u)
+		if err != nil {
+			closeWriters(writers[:i])
+			return nil, err
+		}
+		writers[i] = w
+	}
+
+	key := strings.Join([]string{"subscriber", se.db, se.rp, se.name}, ":")
+	tags := map[string]string{"database": se.db, "retention_policy": se.rp, "name": se.name, "mode": mode}
+	cw := &chanWriter{
+		writeRequests: make(chan *cluster.WritePointsRequest, s.config.WriteBufferSize),
+		pw:            &balancewriter{bm: bm, writers: writers},
+		statMap:       influxdb.NewStatistics(key, "subscriber", tags),
+		Logger:        s.Logger,
+	}
+	cw.wg.Add(1)
+	go cw.run()
+	return cw, nil
+}
+
+// writePoints dispatches incoming write requests to matching subscriptions.
+func (s *Service) writePoints(closing chan struct{}) {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case p := <-s.points:
+			s.mu.Lock()
+			for se, cw := range s.subs {
+				if p.Database == se.db && p.RetentionPolicy == se.rp {
+					cw.write(p)
+				}
+			}
+			s.mu.Unlock()
+		case <-closing:
+			return
+		}
+	}
+}
+
+// waitForMetaUpdates updates subscriptions whenever the meta store changes.
+func (s *Service) waitForMetaUpdates(closing chan struct{}) {
+	for {
+		if err := s.MetaStore.WaitForDataChanged(); err != nil {
+			s.Logger.Printf("error while waiting for meta data changes: %s", err)
+			return
+		}
+
+		// Check if we are closing.
+		select {
+		case <-closing:
+			return
+		default:
+		}
+
+		if err := s.Update(); err != nil {
+			s.Logger.Printf("error updating subscriptions: %s", err)
+		}
+	}
+}
+
+// chanWriter buffers write requests for a single subscription and
+// writes them from its own goroutine so that slow destinations do not
+// block other subscriptions.
+type chanWriter struct {
+	writeRequests chan *cluster.WritePointsRequest
+	pw            PointsWriter
+	statMap       *expvar.Map
+	Logger        *log.Logger
+	wg            sync.WaitGroup
+}
+
+// write queues p for writing, dropping it if the buffer is full.
+func (c *chanWriter) write(p *cluster.WritePointsRequest) {
+	select {
+	case c.writeRequests <- p:
+	default:
+		c.statMap.Add(statWriteDropped, int64(len(p.Points)))
+	}
+}
+
+func (c *chanWriter) run() {
+	defer c.wg.Done()
+	for p := range c.writeRequests {
+		if err := c.pw.WritePoints(p); err != nil {
+			c.Logger.Printf("failed to write points to subscriber: %s", err)
+			c.statMap.Add(statWriteFailures, 1)
+			continue
+		}
+		c.statMap.Add(statPointsWritten, int64(len(p.Points)))
+	}
+}
+
+// close stops the writer after all buffered requests have been written.
+func (c *chanWriter) close() {
+	close(c.writeRequests)
+	c.wg.Wait()
+	if cl, ok := c.pw.(io.Closer); ok {
+		cl.Close()
+	}
+}
+
+// BalanceMode specifies what balance mode to use on a subscription.
+type BalanceMode int
+
+const (
+	// ALL indicates to send writes to all subscriber destinations.
+	ALL BalanceMode = iota
+
+	// ANY indicates to send writes to a single subscriber destination, round robin.
+	ANY
+)
+
+// balancewriter balances writes across PointsWriters according to BalanceMode.
+type balancewriter struct {
+	bm      BalanceMode
+	writers []PointsWriter
+	i       int
+}
+
+func (b *balancewriter) WritePoints(p *cluster.WritePointsRequest) error {
+	var lastErr error
+	for range b.writers {
+		// Round robin through destinations.
+		i := b.i
+		w := b.writers[i]
+		b.i = (b.i + 1) % len(b.writers)
+
+		// Write points to the destination.
+		err := w.WritePoints(p)
+		if err != nil {
+			lastErr = err
+		}
+
+		// If we're only writing to a single destination then we can stop
+		// as soon as one write succeeds.
+		if b.bm == ANY && err == nil {
+			return nil
+		}
+	}
+	return lastErr
+}
+
+// Close closes all destination writers.
+func (b *balancewriter) Close() error {
+	closeWriters(b.writers)
+	return nil
+}
+
+// closeWriters closes any writers which hold resources.
+func closeWriters(writers []PointsWriter) {
+	for _, w := range writers {
+		if c, ok := w.(io.Closer); ok {
+			c.Close()
+		}
+	}
+}
+
+// newPointsWriter returns a new PointsWriter from the given URL.
+func newPointsWriter(u url.URL) (PointsWriter, error) {
+	switch u.Scheme {
+	case "udp":
+		return NewUDP(u.Host), nil
+	case "http", "https":
+		return NewHTTP(u)
+	default:
+		return nil, fmt.Errorf("unknown destination scheme %s", u.Scheme)
+	}
+}
@@ -0,0 +1,301 @@
+package subscriber_test
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/cluster"
+	"github.com/influxdb/influxdb/meta"
+	"github.com/influxdb/influxdb/services/subscriber"
+)
+
+type MetaStore struct {
+	DatabasesFn          func() ([]meta.DatabaseInfo, error)
+	WaitForDataChangedFn func() error
+}
+
+func (m MetaStore) Databases() ([]meta.DatabaseInfo, error) {
+	return m.DatabasesFn()
+}
+
+func (m MetaStore) WaitForDataChanged() error {
+	return m.WaitForDataChangedFn()
+}
+
+type Subscription struct {
+	WritePointsFn func(*cluster.WritePointsRequest) error
+}
+
+func (s Subscription) WritePoints(p *cluster.WritePointsRequest) error {
+	return s.WritePointsFn(p)
+}
+
+func TestService_IgnoreNonMatch(t *testing.T) {
+	dataChanged := make(chan bool)
+	ms := MetaStore{}
+	ms.WaitForDataChangedFn = func() error {
+		<-dataChanged
+		return nil
+	}
+	ms.DatabasesFn = func() ([]meta.DatabaseInfo, error) {
+		return []meta.DatabaseInfo{
+			{
+				Name: "db0",
+				RetentionPolicies: []meta.RetentionPolicyInfo{
+					{
+						Name: "rp0",
+						Subscriptions: []meta.SubscriptionInfo{
+							{Name: "s0", Mode: "ANY", Destinations: []string{"udp://h0:9093", "udp://h1:9093"}},
+						},
+					},
+				},
+			},
+		}, nil
+	}
+
+	prs := make(chan *cluster.WritePointsRequest, 2)
+	urls := make(chan url.URL, 2)
+	newPointsWriter := func(u url.URL) (subscriber.PointsWriter, error) {
+		sub := Subscription{}
+		sub.WritePointsFn = func(p *cluster.WritePointsRequest) error {
+			prs <- p
+			return nil
+		}
+		urls <- u
+		return sub, nil
+	}
+
+	s := subscriber.NewService(subscriber.NewConfig())
+	s.MetaStore = ms
+	s.NewPointsWriter = newPointsWriter
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// Signal that data has changed
+	dataChanged <- true
+
+	for _, expURLStr := range []string{"udp://h0:9093", "udp://h1:9093"} {
+		var u url.URL
+		expURL, _ := url.Parse(expURLStr)
+		select {
+		case u = <-urls:
+		case <-time.After(10 * time.Millisecond):
+			t.Fatal("expected urls")
+		}
+		if expURL.String() != u.String() {
+			t.Fatalf("unexpected url: got %s exp %s", u.String(), expURL.String())
+		}
+	}
+
+	// Write points that don't match any subscription.
+	s.Points() <- &cluster.WritePointsRequest{
+		Database:        "db1",
+		RetentionPolicy: "rp0",
+	}
+	s.Points() <- &cluster.WritePointsRequest{
+		Database:        "db0",
+		RetentionPolicy: "rp2",
+	}
+
+	// Shouldn't get any prs back
+	select {
+	case pr := <-prs:
+		t.Fatalf("unexpected points request %v", pr)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestService_ModeALL(t *testing.T) {
+	dataChanged := make(chan bool)
+	ms := MetaStore{}
+	ms.WaitForDataChangedFn = func() error {
+		<-dataChanged
+		return nil
+	}
+	ms.DatabasesFn = func() ([]meta.DatabaseInfo, error) {
+		return []meta.DatabaseInfo{
+			{
+				Name: "db0",
+				RetentionPolicies: []meta.RetentionPolicyInfo{
+					{
+						Name: "rp0",
+						Subscriptions: []meta.SubscriptionInfo{
+							{Name: "s0", Mode: "ALL", Destinations: []string{"udp://h0:9093", "udp://h1:9093"}},
+						},
+					},
+				},
+			},
+		}, nil
+	}
+
+	prs := make(chan *cluster.WritePointsRequest, 2)
+	urls := make(chan url.URL, 2)
+	newPointsWriter := func(u url.URL) (subscriber.PointsWriter, error) {
+		sub := Subscription{}
+		sub.WritePointsFn = func(p *cluster.WritePointsRequest) error {
+			prs <- p
+			return nil
+		}
+		urls <- u
+		return sub, nil
+	}
+
+	s := subscriber.NewService(subscriber.NewConfig())
+	s.MetaStore = ms
+	s.NewPointsWriter = newPointsWriter
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for _, expURLStr := range []string{"udp://h0:9093", "udp://h1:9093"} {
+		var u url.URL
+		expURL, _ := url.Parse(expURLStr)
+		select {
+		case u = <-urls:
+		case <-time.After(10 * time.Millisecond):
+			t.Fatal("expected urls")
+		}
+		if expURL.String() != u.String() {
+			t.Fatalf("unexpected url: got %s exp %s", u.String(), expURL.String())
+		}
+	}
+
+	// Write points that match subscription with mode ALL
+	expPR := &cluster.WritePointsRequest{
+		Database:        "db0",
+		RetentionPolicy: "rp0",
+	}
+	s.Points() <- expPR
+
+	// Should get pr back twice
+	for i := 0; i < 2; i++ {
+		var pr *cluster.WritePointsRequest
+		select {
+		case pr = <-prs:
+		case <-time.After(10 * time.Millisecond):
+			t.Fatalf("expected points request: got %d exp 2", i)
+		}
+		if pr != expPR {
+			t.Errorf("unexpected points request: got %v, exp %v", pr, expPR)
+		}
+	}
+}
+
+func TestService_ModeANY(t *testing.T) {
+	dataChanged := make(chan bool)
+	ms := MetaStore{}
+	ms.WaitForDataChangedFn = func() error {
+		<-dataChanged
+		return nil
+	}
+	ms.DatabasesFn = func() ([]meta.DatabaseInfo, error) {
+		return []meta.DatabaseInfo{
+			{
+				Name: "db0",
+				RetentionPolicies: []meta.RetentionPolicyInfo{
+					{
+						Name: "rp0",
+						Subscriptions: []meta.SubscriptionInfo{
+							{Name: "s0", Mode: "ANY", Destinations: []string{"udp://h0:9093", "udp://h1:9093"}},
+						},
+					},
+				},
+			},
+		}, nil
+	}
+
+	prs := make(chan *cluster.WritePointsRequest, 2)
+	newPointsWriter := func(u url.URL) (subscriber.PointsWriter, error) {
+		sub := Subscription{}
+		sub.WritePointsFn = func(p *cluster.WritePointsRequest) error {
+			prs <- p
+			return nil
+		}
+		return sub, nil
+	}
+
+	s := subscriber.NewService(subscriber.NewConfig())
+	s.MetaStore = ms
+	s.NewPointsWriter = newPointsWriter
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// Write points that match subscription with mode ANY
+	expPR := &cluster.WritePointsRequest{
+		Database:        "db0",
+		RetentionPolicy: "rp0",
+	}
+	s.Points() <- expPR
+
+	// Validate we get the pr back just once
+	var pr *cluster.WritePointsRequest
+	select {
+	case pr = <-prs:
+	case <-time.After(10 * time.Millisecond):
+		t.Fatal("expected points request")
+	}
+	if pr != expPR {
+		t.Errorf("unexpected points request: got %v, exp %v", pr, expPR)
+	}
+
+	// shouldn't get it a second time
+	select {
+	case pr = <-prs:
+		t.Fatalf("unexpected points request %v", pr)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+// Ensure subscriptions removed from the meta store are stopped.
+func TestService_Update_RemovesSubscription(t *testing.T) {
+	subs := []meta.SubscriptionInfo{
+		{Name: "s0", Mode: "ALL", Destinations: []string{"udp://h0:9093"}},
+	}
+	ms := MetaStore{}
+	ms.WaitForDataChangedFn = func() error {
+		select {}
+	}
+	ms.DatabasesFn = func() ([]meta.DatabaseInfo, error) {
+		return []meta.DatabaseInfo{
+			{
+				Name: "db0",
+				RetentionPolicies: []meta.RetentionPolicyInfo{
+					{Name: "rp0", Subscriptions: subs},
+				},
+			},
+		}, nil
+	}
+
+	prs := make(chan *cluster.WritePointsRequest, 2)
+	s := subscriber.NewService(subscriber.NewConfig())
+	s.MetaStore = ms
+	s.NewPointsWriter = func(u url.URL) (subscriber.PointsWriter, error) {
+		return Subscription{WritePointsFn: func(p *cluster.WritePointsRequest) error {
+			prs <- p
+			return nil
+		}}, nil
+	}
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// Remove the subscription.
+	subs = nil
+	if err := s.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Points() <- &cluster.WritePointsRequest{Database: "db0", RetentionPolicy: "rp0"}
+	select {
+	case pr := <-prs:
+		t.Fatalf("unexpected points request %v", pr)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
@@ -0,0 +1,43 @@
+package subscriber
+
+import (
+	"bytes"
+	"net"
+
+	"github.com/influxdb/influxdb/cluster"
+)
+
+// UDP writes points over UDP using the line protocol.
+type UDP struct {
+	addr string
+}
+
+// NewUDP returns a new UDP listener with default options.
+func NewUDP(addr string) *UDP {
+	return &UDP{addr: addr}
+}
+
+// WritePoints writes points over UDP transport.
+func (u *UDP) WritePoints(p *cluster.WritePointsRequest) (err error) {
+	var addr *net.UDPAddr
+	var con *net.UDPConn
+	addr, err = net.ResolveUDPAddr("udp", u.addr)
+	if err != nil {
+		return
+	}
+
+	con, err = net.DialUDP("udp", nil, addr)
+	if err != nil {
+		return
+	}
+	defer con.Close()
+
+	var buf bytes.Buffer
+	for _, pt := range p.Points {
+		buf.WriteString(pt.String())
+		buf.WriteByte('\n')
+	}
+
+	_, err = con.Write(buf.Bytes())
+	return
+}
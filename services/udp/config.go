@@ -15,16 +15,33 @@ const (
 
 	// DefaultBatchTimeout is the default UDP batch timeout.
 	DefaultBatchTimeout = time.Second
+
+	// DefaultConsistencyLevel is the default write consistency for the UDP input.
+	DefaultConsistencyLevel = "one"
+
+	// DefaultSigningWindow is the default time signed datagrams are accepted
+	// for before or after the time they were signed at.
+	DefaultSigningWindow = 30 * time.Second
 )
 
 type Config struct {
 	Enabled     bool   `toml:"enabled"`
 	BindAddress string `toml:"bind-address"`
 
-	Database     string        `toml:"database"`
-	BatchSize    int           `toml:"batch-size"`
-	BatchPending int           `toml:"batch-pending"`
-	BatchTimeout toml.Duration `toml:"batch-timeout"`
+	Database         string        `toml:"database"`
+	ConsistencyLevel string        `toml:"consistency-level"`
+	BatchSize        int           `toml:"batch-size"`
+	BatchPending     int           `toml:"batch-pending"`
+	BatchTimeout     toml.Duration `toml:"batch-timeout"`
+
+	// SigningKey is the key datagrams are signed with. Datagrams must be
+	// prefixed with the HMAC-SHA256 of the rest of the datagram, followed by
+	// the time they were signed at, if it's set.
+	SigningKey string `toml:"signing-key"`
+
+	// SigningWindow is how far the time a datagram was signed at may be from
+	// the time it's received, so captured datagrams can't be replayed later.
+	SigningWindow toml.Duration `toml:"signing-window"`
 }
 
 // WithDefaults takes the given config and returns a new config with any required
@@ -40,5 +57,11 @@ func (c *Config) WithDefaults() *Config {
 	if d.BatchTimeout == 0 {
 		d.BatchTimeout = toml.Duration(DefaultBatchTimeout)
 	}
+	if d.SigningWindow == 0 {
+		d.SigningWindow = toml.Duration(DefaultSigningWindow)
+	}
+	if d.ConsistencyLevel == "" {
+		d.ConsistencyLevel = DefaultConsistencyLevel
+	}
 	return &d
 }
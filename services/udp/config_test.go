@@ -15,9 +15,12 @@ func TestConfig_Parse(t *testing.T) {
 enabled = true
 bind-address = ":4444"
 database = "awesomedb"
+consistency-level = "any"
 batch-size = 100
 batch-pending = 9
 batch-timeout = "10ms"
+signing-key = "secret"
+signing-window = "1m"
 `, &c); err != nil {
 		t.Fatal(err)
 	}
@@ -29,11 +32,17 @@ batch-timeout = "10ms"
 		t.Fatalf("unexpected bind address: %s", c.BindAddress)
 	} else if c.Database != "awesomedb" {
 		t.Fatalf("unexpected database: %s", c.Database)
+	} else if c.ConsistencyLevel != "any" {
+		t.Fatalf("unexpected consistency level: %s", c.ConsistencyLevel)
 	} else if c.BatchSize != 100 {
 		t.Fatalf("unexpected batch size: %d", c.BatchSize)
 	} else if c.BatchPending != 9 {
 		t.Fatalf("unexpected batch pending: %d", c.BatchPending)
 	} else if time.Duration(c.BatchTimeout) != (10 * time.Millisecond) {
 		t.Fatalf("unexpected batch timeout: %v", c.BatchTimeout)
+	} else if c.SigningKey != "secret" {
+		t.Fatalf("unexpected signing key: %s", c.SigningKey)
+	} else if time.Duration(c.SigningWindow) != time.Minute {
+		t.Fatalf("unexpected signing window: %v", c.SigningWindow)
 	}
 }
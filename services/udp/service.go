@@ -1,8 +1,12 @@
 package udp
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
 	"errors"
 	"expvar"
+	"fmt"
 	"log"
 	"net"
 	"os"
@@ -25,6 +29,7 @@ const (
 	statBytesReceived       = "bytes_rx"
 	statPointsParseFail     = "points_parse_fail"
 	statReadFail            = "read_fail"
+	statSignatureFail       = "signature_fail"
 	statBatchesTrasmitted   = "batches_tx"
 	statPointsTransmitted   = "points_tx"
 	statBatchesTransmitFail = "batches_tx_fail"
@@ -44,6 +49,8 @@ type Service struct {
 	batcher *tsdb.PointBatcher
 	config  Config
 
+	consistencyLevel cluster.ConsistencyLevel
+
 	PointsWriter interface {
 		WritePoints(p *cluster.WritePointsRequest) error
 	}
@@ -75,6 +82,9 @@ func (s *Service) Open() (err error) {
 	if s.config.Database == "" {
 		return errors.New("database has to be specified in config")
 	}
+	if s.consistencyLevel, err = cluster.ParseConsistencyLevel(s.config.ConsistencyLevel); err != nil {
+		return fmt.Errorf("consistency level %q: %s", s.config.ConsistencyLevel, err)
+	}
 
 	s.addr, err = net.ResolveUDPAddr("udp", s.config.BindAddress)
 	if err != nil {
@@ -106,7 +116,7 @@ func (s *Service) writePoints() {
 			if err := s.PointsWriter.WritePoints(&cluster.WritePointsRequest{
 				Database:         s.config.Database,
 				RetentionPolicy:  "",
-				ConsistencyLevel: cluster.ConsistencyLevelOne,
+				ConsistencyLevel: s.consistencyLevel,
 				Points:           batch,
 			}); err == nil {
 				s.statMap.Add(statBatchesTrasmitted, 1)
@@ -145,7 +155,16 @@ func (s *Service) serve() {
 		}
 		s.statMap.Add(statBytesReceived, int64(n))
 
-		points, err := tsdb.ParsePoints(buf[:n])
+		data := buf[:n]
+		if s.config.SigningKey != "" {
+			var ok bool
+			if data, ok = verifySignature([]byte(s.config.SigningKey), data, time.Now(), time.Duration(s.config.SigningWindow)); !ok {
+				s.statMap.Add(statSignatureFail, 1)
+				continue
+			}
+		}
+
+		points, err := tsdb.ParsePoints(data)
 		if err != nil {
 			s.statMap.Add(statPointsParseFail, 1)
 			s.Logger.Printf("Failed to parse points: %s", err)
@@ -159,6 +178,27 @@ func (s *Service) serve() {
 	}
 }
 
+// verifySignature checks the HMAC-SHA256 signature prefixing a datagram and
+// returns the points following the time it was signed at, in nanoseconds since
+// the epoch as 8 big endian bytes. Returns false if the signature is invalid or
+// the datagram was signed more than window away from now.
+func verifySignature(key, data []byte, now time.Time, window time.Duration) ([]byte, bool) {
+	if len(data) < sha256.Size+8 {
+		return nil, false
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data[sha256.Size:])
+	if !hmac.Equal(data[:sha256.Size], mac.Sum(nil)) {
+		return nil, false
+	}
+
+	signed := time.Unix(0, int64(binary.BigEndian.Uint64(data[sha256.Size:])))
+	if d := now.Sub(signed); d > window || d < -window {
+		return nil, false
+	}
+	return data[sha256.Size+8:], true
+}
+
 func (s *Service) Close() error {
 	if s.conn == nil {
 		return errors.New("Service already closed")
@@ -0,0 +1,48 @@
+package udp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+// Ensure datagrams are only accepted with a valid signature made recently.
+func TestVerifySignature(t *testing.T) {
+	key := []byte("secret")
+	payload := []byte("cpu value=1")
+	now := time.Unix(1000, 0)
+
+	sign := func(key []byte, signed time.Time) []byte {
+		data := make([]byte, 8, 8+len(payload))
+		binary.BigEndian.PutUint64(data, uint64(signed.UnixNano()))
+		data = append(data, payload...)
+
+		mac := hmac.New(sha256.New, key)
+		mac.Write(data)
+		return append(mac.Sum(nil), data...)
+	}
+	signed := sign(key, now.Add(-time.Second))
+
+	if data, ok := verifySignature(key, signed, now, 30*time.Second); !ok {
+		t.Fatal("expected valid signature")
+	} else if string(data) != string(payload) {
+		t.Fatalf("unexpected data: %q", data)
+	}
+
+	tampered := append([]byte{}, signed...)
+	tampered[len(tampered)-1] = '2'
+	for i, data := range [][]byte{
+		payload,
+		tampered,
+		signed[:sha256.Size+7],
+		sign([]byte("other"), now),
+		sign(key, now.Add(-time.Minute)),
+		sign(key, now.Add(time.Minute)),
+	} {
+		if _, ok := verifySignature(key, data, now, 30*time.Second); ok {
+			t.Errorf("%d. expected invalid signature", i)
+		}
+	}
+}
@@ -140,9 +140,10 @@ var showDatabaseWarning = function(message) {
     $("div#database-warning").html("<p>" + message + "</p>").show();
 }
 
-// clear out the results table
+// clear out the results table and chart
 var clearResults = function() {
     $("div#table").empty();
+    React.unmountComponentAtNode(document.getElementById('chart'));
 }
 
 // handle submissions of the query bar
@@ -189,6 +190,10 @@ var handleSubmit = function(e) {
             });
 
             hideDatabaseWarning();
+            React.render(
+              React.createElement(LineChart, {series: series}),
+              document.getElementById('chart')
+            );
             React.render(
               React.createElement(DataTable, {series: series}),
               document.getElementById('table')
@@ -341,9 +346,388 @@ var pretty = function(val) {
     }
 }
 
+// quote an identifier, such as a measurement or tag key, for use in a query
+var quoteIdent = function(name) {
+    return "\"" + name.replace(/\\/g, "\\\\").replace(/"/g, "\\\"") + "\"";
+}
+
+// quote a string, such as a tag value, for use in a query
+var quoteString = function(s) {
+    return "'" + s.replace(/\\/g, "\\\\").replace(/'/g, "\\'") + "'";
+}
+
+// run a query against the current database and pass the values of the first
+// column of its result, across all series, to callback
+var queryFirstColumn = function(q, callback) {
+    var query = $.get(connectionString() + "/query", {q: q, db: currentlySelectedDatabase});
+
+    query.fail(handleRequestError);
+
+    query.done(function (data) {
+        var firstRow = data.results[0];
+        if (firstRow.error) {
+            showQueryError("Server returned error: " + firstRow.error);
+            return;
+        }
+
+        var values = [];
+        (firstRow.series || []).forEach(function(series) {
+            (series.values || []).forEach(function(value) {
+                if (values.indexOf(value[0]) == -1) { values.push(value[0]); }
+            });
+        });
+        callback(values.sort());
+    });
+}
+
+// fill the query bar with q and run it
+var submitQuery = function(q) {
+    $("input#query").val(q).focus();
+    handleSubmit();
+}
+
+// browses the measurements of the current database, and their tags and fields
+var SchemaBrowser = React.createClass({
+    getInitialState: function() {
+        return {measurements: [], expanded: {}, tags: {}, fields: {}, tagValues: {}};
+    },
+
+    componentDidMount: function() {
+        this.load(this.props.database);
+    },
+
+    componentWillReceiveProps: function(nextProps) {
+        if (nextProps.database != this.props.database) {
+            this.load(nextProps.database);
+        }
+    },
+
+    load: function(database) {
+        this.setState(this.getInitialState());
+        if (database == null) { return; }
+
+        queryFirstColumn("SHOW MEASUREMENTS", function(measurements) {
+            this.setState({measurements: measurements});
+        }.bind(this));
+    },
+
+    toggleMeasurement: function(m, e) {
+        e.preventDefault();
+
+        var expanded = this.state.expanded;
+        expanded[m] = !expanded[m];
+        this.setState({expanded: expanded});
+        if (!expanded[m]) { return; }
+
+        queryFirstColumn("SHOW TAG KEYS FROM " + quoteIdent(m), function(keys) {
+            var tags = this.state.tags;
+            tags[m] = keys;
+            this.setState({tags: tags});
+        }.bind(this));
+
+        queryFirstColumn("SHOW FIELD KEYS FROM " + quoteIdent(m), function(keys) {
+            var fields = this.state.fields;
+            fields[m] = keys;
+            this.setState({fields: fields});
+        }.bind(this));
+    },
+
+    toggleTag: function(m, k, e) {
+        e.preventDefault();
+
+        var id = m + "\x00" + k;
+        var tagValues = this.state.tagValues;
+        if (tagValues[id]) {
+            delete tagValues[id];
+            this.setState({tagValues: tagValues});
+            return;
+        }
+
+        queryFirstColumn("SHOW TAG VALUES FROM " + quoteIdent(m) + " WITH KEY = " + quoteIdent(k), function(values) {
+            tagValues[id] = values;
+            this.setState({tagValues: tagValues});
+        }.bind(this));
+    },
+
+    selectField: function(m, f, e) {
+        e.preventDefault();
+        submitQuery("SELECT " + quoteIdent(f) + " FROM " + quoteIdent(m) + " WHERE time > now() - 1h");
+    },
+
+    selectTagValue: function(m, k, v, e) {
+        e.preventDefault();
+        submitQuery("SELECT * FROM " + quoteIdent(m) + " WHERE " + quoteIdent(k) + " = " + quoteString(v) + " AND time > now() - 1h");
+    },
+
+    renderMeasurement: function(m) {
+        var children = null;
+
+        if (this.state.expanded[m]) {
+            var tags = (this.state.tags[m] || []).map(function(k) {
+                var values = (this.state.tagValues[m + "\x00" + k] || []).map(function(v) {
+                    return React.createElement("li", {key: v},
+                        React.createElement("a", {href: "#", onClick: this.selectTagValue.bind(this, m, k, v)}, v)
+                    );
+                }.bind(this));
+
+                return React.createElement("li", {key: "tag-" + k},
+                    React.createElement("a", {href: "#", onClick: this.toggleTag.bind(this, m, k)}, k),
+                    React.createElement("span", {className: "kind"}, "tag"),
+                    React.createElement("ul", null, values)
+                );
+            }.bind(this));
+
+            var fields = (this.state.fields[m] || []).map(function(f) {
+                return React.createElement("li", {key: "field-" + f},
+                    React.createElement("a", {href: "#", className: "field", onClick: this.selectField.bind(this, m, f)}, f),
+                    React.createElement("span", {className: "kind"}, "field")
+                );
+            }.bind(this));
+
+            children = React.createElement("ul", null, tags, fields);
+        }
+
+        return React.createElement("li", {key: m},
+            React.createElement("a", {href: "#", onClick: this.toggleMeasurement.bind(this, m)}, m),
+            children
+        );
+    },
+
+    render: function() {
+        var measurements = this.state.measurements.map(this.renderMeasurement);
+
+        return React.createElement("div", {className: "panel panel-default"},
+            React.createElement("div", {className: "panel-heading"},
+                React.createElement("h3", {className: "panel-title"}, "Measurements")
+            ),
+            React.createElement("div", {className: "panel-body"},
+                React.createElement("ul", null, measurements)
+            )
+        );
+    }
+});
+
+// builds a SELECT query from the measurements, fields and tags of the current database
+var QueryBuilder = React.createClass({
+    getInitialState: function() {
+        return {
+            measurements: [], fields: [], tagKeys: [], tagValues: [],
+            measurement: "", field: "", func: "", range: "1h", interval: "", tagKey: "", tagValue: ""
+        };
+    },
+
+    componentDidMount: function() {
+        this.load(this.props.database);
+    },
+
+    componentWillReceiveProps: function(nextProps) {
+        if (nextProps.database != this.props.database) {
+            this.load(nextProps.database);
+        }
+    },
+
+    load: function(database) {
+        this.setState(this.getInitialState());
+        if (database == null) { return; }
+
+        queryFirstColumn("SHOW MEASUREMENTS", function(measurements) {
+            this.setState({measurements: measurements});
+        }.bind(this));
+    },
+
+    changeMeasurement: function(e) {
+        var m = e.target.value;
+        this.setState({measurement: m, field: "", fields: [], tagKey: "", tagKeys: [], tagValue: "", tagValues: []});
+        if (m == "") { return; }
+
+        queryFirstColumn("SHOW FIELD KEYS FROM " + quoteIdent(m), function(fields) {
+            this.setState({fields: fields});
+        }.bind(this));
+
+        queryFirstColumn("SHOW TAG KEYS FROM " + quoteIdent(m), function(keys) {
+            this.setState({tagKeys: keys});
+        }.bind(this));
+    },
+
+    changeTagKey: function(e) {
+        var k = e.target.value;
+        this.setState({tagKey: k, tagValue: "", tagValues: []});
+        if (k == "") { return; }
+
+        queryFirstColumn("SHOW TAG VALUES FROM " + quoteIdent(this.state.measurement) + " WITH KEY = " + quoteIdent(k), function(values) {
+            this.setState({tagValues: values});
+        }.bind(this));
+    },
+
+    change: function(name, e) {
+        var state = {};
+        state[name] = e.target.value;
+        this.setState(state);
+    },
+
+    // returns the query described by the current selections
+    query: function() {
+        var s = this.state;
+
+        // aggregates need a field
+        var field = "*";
+        if (s.field != "") {
+            field = quoteIdent(s.field);
+            if (s.func != "") { field = s.func + "(" + field + ")"; }
+        }
+
+        var conditions = ["time > now() - " + s.range];
+        if (s.tagKey != "" && s.tagValue != "") {
+            conditions.push(quoteIdent(s.tagKey) + " = " + quoteString(s.tagValue));
+        }
+
+        var q = "SELECT " + field + " FROM " + quoteIdent(s.measurement) + " WHERE " + conditions.join(" AND ");
+        if (s.field != "" && s.func != "" && s.interval != "") {
+            q += " GROUP BY time(" + s.interval + ")";
+        }
+        return q;
+    },
+
+    handleSubmit: function(e) {
+        e.preventDefault();
+        if (this.state.measurement == "") { return; }
+        submitQuery(this.query());
+    },
+
+    select: function(label, value, options, onChange) {
+        var opts = options.map(function(o) {
+            return React.createElement("option", {key: o[0], value: o[0]}, o[1]);
+        });
+
+        return React.createElement("div", {className: "form-group"},
+            React.createElement("label", null, label + " "),
+            React.createElement("select", {className: "form-control", value: value, onChange: onChange}, opts)
+        );
+    },
+
+    render: function() {
+        var pairs = function(values, none) {
+            return [["", none]].concat(values.map(function(v) { return [v, v]; }));
+        };
+
+        return React.createElement("div", {className: "panel panel-info"},
+            React.createElement("div", {className: "panel-heading"},
+                React.createElement("h3", {className: "panel-title"}, "Query Builder")
+            ),
+            React.createElement("div", {className: "panel-body"},
+                React.createElement("form", {className: "form-inline", onSubmit: this.handleSubmit},
+                    this.select("From", this.state.measurement, pairs(this.state.measurements, "measurement"), this.changeMeasurement),
+                    this.select("Select", this.state.field, pairs(this.state.fields, "*"), this.change.bind(this, "field")),
+                    this.select("Function", this.state.func, [["", "none"], ["mean", "mean"], ["median", "median"], ["sum", "sum"], ["count", "count"], ["min", "min"], ["max", "max"], ["last", "last"]], this.change.bind(this, "func")),
+                    this.select("Where", this.state.tagKey, pairs(this.state.tagKeys, "tag"), this.changeTagKey),
+                    this.select("=", this.state.tagValue, pairs(this.state.tagValues, "value"), this.change.bind(this, "tagValue")),
+                    this.select("Last", this.state.range, [["15m", "15 minutes"], ["1h", "hour"], ["6h", "6 hours"], ["24h", "day"], ["7d", "week"], ["30d", "30 days"]], this.change.bind(this, "range")),
+                    this.select("Group by", this.state.interval, [["", "none"], ["10s", "10s"], ["1m", "1m"], ["5m", "5m"], ["1h", "1h"], ["1d", "1d"]], this.change.bind(this, "interval")),
+                    React.createElement("div", {className: "form-group"},
+                        React.createElement("button", {type: "submit", className: "btn btn-default", disabled: this.state.measurement == ""}, "Run")
+                    )
+                )
+            )
+        );
+    }
+});
+
+// colors of the lines of a chart, reused in order
+var chartColors = ["#22adf6", "#dc4e58", "#4ed8a0", "#ffb94a", "#7a65f2", "#999999"];
+
+// parses a timestamp of a result. Browsers only parse up to millisecond precision.
+var parseTime = function(t) {
+    if (typeof t == "number") { return t; }
+    return Date.parse(String(t).replace(/(\.\d{3})\d+/, "$1"));
+}
+
+// returns a line for each numeric column of series with at least two points
+var chartLines = function(series) {
+    var lines = [];
+
+    series.forEach(function(s) {
+        if (s.columns[0] != "time" || s.values == null) { return; }
+
+        s.columns.forEach(function(column, i) {
+            if (i == 0) { return; }
+
+            var points = [];
+            s.values.forEach(function(row) {
+                var t = parseTime(row[0]);
+                if (typeof row[i] == "number" && !isNaN(t)) { points.push([t, row[i]]); }
+            });
+            if (points.length < 2) { return; }
+
+            var name = s.name + "." + column;
+            if (s.tags) { name += " (" + stringifyTags(s.tags) + ")"; }
+            lines.push({name: name, points: points});
+        });
+    });
+
+    return lines;
+}
+
+// renders the numeric columns of results as lines over time
+var LineChart = React.createClass({
+    render: function() {
+        var lines = chartLines(this.props.series);
+        if (lines.length == 0) { return null; }
+
+        var width = 1000, height = 250, left = 60, bottom = 20, top = 10;
+
+        var minX = Infinity, maxX = -Infinity, minY = Infinity, maxY = -Infinity;
+        lines.forEach(function(line) {
+            line.points.forEach(function(p) {
+                minX = Math.min(minX, p[0]); maxX = Math.max(maxX, p[0]);
+                minY = Math.min(minY, p[1]); maxY = Math.max(maxY, p[1]);
+            });
+        });
+        var dx = (maxX - minX) || 1, dy = (maxY - minY) || 1;
+
+        var polylines = lines.map(function(line, i) {
+            var points = line.points.map(function(p) {
+                var x = left + (p[0] - minX) / dx * (width - left);
+                var y = top + (1 - (p[1] - minY) / dy) * (height - top - bottom);
+                return x.toFixed(1) + "," + y.toFixed(1);
+            });
+            return React.createElement("polyline", {key: i, points: points.join(" "), stroke: chartColors[i % chartColors.length]});
+        });
+
+        var legend = lines.map(function(line, i) {
+            return React.createElement("span", {key: i, style: {color: chartColors[i % chartColors.length], marginRight: "15px"}}, line.name);
+        });
+
+        return React.createElement("div", null,
+            React.createElement("svg", {viewBox: "0 0 " + width + " " + height},
+                React.createElement("line", {className: "axis", x1: left, y1: top, x2: left, y2: height - bottom}),
+                React.createElement("line", {className: "axis", x1: left, y1: height - bottom, x2: width, y2: height - bottom}),
+                React.createElement("text", {x: left - 5, y: top + 10, textAnchor: "end"}, maxY),
+                React.createElement("text", {x: left - 5, y: height - bottom, textAnchor: "end"}, minY),
+                React.createElement("text", {x: left, y: height - 5}, new Date(minX).toISOString()),
+                React.createElement("text", {x: width, y: height - 5, textAnchor: "end"}, new Date(maxX).toISOString()),
+                polylines
+            ),
+            React.createElement("p", null, legend)
+        );
+    }
+});
+
+// render the schema browser and query builder for the current database
+var renderSchema = function() {
+    React.render(
+        React.createElement(SchemaBrowser, {database: currentlySelectedDatabase}),
+        document.getElementById('schema')
+    );
+    React.render(
+        React.createElement(QueryBuilder, {database: currentlySelectedDatabase}),
+        document.getElementById('builder')
+    );
+}
+
 var chooseDatabase = function (databaseName) {
     currentlySelectedDatabase = databaseName;
     document.getElementById("content-current-database").innerHTML = currentlySelectedDatabase;
+    renderSchema();
 }
 
 var getDatabases = function () {
@@ -408,6 +792,12 @@ $(document).ready(function () {
         $("#settings").toggle();
     });
 
+    // bind to the query builder link in the navbar
+    $("#action-builder").click(function (e) {
+        $("#builder").toggle();
+        e.preventDefault();
+    });
+
     // bind to the save button in the settings form
     $("#form-settings").submit(function (e) {
         updateSettings();
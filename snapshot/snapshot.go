@@ -82,9 +82,10 @@ loop:
 
 // File represents a single file in a manifest.
 type File struct {
-	Name    string    `json:"name"`         // filename
-	Size    int64     `json:"size"`         // file size
-	ModTime time.Time `json:"lastModified"` // last modified time
+	Name     string    `json:"name"`               // filename
+	Size     int64     `json:"size"`               // file size
+	ModTime  time.Time `json:"lastModified"`       // last modified time
+	Checksum string    `json:"checksum,omitempty"` // hex encoded SHA-256 of the contents
 }
 
 // Files represents a sortable list of files.
@@ -40,6 +40,22 @@ const (
 	// This number multiplied by the parition count is roughly the max possible memory
 	// size for the in-memory WAL cache.
 	DefaultPartitionSizeThreshold = 20 * 1024 * 1024 // 20MB
+
+	// DefaultDropBatchSize is the number of dropped series whose points are
+	// deleted per transaction.
+	DefaultDropBatchSize = 100
+
+	// DefaultDropBatchDelay is the sleep time between deleting batches of
+	// dropped series.
+	DefaultDropBatchDelay = 100 * time.Millisecond
+
+	// DefaultCompactFullWriteColdDuration is how long a shard goes without
+	// writes before it is fully compacted.
+	DefaultCompactFullWriteColdDuration = 4 * time.Hour
+
+	// DefaultReplayConcurrency is the default number of shards opened at
+	// once, replaying their WALs, when the store is opened.
+	DefaultReplayConcurrency = 4
 )
 
 type Config struct {
@@ -58,6 +74,53 @@ type Config struct {
 	WALMaxSeriesSize          int           `toml:"wal-max-series-size"`
 	WALFlushColdInterval      toml.Duration `toml:"wal-flush-cold-interval"`
 	WALPartitionSizeThreshold uint64        `toml:"wal-partition-size-threshold"`
+
+	// Drop the pages written by WAL flushes and compactions from the page
+	// cache, so they don't evict the pages of hot shards.
+	WALFlushBypassCache bool `toml:"wal-flush-bypass-cache"`
+
+	// Verify the blocks and index of each shard when the store is opened.
+	// Shards failing verification or failing to open are quarantined.
+	VerifyShardsOnOpen bool `toml:"verify-shards-on-open"`
+
+	// Open the shards and replay their WALs in the background when the store
+	// is opened, so the shards already opened can be queried while the rest
+	// replay. Writes to the shards still replaying fail until they're opened.
+	ReplayInBackground bool `toml:"replay-in-background"`
+
+	// Number of shards opened at once when the store is opened, each
+	// replaying its WAL.
+	ReplayConcurrency int `toml:"replay-concurrency"`
+
+	// Advise the operating system how queries read the memory-mapped shard
+	// files, so it reads ahead for scans and doesn't for lookups.
+	MmapHintsEnabled bool `toml:"mmap-hints-enabled"`
+
+	// Read the most recent blocks of the series the WAL flushes to a shard in
+	// the background, so the first queries find them cached. It doesn't apply
+	// if the flushed pages bypass the page cache.
+	PrewarmEnabled bool `toml:"prewarm-enabled"`
+
+	// Dropped series are removed from the index right away and their points
+	// are deleted in the background, in batches of DropBatchSize series with
+	// DropBatchDelay between batches.
+	DropBatchSize  int           `toml:"drop-batch-size"`
+	DropBatchDelay toml.Duration `toml:"drop-batch-delay"`
+
+	// Throttle the writes to all shards, and to each shard, to these points and
+	// bytes per second, so bulk backfills don't overwhelm compactions. Writes
+	// wait rather than fail. Zero is unlimited. The throttles can be changed
+	// at runtime through the /throttle endpoint.
+	WriteThrottlePoints      int `toml:"write-throttle-points"`
+	WriteThrottleBytes       int `toml:"write-throttle-bytes"`
+	ShardWriteThrottlePoints int `toml:"shard-write-throttle-points"`
+	ShardWriteThrottleBytes  int `toml:"shard-write-throttle-bytes"`
+
+	// Fully compact shards which haven't been written to for this long: the
+	// WAL is flushed and closed until the next write, and the blocks of each
+	// series are rewritten into full, tightly packed blocks. Zero disables
+	// full compactions.
+	CompactFullWriteColdDuration toml.Duration `toml:"compact-full-write-cold-duration"`
 }
 
 func NewConfig() Config {
@@ -72,5 +135,11 @@ func NewConfig() Config {
 		WALMaxSeriesSize:          DefaultMaxSeriesSize,
 		WALFlushColdInterval:      toml.Duration(DefaultFlushColdInterval),
 		WALPartitionSizeThreshold: DefaultPartitionSizeThreshold,
+
+		DropBatchSize:  DefaultDropBatchSize,
+		DropBatchDelay: toml.Duration(DefaultDropBatchDelay),
+
+		CompactFullWriteColdDuration: toml.Duration(DefaultCompactFullWriteColdDuration),
+		ReplayConcurrency:            DefaultReplayConcurrency,
 	}
 }
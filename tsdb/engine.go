@@ -15,6 +15,12 @@ import (
 var (
 	// ErrFormatNotFound is returned when no format can be determined from a path.
 	ErrFormatNotFound = errors.New("format not found")
+
+	// ErrInspectNotSupported is returned when the engine can't inspect its blocks.
+	ErrInspectNotSupported = errors.New("engine does not support block inspection")
+
+	// ErrCompactNotSupported is returned when the engine can't be fully compacted.
+	ErrCompactNotSupported = errors.New("engine does not support full compaction")
 )
 
 // DefaultEngine is the default engine used by the shard when initializing.
@@ -98,6 +104,92 @@ func NewEngine(path string, walPath string, options EngineOptions) (Engine, erro
 	return fn(path, walPath, options), nil
 }
 
+// BlockInspector is implemented by engines that store point data in blocks
+// and can iterate over them for verification and reporting.
+type BlockInspector interface {
+	InspectBlocks(fn func(BlockInfo) error) error
+}
+
+// CacheSizer is implemented by engines that hold written points in memory
+// before they are written to the index.
+type CacheSizer interface {
+	CacheSize() int64
+}
+
+// WALReporter is implemented by engines that write points to a write ahead log
+// before they are written to the index.
+type WALReporter interface {
+	WALStats() WALStats
+}
+
+// WALStats describes the state of an engine's write ahead log.
+type WALStats struct {
+	SegmentN          int           // segment files on disk
+	SegmentBytes      int64         // size of the segment files, in bytes
+	CacheSeriesN      int           // series with points waiting to be flushed
+	LastFlushDuration time.Duration // time taken by the last flush to the index
+	BacklogBytes      int64         // size of the segment files waiting to be compacted, in bytes
+}
+
+// PointCounter is implemented by engines that can count the points of a
+// series from their block metadata, without decoding the stored values.
+type PointCounter interface {
+	PointCounts(keys []string, min, max int64) (map[string]int64, error)
+}
+
+// FullCompactor is implemented by engines that can rewrite the data in their
+// data file into its most compact form once the shard is no longer written to.
+type FullCompactor interface {
+	CompactFull() error
+}
+
+// DeferredWALOpener is implemented by engines which can replay their WAL
+// after loading the metadata index. Once DeferWALOpen is called,
+// LoadMetadataIndex leaves the WAL for OpenWAL to replay, so shards replay
+// without holding the lock of their database index.
+type DeferredWALOpener interface {
+	DeferWALOpen()
+	OpenWAL() error
+}
+
+// AccessPattern describes how a query reads the data of a shard.
+type AccessPattern int
+
+const (
+	// NormalAccess is the default access pattern.
+	NormalAccess AccessPattern = iota
+
+	// SequentialAccess reads the blocks of each series in order.
+	SequentialAccess
+
+	// RandomAccess reads a few points of each series.
+	RandomAccess
+)
+
+// AccessAdvisor is implemented by transactions that can tell the operating
+// system how their data is read, so it can read ahead or avoid doing so.
+type AccessAdvisor interface {
+	Advise(p AccessPattern) error
+}
+
+// SeriesFilter is implemented by engines that can rule out series they hold
+// no points for without reading their index. MayContainSeries may return
+// false positives but never returns false for a series with points.
+type SeriesFilter interface {
+	MayContainSeries(key string) bool
+}
+
+// BlockInfo describes a single block of point data.
+type BlockInfo struct {
+	Key     string // series key
+	MinTime int64
+	MaxTime int64
+	Points  int
+	Size    int   // stored size, in bytes
+	RawSize int   // uncompressed size, in bytes
+	Err     error // set if the block failed verification
+}
+
 // EngineOptions represents the options used to initialize the engine.
 type EngineOptions struct {
 	EngineVersion          string
@@ -105,6 +197,10 @@ type EngineOptions struct {
 	WALFlushInterval       time.Duration
 	WALPartitionFlushDelay time.Duration
 
+	// Replay, if set, tracks the replay of the WAL of shards opened by the
+	// store.
+	Replay *ReplayProgress
+
 	Config Config
 }
 
@@ -129,6 +225,13 @@ type Tx interface {
 	Rollback() error
 }
 
+// CacheTx is a transaction which counts the points its cursors have read
+// from the in-memory cache of the engine, rather than from disk.
+type CacheTx interface {
+	Tx
+	CacheHitN() int64
+}
+
 // Cursor represents an iterator over a series.
 type Cursor interface {
 	Seek(seek []byte) (key, value []byte)
@@ -512,6 +512,13 @@ func (e *Engine) SeriesCount() (n int, err error) {
 	return
 }
 
+// CacheSize returns the approximate size of the points in the WAL cache.
+func (e *Engine) CacheSize() int64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return int64(e.walSize)
+}
+
 // Begin starts a new transaction on the engine.
 func (e *Engine) Begin(writable bool) (tsdb.Tx, error) {
 	tx, err := e.db.Begin(writable)
@@ -7,17 +7,21 @@ import (
 	"errors"
 	"expvar"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"log"
 	"math"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/boltdb/bolt"
 	"github.com/golang/snappy"
 	"github.com/influxdb/influxdb"
+	"github.com/influxdb/influxdb/pkg/bloom"
 	"github.com/influxdb/influxdb/tsdb"
+	"github.com/influxdb/influxdb/tsdb/engine/codec"
 	"github.com/influxdb/influxdb/tsdb/engine/wal"
 )
 
@@ -38,6 +42,13 @@ const (
 	statBlocksWrite              = "blks_write"
 	statBlocksWriteBytes         = "blks_write_bytes"
 	statBlocksWriteBytesCompress = "blks_write_bytes_c"
+	statPrewarm                  = "prewarm"
+	statPrewarmBytes             = "prewarm_bytes"
+	statCacheDrop                = "cache_drop"
+	statDropSeries               = "drop_series"  // series whose points were deleted after a drop
+	statDropPending              = "drop_pending" // dropped series with points waiting to be deleted
+	statCompactFull              = "compact_full"
+	statCompactFullSeries        = "compact_full_series" // series rewritten by full compactions
 )
 
 func init() {
@@ -47,6 +58,9 @@ func init() {
 const (
 	// DefaultBlockSize is the default size of uncompressed points blocks.
 	DefaultBlockSize = 4 * 1024 // 4KB
+
+	// seriesFilterRate is the false positive rate of the series filter.
+	seriesFilterRate = 0.01
 )
 
 // Ensure Engine implements the interface.
@@ -66,6 +80,41 @@ type Engine struct {
 
 	// Size of uncompressed points to write to a block.
 	BlockSize int
+
+	// Whether transactions pass access hints for the data file's memory
+	// mapping on to the operating system.
+	MmapHintsEnabled bool
+	advisedN         int32 // open transactions which gave a hint
+
+	// Whether the pages the WAL flushes to the data file are dropped from the
+	// page cache, so large flushes don't evict the cache of other shards.
+	FlushBypassCache bool
+
+	// Whether the data file is pre-warmed after the WAL flushes to it.
+	PrewarmEnabled bool
+	prewarming     int32 // set while a pre-warm is running
+
+	// Points of dropped series are deleted in the background, DropBatchSize
+	// series per transaction with DropBatchDelay between transactions, so
+	// large drops don't block writes.
+	DropBatchSize  int
+	DropBatchDelay time.Duration
+	dropPendingN   int64         // tombstoned series
+	dropc          chan struct{} // signals series were tombstoned
+	done           chan struct{}
+	wg             sync.WaitGroup
+
+	// Codec version the blocks are written with. Zero is the original block
+	// format, which snappy compresses the entries as a whole.
+	codec byte
+
+	// Bloom filter over the keys of the series in the file. Nil until the
+	// metadata index is loaded.
+	filterMu sync.RWMutex
+	filter   *bloom.Filter
+
+	// Set if the WAL is opened by OpenWAL instead of LoadMetadataIndex.
+	deferWALOpen bool
 }
 
 // WAL represents a write ahead log that can be queried
@@ -96,14 +145,21 @@ func NewEngine(path string, walPath string, opt tsdb.EngineOptions) tsdb.Engine
 	w.PartitionSizeThreshold = opt.Config.WALPartitionSizeThreshold
 	w.ReadySeriesSize = opt.Config.WALReadySeriesSize
 	w.LoggingEnabled = opt.Config.WALLoggingEnabled
+	w.Replay = opt.Replay
 
 	e := &Engine{
 		path: path,
 
-		statMap:   statMap,
-		BlockSize: DefaultBlockSize,
-		WAL:       w,
+		statMap:          statMap,
+		BlockSize:        DefaultBlockSize,
+		MmapHintsEnabled: opt.Config.MmapHintsEnabled,
+		FlushBypassCache: opt.Config.WALFlushBypassCache,
+		PrewarmEnabled:   opt.Config.PrewarmEnabled,
+		DropBatchSize:    opt.Config.DropBatchSize,
+		DropBatchDelay:   time.Duration(opt.Config.DropBatchDelay),
+		WAL:              w,
 	}
+	statMap.Set(statDropPending, expvar.Func(func() interface{} { return atomic.LoadInt64(&e.dropPendingN) }))
 
 	w.Index = e
 
@@ -130,12 +186,32 @@ func (e *Engine) Open() error {
 		if err := e.db.Update(func(tx *bolt.Tx) error {
 			_, _ = tx.CreateBucketIfNotExists([]byte("points"))
 
-			// Set file format, if not set yet.
+			// Dropped series are tombstoned until their points are deleted.
+			tombstones, err := tx.CreateBucketIfNotExists([]byte("tombstones"))
+			if err != nil {
+				return fmt.Errorf("create tombstones: %s", err)
+			}
+			atomic.StoreInt64(&e.dropPendingN, int64(tombstones.Stats().KeyN))
+
+			// Set file format, if not set yet. New files use the latest codecs.
 			b, _ := tx.CreateBucketIfNotExists([]byte("meta"))
 			if v := b.Get([]byte("format")); v == nil {
 				if err := b.Put([]byte("format"), []byte(Format)); err != nil {
 					return fmt.Errorf("set format: %s", err)
 				}
+				if err := b.Put([]byte("codec"), []byte{codec.Version}); err != nil {
+					return fmt.Errorf("set codec: %s", err)
+				}
+			}
+
+			// Files without a codec version predate codecs and keep being
+			// written in the original block format.
+			e.codec = 0
+			if v := b.Get([]byte("codec")); len(v) == 1 {
+				e.codec = v[0]
+			}
+			if e.codec > codec.Version {
+				return fmt.Errorf("unsupported codec version: %d", e.codec)
 			}
 
 			return nil
@@ -143,6 +219,11 @@ func (e *Engine) Open() error {
 			return fmt.Errorf("init: %s", err)
 		}
 
+		// Delete the points of series dropped before the file was closed.
+		e.dropc, e.done = make(chan struct{}, 1), make(chan struct{})
+		e.wg.Add(1)
+		go e.dropLoop(e.done)
+
 		return nil
 	}(); err != nil {
 		e.close()
@@ -157,6 +238,13 @@ func (e *Engine) Close() error {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
+	// Stop deleting dropped series. Deletion resumes when the file is reopened.
+	if e.done != nil {
+		close(e.done)
+		e.wg.Wait()
+		e.done = nil
+	}
+
 	if err := e.WAL.Close(); err != nil {
 		return err
 	}
@@ -176,9 +264,12 @@ func (e *Engine) SetLogOutput(w io.Writer) {}
 
 // LoadMetadataIndex loads the shard metadata into memory.
 func (e *Engine) LoadMetadataIndex(index *tsdb.DatabaseIndex, measurementFields map[string]*tsdb.MeasurementFields) error {
-	if err := e.db.View(func(tx *bolt.Tx) error {
+	var fields map[string]*tsdb.MeasurementFields
+	var series map[string]*tsdb.Series
+	var fieldsRebuilt, rebuilt bool
+	if err := e.db.View(func(tx *bolt.Tx) (err error) {
 		// Load measurement metadata
-		fields, err := e.readFields(tx)
+		fields, fieldsRebuilt, err = e.readFields(tx)
 		if err != nil {
 			return err
 		}
@@ -191,10 +282,15 @@ func (e *Engine) LoadMetadataIndex(index *tsdb.DatabaseIndex, measurementFields
 			measurementFields[m.Name] = mf
 		}
 
-		// Load series metadata
-		series, err := e.readSeries(tx)
+		// Load series metadata, rebuilding it from the series with points
+		// if it can't be read.
+		series, err = e.readSeries(tx)
 		if err != nil {
-			return err
+			log.Printf("rebuilding series metadata of %s: %s", e.path, err)
+			if series, err = e.rebuildSeries(tx); err != nil {
+				return fmt.Errorf("rebuild series: %s", err)
+			}
+			rebuilt = true
 		}
 
 		// Load the series into the in-memory index in sorted order to ensure
@@ -209,23 +305,60 @@ func (e *Engine) LoadMetadataIndex(index *tsdb.DatabaseIndex, measurementFields
 			s.InitializeShards()
 			index.CreateSeriesIndexIfNotExists(tsdb.MeasurementFromSeriesKey(string(key)), s)
 		}
+
+		// Build the series filter, leaving room for new series.
+		e.filterMu.Lock()
+		e.filter = bloom.NewFilter(2*len(a), seriesFilterRate)
+		for _, key := range a {
+			e.filter.Insert([]byte(key))
+		}
+		e.filterMu.Unlock()
 		return nil
 	}); err != nil {
 		return err
 	}
 
+	// Save the rebuilt metadata.
+	if fieldsRebuilt {
+		if err := e.db.Update(func(tx *bolt.Tx) error {
+			return e.writeFields(tx, fields)
+		}); err != nil {
+			return fmt.Errorf("write rebuilt fields: %s", err)
+		}
+	}
+	if rebuilt {
+		if err := e.db.Update(func(tx *bolt.Tx) error {
+			return e.writeSeries(tx, series)
+		}); err != nil {
+			return fmt.Errorf("write rebuilt series: %s", err)
+		}
+	}
+
 	// now flush the metadata that was in the WAL, but hadn't yet been flushed
 	if err := e.WAL.LoadMetadataIndex(index, measurementFields); err != nil {
 		return err
 	}
 
-	// finally open the WAL up
+	// finally open the WAL up, unless it's opened separately
+	if e.deferWALOpen {
+		return nil
+	}
 	return e.WAL.Open()
 }
 
+// DeferWALOpen leaves the WAL to be opened by OpenWAL after the metadata
+// index is loaded.
+func (e *Engine) DeferWALOpen() { e.deferWALOpen = true }
+
+// OpenWAL opens the WAL, replaying its segments, after LoadMetadataIndex.
+func (e *Engine) OpenWAL() error { return e.WAL.Open() }
+
 // WritePoints writes metadata and point data into the engine.
 // Returns an error if new points are added to an existing key.
 func (e *Engine) WritePoints(points []tsdb.Point, measurementFieldsToSave map[string]*tsdb.MeasurementFields, seriesToCreate []*tsdb.SeriesCreate) error {
+	// Add new series to the filter first so queries find the points in the WAL.
+	e.addSeriesToFilter(seriesToCreate)
+
 	// Write points to the WAL.
 	if err := e.WAL.WritePoints(points, measurementFieldsToSave, seriesToCreate); err != nil {
 		return fmt.Errorf("write points: %s", err)
@@ -236,7 +369,8 @@ func (e *Engine) WritePoints(points []tsdb.Point, measurementFieldsToSave map[st
 
 // WriteIndex writes marshaled points to the engine's underlying index.
 func (e *Engine) WriteIndex(pointsByKey map[string][][]byte, measurementFieldsToSave map[string]*tsdb.MeasurementFields, seriesToCreate []*tsdb.SeriesCreate) error {
-	return e.db.Update(func(tx *bolt.Tx) error {
+	var dropped int
+	if err := e.db.Update(func(tx *bolt.Tx) error {
 		// Write series & field metadata.
 		if err := e.writeNewSeries(tx, seriesToCreate); err != nil {
 			return fmt.Errorf("write series: %s", err)
@@ -245,13 +379,91 @@ func (e *Engine) WriteIndex(pointsByKey map[string][][]byte, measurementFieldsTo
 			return fmt.Errorf("write fields: %s", err)
 		}
 
+		// Blocks store the values of fields by type since codec version 3.
+		var fields map[string]*tsdb.MeasurementFields
+		if e.codec >= 3 && len(pointsByKey) > 0 {
+			var err error
+			if fields, _, err = e.readFields(tx); err != nil {
+				return fmt.Errorf("read fields: %s", err)
+			}
+		}
+
+		tombstones := tx.Bucket([]byte("tombstones"))
 		for key, values := range pointsByKey {
-			if err := e.writeIndex(tx, key, values); err != nil {
+			// Points written to a dropped series replace its old points, so
+			// delete them first.
+			if tombstones.Get([]byte(key)) != nil {
+				if err := deleteTombstoned(tx, [][]byte{[]byte(key)}); err != nil {
+					return err
+				}
+				dropped++
+			}
+
+			if err := e.writeIndex(tx, key, values, newFieldTypes(fields, key)); err != nil {
 				return fmt.Errorf("write: key=%x, err=%s", key, err)
 			}
 		}
 		return nil
-	})
+	}); err != nil {
+		return err
+	}
+	e.dropped(dropped)
+
+	// Drop the flushed pages from the page cache. Commits sync the file so
+	// the pages are clean and can be dropped right away. The pages queries
+	// have read are mapped and stay cached.
+	if e.FlushBypassCache && len(pointsByKey) > 0 {
+		if err := dropCache(e.path); err != nil {
+			log.Printf("drop cache of %s: %s", e.path, err)
+		} else {
+			e.statMap.Add(statCacheDrop, 1)
+		}
+	}
+
+	// Pre-warm the flushed series in the background so the first queries
+	// after a flush don't wait on the rewritten pages. Flushes during a
+	// pre-warm don't start another one. Pages dropped from the cache aren't
+	// read back in.
+	if e.PrewarmEnabled && !e.FlushBypassCache && len(pointsByKey) > 0 && atomic.CompareAndSwapInt32(&e.prewarming, 0, 1) {
+		keys := make([]string, 0, len(pointsByKey))
+		for key := range pointsByKey {
+			keys = append(keys, key)
+		}
+		go func(db *bolt.DB) {
+			defer atomic.StoreInt32(&e.prewarming, 0)
+			e.prewarm(db, keys)
+		}(e.db)
+	}
+	return nil
+}
+
+// prewarm reads the most recent block of each series of keys, so the pages
+// queries read first are in the page cache. Errors are ignored since the file
+// may be closed while pre-warming.
+func (e *Engine) prewarm(db *bolt.DB, keys []string) {
+	tx, err := db.Begin(false)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback()
+
+	var n int64
+	points := tx.Bucket([]byte("points"))
+	for _, key := range keys {
+		b := points.Bucket([]byte(key))
+		if b == nil {
+			continue
+		}
+
+		// Checksum the last block so all of its pages are read.
+		if _, v := b.Cursor().Last(); v != nil {
+			crc32.ChecksumIEEE(v)
+			n += int64(len(v))
+		}
+	}
+
+	e.statMap.Add(statPrewarm, 1)
+	e.statMap.Add(statPrewarmBytes, n)
 }
 
 func (e *Engine) writeNewFields(tx *bolt.Tx, measurementFieldsToSave map[string]*tsdb.MeasurementFields) error {
@@ -260,7 +472,7 @@ func (e *Engine) writeNewFields(tx *bolt.Tx, measurementFieldsToSave map[string]
 	}
 
 	// read in all the previously saved fields
-	fields, err := e.readFields(tx)
+	fields, _, err := e.readFields(tx)
 	if err != nil {
 		return err
 	}
@@ -283,23 +495,49 @@ func (e *Engine) writeFields(tx *bolt.Tx, fields map[string]*tsdb.MeasurementFie
 	return tx.Bucket([]byte("meta")).Put([]byte("fields"), snappy.Encode(nil, data))
 }
 
-func (e *Engine) readFields(tx *bolt.Tx) (map[string]*tsdb.MeasurementFields, error) {
+// readFields returns the fields of the measurements. Fields which can't be
+// read are rebuilt from the series metadata, and true is returned with them.
+func (e *Engine) readFields(tx *bolt.Tx) (map[string]*tsdb.MeasurementFields, bool, error) {
 	fields := make(map[string]*tsdb.MeasurementFields)
 
 	b := tx.Bucket([]byte("meta")).Get([]byte("fields"))
 	if b == nil {
-		return fields, nil
+		return fields, false, nil
 	}
 
 	data, err := snappy.Decode(nil, b)
+	if err == nil {
+		err = json.Unmarshal(data, &fields)
+	}
 	if err != nil {
-		return nil, err
+		log.Printf("rebuilding fields of %s: %s", e.path, err)
+		if fields, err = e.rebuildFields(tx); err != nil {
+			return nil, false, fmt.Errorf("rebuild fields: %s", err)
+		}
+		return fields, true, nil
 	}
 
-	if err := json.Unmarshal(data, &fields); err != nil {
-		return nil, err
+	return fields, false, nil
+}
+
+// rebuildFields returns the measurements of the series metadata without
+// fields. The names and types of the fields aren't stored anywhere else, so
+// the field values of the points already written can't be decoded.
+func (e *Engine) rebuildFields(tx *bolt.Tx) (map[string]*tsdb.MeasurementFields, error) {
+	series, err := e.readSeries(tx)
+	if err != nil {
+		if series, err = e.rebuildSeries(tx); err != nil {
+			return nil, err
+		}
 	}
 
+	fields := make(map[string]*tsdb.MeasurementFields)
+	for key := range series {
+		name := tsdb.MeasurementFromSeriesKey(key)
+		if fields[name] == nil {
+			fields[name] = &tsdb.MeasurementFields{Fields: make(map[string]*tsdb.Field)}
+		}
+	}
 	return fields, nil
 }
 
@@ -318,6 +556,7 @@ func (e *Engine) writeNewSeries(tx *bolt.Tx, seriesToCreate []*tsdb.SeriesCreate
 	for _, s := range seriesToCreate {
 		series[s.Series.Key] = s.Series
 	}
+	e.addSeriesToFilter(seriesToCreate)
 
 	return e.writeSeries(tx, series)
 }
@@ -331,6 +570,23 @@ func (e *Engine) writeSeries(tx *bolt.Tx, series map[string]*tsdb.Series) error
 	return tx.Bucket([]byte("meta")).Put([]byte("series"), snappy.Encode(nil, data))
 }
 
+// rebuildSeries returns the series metadata of the series with points.
+func (e *Engine) rebuildSeries(tx *bolt.Tx) (map[string]*tsdb.Series, error) {
+	series := make(map[string]*tsdb.Series)
+	tombstones := tx.Bucket([]byte("tombstones"))
+	if err := tx.Bucket([]byte("points")).ForEach(func(k, _ []byte) error {
+		if tombstones.Get(k) != nil {
+			return nil // dropped
+		}
+		_, tags := tsdb.ParseKey(string(k))
+		series[string(k)] = &tsdb.Series{Key: string(k), Tags: tags}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
 func (e *Engine) readSeries(tx *bolt.Tx) (map[string]*tsdb.Series, error) {
 	series := make(map[string]*tsdb.Series)
 
@@ -351,8 +607,9 @@ func (e *Engine) readSeries(tx *bolt.Tx) (map[string]*tsdb.Series, error) {
 	return series, nil
 }
 
-// writeIndex writes a set of points for a single key.
-func (e *Engine) writeIndex(tx *bolt.Tx, key string, a [][]byte) error {
+// writeIndex writes a set of points for a single key. The types of the
+// fields of the key's measurement are used to encode the blocks.
+func (e *Engine) writeIndex(tx *bolt.Tx, key string, a [][]byte, types fieldTypes) error {
 	// Ignore if there are no points.
 	if len(a) == 0 {
 		return nil
@@ -385,7 +642,7 @@ func (e *Engine) writeIndex(tx *bolt.Tx, key string, a [][]byte) error {
 	// with existing blocks on disk and rewrite all the blocks for that range.
 	if k, v := c.Last(); k == nil {
 		bkt.FillPercent = 1.0
-		if err := e.writeBlocks(bkt, a); err != nil {
+		if err := e.writeBlocks(bkt, a, types); err != nil {
 			return fmt.Errorf("new blocks: %s", err)
 		}
 		return nil
@@ -393,7 +650,7 @@ func (e *Engine) writeIndex(tx *bolt.Tx, key string, a [][]byte) error {
 	} else if int64(btou64(v[0:8])) < tmin {
 		// Append new blocks if our time range is past the last on-disk time.
 		bkt.FillPercent = 1.0
-		if err := e.writeBlocks(bkt, a); err != nil {
+		if err := e.writeBlocks(bkt, a, types); err != nil {
 			return fmt.Errorf("append blocks: %s", err)
 		}
 		return nil
@@ -420,15 +677,18 @@ func (e *Engine) writeIndex(tx *bolt.Tx, key string, a [][]byte) error {
 		}
 
 		// Decode block.
-		buf, err := snappy.Decode(nil, v[8:])
+		blk, err := decodeBlock(v, e.codec)
 		if err != nil {
 			return fmt.Errorf("decode block: %s", err)
+		} else if err := blk.decodeValues(); err != nil {
+			return fmt.Errorf("decode block: %s", err)
 		}
 
 		// Copy out any entries that aren't being overwritten.
-		for _, entry := range SplitEntries(buf) {
-			if _, ok := m[int64(btou64(entry[0:8]))]; !ok {
-				existing = append(existing, entry)
+		for i := 0; i < blk.len(); i++ {
+			timestamp := int64(btou64(blk.key(i)))
+			if _, ok := m[timestamp]; !ok {
+				existing = append(existing, MarshalEntry(timestamp, blk.values[i]))
 			}
 		}
 
@@ -441,7 +701,7 @@ func (e *Engine) writeIndex(tx *bolt.Tx, key string, a [][]byte) error {
 	sort.Sort(tsdb.ByteSlices(a))
 
 	// Rewrite points to new blocks.
-	if err := e.writeBlocks(bkt, a); err != nil {
+	if err := e.writeBlocks(bkt, a, types); err != nil {
 		return fmt.Errorf("rewrite blocks: %s", err)
 	}
 
@@ -449,7 +709,7 @@ func (e *Engine) writeIndex(tx *bolt.Tx, key string, a [][]byte) error {
 }
 
 // writeBlocks writes point data to the bucket in blocks.
-func (e *Engine) writeBlocks(bkt *bolt.Bucket, a [][]byte) error {
+func (e *Engine) writeBlocks(bkt *bolt.Bucket, a [][]byte, types fieldTypes) error {
 	var block []byte
 
 	// Group points into blocks by size.
@@ -473,10 +733,10 @@ func (e *Engine) writeBlocks(bkt *bolt.Bucket, a [][]byte) error {
 			e.statMap.Add(statBlocksWrite, 1)
 			e.statMap.Add(statBlocksWriteBytes, int64(len(block)))
 
-			// Encode block in the following format:
-			//   tmax int64
-			//   data []byte (snappy compressed)
-			value := append(u64tob(uint64(tmax)), snappy.Encode(nil, block)...)
+			value, err := encodeBlock(tmax, block, e.codec, types)
+			if err != nil {
+				return fmt.Errorf("encode: ts=%d-%d, err=%s", tmin, tmax, err)
+			}
 
 			// Write block to the bucket.
 			if err := bkt.Put(u64tob(uint64(tmin)), value); err != nil {
@@ -493,38 +753,38 @@ func (e *Engine) writeBlocks(bkt *bolt.Bucket, a [][]byte) error {
 	return nil
 }
 
-// DeleteSeries deletes the series from the engine.
+// DeleteSeries deletes the series from the engine. The series are removed
+// from the index and tombstoned right away. Their points are deleted in the
+// background.
 func (e *Engine) DeleteSeries(keys []string) error {
 	// remove it from the WAL first
 	if err := e.WAL.DeleteSeries(keys); err != nil {
 		return err
 	}
 
-	return e.db.Update(func(tx *bolt.Tx) error {
-		series, err := e.readSeries(tx)
-		if err != nil {
-			return err
-		}
-		for _, k := range keys {
-			delete(series, k)
-			if err := tx.Bucket([]byte("points")).DeleteBucket([]byte(k)); err != nil && err != bolt.ErrBucketNotFound {
-				return fmt.Errorf("delete series data: %s", err)
-			}
-		}
-
-		return e.writeSeries(tx, series)
-	})
+	var n int
+	if err := e.db.Update(func(tx *bolt.Tx) (err error) {
+		n, err = e.tombstoneSeries(tx, keys)
+		return err
+	}); err != nil {
+		return err
+	}
+	e.tombstoned(n)
+	return nil
 }
 
-// DeleteMeasurement deletes a measurement and all related series.
+// DeleteMeasurement deletes a measurement and all related series. The series
+// are removed from the index and tombstoned right away. Their points are
+// deleted in the background.
 func (e *Engine) DeleteMeasurement(name string, seriesKeys []string) error {
 	// remove from the WAL first so it won't get flushed after removing from Bolt
 	if err := e.WAL.DeleteSeries(seriesKeys); err != nil {
 		return err
 	}
 
-	return e.db.Update(func(tx *bolt.Tx) error {
-		fields, err := e.readFields(tx)
+	var n int
+	if err := e.db.Update(func(tx *bolt.Tx) (err error) {
+		fields, _, err := e.readFields(tx)
 		if err != nil {
 			return err
 		}
@@ -533,33 +793,290 @@ func (e *Engine) DeleteMeasurement(name string, seriesKeys []string) error {
 			return err
 		}
 
-		series, err := e.readSeries(tx)
+		n, err = e.tombstoneSeries(tx, seriesKeys)
+		return err
+	}); err != nil {
+		return err
+	}
+	e.tombstoned(n)
+	return nil
+}
+
+// tombstoneSeries removes series from the index and tombstones the ones with
+// points. It returns the number of series tombstoned.
+func (e *Engine) tombstoneSeries(tx *bolt.Tx, keys []string) (n int, err error) {
+	series, err := e.readSeries(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	points, tombstones := tx.Bucket([]byte("points")), tx.Bucket([]byte("tombstones"))
+	for _, k := range keys {
+		delete(series, k)
+		if points.Bucket([]byte(k)) == nil || tombstones.Get([]byte(k)) != nil {
+			continue
+		}
+		if err := tombstones.Put([]byte(k), u64tob(uint64(time.Now().UnixNano()))); err != nil {
+			return 0, fmt.Errorf("tombstone series: %s", err)
+		}
+		n++
+	}
+
+	return n, e.writeSeries(tx, series)
+}
+
+// tombstoned records n newly tombstoned series and wakes the drop loop.
+func (e *Engine) tombstoned(n int) {
+	if n == 0 {
+		return
+	}
+	atomic.AddInt64(&e.dropPendingN, int64(n))
+
+	select {
+	case e.dropc <- struct{}{}:
+	default:
+	}
+}
+
+// dropped records the deletion of the points of n tombstoned series.
+func (e *Engine) dropped(n int) {
+	if n == 0 {
+		return
+	}
+	atomic.AddInt64(&e.dropPendingN, -int64(n))
+	e.statMap.Add(statDropSeries, int64(n))
+}
+
+// dropLoop deletes the points of tombstoned series in batches until done is
+// closed.
+func (e *Engine) dropLoop(done chan struct{}) {
+	defer e.wg.Done()
+
+	for {
+		n, err := e.dropBatch()
 		if err != nil {
+			log.Printf("delete dropped series of %s: %s", e.path, err)
+		}
+
+		// Pause between batches so deletes don't starve writes.
+		if n > 0 && err == nil {
+			select {
+			case <-done:
+				return
+			case <-time.After(e.DropBatchDelay):
+			}
+			continue
+		}
+
+		// Wait for more series to be dropped once all are deleted.
+		select {
+		case <-done:
+			return
+		case <-e.dropc:
+		}
+	}
+}
+
+// dropBatch deletes the points of a batch of tombstoned series. It returns the
+// number of series deleted.
+func (e *Engine) dropBatch() (n int, err error) {
+	size := e.DropBatchSize
+	if size <= 0 {
+		size = tsdb.DefaultDropBatchSize
+	}
+
+	if err := e.db.Update(func(tx *bolt.Tx) error {
+		var keys [][]byte
+		c := tx.Bucket([]byte("tombstones")).Cursor()
+		for k, _ := c.First(); k != nil && len(keys) < size; k, _ = c.Next() {
+			keys = append(keys, append([]byte(nil), k...))
+		}
+		n = len(keys)
+		return deleteTombstoned(tx, keys)
+	}); err != nil {
+		return 0, err
+	}
+	e.dropped(n)
+	return n, nil
+}
+
+// deleteTombstoned deletes the points and tombstones of tombstoned series.
+func deleteTombstoned(tx *bolt.Tx, keys [][]byte) error {
+	points, tombstones := tx.Bucket([]byte("points")), tx.Bucket([]byte("tombstones"))
+	for _, k := range keys {
+		if err := points.DeleteBucket(k); err != nil && err != bolt.ErrBucketNotFound {
+			return fmt.Errorf("delete series data: %s", err)
+		}
+		if err := tombstones.Delete(k); err != nil {
+			return fmt.Errorf("delete tombstone: %s", err)
+		}
+	}
+	return nil
+}
+
+// CompactFull flushes the WAL, which closes its segment file until the next
+// write, and rewrites the blocks of each series into full blocks on fully
+// packed pages. The series needing it are found in a read transaction, and
+// each is rewritten in its own transaction so writes aren't blocked for the
+// whole compaction. The data file isn't shrunk: the pages freed are reused by
+// later writes. Closing the engine stops the compaction.
+func (e *Engine) CompactFull() error {
+	e.mu.Lock()
+	done := e.done
+	if done == nil {
+		e.mu.Unlock()
+		return errors.New("engine closed")
+	}
+	e.wg.Add(1)
+	e.mu.Unlock()
+	defer e.wg.Done()
+
+	if err := e.WAL.Flush(); err != nil {
+		return fmt.Errorf("flush wal: %s", err)
+	}
+
+	var keys [][]byte
+	var fields map[string]*tsdb.MeasurementFields
+	if err := e.db.View(func(tx *bolt.Tx) (err error) {
+		if fields, _, err = e.readFields(tx); err != nil {
 			return err
 		}
-		for _, k := range seriesKeys {
-			delete(series, k)
-			if err := tx.Bucket([]byte("points")).DeleteBucket([]byte(k)); err != nil && err != bolt.ErrBucketNotFound {
-				return fmt.Errorf("delete series data: %s", err)
+
+		tombstones := tx.Bucket([]byte("tombstones"))
+		return tx.Bucket([]byte("points")).ForEach(func(k, _ []byte) error {
+			if tombstones.Get(k) == nil {
+				keys = append(keys, append([]byte(nil), k...))
 			}
+			return nil
+		})
+	}); err != nil {
+		return err
+	}
+
+	var n int
+	for _, key := range keys {
+		select {
+		case <-done:
+			return errors.New("engine closed")
+		default:
 		}
 
-		return e.writeSeries(tx, series)
-	})
+		// Only take the write lock for the series which need rewriting.
+		var rewrite bool
+		if err := e.db.View(func(tx *bolt.Tx) (err error) {
+			if bkt := tx.Bucket([]byte("points")).Bucket(key); bkt != nil {
+				_, rewrite, err = e.seriesEntries(bkt)
+			}
+			return err
+		}); err != nil {
+			return fmt.Errorf("compact: key=%x, err=%s", key, err)
+		} else if !rewrite {
+			continue
+		}
+
+		if err := e.db.Update(func(tx *bolt.Tx) error {
+			ok, err := e.compactSeries(tx, key, newFieldTypes(fields, string(key)))
+			if ok {
+				n++
+			}
+			return err
+		}); err != nil {
+			return fmt.Errorf("compact: key=%x, err=%s", key, err)
+		}
+	}
+
+	e.statMap.Add(statCompactFull, 1)
+	e.statMap.Add(statCompactFullSeries, int64(n))
+	return nil
 }
 
-// SeriesCount returns the number of series buckets on the shard.
+// compactSeries rewrites the blocks of the series with key into full blocks,
+// unless only its last block isn't full. Returns true if it was rewritten.
+func (e *Engine) compactSeries(tx *bolt.Tx, key []byte, types fieldTypes) (bool, error) {
+	points := tx.Bucket([]byte("points"))
+	bkt := points.Bucket(key)
+	if bkt == nil || tx.Bucket([]byte("tombstones")).Get(key) != nil {
+		return false, nil
+	}
+
+	a, rewrite, err := e.seriesEntries(bkt)
+	if err != nil || !rewrite {
+		return false, err
+	}
+
+	// Recreate the bucket so its pages are filled completely.
+	if err := points.DeleteBucket(key); err != nil {
+		return false, fmt.Errorf("delete series bucket: %s", err)
+	}
+	bkt, err = points.CreateBucket(key)
+	if err != nil {
+		return false, fmt.Errorf("create series bucket: %s", err)
+	}
+	bkt.FillPercent = 1.0
+	if err := e.writeBlocks(bkt, a, types); err != nil {
+		return false, fmt.Errorf("rewrite blocks: %s", err)
+	}
+	return true, nil
+}
+
+// seriesEntries returns the entries of the blocks of a series bucket, and true
+// if a block other than the last isn't full so they should be rewritten.
+func (e *Engine) seriesEntries(bkt *bolt.Bucket) ([][]byte, bool, error) {
+	var a [][]byte
+	var partial, rewrite bool
+	c := bkt.Cursor()
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		blk, err := decodeBlock(v, e.codec)
+		if err != nil {
+			return nil, false, fmt.Errorf("decode block: %s", err)
+		} else if err := blk.decodeValues(); err != nil {
+			return nil, false, fmt.Errorf("decode block: %s", err)
+		}
+
+		var size int
+		for i := 0; i < blk.len(); i++ {
+			entry := MarshalEntry(int64(btou64(blk.key(i))), blk.values[i])
+			a = append(a, entry)
+			size += len(entry)
+		}
+
+		// Only a block followed by another may be rewritten fuller.
+		rewrite = rewrite || partial
+		partial = size < e.BlockSize
+	}
+	return a, rewrite, nil
+}
+
+// SeriesCount returns the number of series buckets on the shard, excluding
+// dropped series.
 func (e *Engine) SeriesCount() (n int, err error) {
 	err = e.db.View(func(tx *bolt.Tx) error {
 		c := tx.Bucket([]byte("points")).Cursor()
 		for k, _ := c.First(); k != nil; k, _ = c.Next() {
 			n++
 		}
+		n -= tx.Bucket([]byte("tombstones")).Stats().KeyN
 		return nil
 	})
 	return
 }
 
+// CacheSize returns the size of the points cached by the WAL.
+func (e *Engine) CacheSize() int64 {
+	if c, ok := e.WAL.(tsdb.CacheSizer); ok {
+		return c.CacheSize()
+	}
+	return 0
+}
+
+// WALStats returns the state of the WAL.
+func (e *Engine) WALStats() tsdb.WALStats {
+	if r, ok := e.WAL.(tsdb.WALReporter); ok {
+		return r.WALStats()
+	}
+	return tsdb.WALStats{}
+}
+
 // Begin starts a new transaction on the engine.
 func (e *Engine) Begin(writable bool) (tsdb.Tx, error) {
 	tx, err := e.db.Begin(writable)
@@ -569,6 +1086,39 @@ func (e *Engine) Begin(writable bool) (tsdb.Tx, error) {
 	return &Tx{Tx: tx, engine: e, wal: e.WAL}, nil
 }
 
+// MayContainSeries returns false if the engine holds no points for a series.
+// It uses a bloom filter over the series keys, so it may return true for
+// series without points, such as deleted series, but never returns false for
+// series with points.
+func (e *Engine) MayContainSeries(key string) bool {
+	e.filterMu.RLock()
+	defer e.filterMu.RUnlock()
+	if e.filter == nil {
+		return true
+	}
+	return e.filter.Contains([]byte(key))
+}
+
+// addSeriesToFilter adds the keys of new series to the series filter.
+func (e *Engine) addSeriesToFilter(seriesToCreate []*tsdb.SeriesCreate) {
+	e.filterMu.Lock()
+	defer e.filterMu.Unlock()
+	if e.filter == nil {
+		return
+	}
+
+	for _, s := range seriesToCreate {
+		// Series are created in the WAL and again when flushed. Skip keys
+		// already in the filter so they don't use up its room twice.
+		if key := []byte(s.Series.Key); !e.filter.Contains(key) {
+			e.filter.Insert(key)
+		}
+	}
+}
+
+// CodecVersion returns the codec version the engine writes blocks with.
+func (e *Engine) CodecVersion() int { return int(e.codec) }
+
 // Stats returns internal statistics for the engine.
 func (e *Engine) Stats() (stats Stats, err error) {
 	err = e.db.View(func(tx *bolt.Tx) error {
@@ -591,7 +1141,12 @@ func (e *Engine) SeriesBucketStats(key string) (stats bolt.BucketStats, err erro
 }
 
 // WriteTo writes the length and contents of the engine to w.
+// The WAL is flushed first so that all written points are included.
 func (e *Engine) WriteTo(w io.Writer) (n int64, err error) {
+	if err := e.WAL.Flush(); err != nil {
+		return 0, err
+	}
+
 	tx, err := e.db.Begin(false)
 	if err != nil {
 		return 0, err
@@ -609,6 +1164,164 @@ func (e *Engine) WriteTo(w io.Writer) (n int64, err error) {
 	return
 }
 
+// InspectBlocks calls fn for each block in the engine. The data file's page
+// structure is checked first. Each block is then decoded and checked for
+// consistency. Block problems are reported through BlockInfo.Err and don't
+// stop the iteration.
+func (e *Engine) InspectBlocks(fn func(tsdb.BlockInfo) error) error {
+	tx, err := e.db.Begin(false)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Drain all errors so the checker can finish.
+	var checkErr error
+	for err := range tx.Check() {
+		if checkErr == nil {
+			checkErr = err
+		}
+	}
+	if checkErr != nil {
+		return fmt.Errorf("check: %s", checkErr)
+	}
+
+	points, tombstones := tx.Bucket([]byte("points")), tx.Bucket([]byte("tombstones"))
+	return points.ForEach(func(name, _ []byte) error {
+		b := points.Bucket(name)
+		if b == nil || tombstones.Get(name) != nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			return fn(inspectBlock(string(name), k, v, e.codec))
+		})
+	})
+}
+
+// inspectBlock decodes and validates a block stored under key k.
+func inspectBlock(key string, k, v []byte, version byte) tsdb.BlockInfo {
+	info := tsdb.BlockInfo{Key: key, Size: len(k) + len(v)}
+	if len(k) != 8 || len(v) < 8 {
+		info.Err = errors.New("block header too short")
+		return info
+	}
+	info.MinTime, info.MaxTime = int64(btou64(k)), int64(btou64(v[0:8]))
+
+	blk, err := decodeBlock(v, version)
+	if err == nil {
+		err = blk.decodeValues()
+	}
+	if err != nil {
+		info.Err = fmt.Errorf("decode block: %s", err)
+		return info
+	}
+
+	// Walk the entries to ensure they are ordered.
+	var tmin, tmax int64
+	for i := 0; i < blk.len(); i++ {
+		info.RawSize += entryHeaderSize + len(blk.values[i])
+
+		timestamp := int64(btou64(blk.key(i)))
+		if info.Points == 0 {
+			tmin = timestamp
+		} else if timestamp <= tmax {
+			info.Err = fmt.Errorf("entry %d out of order", i)
+			return info
+		}
+		tmax = timestamp
+		info.Points++
+	}
+
+	if info.Points == 0 {
+		info.Err = errors.New("empty block")
+	} else if tmin != info.MinTime || tmax != info.MaxTime {
+		info.Err = fmt.Errorf("time range mismatch: header=%d-%d, entries=%d-%d", info.MinTime, info.MaxTime, tmin, tmax)
+	}
+	return info
+}
+
+// PointCounts returns the number of points stored for each series key between
+// min and max, inclusive. Blocks outside the range are skipped using their
+// header times and the remaining blocks are counted from their timestamps,
+// so point values are never decoded. Points still in the WAL are included.
+func (e *Engine) PointCounts(keys []string, min, max int64) (map[string]int64, error) {
+	tx, err := e.db.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	points, tombstones := tx.Bucket([]byte("points")), tx.Bucket([]byte("tombstones"))
+	counts := make(map[string]int64, len(keys))
+	for _, key := range keys {
+		// Collect the cached timestamps so overwritten points aren't counted twice.
+		cached := make(map[int64]struct{})
+		c := e.WAL.Cursor(key, tsdb.Forward)
+		for k, _ := c.Seek(u64tob(uint64(min))); k != nil; k, _ = c.Next() {
+			if int64(btou64(k)) > max {
+				break
+			}
+			cached[int64(btou64(k))] = struct{}{}
+		}
+		n := int64(len(cached))
+
+		if b := points.Bucket([]byte(key)); b != nil && tombstones.Get([]byte(key)) == nil {
+			blocks, err := countBlocks(b.Cursor(), min, max, cached, e.codec)
+			if err != nil {
+				return nil, fmt.Errorf("count %s: %s", key, err)
+			}
+			n += blocks
+		}
+
+		if n > 0 {
+			counts[key] = n
+		}
+	}
+	return counts, nil
+}
+
+// countBlocks returns the number of entries between min and max in the blocks
+// of a series, ignoring the timestamps in skip.
+func countBlocks(c *bolt.Cursor, min, max int64, skip map[int64]struct{}, version byte) (int64, error) {
+	// Move to the block that may contain min. Blocks are keyed by their min time.
+	seek := u64tob(uint64(min))
+	k, v := c.Seek(seek)
+	if k == nil {
+		k, v = c.Last()
+	} else if bytes.Compare(seek, k) == -1 {
+		if pk, pv := c.Prev(); pk != nil {
+			k, v = pk, pv
+		} else {
+			k, v = c.First()
+		}
+	}
+
+	var n int64
+	for ; k != nil && int64(btou64(k)) <= max; k, v = c.Next() {
+		if len(v) < 8 {
+			return 0, errors.New("block header too short")
+		}
+		if int64(btou64(v[0:8])) < min {
+			continue
+		}
+
+		blk, err := decodeBlock(v, version)
+		if err != nil {
+			return 0, fmt.Errorf("decode block: %s", err)
+		}
+		for i := 0; i < blk.len(); i++ {
+			timestamp := int64(btou64(blk.key(i)))
+			if timestamp < min || timestamp > max {
+				continue
+			}
+			if _, ok := skip[timestamp]; !ok {
+				n++
+			}
+		}
+	}
+	return n, nil
+}
+
 // Stats represents internal engine statistics.
 type Stats struct {
 	Size int64 // BoltDB data size
@@ -619,21 +1332,73 @@ type Tx struct {
 	*bolt.Tx
 	engine *Engine
 	wal    WAL
+
+	cacheHitN int64 // points read from the WAL by the cursors
+	advised   bool  // set once a hint was given
 }
 
+// Advise tells the operating system how the data file is read, if mmap hints
+// are enabled. The hint applies to the file's memory mapping, which is shared
+// by all transactions, until a later hint or until the last transaction which
+// gave one is rolled back. The mapping can't be remapped while the
+// transaction is open.
+func (tx *Tx) Advise(p tsdb.AccessPattern) error {
+	if !tx.engine.MmapHintsEnabled || tx.Size() == 0 {
+		return nil
+	}
+	if err := madvise(tx.DB().Info().Data, int(tx.Size()), p); err != nil {
+		return err
+	}
+	if !tx.advised {
+		tx.advised = true
+		atomic.AddInt32(&tx.engine.advisedN, 1)
+	}
+	return nil
+}
+
+// Rollback closes the transaction.
+func (tx *Tx) Rollback() error {
+	tx.resetAdvice()
+	return tx.Tx.Rollback()
+}
+
+// Commit writes the changes of the transaction and closes it.
+func (tx *Tx) Commit() error {
+	tx.resetAdvice()
+	return tx.Tx.Commit()
+}
+
+// resetAdvice restores the default access pattern once no open transaction
+// has given a hint, so the hint of a query doesn't apply to the reads after
+// it.
+func (tx *Tx) resetAdvice() {
+	if !tx.advised {
+		return
+	}
+	tx.advised = false
+	if atomic.AddInt32(&tx.engine.advisedN, -1) == 0 {
+		madvise(tx.DB().Info().Data, int(tx.Size()), tsdb.NormalAccess)
+	}
+}
+
+// CacheHitN returns the number of points the cursors of the transaction have
+// read from the WAL rather than the index.
+func (tx *Tx) CacheHitN() int64 { return tx.cacheHitN }
+
 // Cursor returns an iterator for a key.
 func (tx *Tx) Cursor(key string, direction tsdb.Direction) tsdb.Cursor {
-	walCursor := tx.wal.Cursor(key, direction)
+	walCursor := &countCursor{Cursor: tx.wal.Cursor(key, direction), n: &tx.cacheHitN}
 
-	// Retrieve points bucket. Ignore if there is no bucket.
+	// Retrieve points bucket. Ignore if there is no bucket or the series was dropped.
 	b := tx.Bucket([]byte("points")).Bucket([]byte(key))
-	if b == nil {
+	if b == nil || tx.Bucket([]byte("tombstones")).Get([]byte(key)) != nil {
 		return walCursor
 	}
 
 	c := &Cursor{
 		cursor:    b.Cursor(),
 		direction: direction,
+		codec:     tx.engine.codec,
 	}
 
 	if direction.Reverse() {
@@ -643,28 +1408,66 @@ func (tx *Tx) Cursor(key string, direction tsdb.Direction) tsdb.Cursor {
 	return tsdb.MultiCursor(direction, walCursor, c)
 }
 
+// countCursor counts the points read from a cursor.
+type countCursor struct {
+	tsdb.Cursor
+	n *int64
+}
+
+// Seek moves the cursor to a position and returns the closest key/value pair.
+func (c *countCursor) Seek(seek []byte) (key, value []byte) {
+	key, value = c.Cursor.Seek(seek)
+	if key != nil {
+		*c.n++
+	}
+	return
+}
+
+// Next returns the next key/value pair from the cursor.
+func (c *countCursor) Next() (key, value []byte) {
+	key, value = c.Cursor.Next()
+	if key != nil {
+		*c.n++
+	}
+	return
+}
+
 // Cursor provides ordered iteration across a series.
 type Cursor struct {
-	cursor       *bolt.Cursor
-	buf          []byte // uncompressed buffer
-	off          int    // buffer offset
-	direction    tsdb.Direction
-	fieldIndices []int
-	index        int
+	cursor    *bolt.Cursor
+	blk       *block // current block
+	i         int    // index of the current entry in blk
+	direction tsdb.Direction
+	codec     byte // codec version of the blocks
 }
 
+// last moves the cursor past the end of the last block, so a reverse cursor
+// starts at its last entry.
 func (c *Cursor) last() {
 	_, v := c.cursor.Last()
-	c.setBuf(v)
+	c.setBlock(v)
+	if c.blk != nil {
+		c.i = c.blk.len()
+	}
 }
 
 func (c *Cursor) Direction() tsdb.Direction { return c.direction }
 
 // Seek moves the cursor to a position and returns the closest key/value pair.
+// A forward cursor moves to the first key on or after seek and a reverse
+// cursor to the last key on or before seek.
 func (c *Cursor) Seek(seek []byte) (key, value []byte) {
 	// Move cursor to appropriate block and set to buffer.
 	k, v := c.cursor.Seek(seek)
-	if v == nil { // get the last block, it might have this time
+	if c.direction.Reverse() {
+		// Blocks are keyed by their min time so the block with the seek is
+		// the last block starting on or before it.
+		if v == nil {
+			_, v = c.cursor.Last()
+		} else if bytes.Compare(seek, k) == -1 {
+			_, v = c.cursor.Prev()
+		}
+	} else if v == nil { // get the last block, it might have this time
 		_, v = c.cursor.Last()
 	} else if bytes.Compare(seek, k) == -1 { // the seek key is less than this block, go back one and check
 		_, v = c.cursor.Prev()
@@ -674,130 +1477,262 @@ func (c *Cursor) Seek(seek []byte) (key, value []byte) {
 			_, v = c.cursor.Seek(seek)
 		}
 	}
-	c.setBuf(v)
+	c.setBlock(v)
 
 	// Read current block up to seek position.
-	c.seekBuf(seek)
+	c.seekBlock(seek)
 
 	// Return current entry.
 	return c.read()
 }
 
-// seekBuf moves the cursor to a position within the current buffer.
-func (c *Cursor) seekBuf(seek []byte) (key, value []byte) {
-	for {
-		// Slice off the current entry.
-		buf := c.buf[c.off:]
-
-		// Exit if current entry's timestamp is on or after the seek.
-		if len(buf) == 0 {
-			return
-		}
+// seekBlock moves the cursor to a position within the current block. Only the
+// timestamps of the skipped entries are read.
+func (c *Cursor) seekBlock(seek []byte) {
+	if c.blk == nil {
+		return
+	}
 
-		if c.direction.Forward() && bytes.Compare(buf[0:8], seek) != -1 {
-			return
-		} else if c.direction.Reverse() && bytes.Compare(buf[0:8], seek) != 1 {
-			return
+	if c.direction.Forward() {
+		for c.i < c.blk.len() && bytes.Compare(c.blk.key(c.i), seek) == -1 {
+			c.i++
 		}
-
-		if c.direction.Forward() {
-			// Otherwise skip ahead to the next entry.
-			c.off += entryHeaderSize + entryDataSize(buf)
-		} else {
-			c.index -= 1
-			if c.index < 0 {
-				return
-			}
-			c.off = c.fieldIndices[c.index]
+	} else {
+		for c.i >= 0 && bytes.Compare(c.blk.key(c.i), seek) == 1 {
+			c.i--
 		}
 	}
 }
 
 // Next returns the next key/value pair from the cursor.
 func (c *Cursor) Next() (key, value []byte) {
-	// Ignore if there is no buffer.
-	if len(c.buf) == 0 {
+	// Ignore if there is no block.
+	if c.blk == nil {
 		return nil, nil
 	}
 
+	// Move to the next entry, reading the next block once the current one
+	// has no entries left.
 	if c.direction.Forward() {
-		// Move forward to next entry.
-		c.off += entryHeaderSize + entryDataSize(c.buf[c.off:])
+		if c.i++; c.i >= c.blk.len() {
+			_, v := c.cursor.Next()
+			c.setBlock(v)
+		}
 	} else {
-		// If we've move past the beginning of buf, grab the previous block
-		if c.index < 0 {
+		if c.i--; c.i < 0 {
 			_, v := c.cursor.Prev()
-			c.setBuf(v)
+			c.setBlock(v)
 		}
-
-		if len(c.fieldIndices) > 0 {
-			c.off = c.fieldIndices[c.index]
-		}
-		c.index -= 1
-	}
-
-	// If no items left then read first item from next block.
-	if c.off >= len(c.buf) {
-		_, v := c.cursor.Next()
-		c.setBuf(v)
 	}
 
 	return c.read()
 }
 
-// setBuf saves a compressed block to the buffer.
-func (c *Cursor) setBuf(block []byte) {
+// setBlock decodes a block and moves to its first entry, or its last entry for
+// a reverse cursor.
+func (c *Cursor) setBlock(v []byte) {
 	// Clear if the block is empty.
-	if len(block) == 0 {
-		c.buf, c.off, c.fieldIndices, c.index = c.buf[0:0], 0, c.fieldIndices[0:0], 0
+	c.blk, c.i = nil, 0
+	if len(v) == 0 {
 		return
 	}
 
-	// Otherwise decode block into buffer.
-	// Skip over the first 8 bytes since they are the max timestamp.
-	buf, err := snappy.Decode(nil, block[8:])
+	blk, err := decodeBlock(v, c.codec)
 	if err != nil {
-		c.buf = c.buf[0:0]
 		log.Printf("block decode error: %s", err)
+		return
 	}
 
-	if c.direction.Forward() {
-		c.buf, c.off = buf, 0
-	} else {
-		c.buf, c.off = buf, 0
-
-		// Buf contains multiple fields packed into a byte slice with timestamp
-		// and data lengths.  We need to build an index into this byte slice that
-		// tells us where each field block is in buf so we can iterate backward without
-		// rescanning the buf each time Next is called.  Forward iteration does not
-		// need this because we know the entries lenghth and the header size so we can
-		// skip forward that many bytes.
-		c.fieldIndices = []int{}
-		for {
-			if c.off >= len(buf) {
-				break
-			}
-
-			c.fieldIndices = append(c.fieldIndices, c.off)
-			c.off += entryHeaderSize + entryDataSize(buf[c.off:])
-		}
-
-		c.off = c.fieldIndices[len(c.fieldIndices)-1]
-		c.index = len(c.fieldIndices) - 1
+	c.blk = blk
+	if c.direction.Reverse() {
+		c.i = blk.len() - 1
 	}
 }
 
 // read reads the current key and value from the current block.
 func (c *Cursor) read() (key, value []byte) {
-	// Return nil if the offset is at the end of the buffer.
-	if c.off >= len(c.buf) {
+	// Return nil if the cursor is outside the block.
+	if c.blk == nil || c.i < 0 || c.i >= c.blk.len() {
+		return nil, nil
+	}
+
+	// Decode the values on first read.
+	if err := c.blk.decodeValues(); err != nil {
+		log.Printf("block decode error: %s", err)
+		c.blk = nil
 		return nil, nil
 	}
+	return c.blk.key(c.i), c.blk.values[c.i]
+}
+
+// block is a decoded block. The timestamps are decoded with the block and the
+// values on first use, so seeking through a block or counting its entries
+// doesn't decode its values.
+type block struct {
+	keys    []byte   // 8-byte timestamps of the entries
+	values  [][]byte // nil until decoded
+	data    []byte   // encoded values
+	version byte
+}
+
+// len returns the number of entries in the block.
+func (b *block) len() int { return len(b.keys) / 8 }
 
-	// Otherwise read the current entry.
-	buf := c.buf[c.off:]
-	dataSize := entryDataSize(buf)
-	return buf[0:8], buf[entryHeaderSize : entryHeaderSize+dataSize]
+// key returns the timestamp of the entry at i as a key.
+func (b *block) key(i int) []byte { return b.keys[i*8 : i*8+8] }
+
+// decodeValues decodes the values of the block, if not decoded yet.
+func (b *block) decodeValues() error {
+	if b.values != nil || b.len() == 0 {
+		return nil
+	}
+
+	// Version 3 and later store the values of the fields in columns.
+	if b.version >= 3 {
+		values, err := decodeColumns(b.data, b.len())
+		if err != nil {
+			return err
+		}
+		b.values = values
+		return nil
+	}
+
+	// Version 2 dictionary encodes the values, so repeated values share
+	// their bytes.
+	if b.version >= 2 {
+		values, err := codec.DecodeDictionary(b.data)
+		if err != nil {
+			return err
+		} else if len(values) != b.len() {
+			return fmt.Errorf("value count mismatch: %d, expected %d", len(values), b.len())
+		}
+		b.values = values
+		return nil
+	}
+
+	data, err := snappy.Decode(nil, b.data)
+	if err != nil {
+		return err
+	}
+	values := make([][]byte, 0, b.len())
+	for len(values) < b.len() {
+		if len(data) < 4 || len(data)-4 < int(binary.BigEndian.Uint32(data[0:4])) {
+			return errors.New("data too short")
+		}
+		dataSize := 4 + int(binary.BigEndian.Uint32(data[0:4]))
+		values = append(values, data[4:dataSize])
+		data = data[dataSize:]
+	}
+	if len(data) != 0 {
+		return errors.New("unexpected trailing data")
+	}
+	b.values = values
+	return nil
+}
+
+// encodeBlock encodes the entries of a block with a codec version.
+//
+// Blocks of version zero are in the following format:
+//
+//     int64  tmax
+//     []byte entries (snappy compressed)
+//
+// Later versions store the timestamps and the values apart, so the timestamps
+// are encoded with the timestamp codec:
+//
+//     int64  tmax
+//     uvarint timestamps length
+//     []byte timestamps (codec encoded)
+//     []byte values
+//
+// Version 1 values are the entries' data lengths and data, snappy compressed.
+// Version 2 values are dictionary encoded. Version 3 values are split into
+// columns of the fields, see encodeColumns.
+//
+func encodeBlock(tmax int64, entries []byte, version byte, types fieldTypes) ([]byte, error) {
+	value := u64tob(uint64(tmax))
+	if version == 0 {
+		return append(value, snappy.Encode(nil, entries)...), nil
+	}
+
+	var timestamps []int64
+	var values [][]byte
+	for off := 0; off < len(entries); off += entryHeaderSize + entryDataSize(entries[off:]) {
+		timestamps = append(timestamps, int64(btou64(entries[off:off+8])))
+		values = append(values, entries[off+entryHeaderSize:off+entryHeaderSize+entryDataSize(entries[off:])])
+	}
+
+	ts, err := codec.Encode(nil, codec.Timestamp, version, timestamps)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf [binary.MaxVarintLen64]byte
+	value = append(value, buf[:binary.PutUvarint(buf[:], uint64(len(ts)))]...)
+	value = append(value, ts...)
+	if version >= 3 {
+		return encodeColumns(value, values, types, version)
+	} else if version >= 2 {
+		return codec.EncodeDictionary(value, values), nil
+	}
+
+	data := make([]byte, 0, len(entries))
+	for _, v := range values {
+		binary.BigEndian.PutUint32(buf[:4], uint32(len(v)))
+		data = append(data, buf[:4]...)
+		data = append(data, v...)
+	}
+	return append(value, snappy.Encode(nil, data)...), nil
+}
+
+// decodeBlock decodes a block encoded by encodeBlock. The values of blocks of
+// version zero are decoded right away since they're compressed together with
+// the timestamps.
+func decodeBlock(v []byte, version byte) (*block, error) {
+	if version == 0 {
+		buf, err := snappy.Decode(nil, v[8:])
+		if err != nil {
+			return nil, err
+		}
+
+		b := &block{values: [][]byte{}}
+		for off := 0; off < len(buf); {
+			if len(buf)-off < entryHeaderSize {
+				return nil, fmt.Errorf("truncated entry header at offset %d", off)
+			}
+			n := entryHeaderSize + entryDataSize(buf[off:])
+			if off+n > len(buf) {
+				return nil, fmt.Errorf("truncated entry at offset %d", off)
+			}
+			b.keys = append(b.keys, buf[off:off+8]...)
+			b.values = append(b.values, buf[off+entryHeaderSize:off+n])
+			off += n
+		}
+		return b, nil
+	}
+
+	v = v[8:]
+	n, sz := binary.Uvarint(v)
+	if sz <= 0 || n > uint64(len(v)-sz) {
+		return nil, errors.New("timestamps too short")
+	}
+	typ, values, err := codec.Decode(v[sz : sz+int(n)])
+	if err != nil {
+		return nil, err
+	} else if typ != codec.Timestamp {
+		return nil, fmt.Errorf("unexpected %s timestamps", typ)
+	}
+	timestamps := values.([]int64)
+
+	b := &block{
+		keys:    make([]byte, 0, len(timestamps)*8),
+		data:    v[sz+int(n):],
+		version: version,
+	}
+	for _, timestamp := range timestamps {
+		b.keys = append(b.keys, u64tob(uint64(timestamp))...)
+	}
+	return b, nil
 }
 
 // MarshalEntry encodes point data into a single byte slice.
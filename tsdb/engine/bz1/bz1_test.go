@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"errors"
+	"expvar"
 	"fmt"
 	"io/ioutil"
 	"math"
@@ -17,9 +18,12 @@ import (
 	"testing/quick"
 	"time"
 
+	"github.com/boltdb/bolt"
 	"github.com/influxdb/influxdb/influxql"
+	"github.com/influxdb/influxdb/toml"
 	"github.com/influxdb/influxdb/tsdb"
 	"github.com/influxdb/influxdb/tsdb/engine/bz1"
+	"github.com/influxdb/influxdb/tsdb/engine/codec"
 	"github.com/influxdb/influxdb/tsdb/engine/wal"
 )
 
@@ -73,6 +77,39 @@ func TestEngine_LoadMetadataIndex_Series(t *testing.T) {
 	}
 }
 
+// Ensure the series filter rules out series without points in the engine.
+func TestEngine_MayContainSeries(t *testing.T) {
+	e := OpenDefaultEngine()
+	defer e.Close()
+
+	// Series are assumed to exist until the metadata index is loaded.
+	if !e.MayContainSeries("cpu,host=server0") {
+		t.Fatal("expected series before load")
+	}
+
+	// Write series metadata and load it.
+	seriesToCreate := []*tsdb.SeriesCreate{{Series: tsdb.NewSeries("cpu,host=server0", map[string]string{"host": "server0"})}}
+	e.PointsWriter.WritePointsFn = func(a []tsdb.Point) error { return nil }
+	if err := e.WriteIndex(nil, nil, seriesToCreate); err != nil {
+		t.Fatal(err)
+	} else if err := e.LoadMetadataIndex(tsdb.NewDatabaseIndex(), make(map[string]*tsdb.MeasurementFields)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Write a series to the WAL.
+	if err := e.WritePoints(nil, nil, []*tsdb.SeriesCreate{{Series: tsdb.NewSeries("cpu,host=server1", map[string]string{"host": "server1"})}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !e.MayContainSeries("cpu,host=server0") {
+		t.Fatal("expected loaded series")
+	} else if !e.MayContainSeries("cpu,host=server1") {
+		t.Fatal("expected written series")
+	} else if e.MayContainSeries("cpu,host=server2") {
+		t.Fatal("unexpected series")
+	}
+}
+
 // Ensure the engine can write field metadata and reload it.
 func TestEngine_LoadMetadataIndex_Fields(t *testing.T) {
 	e := OpenDefaultEngine()
@@ -107,6 +144,42 @@ func TestEngine_LoadMetadataIndex_Fields(t *testing.T) {
 	}
 }
 
+// Ensure the measurements of corrupt field metadata are rebuilt from the series.
+func TestEngine_LoadMetadataIndex_CorruptFields(t *testing.T) {
+	e := OpenDefaultEngine()
+	defer e.Close()
+
+	fields := map[string]*tsdb.MeasurementFields{
+		"cpu": &tsdb.MeasurementFields{Fields: map[string]*tsdb.Field{"value": &tsdb.Field{ID: 1, Name: "value", Type: influxql.Float}}},
+	}
+	seriesToCreate := []*tsdb.SeriesCreate{{Series: tsdb.NewSeries("cpu,host=server0", map[string]string{"host": "server0"})}}
+	if err := e.WriteIndex(nil, fields, seriesToCreate); err != nil {
+		t.Fatal(err)
+	}
+
+	// Corrupt the field metadata.
+	tx := e.MustBegin(true).(*bz1.Tx)
+	if err := tx.Bucket([]byte("meta")).Put([]byte("fields"), []byte("garbage")); err != nil {
+		t.Fatal(err)
+	} else if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		mfs := make(map[string]*tsdb.MeasurementFields)
+		if err := e.LoadMetadataIndex(tsdb.NewDatabaseIndex(), mfs); err != nil {
+			t.Fatal(err)
+		} else if mf := mfs["cpu"]; mf == nil || len(mf.Fields) != 0 {
+			t.Fatalf("unexpected fields: %#v", mf)
+		}
+	}
+
+	// New fields can be written again.
+	if err := e.WriteIndex(nil, fields, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
 // Ensure the engine can write points to storage.
 func TestEngine_WritePoints_PointsWriter(t *testing.T) {
 	e := OpenDefaultEngine()
@@ -194,6 +267,339 @@ func TestEngine_WriteIndex_Append(t *testing.T) {
 	}
 }
 
+// Ensure the engine can iterate over and verify its blocks.
+func TestEngine_InspectBlocks(t *testing.T) {
+	e := OpenDefaultEngine()
+	defer e.Close()
+
+	if err := e.WriteIndex(map[string][][]byte{
+		"cpu": [][]byte{
+			append(u64tob(1), 0x10),
+			append(u64tob(2), 0x20),
+		},
+		"mem": [][]byte{
+			append(u64tob(5), 0x30),
+		},
+	}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var a []tsdb.BlockInfo
+	if err := e.InspectBlocks(func(b tsdb.BlockInfo) error {
+		a = append(a, b)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(a) != 2 {
+		t.Fatalf("unexpected block count: %d", len(a))
+	} else if b := a[0]; b.Key != "cpu" || b.MinTime != 1 || b.MaxTime != 2 || b.Points != 2 || b.RawSize != 26 || b.Err != nil {
+		t.Fatalf("unexpected block: %+v", b)
+	} else if b := a[1]; b.Key != "mem" || b.MinTime != 5 || b.MaxTime != 5 || b.Points != 1 || b.Err != nil {
+		t.Fatalf("unexpected block: %+v", b)
+	}
+}
+
+// Ensure a full compaction rewrites series into full blocks.
+func TestEngine_CompactFull(t *testing.T) {
+	e := OpenDefaultEngine()
+	defer e.Close()
+	e.BlockSize = 39 // 3 entries
+
+	// Append each point separately so each is in its own block.
+	for i := 1; i <= 3; i++ {
+		if err := e.WriteIndex(map[string][][]byte{
+			"cpu": [][]byte{append(u64tob(uint64(i)), byte(i))},
+		}, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := e.WriteIndex(map[string][][]byte{
+		"mem": [][]byte{append(u64tob(5), 0x30)},
+	}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.CompactFull(); err != nil {
+		t.Fatal(err)
+	}
+
+	var a []tsdb.BlockInfo
+	if err := e.InspectBlocks(func(b tsdb.BlockInfo) error {
+		a = append(a, b)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(a) != 2 {
+		t.Fatalf("unexpected block count: %d", len(a))
+	} else if b := a[0]; b.Key != "cpu" || b.MinTime != 1 || b.MaxTime != 3 || b.Points != 3 || b.Err != nil {
+		t.Fatalf("unexpected block: %+v", b)
+	} else if b := a[1]; b.Key != "mem" || b.Points != 1 || b.Err != nil {
+		t.Fatalf("unexpected block: %+v", b)
+	}
+
+	// Only the series with partial blocks is rewritten.
+	if v := e.Statistics().Get("compact_full_series"); v == nil || v.String() != "1" {
+		t.Fatalf("unexpected compacted series: %v", v)
+	}
+
+	// Iterate over "cpu" series.
+	tx := e.MustBegin(false)
+	defer tx.Rollback()
+	c := tx.Cursor("cpu", tsdb.Forward)
+	if k, v := c.Seek(u64tob(0)); !reflect.DeepEqual(k, u64tob(1)) || !reflect.DeepEqual(v, []byte{1}) {
+		t.Fatalf("unexpected key/value: %x / %x", k, v)
+	} else if k, v = c.Next(); !reflect.DeepEqual(k, u64tob(2)) || !reflect.DeepEqual(v, []byte{2}) {
+		t.Fatalf("unexpected key/value: %x / %x", k, v)
+	} else if k, v = c.Next(); !reflect.DeepEqual(k, u64tob(3)) || !reflect.DeepEqual(v, []byte{3}) {
+		t.Fatalf("unexpected key/value: %x / %x", k, v)
+	} else if k, _ = c.Next(); k != nil {
+		t.Fatalf("unexpected key/value: %x / %x", k, v)
+	}
+}
+
+// Ensure new files use the latest codecs and files without a codec version
+// keep the original block format.
+func TestEngine_CodecVersion(t *testing.T) {
+	e := OpenDefaultEngine()
+	defer e.Close()
+	if v := e.CodecVersion(); v != codec.Version {
+		t.Fatalf("unexpected codec version: %d", v)
+	}
+
+	// Remove the codec version as files created before codecs lack it.
+	e.Engine.Close()
+	db, err := bolt.Open(e.Path(), 0666, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("meta")).Delete([]byte("codec"))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	if err := e.Open(); err != nil {
+		t.Fatal(err)
+	} else if v := e.CodecVersion(); v != 0 {
+		t.Fatalf("unexpected codec version: %d", v)
+	}
+
+	// Write and read back points in the original format.
+	if err := e.WriteIndex(map[string][][]byte{
+		"cpu": [][]byte{
+			append(u64tob(1), 0x10),
+			append(u64tob(2), 0x20),
+		},
+	}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tx := e.MustBegin(false)
+	defer tx.Rollback()
+	c := tx.Cursor("cpu", tsdb.Forward)
+	if k, v := c.Seek(u64tob(0)); !reflect.DeepEqual(k, u64tob(1)) || !reflect.DeepEqual(v, []byte{0x10}) {
+		t.Fatalf("unexpected key/value: %x / %x", k, v)
+	} else if k, v = c.Next(); !reflect.DeepEqual(k, u64tob(2)) || !reflect.DeepEqual(v, []byte{0x20}) {
+		t.Fatalf("unexpected key/value: %x / %x", k, v)
+	}
+}
+
+// Ensure blocks of low cardinality values are dictionary encoded and smaller
+// than blocks of the previous codec version.
+func TestEngine_WriteIndex_Dictionary(t *testing.T) {
+	levels := [][]byte{[]byte("info: request served"), []byte("warn: slow request"), []byte("error: request failed")}
+	var points [][]byte
+	for i := 0; i < 100; i++ {
+		points = append(points, append(u64tob(uint64(i)), levels[i%7%3]...))
+	}
+
+	size := func(version byte) (n int) {
+		e := OpenDefaultEngine()
+		defer e.Close()
+
+		// Switch the file to the codec version before writing.
+		e.Engine.Close()
+		db, err := bolt.Open(e.Path(), 0666, nil)
+		if err != nil {
+			t.Fatal(err)
+		} else if err := db.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket([]byte("meta")).Put([]byte("codec"), []byte{version})
+		}); err != nil {
+			t.Fatal(err)
+		}
+		db.Close()
+		if err := e.Open(); err != nil {
+			t.Fatal(err)
+		}
+
+		a := make([][]byte, len(points))
+		for i := range points {
+			a[i] = copyBytes(points[i])
+		}
+		if err := e.WriteIndex(map[string][][]byte{"cpu": a}, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		// Read the points back in both directions.
+		tx := e.MustBegin(false)
+		defer tx.Rollback()
+		c := tx.Cursor("cpu", tsdb.Forward)
+		i := 0
+		for k, v := c.Seek(u64tob(0)); k != nil; k, v = c.Next() {
+			if !bytes.Equal(k, points[i][0:8]) || !bytes.Equal(v, points[i][8:]) {
+				t.Fatalf("%d. unexpected key/value: %x / %s", i, k, v)
+			}
+			i++
+		}
+		if i != len(points) {
+			t.Fatalf("unexpected point count: %d", i)
+		}
+
+		c = tx.Cursor("cpu", tsdb.Reverse)
+		for k, v := c.Seek(u64tob(math.MaxUint64)); k != nil; k, v = c.Next() {
+			i--
+			if !bytes.Equal(k, points[i][0:8]) || !bytes.Equal(v, points[i][8:]) {
+				t.Fatalf("%d. unexpected key/value: %x / %s", i, k, v)
+			}
+		}
+		if i != 0 {
+			t.Fatalf("unexpected reverse point count: %d", len(points)-i)
+		}
+
+		if err := e.InspectBlocks(func(b tsdb.BlockInfo) error {
+			if b.Err != nil {
+				t.Fatalf("unexpected block error: %s", b.Err)
+			}
+			n += b.Size
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	if v1, v2 := size(1), size(2); v2 >= v1 {
+		t.Fatalf("unexpected size: %d, v1=%d", v2, v1)
+	}
+}
+
+// Ensure the values of fields are stored in columns by type and read back.
+func TestEngine_WriteIndex_Columns(t *testing.T) {
+	e := OpenDefaultEngine()
+	defer e.Close()
+
+	mf := &tsdb.MeasurementFields{Fields: map[string]*tsdb.Field{
+		"value":  &tsdb.Field{ID: 1, Name: "value", Type: influxql.Float},
+		"count":  &tsdb.Field{ID: 2, Name: "count", Type: influxql.Integer},
+		"ok":     &tsdb.Field{ID: 3, Name: "ok", Type: influxql.Boolean},
+		"status": &tsdb.Field{ID: 4, Name: "status", Type: influxql.String},
+	}}
+	c := tsdb.NewFieldCodec(mf.Fields)
+
+	var fields []tsdb.Fields
+	var points [][]byte
+	for i := 0; i < 100; i++ {
+		f := tsdb.Fields{"value": float64(i) / 2, "count": int64(i * 3)}
+		if i%3 == 0 {
+			f["ok"] = i%2 == 0
+		}
+		if i%5 == 0 {
+			f["status"] = "ok"
+			delete(f, "value")
+		}
+		fields = append(fields, f)
+		points = append(points, append(u64tob(uint64(i)), MustEncodeFields(c, f)...))
+	}
+	if err := e.WriteIndex(map[string][][]byte{"cpu": points}, map[string]*tsdb.MeasurementFields{"cpu": mf}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tx := e.MustBegin(false)
+	defer tx.Rollback()
+	cur := tx.Cursor("cpu", tsdb.Forward)
+	i := 0
+	for k, v := cur.Seek(u64tob(0)); k != nil; k, v = cur.Next() {
+		if got, err := c.DecodeFieldsWithNames(v); err != nil {
+			t.Fatal(err)
+		} else if btou64(k) != uint64(i) || !reflect.DeepEqual(got, map[string]interface{}(fields[i])) {
+			t.Fatalf("%d. unexpected key/fields: %d / %v", i, btou64(k), got)
+		}
+		i++
+	}
+	if i != len(points) {
+		t.Fatalf("unexpected point count: %d", i)
+	}
+}
+
+// Ensure the values of low cardinality string fields are dictionary encoded
+// apart from the other fields.
+func TestEngine_WriteIndex_StringColumns(t *testing.T) {
+	mf := &tsdb.MeasurementFields{Fields: map[string]*tsdb.Field{
+		"value":  &tsdb.Field{ID: 1, Name: "value", Type: influxql.Float},
+		"status": &tsdb.Field{ID: 2, Name: "status", Type: influxql.String},
+	}}
+	c := tsdb.NewFieldCodec(mf.Fields)
+	levels := []string{"info: request served", "warn: slow request", "error: request failed"}
+
+	size := func(version byte) (n int) {
+		e := OpenDefaultEngine()
+		defer e.Close()
+
+		// Switch the file to the codec version before writing.
+		e.Engine.Close()
+		db, err := bolt.Open(e.Path(), 0666, nil)
+		if err != nil {
+			t.Fatal(err)
+		} else if err := db.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket([]byte("meta")).Put([]byte("codec"), []byte{version})
+		}); err != nil {
+			t.Fatal(err)
+		}
+		db.Close()
+		if err := e.Open(); err != nil {
+			t.Fatal(err)
+		}
+
+		// Every point is distinct, but its status is one of a few.
+		var points [][]byte
+		for i := 0; i < 100; i++ {
+			f := tsdb.Fields{"value": float64(i), "status": levels[i%7%3]}
+			points = append(points, append(u64tob(uint64(i)), MustEncodeFields(c, f)...))
+		}
+		if err := e.WriteIndex(map[string][][]byte{"cpu": points}, map[string]*tsdb.MeasurementFields{"cpu": mf}, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		tx := e.MustBegin(false)
+		defer tx.Rollback()
+		cur := tx.Cursor("cpu", tsdb.Forward)
+		i := 0
+		for k, v := cur.Seek(u64tob(0)); k != nil; k, v = cur.Next() {
+			if got, err := c.DecodeFieldsWithNames(v); err != nil {
+				t.Fatal(err)
+			} else if got["status"] != levels[i%7%3] {
+				t.Fatalf("%d. unexpected fields: %v", i, got)
+			}
+			i++
+		}
+
+		if err := e.InspectBlocks(func(b tsdb.BlockInfo) error {
+			n += b.Size
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	if v2, v3 := size(2), size(3); v3 >= v2 {
+		t.Fatalf("unexpected size: %d, v2=%d", v3, v2)
+	}
+}
+
 // Ensure the engine can rewrite blocks that contain the new point range.
 func TestEngine_WriteIndex_Insert(t *testing.T) {
 	e := OpenDefaultEngine()
@@ -305,7 +711,11 @@ func TestEngine_Cursor_Reverse(t *testing.T) {
 		t.Fatalf("unexpected key/value: %x / %x", k, v)
 	} else if k, v = c.Next(); btou64(k) != 10 || !bytes.Equal(v, []byte{0xFF}) {
 		t.Fatalf("unexpected key/value: %x / %x", k, v)
-	} else if k, v = c.Seek(u64tob(0)); btou64(k) != 9 || !bytes.Equal(v, []byte{0x09}) {
+	} else if k, v = c.Seek(u64tob(9)); btou64(k) != 9 || !bytes.Equal(v, []byte{0x09}) {
+		t.Fatalf("unexpected key/value: %x / %x", k, v)
+	} else if k, v = c.Seek(u64tob(24)); btou64(k) != 20 || !bytes.Equal(v, []byte{0x20}) {
+		t.Fatalf("unexpected key/value: %x / %x", k, v)
+	} else if k, v = c.Seek(u64tob(0)); k != nil {
 		t.Fatalf("unexpected key/value: %x / %x", k, v)
 	}
 }
@@ -345,6 +755,156 @@ func TestEngine_WriteIndex_SeekAgainstInBlockValue(t *testing.T) {
 	}
 }
 
+// Ensure transactions can pass access hints on while reading points.
+func TestTx_Advise(t *testing.T) {
+	e := OpenDefaultEngine()
+	defer e.Close()
+	e.MmapHintsEnabled = true
+
+	if err := e.WriteIndex(map[string][][]byte{
+		"cpu": [][]byte{append(u64tob(1), 0x10)},
+	}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tx := e.MustBegin(false)
+	defer tx.Rollback()
+	for _, p := range []tsdb.AccessPattern{tsdb.SequentialAccess, tsdb.RandomAccess, tsdb.NormalAccess} {
+		if err := tx.(tsdb.AccessAdvisor).Advise(p); err != nil {
+			t.Fatalf("advise %d: %s", p, err)
+		}
+	}
+
+	if k, v := tx.Cursor("cpu", tsdb.Forward).Seek(u64tob(0)); btou64(k) != 1 || !bytes.Equal(v, []byte{0x10}) {
+		t.Fatalf("unexpected key/value: %x / %x", k, v)
+	}
+
+	// Closing the transaction resets the hint.
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Ensure the engine pre-warms the series written to it in the background, if
+// enabled.
+func TestEngine_Prewarm(t *testing.T) {
+	e := OpenDefaultEngine()
+	defer e.Close()
+	if e.PrewarmEnabled {
+		t.Fatal("expected pre-warm to be disabled by default")
+	}
+	e.PrewarmEnabled = true
+
+	if err := e.WriteIndex(map[string][][]byte{
+		"cpu": [][]byte{append(u64tob(1), 0x10)},
+		"mem": [][]byte{append(u64tob(2), 0x20)},
+	}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Wait for the pre-warm to finish.
+	for i := 0; ; i++ {
+		if values := e.Statistics(); values.Get("prewarm") != nil {
+			if v := values.Get("prewarm_bytes").String(); v == "0" {
+				t.Fatalf("unexpected pre-warm bytes: %s", v)
+			}
+			break
+		} else if i == 100 {
+			t.Fatal("timed out waiting for pre-warm")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Ensure the engine drops flushed pages from the page cache if enabled, and
+// doesn't pre-warm them.
+func TestEngine_FlushBypassCache(t *testing.T) {
+	e := OpenDefaultEngine()
+	defer e.Close()
+	e.FlushBypassCache = true
+	e.PrewarmEnabled = true
+
+	if err := e.WriteIndex(map[string][][]byte{
+		"cpu": [][]byte{append(u64tob(1), 0x10)},
+	}, nil, nil); err != nil {
+		t.Fatal(err)
+	} else if v := e.Statistics().Get("cache_drop"); v == nil || v.String() != "1" {
+		t.Fatalf("unexpected cache drops: %v", v)
+	} else if v := e.Statistics().Get("prewarm"); v != nil {
+		t.Fatalf("unexpected pre-warms: %v", v)
+	}
+
+	// Dropped pages are read back from disk.
+	tx := e.MustBegin(false)
+	defer tx.Rollback()
+	if k, v := tx.Cursor("cpu", tsdb.Forward).Seek(u64tob(0)); btou64(k) != 1 || !bytes.Equal(v, []byte{0x10}) {
+		t.Fatalf("unexpected key/value: %x / %x", k, v)
+	}
+}
+
+// Ensure dropped series are hidden right away and their points are deleted in
+// the background, one batch at a time.
+func TestEngine_DeleteMeasurement(t *testing.T) {
+	opt := tsdb.NewEngineOptions()
+	opt.Config.DropBatchSize = 1
+	opt.Config.DropBatchDelay = toml.Duration(time.Hour)
+	e := OpenEngine(opt)
+	defer e.Close()
+
+	if err := e.WriteIndex(map[string][][]byte{
+		"cpu,host=A": [][]byte{append(u64tob(1), 0x10)},
+		"cpu,host=B": [][]byte{append(u64tob(1), 0x20)},
+		"mem":        [][]byte{append(u64tob(1), 0x30)},
+	}, nil, nil); err != nil {
+		t.Fatal(err)
+	} else if err := e.DeleteMeasurement("cpu", []string{"cpu,host=A", "cpu,host=B"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Dropped series are gone before their points are deleted.
+	tx := e.MustBegin(false)
+	if k, _ := tx.Cursor("cpu,host=B", tsdb.Forward).Seek(u64tob(0)); k != nil {
+		t.Fatalf("unexpected key: %x", k)
+	}
+	tx.Rollback()
+	if n, err := e.SeriesCount(); err != nil {
+		t.Fatal(err)
+	} else if n != 1 {
+		t.Fatalf("unexpected series count: %d", n)
+	}
+
+	// The first batch is deleted, the second waits on the delay.
+	for i := 0; e.Statistics().Get("drop_series") == nil; i++ {
+		if i == 100 {
+			t.Fatal("dropped series not deleted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if v := e.Statistics().Get("drop_pending"); v.String() != "1" {
+		t.Fatalf("unexpected pending drops: %s", v)
+	} else if stats, _ := e.SeriesBucketStats("cpu,host=A"); stats.KeyN != 0 {
+		t.Fatalf("unexpected blocks: %d", stats.KeyN)
+	}
+
+	// Writing to a series waiting to be deleted replaces its points.
+	if err := e.WriteIndex(map[string][][]byte{
+		"cpu,host=B": [][]byte{append(u64tob(2), 0x40)},
+	}, nil, nil); err != nil {
+		t.Fatal(err)
+	} else if v := e.Statistics().Get("drop_pending"); v.String() != "0" {
+		t.Fatalf("unexpected pending drops: %s", v)
+	}
+
+	tx = e.MustBegin(false)
+	defer tx.Rollback()
+	c := tx.Cursor("cpu,host=B", tsdb.Forward)
+	if k, v := c.Seek(u64tob(0)); btou64(k) != 2 || !bytes.Equal(v, []byte{0x40}) {
+		t.Fatalf("unexpected key/value: %x / %x", k, v)
+	} else if k, _ := c.Next(); k != nil {
+		t.Fatalf("unexpected key: %x", k)
+	}
+}
+
 // Ensure the engine ignores writes without keys.
 func TestEngine_WriteIndex_NoKeys(t *testing.T) {
 	e := OpenDefaultEngine()
@@ -554,6 +1114,12 @@ func (e *Engine) Close() error {
 	return nil
 }
 
+// Statistics returns the values of the engine's registered statistics.
+func (e *Engine) Statistics() *expvar.Map {
+	m := expvar.Get(fmt.Sprintf("engine:%s:%s", tsdb.DefaultEngine, e.Path())).(*expvar.Map)
+	return m.Get("values").(*expvar.Map)
+}
+
 // MustBegin returns a new tranaction. Panic on error.
 func (e *Engine) MustBegin(writable bool) tsdb.Tx {
 	tx, err := e.Begin(writable)
@@ -0,0 +1,306 @@
+package bz1
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/influxdb/influxdb/influxql"
+	"github.com/influxdb/influxdb/tsdb"
+	"github.com/influxdb/influxdb/tsdb/engine/codec"
+)
+
+// fieldTypes are the types of the fields of a measurement by their IDs.
+type fieldTypes map[uint8]influxql.DataType
+
+// newFieldTypes returns the types of the fields of the measurement of the
+// series key, or nil if the measurement has no fields.
+func newFieldTypes(fields map[string]*tsdb.MeasurementFields, key string) fieldTypes {
+	mf := fields[tsdb.MeasurementFromSeriesKey(key)]
+	if mf == nil || len(mf.Fields) == 0 {
+		return nil
+	}
+	types := make(fieldTypes, len(mf.Fields))
+	for _, f := range mf.Fields {
+		types[f.ID] = f.Type
+	}
+	return types
+}
+
+// columnType returns the codec type the values of fields of typ are stored
+// in, or false if they aren't stored in columns.
+func columnType(typ influxql.DataType) (codec.Type, bool) {
+	switch typ {
+	case influxql.Float:
+		return codec.Float, true
+	case influxql.Integer, influxql.Unsigned:
+		return codec.Integer, true
+	case influxql.Boolean:
+		return codec.Boolean, true
+	case influxql.String:
+		return codec.String, true
+	}
+	return 0, false
+}
+
+// fieldValue is the encoded value of a field of an entry.
+type fieldValue struct {
+	id   uint8
+	data []byte
+}
+
+// splitColumnFields splits the encoded fields of an entry into the values
+// stored in columns and the rest. Returns false if a field of the entry isn't
+// in types, since the sizes of the fields after it aren't known.
+func splitColumnFields(v []byte, types fieldTypes) ([]fieldValue, []byte, bool) {
+	var values []fieldValue
+	var rest []byte
+	for b := v; len(b) > 0; {
+		typ, ok := types[b[0]]
+		if !ok {
+			return nil, nil, false
+		}
+
+		var n int
+		switch typ {
+		case influxql.Float, influxql.Integer, influxql.Unsigned:
+			n = 9
+		case influxql.Boolean:
+			n = 2
+		case influxql.String:
+			if len(b) < 3 {
+				return nil, nil, false
+			}
+			n = 3 + int(binary.BigEndian.Uint16(b[1:3]))
+		default:
+			return nil, nil, false
+		}
+		if len(b) < n {
+			return nil, nil, false
+		}
+
+		if _, ok := columnType(typ); ok {
+			values = append(values, fieldValue{id: b[0], data: b[1:n]})
+		} else {
+			rest = append(rest, b[:n]...)
+		}
+		b = b[n:]
+	}
+	return values, rest, true
+}
+
+// encodeColumns appends the encoding of the encoded fields of the entries of a
+// block to dst. The values of the fields are encoded with the codec of their
+// type, field by field, so strings are dictionary encoded apart from the other
+// fields. The entries with fields not in types are dictionary encoded
+// together.
+//
+// The encoding is the number of columns as a uvarint followed by each column
+// and then the entries not split into columns:
+//
+//     uint8   field ID
+//     uvarint presence length
+//     []byte  presence of the field in each entry (boolean codec)
+//     uvarint values length
+//     []byte  values of the entries with the field (codec of the field type)
+//
+func encodeColumns(dst []byte, entries [][]byte, types fieldTypes, version byte) ([]byte, error) {
+	fields := make([][]fieldValue, len(entries))
+	rest := make([][]byte, len(entries))
+	ids := make(map[uint8]codec.Type)
+	for i, v := range entries {
+		values, r, ok := splitColumnFields(v, types)
+		if !ok {
+			rest[i] = v
+			continue
+		}
+		fields[i], rest[i] = values, r
+		for _, f := range values {
+			ids[f.id], _ = columnType(types[f.id])
+		}
+	}
+
+	sorted := make([]int, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, int(id))
+	}
+	sort.Ints(sorted)
+
+	var buf [binary.MaxVarintLen64]byte
+	dst = append(dst, buf[:binary.PutUvarint(buf[:], uint64(len(sorted)))]...)
+	for _, id := range sorted {
+		typ := ids[uint8(id)]
+		present := make([]bool, len(entries))
+		var floats []float64
+		var integers []int64
+		var booleans []bool
+		var strs []string
+		for i, values := range fields {
+			for _, f := range values {
+				if f.id != uint8(id) {
+					continue
+				}
+				present[i] = true
+				switch typ {
+				case codec.Float:
+					floats = append(floats, math.Float64frombits(binary.BigEndian.Uint64(f.data)))
+				case codec.Integer:
+					integers = append(integers, int64(binary.BigEndian.Uint64(f.data)))
+				case codec.Boolean:
+					booleans = append(booleans, f.data[0] != 0)
+				case codec.String:
+					strs = append(strs, string(f.data[2:]))
+				}
+			}
+		}
+
+		var values interface{}
+		switch typ {
+		case codec.Float:
+			values = floats
+		case codec.Integer:
+			values = integers
+		case codec.Boolean:
+			values = booleans
+		case codec.String:
+			values = strs
+		}
+
+		p, err := codec.Encode(nil, codec.Boolean, version, present)
+		if err != nil {
+			return nil, err
+		}
+		v, err := codec.Encode(nil, typ, version, values)
+		if err != nil {
+			return nil, err
+		}
+
+		dst = append(dst, uint8(id))
+		dst = append(dst, buf[:binary.PutUvarint(buf[:], uint64(len(p)))]...)
+		dst = append(dst, p...)
+		dst = append(dst, buf[:binary.PutUvarint(buf[:], uint64(len(v)))]...)
+		dst = append(dst, v...)
+	}
+	return codec.EncodeDictionary(dst, rest), nil
+}
+
+// decodeColumns decodes the encoded fields of the n entries of a block encoded
+// by encodeColumns.
+func decodeColumns(src []byte, n int) ([][]byte, error) {
+	colN, sz := binary.Uvarint(src)
+	if sz <= 0 {
+		return nil, errors.New("column count too short")
+	}
+	src = src[sz:]
+
+	entries := make([][]byte, n)
+	for c := uint64(0); c < colN; c++ {
+		if len(src) == 0 {
+			return nil, errors.New("column too short")
+		}
+		id := src[0]
+		src = src[1:]
+
+		_, present, err := decodeColumn(&src)
+		if err != nil {
+			return nil, fmt.Errorf("presence of field %d: %s", id, err)
+		}
+		typ, values, err := decodeColumn(&src)
+		if err != nil {
+			return nil, fmt.Errorf("values of field %d: %s", id, err)
+		}
+
+		p, ok := present.([]bool)
+		if !ok || len(p) != n {
+			return nil, fmt.Errorf("presence of field %d: count mismatch", id)
+		}
+		var j int
+		for i := range entries {
+			if !p[i] {
+				continue
+			}
+
+			var v []byte
+			switch typ {
+			case codec.Float:
+				a := values.([]float64)
+				if j >= len(a) {
+					return nil, fmt.Errorf("values of field %d: count mismatch", id)
+				}
+				v = make([]byte, 8)
+				binary.BigEndian.PutUint64(v, math.Float64bits(a[j]))
+			case codec.Integer:
+				a := values.([]int64)
+				if j >= len(a) {
+					return nil, fmt.Errorf("values of field %d: count mismatch", id)
+				}
+				v = make([]byte, 8)
+				binary.BigEndian.PutUint64(v, uint64(a[j]))
+			case codec.Boolean:
+				a := values.([]bool)
+				if j >= len(a) {
+					return nil, fmt.Errorf("values of field %d: count mismatch", id)
+				}
+				v = []byte{0}
+				if a[j] {
+					v[0] = 1
+				}
+			case codec.String:
+				a := values.([]string)
+				if j >= len(a) {
+					return nil, fmt.Errorf("values of field %d: count mismatch", id)
+				}
+				v = make([]byte, 2, 2+len(a[j]))
+				binary.BigEndian.PutUint16(v, uint16(len(a[j])))
+				v = append(v, a[j]...)
+			default:
+				return nil, fmt.Errorf("unexpected %s values of field %d", typ, id)
+			}
+			entries[i] = append(append(entries[i], id), v...)
+			j++
+		}
+		if j != valueCount(values) {
+			return nil, fmt.Errorf("values of field %d: count mismatch", id)
+		}
+	}
+
+	rest, err := codec.DecodeDictionary(src)
+	if err != nil {
+		return nil, err
+	} else if len(rest) != n {
+		return nil, fmt.Errorf("value count mismatch: %d, expected %d", len(rest), n)
+	}
+	for i, r := range rest {
+		entries[i] = append(entries[i], r...)
+	}
+	return entries, nil
+}
+
+// valueCount returns the number of decoded column values.
+func valueCount(values interface{}) int {
+	switch a := values.(type) {
+	case []float64:
+		return len(a)
+	case []int64:
+		return len(a)
+	case []bool:
+		return len(a)
+	case []string:
+		return len(a)
+	}
+	return -1
+}
+
+// decodeColumn decodes a length prefixed, codec encoded column from the start
+// of *src and advances it past the column.
+func decodeColumn(src *[]byte) (codec.Type, interface{}, error) {
+	l, sz := binary.Uvarint(*src)
+	if sz <= 0 || l > uint64(len(*src)-sz) {
+		return 0, nil, errors.New("too short")
+	}
+	b := (*src)[sz : sz+int(l)]
+	*src = (*src)[sz+int(l):]
+	return codec.Decode(b)
+}
@@ -0,0 +1,26 @@
+// +build amd64 arm64
+
+package bz1
+
+import (
+	"os"
+	"syscall"
+)
+
+// fadvDontNeed is POSIX_FADV_DONTNEED.
+const fadvDontNeed = 4
+
+// dropCache asks the kernel to drop the cached pages of a file. Pages mapped by
+// a process, such as those read through the file's memory mapping, are kept.
+func dropCache(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, _, errno := syscall.Syscall6(syscall.SYS_FADVISE64, f.Fd(), 0, 0, fadvDontNeed, 0, 0); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,6 @@
+// +build !linux !amd64,!arm64
+
+package bz1
+
+// dropCache is a no-op on platforms without fadvise.
+func dropCache(path string) error { return nil }
@@ -0,0 +1,24 @@
+package bz1
+
+import (
+	"syscall"
+
+	"github.com/influxdb/influxdb/tsdb"
+)
+
+// madvise advises the kernel how the n bytes of memory mapped at addr are
+// read.
+func madvise(addr uintptr, n int, p tsdb.AccessPattern) error {
+	advice := syscall.MADV_NORMAL
+	switch p {
+	case tsdb.SequentialAccess:
+		advice = syscall.MADV_SEQUENTIAL
+	case tsdb.RandomAccess:
+		advice = syscall.MADV_RANDOM
+	}
+
+	if _, _, errno := syscall.Syscall(syscall.SYS_MADVISE, addr, uintptr(n), uintptr(advice)); errno != 0 {
+		return errno
+	}
+	return nil
+}
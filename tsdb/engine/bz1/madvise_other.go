@@ -0,0 +1,8 @@
+// +build !linux
+
+package bz1
+
+import "github.com/influxdb/influxdb/tsdb"
+
+// madvise is a no-op on platforms without access hints.
+func madvise(addr uintptr, n int, p tsdb.AccessPattern) error { return nil }
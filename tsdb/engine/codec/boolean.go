@@ -0,0 +1,44 @@
+package codec
+
+import "encoding/binary"
+
+// BooleanCodec packs booleans into bits, eight values per byte.
+//
+// The encoding is the number of values as a varint followed by the values,
+// most significant bit first, with the last byte zero padded.
+type BooleanCodec struct{}
+
+// Encode appends the encoding of []bool values to dst.
+func (BooleanCodec) Encode(dst []byte, values interface{}) ([]byte, error) {
+	a, ok := values.([]bool)
+	if !ok {
+		return nil, typeError(Boolean, values)
+	}
+
+	var buf [binary.MaxVarintLen64]byte
+	w := bitWriter{buf: append(dst, buf[:binary.PutUvarint(buf[:], uint64(len(a)))]...)}
+	for _, v := range a {
+		if v {
+			w.write(1, 1)
+		} else {
+			w.write(0, 1)
+		}
+	}
+	return w.bytes(), nil
+}
+
+// Decode decodes booleans encoded by Encode.
+func (BooleanCodec) Decode(src []byte) (interface{}, error) {
+	n, sz := binary.Uvarint(src)
+	if sz <= 0 || n > uint64(len(src)-sz)*8 {
+		return nil, ErrCorruptBlock
+	}
+
+	r := bitReader{buf: src[sz:]}
+	a := make([]bool, n)
+	for i := range a {
+		v, _ := r.read(1)
+		a[i] = v == 1
+	}
+	return a, nil
+}
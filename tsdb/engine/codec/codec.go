@@ -0,0 +1,184 @@
+// Package codec implements the compression codecs of the values stored in
+// engine blocks. Every block type has its own encoding suited to its values:
+// timestamps are delta and run-length encoded, floats are XOR encoded,
+// integers are ZigZag and simple8b encoded, strings are dictionary encoded or
+// snappy compressed and booleans are bit-packed.
+//
+// Codecs are versioned. An encoded block starts with a header naming its type
+// and the codec version it was encoded with, so blocks remain readable after
+// newer codec versions are introduced. Engines should record the version they
+// write with per shard so existing shards keep being written in a version the
+// release that created them can read.
+package codec
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Type is the type of the values of a block.
+type Type byte
+
+const (
+	// Timestamp blocks hold []int64 values of nanosecond timestamps.
+	Timestamp Type = iota + 1
+
+	// Float blocks hold []float64 values.
+	Float
+
+	// Integer blocks hold []int64 values.
+	Integer
+
+	// String blocks hold []string values.
+	String
+
+	// Boolean blocks hold []bool values.
+	Boolean
+)
+
+// String returns the name of the block type.
+func (t Type) String() string {
+	switch t {
+	case Timestamp:
+		return "timestamp"
+	case Float:
+		return "float"
+	case Integer:
+		return "integer"
+	case String:
+		return "string"
+	case Boolean:
+		return "boolean"
+	}
+	return fmt.Sprintf("type(%d)", byte(t))
+}
+
+const (
+	// Version is the latest codec version. Every block type has a codec of
+	// this version.
+	Version = 3
+
+	// MaxVersion is the highest version a codec may be registered with.
+	MaxVersion = 0x0F
+)
+
+var (
+	// ErrShortBlock is returned when decoding a block without a header.
+	ErrShortBlock = errors.New("block too short")
+
+	// ErrCorruptBlock is returned when decoding a block that isn't a valid
+	// encoding of its type.
+	ErrCorruptBlock = errors.New("corrupt block")
+)
+
+// Codec encodes and decodes the values of a block type.
+type Codec interface {
+	// Encode appends the encoding of values to dst and returns the result.
+	// Values must be a slice of the Go type of the block type.
+	Encode(dst []byte, values interface{}) ([]byte, error)
+
+	// Decode decodes an encoding produced by Encode.
+	Decode(src []byte) (interface{}, error)
+}
+
+// key identifies a registered codec.
+type key struct {
+	typ     Type
+	version byte
+}
+
+var (
+	mu     sync.RWMutex
+	codecs = make(map[key]Codec)
+)
+
+func init() {
+	Register(Timestamp, 1, TimestampCodec{})
+	Register(Float, 1, FloatCodec{})
+	Register(Integer, 1, IntegerCodec{})
+	Register(String, 1, StringCodec{})
+	Register(Boolean, 1, BooleanCodec{})
+
+	// Version 2 dictionary encodes strings.
+	Register(Timestamp, 2, TimestampCodec{})
+	Register(Float, 2, FloatCodec{})
+	Register(Integer, 2, IntegerCodec{})
+	Register(String, 2, DictionaryCodec{})
+	Register(Boolean, 2, BooleanCodec{})
+
+	// Version 3 blocks store the values of fields in columns by type.
+	Register(Timestamp, 3, TimestampCodec{})
+	Register(Float, 3, FloatCodec{})
+	Register(Integer, 3, IntegerCodec{})
+	Register(String, 3, DictionaryCodec{})
+	Register(Boolean, 3, BooleanCodec{})
+}
+
+// Register makes a codec available for a version of a block type. It panics
+// if the version is invalid or already registered.
+func Register(typ Type, version byte, c Codec) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if version == 0 || version > MaxVersion {
+		panic(fmt.Sprintf("codec: invalid version %d for %s", version, typ))
+	} else if _, ok := codecs[key{typ, version}]; ok {
+		panic(fmt.Sprintf("codec: %s version %d already registered", typ, version))
+	}
+	codecs[key{typ, version}] = c
+}
+
+// Lookup returns the codec of a version of a block type, if registered.
+func Lookup(typ Type, version byte) (Codec, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	c, ok := codecs[key{typ, version}]
+	return c, ok
+}
+
+// Encode appends a block of values encoded with the codec of a version of a
+// block type to dst. The block starts with a one byte header holding the type
+// in the high bits and the version in the low bits.
+func Encode(dst []byte, typ Type, version byte, values interface{}) ([]byte, error) {
+	c, ok := Lookup(typ, version)
+	if !ok {
+		return nil, fmt.Errorf("codec: unknown %s version %d", typ, version)
+	}
+	return c.Encode(append(dst, byte(typ)<<4|version), values)
+}
+
+// Decode decodes a block produced by Encode with the codec it was encoded
+// with. It returns the type of the block and its values.
+func Decode(src []byte) (Type, interface{}, error) {
+	if len(src) == 0 {
+		return 0, nil, ErrShortBlock
+	}
+
+	typ, version := Type(src[0]>>4), src[0]&MaxVersion
+	c, ok := Lookup(typ, version)
+	if !ok {
+		return 0, nil, fmt.Errorf("codec: unknown %s version %d", typ, version)
+	}
+
+	values, err := c.Decode(src[1:])
+	if err != nil {
+		return 0, nil, err
+	}
+	return typ, values, nil
+}
+
+// typeError returns the error of a codec given values of the wrong type.
+func typeError(typ Type, values interface{}) error {
+	return fmt.Errorf("codec: invalid %s values: %T", typ, values)
+}
+
+// capHint returns the capacity to preallocate for n decoded values, bounded
+// so a corrupt count can't allocate unbounded memory up front.
+func capHint(n uint64) int {
+	const max = 1 << 16
+	if n > max {
+		return max
+	}
+	return int(n)
+}
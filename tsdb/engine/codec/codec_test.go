@@ -0,0 +1,178 @@
+package codec_test
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/influxdb/influxdb/tsdb/engine/codec"
+)
+
+// Ensure every block type round trips through its codec.
+func TestEncode_RoundTrip(t *testing.T) {
+	for i, tt := range []struct {
+		typ    codec.Type
+		values interface{}
+	}{
+		{typ: codec.Timestamp, values: []int64{}},
+		{typ: codec.Timestamp, values: []int64{1000, 2000, 3000, 4000, 4500, 5000, 100, math.MinInt64, math.MaxInt64}},
+		{typ: codec.Float, values: []float64{}},
+		{typ: codec.Float, values: []float64{1.5, 1.5, 1.75, -2, 0, math.Inf(1), math.MaxFloat64, math.SmallestNonzeroFloat64, 12.25}},
+		{typ: codec.Integer, values: []int64{}},
+		{typ: codec.Integer, values: []int64{0, 1, 2, 2, 2, -5, 1 << 40, 7}},
+		{typ: codec.Integer, values: []int64{0, math.MaxInt64, math.MinInt64, 1}},
+		{typ: codec.String, values: []string{}},
+		{typ: codec.String, values: []string{"ok", "ok", "", "warning", "ok"}},
+		{typ: codec.String, values: []string{"ok", "ok", "ok", "ok", "warning", "ok"}},
+		{typ: codec.Boolean, values: []bool{}},
+		{typ: codec.Boolean, values: []bool{true, false, false, true, true, true, false, true, true}},
+	} {
+		b, err := codec.Encode(nil, tt.typ, codec.Version, tt.values)
+		if err != nil {
+			t.Fatalf("%d. %s: encode: %s", i, tt.typ, err)
+		}
+
+		typ, values, err := codec.Decode(b)
+		if err != nil {
+			t.Fatalf("%d. %s: decode: %s", i, tt.typ, err)
+		} else if typ != tt.typ {
+			t.Fatalf("%d. unexpected type: %s, expected %s", i, typ, tt.typ)
+		} else if !reflect.DeepEqual(values, tt.values) {
+			t.Fatalf("%d. %s: unexpected values:\n\nexp=%v\n\ngot=%v\n\n", i, tt.typ, tt.values, values)
+		}
+	}
+}
+
+// Ensure random values round trip through the numeric codecs.
+func TestEncode_RoundTrip_Random(t *testing.T) {
+	rand.Seed(0)
+	for n := 0; n < 100; n++ {
+		ints, floats := make([]int64, rand.Intn(1000)), make([]float64, rand.Intn(1000))
+		for i := range ints {
+			ints[i] = rand.Int63n(1<<uint(rand.Intn(63))) - rand.Int63n(1000)
+		}
+		for i := range floats {
+			floats[i] = float64(rand.Intn(100)) + rand.Float64()
+		}
+
+		for _, tt := range []struct {
+			typ    codec.Type
+			values interface{}
+		}{
+			{codec.Timestamp, ints},
+			{codec.Integer, ints},
+			{codec.Float, floats},
+		} {
+			b, err := codec.Encode(nil, tt.typ, codec.Version, tt.values)
+			if err != nil {
+				t.Fatal(err)
+			} else if _, values, err := codec.Decode(b); err != nil {
+				t.Fatal(err)
+			} else if !reflect.DeepEqual(values, tt.values) {
+				t.Fatalf("%s: unexpected values:\n\nexp=%v\n\ngot=%v\n\n", tt.typ, tt.values, values)
+			}
+		}
+	}
+}
+
+// Ensure regular timestamps and small integers encode compactly.
+func TestEncode_Size(t *testing.T) {
+	timestamps, counters := make([]int64, 1000), make([]int64, 1000)
+	for i := range timestamps {
+		timestamps[i] = 1444000000000000000 + int64(i)*10000000000
+		counters[i] = int64(i * 3)
+	}
+
+	if b, _ := codec.Encode(nil, codec.Timestamp, codec.Version, timestamps); len(b) > 20 {
+		t.Fatalf("unexpected timestamps size: %d", len(b))
+	} else if b, _ := codec.Encode(nil, codec.Integer, codec.Version, counters); len(b) > 1000 {
+		t.Fatalf("unexpected integers size: %d", len(b))
+	}
+}
+
+// Ensure encoding values of the wrong type returns an error.
+func TestEncode_ErrType(t *testing.T) {
+	if _, err := codec.Encode(nil, codec.Float, codec.Version, []int64{1}); err == nil || err.Error() != `codec: invalid float values: []int64` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure blocks of unknown versions and corrupt blocks can't be decoded.
+func TestDecode_Err(t *testing.T) {
+	b, _ := codec.Encode(nil, codec.Integer, codec.Version, []int64{1, 2, 3})
+
+	if _, _, err := codec.Decode(nil); err != codec.ErrShortBlock {
+		t.Fatalf("unexpected error: %v", err)
+	} else if _, _, err := codec.Decode([]byte{byte(codec.Integer)<<4 | 0x0F}); err == nil || err.Error() != `codec: unknown integer version 15` {
+		t.Fatalf("unexpected error: %v", err)
+	} else if _, _, err := codec.Decode(b[:len(b)-1]); err != codec.ErrCorruptBlock {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure new codec versions can be registered next to existing ones.
+func TestRegister(t *testing.T) {
+	codec.Register(codec.Boolean, 0x0E, codec.IntegerCodec{})
+
+	b, err := codec.Encode(nil, codec.Boolean, 0x0E, []int64{4, 5})
+	if err != nil {
+		t.Fatal(err)
+	} else if _, values, err := codec.Decode(b); err != nil || !reflect.DeepEqual(values, []int64{4, 5}) {
+		t.Fatalf("unexpected values: %v, err=%v", values, err)
+	}
+
+	// Registering a version twice panics.
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("expected panic")
+		}
+	}()
+	codec.Register(codec.Boolean, 0x0E, codec.IntegerCodec{})
+}
+
+// Ensure low cardinality strings are dictionary encoded and share their bytes.
+func TestDictionary(t *testing.T) {
+	levels := [][]byte{[]byte("info: request served"), []byte("warn: slow request"), []byte("error: request failed")}
+	values := make([][]byte, 1000)
+	for i := range values {
+		values[i] = levels[rand.Intn(len(levels))]
+	}
+
+	b := codec.EncodeDictionary(nil, values)
+	if plain, _ := codec.Encode(nil, codec.String, 1, bytesToStrings(values)); len(b) >= len(plain) {
+		t.Fatalf("unexpected size: %d, plain=%d", len(b), len(plain))
+	}
+
+	a, err := codec.DecodeDictionary(b)
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(a, values) {
+		t.Fatal("unexpected values")
+	}
+	for i := range a {
+		if bytes.Equal(a[i], a[0]) && &a[i][0] != &a[0][0] {
+			t.Fatalf("value %d doesn't share its bytes", i)
+		}
+	}
+
+	// High cardinality values are stored as is.
+	for i := range values {
+		values[i] = []byte(strconv.Itoa(i))
+	}
+	if a, err := codec.DecodeDictionary(codec.EncodeDictionary(nil, values)); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(a, values) {
+		t.Fatal("unexpected values")
+	}
+}
+
+func bytesToStrings(a [][]byte) []string {
+	other := make([]string, len(a))
+	for i, b := range a {
+		other[i] = string(b)
+	}
+	return other
+}
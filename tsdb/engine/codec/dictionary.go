@@ -0,0 +1,139 @@
+package codec
+
+import (
+	"encoding/binary"
+
+	"github.com/golang/snappy"
+)
+
+// DictionaryCodec dictionary encodes strings with few distinct values, such
+// as status or level fields of logs. Each distinct value is stored once and
+// the values refer to it by index, packed with simple8b. Blocks with many
+// distinct values are snappy compressed instead, as by StringCodec.
+type DictionaryCodec struct{}
+
+const (
+	dictionaryPlain   = 0
+	dictionaryIndexed = 1
+)
+
+// Encode appends the encoding of []string values to dst.
+func (DictionaryCodec) Encode(dst []byte, values interface{}) ([]byte, error) {
+	a, ok := values.([]string)
+	if !ok {
+		return nil, typeError(String, values)
+	}
+	return EncodeDictionary(dst, stringsToBytes(a)), nil
+}
+
+// Decode decodes strings encoded by Encode.
+func (DictionaryCodec) Decode(src []byte) (interface{}, error) {
+	a, err := DecodeDictionary(src)
+	if err != nil {
+		return nil, err
+	}
+	return bytesToStrings(a), nil
+}
+
+// EncodeDictionary appends the dictionary encoding of byte slice values to
+// dst. Values are dictionary encoded if at most half of them are distinct.
+//
+// The encoding starts with a byte naming the layout. Plain values are snappy
+// compressed as by StringCodec. Indexed values are the number of values and
+// the length of the dictionary as varints, the snappy compressed dictionary
+// of distinct values and the index of each value packed in simple8b words.
+func EncodeDictionary(dst []byte, values [][]byte) []byte {
+	index := make(map[string]uint64)
+	var dict [][]byte
+	refs := make([]uint64, len(values))
+	for i, v := range values {
+		ref, ok := index[string(v)]
+		if !ok {
+			ref = uint64(len(dict))
+			index[string(v)] = ref
+			dict = append(dict, v)
+		}
+		refs[i] = ref
+	}
+
+	if len(dict)*2 > len(values) {
+		dst = append(dst, dictionaryPlain)
+		return append(dst, snappy.Encode(nil, appendValues(nil, values))...)
+	}
+
+	var buf [binary.MaxVarintLen64]byte
+	d := snappy.Encode(nil, appendValues(nil, dict))
+	dst = append(dst, dictionaryIndexed)
+	dst = append(dst, buf[:binary.PutUvarint(buf[:], uint64(len(values)))]...)
+	dst = append(dst, buf[:binary.PutUvarint(buf[:], uint64(len(d)))]...)
+	dst = append(dst, d...)
+	for _, w := range simple8bEncode(refs) {
+		binary.BigEndian.PutUint64(buf[:8], w)
+		dst = append(dst, buf[:8]...)
+	}
+	return dst
+}
+
+// DecodeDictionary decodes values encoded by EncodeDictionary. Equal values of
+// a dictionary encoded block share their bytes, so decoding them doesn't copy
+// each value.
+func DecodeDictionary(src []byte) ([][]byte, error) {
+	if len(src) == 0 {
+		return nil, ErrCorruptBlock
+	}
+
+	switch src[0] {
+	case dictionaryPlain:
+		raw, err := snappy.Decode(nil, src[1:])
+		if err != nil {
+			return nil, ErrCorruptBlock
+		}
+		return splitValues(raw)
+
+	case dictionaryIndexed:
+		src = src[1:]
+		n, sz := binary.Uvarint(src)
+		if sz <= 0 {
+			return nil, ErrCorruptBlock
+		}
+		src = src[sz:]
+
+		l, sz := binary.Uvarint(src)
+		if sz <= 0 || l > uint64(len(src)-sz) {
+			return nil, ErrCorruptBlock
+		}
+		raw, err := snappy.Decode(nil, src[sz:sz+int(l)])
+		if err != nil {
+			return nil, ErrCorruptBlock
+		}
+		dict, err := splitValues(raw)
+		if err != nil {
+			return nil, err
+		}
+		src = src[sz+int(l):]
+
+		if len(src)%8 != 0 {
+			return nil, ErrCorruptBlock
+		}
+		a := make([][]byte, 0, capHint(n))
+		for ; len(src) > 0; src = src[8:] {
+			refs, err := simple8bDecode(binary.BigEndian.Uint64(src))
+			if err != nil {
+				return nil, err
+			}
+			for _, ref := range refs {
+				if uint64(len(a)) == n {
+					break // the last word is padded.
+				} else if ref >= uint64(len(dict)) {
+					return nil, ErrCorruptBlock
+				}
+				a = append(a, dict[ref])
+			}
+		}
+		if uint64(len(a)) != n {
+			return nil, ErrCorruptBlock
+		}
+		return a, nil
+	}
+	return nil, ErrCorruptBlock
+}
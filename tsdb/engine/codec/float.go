@@ -0,0 +1,206 @@
+package codec
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// FloatCodec XOR encodes floats against their previous value, as described in
+// Facebook's Gorilla paper. Slowly changing values share their sign, exponent
+// and high mantissa bits, so only the few bits that differ are stored.
+//
+// The encoding is the number of values as a varint and the first value's 64
+// bits, followed by one entry per value. An entry is a single 0 bit for a
+// value equal to the previous one. Otherwise it is a 1 bit followed by either
+// a 0 bit and the meaningful bits of the XOR inside the previous entry's
+// window, or a 1 bit, 5 bits of leading zeros, 6 bits of meaningful bit count
+// and the meaningful bits.
+type FloatCodec struct{}
+
+// Encode appends the encoding of []float64 values to dst.
+func (FloatCodec) Encode(dst []byte, values interface{}) ([]byte, error) {
+	a, ok := values.([]float64)
+	if !ok {
+		return nil, typeError(Float, values)
+	}
+
+	var buf [binary.MaxVarintLen64]byte
+	dst = append(dst, buf[:binary.PutUvarint(buf[:], uint64(len(a)))]...)
+	if len(a) == 0 {
+		return dst, nil
+	}
+
+	w := bitWriter{buf: dst}
+	prev := math.Float64bits(a[0])
+	w.write(prev, 64)
+
+	leading, trailing := uint(math.MaxUint8), uint(0)
+	for _, f := range a[1:] {
+		v := math.Float64bits(f)
+		x := v ^ prev
+		prev = v
+
+		if x == 0 {
+			w.write(0, 1)
+			continue
+		}
+		w.write(1, 1)
+
+		l, t := uint(leadingZeros(x)), uint(trailingZeros(x))
+		if l >= 32 {
+			l = 31 // only 5 bits are available.
+		}
+
+		if leading != math.MaxUint8 && l >= leading && t >= trailing {
+			// Reuse the window of the previous entry.
+			w.write(0, 1)
+			w.write(x>>trailing, 64-leading-trailing)
+			continue
+		}
+
+		leading, trailing = l, t
+		n := 64 - l - t
+		w.write(1, 1)
+		w.write(uint64(l), 5)
+		w.write(uint64(n&0x3F), 6) // 64 meaningful bits are written as 0.
+		w.write(x>>t, n)
+	}
+	return w.bytes(), nil
+}
+
+// Decode decodes floats encoded by Encode.
+func (FloatCodec) Decode(src []byte) (interface{}, error) {
+	n, sz := binary.Uvarint(src)
+	if sz <= 0 {
+		return nil, ErrCorruptBlock
+	}
+
+	a := make([]float64, 0, capHint(n))
+	if n == 0 {
+		return a, nil
+	}
+
+	r := bitReader{buf: src[sz:]}
+	prev, ok := r.read(64)
+	if !ok {
+		return nil, ErrCorruptBlock
+	}
+	a = append(a, math.Float64frombits(prev))
+
+	var leading, trailing uint
+	var window bool // whether an entry has set a window yet
+	for uint64(len(a)) < n {
+		same, ok := r.read(1)
+		if !ok {
+			return nil, ErrCorruptBlock
+		} else if same == 0 {
+			a = append(a, math.Float64frombits(prev))
+			continue
+		}
+
+		reuse, ok := r.read(1)
+		if !ok {
+			return nil, ErrCorruptBlock
+		} else if reuse == 1 {
+			l, ok1 := r.read(5)
+			m, ok2 := r.read(6)
+			if !ok1 || !ok2 {
+				return nil, ErrCorruptBlock
+			}
+			if m == 0 {
+				m = 64
+			}
+			if uint(l)+uint(m) > 64 {
+				return nil, ErrCorruptBlock
+			}
+			leading, trailing, window = uint(l), 64-uint(l)-uint(m), true
+		} else if !window {
+			return nil, ErrCorruptBlock
+		}
+
+		x, ok := r.read(64 - leading - trailing)
+		if !ok {
+			return nil, ErrCorruptBlock
+		}
+		prev ^= x << trailing
+		a = append(a, math.Float64frombits(prev))
+	}
+	return a, nil
+}
+
+// bitWriter appends bits to a byte slice, most significant bit first.
+type bitWriter struct {
+	buf []byte
+	n   uint // bits used in the last byte, 0 if it's full
+}
+
+// write appends the low n bits of v.
+func (w *bitWriter) write(v uint64, n uint) {
+	for n > 0 {
+		if w.n == 0 {
+			w.buf = append(w.buf, 0)
+		}
+
+		// Fill as much of the last byte as possible.
+		free := 8 - w.n
+		if free > n {
+			free = n
+		}
+		bits := byte(v>>(n-free)) & (1<<free - 1)
+		w.buf[len(w.buf)-1] |= bits << (8 - w.n - free)
+
+		w.n = (w.n + free) % 8
+		n -= free
+	}
+}
+
+// bytes returns the written bytes, with the last byte zero padded.
+func (w *bitWriter) bytes() []byte { return w.buf }
+
+// bitReader reads bits written by a bitWriter.
+type bitReader struct {
+	buf []byte
+	off uint // bit offset into buf
+}
+
+// read returns the next n bits, or false if fewer than n bits are left.
+func (r *bitReader) read(n uint) (uint64, bool) {
+	if r.off+n > uint(len(r.buf))*8 {
+		return 0, false
+	}
+
+	var v uint64
+	for n > 0 {
+		used := r.off % 8
+		avail := 8 - used
+		if avail > n {
+			avail = n
+		}
+		b := r.buf[r.off/8] >> (8 - used - avail) & (1<<avail - 1)
+		v = v<<avail | uint64(b)
+
+		r.off += avail
+		n -= avail
+	}
+	return v, true
+}
+
+// leadingZeros returns the number of leading zero bits of a non-zero value.
+func leadingZeros(x uint64) int {
+	n := 0
+	for x&(1<<63) == 0 {
+		x <<= 1
+		n++
+	}
+	return n
+}
+
+// trailingZeros returns the number of trailing zero bits of a non-zero value.
+func trailingZeros(x uint64) int {
+	n := 0
+	for x&1 == 0 {
+		x >>= 1
+		n++
+	}
+	return n
+}
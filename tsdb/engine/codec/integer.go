@@ -0,0 +1,184 @@
+package codec
+
+import "encoding/binary"
+
+// IntegerCodec delta encodes integers, maps the deltas to unsigned values with
+// ZigZag encoding so small negative deltas stay small, and packs them into
+// 64-bit words with simple8b. Counters and slowly changing gauges pack many
+// values per word.
+//
+// The encoding starts with a byte naming the packing and the number of values
+// as a varint. Packed values are followed by simple8b words. Deltas too large
+// for simple8b fall back to big endian 8-byte values.
+type IntegerCodec struct{}
+
+const (
+	integerUncompressed = 0
+	integerSimple8b     = 1
+)
+
+// Encode appends the encoding of []int64 values to dst.
+func (IntegerCodec) Encode(dst []byte, values interface{}) ([]byte, error) {
+	a, ok := values.([]int64)
+	if !ok {
+		return nil, typeError(Integer, values)
+	}
+
+	deltas := make([]uint64, len(a))
+	packable := true
+	for i, v := range a {
+		delta := v
+		if i > 0 {
+			delta = v - a[i-1]
+		}
+		if deltas[i] = zigZagEncode(delta); deltas[i] > simple8bMaxValue {
+			packable = false
+		}
+	}
+
+	var buf [binary.MaxVarintLen64]byte
+	if !packable {
+		dst = append(dst, integerUncompressed)
+		dst = append(dst, buf[:binary.PutUvarint(buf[:], uint64(len(a)))]...)
+		for _, v := range a {
+			binary.BigEndian.PutUint64(buf[:8], uint64(v))
+			dst = append(dst, buf[:8]...)
+		}
+		return dst, nil
+	}
+
+	dst = append(dst, integerSimple8b)
+	dst = append(dst, buf[:binary.PutUvarint(buf[:], uint64(len(a)))]...)
+	for _, w := range simple8bEncode(deltas) {
+		binary.BigEndian.PutUint64(buf[:8], w)
+		dst = append(dst, buf[:8]...)
+	}
+	return dst, nil
+}
+
+// Decode decodes integers encoded by Encode.
+func (IntegerCodec) Decode(src []byte) (interface{}, error) {
+	if len(src) == 0 {
+		return nil, ErrCorruptBlock
+	}
+	packing := src[0]
+
+	n, sz := binary.Uvarint(src[1:])
+	if sz <= 0 {
+		return nil, ErrCorruptBlock
+	}
+	src = src[1+sz:]
+
+	if len(src)%8 != 0 {
+		return nil, ErrCorruptBlock
+	}
+
+	switch packing {
+	case integerUncompressed:
+		if uint64(len(src)/8) != n {
+			return nil, ErrCorruptBlock
+		}
+		a := make([]int64, n)
+		for i := range a {
+			a[i] = int64(binary.BigEndian.Uint64(src[i*8:]))
+		}
+		return a, nil
+
+	case integerSimple8b:
+		a := make([]int64, 0, capHint(n))
+		var v int64
+		for ; len(src) > 0; src = src[8:] {
+			deltas, err := simple8bDecode(binary.BigEndian.Uint64(src))
+			if err != nil {
+				return nil, err
+			}
+			for _, d := range deltas {
+				if uint64(len(a)) == n {
+					break // the last word is padded.
+				}
+				v += zigZagDecode(d)
+				a = append(a, v)
+			}
+		}
+		if uint64(len(a)) != n {
+			return nil, ErrCorruptBlock
+		}
+		return a, nil
+	}
+	return nil, ErrCorruptBlock
+}
+
+// zigZagEncode maps signed integers to unsigned ones so values of a small
+// magnitude have a small encoding: 0, -1, 1, -2, 2 map to 0, 1, 2, 3, 4.
+func zigZagEncode(v int64) uint64 { return uint64(v<<1) ^ uint64(v>>63) }
+
+// zigZagDecode reverses zigZagEncode.
+func zigZagDecode(v uint64) int64 { return int64(v>>1) ^ -int64(v&1) }
+
+// simple8bMaxValue is the largest value simple8b can pack.
+const simple8bMaxValue = 1<<60 - 1
+
+// simple8bSelectors are the number of values and bits per value packed into
+// a word for each 4-bit selector. Selectors 0 and 1 pack runs of zeros.
+var simple8bSelectors = [16]struct{ n, bits uint }{
+	{240, 0}, {120, 0}, {60, 1}, {30, 2}, {20, 3}, {15, 4}, {12, 5}, {10, 6},
+	{8, 7}, {7, 8}, {6, 10}, {5, 12}, {4, 15}, {3, 20}, {2, 30}, {1, 60},
+}
+
+// simple8bEncode packs values of at most simple8bMaxValue into words, each
+// holding a selector in its high 4 bits and as many values as fit in the
+// other 60 bits. The last word may be padded with zeros.
+func simple8bEncode(a []uint64) []uint64 {
+	var words []uint64
+	for len(a) > 0 {
+		for sel, s := range simple8bSelectors {
+			n := s.n
+			if uint(len(a)) < n {
+				if s.bits == 0 {
+					continue // runs of zeros must be complete.
+				}
+				n = uint(len(a))
+			}
+			if !fits(a[:n], s.bits) {
+				continue
+			}
+
+			w := uint64(sel) << 60
+			for i, v := range a[:n] {
+				w |= v << (uint(i) * s.bits)
+			}
+			words = append(words, w)
+			a = a[n:]
+			break
+		}
+	}
+	return words
+}
+
+// simple8bDecode unpacks the values of a word packed by simple8bEncode.
+func simple8bDecode(w uint64) ([]uint64, error) {
+	s := simple8bSelectors[w>>60]
+	a := make([]uint64, s.n)
+	if s.bits == 0 {
+		if w&simple8bMaxValue != 0 {
+			return nil, ErrCorruptBlock
+		}
+		return a, nil
+	}
+
+	mask := uint64(1)<<s.bits - 1
+	for i := range a {
+		a[i] = w >> (uint(i) * s.bits) & mask
+	}
+	return a, nil
+}
+
+// fits returns true if every value fits in bits.
+func fits(a []uint64, bits uint) bool {
+	for _, v := range a {
+		if v>>bits != 0 {
+			return false
+		}
+	}
+	return true
+}
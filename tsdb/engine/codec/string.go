@@ -0,0 +1,90 @@
+package codec
+
+import (
+	"encoding/binary"
+
+	"github.com/golang/snappy"
+)
+
+// StringCodec snappy compresses strings. Repeated values, such as status
+// strings of a series, compress well across the block.
+//
+// The encoding is snappy compressed data holding the number of values as a
+// varint, followed by each value's length as a varint and its bytes.
+type StringCodec struct{}
+
+// Encode appends the encoding of []string values to dst.
+func (StringCodec) Encode(dst []byte, values interface{}) ([]byte, error) {
+	a, ok := values.([]string)
+	if !ok {
+		return nil, typeError(String, values)
+	}
+	return append(dst, snappy.Encode(nil, appendValues(nil, stringsToBytes(a)))...), nil
+}
+
+// Decode decodes strings encoded by Encode.
+func (StringCodec) Decode(src []byte) (interface{}, error) {
+	raw, err := snappy.Decode(nil, src)
+	if err != nil {
+		return nil, ErrCorruptBlock
+	}
+
+	a, err := splitValues(raw)
+	if err != nil {
+		return nil, err
+	}
+	return bytesToStrings(a), nil
+}
+
+// appendValues appends the number of values and each value prefixed with its
+// length to dst.
+func appendValues(dst []byte, values [][]byte) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	dst = append(dst, buf[:binary.PutUvarint(buf[:], uint64(len(values)))]...)
+	for _, v := range values {
+		dst = append(dst, buf[:binary.PutUvarint(buf[:], uint64(len(v)))]...)
+		dst = append(dst, v...)
+	}
+	return dst
+}
+
+// splitValues returns the values appended by appendValues. The values point
+// into src.
+func splitValues(src []byte) ([][]byte, error) {
+	n, sz := binary.Uvarint(src)
+	if sz <= 0 || n > uint64(len(src)) {
+		return nil, ErrCorruptBlock
+	}
+	src = src[sz:]
+
+	a := make([][]byte, 0, n)
+	for uint64(len(a)) < n {
+		l, sz := binary.Uvarint(src)
+		if sz <= 0 || l > uint64(len(src)-sz) {
+			return nil, ErrCorruptBlock
+		}
+		a = append(a, src[sz:sz+int(l)])
+		src = src[sz+int(l):]
+	}
+
+	if len(src) != 0 {
+		return nil, ErrCorruptBlock
+	}
+	return a, nil
+}
+
+func stringsToBytes(a []string) [][]byte {
+	other := make([][]byte, len(a))
+	for i, s := range a {
+		other[i] = []byte(s)
+	}
+	return other
+}
+
+func bytesToStrings(a [][]byte) []string {
+	other := make([]string, len(a))
+	for i, b := range a {
+		other[i] = string(b)
+	}
+	return other
+}
@@ -0,0 +1,90 @@
+package codec
+
+import "encoding/binary"
+
+// TimestampCodec delta encodes timestamps and run-length encodes the deltas.
+// Points written at a regular interval compress to a few bytes per block.
+//
+// The encoding is the number of values and the first value, followed by runs
+// of equal deltas as the delta and the number of times it repeats. All
+// integers are varints.
+type TimestampCodec struct{}
+
+// Encode appends the encoding of []int64 timestamps to dst.
+func (TimestampCodec) Encode(dst []byte, values interface{}) ([]byte, error) {
+	a, ok := values.([]int64)
+	if !ok {
+		return nil, typeError(Timestamp, values)
+	}
+	return encodeDeltaRLE(dst, a), nil
+}
+
+// Decode decodes timestamps encoded by Encode.
+func (TimestampCodec) Decode(src []byte) (interface{}, error) {
+	return decodeDeltaRLE(src)
+}
+
+func encodeDeltaRLE(dst []byte, a []int64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	dst = append(dst, buf[:binary.PutUvarint(buf[:], uint64(len(a)))]...)
+	if len(a) == 0 {
+		return dst
+	}
+	dst = append(dst, buf[:binary.PutVarint(buf[:], a[0])]...)
+
+	for i := 1; i < len(a); {
+		// Find the run of the delta at i.
+		delta, n := a[i]-a[i-1], 1
+		for i+n < len(a) && a[i+n]-a[i+n-1] == delta {
+			n++
+		}
+		dst = append(dst, buf[:binary.PutVarint(buf[:], delta)]...)
+		dst = append(dst, buf[:binary.PutUvarint(buf[:], uint64(n))]...)
+		i += n
+	}
+	return dst
+}
+
+func decodeDeltaRLE(src []byte) ([]int64, error) {
+	n, sz := binary.Uvarint(src)
+	if sz <= 0 {
+		return nil, ErrCorruptBlock
+	}
+	src = src[sz:]
+
+	a := make([]int64, 0, capHint(n))
+	if n == 0 {
+		return a, nil
+	}
+
+	v, sz := binary.Varint(src)
+	if sz <= 0 {
+		return nil, ErrCorruptBlock
+	}
+	src = src[sz:]
+	a = append(a, v)
+
+	for uint64(len(a)) < n {
+		delta, sz := binary.Varint(src)
+		if sz <= 0 {
+			return nil, ErrCorruptBlock
+		}
+		src = src[sz:]
+
+		run, sz := binary.Uvarint(src)
+		if sz <= 0 || run == 0 || run > n-uint64(len(a)) {
+			return nil, ErrCorruptBlock
+		}
+		src = src[sz:]
+
+		for ; run > 0; run-- {
+			v += delta
+			a = append(a, v)
+		}
+	}
+
+	if len(src) != 0 {
+		return nil, ErrCorruptBlock
+	}
+	return a, nil
+}
@@ -27,6 +27,7 @@ import (
 	"errors"
 	"expvar"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"log"
 	"os"
@@ -52,6 +53,11 @@ const (
 	// MetaFileExtension is the file extension for the log files of new fields and measurements that get created
 	MetaFileExtension = "meta"
 
+	// metaChecksumFlag is set in the length of the metadata records which are
+	// followed by the CRC-32 checksum of their data. Records written before
+	// checksums were added don't have it.
+	metaChecksumFlag = uint64(1) << 63
+
 	// CompactionExtension is the file extension we expect for compaction files
 	CompactionExtension = "CPT"
 
@@ -169,6 +175,9 @@ type Log struct {
 	// LoggingEnabled specifies if detailed logs should be output
 	LoggingEnabled bool
 
+	// Replay, if set, is told about the segments replayed when the log opens.
+	Replay *tsdb.ReplayProgress
+
 	// expvar-based statistics
 	statMap *expvar.Map
 }
@@ -253,6 +262,32 @@ func (l *Log) DiskSize() (int64, error) {
 	return stat.Size(), nil
 }
 
+// WALStats returns the state of the log's segment files and cache.
+func (l *Log) WALStats() tsdb.WALStats {
+	l.mu.RLock()
+	p := l.partition
+	l.mu.RUnlock()
+	if p == nil {
+		return tsdb.WALStats{}
+	}
+	return p.stats()
+}
+
+// CacheSize returns the size in memory of the points waiting to be flushed
+// to the index.
+func (l *Log) CacheSize() int64 {
+	l.mu.RLock()
+	p := l.partition
+	l.mu.RUnlock()
+	if p == nil {
+		return 0
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return int64(p.memorySize)
+}
+
 // Cursor will return a cursor object to Seek and iterate with Next for the WAL cache for the given
 func (l *Log) Cursor(key string, direction tsdb.Direction) tsdb.Cursor {
 	l.mu.RLock()
@@ -271,13 +306,20 @@ func (l *Log) WritePoints(points []tsdb.Point, fields map[string]*tsdb.Measureme
 		return err
 	}
 
-	// persist the raw point data
+	// persist the raw point data, if it isn't only registering series
+	if len(points) == 0 {
+		return nil
+	}
 	return l.partition.Write(points)
 }
 
-// Flush will force a flush on all paritions
+// Flush will force a flush of the metadata and all paritions
 func (l *Log) Flush() error {
 	l.statMap.Add(statFlush, 1)
+	if err := l.flushMetadata(); err != nil {
+		return err
+	}
+
 	l.mu.RLock()
 	defer l.mu.RUnlock()
 
@@ -357,16 +399,24 @@ func (l *Log) DeleteSeries(keys []string) error {
 
 // readMetadataFile will read the entire contents of the meta file and return a slice of the
 // seriesAndFields objects that were written in. It ignores file errors since those can't be
-// recovered.
+// recovered: reading stops at the first truncated or corrupt record.
 func (l *Log) readMetadataFile(fileName string) ([]*seriesAndFields, error) {
 	f, err := os.OpenFile(fileName, os.O_RDWR, 0666)
 	if err != nil {
 		return nil, err
 	}
 
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	remaining := st.Size()
+
 	a := make([]*seriesAndFields, 0)
 
 	length := make([]byte, 8)
+	checksum := make([]byte, 4)
 	for {
 		// get the length of the compressed seriesAndFields blob
 		_, err := io.ReadFull(f, length)
@@ -376,12 +426,30 @@ func (l *Log) readMetadataFile(fileName string) ([]*seriesAndFields, error) {
 			f.Close()
 			return nil, err
 		}
+		remaining -= int64(len(length))
 
 		dataLength := btou64(length)
 		if dataLength == 0 {
 			break
 		}
 
+		// newer records are followed by the checksum of their data
+		checksummed := dataLength&metaChecksumFlag != 0
+		dataLength &^= metaChecksumFlag
+		if checksummed {
+			if _, err := io.ReadFull(f, checksum); err != nil {
+				l.logger.Println("error reading checksum of metadata:", err.Error())
+				break
+			}
+			remaining -= int64(len(checksum))
+		}
+
+		// don't allocate a block for a length which was only partially written
+		if dataLength > uint64(remaining) {
+			l.logger.Printf("metadata truncated in %s: %d bytes expected, %d remaining\n", fileName, dataLength, remaining)
+			break
+		}
+
 		// read in the compressed block and decod it
 		b := make([]byte, dataLength)
 
@@ -393,6 +461,13 @@ func (l *Log) readMetadataFile(fileName string) ([]*seriesAndFields, error) {
 			l.logger.Println("error reading length of metadata:", err.Error())
 			break
 		}
+		remaining -= int64(dataLength)
+
+		if checksummed && crc32.ChecksumIEEE(b) != binary.BigEndian.Uint32(checksum) {
+			// print the error and move on since we can't recover the file
+			l.logger.Printf("metadata checksum mismatch in %s\n", fileName)
+			break
+		}
 
 		buf, err := snappy.Decode(nil, b)
 		if err != nil {
@@ -436,11 +511,12 @@ func (l *Log) writeSeriesAndFields(fields map[string]*tsdb.MeasurementFields, se
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	if _, err := l.metaFile.Write(u64tob(uint64(len(cb)))); err != nil {
-		return err
-	}
-
-	if _, err := l.metaFile.Write(cb); err != nil {
+	// write the record in a single call so it's either complete or detected
+	// as truncated when it's read
+	buf := make([]byte, 12, 12+len(cb))
+	binary.BigEndian.PutUint64(buf[0:8], uint64(len(cb))|metaChecksumFlag)
+	binary.BigEndian.PutUint32(buf[8:12], crc32.ChecksumIEEE(cb))
+	if _, err := l.metaFile.Write(append(buf, cb...)); err != nil {
 		return err
 	}
 
@@ -514,6 +590,7 @@ func (l *Log) openPartitionFile() error {
 	if l.LoggingEnabled && len(fileNames) > 0 {
 		l.logger.Println("reading WAL files to flush to index")
 	}
+	l.Replay.AddSegments(len(fileNames))
 	for _, n := range fileNames {
 		entries, err := l.partition.readFile(n)
 		if err != nil {
@@ -534,6 +611,7 @@ func (l *Log) openPartitionFile() error {
 		if err := os.Remove(n); err != nil {
 			return err
 		}
+		l.Replay.SegmentReplayed(len(entries))
 	}
 
 	return nil
@@ -734,6 +812,9 @@ type Partition struct {
 	flushColdInterval time.Duration
 	lastWriteTime     time.Time
 
+	// lastFlushDuration is the time taken by the last write of the cache to the index.
+	lastFlushDuration time.Duration
+
 	log     *Log
 	statMap *expvar.Map
 
@@ -999,8 +1080,19 @@ func (p *Partition) flushAndCompact(flush flushType) error {
 		return nil
 	} else if err != nil {
 		return err
-	} else if len(c.seriesToFlush) == 0 { // nothing to flush!
-		return nil
+	}
+
+	// ensure that we mark that compaction is no longer running
+	defer func() {
+		p.mu.Lock()
+		p.compactionRunning = false
+		p.mu.Unlock()
+	}()
+
+	// nothing to flush, but the old segments may still have the points of
+	// deleted series so remove them.
+	if len(c.seriesToFlush) == 0 {
+		return p.removeOldSegmentFiles(c)
 	}
 
 	// Logging and stats.
@@ -1033,6 +1125,9 @@ func (p *Partition) flushAndCompact(flush flushType) error {
 
 	writeDuration := time.Since(startTime)
 	p.statMap.AddFloat(statFlushDuration, writeDuration.Seconds())
+	p.mu.Lock()
+	p.lastFlushDuration = writeDuration
+	p.mu.Unlock()
 	if p.log.LoggingEnabled {
 		p.log.logger.Printf("write to index of partition %d took %s\n", p.id, writeDuration)
 	}
@@ -1044,13 +1139,6 @@ func (p *Partition) flushAndCompact(flush flushType) error {
 	p.mu.Unlock()
 	p.statMap.Add(statMemorySize, -int64(c.flushSize))
 
-	// ensure that we mark that compaction is no longer running
-	defer func() {
-		p.mu.Lock()
-		p.compactionRunning = false
-		p.mu.Unlock()
-	}()
-
 	return p.removeOldSegmentFiles(c)
 }
 
@@ -1256,6 +1344,37 @@ func (p *Partition) idFromFileName(name string) (uint32, error) {
 	return uint32(id), err
 }
 
+// stats returns the state of the partition's segment files and cache. Segment
+// files older than the current one are waiting to be compacted.
+func (p *Partition) stats() tsdb.WALStats {
+	p.mu.RLock()
+	stats := tsdb.WALStats{CacheSeriesN: len(p.cache), LastFlushDuration: p.lastFlushDuration}
+	currentSegmentID := p.currentSegmentID
+	p.mu.RUnlock()
+
+	names, err := p.segmentFileNames()
+	if err != nil {
+		return stats
+	}
+	for _, name := range names {
+		id, err := p.idFromFileName(name)
+		if err != nil {
+			continue
+		}
+		fi, err := os.Stat(name)
+		if err != nil {
+			// The file was removed by a compaction.
+			continue
+		}
+		stats.SegmentN++
+		stats.SegmentBytes += fi.Size()
+		if id < currentSegmentID {
+			stats.BacklogBytes += fi.Size()
+		}
+	}
+	return stats
+}
+
 // segmentFileNames returns all the segment files names for the partition
 func (p *Partition) segmentFileNames() ([]string, error) {
 	path := filepath.Join(p.path, fmt.Sprintf("*.%s", FileExtension))
@@ -1426,9 +1545,9 @@ func (c *cursor) Seek(seek []byte) (key, value []byte) {
 		return bytes.Compare(c.cache[i][0:8], seek) != -1
 	})
 
-	// If seek is not in the cache, return the last value in the cache
-	if c.direction.Reverse() && c.position >= len(c.cache) {
-		c.position = len(c.cache)
+	// A reverse cursor moves to the last value on or before seek.
+	if c.direction.Reverse() && (c.position >= len(c.cache) || bytes.Compare(c.cache[c.position][0:8], seek) == 1) {
+		c.position--
 	}
 
 	// Make sure our position points to something in the cache
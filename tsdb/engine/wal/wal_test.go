@@ -363,6 +363,53 @@ func TestWAL_CompactAfterTimeWithoutWrite(t *testing.T) {
 	}
 }
 
+func TestWAL_WALStats(t *testing.T) {
+	log := openTestWAL()
+	defer log.Close()
+	defer os.RemoveAll(log.path)
+
+	log.Index = &testIndexWriter{fn: func(pointsByKey map[string][][]byte, measurementFieldsToSave map[string]*tsdb.MeasurementFields, seriesToCreate []*tsdb.SeriesCreate) error {
+		return nil
+	}}
+
+	if err := log.Open(); err != nil {
+		t.Fatalf("couldn't open wal: %s", err.Error())
+	}
+
+	codec := tsdb.NewFieldCodec(map[string]*tsdb.Field{
+		"value": {
+			ID:   uint8(1),
+			Name: "value",
+			Type: influxql.Float,
+		},
+	})
+
+	points := parsePoints("cpu,host=A value=23.2 1\ncpu,host=B value=25.3 4\n", codec)
+	if err := log.WritePoints(points, nil, nil); err != nil {
+		t.Fatalf("failed to write points: %s", err.Error())
+	}
+
+	stats := log.WALStats()
+	if stats.SegmentN != 1 || stats.SegmentBytes == 0 {
+		t.Fatalf("unexpected segments: n=%d, bytes=%d", stats.SegmentN, stats.SegmentBytes)
+	} else if stats.CacheSeriesN != 2 {
+		t.Fatalf("unexpected cache series: %d", stats.CacheSeriesN)
+	} else if stats.BacklogBytes != 0 {
+		t.Fatalf("unexpected backlog: %d", stats.BacklogBytes)
+	}
+
+	if err := log.Flush(); err != nil {
+		t.Fatalf("failed to flush: %s", err.Error())
+	}
+
+	stats = log.WALStats()
+	if stats.CacheSeriesN != 0 {
+		t.Fatalf("unexpected cache series after flush: %d", stats.CacheSeriesN)
+	} else if stats.BacklogBytes != 0 {
+		t.Fatalf("unexpected backlog after flush: %d", stats.BacklogBytes)
+	}
+}
+
 func TestWAL_SeriesAndFieldsGetPersisted(t *testing.T) {
 	log := openTestWAL()
 	defer log.Close()
@@ -483,6 +530,69 @@ func TestWAL_SeriesAndFieldsGetPersisted(t *testing.T) {
 	}
 }
 
+// Ensure metadata records after a truncated or corrupt record are ignored when loading the index.
+func TestWAL_CorruptMetadata(t *testing.T) {
+	for _, tt := range []struct {
+		corrupt func(b []byte) []byte
+		series  []string
+	}{
+		// Length of a record which was only partially written.
+		{
+			corrupt: func(b []byte) []byte { return append(b, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF) },
+			series:  []string{"cpu,host=A", "cpu,host=B"},
+		},
+		// Data of the last record doesn't match its checksum.
+		{
+			corrupt: func(b []byte) []byte { b[len(b)-1] ^= 0xFF; return b },
+			series:  []string{"cpu,host=A"},
+		},
+	} {
+		func() {
+			log := openTestWAL()
+			defer log.Close()
+			defer os.RemoveAll(log.path)
+
+			if err := log.Open(); err != nil {
+				t.Fatalf("couldn't open wal: %s", err.Error())
+			}
+			log.Index = &testIndexWriter{fn: func(pointsByKey map[string][][]byte, measurementFieldsToSave map[string]*tsdb.MeasurementFields, seriesToCreate []*tsdb.SeriesCreate) error {
+				return nil
+			}}
+
+			for _, host := range []string{"A", "B"} {
+				tags := map[string]string{"host": host}
+				if err := log.writeSeriesAndFields(nil, []*tsdb.SeriesCreate{
+					{Measurement: "cpu", Series: tsdb.NewSeries(string(tsdb.MakeKey([]byte("cpu"), tags)), tags)},
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			fileName := log.metaFile.Name()
+			log.Close()
+
+			b, err := ioutil.ReadFile(fileName)
+			if err != nil {
+				t.Fatal(err)
+			} else if err := ioutil.WriteFile(fileName, tt.corrupt(b), 0666); err != nil {
+				t.Fatal(err)
+			}
+
+			idx := tsdb.NewDatabaseIndex()
+			if err := log.LoadMetadataIndex(idx, make(map[string]*tsdb.MeasurementFields)); err != nil {
+				t.Fatalf("error loading metadata index: %s", err.Error())
+			}
+			if got := idx.SeriesN(); got != len(tt.series) {
+				t.Fatalf("unexpected series count: %d", got)
+			}
+			for _, key := range tt.series {
+				if idx.Series(key) == nil {
+					t.Fatalf("expected to find series %s in index", key)
+				}
+			}
+		}()
+	}
+}
+
 func TestWAL_DeleteSeries(t *testing.T) {
 	log := openTestWAL()
 	defer log.Close()
@@ -585,6 +695,58 @@ func TestWAL_DeleteSeries(t *testing.T) {
 	}
 }
 
+// Ensure the points of deleted series aren't replayed when no other series
+// were left to flush.
+func TestWAL_DeleteSeries_All(t *testing.T) {
+	log := openTestWAL()
+	defer log.Close()
+	defer os.RemoveAll(log.path)
+
+	codec := tsdb.NewFieldCodec(map[string]*tsdb.Field{
+		"value": {
+			ID:   uint8(1),
+			Name: "value",
+			Type: influxql.Float,
+		},
+	})
+
+	points := make(map[string][][]byte)
+	log.Index = &testIndexWriter{fn: func(pointsByKey map[string][][]byte, measurementFieldsToSave map[string]*tsdb.MeasurementFields, seriesToCreate []*tsdb.SeriesCreate) error {
+		for k, v := range pointsByKey {
+			points[k] = append(points[k], v...)
+		}
+		return nil
+	}}
+
+	if err := log.Open(); err != nil {
+		t.Fatalf("couldn't open wal: %s", err.Error())
+	}
+
+	p1 := parsePoint("cpu,host=A value=23.2 1", codec)
+	if err := log.WritePoints([]tsdb.Point{p1}, nil, nil); err != nil {
+		t.Fatalf("failed to write points: %s", err.Error())
+	}
+	if err := log.DeleteSeries([]string{"cpu,host=A"}); err != nil {
+		t.Fatalf("error deleting series: %s", err.Error())
+	}
+
+	// re-open the WAL to ensure the point didn't show back up
+	if err := log.Close(); err != nil {
+		t.Fatalf("error closing log: %s", err.Error())
+	}
+	points = make(map[string][][]byte)
+	if err := log.Open(); err != nil {
+		t.Fatalf("error opening log: %s", err.Error())
+	}
+	if len(points) != 0 {
+		t.Fatal("expected no data to be flushed on open")
+	}
+	c := log.Cursor("cpu,host=A", tsdb.Forward)
+	if k, _ := c.Next(); k != nil {
+		t.Fatal("expected no data for cpu,host=A")
+	}
+}
+
 func TestWAL_QueryDuringCompaction(t *testing.T) {
 	log := openTestWAL()
 	defer log.Close()
@@ -727,6 +889,51 @@ func TestWAL_Cursor_Reverse(t *testing.T) {
 	}
 }
 
+// Ensure a reverse cursor seeks to the last point on or before the seek.
+func TestWAL_Cursor_SeekReverse(t *testing.T) {
+	log := openTestWAL()
+	defer log.Close()
+	defer os.RemoveAll(log.path)
+
+	if err := log.Open(); err != nil {
+		t.Fatalf("couldn't open wal: %s", err.Error())
+	}
+
+	codec := tsdb.NewFieldCodec(map[string]*tsdb.Field{
+		"value": {
+			ID:   uint8(1),
+			Name: "value",
+			Type: influxql.Float,
+		},
+	})
+
+	p1 := parsePoint("cpu,host=A value=1.1 1", codec)
+	p2 := parsePoint("cpu,host=A value=4.4 4", codec)
+	p3 := parsePoint("cpu,host=A value=6.6 6", codec)
+	if err := log.WritePoints([]tsdb.Point{p1, p2, p3}, nil, nil); err != nil {
+		t.Fatalf("failed to write points: %s", err.Error())
+	}
+
+	for _, tt := range []struct {
+		seek uint64
+		exp  []uint64
+	}{
+		{seek: 10, exp: []uint64{6, 4, 1}},
+		{seek: 6, exp: []uint64{6, 4, 1}},
+		{seek: 5, exp: []uint64{4, 1}},
+		{seek: 0, exp: nil},
+	} {
+		c := log.Cursor("cpu,host=A", tsdb.Reverse)
+		var got []uint64
+		for k, _ := c.Seek(u64tob(tt.seek)); k != nil; k, _ = c.Next() {
+			got = append(got, btou64(k))
+		}
+		if !reflect.DeepEqual(got, tt.exp) {
+			t.Fatalf("seek %d: unexpected keys: %v", tt.seek, got)
+		}
+	}
+}
+
 type testIndexWriter struct {
 	fn func(pointsByKey map[string][][]byte, measurementFieldsToSave map[string]*tsdb.MeasurementFields, seriesToCreate []*tsdb.SeriesCreate) error
 }
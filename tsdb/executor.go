@@ -236,7 +236,7 @@ func (e *SelectExecutor) executeRaw(out chan *influxql.Row) {
 					}
 				}
 
-				if e.tagSetIsLimited(m.bufferedChunk.Name) {
+				if e.tagSetIsLimited(m.bufferedChunk.key()) {
 					// chunk's tagset is limited, so no good. Try again.
 					m.bufferedChunk = nil
 					continue
@@ -318,7 +318,9 @@ func (e *SelectExecutor) executeRaw(out chan *influxql.Row) {
 					Tags:      m.bufferedChunk.Tags,
 					cursorKey: m.bufferedChunk.key(),
 				}
-				chunkedOutput.Values = m.bufferedChunk.Values[:ind]
+				// Copy the values so appending another mapper's values doesn't
+				// overwrite the remainder of this mapper's chunk.
+				chunkedOutput.Values = append([]*MapperValue(nil), m.bufferedChunk.Values[:ind]...)
 			} else {
 				chunkedOutput.Values = append(chunkedOutput.Values, m.bufferedChunk.Values[:ind]...)
 			}
@@ -332,11 +334,17 @@ func (e *SelectExecutor) executeRaw(out chan *influxql.Row) {
 			}
 		}
 
+		// Sort the values by time first so we can then handle offset and limit.
+		// The sort is stable so values for the same time stay in shard order.
 		if ascending {
-			// Sort the values by time first so we can then handle offset and limit
-			sort.Sort(MapperValues(chunkedOutput.Values))
+			sort.Stable(MapperValues(chunkedOutput.Values))
 		} else {
-			sort.Sort(sort.Reverse(MapperValues(chunkedOutput.Values)))
+			sort.Stable(sort.Reverse(MapperValues(chunkedOutput.Values)))
+		}
+
+		// Overlapping shards may return the same point, keep it once.
+		if len(e.mappers) > 1 {
+			chunkedOutput.Values = dedupeMapperValues(chunkedOutput.Values)
 		}
 
 		// Now that we have full name and tag details, initialize the rowWriter.
@@ -363,6 +371,7 @@ func (e *SelectExecutor) executeRaw(out chan *influxql.Row) {
 			rowWriter.transformer = &RawQueryDerivativeProcessor{
 				IsNonNegative:      e.stmt.FunctionCalls()[0].Name == "non_negative_derivative",
 				DerivativeInterval: interval,
+				MaxGap:             derivativeMaxGap(e.stmt),
 			}
 		}
 
@@ -377,6 +386,41 @@ func (e *SelectExecutor) executeRaw(out chan *influxql.Row) {
 	close(out)
 }
 
+// dedupeMapperValues removes values of the same series at the same time from
+// values sorted by time, keeping the last one. Raw values are merged in shard
+// order so the value from the most recently created shard wins.
+func dedupeMapperValues(a []*MapperValue) []*MapperValue {
+	other := a[:0]
+	for i := 0; i < len(a); {
+		// Find the values at the same time.
+		j := i + 1
+		for j < len(a) && a[j].Time == a[i].Time {
+			j++
+		}
+		if j-i == 1 {
+			other = append(other, a[i])
+			i = j
+			continue
+		}
+
+		// Keep the last value of each series, in the order the series first appeared.
+		var keys []string
+		last := make(map[string]*MapperValue, j-i)
+		for _, v := range a[i:j] {
+			k := string(MarshalTags(v.Tags))
+			if _, ok := last[k]; !ok {
+				keys = append(keys, k)
+			}
+			last[k] = v
+		}
+		for _, k := range keys {
+			other = append(other, last[k])
+		}
+		i = j
+	}
+	return other
+}
+
 func (e *SelectExecutor) executeAggregate(out chan *influxql.Row) {
 	// It's important to close all resources when execution completes.
 	defer e.close()
@@ -609,7 +653,7 @@ func (e *SelectExecutor) processDerivative(results [][]interface{}) [][]interfac
 
 		// Determines whether to drop negative differences
 		isNonNegative := e.stmt.FunctionCalls()[0].Name == "non_negative_derivative"
-		return ProcessAggregateDerivative(results, isNonNegative, interval)
+		return ProcessAggregateDerivative(results, isNonNegative, interval, derivativeMaxGap(e.stmt))
 	}
 	return results
 }
@@ -888,6 +932,7 @@ type RawQueryDerivativeProcessor struct {
 	LastValueFromPreviousChunk *MapperValue
 	IsNonNegative              bool // Whether to drop negative differences
 	DerivativeInterval         time.Duration
+	MaxGap                     time.Duration // Points farther apart have a null derivative. Zero is no max.
 }
 
 func (rqdp *RawQueryDerivativeProcessor) canProcess(input []*MapperValue) bool {
@@ -900,7 +945,7 @@ func (rqdp *RawQueryDerivativeProcessor) canProcess(input []*MapperValue) bool {
 	// See if the field value is numeric, if it's not, we can't process the derivative
 	validType := false
 	switch input[0].Value.(type) {
-	case int64:
+	case int64, uint64:
 		validType = true
 	case float64:
 		validType = true
@@ -923,12 +968,16 @@ func (rqdp *RawQueryDerivativeProcessor) Process(input []*MapperValue) []*Mapper
 		}
 	}
 
+	// The first value of the first chunk has no derivative. The first value
+	// of later chunks follows the last value of the previous chunk.
+	start := 0
 	if rqdp.LastValueFromPreviousChunk == nil {
 		rqdp.LastValueFromPreviousChunk = input[0]
+		start = 1
 	}
 
 	derivativeValues := []*MapperValue{}
-	for i := 1; i < len(input); i++ {
+	for i := start; i < len(input); i++ {
 		v := input[i]
 
 		// Calculate the derivative of successive points by dividing the difference
@@ -937,13 +986,19 @@ func (rqdp *RawQueryDerivativeProcessor) Process(input []*MapperValue) []*Mapper
 
 		elapsed := v.Time - rqdp.LastValueFromPreviousChunk.Time
 
+		rqdp.LastValueFromPreviousChunk = v
+
+		// Don't smear the change over a gap wider than allowed.
+		if rqdp.MaxGap > 0 && time.Duration(elapsed) > rqdp.MaxGap {
+			derivativeValues = append(derivativeValues, &MapperValue{Time: v.Time})
+			continue
+		}
+
 		value := 0.0
 		if elapsed > 0 {
 			value = diff / (float64(elapsed) / float64(rqdp.DerivativeInterval))
 		}
 
-		rqdp.LastValueFromPreviousChunk = v
-
 		// Drop negative values for non-negative derivatives
 		if rqdp.IsNonNegative && diff < 0 {
 			continue
@@ -993,8 +1048,9 @@ func processForMath(fields influxql.Fields, results [][]interface{}) [][]interfa
 	return mathResults
 }
 
-// ProcessAggregateDerivative returns the derivatives of an aggregate result set
-func ProcessAggregateDerivative(results [][]interface{}, isNonNegative bool, interval time.Duration) [][]interface{} {
+// ProcessAggregateDerivative returns the derivatives of an aggregate result set.
+// The derivative between results farther apart than a non-zero maxGap is null.
+func ProcessAggregateDerivative(results [][]interface{}, isNonNegative bool, interval, maxGap time.Duration) [][]interface{} {
 	// Return early if we can't calculate derivatives
 	if len(results) == 0 {
 		return results
@@ -1012,7 +1068,7 @@ func ProcessAggregateDerivative(results [][]interface{}, isNonNegative bool, int
 	// because derivatives cannot be combined with other aggregates currently.
 	validType := false
 	switch results[0][1].(type) {
-	case int64:
+	case int64, uint64:
 		validType = true
 	case float64:
 		validType = true
@@ -1037,6 +1093,11 @@ func ProcessAggregateDerivative(results [][]interface{}, isNonNegative bool, int
 		}
 
 		elapsed := cur[0].(time.Time).Sub(prev[0].(time.Time))
+		if maxGap > 0 && elapsed > maxGap {
+			derivatives = append(derivatives, []interface{}{cur[0], nil})
+			continue
+		}
+
 		diff := int64toFloat64(cur[1]) - int64toFloat64(prev[1])
 		value := 0.0
 		if elapsed > 0 {
@@ -1073,6 +1134,14 @@ func derivativeInterval(stmt *influxql.SelectStatement) (time.Duration, error) {
 	return time.Second, nil
 }
 
+// derivativeMaxGap returns the max gap for the one (and only) derivative func, or zero if it has none.
+func derivativeMaxGap(stmt *influxql.SelectStatement) time.Duration {
+	if args := stmt.FunctionCalls()[0].Args; len(args) == 3 {
+		return args[2].(*influxql.DurationLiteral).Val
+	}
+	return 0
+}
+
 // resultsEmpty will return true if the all the result values are empty or contain only nulls
 func resultsEmpty(resultValues [][]interface{}) bool {
 	for _, vals := range resultValues {
@@ -1090,10 +1159,12 @@ func int64toFloat64(v interface{}) float64 {
 	switch v.(type) {
 	case int64:
 		return float64(v.(int64))
+	case uint64:
+		return float64(v.(uint64))
 	case float64:
 		return v.(float64)
 	}
-	panic(fmt.Sprintf("expected either int64 or float64, got %v", v))
+	panic(fmt.Sprintf("expected either int64, uint64 or float64, got %v", v))
 }
 
 type int64arr []int64
@@ -6,6 +6,7 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 
@@ -139,7 +140,7 @@ func TestWritePointsAndExecuteTwoShards(t *testing.T) {
 			t.Logf("Skipping test %s", tt.stmt)
 			continue
 		}
-		executor, err := query_executor.PlanSelect(mustParseSelectStatement(tt.stmt), tt.chunkSize)
+		executor, err := query_executor.PlanSelect(mustParseSelectStatement(tt.stmt), tt.chunkSize, nil)
 		if err != nil {
 			t.Fatalf("failed to plan query: %s", err.Error())
 		}
@@ -238,7 +239,7 @@ func TestWritePointsAndExecuteTwoShardsAlign(t *testing.T) {
 			t.Logf("Skipping test %s", tt.stmt)
 			continue
 		}
-		executor, err := query_executor.PlanSelect(mustParseSelectStatement(tt.stmt), tt.chunkSize)
+		executor, err := query_executor.PlanSelect(mustParseSelectStatement(tt.stmt), tt.chunkSize, nil)
 		if err != nil {
 			t.Fatalf("failed to plan query: %s", err.Error())
 		}
@@ -296,6 +297,10 @@ func TestWritePointsAndExecuteTwoShardsQueryRewrite(t *testing.T) {
 			stmt:     `SELECT * FROM cpu GROUP BY *`,
 			expected: `[{"name":"cpu","tags":{"host":"serverA"},"columns":["time","value1","value2"],"values":[["1970-01-01T00:00:01Z",100,null]]},{"name":"cpu","tags":{"host":"serverB"},"columns":["time","value1","value2"],"values":[["1970-01-01T00:00:02Z",null,200]]}]`,
 		},
+		{
+			stmt:     `SELECT *::field FROM cpu`,
+			expected: `[{"name":"cpu","columns":["time","value1","value2"],"values":[["1970-01-01T00:00:01Z",100,null],["1970-01-01T00:00:02Z",null,200]]}]`,
+		},
 	}
 	for _, tt := range tests {
 		if tt.skip {
@@ -325,6 +330,121 @@ func TestWritePointsAndExecuteTwoShardsQueryRewrite(t *testing.T) {
 	}
 }
 
+// Test to ensure points duplicated across overlapping shards are returned once.
+func TestWritePointsAndExecuteTwoShardsDuplicatePoints(t *testing.T) {
+	store0 := testStore()
+	defer os.RemoveAll(store0.Path())
+	store1 := testStore()
+	defer os.RemoveAll(store1.Path())
+
+	database := "foo"
+	retentionPolicy := "bar"
+	store0.CreateShard(database, retentionPolicy, sID0)
+	store1.CreateShard(database, retentionPolicy, sID1)
+
+	// Write the same point to both shards, with a different value in the later shard.
+	for _, w := range []struct {
+		store   *tsdb.Store
+		shardID uint64
+		host    string
+		value   float64
+		sec     int64
+	}{
+		{store0, sID0, "serverA", 100, 1},
+		{store0, sID0, "serverB", 300, 2},
+		{store1, sID1, "serverA", 200, 1},
+	} {
+		if err := w.store.WriteToShard(w.shardID, []tsdb.Point{tsdb.NewPoint(
+			"cpu",
+			map[string]string{"host": w.host},
+			map[string]interface{}{"value": w.value},
+			time.Unix(w.sec, 0).UTC(),
+		)}); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	var tests = []struct {
+		stmt     string
+		expected string
+	}{
+		{
+			stmt:     `SELECT value FROM cpu`,
+			expected: `[{"name":"cpu","columns":["time","value"],"values":[["1970-01-01T00:00:01Z",200],["1970-01-01T00:00:02Z",300]]}]`,
+		},
+		{
+			stmt:     `SELECT value FROM cpu GROUP BY host`,
+			expected: `[{"name":"cpu","tags":{"host":"serverA"},"columns":["time","value"],"values":[["1970-01-01T00:00:01Z",200]]},{"name":"cpu","tags":{"host":"serverB"},"columns":["time","value"],"values":[["1970-01-01T00:00:02Z",300]]}]`,
+		},
+	}
+	for _, tt := range tests {
+		stmt := mustParseSelectStatement(tt.stmt)
+
+		mapper0, err := store0.CreateMapper(sID0, stmt, 0)
+		if err != nil {
+			t.Fatalf("failed to create mapper0: %s", err.Error())
+		}
+		mapper1, err := store1.CreateMapper(sID1, stmt, 0)
+		if err != nil {
+			t.Fatalf("failed to create mapper1: %s", err.Error())
+		}
+		executor := tsdb.NewSelectExecutor(stmt, []tsdb.Mapper{mapper0, mapper1}, 0)
+
+		if got := executeAndGetResults(executor); got != tt.expected {
+			t.Fatalf("Test %s\nexp: %s\ngot: %s\n", tt.stmt, tt.expected, got)
+		}
+	}
+}
+
+// Test that unsigned values beyond the range of int64 are queried and
+// aggregated without losing precision.
+func TestWritePointsAndExecuteUnsigned(t *testing.T) {
+	store := testStore()
+	defer os.RemoveAll(store.Path())
+	store.CreateShard("foo", "bar", sID0)
+
+	for i, v := range []uint64{18446744073709551000, 615} {
+		if err := store.WriteToShard(sID0, []tsdb.Point{tsdb.NewPoint(
+			"cpu",
+			map[string]string{"host": "serverA"},
+			map[string]interface{}{"value": v},
+			time.Unix(int64(i+1), 0).UTC(),
+		)}); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	var tests = []struct {
+		stmt     string
+		expected string
+	}{
+		{
+			stmt:     `SELECT value FROM cpu WHERE value > 1000`,
+			expected: `[{"name":"cpu","columns":["time","value"],"values":[["1970-01-01T00:00:01Z",18446744073709551000]]}]`,
+		},
+		{
+			stmt:     `SELECT sum(value),max(value),min(value) FROM cpu`,
+			expected: `[{"name":"cpu","columns":["time","sum","max","min"],"values":[["1970-01-01T00:00:00Z",18446744073709551615,18446744073709551000,615]]}]`,
+		},
+		{
+			stmt:     `SELECT spread(value) FROM cpu`,
+			expected: `[{"name":"cpu","columns":["time","spread"],"values":[["1970-01-01T00:00:00Z",18446744073709550385]]}]`,
+		},
+	}
+	for _, tt := range tests {
+		stmt := mustParseSelectStatement(tt.stmt)
+		mapper, err := store.CreateMapper(sID0, stmt, 0)
+		if err != nil {
+			t.Fatalf("failed to create mapper: %s", err.Error())
+		}
+		executor := tsdb.NewSelectExecutor(stmt, []tsdb.Mapper{mapper}, 0)
+
+		if got := executeAndGetResults(executor); got != tt.expected {
+			t.Fatalf("Test %s\nexp: %s\ngot: %s\n", tt.stmt, tt.expected, got)
+		}
+	}
+}
+
 // Test that executor correctly orders data across shards when the tagsets
 // are not presented in alphabetically order across shards.
 func TestWritePointsAndExecuteTwoShardsTagSetOrdering(t *testing.T) {
@@ -421,7 +541,7 @@ func TestWritePointsAndExecuteTwoShardsTagSetOrdering(t *testing.T) {
 			t.Logf("Skipping test %s", tt.stmt)
 			continue
 		}
-		executor, err := query_executor.PlanSelect(mustParseSelectStatement(tt.stmt), tt.chunkSize)
+		executor, err := query_executor.PlanSelect(mustParseSelectStatement(tt.stmt), tt.chunkSize, nil)
 		if err != nil {
 			t.Fatalf("failed to plan query: %s", err.Error())
 		}
@@ -720,7 +840,7 @@ func TestProcessAggregateDerivative(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		got := tsdb.ProcessAggregateDerivative(test.in, test.fn == "non_negative_derivative", test.interval)
+		got := tsdb.ProcessAggregateDerivative(test.in, test.fn == "non_negative_derivative", test.interval, 0)
 
 		if len(got) != len(test.exp) {
 			t.Fatalf("ProcessAggregateDerivative(%s) - %s\nlen mismatch: got %d, exp %d", test.fn, test.name, len(got), len(test.exp))
@@ -1002,6 +1122,63 @@ func TestProcessRawQueryDerivative(t *testing.T) {
 	}
 }
 
+// Ensure the derivative across a gap wider than the max gap is null.
+func TestProcessAggregateDerivative_MaxGap(t *testing.T) {
+	in := [][]interface{}{
+		{time.Unix(0, 0), 1.0},
+		{time.Unix(0, 0).Add(time.Minute), 2.0},
+		{time.Unix(0, 0).Add(time.Hour), 10.0},
+		{time.Unix(0, 0).Add(time.Hour + time.Minute), 8.0},
+	}
+	exp := [][]interface{}{
+		{time.Unix(0, 0).Add(time.Minute), 1.0},
+		{time.Unix(0, 0).Add(time.Hour), nil},
+		{time.Unix(0, 0).Add(time.Hour + time.Minute), -2.0},
+	}
+	if got := tsdb.ProcessAggregateDerivative(in, false, time.Minute, 10*time.Minute); !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected derivatives:\ngot %v\nexp %v", got, exp)
+	}
+
+	// Negative changes are still dropped for non-negative derivatives.
+	exp = [][]interface{}{
+		{time.Unix(0, 0).Add(time.Minute), 1.0},
+		{time.Unix(0, 0).Add(time.Hour), nil},
+	}
+	if got := tsdb.ProcessAggregateDerivative(in, true, time.Minute, 10*time.Minute); !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected non-negative derivatives:\ngot %v\nexp %v", got, exp)
+	}
+}
+
+// Ensure the raw derivative across a gap wider than the max gap is null,
+// including between chunks.
+func TestProcessRawQueryDerivative_MaxGap(t *testing.T) {
+	p := tsdb.RawQueryDerivativeProcessor{DerivativeInterval: time.Minute, MaxGap: 10 * time.Minute}
+	got := p.Process([]*tsdb.MapperValue{
+		{Time: 0, Value: 1.0},
+		{Time: int64(time.Minute), Value: 2.0},
+		{Time: int64(time.Hour), Value: 10.0},
+	})
+	got = append(got, p.Process([]*tsdb.MapperValue{
+		{Time: int64(time.Hour + time.Minute), Value: 12.0},
+		{Time: int64(2 * time.Hour), Value: 13.0},
+	})...)
+
+	exp := []*tsdb.MapperValue{
+		{Time: int64(time.Minute), Value: 1.0},
+		{Time: int64(time.Hour)},
+		{Time: int64(time.Hour + time.Minute), Value: 2.0},
+		{Time: int64(2 * time.Hour)},
+	}
+	if len(got) != len(exp) {
+		t.Fatalf("len mismatch: got %d, exp %d", len(got), len(exp))
+	}
+	for i := range exp {
+		if !reflect.DeepEqual(got[i], exp[i]) {
+			t.Fatalf("%d. unexpected derivative: got %v, exp %v", i, *got[i], *exp[i])
+		}
+	}
+}
+
 type testQEMetastore struct {
 	sgFunc func(database, policy string, min, max time.Time) (a []meta.ShardGroupInfo, err error)
 }
@@ -1021,6 +1198,9 @@ func (t *testQEMetastore) RetentionPolicy(database, name string) (rpi *meta.Rete
 	return nil, nil
 }
 func (t *testQEMetastore) UserCount() (int, error) { return 0, nil }
+func (t *testQEMetastore) CreateShardGroupIfNotExists(database, policy string, timestamp time.Time) (*meta.ShardGroupInfo, error) {
+	return nil, nil
+}
 
 func (t *testQEMetastore) NodeID() uint64 { return nID }
 
@@ -1054,7 +1234,7 @@ type testQEShardMapper struct {
 	store *tsdb.Store
 }
 
-func (t *testQEShardMapper) CreateMapper(shard meta.ShardInfo, stmt influxql.Statement, chunkSize int) (tsdb.Mapper, error) {
+func (t *testQEShardMapper) CreateMapper(shard meta.ShardInfo, stmt influxql.Statement, chunkSize int, opt *tsdb.ReadOptions) (tsdb.Mapper, error) {
 	return t.store.CreateMapper(shard.ID, stmt, chunkSize)
 }
 
@@ -0,0 +1,895 @@
+// Generated by tmpl
+// https://github.com/benbjohnson/tmpl
+//
+// DO NOT EDIT!
+// Source: functions.gen.go.tmpl
+
+package tsdb
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"math"
+
+	"github.com/influxdb/influxdb/influxql"
+)
+
+// typedMapFuncs holds the typed mapping functions of an aggregate call, one per
+// value type. A nil function means the call has no typed mapper for the type.
+type typedMapFuncs struct {
+	float   floatMapFunc
+	integer integerMapFunc
+	boolean booleanMapFunc
+	string  stringMapFunc
+}
+
+// initializeTypedMapFuncs returns the typed mapping functions of an aggregate call.
+func initializeTypedMapFuncs(c *influxql.Call) typedMapFuncs {
+	// Only calls directly over a field have typed mappers.
+	if _, ok := c.Args[0].(*influxql.VarRef); !ok {
+		return typedMapFuncs{}
+	}
+
+	return typedMapFuncs{
+		float:   initializeFloatMapFunc(c),
+		integer: initializeIntegerMapFunc(c),
+		boolean: initializeBooleanMapFunc(c),
+		string:  initializeStringMapFunc(c),
+	}
+}
+
+// call runs the typed mapping function for values of typ over the cursor. It returns
+// false if the call has no typed mapper for typ.
+func (fns typedMapFuncs) call(typ influxql.DataType, c *typedTagSetCursor) (interface{}, bool) {
+	switch typ {
+	case influxql.Float:
+		if fns.float != nil {
+			return fns.float(c), true
+		}
+	case influxql.Integer:
+		if fns.integer != nil {
+			return fns.integer(c), true
+		}
+	case influxql.Boolean:
+		if fns.boolean != nil {
+			return fns.boolean(c), true
+		}
+	case influxql.String:
+		if fns.string != nil {
+			return fns.string(c), true
+		}
+	}
+	return nil, false
+}
+
+// floatIterator represents a forward-only iterator over float64 values.
+type floatIterator interface {
+	NextFloat() (time int64, value float64)
+	Tags() map[string]string
+	TMin() int64
+}
+
+// floatMapFunc represents a function used for mapping over float64 values.
+type floatMapFunc func(floatIterator) interface{}
+
+// initializeFloatMapFunc returns the float mapFunc of an aggregate call, or nil.
+func initializeFloatMapFunc(c *influxql.Call) floatMapFunc {
+	switch c.Name {
+	case "count":
+		return MapFloatCount
+	case "first":
+		return MapFloatFirst
+	case "last":
+		return MapFloatLast
+	case "sum":
+		return MapFloatSum
+	case "mean":
+		return MapFloatMean
+	case "median", "stddev":
+		return MapFloatStddev
+	case "min":
+		return MapFloatMin
+	case "max":
+		return MapFloatMax
+	case "spread":
+		return MapFloatSpread
+	}
+	return nil
+}
+
+// decodeFloatByID scans a byte slice for the float field with the given ID and
+// returns its value.
+func (f *FieldCodec) decodeFloatByID(targetID uint8, b []byte) (float64, error) {
+	field, b, err := f.lookupByID(targetID, b)
+	if err != nil {
+		return 0, err
+	} else if field.Type != influxql.Float {
+		return 0, ErrFieldTypeConflict
+	}
+	return math.Float64frombits(binary.BigEndian.Uint64(b[1:9])), nil
+}
+
+// NextFloat returns the next float64 value of the field with the given ID for the
+// tagset. Values are only boxed when a WHERE clause on fields has to be matched.
+func (tsc *tagSetCursor) NextFloat(tmin, tmax int64, fieldID uint8, selectFields, whereFields []string) (int64, float64) {
+	for {
+		// If we're out of points, we're done.
+		if tsc.pointHeap.Len() == 0 {
+			return -1, 0
+		}
+
+		// Grab the next point with the lowest timestamp.
+		p := heap.Pop(tsc.pointHeap).(*pointHeapItem)
+
+		// We're done if the point is outside the query's time range [tmin:tmax).
+		if p.timestamp != tmin && (p.timestamp < tmin || p.timestamp >= tmax) {
+			return -1, 0
+		}
+
+		// Decode the raw point.
+		var value float64
+		var ok bool
+		if p.cursor.filter != nil {
+			value, ok = tsc.decodeRawPoint(p, selectFields, whereFields).(float64)
+		} else {
+			v, err := tsc.decoder.decodeFloatByID(fieldID, p.value)
+			value, ok = v, err == nil
+		}
+		timestamp := p.timestamp
+
+		// Keep track of the current tags for the series cursor so we can
+		// respond with them if asked
+		tsc.currentTags = p.cursor.tags
+
+		// Advance the cursor
+		nextKey, nextVal := p.cursor.Next()
+		if nextKey != -1 {
+			*p = pointHeapItem{
+				timestamp: nextKey,
+				value:     nextVal,
+				cursor:    p.cursor,
+			}
+			heap.Push(tsc.pointHeap, p)
+		}
+
+		// Value didn't match, look for the next one.
+		if !ok {
+			continue
+		}
+
+		return timestamp, value
+	}
+}
+
+// NextFloat returns the next float64 value of the interval.
+func (c *typedTagSetCursor) NextFloat() (int64, float64) {
+	return c.tsc.NextFloat(c.tmin, c.tmax, c.fieldID, c.selectFields, c.whereFields)
+}
+
+// MapFloatCount computes the number of values in an iterator.
+func MapFloatCount(itr floatIterator) interface{} {
+	n := float64(0)
+	for k, _ := itr.NextFloat(); k != -1; k, _ = itr.NextFloat() {
+		n++
+	}
+	if n > 0 {
+		return n
+	}
+	return nil
+}
+
+// MapFloatFirst collects the values to pass to the reducer
+// This function assumes time ordered input
+func MapFloatFirst(itr floatIterator) interface{} {
+	k, v := itr.NextFloat()
+	if k == -1 {
+		return nil
+	}
+	nextk, nextv := itr.NextFloat()
+	for nextk == k {
+		if floatGreaterThan(nextv, v) {
+			v = nextv
+		}
+		nextk, nextv = itr.NextFloat()
+	}
+	return &firstLastMapOutput{k, v}
+}
+
+// MapFloatLast collects the values to pass to the reducer
+func MapFloatLast(itr floatIterator) interface{} {
+	var time int64
+	var val float64
+	pointsYielded := false
+
+	for k, v := itr.NextFloat(); k != -1; k, v = itr.NextFloat() {
+		// Initialize last
+		if !pointsYielded {
+			time = k
+			val = v
+			pointsYielded = true
+		}
+		if k > time {
+			time = k
+			val = v
+		} else if k == time && floatGreaterThan(v, val) {
+			val = v
+		}
+	}
+	if pointsYielded {
+		return &firstLastMapOutput{time, val}
+	}
+	return nil
+}
+
+// floatGreaterThan is greaterThan for float64 values.
+func floatGreaterThan(a, b float64) bool {
+	return a > b
+}
+
+// MapFloatSum computes the summation of values in an iterator.
+func MapFloatSum(itr floatIterator) interface{} {
+	n := float64(0)
+	count := 0
+	for k, v := itr.NextFloat(); k != -1; k, v = itr.NextFloat() {
+		count++
+		n += float64(v)
+	}
+	if count > 0 {
+		return float64(n)
+	}
+	return nil
+}
+
+// MapFloatMean computes the count and sum of values in an iterator to be combined by the reducer.
+func MapFloatMean(itr floatIterator) interface{} {
+	out := &meanMapOutput{ResultType: Float64Type}
+
+	for k, v := itr.NextFloat(); k != -1; k, v = itr.NextFloat() {
+		out.Count++
+		out.Mean += (float64(v) - out.Mean) / float64(out.Count)
+	}
+
+	if out.Count > 0 {
+		return out
+	}
+
+	return nil
+}
+
+// MapFloatMin collects the values to pass to the reducer
+func MapFloatMin(itr floatIterator) interface{} {
+	min := &minMaxMapOut{Type: Float64Type}
+
+	pointsYielded := false
+	for k, v := itr.NextFloat(); k != -1; k, v = itr.NextFloat() {
+		// Initialize min
+		if !pointsYielded {
+			min.Val = float64(v)
+			pointsYielded = true
+		}
+		min.Val = math.Min(min.Val, float64(v))
+	}
+	if pointsYielded {
+		return min
+	}
+	return nil
+}
+
+// MapFloatMax collects the values to pass to the reducer
+func MapFloatMax(itr floatIterator) interface{} {
+	max := &minMaxMapOut{Type: Float64Type}
+
+	pointsYielded := false
+	for k, v := itr.NextFloat(); k != -1; k, v = itr.NextFloat() {
+		// Initialize max
+		if !pointsYielded {
+			max.Val = float64(v)
+			pointsYielded = true
+		}
+		max.Val = math.Max(max.Val, float64(v))
+	}
+	if pointsYielded {
+		return max
+	}
+	return nil
+}
+
+// MapFloatSpread collects the values to pass to the reducer
+func MapFloatSpread(itr floatIterator) interface{} {
+	out := &spreadMapOutput{Type: Float64Type}
+
+	pointsYielded := false
+	for k, v := itr.NextFloat(); k != -1; k, v = itr.NextFloat() {
+		// Initialize
+		if !pointsYielded {
+			out.Max = float64(v)
+			out.Min = float64(v)
+			pointsYielded = true
+		}
+		out.Max = math.Max(out.Max, float64(v))
+		out.Min = math.Min(out.Min, float64(v))
+	}
+	if pointsYielded {
+		return out
+	}
+	return nil
+}
+
+// MapFloatStddev collects the values to pass to the reducer
+func MapFloatStddev(itr floatIterator) interface{} {
+	var values []float64
+
+	for k, v := itr.NextFloat(); k != -1; k, v = itr.NextFloat() {
+		values = append(values, float64(v))
+	}
+
+	return values
+}
+
+// integerIterator represents a forward-only iterator over int64 values.
+type integerIterator interface {
+	NextInteger() (time int64, value int64)
+	Tags() map[string]string
+	TMin() int64
+}
+
+// integerMapFunc represents a function used for mapping over int64 values.
+type integerMapFunc func(integerIterator) interface{}
+
+// initializeIntegerMapFunc returns the integer mapFunc of an aggregate call, or nil.
+func initializeIntegerMapFunc(c *influxql.Call) integerMapFunc {
+	switch c.Name {
+	case "count":
+		return MapIntegerCount
+	case "first":
+		return MapIntegerFirst
+	case "last":
+		return MapIntegerLast
+	case "sum":
+		return MapIntegerSum
+	case "mean":
+		return MapIntegerMean
+	case "median", "stddev":
+		return MapIntegerStddev
+	case "min":
+		return MapIntegerMin
+	case "max":
+		return MapIntegerMax
+	case "spread":
+		return MapIntegerSpread
+	}
+	return nil
+}
+
+// decodeIntegerByID scans a byte slice for the integer field with the given ID and
+// returns its value.
+func (f *FieldCodec) decodeIntegerByID(targetID uint8, b []byte) (int64, error) {
+	field, b, err := f.lookupByID(targetID, b)
+	if err != nil {
+		return 0, err
+	} else if field.Type != influxql.Integer {
+		return 0, ErrFieldTypeConflict
+	}
+	return int64(binary.BigEndian.Uint64(b[1:9])), nil
+}
+
+// NextInteger returns the next int64 value of the field with the given ID for the
+// tagset. Values are only boxed when a WHERE clause on fields has to be matched.
+func (tsc *tagSetCursor) NextInteger(tmin, tmax int64, fieldID uint8, selectFields, whereFields []string) (int64, int64) {
+	for {
+		// If we're out of points, we're done.
+		if tsc.pointHeap.Len() == 0 {
+			return -1, 0
+		}
+
+		// Grab the next point with the lowest timestamp.
+		p := heap.Pop(tsc.pointHeap).(*pointHeapItem)
+
+		// We're done if the point is outside the query's time range [tmin:tmax).
+		if p.timestamp != tmin && (p.timestamp < tmin || p.timestamp >= tmax) {
+			return -1, 0
+		}
+
+		// Decode the raw point.
+		var value int64
+		var ok bool
+		if p.cursor.filter != nil {
+			value, ok = tsc.decodeRawPoint(p, selectFields, whereFields).(int64)
+		} else {
+			v, err := tsc.decoder.decodeIntegerByID(fieldID, p.value)
+			value, ok = v, err == nil
+		}
+		timestamp := p.timestamp
+
+		// Keep track of the current tags for the series cursor so we can
+		// respond with them if asked
+		tsc.currentTags = p.cursor.tags
+
+		// Advance the cursor
+		nextKey, nextVal := p.cursor.Next()
+		if nextKey != -1 {
+			*p = pointHeapItem{
+				timestamp: nextKey,
+				value:     nextVal,
+				cursor:    p.cursor,
+			}
+			heap.Push(tsc.pointHeap, p)
+		}
+
+		// Value didn't match, look for the next one.
+		if !ok {
+			continue
+		}
+
+		return timestamp, value
+	}
+}
+
+// NextInteger returns the next int64 value of the interval.
+func (c *typedTagSetCursor) NextInteger() (int64, int64) {
+	return c.tsc.NextInteger(c.tmin, c.tmax, c.fieldID, c.selectFields, c.whereFields)
+}
+
+// MapIntegerCount computes the number of values in an iterator.
+func MapIntegerCount(itr integerIterator) interface{} {
+	n := float64(0)
+	for k, _ := itr.NextInteger(); k != -1; k, _ = itr.NextInteger() {
+		n++
+	}
+	if n > 0 {
+		return n
+	}
+	return nil
+}
+
+// MapIntegerFirst collects the values to pass to the reducer
+// This function assumes time ordered input
+func MapIntegerFirst(itr integerIterator) interface{} {
+	k, v := itr.NextInteger()
+	if k == -1 {
+		return nil
+	}
+	nextk, nextv := itr.NextInteger()
+	for nextk == k {
+		if integerGreaterThan(nextv, v) {
+			v = nextv
+		}
+		nextk, nextv = itr.NextInteger()
+	}
+	return &firstLastMapOutput{k, v}
+}
+
+// MapIntegerLast collects the values to pass to the reducer
+func MapIntegerLast(itr integerIterator) interface{} {
+	var time int64
+	var val int64
+	pointsYielded := false
+
+	for k, v := itr.NextInteger(); k != -1; k, v = itr.NextInteger() {
+		// Initialize last
+		if !pointsYielded {
+			time = k
+			val = v
+			pointsYielded = true
+		}
+		if k > time {
+			time = k
+			val = v
+		} else if k == time && integerGreaterThan(v, val) {
+			val = v
+		}
+	}
+	if pointsYielded {
+		return &firstLastMapOutput{time, val}
+	}
+	return nil
+}
+
+// integerGreaterThan is greaterThan for int64 values.
+func integerGreaterThan(a, b int64) bool {
+	return a > b
+}
+
+// MapIntegerSum computes the summation of values in an iterator.
+func MapIntegerSum(itr integerIterator) interface{} {
+	n := float64(0)
+	count := 0
+	for k, v := itr.NextInteger(); k != -1; k, v = itr.NextInteger() {
+		count++
+		n += float64(v)
+	}
+	if count > 0 {
+		return int64(n)
+	}
+	return nil
+}
+
+// MapIntegerMean computes the count and sum of values in an iterator to be combined by the reducer.
+func MapIntegerMean(itr integerIterator) interface{} {
+	out := &meanMapOutput{ResultType: Int64Type}
+
+	for k, v := itr.NextInteger(); k != -1; k, v = itr.NextInteger() {
+		out.Count++
+		out.Mean += (float64(v) - out.Mean) / float64(out.Count)
+	}
+
+	if out.Count > 0 {
+		return out
+	}
+
+	return nil
+}
+
+// MapIntegerMin collects the values to pass to the reducer
+func MapIntegerMin(itr integerIterator) interface{} {
+	min := &minMaxMapOut{Type: Int64Type}
+
+	pointsYielded := false
+	for k, v := itr.NextInteger(); k != -1; k, v = itr.NextInteger() {
+		// Initialize min
+		if !pointsYielded {
+			min.Val = float64(v)
+			pointsYielded = true
+		}
+		min.Val = math.Min(min.Val, float64(v))
+	}
+	if pointsYielded {
+		return min
+	}
+	return nil
+}
+
+// MapIntegerMax collects the values to pass to the reducer
+func MapIntegerMax(itr integerIterator) interface{} {
+	max := &minMaxMapOut{Type: Int64Type}
+
+	pointsYielded := false
+	for k, v := itr.NextInteger(); k != -1; k, v = itr.NextInteger() {
+		// Initialize max
+		if !pointsYielded {
+			max.Val = float64(v)
+			pointsYielded = true
+		}
+		max.Val = math.Max(max.Val, float64(v))
+	}
+	if pointsYielded {
+		return max
+	}
+	return nil
+}
+
+// MapIntegerSpread collects the values to pass to the reducer
+func MapIntegerSpread(itr integerIterator) interface{} {
+	out := &spreadMapOutput{Type: Int64Type}
+
+	pointsYielded := false
+	for k, v := itr.NextInteger(); k != -1; k, v = itr.NextInteger() {
+		// Initialize
+		if !pointsYielded {
+			out.Max = float64(v)
+			out.Min = float64(v)
+			pointsYielded = true
+		}
+		out.Max = math.Max(out.Max, float64(v))
+		out.Min = math.Min(out.Min, float64(v))
+	}
+	if pointsYielded {
+		return out
+	}
+	return nil
+}
+
+// MapIntegerStddev collects the values to pass to the reducer
+func MapIntegerStddev(itr integerIterator) interface{} {
+	var values []float64
+
+	for k, v := itr.NextInteger(); k != -1; k, v = itr.NextInteger() {
+		values = append(values, float64(v))
+	}
+
+	return values
+}
+
+// booleanIterator represents a forward-only iterator over bool values.
+type booleanIterator interface {
+	NextBoolean() (time int64, value bool)
+	Tags() map[string]string
+	TMin() int64
+}
+
+// booleanMapFunc represents a function used for mapping over bool values.
+type booleanMapFunc func(booleanIterator) interface{}
+
+// initializeBooleanMapFunc returns the boolean mapFunc of an aggregate call, or nil.
+func initializeBooleanMapFunc(c *influxql.Call) booleanMapFunc {
+	switch c.Name {
+	case "count":
+		return MapBooleanCount
+	case "first":
+		return MapBooleanFirst
+	case "last":
+		return MapBooleanLast
+	}
+	return nil
+}
+
+// decodeBooleanByID scans a byte slice for the boolean field with the given ID and
+// returns its value.
+func (f *FieldCodec) decodeBooleanByID(targetID uint8, b []byte) (bool, error) {
+	field, b, err := f.lookupByID(targetID, b)
+	if err != nil {
+		return false, err
+	} else if field.Type != influxql.Boolean {
+		return false, ErrFieldTypeConflict
+	}
+	return b[1] == 1, nil
+}
+
+// NextBoolean returns the next bool value of the field with the given ID for the
+// tagset. Values are only boxed when a WHERE clause on fields has to be matched.
+func (tsc *tagSetCursor) NextBoolean(tmin, tmax int64, fieldID uint8, selectFields, whereFields []string) (int64, bool) {
+	for {
+		// If we're out of points, we're done.
+		if tsc.pointHeap.Len() == 0 {
+			return -1, false
+		}
+
+		// Grab the next point with the lowest timestamp.
+		p := heap.Pop(tsc.pointHeap).(*pointHeapItem)
+
+		// We're done if the point is outside the query's time range [tmin:tmax).
+		if p.timestamp != tmin && (p.timestamp < tmin || p.timestamp >= tmax) {
+			return -1, false
+		}
+
+		// Decode the raw point.
+		var value bool
+		var ok bool
+		if p.cursor.filter != nil {
+			value, ok = tsc.decodeRawPoint(p, selectFields, whereFields).(bool)
+		} else {
+			v, err := tsc.decoder.decodeBooleanByID(fieldID, p.value)
+			value, ok = v, err == nil
+		}
+		timestamp := p.timestamp
+
+		// Keep track of the current tags for the series cursor so we can
+		// respond with them if asked
+		tsc.currentTags = p.cursor.tags
+
+		// Advance the cursor
+		nextKey, nextVal := p.cursor.Next()
+		if nextKey != -1 {
+			*p = pointHeapItem{
+				timestamp: nextKey,
+				value:     nextVal,
+				cursor:    p.cursor,
+			}
+			heap.Push(tsc.pointHeap, p)
+		}
+
+		// Value didn't match, look for the next one.
+		if !ok {
+			continue
+		}
+
+		return timestamp, value
+	}
+}
+
+// NextBoolean returns the next bool value of the interval.
+func (c *typedTagSetCursor) NextBoolean() (int64, bool) {
+	return c.tsc.NextBoolean(c.tmin, c.tmax, c.fieldID, c.selectFields, c.whereFields)
+}
+
+// MapBooleanCount computes the number of values in an iterator.
+func MapBooleanCount(itr booleanIterator) interface{} {
+	n := float64(0)
+	for k, _ := itr.NextBoolean(); k != -1; k, _ = itr.NextBoolean() {
+		n++
+	}
+	if n > 0 {
+		return n
+	}
+	return nil
+}
+
+// MapBooleanFirst collects the values to pass to the reducer
+// This function assumes time ordered input
+func MapBooleanFirst(itr booleanIterator) interface{} {
+	k, v := itr.NextBoolean()
+	if k == -1 {
+		return nil
+	}
+	nextk, nextv := itr.NextBoolean()
+	for nextk == k {
+		if booleanGreaterThan(nextv, v) {
+			v = nextv
+		}
+		nextk, nextv = itr.NextBoolean()
+	}
+	return &firstLastMapOutput{k, v}
+}
+
+// MapBooleanLast collects the values to pass to the reducer
+func MapBooleanLast(itr booleanIterator) interface{} {
+	var time int64
+	var val bool
+	pointsYielded := false
+
+	for k, v := itr.NextBoolean(); k != -1; k, v = itr.NextBoolean() {
+		// Initialize last
+		if !pointsYielded {
+			time = k
+			val = v
+			pointsYielded = true
+		}
+		if k > time {
+			time = k
+			val = v
+		} else if k == time && booleanGreaterThan(v, val) {
+			val = v
+		}
+	}
+	if pointsYielded {
+		return &firstLastMapOutput{time, val}
+	}
+	return nil
+}
+
+// booleanGreaterThan is greaterThan for bool values.
+func booleanGreaterThan(a, b bool) bool {
+	return a
+}
+
+// stringIterator represents a forward-only iterator over string values.
+type stringIterator interface {
+	NextString() (time int64, value string)
+	Tags() map[string]string
+	TMin() int64
+}
+
+// stringMapFunc represents a function used for mapping over string values.
+type stringMapFunc func(stringIterator) interface{}
+
+// initializeStringMapFunc returns the string mapFunc of an aggregate call, or nil.
+func initializeStringMapFunc(c *influxql.Call) stringMapFunc {
+	switch c.Name {
+	case "count":
+		return MapStringCount
+	case "first":
+		return MapStringFirst
+	case "last":
+		return MapStringLast
+	}
+	return nil
+}
+
+// decodeStringByID scans a byte slice for the string field with the given ID and
+// returns its value.
+func (f *FieldCodec) decodeStringByID(targetID uint8, b []byte) (string, error) {
+	field, b, err := f.lookupByID(targetID, b)
+	if err != nil {
+		return "", err
+	} else if field.Type != influxql.String {
+		return "", ErrFieldTypeConflict
+	}
+	return string(b[3 : 3+int(binary.BigEndian.Uint16(b[1:3]))]), nil
+}
+
+// NextString returns the next string value of the field with the given ID for the
+// tagset. Values are only boxed when a WHERE clause on fields has to be matched.
+func (tsc *tagSetCursor) NextString(tmin, tmax int64, fieldID uint8, selectFields, whereFields []string) (int64, string) {
+	for {
+		// If we're out of points, we're done.
+		if tsc.pointHeap.Len() == 0 {
+			return -1, ""
+		}
+
+		// Grab the next point with the lowest timestamp.
+		p := heap.Pop(tsc.pointHeap).(*pointHeapItem)
+
+		// We're done if the point is outside the query's time range [tmin:tmax).
+		if p.timestamp != tmin && (p.timestamp < tmin || p.timestamp >= tmax) {
+			return -1, ""
+		}
+
+		// Decode the raw point.
+		var value string
+		var ok bool
+		if p.cursor.filter != nil {
+			value, ok = tsc.decodeRawPoint(p, selectFields, whereFields).(string)
+		} else {
+			v, err := tsc.decoder.decodeStringByID(fieldID, p.value)
+			value, ok = v, err == nil
+		}
+		timestamp := p.timestamp
+
+		// Keep track of the current tags for the series cursor so we can
+		// respond with them if asked
+		tsc.currentTags = p.cursor.tags
+
+		// Advance the cursor
+		nextKey, nextVal := p.cursor.Next()
+		if nextKey != -1 {
+			*p = pointHeapItem{
+				timestamp: nextKey,
+				value:     nextVal,
+				cursor:    p.cursor,
+			}
+			heap.Push(tsc.pointHeap, p)
+		}
+
+		// Value didn't match, look for the next one.
+		if !ok {
+			continue
+		}
+
+		return timestamp, value
+	}
+}
+
+// NextString returns the next string value of the interval.
+func (c *typedTagSetCursor) NextString() (int64, string) {
+	return c.tsc.NextString(c.tmin, c.tmax, c.fieldID, c.selectFields, c.whereFields)
+}
+
+// MapStringCount computes the number of values in an iterator.
+func MapStringCount(itr stringIterator) interface{} {
+	n := float64(0)
+	for k, _ := itr.NextString(); k != -1; k, _ = itr.NextString() {
+		n++
+	}
+	if n > 0 {
+		return n
+	}
+	return nil
+}
+
+// MapStringFirst collects the values to pass to the reducer
+// This function assumes time ordered input
+func MapStringFirst(itr stringIterator) interface{} {
+	k, v := itr.NextString()
+	if k == -1 {
+		return nil
+	}
+	nextk, nextv := itr.NextString()
+	for nextk == k {
+		if stringGreaterThan(nextv, v) {
+			v = nextv
+		}
+		nextk, nextv = itr.NextString()
+	}
+	return &firstLastMapOutput{k, v}
+}
+
+// MapStringLast collects the values to pass to the reducer
+func MapStringLast(itr stringIterator) interface{} {
+	var time int64
+	var val string
+	pointsYielded := false
+
+	for k, v := itr.NextString(); k != -1; k, v = itr.NextString() {
+		// Initialize last
+		if !pointsYielded {
+			time = k
+			val = v
+			pointsYielded = true
+		}
+		if k > time {
+			time = k
+			val = v
+		} else if k == time && stringGreaterThan(v, val) {
+			val = v
+		}
+	}
+	if pointsYielded {
+		return &firstLastMapOutput{time, val}
+	}
+	return nil
+}
+
+// stringGreaterThan is greaterThan for string values.
+func stringGreaterThan(a, b string) bool {
+	return a > b
+}
@@ -5,6 +5,11 @@ package tsdb
 // paradigm popularized by Google and Hadoop.
 //
 // When adding an aggregate function, define a mapper, a reducer, and add them in the switch statement in the MapreduceFuncs function
+//
+// Aggregates over fields of a single type also have typed mappers, generated from functions.gen.go.tmpl,
+// which read values without boxing them in an interface{}. Their output is the same as the generic mapper's.
+
+//go:generate tmpl -data=@functions.gen.go.tmpldata functions.gen.go.tmpl
 
 import (
 	"encoding/json"
@@ -76,6 +81,17 @@ func initializeMapFunc(c *influxql.Call) (mapFunc, error) {
 	case "last":
 		return MapLast, nil
 	case "top":
+		// If the arg is an aggregate e.g. top(mean(value), host, 5), then
+		// map the nested aggregate for each set of tag values
+		if fn, ok := c.Args[0].(*influxql.Call); ok {
+			mapFn, err := initializeMapFunc(fn)
+			if err != nil {
+				return nil, err
+			}
+			return func(itr iterator) interface{} {
+				return MapTopAggregate(itr, c, mapFn)
+			}, nil
+		}
 		return func(itr iterator) interface{} {
 			return MapTop(itr, c)
 		}, nil
@@ -128,6 +144,15 @@ func initializeReduceFunc(c *influxql.Call) (reduceFunc, error) {
 	case "last":
 		return ReduceLast, nil
 	case "top":
+		if fn, ok := c.Args[0].(*influxql.Call); ok {
+			reduceFn, err := initializeReduceFunc(fn)
+			if err != nil {
+				return nil, err
+			}
+			return func(values []interface{}) interface{} {
+				return ReduceTopAggregate(values, c, reduceFn)
+			}, nil
+		}
 		return func(values []interface{}) interface{} {
 			return ReduceTop(values, c)
 		}, nil
@@ -326,11 +351,15 @@ type NumberType int8
 const (
 	Float64Type NumberType = iota
 	Int64Type
+	Uint64Type
 )
 
-// MapSum computes the summation of values in an iterator.
+// MapSum computes the summation of values in an iterator. Unsigned sums which
+// overflow are promoted to floats.
 func MapSum(itr iterator) interface{} {
 	n := float64(0)
+	var u uint64 // unsigned values are summed exactly unless they overflow
+	var overflow bool
 	count := 0
 	var resultType NumberType
 	for k, v := itr.Next(); k != -1; k, v = itr.Next() {
@@ -341,6 +370,11 @@ func MapSum(itr iterator) interface{} {
 		case int64:
 			n += float64(n1)
 			resultType = Int64Type
+		case uint64:
+			n += float64(n1)
+			overflow = overflow || u+n1 < u
+			u += n1
+			resultType = Uint64Type
 		}
 	}
 	if count > 0 {
@@ -349,14 +383,22 @@ func MapSum(itr iterator) interface{} {
 			return n
 		case Int64Type:
 			return int64(n)
+		case Uint64Type:
+			if overflow {
+				return n
+			}
+			return u
 		}
 	}
 	return nil
 }
 
-// ReduceSum computes the sum of values for each key.
+// ReduceSum computes the sum of values for each key. Unsigned sums which
+// overflow, here or in a mapper, are promoted to floats.
 func ReduceSum(values []interface{}) interface{} {
 	var n float64
+	var u uint64
+	var overflow, float bool
 	count := 0
 	var resultType NumberType
 	for _, v := range values {
@@ -367,9 +409,15 @@ func ReduceSum(values []interface{}) interface{} {
 		switch n1 := v.(type) {
 		case float64:
 			n += n1
+			float = true
 		case int64:
 			n += float64(n1)
 			resultType = Int64Type
+		case uint64:
+			n += float64(n1)
+			overflow = overflow || u+n1 < u
+			u += n1
+			resultType = Uint64Type
 		}
 	}
 	if count > 0 {
@@ -378,6 +426,11 @@ func ReduceSum(values []interface{}) interface{} {
 			return n
 		case Int64Type:
 			return int64(n)
+		case Uint64Type:
+			if overflow || float {
+				return n
+			}
+			return u
 		}
 	}
 	return nil
@@ -395,6 +448,9 @@ func MapMean(itr iterator) interface{} {
 		case int64:
 			out.Mean += (float64(n1) - out.Mean) / float64(out.Count)
 			out.ResultType = Int64Type
+		case uint64:
+			out.Mean += (float64(n1) - out.Mean) / float64(out.Count)
+			out.ResultType = Uint64Type
 		}
 	}
 
@@ -586,6 +642,7 @@ func partition(data []float64) (lows []float64, pivotValue float64, highs []floa
 
 type minMaxMapOut struct {
 	Val  float64
+	UVal uint64 // set instead of Val for unsigned values
 	Type NumberType
 }
 
@@ -603,6 +660,11 @@ func MapMin(itr iterator) interface{} {
 		case int64:
 			val = float64(n)
 			min.Type = Int64Type
+		case uint64:
+			if !pointsYielded || n < min.UVal {
+				min.UVal = n
+			}
+			min.Type = Uint64Type
 		}
 
 		// Initialize min
@@ -636,10 +698,14 @@ func ReduceMin(values []interface{}) interface{} {
 		// Initialize min
 		if !pointsYielded {
 			min.Val = v.Val
+			min.UVal = v.UVal
 			min.Type = v.Type
 			pointsYielded = true
 		}
 		min.Val = math.Min(min.Val, v.Val)
+		if v.Type == Uint64Type && v.UVal < min.UVal {
+			min.UVal = v.UVal
+		}
 	}
 	if pointsYielded {
 		switch min.Type {
@@ -647,6 +713,8 @@ func ReduceMin(values []interface{}) interface{} {
 			return min.Val
 		case Int64Type:
 			return int64(min.Val)
+		case Uint64Type:
+			return min.UVal
 		}
 	}
 	return nil
@@ -666,6 +734,11 @@ func MapMax(itr iterator) interface{} {
 		case int64:
 			val = float64(n)
 			max.Type = Int64Type
+		case uint64:
+			if !pointsYielded || n > max.UVal {
+				max.UVal = n
+			}
+			max.Type = Uint64Type
 		}
 
 		// Initialize max
@@ -699,10 +772,14 @@ func ReduceMax(values []interface{}) interface{} {
 		// Initialize max
 		if !pointsYielded {
 			max.Val = v.Val
+			max.UVal = v.UVal
 			max.Type = v.Type
 			pointsYielded = true
 		}
 		max.Val = math.Max(max.Val, v.Val)
+		if v.Type == Uint64Type && v.UVal > max.UVal {
+			max.UVal = v.UVal
+		}
 	}
 	if pointsYielded {
 		switch max.Type {
@@ -710,14 +787,17 @@ func ReduceMax(values []interface{}) interface{} {
 			return max.Val
 		case Int64Type:
 			return int64(max.Val)
+		case Uint64Type:
+			return max.UVal
 		}
 	}
 	return nil
 }
 
 type spreadMapOutput struct {
-	Min, Max float64
-	Type     NumberType
+	Min, Max   float64
+	UMin, UMax uint64 // set instead of Min and Max for unsigned values
+	Type       NumberType
 }
 
 // MapSpread collects the values to pass to the reducer
@@ -733,6 +813,14 @@ func MapSpread(itr iterator) interface{} {
 		case int64:
 			val = float64(n)
 			out.Type = Int64Type
+		case uint64:
+			if !pointsYielded || n > out.UMax {
+				out.UMax = n
+			}
+			if !pointsYielded || n < out.UMin {
+				out.UMin = n
+			}
+			out.Type = Uint64Type
 		}
 
 		// Initialize
@@ -764,11 +852,19 @@ func ReduceSpread(values []interface{}) interface{} {
 		if !pointsYielded {
 			result.Max = val.Max
 			result.Min = val.Min
+			result.UMax = val.UMax
+			result.UMin = val.UMin
 			result.Type = val.Type
 			pointsYielded = true
 		}
 		result.Max = math.Max(result.Max, val.Max)
 		result.Min = math.Min(result.Min, val.Min)
+		if val.Type == Uint64Type && val.UMax > result.UMax {
+			result.UMax = val.UMax
+		}
+		if val.Type == Uint64Type && val.UMin < result.UMin {
+			result.UMin = val.UMin
+		}
 	}
 	if pointsYielded {
 		switch result.Type {
@@ -776,6 +872,8 @@ func ReduceSpread(values []interface{}) interface{} {
 			return result.Max - result.Min
 		case Int64Type:
 			return int64(result.Max - result.Min)
+		case Uint64Type:
+			return result.UMax - result.UMin
 		}
 	}
 	return nil
@@ -791,6 +889,8 @@ func MapStddev(itr iterator) interface{} {
 			values = append(values, n)
 		case int64:
 			values = append(values, float64(n))
+		case uint64:
+			values = append(values, float64(n))
 		}
 	}
 
@@ -1349,6 +1449,116 @@ func ReduceTop(values []interface{}, c *influxql.Call) interface{} {
 	return nil
 }
 
+// topAggregate is the mapped nested aggregate of a top() call for one set of
+// the call's tag values.
+type topAggregate struct {
+	Time  int64
+	Tags  map[string]string
+	Value interface{}
+}
+
+// topAggregates are the mapped nested aggregates of a top() call, keyed by tag values.
+type topAggregates map[string]*topAggregate
+
+// bufIterator is an iterator over buffered points sharing the same tags.
+type bufIterator struct {
+	times  []int64
+	values []interface{}
+	tags   map[string]string
+	tmin   int64
+}
+
+func (itr *bufIterator) Next() (int64, interface{}) {
+	if len(itr.times) == 0 {
+		return -1, nil
+	}
+	k, v := itr.times[0], itr.values[0]
+	itr.times, itr.values = itr.times[1:], itr.values[1:]
+	return k, v
+}
+
+func (itr *bufIterator) Tags() map[string]string { return itr.tags }
+func (itr *bufIterator) TMin() int64             { return itr.tmin }
+
+// MapTopAggregate maps the nested aggregate of a top() call, e.g. the mean in
+// top(mean(value), host, 5), separately for each set of the call's tag values.
+func MapTopAggregate(itr iterator, c *influxql.Call, fn mapFunc) interface{} {
+	callArgs := topCallArgs(c)
+
+	// Group the points by the values of the call's tags.
+	groups := make(map[string]*bufIterator)
+	for k, v := itr.Next(); k != -1; k, v = itr.Next() {
+		tags := itr.Tags()
+		key := ""
+		for _, a := range callArgs {
+			key += a + ":" + tags[a] + ","
+		}
+
+		g, ok := groups[key]
+		if !ok {
+			g = &bufIterator{tags: make(map[string]string), tmin: itr.TMin()}
+			for _, a := range callArgs {
+				if v, ok := tags[a]; ok {
+					g.tags[a] = v
+				}
+			}
+			groups[key] = g
+		}
+		g.times = append(g.times, k)
+		g.values = append(g.values, v)
+	}
+
+	out := make(topAggregates)
+	for key, g := range groups {
+		t := g.tmin
+		if t < 0 {
+			t = 0
+		}
+		if v := fn(g); v != nil {
+			out[key] = &topAggregate{Time: t, Tags: g.tags, Value: v}
+		}
+	}
+	if len(out) > 0 {
+		return out
+	}
+	return nil
+}
+
+// ReduceTopAggregate reduces the nested aggregate of a top() call for each set
+// of tag values and returns the tag values with the top aggregates.
+func ReduceTopAggregate(values []interface{}, c *influxql.Call, fn reduceFunc) interface{} {
+	lit, _ := c.Args[len(c.Args)-1].(*influxql.NumberLiteral)
+	limit := int64(lit.Val)
+
+	// Collect the mapped aggregates of each set of tag values from all mappers.
+	mapped := make(map[string][]interface{})
+	aggs := make(map[string]*topAggregate)
+	for _, v := range values {
+		m, _ := v.(topAggregates)
+		for key, a := range m {
+			mapped[key] = append(mapped[key], a.Value)
+			aggs[key] = a
+		}
+	}
+
+	out := positionOut{callArgs: topCallArgs(c)}
+	for key, vals := range mapped {
+		if v := fn(vals); v != nil {
+			out.points = append(out.points, PositionPoint{aggs[key].Time, v, aggs[key].Tags})
+		}
+	}
+
+	// Keep the top aggregates, highest first.
+	sort.Sort(topMapOut{out})
+	if int64(len(out.points)) > limit {
+		out.points = out.points[:limit]
+	}
+	if len(out.points) > 0 {
+		return out.points
+	}
+	return nil
+}
+
 // MapEcho emits the data points for each group by interval
 func MapEcho(itr iterator) interface{} {
 	var values []interface{}
@@ -1378,6 +1588,8 @@ func ReducePercentile(values []interface{}, c *influxql.Call) interface{} {
 			switch v.(type) {
 			case int64:
 				allValues = append(allValues, float64(v.(int64)))
+			case uint64:
+				allValues = append(allValues, float64(v.(uint64)))
 			case float64:
 				allValues = append(allValues, v.(float64))
 			}
@@ -1434,6 +1646,8 @@ func greaterThan(a, b interface{}) bool {
 	switch t := a.(type) {
 	case int64:
 		return t > b.(int64)
+	case uint64:
+		return t > b.(uint64)
 	case float64:
 		return t > b.(float64)
 	case string:
@@ -1,6 +1,8 @@
 package tsdb
 
 import (
+	"container/heap"
+	"math"
 	"reflect"
 	"testing"
 	"time"
@@ -54,6 +56,102 @@ func (t *testIterator) TMin() int64 {
 	return -1
 }
 
+func (t *testIterator) NextFloat() (int64, float64) {
+	k, v := t.Next()
+	if k == -1 {
+		return -1, 0
+	}
+	return k, v.(float64)
+}
+
+func (t *testIterator) NextInteger() (int64, int64) {
+	k, v := t.Next()
+	if k == -1 {
+		return -1, 0
+	}
+	return k, v.(int64)
+}
+
+func (t *testIterator) NextBoolean() (int64, bool) {
+	k, v := t.Next()
+	if k == -1 {
+		return -1, false
+	}
+	return k, v.(bool)
+}
+
+func (t *testIterator) NextString() (int64, string) {
+	k, v := t.Next()
+	if k == -1 {
+		return -1, ""
+	}
+	return k, v.(string)
+}
+
+// Ensure the typed map functions have the same output as the generic ones.
+func TestTypedMapFuncs(t *testing.T) {
+	inputs := map[influxql.DataType][]testPoint{
+		influxql.Float:   {{"0", 1, 2.5, nil}, {"0", 1, 3.5, nil}, {"0", 2, -1.0, nil}, {"0", 3, 8.0, nil}, {"0", 3, 1.0, nil}},
+		influxql.Integer: {{"0", 1, int64(2), nil}, {"0", 1, int64(3), nil}, {"0", 2, int64(-1), nil}, {"0", 3, int64(8), nil}, {"0", 3, int64(1), nil}},
+		influxql.Boolean: {{"0", 1, false, nil}, {"0", 1, true, nil}, {"0", 2, false, nil}, {"0", 3, false, nil}, {"0", 3, true, nil}},
+		influxql.String:  {{"0", 1, "b", nil}, {"0", 1, "c", nil}, {"0", 2, "a", nil}, {"0", 3, "a", nil}, {"0", 3, "b", nil}},
+	}
+
+	for _, name := range []string{"count", "first", "last", "sum", "mean", "median", "stddev", "min", "max", "spread"} {
+		c := &influxql.Call{Name: name, Args: []influxql.Expr{&influxql.VarRef{Val: "value"}}}
+		mapFn, err := initializeMapFunc(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fns := initializeTypedMapFuncs(c)
+
+		for typ, input := range inputs {
+			for _, points := range [][]testPoint{input, nil} {
+				exp := mapFn(&testIterator{values: points})
+
+				itr := &testIterator{values: points}
+				var got interface{}
+				var ok bool
+				switch typ {
+				case influxql.Float:
+					if ok = fns.float != nil; ok {
+						got = fns.float(itr)
+					}
+				case influxql.Integer:
+					if ok = fns.integer != nil; ok {
+						got = fns.integer(itr)
+					}
+				case influxql.Boolean:
+					if ok = fns.boolean != nil; ok {
+						got = fns.boolean(itr)
+					}
+				case influxql.String:
+					if ok = fns.string != nil; ok {
+						got = fns.string(itr)
+					}
+				}
+
+				// Numeric aggregates only have typed mappers for numbers.
+				if numeric := typ == influxql.Float || typ == influxql.Integer; ok != (numeric || !IsNumeric(c)) {
+					t.Fatalf("%s(%s): unexpected typed mapper: %v", name, typ, ok)
+				} else if ok && !reflect.DeepEqual(got, exp) {
+					t.Fatalf("%s(%s): output mismatch:\n\nexp=%s\n\ngot=%s\n\n", name, typ, spew.Sdump(exp), spew.Sdump(got))
+				}
+			}
+		}
+	}
+
+	// Nested and distinct calls use the generic map functions.
+	for _, s := range []string{`count(distinct(value))`, `derivative(mean(value))`} {
+		expr, err := influxql.ParseExpr(s)
+		if err != nil {
+			t.Fatal(err)
+		} else if fns := initializeTypedMapFuncs(expr.(*influxql.Call)); fns.float != nil || fns.integer != nil || fns.boolean != nil || fns.string != nil {
+			t.Fatalf("%s: unexpected typed mappers", s)
+		}
+	}
+}
+
 func TestMapMeanNoValues(t *testing.T) {
 	iter := &testIterator{}
 	if got := MapMean(iter); got != nil {
@@ -96,6 +194,41 @@ func TestMapMean(t *testing.T) {
 	}
 }
 
+// Ensure unsigned values are summed and compared without converting them to floats.
+func TestMapReduceUnsigned(t *testing.T) {
+	values := func() *testIterator {
+		return &testIterator{values: []testPoint{
+			{"0", 1, uint64(18446744073709551000), nil},
+			{"0", 2, uint64(18446744073709551001), nil},
+		}}
+	}
+
+	sum := &testIterator{values: []testPoint{
+		{"0", 1, uint64(18446744073709551000), nil},
+		{"0", 2, uint64(1), nil},
+	}}
+	if got := ReduceSum([]interface{}{MapSum(sum), uint64(614)}); got != uint64(18446744073709551615) {
+		t.Errorf("unexpected sum: %v", got)
+	}
+
+	// Sums which overflow are promoted to floats, in the mapper or the reducer.
+	if got, ok := ReduceSum([]interface{}{MapSum(values()), uint64(614)}).(float64); !ok || math.Abs(got-3.6893488147419103e19) > 1e5 {
+		t.Errorf("unexpected overflowed sum: %v", got)
+	}
+	if got, ok := ReduceSum([]interface{}{uint64(18446744073709551000), uint64(18446744073709551001)}).(float64); !ok || math.Abs(got-3.6893488147419103e19) > 1e5 {
+		t.Errorf("unexpected overflowed sum: %v", got)
+	}
+	if got := ReduceMax([]interface{}{MapMax(values()), nil}); got != uint64(18446744073709551001) {
+		t.Errorf("unexpected max: %v", got)
+	}
+	if got := ReduceMin([]interface{}{MapMin(values())}); got != uint64(18446744073709551000) {
+		t.Errorf("unexpected min: %v", got)
+	}
+	if got := ReduceSpread([]interface{}{MapSpread(values())}); got != uint64(1) {
+		t.Errorf("unexpected spread: %v", got)
+	}
+}
+
 func TestInitializeMapFuncDerivative(t *testing.T) {
 
 	for _, fn := range []string{"derivative", "non_negative_derivative"} {
@@ -752,3 +885,119 @@ func TestReduceTop(t *testing.T) {
 		}
 	}
 }
+
+func TestMapReduceTopAggregate(t *testing.T) {
+	call := &influxql.Call{Name: "top", Args: []influxql.Expr{
+		&influxql.Call{Name: "mean", Args: []influxql.Expr{&influxql.VarRef{Val: "field1"}}},
+		&influxql.VarRef{Val: "host"},
+		&influxql.NumberLiteral{Val: 2},
+	}}
+	mapFn, err := initializeMapFunc(call)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reduceFn, err := initializeReduceFunc(call)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each host's mean is computed across mappers before selecting the top hosts.
+	tmin := func() int64 { return 10 }
+	values := []interface{}{
+		mapFn(&testIterator{tMinFunc: tmin, values: []testPoint{
+			{"", 10, 10.0, map[string]string{"host": "a", "region": "east"}},
+			{"", 11, 5.0, map[string]string{"host": "b", "region": "east"}},
+			{"", 12, 30.0, map[string]string{"host": "c", "region": "east"}},
+			{"", 13, 20.0, map[string]string{"host": "a", "region": "west"}},
+		}}),
+		mapFn(&testIterator{tMinFunc: tmin, values: []testPoint{
+			{"", 10, 30.0, map[string]string{"host": "a", "region": "west"}},
+			{"", 11, 100.0, map[string]string{"host": "b", "region": "west"}},
+			{"", 12, 0.0, map[string]string{"host": "c", "region": "west"}},
+		}}),
+		nil,
+	}
+
+	exp := PositionPoints{
+		{10, 52.5, map[string]string{"host": "b"}},
+		{10, 20.0, map[string]string{"host": "a"}},
+	}
+	if got := reduceFn(values); !reflect.DeepEqual(got, exp) {
+		t.Errorf("Wrong values. \nexp\n %v\ngot\n %v", spew.Sdump(exp), spew.Sdump(got))
+	}
+
+	// No points map to nothing.
+	if got := mapFn(&testIterator{}); got != nil {
+		t.Errorf("unexpected map output: %v", got)
+	} else if got := reduceFn([]interface{}{nil}); got != nil {
+		t.Errorf("unexpected reduce output: %v", got)
+	}
+}
+
+// sliceCursor is a cursor over encoded points held in memory.
+type sliceCursor struct {
+	keys, values [][]byte
+	i            int
+}
+
+func (c *sliceCursor) Seek(seek []byte) (key, value []byte) {
+	c.i = sort.Search(len(c.keys), func(i int) bool { return string(c.keys[i]) >= string(seek) })
+	return c.Next()
+}
+
+func (c *sliceCursor) Next() (key, value []byte) {
+	if c.i >= len(c.keys) {
+		return nil, nil
+	}
+	c.i++
+	return c.keys[c.i-1], c.values[c.i-1]
+}
+
+func (c *sliceCursor) Direction() Direction { return Forward }
+
+func BenchmarkMapMean_Generic(b *testing.B) { benchmarkMapMean(b, false) }
+func BenchmarkMapMean_Typed(b *testing.B)   { benchmarkMapMean(b, true) }
+
+// benchmarkMapMean benchmarks the mean of 10K float values of two fields read
+// through a tagset cursor with the generic or the typed map function.
+func benchmarkMapMean(b *testing.B, typed bool) {
+	mf := &MeasurementFields{Fields: make(map[string]*Field)}
+	mf.CreateFieldIfNotExists("value", influxql.Float)
+	mf.CreateFieldIfNotExists("other", influxql.Float)
+
+	cur := &sliceCursor{}
+	for i := 0; i < 10000; i++ {
+		v, err := mf.Codec.EncodeFields(map[string]interface{}{"value": float64(i), "other": 1.0})
+		if err != nil {
+			b.Fatal(err)
+		}
+		cur.keys = append(cur.keys, u64tob(uint64(i)))
+		cur.values = append(cur.values, v)
+	}
+	sc := newSeriesCursor(cur, nil, nil)
+	tsc := newTagSetCursor("cpu", nil, []*seriesCursor{sc}, mf.Codec)
+	tminf := func() int64 { return -1 }
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sc.seekto = -1
+		k, v := sc.SeekTo(0)
+		tsc.pointHeap = newPointHeap(Forward)
+		heap.Push(tsc.pointHeap, &pointHeapItem{timestamp: k, value: v, cursor: sc})
+
+		var out interface{}
+		if typed {
+			out = MapFloatMean(&typedTagSetCursor{tsc: tsc, tmin: 0, tmax: 10000, fieldID: 1, selectFields: []string{"value"}, tMinFunc: tminf})
+		} else {
+			out = MapMean(&aggTagSetCursor{
+				nextFunc: func() (int64, interface{}) { return tsc.Next(0, 10000, []string{"value"}, nil) },
+				tagsFunc: tsc.Tags,
+				tMinFunc: tminf,
+			})
+		}
+		if out.(*meanMapOutput).Count != 10000 {
+			b.Fatalf("unexpected output: %v", out)
+		}
+	}
+}
@@ -24,6 +24,13 @@ type MapperValue struct {
 
 type MapperValues []*MapperValue
 
+// MapperStats are the statistics of the data read by a mapper.
+type MapperStats struct {
+	SeriesN   int64 // Series read.
+	PointN    int64 // Points read.
+	CacheHitN int64 // Points read from the in-memory cache of the engine.
+}
+
 func (a MapperValues) Len() int           { return len(a) }
 func (a MapperValues) Less(i, j int) bool { return a[i].Time < a[j].Time }
 func (a MapperValues) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
@@ -56,6 +63,11 @@ type SelectMapper struct {
 	selectTags      []string        // tag keys that occur in the select clause
 	cursors         []*tagSetCursor // Cursors per tag sets.
 	currCursorIndex int             // Current tagset cursor being drained.
+	currCursorN     int             // Values emitted for the current tagset cursor.
+	limit           int             // Maximum values emitted per tagset in raw mode. Zero is unlimited.
+	seriesN         int64           // Series with a cursor.
+	pointN          int64           // Points read from the cursors.
+	tagSets         *TagSetCache    // Tags of the values from a remote mapper.
 
 	// The following attributes are only used when mappers are for aggregate queries.
 
@@ -65,6 +77,11 @@ type SelectMapper struct {
 	currInterval    int       // Current interval for which data is being fetched.
 	mapFuncs        []mapFunc // The mapping functions.
 	fieldNames      []string  // the field name being read for mapping.
+	tagKeys         []string  // the tag key of each distinct() call of a tag, if any.
+
+	// The typed mapping functions, used instead of the generic ones for
+	// fields of a type they support.
+	typedMapFuncs []typedMapFuncs
 }
 
 // NewSelectMapper returns a mapper for the given shard, which will return data for the SELECT statement.
@@ -82,6 +99,15 @@ func (lm *SelectMapper) openMeta() error {
 	return errors.New("not implemented")
 }
 
+// accessPattern returns how the statement reads the shard. Raw queries with a
+// LIMIT read a few points of each series, other queries scan each series.
+func (lm *SelectMapper) accessPattern() AccessPattern {
+	if lm.rawMode && lm.selectStmt.Limit > 0 {
+		return RandomAccess
+	}
+	return SequentialAccess
+}
+
 func (lm *SelectMapper) timeDirection() Direction {
 	if len(lm.selectStmt.SortFields) > 0 {
 		if lm.selectStmt.SortFields[0].Ascending {
@@ -120,6 +146,18 @@ func (lm *SelectMapper) Open() error {
 			}
 			lm.selectStmt = stmt
 			lm.rawMode = (s.IsRawQuery && !s.HasDistinct()) || s.IsSimpleDerivative()
+
+			// The executor emits at most LIMIT values per tagset after
+			// skipping OFFSET values, so no more are ever needed from a shard.
+			if lm.rawMode && stmt.Limit > 0 {
+				lm.limit = stmt.Limit + stmt.Offset
+			}
+
+			// Hint how the shard will be read. Hints are best effort so
+			// errors are ignored.
+			if a, ok := lm.tx.(AccessAdvisor); ok {
+				a.Advise(lm.accessPattern())
+			}
 		} else {
 			return lm.openMeta()
 		}
@@ -204,9 +242,10 @@ func (lm *SelectMapper) Open() error {
 			selectFields.add(tsf.selectFields...)
 			selectTags.add(tsf.selectTags...)
 			whereFields.add(tsf.whereFields...)
+			lm.setDistinctTagKeys(m)
 
 			// If we only have tags in our select clause we just return
-			if len(selectFields) == 0 && len(selectTags) > 0 {
+			if len(selectFields) == 0 && len(selectTags) > 0 && !lm.hasDistinctTagKeys() {
 				return fmt.Errorf("statement must have at least one field in select clause")
 			}
 
@@ -241,6 +280,11 @@ func (lm *SelectMapper) Open() error {
 				cursors := []*seriesCursor{}
 
 				for i, key := range t.SeriesKeys {
+					// Skip series the shard holds no points for without reading its index.
+					if !lm.shard.MayContainSeries(key) {
+						continue
+					}
+
 					c := lm.tx.Cursor(key, direction)
 					if c == nil {
 						// No data exists for this key.
@@ -248,26 +292,24 @@ func (lm *SelectMapper) Open() error {
 					}
 					seriesTags := lm.shard.index.TagsForSeries(key)
 					cm := newSeriesCursor(c, t.Filters[i], seriesTags)
+					cm.n = &lm.pointN
 					cursors = append(cursors, cm)
+					lm.seriesN++
 				}
 
 				tsc := newTagSetCursor(m.Name, t.Tags, cursors, lm.shard.FieldCodec(m.Name))
 				if lm.rawMode {
-					tsc.pointHeap = newPointHeap()
-					//Prime the buffers.
+					tsc.pointHeap = newPointHeap(direction)
+
+					// Prime the buffers by seeking each cursor to its first point in
+					// the time range [queryTMin:queryTMax), so the points before it
+					// in the scan's direction are never read.
+					seek := lm.queryTMin
+					if direction.Reverse() && lm.queryTMax > lm.queryTMin {
+						seek = lm.queryTMax - 1
+					}
 					for i := 0; i < len(tsc.cursors); i++ {
-						var k int64
-						var v []byte
-						if direction.Forward() {
-							k, v = tsc.cursors[i].SeekTo(lm.queryTMin)
-						} else {
-							k, v = tsc.cursors[i].SeekTo(lm.queryTMax)
-						}
-
-						if k == -1 {
-							k, v = tsc.cursors[i].Next()
-						}
-
+						k, v := tsc.cursors[i].SeekTo(seek)
 						if k == -1 {
 							continue
 						}
@@ -325,6 +367,15 @@ func (lm *SelectMapper) NextChunk() (interface{}, error) {
 			// Mapper on other node sent 0 values so it's done.
 			return nil, nil
 		}
+
+		// Decoding gives every value its own tags, so share them by tag set.
+		if lm.tagSets == nil {
+			lm.tagSets = NewTagSetCache()
+		}
+		mo.Tags = lm.tagSets.Intern(mo.Tags)
+		for _, v := range mo.Values {
+			v.Tags = lm.tagSets.Intern(v.Tags)
+		}
 		return mo, nil
 	}
 
@@ -348,10 +399,16 @@ func (lm *SelectMapper) nextChunkRaw() (interface{}, error) {
 		}
 		cursor := lm.cursors[lm.currCursorIndex]
 
-		k, v := cursor.Next(lm.queryTMin, lm.queryTMax, lm.selectFields, lm.whereFields)
+		// Stop reading the tagset cursor once it reached the limit.
+		var k int64
+		var v interface{}
+		if lm.limit == 0 || lm.currCursorN < lm.limit {
+			k, v = cursor.Next(lm.queryTMin, lm.queryTMax, lm.selectFields, lm.whereFields)
+		}
 		if v == nil {
 			// Tagset cursor is empty, move to next one.
 			lm.currCursorIndex++
+			lm.currCursorN = 0
 			if output != nil {
 				// There is data, so return it and continue when next called.
 				return output, nil
@@ -370,6 +427,7 @@ func (lm *SelectMapper) nextChunkRaw() (interface{}, error) {
 		}
 		value := &MapperValue{Time: k, Value: v, Tags: cursor.Tags()}
 		output.Values = append(output.Values, value)
+		lm.currCursorN++
 		if len(output.Values) == lm.chunkSize {
 			return output, nil
 		}
@@ -426,8 +484,15 @@ func (lm *SelectMapper) nextChunkAgg() (interface{}, error) {
 			qmax = lm.queryTMax + 1
 		}
 
-		tsc.pointHeap = newPointHeap()
+		tsc.pointHeap = newPointHeap(Forward)
 		for i := range lm.mapFuncs {
+			// The distinct values of a tag only depend on which series have data.
+			if lm.tagKeys != nil && lm.tagKeys[i] != "" {
+				values := output.Values[0].Value.([]interface{})
+				output.Values[0].Value = append(values, mapDistinctTagValues(tsc, lm.tagKeys[i], qmin, qmax))
+				continue
+			}
+
 			// Prime the tagset cursor for the start of the interval. This is not ideal, as
 			// it should really calculate the values all in 1 pass, but that would require
 			// changes to the mapper functions, which can come later.
@@ -444,16 +509,6 @@ func (lm *SelectMapper) nextChunkAgg() (interface{}, error) {
 				}
 				heap.Push(tsc.pointHeap, p)
 			}
-			// Wrap the tagset cursor so it implements the mapping functions interface.
-			nextf := func() (_ int64, value interface{}) {
-				k, v := tsc.Next(qmin, qmax, []string{lm.fieldNames[i]}, lm.whereFields)
-				return k, v
-			}
-
-			tagf := func() map[string]string {
-				return tsc.Tags()
-			}
-
 			tminf := func() int64 {
 				if len(lm.selectStmt.Dimensions) == 0 {
 					return -1
@@ -464,6 +519,35 @@ func (lm *SelectMapper) nextChunkAgg() (interface{}, error) {
 				return -1
 			}
 
+			// Map the values of the field without boxing them if the call has a
+			// typed mapping function for the type of the field.
+			if f := tsc.decoder.fieldByName(lm.fieldNames[i]); f != nil {
+				c := &typedTagSetCursor{
+					tsc:          tsc,
+					tmin:         qmin,
+					tmax:         qmax,
+					fieldID:      f.ID,
+					selectFields: []string{lm.fieldNames[i]},
+					whereFields:  lm.whereFields,
+					tMinFunc:     tminf,
+				}
+				if v, ok := lm.typedMapFuncs[i].call(f.Type, c); ok {
+					values := output.Values[0].Value.([]interface{})
+					output.Values[0].Value = append(values, v)
+					continue
+				}
+			}
+
+			// Wrap the tagset cursor so it implements the mapping functions interface.
+			nextf := func() (_ int64, value interface{}) {
+				k, v := tsc.Next(qmin, qmax, []string{lm.fieldNames[i]}, lm.whereFields)
+				return k, v
+			}
+
+			tagf := func() map[string]string {
+				return tsc.Tags()
+			}
+
 			tagSetCursor := &aggTagSetCursor{
 				nextFunc: nextf,
 				tagsFunc: tagf,
@@ -479,6 +563,76 @@ func (lm *SelectMapper) nextChunkAgg() (interface{}, error) {
 	}
 }
 
+// setDistinctTagKeys records the distinct() calls of the statement over a
+// tag, rather than a field, of m.
+func (lm *SelectMapper) setDistinctTagKeys(m *Measurement) {
+	if lm.rawMode {
+		return
+	}
+	for i, c := range lm.selectStmt.FunctionCalls() {
+		if c.Name != "distinct" || m.HasField(lm.fieldNames[i]) || !m.HasTagKey(lm.fieldNames[i]) {
+			continue
+		}
+		if lm.tagKeys == nil {
+			lm.tagKeys = make([]string, len(lm.mapFuncs))
+		}
+		lm.tagKeys[i] = lm.fieldNames[i]
+	}
+}
+
+// hasDistinctTagKeys returns true if the statement selects the distinct values of a tag.
+func (lm *SelectMapper) hasDistinctTagKeys() bool {
+	for _, k := range lm.tagKeys {
+		if k != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// mapDistinctTagValues returns the distinct values of the tag key of the series
+// in tsc with data in [qmin, qmax). Each series' cursor is only read until it
+// finds a point, so the values of series without data in the range are skipped
+// without scanning the series.
+func mapDistinctTagValues(tsc *tagSetCursor, key string, qmin, qmax int64) interface{} {
+	index := make(map[string]struct{})
+	for _, c := range tsc.cursors {
+		v := c.tags[key]
+		if v == "" {
+			continue
+		} else if _, ok := index[v]; ok {
+			continue
+		}
+		if tsc.seriesHasData(c, qmin, qmax) {
+			index[v] = struct{}{}
+		}
+	}
+
+	if len(index) == 0 {
+		return nil
+	}
+	results := make(interfaceValues, 0, len(index))
+	for v := range index {
+		results = append(results, v)
+	}
+	sort.Sort(results)
+	return results
+}
+
+// seriesHasData returns true if the series of c has a point in [tmin, tmax)
+// matching its WHERE clause.
+func (tsc *tagSetCursor) seriesHasData(c *seriesCursor, tmin, tmax int64) bool {
+	for k, v := c.SeekTo(tmin); k != -1 && k < tmax; k, v = c.Next() {
+		if c.filter == nil {
+			return true
+		}
+		if fields, err := tsc.decoder.DecodeFieldsWithNames(v); err == nil && matchesWhere(c.filter, fields) {
+			return true
+		}
+	}
+	return false
+}
+
 // nextInterval returns the next interval for which to return data. If start is less than 0
 // there are no more intervals.
 func (lm *SelectMapper) nextInterval() (start, end int64) {
@@ -501,12 +655,14 @@ func (lm *SelectMapper) initializeMapFunctions() error {
 	// Set up each mapping function for this statement.
 	aggregates := lm.selectStmt.FunctionCalls()
 	lm.mapFuncs = make([]mapFunc, len(aggregates))
+	lm.typedMapFuncs = make([]typedMapFuncs, len(aggregates))
 	lm.fieldNames = make([]string, len(lm.mapFuncs))
 	for i, c := range aggregates {
 		lm.mapFuncs[i], err = initializeMapFunc(c)
 		if err != nil {
 			return err
 		}
+		lm.typedMapFuncs[i] = initializeTypedMapFuncs(c)
 
 		// Check for calls like `derivative(lmean(value), 1d)`
 		var nested *influxql.Call = c
@@ -551,6 +707,7 @@ func (lm *SelectMapper) rewriteSelectStatement(stmt *influxql.SelectStatement) (
 // If only a `SELECT *` is present, without a `GROUP BY *`, both tags and fields expand in the SELECT
 // If a `SELECT *` and a `GROUP BY *` are both present, then only fiels are expanded in the `SELECT` and only
 // tags are expanded in the `GROUP BY`
+// A `SELECT *::field` or `SELECT *::tag` only expands to the fields or the tags
 func (lm *SelectMapper) expandWildcards(stmt *influxql.SelectStatement) (*influxql.SelectStatement, error) {
 	// If there are no wildcards in the statement, return it as-is.
 	if !stmt.HasWildcard() {
@@ -559,7 +716,7 @@ func (lm *SelectMapper) expandWildcards(stmt *influxql.SelectStatement) (*influx
 	// Use sets to avoid duplicate field names.
 	fieldSet := map[string]struct{}{}
 	dimensionSet := map[string]struct{}{}
-	var fields influxql.Fields
+	var fields, tags influxql.Fields
 	var dimensions influxql.Dimensions
 
 	// keep track of where the wildcards are in the select statement
@@ -585,14 +742,15 @@ func (lm *SelectMapper) expandWildcards(stmt *influxql.SelectStatement) (*influx
 				fields = append(fields, &influxql.Field{Expr: &influxql.VarRef{Val: name}})
 			}
 
-			// Add tags to fields if a field wildcard was provided and a dimension wildcard was not.
-			if hasFieldWildcard && !hasDimensionWildcard {
+			// Add tags if a field wildcard was provided. They are only selected by a
+			// plain wildcard if there's no dimension wildcard.
+			if hasFieldWildcard {
 				for _, t := range mm.TagKeys() {
 					if _, ok := fieldSet[t]; ok {
 						continue
 					}
 					fieldSet[t] = struct{}{}
-					fields = append(fields, &influxql.Field{Expr: &influxql.VarRef{Val: t}})
+					tags = append(tags, &influxql.Field{Expr: &influxql.VarRef{Val: t}})
 				}
 			}
 
@@ -610,7 +768,7 @@ func (lm *SelectMapper) expandWildcards(stmt *influxql.SelectStatement) (*influx
 	}
 
 	// Return a new SelectStatement with the wild cards rewritten.
-	return stmt.RewriteWildcards(fields, dimensions), nil
+	return stmt.RewriteWildcards(fields, tags, dimensions), nil
 }
 
 // TagSets returns the list of TagSets for which this mapper has data.
@@ -630,6 +788,19 @@ func (lm *SelectMapper) Fields() []string {
 	return append(lm.selectFields, lm.selectTags...)
 }
 
+// Stats returns the statistics of the mapper so far. Returns false if the
+// mapper reads from a remote node.
+func (lm *SelectMapper) Stats() (MapperStats, bool) {
+	if lm.remote != nil {
+		return MapperStats{}, false
+	}
+	st := MapperStats{SeriesN: lm.seriesN, PointN: lm.pointN}
+	if tx, ok := lm.tx.(CacheTx); ok {
+		st.CacheHitN = tx.CacheHitN()
+	}
+	return st, true
+}
+
 // Close closes the mapper.
 func (lm *SelectMapper) Close() {
 	if lm.remote != nil {
@@ -665,39 +836,64 @@ func (a *aggTagSetCursor) TMin() int64 {
 	return a.tMinFunc()
 }
 
+// typedTagSetCursor wraps a tagSetCursor, such that the values of a single field it
+// emits over an interval are read by the typed mapping functions.
+type typedTagSetCursor struct {
+	tsc          *tagSetCursor
+	tmin, tmax   int64
+	fieldID      uint8
+	selectFields []string
+	whereFields  []string
+	tMinFunc     func() int64
+}
+
+// Tags returns the current tags for the cursor
+func (c *typedTagSetCursor) Tags() map[string]string { return c.tsc.Tags() }
+
+// TMin returns the current floor time for the bucket being worked on
+func (c *typedTagSetCursor) TMin() int64 { return c.tMinFunc() }
+
 type pointHeapItem struct {
 	timestamp int64
 	value     []byte
 	cursor    *seriesCursor // cursor whence pointHeapItem came
 }
 
-type pointHeap []*pointHeapItem
+// pointHeap orders points by timestamp in the direction of its cursors.
+type pointHeap struct {
+	items     []*pointHeapItem
+	direction Direction
+}
 
-func newPointHeap() *pointHeap {
-	q := make(pointHeap, 0)
-	heap.Init(&q)
-	return &q
+func newPointHeap(direction Direction) *pointHeap {
+	q := &pointHeap{direction: direction}
+	heap.Init(q)
+	return q
 }
 
-func (pq pointHeap) Len() int { return len(pq) }
+func (pq *pointHeap) Len() int { return len(pq.items) }
 
-func (pq pointHeap) Less(i, j int) bool {
-	// We want a min-heap (points in chronological order), so use less than.
-	return pq[i].timestamp < pq[j].timestamp
+func (pq *pointHeap) Less(i, j int) bool {
+	// A forward heap is a min-heap (points in chronological order) and a
+	// reverse heap is a max-heap.
+	if pq.direction.Reverse() {
+		return pq.items[i].timestamp > pq.items[j].timestamp
+	}
+	return pq.items[i].timestamp < pq.items[j].timestamp
 }
 
-func (pq pointHeap) Swap(i, j int) { pq[i], pq[j] = pq[j], pq[i] }
+func (pq *pointHeap) Swap(i, j int) { pq.items[i], pq.items[j] = pq.items[j], pq.items[i] }
 
 func (pq *pointHeap) Push(x interface{}) {
 	item := x.(*pointHeapItem)
-	*pq = append(*pq, item)
+	pq.items = append(pq.items, item)
 }
 
 func (pq *pointHeap) Pop() interface{} {
-	old := *pq
+	old := pq.items
 	n := len(old)
 	item := old[n-1]
-	*pq = old[0 : n-1]
+	pq.items = old[0 : n-1]
 	return item
 }
 
@@ -710,8 +906,8 @@ type tagSetCursor struct {
 	decoder     *FieldCodec       // decoder for the raw data bytes
 	currentTags map[string]string // the current tags for the underlying series cursor in play
 
-	// pointHeap is a min-heap, ordered by timestamp, that contains the next
-	// point from each seriesCursor. Queries sometimes pull points from
+	// pointHeap is a heap, ordered by timestamp in the direction of the
+	// cursors, that contains the next point from each seriesCursor. Queries sometimes pull points from
 	// thousands of series. This makes it reasonably efficient to find the
 	// point with the next lowest timestamp among the thousands of series that
 	// the query is pulling points from.
@@ -747,7 +943,7 @@ func newTagSetCursor(m string, t map[string]string, c []*seriesCursor, d *FieldC
 		tags:        t,
 		cursors:     c,
 		decoder:     d,
-		pointHeap:   newPointHeap(),
+		pointHeap:   newPointHeap(Forward),
 	}
 
 	return tsc
@@ -854,6 +1050,7 @@ type seriesCursor struct {
 	cursor     Cursor // BoltDB cursor for a series
 	filter     influxql.Expr
 	tags       map[string]string
+	n          *int64 // Incremented for each point read, if set.
 	seekto     int64
 	seekResult struct {
 		k int64
@@ -871,9 +1068,10 @@ func newSeriesCursor(cur Cursor, filter influxql.Expr, tags map[string]string) *
 	}
 }
 
-// Seek positions returning the timestamp and value at that key.
+// SeekTo positions the cursor at the first point on or after key, or on or
+// before key for a reverse cursor, returning its timestamp and value.
 func (sc *seriesCursor) SeekTo(key int64) (timestamp int64, value []byte) {
-	if sc.seekto != -1 && sc.seekto < key && (sc.seekResult.k == -1 || sc.seekResult.k >= key) {
+	if sc.seekto != -1 && sc.seekedPast(key) {
 		// we've seeked on this cursor. This seek is after that previous cached seek
 		// and the result it gave was after the key for this seek.
 		//
@@ -886,6 +1084,7 @@ func (sc *seriesCursor) SeekTo(key int64) (timestamp int64, value []byte) {
 		timestamp = -1
 	} else {
 		timestamp, value = int64(btou64(k)), v
+		sc.count()
 	}
 	sc.seekto = key
 	sc.seekResult.k = timestamp
@@ -893,6 +1092,15 @@ func (sc *seriesCursor) SeekTo(key int64) (timestamp int64, value []byte) {
 	return
 }
 
+// seekedPast returns true if the last seek was before key, in the direction
+// of the cursor, and returned a point after key, or none.
+func (sc *seriesCursor) seekedPast(key int64) bool {
+	if sc.cursor.Direction().Reverse() {
+		return sc.seekto > key && (sc.seekResult.k == -1 || sc.seekResult.k <= key)
+	}
+	return sc.seekto < key && (sc.seekResult.k == -1 || sc.seekResult.k >= key)
+}
+
 // Next returns the next timestamp and value from the cursor.
 func (sc *seriesCursor) Next() (key int64, value []byte) {
 	// calling next on this cursor means that we need to invalidate the seek
@@ -904,10 +1112,18 @@ func (sc *seriesCursor) Next() (key int64, value []byte) {
 		key = -1
 	} else {
 		key, value = int64(btou64(k)), v
+		sc.count()
 	}
 	return
 }
 
+// count counts a point read from the cursor.
+func (sc *seriesCursor) count() {
+	if sc.n != nil {
+		*sc.n++
+	}
+}
+
 type tagSetsAndFields struct {
 	tagSets      []*influxql.TagSet
 	selectFields []string
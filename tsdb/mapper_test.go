@@ -197,6 +197,37 @@ func TestShardMapper_WriteAndSingleMapperRawQuerySingleValue(t *testing.T) {
 			stmt:     fmt.Sprintf(`SELECT load FROM cpu WHERE time > '%s'`, pt2time.Format(influxql.DateTimeFormat)),
 			expected: []string{`null`},
 		},
+		{
+			stmt:     `SELECT load FROM cpu ORDER BY time DESC`,
+			expected: []string{`{"name":"cpu","fields":["load"],"values":[{"time":2000000000,"value":60,"tags":{"host":"serverB","region":"us-east"}},{"time":1000000000,"value":42,"tags":{"host":"serverA","region":"us-east"}}]}`, `null`},
+		},
+		{
+			stmt:     fmt.Sprintf(`SELECT load FROM cpu WHERE time < '%s' ORDER BY time DESC`, pt2time.Format(influxql.DateTimeFormat)),
+			expected: []string{`{"name":"cpu","fields":["load"],"values":[{"time":1000000000,"value":42,"tags":{"host":"serverA","region":"us-east"}}]}`, `null`},
+		},
+		{
+			stmt:     fmt.Sprintf(`SELECT load FROM cpu WHERE time > '%s' ORDER BY time DESC`, pt1time.Format(influxql.DateTimeFormat)),
+			expected: []string{`{"name":"cpu","fields":["load"],"values":[{"time":2000000000,"value":60,"tags":{"host":"serverB","region":"us-east"}}]}`, `null`},
+		},
+		{
+			stmt:     `SELECT load FROM cpu LIMIT 1`,
+			expected: []string{`{"name":"cpu","fields":["load"],"values":[{"time":1000000000,"value":42,"tags":{"host":"serverA","region":"us-east"}}]}`, `null`},
+		},
+		{
+			stmt:     `SELECT load FROM cpu LIMIT 1 OFFSET 1`,
+			expected: []string{`{"name":"cpu","fields":["load"],"values":[{"time":1000000000,"value":42,"tags":{"host":"serverA","region":"us-east"}},{"time":2000000000,"value":60,"tags":{"host":"serverB","region":"us-east"}}]}`, `null`},
+		},
+		{
+			stmt:     `SELECT load FROM cpu ORDER BY time DESC LIMIT 1`,
+			expected: []string{`{"name":"cpu","fields":["load"],"values":[{"time":2000000000,"value":60,"tags":{"host":"serverB","region":"us-east"}}]}`, `null`},
+		},
+		{
+			stmt: `SELECT load FROM cpu GROUP BY region LIMIT 1`,
+			expected: []string{
+				`{"name":"cpu","tags":{"region":"us-east"},"fields":["load"],"values":[{"time":1000000000,"value":42,"tags":{"host":"serverA","region":"us-east"}}]}`,
+				`null`,
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -393,6 +424,12 @@ func TestShardMapper_WriteAndSingleMapperAggregateQuery(t *testing.T) {
 				`{"name":"cpu","fields":["value"],"values":[{"value":[60]}]}`,
 				`null`},
 		},
+		{
+			stmt: `SELECT sum(value) FROM cpu WHERE value > 10`,
+			expected: []string{
+				`{"name":"cpu","fields":["value"],"values":[{"value":[60]}]}`,
+				`null`},
+		},
 		{
 			stmt: fmt.Sprintf(`SELECT sum(value) FROM cpu WHERE time = '%s'`, pt1time.Format(influxql.DateTimeFormat)),
 			expected: []string{
@@ -427,6 +464,65 @@ func TestShardMapper_WriteAndSingleMapperAggregateQuery(t *testing.T) {
 	}
 }
 
+// Ensure distinct() of a tag returns the values of the series with data in the range.
+func TestShardMapper_WriteAndSingleMapperAggregateQuery_DistinctTag(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(tmpDir)
+	shard := mustCreateShard(tmpDir)
+
+	pt1time := time.Unix(10, 0).UTC()
+	pt2time := time.Unix(20, 0).UTC()
+	if err := shard.WritePoints([]tsdb.Point{
+		tsdb.NewPoint("cpu", map[string]string{"host": "serverA", "region": "us-east"}, map[string]interface{}{"value": 1}, pt1time),
+		tsdb.NewPoint("cpu", map[string]string{"host": "serverB", "region": "us-east"}, map[string]interface{}{"value": 60}, pt2time),
+		tsdb.NewPoint("cpu", map[string]string{"region": "us-west"}, map[string]interface{}{"value": 30}, pt2time),
+	}); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	var tests = []struct {
+		stmt     string
+		expected []string
+	}{
+		{
+			stmt:     `SELECT distinct(host) FROM cpu`,
+			expected: []string{`{"name":"cpu","values":[{"value":[["serverA","serverB"]]}]}`, `null`},
+		},
+		{
+			stmt:     fmt.Sprintf(`SELECT distinct(host) FROM cpu WHERE time > '%s'`, pt1time.Format(influxql.DateTimeFormat)),
+			expected: []string{`{"name":"cpu","values":[{"time":10000000001,"value":[["serverB"]]}]}`, `null`},
+		},
+		{
+			stmt:     fmt.Sprintf(`SELECT distinct(host) FROM cpu WHERE time > '%s'`, pt2time.Format(influxql.DateTimeFormat)),
+			expected: []string{`{"name":"cpu","values":[{"time":20000000001,"value":[null]}]}`, `null`},
+		},
+		{
+			stmt:     `SELECT distinct(host) FROM cpu WHERE value < 10`,
+			expected: []string{`{"name":"cpu","values":[{"value":[["serverA"]]}]}`, `null`},
+		},
+		{
+			stmt: `SELECT distinct(host) FROM cpu GROUP BY region`,
+			expected: []string{
+				`{"name":"cpu","tags":{"region":"us-east"},"values":[{"value":[["serverA","serverB"]]}]}`,
+				`{"name":"cpu","tags":{"region":"us-west"},"values":[{"value":[null]}]}`,
+				`null`},
+		},
+	}
+
+	for _, tt := range tests {
+		stmt := mustParseSelectStatement(tt.stmt)
+		mapper := openSelectMapperOrFail(t, shard, stmt)
+
+		for i := range tt.expected {
+			got := aggIntervalAsJson(t, mapper)
+			if got != tt.expected[i] {
+				t.Fatalf("test '%s'\n\tgot      %s\n\texpected %s", tt.stmt, got, tt.expected[i])
+				break
+			}
+		}
+	}
+}
+
 func TestShardMapper_SelectMapperTagSetsFields(t *testing.T) {
 	tmpDir, _ := ioutil.TempDir("", "shard_test")
 	defer os.RemoveAll(tmpDir)
@@ -576,3 +672,100 @@ func aggIntervalAsJson(t *testing.T, mapper *tsdb.SelectMapper) string {
 	}
 	return string(b)
 }
+
+func BenchmarkSelectMapper_Raw_Ascending_LongSeries(b *testing.B) {
+	benchmarkSelectMapperRaw(b, "ASC")
+}
+
+func BenchmarkSelectMapper_Raw_Descending_LongSeries(b *testing.B) {
+	benchmarkSelectMapperRaw(b, "DESC")
+}
+
+// benchmarkSelectMapperRaw benchmarks reading the last minute of a series
+// with a day of points, one per second, in the order of the direction.
+func benchmarkSelectMapperRaw(b *testing.B, direction string) {
+	tmpDir, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(tmpDir)
+	shard := mustCreateShard(tmpDir)
+	defer shard.Close()
+
+	end := time.Unix(86400, 0).UTC()
+	points := make([]tsdb.Point, 0, 86400)
+	for t := time.Unix(0, 0).UTC(); t.Before(end); t = t.Add(time.Second) {
+		points = append(points, tsdb.NewPoint("cpu", map[string]string{"host": "serverA"}, map[string]interface{}{"value": 1.0}, t))
+	}
+	if err := shard.WritePoints(points); err != nil {
+		b.Fatal(err)
+	}
+
+	stmt := mustParseSelectStatement(fmt.Sprintf(`SELECT value FROM cpu WHERE time >= '%s' AND time < '%s' ORDER BY time %s`,
+		end.Add(-time.Minute).Format(influxql.DateTimeFormat), end.Format(influxql.DateTimeFormat), direction))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mapper := tsdb.NewSelectMapper(shard, stmt, 1000)
+		if err := mapper.Open(); err != nil {
+			b.Fatal(err)
+		}
+		for {
+			chunk, err := mapper.NextChunk()
+			if err != nil {
+				b.Fatal(err)
+			} else if chunk == nil {
+				break
+			}
+		}
+		mapper.Close()
+	}
+}
+
+func BenchmarkSelectMapper_Agg_Mean_Float(b *testing.B) {
+	benchmarkSelectMapperAgg(b, "mean", 1.0)
+}
+
+func BenchmarkSelectMapper_Agg_Max_Integer(b *testing.B) {
+	benchmarkSelectMapperAgg(b, "max", int64(1))
+}
+
+func BenchmarkSelectMapper_Agg_Last_String(b *testing.B) {
+	benchmarkSelectMapperAgg(b, "last", "ok")
+}
+
+// benchmarkSelectMapperAgg benchmarks an aggregate over an hour of a series of
+// points of value, one per second, grouped by minute.
+func benchmarkSelectMapperAgg(b *testing.B, fn string, value interface{}) {
+	tmpDir, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(tmpDir)
+	shard := mustCreateShard(tmpDir)
+	defer shard.Close()
+
+	end := time.Unix(3600, 0).UTC()
+	points := make([]tsdb.Point, 0, 3600)
+	for t := time.Unix(0, 0).UTC(); t.Before(end); t = t.Add(time.Second) {
+		points = append(points, tsdb.NewPoint("cpu", map[string]string{"host": "serverA"}, map[string]interface{}{"value": value}, t))
+	}
+	if err := shard.WritePoints(points); err != nil {
+		b.Fatal(err)
+	}
+
+	stmt := mustParseSelectStatement(fmt.Sprintf(`SELECT %s(value) FROM cpu WHERE time >= '%s' AND time < '%s' GROUP BY time(1m)`,
+		fn, time.Unix(0, 0).UTC().Format(influxql.DateTimeFormat), end.Format(influxql.DateTimeFormat)))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mapper := tsdb.NewSelectMapper(shard, stmt, 1000)
+		if err := mapper.Open(); err != nil {
+			b.Fatal(err)
+		}
+		for {
+			chunk, err := mapper.NextChunk()
+			if err != nil {
+				b.Fatal(err)
+			} else if chunk == nil {
+				break
+			}
+		}
+		mapper.Close()
+	}
+}
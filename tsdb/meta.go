@@ -1,6 +1,7 @@
 package tsdb
 
 import (
+	"expvar"
 	"fmt"
 	"regexp"
 	"sort"
@@ -8,7 +9,10 @@ import (
 	"sync"
 	"time"
 
+	"github.com/influxdb/influxdb"
 	"github.com/influxdb/influxdb/influxql"
+	"github.com/influxdb/influxdb/pkg/hll"
+	"github.com/influxdb/influxdb/pkg/roaring"
 	"github.com/influxdb/influxdb/tsdb/internal"
 
 	"github.com/gogo/protobuf/proto"
@@ -20,6 +24,18 @@ const (
 	maxStringLength = 64 * 1024
 )
 
+const (
+	// Estimated number of series, reported by the database and by each of
+	// its measurements.
+	statSeriesNEstimate = "series_n_est"
+
+	// Precisions of the sketches estimating the series of a database and of
+	// a measurement. Measurement sketches take 1KB and are accurate to about
+	// 3%, database sketches take 16KB and are accurate to about 1%.
+	databaseSketchPrecision    = 14
+	measurementSketchPrecision = 10
+)
+
 // DatabaseIndex is the in memory index of a collection of measurements, time series, and their tags.
 // Exported functions are goroutine safe while un-exported functions assume the caller will use the appropriate locks
 type DatabaseIndex struct {
@@ -28,15 +44,78 @@ type DatabaseIndex struct {
 	measurements map[string]*Measurement // measurement name to object and index
 	series       map[string]*Series      // map series key to the Series object
 	lastID       uint64                  // last used series ID. They're in memory only for this shard
+
+	// Sketch of the keys of the series, estimating their number. It's
+	// rebuilt from the remaining series when series are dropped.
+	sketch *hll.Sketch
+
+	// Name of the database and the statistics of its measurements, set if
+	// the index reports its statistics.
+	name             string
+	measurementStats *expvar.Map
+
+	// Locks blocking the writes to the series being rewritten.
+	locks seriesLocks
 }
 
 func NewDatabaseIndex() *DatabaseIndex {
 	return &DatabaseIndex{
 		measurements: make(map[string]*Measurement),
 		series:       make(map[string]*Series),
+		sketch:       hll.NewSketch(databaseSketchPrecision),
+	}
+}
+
+// SeriesNEstimate returns the estimated number of series. Unlike the exact
+// count it is maintained as series are created and dropped.
+func (d *DatabaseIndex) SeriesNEstimate() int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return int64(d.sketch.Count())
+}
+
+// rebuildSketch rebuilds the sketch from the series left after a drop. The
+// lock must be held by the caller.
+func (d *DatabaseIndex) rebuildSketch() {
+	d.sketch = hll.NewSketch(databaseSketchPrecision)
+	for key := range d.series {
+		d.sketch.Add([]byte(key))
 	}
 }
 
+// noStatistic is the value of a statistic no longer reported.
+var noStatistic = expvar.Func(func() interface{} { return nil })
+
+// registerStatistics reports the statistics of the index as those of
+// database. The estimates of its measurements are reported together, one
+// value per measurement, so the monitor writes one point per database.
+func (d *DatabaseIndex) registerStatistics(database string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.name = database
+	influxdb.DatabaseStatistics(database).Set(statSeriesNEstimate, expvar.Func(func() interface{} { return d.SeriesNEstimate() }))
+	d.measurementStats = influxdb.NewStatistics("measurement_series:"+database, "measurement_series", map[string]string{"database": database})
+	for _, m := range d.measurements {
+		d.measurementStats.Set(m.Name, m.estimateStatistic())
+	}
+}
+
+// unregisterStatistics stops reporting the statistics of the index and its
+// measurements, once the database is dropped. Statistics without values
+// aren't reported.
+func (d *DatabaseIndex) unregisterStatistics() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.name == "" {
+		return
+	}
+	influxdb.DatabaseStatistics(d.name).Set(statSeriesNEstimate, new(expvar.Int))
+	influxdb.NewStatistics("measurement_series:"+d.name, "measurement_series", nil)
+	d.name, d.measurementStats = "", nil
+}
+
 // Series returns a series by key.
 func (d *DatabaseIndex) Series(key string) *Series {
 	d.mu.RLock()
@@ -84,6 +163,7 @@ func (s *DatabaseIndex) CreateSeriesIndexIfNotExists(measurementName string, ser
 
 	series.measurement = m
 	s.series[series.Key] = series
+	s.sketch.Add([]byte(series.Key))
 
 	m.AddSeries(series)
 
@@ -97,6 +177,9 @@ func (s *DatabaseIndex) CreateMeasurementIndexIfNotExists(name string) *Measurem
 	if m == nil {
 		m = NewMeasurement(name, s)
 		s.measurements[name] = m
+		if s.measurementStats != nil {
+			s.measurementStats.Set(name, m.estimateStatistic())
+		}
 	}
 	return m
 }
@@ -264,20 +347,36 @@ func (db *DatabaseIndex) DropMeasurement(name string) {
 	for _, s := range m.seriesByID {
 		delete(db.series, s.Key)
 	}
+	db.rebuildSketch()
+	if db.measurementStats != nil {
+		db.measurementStats.Set(name, noStatistic)
+	}
 }
 
 // DropSeries removes the series keys and their tags from the index
 func (db *DatabaseIndex) DropSeries(keys []string) {
 	db.mu.Lock()
 	defer db.mu.Unlock()
+
+	dropped := make(map[*Measurement]struct{})
 	for _, k := range keys {
 		series := db.series[k]
 		if series == nil {
 			continue
 		}
-		series.measurement.DropSeries(series.id)
+		series.measurement.dropSeries(series.id)
+		dropped[series.measurement] = struct{}{}
 		delete(db.series, k)
 	}
+	if len(dropped) == 0 {
+		return
+	}
+
+	// Rebuild the sketches once for all the series dropped.
+	db.rebuildSketch()
+	for m := range dropped {
+		m.rebuildSketch()
+	}
 }
 
 // Measurement represents a collection of time series in a database. It also contains in memory
@@ -292,8 +391,12 @@ type Measurement struct {
 	// in-memory index fields
 	seriesByID          map[uint64]*Series // lookup table for series by their id
 	measurement         *Measurement
-	seriesByTagKeyValue map[string]map[string]SeriesIDs // map from tag key to value to sorted set of series ids
-	seriesIDs           SeriesIDs                       // sorted list of series IDs in this measurement
+	seriesByTagKeyValue map[string]map[string]*roaring.Bitmap // map from tag key to value to set of series ids
+	seriesIDs           *roaring.Bitmap                       // set of series IDs in this measurement
+
+	// Sketch of the keys of the series, estimating their number. It's
+	// rebuilt from the remaining series when series are dropped.
+	sketch *hll.Sketch
 }
 
 // NewMeasurement allocates and initializes a new Measurement.
@@ -304,8 +407,33 @@ func NewMeasurement(name string, idx *DatabaseIndex) *Measurement {
 		index:      idx,
 
 		seriesByID:          make(map[uint64]*Series),
-		seriesByTagKeyValue: make(map[string]map[string]SeriesIDs),
-		seriesIDs:           make(SeriesIDs, 0),
+		seriesByTagKeyValue: make(map[string]map[string]*roaring.Bitmap),
+		seriesIDs:           roaring.NewBitmap(),
+		sketch:              hll.NewSketch(measurementSketchPrecision),
+	}
+}
+
+// SeriesNEstimate returns the estimated number of series in the measurement.
+func (m *Measurement) SeriesNEstimate() int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return int64(m.sketch.Count())
+}
+
+// estimateStatistic returns the statistic reporting the estimated number of
+// series in the measurement.
+func (m *Measurement) estimateStatistic() expvar.Var {
+	return expvar.Func(func() interface{} { return m.SeriesNEstimate() })
+}
+
+// rebuildSketch rebuilds the sketch from the series left after a drop.
+func (m *Measurement) rebuildSketch() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sketch = hll.NewSketch(measurementSketchPrecision)
+	for _, s := range m.seriesByID {
+		m.sketch.Add([]byte(s.Key))
 	}
 }
 
@@ -356,6 +484,21 @@ func (m *Measurement) HasTagKey(k string) bool {
 	return hasTag
 }
 
+// SeriesN returns the number of series in this measurement.
+func (m *Measurement) SeriesN() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.seriesIDs.Count()
+}
+
+// TagValueSeriesN returns the number of series in this measurement with the
+// value of the tag key.
+func (m *Measurement) TagValueSeriesN(key, value string) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.seriesByTagKeyValue[key][value].Count()
+}
+
 // HasSeries returns true if there is at least 1 series under this measurement
 func (m *Measurement) HasSeries() bool {
 	m.mu.RLock()
@@ -372,30 +515,22 @@ func (m *Measurement) AddSeries(s *Series) bool {
 		return false
 	}
 	m.seriesByID[s.id] = s
-	m.seriesIDs = append(m.seriesIDs, s.id)
-
-	// the series ID should always be higher than all others because it's a new
-	// series. So don't do the sort if we don't have to.
-	if len(m.seriesIDs) > 1 && m.seriesIDs[len(m.seriesIDs)-1] < m.seriesIDs[len(m.seriesIDs)-2] {
-		sort.Sort(m.seriesIDs)
-	}
+	m.seriesIDs.Add(s.id)
+	m.sketch.Add([]byte(s.Key))
 
 	// add this series id to the tag index on the measurement
 	for k, v := range s.Tags {
 		valueMap := m.seriesByTagKeyValue[k]
 		if valueMap == nil {
-			valueMap = make(map[string]SeriesIDs)
+			valueMap = make(map[string]*roaring.Bitmap)
 			m.seriesByTagKeyValue[k] = valueMap
 		}
 		ids := valueMap[v]
-		ids = append(ids, s.id)
-
-		// most of the time the series ID will be higher than all others because it's a new
-		// series. So don't do the sort if we don't have to.
-		if len(ids) > 1 && ids[len(ids)-1] < ids[len(ids)-2] {
-			sort.Sort(ids)
+		if ids == nil {
+			ids = roaring.NewBitmap()
+			valueMap[v] = ids
 		}
-		valueMap[v] = ids
+		ids.Add(s.id)
 	}
 
 	return true
@@ -403,45 +538,37 @@ func (m *Measurement) AddSeries(s *Series) bool {
 
 // DropSeries will remove a series from the measurementIndex.
 func (m *Measurement) DropSeries(seriesID uint64) {
+	m.dropSeries(seriesID)
+	m.rebuildSketch()
+}
+
+// dropSeries removes a series from the measurementIndex without rebuilding the
+// sketch, so the series dropped together rebuild it once.
+func (m *Measurement) dropSeries(seriesID uint64) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if _, ok := m.seriesByID[seriesID]; !ok {
+	s, ok := m.seriesByID[seriesID]
+	if !ok {
 		return
 	}
 	delete(m.seriesByID, seriesID)
+	m.seriesIDs.Remove(seriesID)
 
-	var ids []uint64
-	for _, id := range m.seriesIDs {
-		if id != seriesID {
-			ids = append(ids, id)
-		}
-	}
-	m.seriesIDs = ids
-
-	// remove this series id to the tag index on the measurement
-	// s.seriesByTagKeyValue is defined as map[string]map[string]SeriesIDs
-	for k, v := range m.seriesByTagKeyValue {
-		values := v
-		for kk, vv := range values {
-			var ids []uint64
-			for _, id := range vv {
-				if id != seriesID {
-					ids = append(ids, id)
-				}
-			}
-			// Check to see if we have any ids, if not, remove the key
-			if len(ids) == 0 {
-				delete(values, kk)
-			} else {
-				values[kk] = ids
+	// remove this series id from the tag index on the measurement
+	for k, v := range s.Tags {
+		values := m.seriesByTagKeyValue[k]
+		if ids := values[v]; ids != nil {
+			ids.Remove(seriesID)
+
+			// Check to see if we have any ids, if not, remove the value
+			if ids.Count() == 0 {
+				delete(values, v)
 			}
 		}
 		// If we have no values, then we delete the key
 		if len(values) == 0 {
 			delete(m.seriesByTagKeyValue, k)
-		} else {
-			m.seriesByTagKeyValue[k] = values
 		}
 	}
 
@@ -452,10 +579,10 @@ func (m *Measurement) DropSeries(seriesID uint64) {
 // matching the where clause and any filter expression that should be applied to each
 func (m *Measurement) filters(stmt *influxql.SelectStatement) (map[uint64]influxql.Expr, error) {
 	if stmt.Condition == nil || stmt.OnlyTimeDimensions() {
-		seriesIdsToExpr := make(map[uint64]influxql.Expr)
-		for _, id := range m.seriesIDs {
+		seriesIdsToExpr := make(map[uint64]influxql.Expr, m.seriesIDs.Count())
+		m.seriesIDs.ForEach(func(id uint64) {
 			seriesIdsToExpr[id] = nil
-		}
+		})
 		return seriesIdsToExpr, nil
 	}
 
@@ -465,13 +592,13 @@ func (m *Measurement) filters(stmt *influxql.SelectStatement) (map[uint64]influx
 	}
 	// Ensure every id is in the map and replace literal true expressions with
 	// nil so the engine doesn't waste time evaluating them.
-	for _, id := range ids {
+	ids.ForEach(func(id uint64) {
 		if expr, ok := seriesIdsToExpr[id]; !ok {
 			seriesIdsToExpr[id] = nil
 		} else if b, ok := expr.(*influxql.BooleanLiteral); ok && b.Val {
 			seriesIdsToExpr[id] = nil
 		}
-	}
+	})
 	return seriesIdsToExpr, nil
 }
 
@@ -547,11 +674,11 @@ func (m *Measurement) TagSets(stmt *influxql.SelectStatement, dimensions []strin
 }
 
 // mergeSeriesFilters merges two sets of filter expressions and culls series IDs.
-func mergeSeriesFilters(op influxql.Token, ids SeriesIDs, lfilters, rfilters map[uint64]influxql.Expr) (SeriesIDs, map[uint64]influxql.Expr) {
+func mergeSeriesFilters(op influxql.Token, ids *roaring.Bitmap, lfilters, rfilters map[uint64]influxql.Expr) (*roaring.Bitmap, map[uint64]influxql.Expr) {
 	// Create a map to hold the final set of series filter expressions.
 	filters := make(map[uint64]influxql.Expr, 0)
-	// Resulting list of series IDs
-	var series SeriesIDs
+	// Resulting set of series IDs
+	series := roaring.NewBitmap()
 
 	// Combining logic:
 	// +==========+==========+==========+=======================+=======================+
@@ -580,7 +707,7 @@ func mergeSeriesFilters(op influxql.Token, ids SeriesIDs, lfilters, rfilters map
 		def = true
 	}
 
-	for _, id := range ids {
+	ids.ForEach(func(id uint64) {
 		// Get LHS and RHS filter expressions for this series ID.
 		lfilter, rfilter := lfilters[id], rfilters[id]
 
@@ -604,19 +731,19 @@ func mergeSeriesFilters(op influxql.Token, ids SeriesIDs, lfilters, rfilters map
 
 		// If the expression reduced to false, exclude this series ID and filter.
 		if b, ok := expr.(*influxql.BooleanLiteral); ok && !b.Val {
-			continue
+			return
 		}
 
 		// Store the series ID and merged filter in the final results.
 		filters[id] = expr
-		series = append(series, id)
-	}
+		series.Add(id)
+	})
 	return series, filters
 }
 
 // idsForExpr will return a collection of series ids and a filter expression that should
 // be used to filter points from those series.
-func (m *Measurement) idsForExpr(n *influxql.BinaryExpr) (SeriesIDs, influxql.Expr, error) {
+func (m *Measurement) idsForExpr(n *influxql.BinaryExpr) (*roaring.Bitmap, influxql.Expr, error) {
 	name, ok := n.LHS.(*influxql.VarRef)
 	value := n.RHS
 	if !ok {
@@ -638,27 +765,35 @@ func (m *Measurement) idsForExpr(n *influxql.BinaryExpr) (SeriesIDs, influxql.Ex
 		return m.seriesIDs, n, nil
 	}
 
-	tagVals, ok := m.seriesByTagKeyValue[name.Val]
-	if !ok {
-		return nil, nil, nil
-	}
+	// Series without the tag have an empty value for it, so if the tag key
+	// doesn't exist every series has the empty value.
+	tagVals := m.seriesByTagKeyValue[name.Val]
 
 	// if we're looking for series with a specific tag value
 	if str, ok := value.(*influxql.StringLiteral); ok {
-		var ids SeriesIDs
+		var ids *roaring.Bitmap
 
-		if n.Op == influxql.EQ {
+		if str.Val == "" {
+			// return series that don't have the tag.
+			ids = m.seriesIDs.Difference(m.seriesIDsWithTagKey(tagVals))
+		} else {
 			// return series that have a tag of specific value.
 			ids = tagVals[str.Val]
-		} else if n.Op == influxql.NEQ {
-			ids = m.seriesIDs.Reject(tagVals[str.Val])
+		}
+
+		switch n.Op {
+		case influxql.EQ:
+		case influxql.NEQ:
+			ids = m.seriesIDs.Difference(ids)
+		default:
+			return nil, nil, nil
 		}
 		return ids, &influxql.BooleanLiteral{Val: true}, nil
 	}
 
 	// if we're looking for series with a tag value that matches a regex
 	if re, ok := value.(*influxql.RegexLiteral); ok {
-		var ids SeriesIDs
+		var ids *roaring.Bitmap
 
 		// The operation is a NEQREGEX, code must start by assuming all match, even
 		// series without any tags.
@@ -672,7 +807,17 @@ func (m *Measurement) idsForExpr(n *influxql.BinaryExpr) (SeriesIDs, influxql.Ex
 			if match && n.Op == influxql.EQREGEX {
 				ids = ids.Union(tagVals[k])
 			} else if match && n.Op == influxql.NEQREGEX {
-				ids = ids.Reject(tagVals[k])
+				ids = ids.Difference(tagVals[k])
+			}
+		}
+
+		// The empty value of series without the tag may match too.
+		if re.Val.MatchString("") {
+			untagged := m.seriesIDs.Difference(m.seriesIDsWithTagKey(tagVals))
+			if n.Op == influxql.EQREGEX {
+				ids = ids.Union(untagged)
+			} else if n.Op == influxql.NEQREGEX {
+				ids = ids.Difference(untagged)
 			}
 		}
 		return ids, &influxql.BooleanLiteral{Val: true}, nil
@@ -681,10 +826,20 @@ func (m *Measurement) idsForExpr(n *influxql.BinaryExpr) (SeriesIDs, influxql.Ex
 	return nil, nil, nil
 }
 
+// seriesIDsWithTagKey returns the ids of the series with any of the values
+// in tagVals, the series of a tag key.
+func (m *Measurement) seriesIDsWithTagKey(tagVals map[string]*roaring.Bitmap) *roaring.Bitmap {
+	var ids *roaring.Bitmap
+	for _, vids := range tagVals {
+		ids = ids.Union(vids)
+	}
+	return ids
+}
+
 // walkWhereForSeriesIds recursively walks the WHERE clause and returns an ordered set of series IDs and
 // a map from those series IDs to filter expressions that should be used to limit points returned in
 // the final query result.
-func (m *Measurement) walkWhereForSeriesIds(expr influxql.Expr) (SeriesIDs, map[uint64]influxql.Expr, error) {
+func (m *Measurement) walkWhereForSeriesIds(expr influxql.Expr) (*roaring.Bitmap, map[uint64]influxql.Expr, error) {
 	switch n := expr.(type) {
 	case *influxql.BinaryExpr:
 		switch n.Op {
@@ -696,9 +851,9 @@ func (m *Measurement) walkWhereForSeriesIds(expr influxql.Expr) (SeriesIDs, map[
 			}
 
 			filters := map[uint64]influxql.Expr{}
-			for _, id := range ids {
+			ids.ForEach(func(id uint64) {
 				filters[id] = expr
-			}
+			})
 
 			return ids, filters, nil
 		case influxql.AND, influxql.OR:
@@ -715,7 +870,7 @@ func (m *Measurement) walkWhereForSeriesIds(expr influxql.Expr) (SeriesIDs, map[
 			}
 
 			// Combine the series IDs from the LHS and RHS.
-			var ids SeriesIDs
+			var ids *roaring.Bitmap
 			switch n.Op {
 			case influxql.AND:
 				ids = lids.Intersect(rids)
@@ -734,6 +889,16 @@ func (m *Measurement) walkWhereForSeriesIds(expr influxql.Expr) (SeriesIDs, map[
 	case *influxql.ParenExpr:
 		// walk down the tree
 		return m.walkWhereForSeriesIds(n.Expr)
+	case *influxql.BooleanLiteral:
+		// A literal true, such as a reduced condition, matches every series.
+		if n.Val {
+			filters := map[uint64]influxql.Expr{}
+			m.seriesIDs.ForEach(func(id uint64) {
+				filters[id] = n
+			})
+			return m.seriesIDs, filters, nil
+		}
+		return nil, nil, nil
 	default:
 		return nil, nil, nil
 	}
@@ -798,12 +963,12 @@ func expandExprWithValues(expr influxql.Expr, keys []string, tagExprs []tagExpr,
 
 // seriesIDsAllOrByExpr walks an expressions for matching series IDs
 // or, if no expressions is given, returns all series IDs for the measurement.
-func (m *Measurement) seriesIDsAllOrByExpr(expr influxql.Expr) (SeriesIDs, error) {
+func (m *Measurement) seriesIDsAllOrByExpr(expr influxql.Expr) (*roaring.Bitmap, error) {
 	// If no expression given or the measurement has no series,
 	// we can take just return the ids or nil accordingly.
 	if expr == nil {
 		return m.seriesIDs, nil
-	} else if len(m.seriesIDs) == 0 {
+	} else if m.seriesIDs.Count() == 0 {
 		return nil, nil
 	}
 
@@ -1179,6 +1344,91 @@ func MarshalTags(tags map[string]string) []byte {
 	return b
 }
 
+// TagSetCache interns tag sets, so the points and values of a series share a
+// single map of tags instead of each holding a copy. Tag sets are looked up by
+// their canonical key, the sorted keys and values as marshaled by MarshalTags.
+//
+// Interned tags are shared and must not be modified. A TagSetCache is not safe
+// for concurrent use; it's meant to be scoped to a write batch or a query.
+type TagSetCache struct {
+	sets   map[string]map[string]string // tag sets by canonical key
+	series map[string]map[string]string // tag sets by series key
+
+	keys []string // scratch space for sorting tag keys
+	buf  []byte   // scratch space for canonical keys
+}
+
+// NewTagSetCache returns a new, empty tag set cache.
+func NewTagSetCache() *TagSetCache {
+	return &TagSetCache{
+		sets:   make(map[string]map[string]string),
+		series: make(map[string]map[string]string),
+	}
+}
+
+// Len returns the number of distinct tag sets in the cache.
+func (c *TagSetCache) Len() int { return len(c.sets) }
+
+// Intern returns the cached tag set equal to tags, caching tags if there's none.
+func (c *TagSetCache) Intern(tags map[string]string) map[string]string {
+	if len(tags) == 0 {
+		return tags
+	}
+
+	c.keys = c.keys[:0]
+	for k := range tags {
+		c.keys = append(c.keys, k)
+	}
+	sort.Strings(c.keys)
+
+	c.buf = c.buf[:0]
+	for _, k := range c.keys {
+		c.buf = append(c.buf, k...)
+		c.buf = append(c.buf, '|')
+	}
+	for i, k := range c.keys {
+		if i > 0 {
+			c.buf = append(c.buf, '|')
+		}
+		c.buf = append(c.buf, tags[k]...)
+	}
+
+	if other, ok := c.sets[string(c.buf)]; ok {
+		// Values with separators in them can marshal to the key of other tags.
+		if tagsEqual(other, tags) {
+			return other
+		}
+		return tags
+	}
+	c.sets[string(c.buf)] = tags
+	return tags
+}
+
+// SeriesTags returns the interned tags of a series key. The key is only parsed
+// the first time it's seen.
+func (c *TagSetCache) SeriesTags(key []byte) map[string]string {
+	if tags, ok := c.series[string(key)]; ok {
+		return tags
+	}
+	_, tags := ParseKey(string(key))
+	interned := c.Intern(tags)
+	c.series[string(key)] = interned
+	return interned
+}
+
+// tagsEqual returns true if a and b have the same tags.
+func tagsEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if other, ok := b[k]; !ok || other != v {
+			return false
+		}
+	}
+	return true
+}
+
 // timeBetweenInclusive returns true if t is between min and max, inclusive.
 func timeBetweenInclusive(t, min, max time.Time) bool {
 	return (t.Equal(min) || t.After(min)) && (t.Equal(max) || t.Before(max))
@@ -1225,7 +1475,7 @@ func (m *Measurement) FieldNames() (a []string) {
 	return
 }
 
-func (m *Measurement) tagValuesByKeyAndSeriesID(tagKeys []string, ids SeriesIDs) map[string]stringSet {
+func (m *Measurement) tagValuesByKeyAndSeriesID(tagKeys []string, ids *roaring.Bitmap) map[string]stringSet {
 	// If no tag keys were passed, get all tag keys for the measurement.
 	if len(tagKeys) == 0 {
 		for k := range m.seriesByTagKeyValue {
@@ -1237,7 +1487,7 @@ func (m *Measurement) tagValuesByKeyAndSeriesID(tagKeys []string, ids SeriesIDs)
 	tagValues := make(map[string]stringSet, 0)
 
 	// Iterate all series to collect tag values.
-	for _, id := range ids {
+	for _, id := range ids.Slice() {
 		s, ok := m.seriesByID[id]
 		if !ok {
 			continue
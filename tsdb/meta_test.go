@@ -3,6 +3,8 @@ package tsdb_test
 import (
 	"bytes"
 	"fmt"
+	"reflect"
+	"sort"
 	"testing"
 
 	"github.com/influxdb/influxdb/influxql"
@@ -92,6 +94,118 @@ func Test_SeriesIDs_Reject(t *testing.T) {
 }
 
 // Ensure tags can be marshaled into a byte slice.
+// Ensure the index evaluates boolean tag expressions to the matching series.
+func TestMeasurement_TagSets_TagExpr(t *testing.T) {
+	idx := tsdb.NewDatabaseIndex()
+	for key, tags := range map[string]map[string]string{
+		"cpu,host=a,region=east": {"host": "a", "region": "east"},
+		"cpu,host=b,region=west": {"host": "b", "region": "west"},
+		"cpu,region=east":        {"region": "east"},
+		"cpu,host=c":             {"host": "c"},
+	} {
+		idx.CreateSeriesIndexIfNotExists("cpu", tsdb.NewSeries(key, tags))
+	}
+	m := idx.Measurement("cpu")
+
+	for _, tt := range []struct {
+		cond string
+		exp  []string
+	}{
+		{cond: `host = 'a' OR region = 'west'`, exp: []string{"cpu,host=a,region=east", "cpu,host=b,region=west"}},
+		{cond: `host = ''`, exp: []string{"cpu,region=east"}},
+		{cond: `host != ''`, exp: []string{"cpu,host=a,region=east", "cpu,host=b,region=west", "cpu,host=c"}},
+		{cond: `host = 'a' OR host = ''`, exp: []string{"cpu,host=a,region=east", "cpu,region=east"}},
+		{cond: `host =~ /^$/ OR region = 'west'`, exp: []string{"cpu,host=b,region=west", "cpu,region=east"}},
+		{cond: `host !~ /a|^$/`, exp: []string{"cpu,host=b,region=west", "cpu,host=c"}},
+		{cond: `dc != 'x'`, exp: []string{"cpu,host=a,region=east", "cpu,host=b,region=west", "cpu,host=c", "cpu,region=east"}},
+		{cond: `(host = 'a' OR host = 'b') AND region != 'east'`, exp: []string{"cpu,host=b,region=west"}},
+		{cond: `dc = 'x' OR host = 'c'`, exp: []string{"cpu,host=c"}},
+	} {
+		stmt := mustParseSelectStatement(`SELECT value FROM cpu WHERE ` + tt.cond)
+		tagSets, err := m.TagSets(stmt, nil)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.cond, err)
+		}
+
+		var keys []string
+		for _, ts := range tagSets {
+			keys = append(keys, ts.SeriesKeys...)
+		}
+		sort.Strings(keys)
+		if !reflect.DeepEqual(keys, tt.exp) {
+			t.Fatalf("%s: unexpected series: %v", tt.cond, keys)
+		}
+	}
+}
+
+// Ensure the number of series of a measurement and its tag values are kept
+// as series are added and dropped.
+func TestMeasurement_SeriesN(t *testing.T) {
+	idx := tsdb.NewDatabaseIndex()
+	idx.CreateSeriesIndexIfNotExists("cpu", tsdb.NewSeries("cpu,host=a", map[string]string{"host": "a"}))
+	idx.CreateSeriesIndexIfNotExists("cpu", tsdb.NewSeries("cpu,host=b", map[string]string{"host": "b"}))
+	idx.CreateSeriesIndexIfNotExists("cpu", tsdb.NewSeries("cpu,host=a,region=east", map[string]string{"host": "a", "region": "east"}))
+
+	m := idx.Measurement("cpu")
+	if n := m.SeriesN(); n != 3 {
+		t.Fatalf("unexpected series: %d", n)
+	} else if n := m.TagValueSeriesN("host", "a"); n != 2 {
+		t.Fatalf("unexpected series of host a: %d", n)
+	}
+
+	idx.DropSeries([]string{"cpu,host=a,region=east"})
+	if n := m.SeriesN(); n != 2 {
+		t.Fatalf("unexpected series: %d", n)
+	} else if n := m.TagValueSeriesN("host", "a"); n != 1 {
+		t.Fatalf("unexpected series of host a: %d", n)
+	} else if m.HasTagKey("region") {
+		t.Fatal("expected region tag key to be dropped")
+	}
+}
+
+// Ensure the estimated series counts follow series being created and dropped.
+func TestDatabaseIndex_SeriesNEstimate(t *testing.T) {
+	idx := tsdb.NewDatabaseIndex()
+	for i := 0; i < 100; i++ {
+		host := fmt.Sprintf("server%d", i)
+		idx.CreateSeriesIndexIfNotExists("cpu", tsdb.NewSeries("cpu,host="+host, map[string]string{"host": host}))
+		idx.CreateSeriesIndexIfNotExists("mem", tsdb.NewSeries("mem,host="+host, map[string]string{"host": host}))
+	}
+
+	if n := idx.SeriesNEstimate(); n < 195 || n > 205 {
+		t.Fatalf("unexpected series: %d", n)
+	} else if n := idx.Measurement("cpu").SeriesNEstimate(); n < 95 || n > 105 {
+		t.Fatalf("unexpected cpu series: %d", n)
+	}
+
+	var keys []string
+	for i := 0; i < 50; i++ {
+		keys = append(keys, fmt.Sprintf("cpu,host=server%d", i))
+	}
+	idx.DropSeries(keys)
+	if n := idx.SeriesNEstimate(); n < 145 || n > 155 {
+		t.Fatalf("unexpected series after drop: %d", n)
+	} else if n := idx.Measurement("cpu").SeriesNEstimate(); n < 45 || n > 55 {
+		t.Fatalf("unexpected cpu series after drop: %d", n)
+	}
+
+	idx.DropMeasurement("mem")
+	if n := idx.SeriesNEstimate(); n < 45 || n > 55 {
+		t.Fatalf("unexpected series after measurement drop: %d", n)
+	}
+
+	// Series recreated after being dropped are counted again.
+	for i := 0; i < 50; i++ {
+		host := fmt.Sprintf("server%d", i)
+		idx.CreateSeriesIndexIfNotExists("cpu", tsdb.NewSeries("cpu,host="+host, map[string]string{"host": host}))
+	}
+	if n := idx.SeriesNEstimate(); n < 95 || n > 105 {
+		t.Fatalf("unexpected series after recreation: %d", n)
+	} else if n := idx.Measurement("cpu").SeriesNEstimate(); n < 95 || n > 105 {
+		t.Fatalf("unexpected cpu series after recreation: %d", n)
+	}
+}
+
 func TestMarshalTags(t *testing.T) {
 	for i, tt := range []struct {
 		tags   map[string]string
@@ -142,6 +256,61 @@ func benchmarkMarshalTags(b *testing.B, keyN int) {
 	}
 }
 
+// Ensure equal tag sets are interned as the same map.
+func TestTagSetCache_Intern(t *testing.T) {
+	c := tsdb.NewTagSetCache()
+
+	a := c.Intern(map[string]string{"host": "serverA", "region": "uswest"})
+	if b := c.Intern(map[string]string{"region": "uswest", "host": "serverA"}); !sameMap(a, b) {
+		t.Fatal("expected equal tags to be interned")
+	} else if b := c.Intern(map[string]string{"host": "serverB", "region": "uswest"}); sameMap(a, b) {
+		t.Fatal("expected different tags not to be interned")
+	}
+
+	// Tags with the canonical key of other tags aren't interned as them.
+	x := c.Intern(map[string]string{"a": "b|c"})
+	if y := c.Intern(map[string]string{"a|b": "c"}); sameMap(x, y) {
+		t.Fatalf("unexpected interned tags: %v", y)
+	}
+
+	if n := c.Len(); n != 3 {
+		t.Fatalf("unexpected tag set count: %d", n)
+	}
+}
+
+// Ensure the tags of series keys are parsed and interned.
+func TestTagSetCache_SeriesTags(t *testing.T) {
+	c := tsdb.NewTagSetCache()
+
+	a := c.SeriesTags([]byte(`cpu,host=serverA,region=us\ west`))
+	if !reflect.DeepEqual(a, map[string]string{"host": "serverA", "region": "us west"}) {
+		t.Fatalf("unexpected tags: %v", a)
+	} else if b := c.SeriesTags([]byte(`mem,host=serverA,region=us\ west`)); !sameMap(a, b) {
+		t.Fatal("expected series with equal tags to share them")
+	} else if tags := c.SeriesTags([]byte(`cpu`)); len(tags) != 0 {
+		t.Fatalf("unexpected tags: %v", tags)
+	}
+}
+
+func BenchmarkTagSetCache_SeriesTags(b *testing.B) {
+	keys := make([][]byte, 100)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("cpu,host=server%d,region=uswest,service=db", i))
+	}
+	c := tsdb.NewTagSetCache()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.SeriesTags(keys[i%len(keys)])
+	}
+}
+
+// sameMap returns true if a and b are the same map.
+func sameMap(a, b map[string]string) bool {
+	return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+}
+
 func BenchmarkCreateSeriesIndex_1K(b *testing.B) {
 	benchmarkCreateSeriesIndex(b, genTestSeries(38, 3, 3))
 }
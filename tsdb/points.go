@@ -2,8 +2,10 @@ package tsdb
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"hash/fnv"
+	"math"
 	"regexp"
 	"sort"
 	"strconv"
@@ -75,6 +77,9 @@ const (
 	// the number of characters for the smallest possible int64 (-9223372036854775808)
 	minInt64Digits = 20
 
+	// the number of characters for the largest possible uint64 (18446744073709551615)
+	maxUint64Digits = 20
+
 	// the number of characters required for the largest float64 before a range check
 	// would occur during parsing
 	maxFloat64Digits = 25
@@ -125,7 +130,14 @@ func ParsePoints(buf []byte) ([]Point, error) {
 	return ParsePointsWithPrecision(buf, time.Now().UTC(), "n")
 }
 
+// ParsePointsWithPrecision returns a slice of Points from a text representation
+// of points with timestamps in the units of precision, as accepted by
+// ParsePrecision. Points without a timestamp are truncated to precision.
 func ParsePointsWithPrecision(buf []byte, defaultTime time.Time, precision string) ([]Point, error) {
+	if _, err := ParsePrecision(precision); err != nil {
+		return nil, err
+	}
+
 	points := []Point{}
 	var (
 		pos   int
@@ -215,7 +227,12 @@ func parsePoint(buf []byte, defaultTime time.Time, precision string) (Point, err
 		if err != nil {
 			return nil, err
 		}
-		pt.time = time.Unix(0, ts*pt.GetPrecisionMultiplier(precision))
+		// Timestamps are scaled to nanoseconds, which must fit an int64.
+		mult := pt.GetPrecisionMultiplier(precision)
+		if ts > math.MaxInt64/mult || ts < math.MinInt64/mult {
+			return nil, ErrTimestampOutOfRange
+		}
+		pt.time = time.Unix(0, ts*mult)
 	}
 	return pt, nil
 }
@@ -485,7 +502,7 @@ func scanFields(buf []byte, i int) (int, []byte, error) {
 				return i, buf[start:i], fmt.Errorf("missing field value")
 			}
 
-			if isNumeric(buf[i+1]) || buf[i+1] == '-' || buf[i+1] == 'N' || buf[i+1] == 'n' {
+			if isNumeric(buf[i+1]) || buf[i+1] == '-' || buf[i+1] == '+' || buf[i+1] == 'N' || buf[i+1] == 'n' || buf[i+1] == 'I' || buf[i+1] == 'i' {
 				var err error
 				i, err = scanNumber(buf, i+1)
 				if err != nil {
@@ -563,7 +580,19 @@ func isNumeric(b byte) bool {
 // error if a invalid number is scanned.
 func scanNumber(buf []byte, i int) (int, error) {
 	start := i
-	var isInt bool
+	var isInt, isUnsigned bool
+
+	// Inf is a valid float and can be signed
+	j := i
+	if j < len(buf) && (buf[j] == '-' || buf[j] == '+') {
+		j += 1
+	}
+	if j+3 <= len(buf) && bytes.EqualFold(buf[j:j+3], []byte("inf")) {
+		if j+3 < len(buf) && buf[j+3] != ',' && buf[j+3] != ' ' {
+			return j + 3, fmt.Errorf("invalid number")
+		}
+		return j + 3, nil
+	}
 
 	// Is negative number?
 	if i < len(buf) && buf[i] == '-' {
@@ -585,12 +614,18 @@ func scanNumber(buf []byte, i int) (int, error) {
 			break
 		}
 
-		if buf[i] == 'i' && i > start && !isInt {
+		if buf[i] == 'i' && i > start && !isInt && !isUnsigned {
 			isInt = true
 			i += 1
 			continue
 		}
 
+		if buf[i] == 'u' && i > start && !isInt && !isUnsigned {
+			isUnsigned = true
+			i += 1
+			continue
+		}
+
 		if buf[i] == '.' {
 			decimals += 1
 		}
@@ -626,7 +661,7 @@ func scanNumber(buf []byte, i int) (int, error) {
 		}
 		i += 1
 	}
-	if isInt && (decimals > 0 || scientific) {
+	if (isInt || isUnsigned) && (decimals > 0 || scientific) {
 		return i, fmt.Errorf("invalid number")
 	}
 
@@ -646,6 +681,16 @@ func scanNumber(buf []byte, i int) (int, error) {
 				return i, fmt.Errorf("unable to parse integer %s: %s", buf[start:i-1], err)
 			}
 		}
+	} else if isUnsigned {
+		// Make sure the last char is a 'u' for unsigned integers and that they aren't negative
+		if buf[i-1] != 'u' || buf[start] == '-' {
+			return i, fmt.Errorf("invalid number")
+		}
+		if len(buf[start:i-1]) >= maxUint64Digits {
+			if _, err := strconv.ParseUint(string(buf[start:i-1]), 10, 64); err != nil {
+				return i, fmt.Errorf("unable to parse unsigned integer %s: %s", buf[start:i-1], err)
+			}
+		}
 	} else {
 		// Parse the float to check bounds if it's scientific or the number of digits could be larger than the max range
 		if scientific || len(buf[start:i]) >= maxFloat64Digits || len(buf[start:i]) >= minFloat64Digits {
@@ -1089,6 +1134,12 @@ func (p *point) Tags() Tags {
 	return tags
 }
 
+// ParseKey returns the measurement name and tags of a series key.
+func ParseKey(key string) (string, Tags) {
+	p := &point{key: []byte(key)}
+	return p.Name(), p.Tags()
+}
+
 func MakeKey(name []byte, tags Tags) []byte {
 	// unescape the name and then re-escape it to avoid double escaping.
 	// The key should always be stored in escaped form.
@@ -1126,37 +1177,38 @@ func (p *point) AddField(name string, value interface{}) {
 
 // SetPrecision will round a time to the specified precision
 func (p *point) SetPrecision(precision string) {
-	switch precision {
-	case "n":
-	case "u":
-		p.SetTime(p.Time().Truncate(time.Microsecond))
-	case "ms":
-		p.SetTime(p.Time().Truncate(time.Millisecond))
-	case "s":
-		p.SetTime(p.Time().Truncate(time.Second))
-	case "m":
-		p.SetTime(p.Time().Truncate(time.Minute))
-	case "h":
-		p.SetTime(p.Time().Truncate(time.Hour))
+	if d, err := ParsePrecision(precision); err == nil && d > time.Nanosecond {
+		p.SetTime(p.Time().Truncate(d))
 	}
 }
 
 // GetPrecisionMultiplier will return a multiplier for the precision specified
 func (p *point) GetPrecisionMultiplier(precision string) int64 {
-	d := time.Nanosecond
+	d, err := ParsePrecision(precision)
+	if err != nil {
+		return int64(time.Nanosecond)
+	}
+	return int64(d)
+}
+
+// ParsePrecision returns the unit of timestamps written with precision: "n" or
+// "ns", "u" or "us", "ms", "s", "m", or "h". An empty precision is nanoseconds.
+func ParsePrecision(precision string) (time.Duration, error) {
 	switch precision {
-	case "u":
-		d = time.Microsecond
+	case "", "n", "ns":
+		return time.Nanosecond, nil
+	case "u", "us":
+		return time.Microsecond, nil
 	case "ms":
-		d = time.Millisecond
+		return time.Millisecond, nil
 	case "s":
-		d = time.Second
+		return time.Second, nil
 	case "m":
-		d = time.Minute
+		return time.Minute, nil
 	case "h":
-		d = time.Hour
+		return time.Hour, nil
 	}
-	return int64(d)
+	return 0, fmt.Errorf("%s: %q", ErrInvalidPrecision, precision)
 }
 
 func (p *point) String() string {
@@ -1227,11 +1279,120 @@ func (t Tags) HashKey() []byte {
 
 type Fields map[string]interface{}
 
+// FloatPolicy determines how writes handle NaN and infinite float values.
+type FloatPolicy int
+
+const (
+	// FloatPolicyReject rejects points with NaN or infinite values.
+	FloatPolicyReject FloatPolicy = iota
+
+	// FloatPolicyDrop drops the fields with NaN or infinite values. Points
+	// without any other fields are dropped as well.
+	FloatPolicyDrop
+
+	// FloatPolicyStore stores NaN and infinite values as they are.
+	FloatPolicyStore
+)
+
+var (
+	// ErrInvalidPrecision is returned when parsing an unknown timestamp precision.
+	ErrInvalidPrecision = errors.New("invalid precision")
+
+	// ErrTimestampOutOfRange is returned when a timestamp can't be represented
+	// in nanoseconds since the epoch.
+	ErrTimestampOutOfRange = errors.New("timestamp out of range")
+
+	// ErrInvalidFloatPolicy is returned when parsing an unknown float policy.
+	ErrInvalidFloatPolicy = errors.New("invalid float policy")
+
+	// ErrNonFiniteFloat is returned when a point with a NaN or infinite
+	// value is rejected.
+	ErrNonFiniteFloat = errors.New("NaN and infinite values are not allowed")
+)
+
+// ParseFloatPolicy returns the float policy named "reject", "drop" or "store".
+func ParseFloatPolicy(policy string) (FloatPolicy, error) {
+	switch strings.ToLower(policy) {
+	case "reject":
+		return FloatPolicyReject, nil
+	case "drop":
+		return FloatPolicyDrop, nil
+	case "store":
+		return FloatPolicyStore, nil
+	default:
+		return 0, ErrInvalidFloatPolicy
+	}
+}
+
+// String returns the name of the policy.
+func (p FloatPolicy) String() string {
+	switch p {
+	case FloatPolicyReject:
+		return "reject"
+	case FloatPolicyDrop:
+		return "drop"
+	case FloatPolicyStore:
+		return "store"
+	}
+	return fmt.Sprintf("FloatPolicy(%d)", int(p))
+}
+
+// ApplyFloatPolicy returns the points with their NaN and infinite values
+// handled by policy. Points are only copied if they have such values.
+func ApplyFloatPolicy(points []Point, policy FloatPolicy) ([]Point, error) {
+	if policy == FloatPolicyStore {
+		return points, nil
+	}
+
+	var a []Point
+	for i, p := range points {
+		fields := p.Fields()
+		var n int
+		for _, v := range fields {
+			if f, ok := v.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+				n++
+			}
+		}
+		if n == 0 {
+			if a != nil {
+				a = append(a, p)
+			}
+			continue
+		} else if policy == FloatPolicyReject {
+			return nil, fmt.Errorf("%s: %s", ErrNonFiniteFloat, p.String())
+		}
+
+		// Copy the points before the first one changed.
+		if a == nil {
+			a = make([]Point, i, len(points))
+			copy(a, points[:i])
+		}
+		if n == len(fields) {
+			continue
+		}
+		finite := make(Fields, len(fields)-n)
+		for k, v := range fields {
+			if f, ok := v.(float64); !ok || !(math.IsNaN(f) || math.IsInf(f, 0)) {
+				finite[k] = v
+			}
+		}
+		a = append(a, NewPoint(p.Name(), p.Tags(), finite, p.Time()))
+	}
+	if a == nil {
+		return points, nil
+	}
+	return a, nil
+}
+
 func parseNumber(val []byte) (interface{}, error) {
 	if val[len(val)-1] == 'i' {
 		val = val[:len(val)-1]
 		return strconv.ParseInt(string(val), 10, 64)
 	}
+	if val[len(val)-1] == 'u' {
+		val = val[:len(val)-1]
+		return strconv.ParseUint(string(val), 10, 64)
+	}
 	for i := 0; i < len(val); i++ {
 		// If there is a decimal or an N (NaN), I (Inf), parse as float
 		if val[i] == '.' || val[i] == 'N' || val[i] == 'n' || val[i] == 'I' || val[i] == 'i' || val[i] == 'e' {
@@ -1297,8 +1458,8 @@ func newFieldsFromBinary(buf []byte) Fields {
 
 // MarshalBinary encodes all the fields to their proper type and returns the binary
 // represenation
-// NOTE: uint64 is specifically not supported due to potential overflow when we decode
-// again later to an int64
+// NOTE: uint64 is encoded as an unsigned integer, while the smaller unsigned types
+// are encoded as integers
 func (p Fields) MarshalBinary() []byte {
 	b := []byte{}
 	keys := make([]string, len(p))
@@ -1341,6 +1502,9 @@ func (p Fields) MarshalBinary() []byte {
 		case uint32:
 			b = append(b, []byte(strconv.FormatInt(int64(t), 10))...)
 			b = append(b, 'i')
+		case uint64:
+			b = append(b, []byte(strconv.FormatUint(t, 10))...)
+			b = append(b, 'u')
 		case float32:
 			val := []byte(strconv.FormatFloat(float64(t), 'f', -1, 32))
 			b = append(b, val...)
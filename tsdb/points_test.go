@@ -999,6 +999,34 @@ func TestNewPointLargeInteger(t *testing.T) {
 	)
 }
 
+func TestNewPointUnsigned(t *testing.T) {
+	test(t, `cpu value=18446744073709551615u 1000000000`,
+		tsdb.NewPoint(
+			"cpu",
+			tsdb.Tags{},
+			tsdb.Fields{
+				"value": uint64(18446744073709551615),
+			},
+			time.Unix(1, 0)),
+	)
+
+	for _, line := range []string{
+		`cpu value=18446744073709551616u`,
+		`cpu value=-1u`,
+		`cpu value=1.5u`,
+		`cpu value=1iu`,
+		`cpu value=1u1`,
+	} {
+		if _, err := tsdb.ParsePointsString(line); err == nil {
+			t.Errorf(`ParsePoints("%s") expected error`, line)
+		}
+	}
+
+	if b := tsdb.Fields(map[string]interface{}{"value": uint64(1)}).MarshalBinary(); string(b) != "value=1u" {
+		t.Errorf("unexpected marshaled fields: %s", b)
+	}
+}
+
 func TestNewPointNaN(t *testing.T) {
 	test(t, `cpu value=NaN 1000000000`,
 		tsdb.NewPoint(
@@ -1032,6 +1060,81 @@ func TestNewPointNaN(t *testing.T) {
 
 }
 
+func TestNewPointInf(t *testing.T) {
+	test(t, `cpu value=+Inf 1000000000`,
+		tsdb.NewPoint(
+			"cpu",
+			tsdb.Tags{},
+			tsdb.Fields{
+				"value": math.Inf(1),
+			},
+			time.Unix(1, 0)),
+	)
+
+	test(t, `cpu value=-inf,other=1 1000000000`,
+		tsdb.NewPoint(
+			"cpu",
+			tsdb.Tags{},
+			tsdb.Fields{
+				"value": math.Inf(-1),
+				"other": 1.0,
+			},
+			time.Unix(1, 0)),
+	)
+
+	test(t, `cpu value=Inf`,
+		tsdb.NewPoint(
+			"cpu",
+			tsdb.Tags{},
+			tsdb.Fields{
+				"value": math.Inf(1),
+			},
+			time.Unix(0, 0)),
+	)
+
+	if _, err := tsdb.ParsePointsString(`cpu value=Infinity`); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+// Ensure NaN and infinite values are rejected, dropped or stored by the float policy.
+func TestApplyFloatPolicy(t *testing.T) {
+	points, err := tsdb.ParsePointsString("cpu value=1 1\ncpu value=NaN,other=1 2\ncpu value=-Inf 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tsdb.ApplyFloatPolicy(points, tsdb.FloatPolicyReject); err == nil || !strings.HasPrefix(err.Error(), tsdb.ErrNonFiniteFloat.Error()) {
+		t.Fatalf("unexpected error: %v", err)
+	} else if a, err := tsdb.ApplyFloatPolicy(points[:1], tsdb.FloatPolicyReject); err != nil || len(a) != 1 {
+		t.Fatalf("unexpected points: %v (%v)", a, err)
+	}
+
+	if a, err := tsdb.ApplyFloatPolicy(points, tsdb.FloatPolicyStore); err != nil || len(a) != 3 {
+		t.Fatalf("unexpected points: %v (%v)", a, err)
+	}
+
+	a, err := tsdb.ApplyFloatPolicy(points, tsdb.FloatPolicyDrop)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(a) != 2 {
+		t.Fatalf("unexpected points: %v", a)
+	} else if fields := a[1].Fields(); !reflect.DeepEqual(fields, tsdb.Fields{"other": 1.0}) || a[1].UnixNano() != 2 {
+		t.Fatalf("unexpected point: %v", a[1])
+	} else if _, ok := points[1].Fields()["value"]; !ok {
+		t.Fatal("original point changed")
+	}
+
+	for s, exp := range map[string]tsdb.FloatPolicy{"reject": tsdb.FloatPolicyReject, "DROP": tsdb.FloatPolicyDrop, "store": tsdb.FloatPolicyStore} {
+		if p, err := tsdb.ParseFloatPolicy(s); err != nil || p != exp {
+			t.Fatalf("unexpected policy for %s: %v (%v)", s, p, err)
+		}
+	}
+	if _, err := tsdb.ParseFloatPolicy("keep"); err != tsdb.ErrInvalidFloatPolicy {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
 func TestNewPointLargeNumberOfTags(t *testing.T) {
 	tags := ""
 	for i := 0; i < 255; i++ {
@@ -1164,6 +1267,18 @@ func TestParsePointsWithPrecision(t *testing.T) {
 			precision: "h",
 			exp:       "cpu,host=serverA,region=us-east value=1.0 946728000000000000",
 		},
+		{
+			name:      "nanosecond (ns)",
+			line:      `cpu,host=serverA,region=us-east value=1.0 946730096789012345`,
+			precision: "ns",
+			exp:       "cpu,host=serverA,region=us-east value=1.0 946730096789012345",
+		},
+		{
+			name:      "microsecond (us)",
+			line:      `cpu,host=serverA,region=us-east value=1.0 946730096789012`,
+			precision: "us",
+			exp:       "cpu,host=serverA,region=us-east value=1.0 946730096789012000",
+		},
 	}
 	for _, test := range tests {
 		pts, err := tsdb.ParsePointsWithPrecision([]byte(test.line), time.Now().UTC(), test.precision)
@@ -1182,6 +1297,30 @@ func TestParsePointsWithPrecision(t *testing.T) {
 	}
 }
 
+func TestParsePointsWithPrecisionErrors(t *testing.T) {
+	if _, err := tsdb.ParsePointsWithPrecision([]byte(`cpu value=1 1`), time.Now().UTC(), "d"); err == nil || err.Error() != `invalid precision: "d"` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The largest timestamps of each precision that fit in nanoseconds.
+	for _, test := range []struct {
+		precision string
+		max       string
+	}{
+		{"n", "9223372036854775807"},
+		{"u", "9223372036854775"},
+		{"s", "9223372036"},
+		{"h", "2562047"},
+	} {
+		if _, err := tsdb.ParsePointsWithPrecision([]byte(`cpu value=1 `+test.max), time.Now().UTC(), test.precision); err != nil {
+			t.Fatalf("%s: unexpected error: %s", test.precision, err)
+		}
+		if _, err := tsdb.ParsePointsWithPrecision([]byte(`cpu value=1 `+test.max+`0`), time.Now().UTC(), test.precision); err == nil || !strings.Contains(err.Error(), "out of range") {
+			t.Fatalf("%s: unexpected error: %v", test.precision, err)
+		}
+	}
+}
+
 func TestParsePointsWithPrecisionNoTime(t *testing.T) {
 	line := `cpu,host=serverA,region=us-east value=1.0`
 	tm, _ := time.Parse(time.RFC3339Nano, "2000-01-01T12:34:56.789012345Z")
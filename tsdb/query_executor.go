@@ -2,15 +2,22 @@ package tsdb
 
 import (
 	"errors"
+	"expvar"
 	"fmt"
-	"log"
+	"hash/fnv"
+	"math"
 	"os"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/influxdb/influxdb"
 	"github.com/influxdb/influxdb/influxql"
+	"github.com/influxdb/influxdb/logger"
 	"github.com/influxdb/influxdb/meta"
+	"github.com/influxdb/influxdb/pkg/roaring"
 )
 
 // QueryExecutor executes every statement in an influxdb Query. It is responsible for
@@ -28,6 +35,7 @@ type QueryExecutor struct {
 		RetentionPolicy(database, name string) (rpi *meta.RetentionPolicyInfo, err error)
 		UserCount() (int, error)
 		ShardGroupsByTimeRange(database, policy string, min, max time.Time) (a []meta.ShardGroupInfo, err error)
+		CreateShardGroupIfNotExists(database, policy string, timestamp time.Time) (*meta.ShardGroupInfo, error)
 		NodeID() uint64
 	}
 
@@ -41,27 +49,366 @@ type QueryExecutor struct {
 		ExecuteStatement(stmt influxql.Statement) *influxql.Result
 	}
 
+	// Execute statements relating to hinted handoff queues.
+	HintedHandoffStatementExecutor interface {
+		ExecuteStatement(stmt influxql.Statement) *influxql.Result
+	}
+
+	// Execute statements relating to copying shards between nodes.
+	CopierStatementExecutor interface {
+		ExecuteStatement(stmt influxql.Statement) *influxql.Result
+	}
+
+	// Execute statements relating to continuous query execution.
+	// Nil if the continuous query service is disabled.
+	ContinuousQueryStatementExecutor interface {
+		ExecuteStatement(stmt influxql.Statement) *influxql.Result
+	}
+
+	// Writes the results of SELECT INTO statements, which may target
+	// another database. Nil if SELECT INTO is unsupported.
+	IntoWriter interface {
+		WritePointsInto(p *IntoWriteRequest) error
+	}
+
 	// Maps shards for queries.
 	ShardMapper interface {
-		CreateMapper(shard meta.ShardInfo, stmt influxql.Statement, chunkSize int) (Mapper, error)
+		CreateMapper(shard meta.ShardInfo, stmt influxql.Statement, chunkSize int, opt *ReadOptions) (Mapper, error)
 	}
 
-	Logger *log.Logger
+	Logger *logger.Logger
 
 	// the local data store
 	Store *Store
+
+	// The ID of the last query executed. Logged with each statement so the
+	// statements of a query can be grouped.
+	queryID uint64
+
+	// Hooks applied to every statement before it is executed.
+	mu        sync.RWMutex
+	rewriters []StatementRewriter
+
+	// Number of queries running per user, for enforcing user limits.
+	running map[string]int
+
+	// Limits and resource usage of each limited database.
+	databases map[string]*databaseUsage
+
+	// Limits of the results of every SELECT statement.
+	selectLimits SelectLimits
+
+	// Admits the SELECT statements of all queries, interactive ones first.
+	queue queryQueue
+}
+
+// SelectLimits bound the results of every SELECT statement so an unbounded
+// query can't exhaust the memory of the server. Zero is unlimited.
+type SelectLimits struct {
+	// Maximum number of points returned by a statement.
+	MaxPointN int
+
+	// Maximum number of series returned by a statement.
+	MaxSeriesN int
+
+	// Truncate results at the limits and mark them partial instead of
+	// failing the statement.
+	Truncate bool
+}
+
+// DatabaseLimits are the resources the statements run against a database
+// may use at once, so one database can't starve the others. Zero is unlimited.
+type DatabaseLimits struct {
+	// Maximum number of statements running at once.
+	MaxQueries int
+
+	// Maximum estimated bytes of points read by the running SELECT statements.
+	MaxQueryMemory int64
+}
+
+// databaseUsage tracks the resources used by the statements of a database.
+type databaseUsage struct {
+	limits  DatabaseLimits
+	running int
+	memory  int64 // accessed atomically
+}
+
+// IntoWriteRequest is a request to write the results of a SELECT INTO
+// statement to its target database and retention policy.
+type IntoWriteRequest struct {
+	Database        string
+	RetentionPolicy string
+	Points          []Point
+}
+
+// StatementRewriter rewrites a statement before it is executed. The statement
+// is normalized, so its measurements are fully qualified, and database is the
+// database the statement runs against. It returns the statement to execute,
+// which may be the statement it was passed, or an error to reject it.
+type StatementRewriter interface {
+	RewriteStatement(stmt influxql.Statement, database string) (influxql.Statement, error)
 }
 
+// StatementRewriterFunc is a function that implements StatementRewriter.
+type StatementRewriterFunc func(stmt influxql.Statement, database string) (influxql.Statement, error)
+
+// RewriteStatement calls fn(stmt, database).
+func (fn StatementRewriterFunc) RewriteStatement(stmt influxql.Statement, database string) (influxql.Statement, error) {
+	return fn(stmt, database)
+}
+
+// The statistics tracked per database.
+const (
+	statDatabaseQueryReq = "query_req"   // Number of statements executed
+	statDatabaseQueryErr = "query_error" // Number of statements that failed
+)
+
 // NewQueryExecutor returns an initialized QueryExecutor
 func NewQueryExecutor(store *Store) *QueryExecutor {
 	return &QueryExecutor{
 		Store:  store,
-		Logger: log.New(os.Stderr, "[query] ", log.LstdFlags),
+		Logger: logger.New(os.Stderr, "query"),
+	}
+}
+
+// AddStatementRewriter registers a hook that rewrites every statement before
+// it is executed. Hooks run in the order they were added.
+func (q *QueryExecutor) AddStatementRewriter(r StatementRewriter) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.rewriters = append(q.rewriters, r)
+}
+
+// rewriteStatement applies the registered rewriters to a statement.
+func (q *QueryExecutor) rewriteStatement(stmt influxql.Statement, database string) (influxql.Statement, error) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	for _, r := range q.rewriters {
+		s, err := r.RewriteStatement(stmt, database)
+		if err != nil {
+			return nil, err
+		}
+		stmt = s
+	}
+	return stmt, nil
+}
+
+// ReadPreference determines which replica of a shard serves a query.
+type ReadPreference int
+
+const (
+	// ReadPreferenceNearest reads from the local node if it owns the shard,
+	// otherwise from a random owner.
+	ReadPreferenceNearest ReadPreference = iota
+
+	// ReadPreferenceLeader always reads from the shard's first owner.
+	ReadPreferenceLeader
+
+	// ReadPreferenceRandom reads from a random owner, spreading load
+	// across all replicas of a shard.
+	ReadPreferenceRandom
+)
+
+// ParseReadPreference converts a read preference string to a ReadPreference.
+// An empty string returns ReadPreferenceNearest.
+func ParseReadPreference(s string) (ReadPreference, error) {
+	switch strings.ToLower(s) {
+	case "", "nearest":
+		return ReadPreferenceNearest, nil
+	case "leader":
+		return ReadPreferenceLeader, nil
+	case "random":
+		return ReadPreferenceRandom, nil
+	default:
+		return 0, fmt.Errorf("invalid read preference: %q", s)
+	}
+}
+
+// String returns the string representation of the read preference.
+func (p ReadPreference) String() string {
+	switch p {
+	case ReadPreferenceLeader:
+		return "leader"
+	case ReadPreferenceRandom:
+		return "random"
+	default:
+		return "nearest"
+	}
+}
+
+// ReadOptions are passed to the shard mapper for each shard read by a statement.
+type ReadOptions struct {
+	Preference ReadPreference
+
+	// Staleness is raised by the shard mapper to the longest time any
+	// replica chosen for the statement is known to be behind on writes.
+	Staleness time.Duration
+
+	// Maximum number of points the statement may read from all shards.
+	// Zero is unlimited.
+	MaxPoints int64
+
+	// Memory budget of the statement's database, if it is limited.
+	budget *queryBudget
+
+	// Statistics of the data read by the mappers, if requested.
+	stats *MapperStats
+}
+
+// statsReporter is a mapper which reports the statistics of the data it has
+// read so far. Returns false if it can't.
+type statsReporter interface {
+	Stats() (MapperStats, bool)
+}
+
+// statsMapper adds the statistics of a mapper to those of its statement.
+// Mappers which can't report statistics count the values they return as
+// points scanned.
+type statsMapper struct {
+	Mapper
+	stats *MapperStats // statistics of all mappers of the statement
+	prev  MapperStats  // statistics of the mapper already added
+}
+
+// NextChunk returns the next chunk of the underlying mapper.
+func (m *statsMapper) NextChunk() (interface{}, error) {
+	c, err := m.Mapper.NextChunk()
+	if err != nil {
+		return nil, err
+	}
+
+	if sm, ok := m.Mapper.(statsReporter); ok {
+		if st, ok := sm.Stats(); ok {
+			atomic.AddInt64(&m.stats.SeriesN, st.SeriesN-m.prev.SeriesN)
+			atomic.AddInt64(&m.stats.PointN, st.PointN-m.prev.PointN)
+			atomic.AddInt64(&m.stats.CacheHitN, st.CacheHitN-m.prev.CacheHitN)
+			m.prev = st
+			return c, nil
+		}
+	}
+	if mo, ok := c.(*MapperOutput); ok && mo != nil {
+		atomic.AddInt64(&m.stats.PointN, int64(len(mo.Values)))
+	}
+	return c, nil
+}
+
+// limitMapper fails a mapper once the mappers of a statement have scanned
+// more than max points in total. Mappers which can't report statistics
+// count the values they return as points scanned.
+type limitMapper struct {
+	Mapper
+	n    *int64 // points scanned by all mappers of the statement
+	max  int64
+	prev int64 // points of the mapper already added
+}
+
+// NextChunk returns the next chunk of the underlying mapper.
+func (m *limitMapper) NextChunk() (interface{}, error) {
+	c, err := m.Mapper.NextChunk()
+	if err != nil {
+		return nil, err
+	}
+
+	if sm, ok := m.Mapper.(statsReporter); ok {
+		if st, ok := sm.Stats(); ok {
+			n := st.PointN - m.prev
+			m.prev = st.PointN
+			return c, m.add(n)
+		}
+	}
+	if mo, ok := c.(*MapperOutput); ok && mo != nil {
+		return c, m.add(int64(len(mo.Values)))
+	}
+	return c, nil
+}
+
+// add adds n points to those scanned by the statement. Returns an error if
+// they are over the limit.
+func (m *limitMapper) add(n int64) error {
+	if atomic.AddInt64(m.n, n) > m.max {
+		return ErrMaxPointsExceeded
+	}
+	return nil
+}
+
+// queryBudget charges the estimated size of the points read by a statement
+// against the memory limit of its database.
+type queryBudget struct {
+	usage *databaseUsage
+	max   int64
+	n     int64 // bytes charged by the statement, accessed atomically
+}
+
+// charge adds n bytes to the memory used by the database. Returns an error
+// if the database uses more than its limit.
+func (b *queryBudget) charge(n int64) error {
+	atomic.AddInt64(&b.n, n)
+	if atomic.AddInt64(&b.usage.memory, n) > b.max {
+		return ErrDatabaseMaxMemoryExceeded
+	}
+	return nil
+}
+
+// release returns the bytes charged by the statement to the database.
+func (b *queryBudget) release() {
+	atomic.AddInt64(&b.usage.memory, -atomic.SwapInt64(&b.n, 0))
+}
+
+// budgetMapper charges the points returned by a mapper to a query budget.
+type budgetMapper struct {
+	Mapper
+	budget *queryBudget
+}
+
+// NextChunk returns the next chunk of the underlying mapper.
+func (m *budgetMapper) NextChunk() (interface{}, error) {
+	c, err := m.Mapper.NextChunk()
+	if err != nil {
+		return nil, err
+	}
+	if mo, ok := c.(*MapperOutput); ok && mo != nil {
+		if err := m.budget.charge(mapperOutputSize(mo)); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// mapperOutputSize returns an estimate of the bytes used by the values of mo.
+func mapperOutputSize(mo *MapperOutput) int64 {
+	var n int64
+	for _, v := range mo.Values {
+		n += 48 + valueSize(v.Value)
+		for k, v := range v.Tags {
+			n += int64(len(k) + len(v))
+		}
 	}
+	return n
+}
+
+// valueSize returns an estimate of the bytes used by a mapper value.
+func valueSize(v interface{}) int64 {
+	switch v := v.(type) {
+	case string:
+		return 16 + int64(len(v))
+	case map[string]interface{}:
+		var n int64
+		for k, v := range v {
+			n += 16 + int64(len(k)) + valueSize(v)
+		}
+		return n
+	case []interface{}:
+		var n int64
+		for _, v := range v {
+			n += 16 + valueSize(v)
+		}
+		return n
+	}
+	return 8
 }
 
 // SetLogger sets the internal logger to the logger passed in.
-func (q *QueryExecutor) SetLogger(l *log.Logger) {
+func (q *QueryExecutor) SetLogger(l *logger.Logger) {
 	q.Logger = l
 }
 
@@ -70,21 +417,26 @@ func (q *QueryExecutor) SetLogger(l *log.Logger) {
 // If no user is provided it will return an error unless the query's first statement is to create
 // a root user.
 func (q *QueryExecutor) Authorize(u *meta.UserInfo, query *influxql.Query, database string) error {
-	// Special case if no users exist.
-	if count, err := q.MetaStore.UserCount(); count == 0 && err == nil {
-		// Ensure there is at least one statement.
-		if len(query.Statements) > 0 {
-			// First statement in the query must create a user with admin privilege.
-			cu, ok := query.Statements[0].(*influxql.CreateUserStatement)
-			if ok && cu.Admin == true {
-				return nil
+	if u == nil {
+		// Special case if no users exist. Users authenticated by an external
+		// provider are authorized even if the meta store has no users.
+		if count, err := q.MetaStore.UserCount(); count == 0 && err == nil {
+			// Ensure there is at least one statement.
+			if len(query.Statements) > 0 {
+				// First statement in the query must create a user with admin privilege.
+				cu, ok := query.Statements[0].(*influxql.CreateUserStatement)
+				if ok && cu.Admin == true {
+					return nil
+				}
 			}
+			return NewErrAuthorize(q, query, "", database, "create admin user first or disable authentication")
 		}
-		return NewErrAuthorize(q, query, "", database, "create admin user first or disable authentication")
+		return NewErrAuthorize(q, query, "", database, "no user provided")
 	}
 
-	if u == nil {
-		return NewErrAuthorize(q, query, "", database, "no user provided")
+	// Write-only users may only write points, not execute statements.
+	if u.WriteOnly {
+		return NewErrAuthorize(q, query, u.Name, database, "write-only users can't execute statements")
 	}
 
 	// Admin privilege allows the user to execute all statements.
@@ -126,97 +478,403 @@ func (q *QueryExecutor) Authorize(u *meta.UserInfo, query *influxql.Query, datab
 // ExecuteQuery executes an InfluxQL query against the server.
 // It sends results down the passed in chan and closes it when done. It will close the chan
 // on the first statement that throws an error.
-func (q *QueryExecutor) ExecuteQuery(query *influxql.Query, database string, chunkSize int) (<-chan *influxql.Result, error) {
+// Shards are read from the replicas chosen by readPref. The query is subject to
+// the limits of u, if a user is given.
+func (q *QueryExecutor) ExecuteQuery(query *influxql.Query, database string, chunkSize int, readPref ReadPreference, u *meta.UserInfo) (<-chan *influxql.Result, error) {
+	// Count the query against the user's limit on concurrent queries.
+	var limits meta.UserLimits
+	if u != nil {
+		limits = u.Limits
+		if err := q.startUserQuery(u.Name, limits.MaxQueries); err != nil {
+			results := make(chan *influxql.Result, len(query.Statements))
+			results <- &influxql.Result{Err: err}
+			for i := 1; i < len(query.Statements); i++ {
+				results <- &influxql.Result{StatementID: i, Err: ErrNotExecuted}
+			}
+			close(results)
+			return results, nil
+		}
+	}
+
+	// A query is a batch query if it asks to be or its user is a batch user.
+	limits.Batch = limits.Batch || query.Batch
+
 	// Execute each statement. Keep the iterator external so we can
 	// track how many of the statements were executed
 	results := make(chan *influxql.Result)
+	qlog := q.Logger.With("query_id", atomic.AddUint64(&q.queryID, 1))
 	go func() {
 		var i int
 		var stmt influxql.Statement
 		for i, stmt = range query.Statements {
-			// If a default database wasn't passed in by the caller, check the statement.
-			// Some types of statements have an associated default database, even if it
-			// is not explicitly included.
-			defaultDB := database
-			if defaultDB == "" {
-				if s, ok := stmt.(influxql.HasDefaultDatabase); ok {
-					defaultDB = s.DefaultDatabase()
-				}
-			}
-
-			// Normalize each statement.
-			if err := q.normalizeStatement(stmt, defaultDB); err != nil {
-				results <- &influxql.Result{Err: err}
+			// Each statement has its own result or error. Unless the query
+			// fails fast, the statements after a failed one still run.
+			if err := q.executeStatement(i, stmt, database, results, chunkSize, readPref, limits, query.Stats, query.Rollup, qlog); err != nil && query.FailFast {
 				break
 			}
-
-			// Log each normalized statement.
-			q.Logger.Println(stmt.String())
-
-			var res *influxql.Result
-			switch stmt := stmt.(type) {
-			case *influxql.SelectStatement:
-				if err := q.executeSelectStatement(i, stmt, results, chunkSize); err != nil {
-					results <- &influxql.Result{Err: err}
-					break
-				}
-			case *influxql.DropSeriesStatement:
-				// TODO: handle this in a cluster
-				res = q.executeDropSeriesStatement(stmt, database)
-			case *influxql.ShowSeriesStatement:
-				res = q.executeShowSeriesStatement(stmt, database)
-			case *influxql.DropMeasurementStatement:
-				// TODO: handle this in a cluster
-				res = q.executeDropMeasurementStatement(stmt, database)
-			case *influxql.ShowMeasurementsStatement:
-				if err := q.executeShowMeasurementsStatement(i, stmt, database, results, chunkSize); err != nil {
-					results <- &influxql.Result{Err: err}
-					break
-				}
-			case *influxql.ShowTagKeysStatement:
-				res = q.executeShowTagKeysStatement(stmt, database)
-			case *influxql.ShowTagValuesStatement:
-				res = q.executeShowTagValuesStatement(stmt, database)
-			case *influxql.ShowFieldKeysStatement:
-				res = q.executeShowFieldKeysStatement(stmt, database)
-			case *influxql.DeleteStatement:
-				res = &influxql.Result{Err: ErrInvalidQuery}
-			case *influxql.DropDatabaseStatement:
-				// TODO: handle this in a cluster
-				res = q.executeDropDatabaseStatement(stmt)
-			case *influxql.ShowStatsStatement, *influxql.ShowDiagnosticsStatement:
-				// Send monitor-related queries to the monitor service.
-				res = q.MonitorStatementExecutor.ExecuteStatement(stmt)
-			default:
-				// Delegate all other meta statements to a separate executor. They don't hit tsdb storage.
-				res = q.MetaStatementExecutor.ExecuteStatement(stmt)
-			}
-
-			if res != nil {
-				// set the StatementID for the handler on the other side to combine results
-				res.StatementID = i
-
-				// If an error occurs then stop processing remaining statements.
-				results <- res
-				if res.Err != nil {
-					break
-				}
-			}
 		}
 
 		// if there was an error send results that the remaining statements weren't executed
 		for ; i < len(query.Statements)-1; i++ {
-			results <- &influxql.Result{Err: ErrNotExecuted}
+			results <- &influxql.Result{StatementID: i + 1, Err: ErrNotExecuted}
 		}
 
+		if u != nil {
+			q.finishUserQuery(u.Name)
+		}
 		close(results)
 	}()
 
 	return results, nil
 }
 
+// QueryLastModified returns the last time the points read by a query were
+// modified and the IDs of the shards read, sorted. Returns false if the
+// results of the query may change without a shard being modified: unless
+// every statement is a SELECT of a time range ended in the past, without
+// now(), reading only shards of this node.
+func (q *QueryExecutor) QueryLastModified(query *influxql.Query, database string) (time.Time, []uint64, bool) {
+	if query.Rollup || query.Stats || len(query.Statements) == 0 {
+		return time.Time{}, nil, false
+	}
+
+	now := time.Now().UTC()
+	var lastModified time.Time
+	shards := make(map[uint64]meta.ShardInfo)
+	for _, s := range query.Statements {
+		stmt, ok := s.(*influxql.SelectStatement)
+		if !ok || stmt.Target != nil || hasNowCall(stmt.Condition) {
+			return time.Time{}, nil, false
+		}
+
+		// Plan against a copy, so the statement is left as it was parsed.
+		stmt = stmt.Clone()
+		if err := q.normalizeStatement(stmt, database); err != nil {
+			return time.Time{}, nil, false
+		}
+		rewritten, err := q.rewriteStatement(stmt, database)
+		if err != nil {
+			return time.Time{}, nil, false
+		}
+		if stmt, ok = rewritten.(*influxql.SelectStatement); !ok {
+			return time.Time{}, nil, false
+		}
+
+		tmin, tmax := influxql.TimeRange(stmt.Condition)
+		if tmax.IsZero() || !tmax.Before(now) {
+			return time.Time{}, nil, false
+		}
+		if tmin.IsZero() {
+			tmin = time.Unix(0, 0)
+		}
+
+		for _, src := range stmt.Sources {
+			mm, ok := src.(*influxql.Measurement)
+			if !ok {
+				return time.Time{}, nil, false
+			}
+			groups, err := q.MetaStore.ShardGroupsByTimeRange(mm.Database, mm.RetentionPolicy, tmin, tmax)
+			if err != nil {
+				return time.Time{}, nil, false
+			}
+			for _, g := range groups {
+				for _, si := range g.Shards {
+					sh := q.Store.Shard(si.ID)
+					if sh == nil {
+						return time.Time{}, nil, false
+					}
+					if t := sh.LastModified(); t.After(lastModified) {
+						lastModified = t
+					}
+					shards[si.ID] = si
+				}
+			}
+		}
+	}
+	if lastModified.IsZero() {
+		return time.Time{}, nil, false
+	}
+
+	sorted := make(shardInfos, 0, len(shards))
+	for _, si := range shards {
+		sorted = append(sorted, si)
+	}
+	sort.Sort(sorted)
+	ids := make([]uint64, len(sorted))
+	for i, si := range sorted {
+		ids[i] = si.ID
+	}
+	return lastModified, ids, true
+}
+
+// hasNowCall returns true if expr calls now().
+func hasNowCall(expr influxql.Expr) bool {
+	var found bool
+	influxql.WalkFunc(expr, func(n influxql.Node) {
+		if call, ok := n.(*influxql.Call); ok && strings.ToLower(call.Name) == "now" {
+			found = true
+		}
+	})
+	return found
+}
+
+// executeStatement executes the statement at index i of a query and sends its
+// results, with its statistics if stats is set. SELECT statements read rolled
+// up points if rollup is set. Returns the error of the statement, which is
+// sent as its result.
+func (q *QueryExecutor) executeStatement(i int, stmt influxql.Statement, database string, results chan *influxql.Result, chunkSize int, readPref ReadPreference, limits meta.UserLimits, stats, rollup bool, qlog *logger.Logger) error {
+	start := time.Now()
+
+	// If a default database wasn't passed in by the caller, check the statement.
+	// Some types of statements have an associated default database, even if it
+	// is not explicitly included.
+	defaultDB := database
+	if defaultDB == "" {
+		if s, ok := stmt.(influxql.HasDefaultDatabase); ok {
+			defaultDB = s.DefaultDatabase()
+		}
+	}
+
+	// Count the statement against its database, if it has one.
+	var dbStats *expvar.Map
+	if defaultDB != "" {
+		dbStats = influxdb.DatabaseStatistics(defaultDB)
+		dbStats.Add(statDatabaseQueryReq, 1)
+	}
+	fail := func(err error) error {
+		if dbStats != nil {
+			dbStats.Add(statDatabaseQueryErr, 1)
+		}
+		results <- &influxql.Result{StatementID: i, Err: err}
+		return err
+	}
+
+	// Normalize each statement.
+	if err := q.normalizeStatement(stmt, defaultDB); err != nil {
+		return fail(err)
+	}
+
+	// Apply the registered rewrite hooks.
+	s, err := q.rewriteStatement(stmt, defaultDB)
+	if err != nil {
+		return fail(err)
+	}
+	stmt = s
+
+	// Count the statement against the limits of its database.
+	du, err := q.startDatabaseQuery(defaultDB)
+	if err != nil {
+		return fail(err)
+	}
+
+	// Log each normalized statement.
+	qlog.Info("executing statement", "database", defaultDB, "statement", stmt.String())
+
+	var res *influxql.Result
+	switch stmt := stmt.(type) {
+	case *influxql.SelectStatement:
+		// Wait for the server to have room for the statement.
+		q.queue.acquire(limits.Batch)
+		err := q.executeSelectStatement(i, stmt, results, chunkSize, readPref, limits, du, stats, rollup)
+		q.queue.release()
+		if err != nil {
+			q.finishDatabaseQuery(du)
+			return fail(err)
+		}
+	case *influxql.DropSeriesStatement:
+		// TODO: handle this in a cluster
+		res = q.executeDropSeriesStatement(stmt, database)
+	case *influxql.ShowSeriesStatement:
+		res = q.executeShowSeriesStatement(stmt, database)
+	case *influxql.CreateSeriesStatement:
+		// TODO: handle this in a cluster
+		res = q.executeCreateSeriesStatement(stmt)
+	case *influxql.DropMeasurementStatement:
+		// TODO: handle this in a cluster
+		res = q.executeDropMeasurementStatement(stmt, database)
+	case *influxql.RenameMeasurementStatement:
+		// TODO: handle this in a cluster
+		res = &influxql.Result{Err: q.Store.RenameMeasurement(database, stmt.Name, stmt.NewName)}
+	case *influxql.RenameTagKeyStatement:
+		// TODO: handle this in a cluster
+		res = rewriteResult(q.Store.RenameTagKey(database, stmt.Measurement, stmt.Key, stmt.NewKey))
+	case *influxql.RenameTagValueStatement:
+		// TODO: handle this in a cluster
+		res = rewriteResult(q.Store.RenameTagValue(database, stmt.Measurement, stmt.Key, stmt.Value, stmt.NewValue))
+	case *influxql.ShowMeasurementsStatement:
+		if err := q.executeShowMeasurementsStatement(i, stmt, database, results, chunkSize, readPref); err != nil {
+			q.finishDatabaseQuery(du)
+			return fail(err)
+		}
+	case *influxql.ShowTagKeysStatement:
+		res = q.executeShowTagKeysStatement(stmt, database)
+	case *influxql.ShowTagValuesStatement:
+		res = q.executeShowTagValuesStatement(stmt, database)
+	case *influxql.ShowFieldKeysStatement:
+		res = q.executeShowFieldKeysStatement(stmt, database)
+	case *influxql.DeleteStatement:
+		res = &influxql.Result{Err: ErrInvalidQuery}
+	case *influxql.DropDatabaseStatement:
+		// TODO: handle this in a cluster
+		res = q.executeDropDatabaseStatement(stmt)
+	case *influxql.RenameDatabaseStatement:
+		// TODO: handle this in a cluster
+		res = q.executeRenameDatabaseStatement(stmt)
+	case *influxql.ShowStatsStatement, *influxql.ShowDiagnosticsStatement:
+		// Send monitor-related queries to the monitor service.
+		res = q.MonitorStatementExecutor.ExecuteStatement(stmt)
+	case *influxql.ShowHintedHandoffStatement, *influxql.DropHintedHandoffStatement:
+		// Send hinted handoff queries to the hinted handoff service.
+		res = q.HintedHandoffStatementExecutor.ExecuteStatement(stmt)
+	case *influxql.CopyShardStatement, *influxql.MoveShardStatement:
+		// Send shard transfers to the copier service.
+		res = q.CopierStatementExecutor.ExecuteStatement(stmt)
+	case *influxql.ShowRetentionPoliciesStatement:
+		res = q.executeShowRetentionPoliciesStatement(stmt)
+	case *influxql.AlterRetentionPolicyStatement:
+		// A new replication factor also applies to the existing shard groups,
+		// so have the copier service create the missing replicas in the
+		// background.
+		res = q.MetaStatementExecutor.ExecuteStatement(stmt)
+		if res.Err == nil && stmt.Replication != nil && q.CopierStatementExecutor != nil {
+			res = q.CopierStatementExecutor.ExecuteStatement(stmt)
+		}
+	case *influxql.ShowContinuousQueriesStatusStatement:
+		// Send continuous query status queries to the continuous query service.
+		if q.ContinuousQueryStatementExecutor == nil {
+			res = &influxql.Result{Err: ErrContinuousQueriesDisabled}
+		} else {
+			res = q.ContinuousQueryStatementExecutor.ExecuteStatement(stmt)
+		}
+	default:
+		// Delegate all other meta statements to a separate executor. They don't hit tsdb storage.
+		res = q.MetaStatementExecutor.ExecuteStatement(stmt)
+	}
+	q.finishDatabaseQuery(du)
+
+	if res != nil {
+		// set the StatementID for the handler on the other side to combine results
+		res.StatementID = i
+		if stats && res.Err == nil {
+			res.Stats = &influxql.Stats{Duration: time.Since(start)}
+		}
+		results <- res
+		if res.Err != nil && dbStats != nil {
+			dbStats.Add(statDatabaseQueryErr, 1)
+		}
+		return res.Err
+	}
+	return nil
+}
+
+// startUserQuery counts a query against the queries running for a user.
+// Returns an error if the user already runs max queries. A max of zero is unlimited.
+func (q *QueryExecutor) startUserQuery(name string, max int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if max > 0 && q.running[name] >= max {
+		return ErrMaxQueriesExceeded
+	}
+	if q.running == nil {
+		q.running = make(map[string]int)
+	}
+	q.running[name]++
+	return nil
+}
+
+// finishUserQuery removes a finished query from the queries running for a user.
+func (q *QueryExecutor) finishUserQuery(name string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.running[name]--; q.running[name] <= 0 {
+		delete(q.running, name)
+	}
+}
+
+// SetDatabaseLimits sets the limits of each database. Databases not in
+// limits are unlimited. It is safe to call while queries are running.
+func (q *QueryExecutor) SetDatabaseLimits(limits map[string]DatabaseLimits) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.databases == nil {
+		q.databases = make(map[string]*databaseUsage)
+	}
+	for name, du := range q.databases {
+		if _, ok := limits[name]; !ok {
+			du.limits = DatabaseLimits{}
+		}
+	}
+	for name, l := range limits {
+		du := q.databases[name]
+		if du == nil {
+			du = &databaseUsage{}
+			q.databases[name] = du
+		}
+		du.limits = l
+	}
+}
+
+// SetSelectLimits sets the limits of the results of SELECT statements. It is
+// safe to call while queries are running.
+func (q *QueryExecutor) SetSelectLimits(limits SelectLimits) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.selectLimits = limits
+}
+
+// SetMaxConcurrentSelects sets the number of SELECT statements that may run
+// at once. The statements over the limit wait, and those of interactive
+// queries run before those of batch queries. Zero is unlimited. It is safe to
+// call while queries are running.
+func (q *QueryExecutor) SetMaxConcurrentSelects(n int) {
+	q.queue.setMax(n)
+}
+
+// startDatabaseQuery counts a statement against the statements running for a
+// database. Returns nil if the database isn't limited, or an error if it
+// already runs as many statements as its limit allows.
+func (q *QueryExecutor) startDatabaseQuery(name string) (*databaseUsage, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	du := q.databases[name]
+	if du == nil {
+		return nil, nil
+	}
+	if du.limits.MaxQueries > 0 && du.running >= du.limits.MaxQueries {
+		return nil, ErrDatabaseMaxQueriesExceeded
+	}
+	du.running++
+	return du, nil
+}
+
+// finishDatabaseQuery removes a finished statement from the statements
+// running for a database.
+func (q *QueryExecutor) finishDatabaseQuery(du *databaseUsage) {
+	if du == nil {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	du.running--
+}
+
+// newQueryBudget returns the memory budget of a statement run against a
+// database with usage du. Returns nil if the database has no memory limit.
+func (q *QueryExecutor) newQueryBudget(du *databaseUsage) *queryBudget {
+	if du == nil {
+		return nil
+	}
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if du.limits.MaxQueryMemory <= 0 {
+		return nil
+	}
+	return &queryBudget{usage: du, max: du.limits.MaxQueryMemory}
+}
+
 // Plan creates an execution plan for the given SelectStatement and returns an Executor.
-func (q *QueryExecutor) PlanSelect(stmt *influxql.SelectStatement, chunkSize int) (Executor, error) {
+// A nil opt reads from the nearest replicas.
+func (q *QueryExecutor) PlanSelect(stmt *influxql.SelectStatement, chunkSize int, opt *ReadOptions) (Executor, error) {
 	shards := map[uint64]meta.ShardInfo{} // Shards requiring mappers.
 
 	// It is important to "stamp" this time so that everywhere we evaluate `now()` in the statement is EXACTLY the same `now`
@@ -232,12 +890,28 @@ func (q *QueryExecutor) PlanSelect(stmt *influxql.SelectStatement, chunkSize int
 		tmin = time.Unix(0, 0)
 	}
 
+	interval, err := stmt.GroupByInterval()
+	if err != nil {
+		return nil, err
+	}
+
 	for _, src := range stmt.Sources {
 		mm, ok := src.(*influxql.Measurement)
 		if !ok {
 			return nil, fmt.Errorf("invalid source type: %#v", src)
 		}
 
+		// Buckets finer than the points of the retention policy are mostly
+		// empty, so reject them rather than return a misleading result.
+		if interval > 0 {
+			rpi, err := q.MetaStore.RetentionPolicy(mm.Database, mm.RetentionPolicy)
+			if err != nil {
+				return nil, err
+			} else if rpi != nil && interval < rpi.Resolution {
+				return nil, ErrIntervalBelowResolution(interval, rpi)
+			}
+		}
+
 		// Build the set of target shards. Using shard IDs as keys ensures each shard ID
 		// occurs only once.
 		shardGroups, err := q.MetaStore.ShardGroupsByTimeRange(mm.Database, mm.RetentionPolicy, tmin, tmax)
@@ -251,10 +925,19 @@ func (q *QueryExecutor) PlanSelect(stmt *influxql.SelectStatement, chunkSize int
 		}
 	}
 
+	// Order the shards by ID. IDs are allocated in increasing order so the executor
+	// can keep the value from the most recently created shard when overlapping
+	// shards return the same point.
+	sorted := make(shardInfos, 0, len(shards))
+	for _, sh := range shards {
+		sorted = append(sorted, sh)
+	}
+	sort.Sort(sorted)
+
 	// Build the Mappers, one per shard.
 	mappers := []Mapper{}
-	for _, sh := range shards {
-		m, err := q.ShardMapper.CreateMapper(sh, stmt, chunkSize)
+	for _, sh := range sorted {
+		m, err := q.ShardMapper.CreateMapper(sh, stmt, chunkSize, opt)
 		if err != nil {
 			return nil, err
 		}
@@ -265,38 +948,270 @@ func (q *QueryExecutor) PlanSelect(stmt *influxql.SelectStatement, chunkSize int
 		mappers = append(mappers, m)
 	}
 
+	// Collect the statistics of the mappers, if requested.
+	if opt != nil && opt.stats != nil {
+		for i, m := range mappers {
+			mappers[i] = &statsMapper{Mapper: m, stats: opt.stats}
+		}
+	}
+
+	// Limit the points read from the shards, if required.
+	if opt != nil && opt.MaxPoints > 0 {
+		var n int64
+		for i, m := range mappers {
+			mappers[i] = &limitMapper{Mapper: m, n: &n, max: opt.MaxPoints}
+		}
+	}
+
+	// Charge the points read to the memory budget of the database, if any.
+	if opt != nil && opt.budget != nil {
+		for i, m := range mappers {
+			mappers[i] = &budgetMapper{Mapper: m, budget: opt.budget}
+		}
+	}
+
 	executor := NewSelectExecutor(stmt, mappers, chunkSize)
 	return executor, nil
 }
 
+// shardInfos sorts shards by ID.
+type shardInfos []meta.ShardInfo
+
+func (a shardInfos) Len() int           { return len(a) }
+func (a shardInfos) Less(i, j int) bool { return a[i].ID < a[j].ID }
+func (a shardInfos) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
 // executeSelectStatement plans and executes a select statement against a database.
-func (q *QueryExecutor) executeSelectStatement(statementID int, stmt *influxql.SelectStatement, results chan *influxql.Result, chunkSize int) error {
+// The statement must stay within limits and the limits of its database, if du is set.
+// Its last result has the statistics of its execution if stats is set. If
+// rollup is set, older points are read from downsampled retention policies.
+func (q *QueryExecutor) executeSelectStatement(statementID int, stmt *influxql.SelectStatement, results chan *influxql.Result, chunkSize int, readPref ReadPreference, limits meta.UserLimits, du *databaseUsage, stats, rollup bool) error {
+	start := time.Now()
+
+	// Ensure the statement doesn't cover more time than allowed.
+	if limits.MaxRange > 0 {
+		now := time.Now().UTC()
+		tmin, tmax := influxql.TimeRange(influxql.Reduce(stmt.Condition, &influxql.NowValuer{Now: now}))
+		if tmax.IsZero() {
+			tmax = now
+		}
+		if tmin.IsZero() || tmax.Sub(tmin) > limits.MaxRange {
+			return ErrMaxRangeExceeded
+		}
+	}
+
 	// Plan statement execution.
-	e, err := q.PlanSelect(stmt, chunkSize)
-	if err != nil {
-		return err
+	opt := &ReadOptions{Preference: readPref, MaxPoints: limits.MaxPoints, budget: q.newQueryBudget(du)}
+	if opt.budget != nil {
+		defer opt.budget.release()
+	}
+	if stats {
+		opt.stats = &MapperStats{}
+	}
+	var e Executor
+	var err error
+	if rollup {
+		if e, err = q.planRollupSelect(stmt, chunkSize, opt); err != nil {
+			return err
+		}
+	}
+	if e == nil {
+		if e, err = q.PlanSelect(stmt, chunkSize, opt); err != nil {
+			return err
+		}
 	}
 
 	// Execute plan.
 	ch := e.Execute()
 
+	// Write the results of a SELECT INTO instead of returning them.
+	if stmt.Target != nil {
+		return q.writeInto(statementID, stmt.Target.Measurement, ch, results, opt)
+	}
+
+	q.mu.RLock()
+	sl := q.selectLimits
+	q.mu.RUnlock()
+
+	// Each result is held back until the next one, so the statistics can be
+	// added to the last result of the statement.
+	var pending *influxql.Result
+	send := func(res *influxql.Result) {
+		if !stats {
+			results <- res
+			return
+		}
+		if pending != nil {
+			results <- pending
+		}
+		pending = res
+	}
+	defer func() {
+		if pending != nil {
+			pending.Stats = &influxql.Stats{
+				SeriesN:   atomic.LoadInt64(&opt.stats.SeriesN),
+				PointN:    atomic.LoadInt64(&opt.stats.PointN),
+				CacheHitN: atomic.LoadInt64(&opt.stats.CacheHitN),
+				Duration:  time.Since(start),
+			}
+			results <- pending
+		}
+	}()
+
 	// Stream results from the channel. We should send an empty result if nothing comes through.
 	resultSent := false
+	var prev *influxql.Row
+	var seriesN, pointN int
 	for row := range ch {
 		if row.Err != nil {
 			return row.Err
 		}
+
+		// Rows of the same series are chunked one after the other.
+		if prev == nil || !prev.SameSeries(row) {
+			seriesN++
+		}
+		prev = row
+
+		var err error
+		if sl.MaxSeriesN > 0 && seriesN > sl.MaxSeriesN {
+			row.Values, err = nil, ErrMaxSelectSeriesExceeded
+		} else if sl.MaxPointN > 0 && pointN+len(row.Values) > sl.MaxPointN {
+			row.Values, err = row.Values[:sl.MaxPointN-pointN], ErrMaxSelectPointsExceeded
+		}
+		if err != nil {
+			// Read the rest of the rows so the executor can finish.
+			for _ = range ch {
+			}
+			if !sl.Truncate {
+				return err
+			}
+
+			var series []*influxql.Row
+			if len(row.Values) > 0 {
+				series = []*influxql.Row{row}
+			}
+			send(&influxql.Result{StatementID: statementID, Series: series, Staleness: opt.Staleness, Partial: true})
+			return nil
+		}
+		pointN += len(row.Values)
+
 		resultSent = true
-		results <- &influxql.Result{StatementID: statementID, Series: []*influxql.Row{row}}
+		send(&influxql.Result{StatementID: statementID, Series: []*influxql.Row{row}, Staleness: opt.Staleness})
 	}
 
 	if !resultSent {
-		results <- &influxql.Result{StatementID: statementID, Series: make([]*influxql.Row, 0)}
+		send(&influxql.Result{StatementID: statementID, Series: make([]*influxql.Row, 0), Staleness: opt.Staleness})
+	}
+
+	return nil
+}
+
+// writeInto writes the rows of a SELECT INTO statement to the target
+// measurement and sends the number of points written as the result.
+func (q *QueryExecutor) writeInto(statementID int, target *influxql.Measurement, ch <-chan *influxql.Row, results chan *influxql.Result, opt *ReadOptions) error {
+	if q.IntoWriter == nil {
+		// Read the rest of the rows so the executor can finish.
+		for _ = range ch {
+		}
+		return ErrSelectIntoUnsupported
+	}
+
+	var written int64
+	var err error
+	for row := range ch {
+		// Read the rest of the rows after an error so the executor can finish.
+		if err != nil {
+			continue
+		}
+
+		var n int
+		n, err = q.writeRowInto(target, row)
+		written += int64(n)
+	}
+	if err != nil {
+		return err
 	}
 
+	results <- &influxql.Result{
+		StatementID: statementID,
+		Series: []*influxql.Row{{
+			Name:    "result",
+			Columns: []string{"time", "written"},
+			Values:  [][]interface{}{{time.Unix(0, 0).UTC(), written}},
+		}},
+		Staleness: opt.Staleness,
+	}
 	return nil
 }
 
+// writeRowInto writes the points of a row to the target of a SELECT INTO
+// statement. Returns the number of points written.
+func (q *QueryExecutor) writeRowInto(target *influxql.Measurement, row *influxql.Row) (int, error) {
+	if row.Err != nil {
+		return 0, row.Err
+	}
+
+	// A target without a name, such as :MEASUREMENT, keeps the name of
+	// the source measurement.
+	name := target.Name
+	if name == "" {
+		name = row.Name
+	}
+
+	points, err := convertRowToPoints(name, row)
+	if err != nil {
+		return 0, err
+	} else if len(points) == 0 {
+		return 0, nil
+	}
+
+	if err := q.IntoWriter.WritePointsInto(&IntoWriteRequest{
+		Database:        target.Database,
+		RetentionPolicy: target.RetentionPolicy,
+		Points:          points,
+	}); err != nil {
+		return 0, err
+	}
+	return len(points), nil
+}
+
+// convertRowToPoints converts a row of a SELECT INTO statement into points
+// of the measurement name. The tags of the row become the tags of the
+// points. Values that are nil, because an interval has no data, are not
+// written, nor are points with no other values.
+func convertRowToPoints(name string, row *influxql.Row) ([]Point, error) {
+	timeIndex := -1
+	for i, c := range row.Columns {
+		if c == "time" {
+			timeIndex = i
+		}
+	}
+	if timeIndex == -1 {
+		return nil, errors.New("error finding time index in result")
+	}
+
+	points := make([]Point, 0, len(row.Values))
+	for _, v := range row.Values {
+		t, ok := v[timeIndex].(time.Time)
+		if !ok {
+			return nil, errors.New("invalid time in result")
+		}
+
+		fields := make(Fields)
+		for i, c := range row.Columns {
+			if i != timeIndex && i < len(v) && v[i] != nil {
+				fields[c] = v[i]
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		points = append(points, NewPoint(name, Tags(row.Tags), fields, t))
+	}
+	return points, nil
+}
+
 // expandSources expands regex sources and removes duplicates.
 // NOTE: sources must be normalized (db and rp set) before calling this function.
 func (q *QueryExecutor) expandSources(sources influxql.Sources) (influxql.Sources, error) {
@@ -384,6 +1299,105 @@ func (q *QueryExecutor) executeDropDatabaseStatement(stmt *influxql.DropDatabase
 	return q.MetaStatementExecutor.ExecuteStatement(stmt)
 }
 
+// executeShowRetentionPoliciesStatement lists the retention policies of a
+// database with the number of their shard groups and the disk size of their
+// shards on this node.
+func (q *QueryExecutor) executeShowRetentionPoliciesStatement(stmt *influxql.ShowRetentionPoliciesStatement) *influxql.Result {
+	res := q.MetaStatementExecutor.ExecuteStatement(stmt)
+	if res.Err != nil || len(res.Series) == 0 {
+		return res
+	}
+
+	di, err := q.MetaStore.Database(stmt.Database)
+	if err != nil {
+		return &influxql.Result{Err: err}
+	} else if di == nil {
+		return res
+	}
+
+	row := res.Series[0]
+	row.Columns = append(row.Columns, "shardGroupN", "diskBytes")
+	for i, values := range row.Values {
+		var groupN int
+		var size int64
+		if name, ok := values[0].(string); ok {
+			if rpi := di.RetentionPolicy(name); rpi != nil {
+				for _, sgi := range rpi.ShardGroups {
+					if sgi.Deleted() {
+						continue
+					}
+					groupN++
+
+					for _, si := range sgi.Shards {
+						sh := q.Store.Shard(si.ID)
+						if sh == nil {
+							continue
+						}
+						n, err := sh.DiskSize()
+						if err != nil {
+							return &influxql.Result{Err: err}
+						}
+						size += n
+					}
+				}
+			}
+		}
+		row.Values[i] = append(values, groupN, size)
+	}
+	return res
+}
+
+// executeRenameDatabaseStatement renames the database in the metastore and
+// then moves the local shards of the database to the new name.
+func (q *QueryExecutor) executeRenameDatabaseStatement(stmt *influxql.RenameDatabaseStatement) *influxql.Result {
+	if res := q.MetaStatementExecutor.ExecuteStatement(stmt); res.Err != nil {
+		return res
+	}
+
+	if err := q.Store.RenameDatabase(stmt.Name, stmt.NewName); err != nil {
+		return &influxql.Result{Err: err}
+	}
+	return &influxql.Result{}
+}
+
+// rewriteResult returns the result of a statement rewriting series, with the
+// number of series and points rewritten.
+func rewriteResult(stats RewriteStats, err error) *influxql.Result {
+	if err != nil {
+		return &influxql.Result{Err: err}
+	}
+	return &influxql.Result{
+		Series: []*influxql.Row{{
+			Name:    "result",
+			Columns: []string{"series", "points"},
+			Values:  [][]interface{}{{stats.SeriesN, stats.PointN}},
+		}},
+	}
+}
+
+// executeCreateSeriesStatement registers a series in the shard points written
+// to it now go to, creating the shard group if needed.
+func (q *QueryExecutor) executeCreateSeriesStatement(stmt *influxql.CreateSeriesStatement) *influxql.Result {
+	mm := stmt.Measurement
+	sgi, err := q.MetaStore.CreateShardGroupIfNotExists(mm.Database, mm.RetentionPolicy, time.Now().UTC())
+	if err != nil {
+		return &influxql.Result{Err: err}
+	}
+
+	key := string(MakeKey([]byte(mm.Name), stmt.Tags))
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	sh := sgi.ShardFor(h.Sum64())
+	if !sh.OwnedBy(q.MetaStore.NodeID()) {
+		return &influxql.Result{Err: ErrShardNotLocal(sh.ID)}
+	}
+
+	if err := q.Store.CreateSeries(mm.Database, mm.RetentionPolicy, sh.ID, []string{key}); err != nil {
+		return &influxql.Result{Err: err}
+	}
+	return &influxql.Result{}
+}
+
 // executeDropMeasurementStatement removes the measurement and all series data from the local store for the given measurement
 func (q *QueryExecutor) executeDropMeasurementStatement(stmt *influxql.DropMeasurementStatement, database string) *influxql.Result {
 	// Find the database.
@@ -397,6 +1411,10 @@ func (q *QueryExecutor) executeDropMeasurementStatement(stmt *influxql.DropMeasu
 		return &influxql.Result{Err: ErrMeasurementNotFound(stmt.Name)}
 	}
 
+	if stmt.Condition != nil {
+		return q.executeDropMeasurementRange(stmt, database)
+	}
+
 	// first remove from the index
 	db.DropMeasurement(m.Name)
 
@@ -408,6 +1426,39 @@ func (q *QueryExecutor) executeDropMeasurementStatement(stmt *influxql.DropMeasu
 	return &influxql.Result{}
 }
 
+// executeDropMeasurementRange deletes the points of a measurement in the time
+// range of the statement's condition from the shards overlapping it.
+func (q *QueryExecutor) executeDropMeasurementRange(stmt *influxql.DropMeasurementStatement, database string) *influxql.Result {
+	di, err := q.MetaStore.Database(database)
+	if err != nil {
+		return &influxql.Result{Err: err}
+	} else if di == nil {
+		return &influxql.Result{Err: ErrDatabaseNotFound(database)}
+	}
+
+	cond := influxql.Reduce(stmt.Condition, &influxql.NowValuer{Now: time.Now().UTC()})
+	tmin, tmax := influxql.TimeRange(cond)
+	if tmin.IsZero() && tmax.IsZero() {
+		return &influxql.Result{Err: fmt.Errorf("invalid time range: %s", stmt.Condition)}
+	}
+
+	min, max := int64(math.MinInt64), int64(math.MaxInt64)
+	if !tmin.IsZero() {
+		min = tmin.UnixNano()
+	}
+	if tmax.IsZero() {
+		tmax = time.Unix(0, math.MaxInt64).UTC()
+	} else {
+		max = tmax.UnixNano()
+	}
+
+	var shardIDs []uint64
+	for _, sh := range shardInfosByTimeRange(di, tmin, tmax) {
+		shardIDs = append(shardIDs, sh.ID)
+	}
+	return rewriteResult(q.Store.DeleteMeasurementRange(database, stmt.Name, shardIDs, min, max))
+}
+
 // executeDropSeriesStatement removes all series from the local store that match the drop query
 func (q *QueryExecutor) executeDropSeriesStatement(stmt *influxql.DropSeriesStatement, database string) *influxql.Result {
 	// Find the database.
@@ -429,7 +1480,7 @@ func (q *QueryExecutor) executeDropSeriesStatement(stmt *influxql.DropSeriesStat
 
 	var seriesKeys []string
 	for _, m := range measurements {
-		var ids SeriesIDs
+		var ids *roaring.Bitmap
 		if stmt.Condition != nil {
 			// Get series IDs that match the WHERE clause.
 			ids, _, err = m.walkWhereForSeriesIds(stmt.Condition)
@@ -441,7 +1492,7 @@ func (q *QueryExecutor) executeDropSeriesStatement(stmt *influxql.DropSeriesStat
 			ids = m.seriesIDs
 		}
 
-		for _, id := range ids {
+		for _, id := range ids.Slice() {
 			seriesKeys = append(seriesKeys, m.seriesByID[id].Key)
 		}
 	}
@@ -457,6 +1508,10 @@ func (q *QueryExecutor) executeDropSeriesStatement(stmt *influxql.DropSeriesStat
 }
 
 func (q *QueryExecutor) executeShowSeriesStatement(stmt *influxql.ShowSeriesStatement, database string) *influxql.Result {
+	if stmt.ExactCounts {
+		return q.executeShowSeriesExactCountsStatement(stmt, database)
+	}
+
 	// Find the database.
 	db := q.Store.DatabaseIndex(database)
 	if db == nil {
@@ -482,7 +1537,7 @@ func (q *QueryExecutor) executeShowSeriesStatement(stmt *influxql.ShowSeriesStat
 
 	// Loop through measurements to build result. One result row / measurement.
 	for _, m := range measurements {
-		var ids SeriesIDs
+		var ids *roaring.Bitmap
 
 		if stmt.Condition != nil {
 			// Get series IDs that match the WHERE clause.
@@ -492,7 +1547,7 @@ func (q *QueryExecutor) executeShowSeriesStatement(stmt *influxql.ShowSeriesStat
 			}
 
 			// If no series matched, then go to the next measurement.
-			if len(ids) == 0 {
+			if ids.Count() == 0 {
 				continue
 			}
 
@@ -509,7 +1564,7 @@ func (q *QueryExecutor) executeShowSeriesStatement(stmt *influxql.ShowSeriesStat
 		}
 
 		// Loop through series IDs getting matching tag sets.
-		for _, id := range ids {
+		for _, id := range ids.Slice() {
 			if s, ok := m.seriesByID[id]; ok {
 				values := make([]interface{}, 0, len(r.Columns))
 
@@ -538,6 +1593,108 @@ func (q *QueryExecutor) executeShowSeriesStatement(stmt *influxql.ShowSeriesStat
 	return result
 }
 
+// executeShowSeriesExactCountsStatement returns the number of points stored
+// for each series within the statement's time range. Series are ordered by
+// their count, largest first, so runaway writers are listed at the top.
+func (q *QueryExecutor) executeShowSeriesExactCountsStatement(stmt *influxql.ShowSeriesStatement, database string) *influxql.Result {
+	// Find the database.
+	db := q.Store.DatabaseIndex(database)
+	if db == nil {
+		return &influxql.Result{}
+	}
+
+	// Expand regex expressions in the FROM clause.
+	sources, err := q.expandSources(stmt.Sources)
+	if err != nil {
+		return &influxql.Result{Err: err}
+	}
+
+	// Get the list of measurements we're interested in.
+	measurements, err := measurementsFromSourcesOrDB(db, sources...)
+	if err != nil {
+		return &influxql.Result{Err: err}
+	}
+
+	// Count from the start of the epoch when there is no lower bound.
+	cond := influxql.Reduce(stmt.Condition, &influxql.NowValuer{Now: time.Now().UTC()})
+	tmin, tmax := influxql.TimeRange(cond)
+	min, max := int64(0), int64(math.MaxInt64)
+	if !tmin.IsZero() && tmin.UnixNano() > 0 {
+		min = tmin.UnixNano()
+	}
+	if !tmax.IsZero() {
+		max = tmax.UnixNano()
+	}
+
+	result := &influxql.Result{
+		Series: make(influxql.Rows, 0, len(measurements)),
+	}
+
+	for _, m := range measurements {
+		ids := m.seriesIDs
+		if cond != nil {
+			if ids, _, err = m.walkWhereForSeriesIds(cond); err != nil {
+				return &influxql.Result{Err: err}
+			}
+		}
+
+		keys := make([]string, 0, ids.Count())
+		for _, id := range ids.Slice() {
+			if s, ok := m.seriesByID[id]; ok {
+				keys = append(keys, s.Key)
+			}
+		}
+		if len(keys) == 0 {
+			continue
+		}
+
+		counts, err := q.Store.PointCounts(database, keys, min, max)
+		if err != nil {
+			return &influxql.Result{Err: err}
+		}
+
+		// Only list series with points in the time range.
+		keys = keys[:0]
+		for k := range counts {
+			keys = append(keys, k)
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		sort.Sort(seriesCounts{keys: keys, counts: counts})
+
+		r := &influxql.Row{
+			Name:    m.Name,
+			Columns: []string{"_key", "count"},
+		}
+		for _, k := range keys {
+			r.Values = append(r.Values, []interface{}{k, counts[k]})
+		}
+		result.Series = append(result.Series, r)
+	}
+
+	if stmt.Limit > 0 || stmt.Offset > 0 {
+		result.Series = q.filterShowSeriesResult(stmt.Limit, stmt.Offset, result.Series)
+	}
+
+	return result
+}
+
+// seriesCounts sorts series keys by their point count, largest first.
+type seriesCounts struct {
+	keys   []string
+	counts map[string]int64
+}
+
+func (a seriesCounts) Len() int      { return len(a.keys) }
+func (a seriesCounts) Swap(i, j int) { a.keys[i], a.keys[j] = a.keys[j], a.keys[i] }
+func (a seriesCounts) Less(i, j int) bool {
+	if ci, cj := a.counts[a.keys[i]], a.counts[a.keys[j]]; ci != cj {
+		return ci > cj
+	}
+	return a.keys[i] < a.keys[j]
+}
+
 // filterShowSeriesResult will limit the number of series returned based on the limit and the offset.
 // Unlike limit and offset on SELECT statements, the limit and offset don't apply to the number of Rows, but
 // to the number of total Values returned, since each Value represents a unique series.
@@ -568,7 +1725,7 @@ func (q *QueryExecutor) filterShowSeriesResult(limit, offset int, rows influxql.
 }
 
 // PlanShowMeasurements creates an execution plan for the given SelectStatement and returns an Executor.
-func (q *QueryExecutor) PlanShowMeasurements(stmt *influxql.ShowMeasurementsStatement, database string, chunkSize int) (Executor, error) {
+func (q *QueryExecutor) PlanShowMeasurements(stmt *influxql.ShowMeasurementsStatement, database string, chunkSize int, opt *ReadOptions) (Executor, error) {
 	// Get the database info.
 	di, err := q.MetaStore.Database(database)
 	if err != nil {
@@ -577,13 +1734,26 @@ func (q *QueryExecutor) PlanShowMeasurements(stmt *influxql.ShowMeasurementsStat
 		return nil, ErrDatabaseNotFound(database)
 	}
 
-	// Get info for all shards in the database.
-	shards := di.ShardInfos()
+	// Replace instances of "now()" with the current time so every shard is
+	// mapped with the same time range.
+	stmt.Condition = influxql.Reduce(stmt.Condition, &influxql.NowValuer{Now: time.Now().UTC()})
+
+	// Get info for the shards in the database, limited to the shards
+	// overlapping the time range if one was specified.
+	var shards []meta.ShardInfo
+	if tmin, tmax := influxql.TimeRange(stmt.Condition); tmin.IsZero() && tmax.IsZero() {
+		shards = di.ShardInfos()
+	} else {
+		if tmax.IsZero() {
+			tmax = time.Unix(0, math.MaxInt64).UTC()
+		}
+		shards = shardInfosByTimeRange(di, tmin, tmax)
+	}
 
 	// Build the Mappers, one per shard.
 	mappers := []Mapper{}
 	for _, sh := range shards {
-		m, err := q.ShardMapper.CreateMapper(sh, stmt, chunkSize)
+		m, err := q.ShardMapper.CreateMapper(sh, stmt, chunkSize, opt)
 		if err != nil {
 			return nil, err
 		}
@@ -598,9 +1768,26 @@ func (q *QueryExecutor) PlanShowMeasurements(stmt *influxql.ShowMeasurementsStat
 	return executor, nil
 }
 
-func (q *QueryExecutor) executeShowMeasurementsStatement(statementID int, stmt *influxql.ShowMeasurementsStatement, database string, results chan *influxql.Result, chunkSize int) error {
+// shardInfosByTimeRange returns the shards of a database from the shard
+// groups overlapping the time range.
+func shardInfosByTimeRange(di *meta.DatabaseInfo, tmin, tmax time.Time) []meta.ShardInfo {
+	var a []meta.ShardInfo
+	for _, rpi := range di.RetentionPolicies {
+		for i := range rpi.ShardGroups {
+			sgi := &rpi.ShardGroups[i]
+			if sgi.Deleted() || !sgi.Overlaps(tmin, tmax) {
+				continue
+			}
+			a = append(a, sgi.Shards...)
+		}
+	}
+	return a
+}
+
+func (q *QueryExecutor) executeShowMeasurementsStatement(statementID int, stmt *influxql.ShowMeasurementsStatement, database string, results chan *influxql.Result, chunkSize int, readPref ReadPreference) error {
 	// Plan statement execution.
-	e, err := q.PlanShowMeasurements(stmt, database, chunkSize)
+	opt := &ReadOptions{Preference: readPref}
+	e, err := q.PlanShowMeasurements(stmt, database, chunkSize, opt)
 	if err != nil {
 		return err
 	}
@@ -615,11 +1802,11 @@ func (q *QueryExecutor) executeShowMeasurementsStatement(statementID int, stmt *
 			return row.Err
 		}
 		resultSent = true
-		results <- &influxql.Result{StatementID: statementID, Series: []*influxql.Row{row}}
+		results <- &influxql.Result{StatementID: statementID, Series: []*influxql.Row{row}, Staleness: opt.Staleness}
 	}
 
 	if !resultSent {
-		results <- &influxql.Result{StatementID: statementID, Series: make([]*influxql.Row, 0)}
+		results <- &influxql.Result{StatementID: statementID, Series: make([]*influxql.Row, 0), Staleness: opt.Staleness}
 	}
 
 	return nil
@@ -704,7 +1891,7 @@ func (q *QueryExecutor) executeShowTagValuesStatement(stmt *influxql.ShowTagValu
 
 	tagValues := make(map[string]stringSet)
 	for _, m := range measurements {
-		var ids SeriesIDs
+		var ids *roaring.Bitmap
 
 		if stmt.Condition != nil {
 			// Get series IDs that match the WHERE clause.
@@ -714,7 +1901,7 @@ func (q *QueryExecutor) executeShowTagValuesStatement(stmt *influxql.ShowTagValu
 			}
 
 			// If no series matched, then go to the next measurement.
-			if len(ids) == 0 {
+			if ids.Count() == 0 {
 				continue
 			}
 
@@ -781,6 +1968,29 @@ func (q *QueryExecutor) executeShowFieldKeysStatement(stmt *influxql.ShowFieldKe
 
 	// Loop through measurements, adding a result row for each.
 	for _, m := range measurements {
+		// The types and last written times of the fields are read from the
+		// local shards.
+		if stmt.Exact {
+			fields, err := q.Store.MeasurementFieldInfo(database, m.Name)
+			if err != nil {
+				return &influxql.Result{Err: err}
+			}
+
+			r := &influxql.Row{
+				Name:    m.Name,
+				Columns: []string{"fieldKey", "fieldType", "lastWritten"},
+			}
+			for _, f := range fields {
+				var lastWritten interface{}
+				if !f.LastWritten.IsZero() {
+					lastWritten = f.LastWritten
+				}
+				r.Values = append(r.Values, []interface{}{f.Name, f.Type.String(), lastWritten})
+			}
+			result.Series = append(result.Series, r)
+			continue
+		}
+
 		// Create a new row.
 		r := &influxql.Row{
 			Name:    m.Name,
@@ -921,8 +2131,6 @@ type ErrAuthorize struct {
 	message  string
 }
 
-const authErrLogFmt string = "unauthorized request | user: %q | query: %q | database %q\n"
-
 // newAuthorizationError returns a new instance of AuthorizationError.
 func NewErrAuthorize(qe *QueryExecutor, q *influxql.Query, u, db, m string) *ErrAuthorize {
 	return &ErrAuthorize{q: qe, query: q, user: u, database: db, message: m}
@@ -930,7 +2138,7 @@ func NewErrAuthorize(qe *QueryExecutor, q *influxql.Query, u, db, m string) *Err
 
 // Error returns the text of the error.
 func (e ErrAuthorize) Error() string {
-	e.q.Logger.Printf(authErrLogFmt, e.user, e.query.String(), e.database)
+	e.q.Logger.Warn("unauthorized request", "user", e.user, "query", e.query.String(), "database", e.database)
 	if e.user == "" {
 		return fmt.Sprint(e.message)
 	}
@@ -944,8 +2152,55 @@ var (
 	// ErrNotExecuted is returned when a statement is not executed in a query.
 	// This can occur when a previous statement in the same query has errored.
 	ErrNotExecuted = errors.New("not executed")
+
+	// ErrSelectIntoUnsupported is returned when executing a SELECT INTO
+	// statement without a writer for its results.
+	ErrSelectIntoUnsupported = errors.New("SELECT INTO is not supported")
+
+	// ErrContinuousQueriesDisabled is returned when requesting continuous query
+	// status while the continuous query service is disabled.
+	ErrContinuousQueriesDisabled = errors.New("continuous query service is disabled")
+
+	// ErrMaxQueriesExceeded is returned when a user runs more queries at once
+	// than their limit allows.
+	ErrMaxQueriesExceeded = errors.New("max concurrent queries exceeded for user")
+
+	// ErrMaxRangeExceeded is returned when a SELECT covers more time than the
+	// user's limit allows. An unbounded lower time is over any limit.
+	ErrMaxRangeExceeded = errors.New("max time range exceeded for user")
+
+	// ErrMaxPointsExceeded is returned when a SELECT reads more points than
+	// the user's limit allows.
+	ErrMaxPointsExceeded = errors.New("max points scanned exceeded for user")
+
+	// ErrMaxSelectPointsExceeded is returned when a SELECT returns more
+	// points than the max-select-point limit allows.
+	ErrMaxSelectPointsExceeded = errors.New("max-select-point limit exceeded")
+
+	// ErrMaxSelectSeriesExceeded is returned when a SELECT returns more
+	// series than the max-select-series limit allows.
+	ErrMaxSelectSeriesExceeded = errors.New("max-select-series limit exceeded")
+
+	// ErrDatabaseMaxQueriesExceeded is returned when more statements run
+	// against a database at once than its limit allows.
+	ErrDatabaseMaxQueriesExceeded = errors.New("max concurrent queries exceeded for database")
+
+	// ErrDatabaseMaxMemoryExceeded is returned when the SELECT statements
+	// running against a database read more points than its memory limit allows.
+	ErrDatabaseMaxMemoryExceeded = errors.New("max query memory exceeded for database")
 )
 
 func ErrDatabaseNotFound(name string) error { return fmt.Errorf("database not found: %s", name) }
 
 func ErrMeasurementNotFound(name string) error { return fmt.Errorf("measurement not found: %s", name) }
+
+func ErrShardNotLocal(id uint64) error { return fmt.Errorf("shard %d is not on this node", id) }
+
+func ErrIntervalBelowResolution(interval time.Duration, rpi *meta.RetentionPolicyInfo) error {
+	return fmt.Errorf("GROUP BY interval %s is finer than the %s resolution of retention policy %s",
+		influxql.FormatDuration(interval), influxql.FormatDuration(rpi.Resolution), rpi.Name)
+}
+
+func ErrMeasurementExists(name string) error {
+	return fmt.Errorf("measurement already exists: %s", name)
+}
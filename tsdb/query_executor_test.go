@@ -2,9 +2,12 @@ package tsdb_test
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -168,6 +171,160 @@ func TestDropSeriesStatement(t *testing.T) {
 	}
 }
 
+func TestCreateSeriesStatement(t *testing.T) {
+	store, executor := testStoreAndExecutor("")
+	defer os.RemoveAll(store.Path())
+
+	got := executeAndGetJSON("CREATE SERIES foo.bar.cpu WITH TAGS host = 'serverA', region = 'uswest'", executor)
+	exepected := `[{}]`
+	if exepected != got {
+		t.Fatalf("exp: %s\ngot: %s", exepected, got)
+	}
+
+	got = executeAndGetJSON("SHOW SERIES", executor)
+	exepected = `[{"series":[{"name":"cpu","columns":["_key","host","region"],"values":[["cpu,host=serverA,region=uswest","serverA","uswest"]]}]}]`
+	if exepected != got {
+		t.Fatalf("exp: %s\ngot: %s", exepected, got)
+	}
+
+	got = executeAndGetJSON("SHOW MEASUREMENTS", executor)
+	exepected = `[{"series":[{"name":"measurements","columns":["name"],"values":[["cpu"]]}]}]`
+	if exepected != got {
+		t.Fatalf("exp: %s\ngot: %s", exepected, got)
+	}
+
+	store.Close()
+	conf := store.EngineOptions.Config
+	store = tsdb.NewStore(store.Path())
+	store.EngineOptions.Config = conf
+	store.Open()
+	executor.Store = store
+	executor.ShardMapper = &testShardMapper{store: store}
+
+	got = executeAndGetJSON("SHOW SERIES", executor)
+	exepected = `[{"series":[{"name":"cpu","columns":["_key","host","region"],"values":[["cpu,host=serverA,region=uswest","serverA","uswest"]]}]}]`
+	if exepected != got {
+		t.Fatalf("exp: %s\ngot: %s", exepected, got)
+	}
+
+	pt := tsdb.NewPoint(
+		"cpu",
+		map[string]string{"host": "serverA", "region": "uswest"},
+		map[string]interface{}{"value": 1.0},
+		time.Unix(1, 2),
+	)
+	if err := store.WriteToShard(shardID, []tsdb.Point{pt}); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	got = executeAndGetJSON("SELECT * FROM cpu GROUP BY *", executor)
+	exepected = `[{"series":[{"name":"cpu","tags":{"host":"serverA","region":"uswest"},"columns":["time","value"],"values":[["1970-01-01T00:00:01.000000002Z",1]]}]}]`
+	if exepected != got {
+		t.Fatalf("exp: %s\ngot: %s", exepected, got)
+	}
+}
+
+func TestShowSeriesExactCountsStatement(t *testing.T) {
+	store, executor := testStoreAndExecutor("")
+	defer os.RemoveAll(store.Path())
+
+	var points []tsdb.Point
+	for i := 1; i <= 3; i++ {
+		points = append(points, tsdb.NewPoint(
+			"cpu",
+			map[string]string{"host": "serverA"},
+			map[string]interface{}{"value": float64(i)},
+			time.Unix(int64(i), 0),
+		))
+	}
+	points = append(points, tsdb.NewPoint(
+		"cpu",
+		map[string]string{"host": "serverB"},
+		map[string]interface{}{"value": 1.0},
+		time.Unix(1, 0),
+	))
+
+	if err := store.WriteToShard(shardID, points); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	got := executeAndGetJSON("SHOW SERIES EXACT COUNTS", executor)
+	exepected := `[{"series":[{"name":"cpu","columns":["_key","count"],"values":[["cpu,host=serverA",3],["cpu,host=serverB",1]]}]}]`
+	if exepected != got {
+		t.Fatalf("exp: %s\ngot: %s", exepected, got)
+	}
+
+	got = executeAndGetJSON("SHOW SERIES EXACT COUNTS FROM cpu WHERE time >= '1970-01-01T00:00:02Z'", executor)
+	exepected = `[{"series":[{"name":"cpu","columns":["_key","count"],"values":[["cpu,host=serverA",2]]}]}]`
+	if exepected != got {
+		t.Fatalf("exp: %s\ngot: %s", exepected, got)
+	}
+
+	got = executeAndGetJSON("SHOW SERIES EXACT COUNTS WHERE host = 'serverB'", executor)
+	exepected = `[{"series":[{"name":"cpu","columns":["_key","count"],"values":[["cpu,host=serverB",1]]}]}]`
+	if exepected != got {
+		t.Fatalf("exp: %s\ngot: %s", exepected, got)
+	}
+
+	// Bounds relative to now() are evaluated.
+	if err := store.WriteToShard(shardID, []tsdb.Point{tsdb.NewPoint(
+		"cpu",
+		map[string]string{"host": "serverB"},
+		map[string]interface{}{"value": 2.0},
+		time.Now().Add(-30*time.Minute),
+	)}); err != nil {
+		t.Fatalf(err.Error())
+	}
+	got = executeAndGetJSON("SHOW SERIES EXACT COUNTS WHERE time > now() - 1h", executor)
+	exepected = `[{"series":[{"name":"cpu","columns":["_key","count"],"values":[["cpu,host=serverB",1]]}]}]`
+	if exepected != got {
+		t.Fatalf("exp: %s\ngot: %s", exepected, got)
+	}
+}
+
+func TestShowFieldKeysExactStatement(t *testing.T) {
+	store, executor := testStoreAndExecutor("")
+	defer os.RemoveAll(store.Path())
+
+	if err := store.WriteToShard(shardID, []tsdb.Point{tsdb.NewPoint(
+		"cpu",
+		map[string]string{"host": "serverA"},
+		map[string]interface{}{"value": 1.0, "status": "ok"},
+		time.Unix(1, 0),
+	)}); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	got := executeAndGetJSON("SHOW FIELD KEYS FROM cpu", executor)
+	exepected := `[{"series":[{"name":"cpu","columns":["fieldKey"],"values":[["status"],["value"]]}]}]`
+	if exepected != got {
+		t.Fatalf("exp: %s\ngot: %s", exepected, got)
+	}
+
+	// Last written times vary, so the rows are checked directly.
+	ch, err := executor.ExecuteQuery(mustParseQuery("SHOW FIELD KEYS FROM cpu EXACT"), "foo", 20, tsdb.ReadPreferenceNearest, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := <-ch
+	if r.Err != nil || len(r.Series) != 1 {
+		t.Fatalf("unexpected result: %v", r)
+	}
+	row := r.Series[0]
+	if !reflect.DeepEqual(row.Columns, []string{"fieldKey", "fieldType", "lastWritten"}) {
+		t.Fatalf("unexpected columns: %v", row.Columns)
+	} else if len(row.Values) != 2 {
+		t.Fatalf("unexpected values: %v", row.Values)
+	}
+	for i, exp := range [][]interface{}{{"status", "string"}, {"value", "float"}} {
+		if !reflect.DeepEqual(row.Values[i][:2], exp) {
+			t.Fatalf("%d. unexpected field: %v", i, row.Values[i])
+		} else if tm, ok := row.Values[i][2].(time.Time); !ok || tm.IsZero() {
+			t.Fatalf("%d. unexpected last written time: %v", i, row.Values[i][2])
+		}
+	}
+}
+
 func TestDropMeasurementStatement(t *testing.T) {
 	store, executor := testStoreAndExecutor("")
 	defer os.RemoveAll(store.Path())
@@ -225,6 +382,127 @@ func TestDropMeasurementStatement(t *testing.T) {
 	validateDrop()
 }
 
+func TestDropMeasurementStatement_TimeRange(t *testing.T) {
+	store, executor := testStoreAndExecutor("")
+	defer os.RemoveAll(store.Path())
+
+	now := time.Now()
+	var points []tsdb.Point
+	for _, p := range []struct {
+		host string
+		age  time.Duration
+	}{
+		{"a", 30 * time.Minute},
+		{"a", 20 * time.Minute},
+		{"b", 30 * time.Minute},
+		{"b", 10 * time.Minute},
+	} {
+		points = append(points, tsdb.NewPoint(
+			"cpu",
+			map[string]string{"host": p.host},
+			map[string]interface{}{"value": 1.0},
+			now.Add(-p.age),
+		))
+	}
+	if err := store.WriteToShard(shardID, points); err != nil {
+		t.Fatal(err)
+	}
+
+	got := executeAndGetJSON("DROP MEASUREMENT cpu WHERE time < now() - 15m", executor)
+	exepected := `[{"series":[{"name":"result","columns":["series","points"],"values":[[1,3]]}]}]`
+	if exepected != got {
+		t.Fatalf("exp: %s\ngot: %s", exepected, got)
+	}
+
+	validateDrop := func() {
+		got = executeAndGetJSON("show series", executor)
+		exepected = `[{"series":[{"name":"cpu","columns":["_key","host"],"values":[["cpu,host=b","b"]]}]}]`
+		if exepected != got {
+			t.Fatalf("exp: %s\ngot: %s", exepected, got)
+		}
+		got = executeAndGetJSON("select count(value) from cpu", executor)
+		exepected = `[{"series":[{"name":"cpu","columns":["time","count"],"values":[["1970-01-01T00:00:00Z",1]]}]}]`
+		if exepected != got {
+			t.Fatalf("exp: %s\ngot: %s", exepected, got)
+		}
+	}
+
+	validateDrop()
+	store.Close()
+	store, executor = testStoreAndExecutor(store.Path())
+	validateDrop()
+
+	// Dropping the remaining points drops the measurement.
+	got = executeAndGetJSON("DROP MEASUREMENT cpu WHERE time > now() - 1h", executor)
+	exepected = `[{"series":[{"name":"result","columns":["series","points"],"values":[[1,1]]}]}]`
+	if exepected != got {
+		t.Fatalf("exp: %s\ngot: %s", exepected, got)
+	}
+	got = executeAndGetJSON("show measurements", executor)
+	exepected = `[{}]`
+	if exepected != got {
+		t.Fatalf("exp: %s\ngot: %s", exepected, got)
+	}
+}
+
+func TestShowMeasurementsStatement_TimeRange(t *testing.T) {
+	store, executor := testStoreAndExecutor("")
+	defer os.RemoveAll(store.Path())
+
+	pt := tsdb.NewPoint(
+		"cpu",
+		map[string]string{"host": "server"},
+		map[string]interface{}{"value": 1.0},
+		time.Now(),
+	)
+
+	if err := store.WriteToShard(shardID, []tsdb.Point{pt}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := executeAndGetJSON("SHOW MEASUREMENTS WHERE time > now() - 10m AND host = 'server'", executor)
+	exepected := `[{"series":[{"name":"measurements","columns":["name"],"values":[["cpu"]]}]}]`
+	if exepected != got {
+		t.Fatalf("exp: %s\ngot: %s", exepected, got)
+	}
+
+	// No shard group overlaps the time range.
+	got = executeAndGetJSON("SHOW MEASUREMENTS WHERE time < now() - 2h", executor)
+	exepected = `[{}]`
+	if exepected != got {
+		t.Fatalf("exp: %s\ngot: %s", exepected, got)
+	}
+}
+
+// Ensure GROUP BY intervals finer than the resolution of the retention policy
+// are rejected.
+func TestQueryExecutor_PlanSelect_Resolution(t *testing.T) {
+	store, executor := testStoreAndExecutor("")
+	defer os.RemoveAll(store.Path())
+	executor.MetaStore = &testMetastore{resolution: 5 * time.Minute}
+
+	pt := tsdb.NewPoint(
+		"cpu",
+		map[string]string{"host": "server"},
+		map[string]interface{}{"value": 1.0},
+		time.Now(),
+	)
+	if err := store.WriteToShard(shardID, []tsdb.Point{pt}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := executeAndGetJSON("SELECT count(value) FROM cpu WHERE time > now() - 10m GROUP BY time(10s)", executor)
+	exp := `[{"error":"GROUP BY interval 10s is finer than the 5m resolution of retention policy bar"}]`
+	if exp != got {
+		t.Fatalf("exp: %s\ngot: %s", exp, got)
+	}
+
+	got = executeAndGetJSON("SELECT count(value) FROM cpu WHERE time > now() - 10m GROUP BY time(1h)", executor)
+	if !strings.Contains(got, `"count"`) || strings.Contains(got, "error") {
+		t.Fatalf("unexpected result: %s", got)
+	}
+}
+
 // mock for the metaExecutor
 type metaExec struct {
 	fn func(stmt influxql.Statement) *influxql.Result
@@ -315,6 +593,521 @@ func TestQueryNoData(t *testing.T) {
 	store.Close()
 }
 
+// Ensure registered rewriters are applied to statements before they are executed.
+func TestQueryExecutor_AddStatementRewriter(t *testing.T) {
+	store, executor := testStoreAndExecutor("")
+	defer os.RemoveAll(store.Path())
+
+	if err := store.WriteToShard(shardID, []tsdb.Point{
+		tsdb.NewPoint("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.0}, time.Unix(1, 0)),
+		tsdb.NewPoint("cpu", map[string]string{"host": "b"}, map[string]interface{}{"value": 2.0}, time.Unix(2, 0)),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Restrict every SELECT to the points of host a.
+	executor.AddStatementRewriter(tsdb.StatementRewriterFunc(func(stmt influxql.Statement, database string) (influxql.Statement, error) {
+		if database != "foo" {
+			t.Fatalf("unexpected database: %s", database)
+		}
+		s, ok := stmt.(*influxql.SelectStatement)
+		if !ok {
+			return stmt, nil
+		}
+		s = s.Clone()
+		cond, err := influxql.ParseExpr(`host = 'a'`)
+		if err != nil {
+			return nil, err
+		}
+		if s.Condition != nil {
+			cond = &influxql.BinaryExpr{Op: influxql.AND, LHS: &influxql.ParenExpr{Expr: s.Condition}, RHS: cond}
+		}
+		s.Condition = cond
+		return s, nil
+	}))
+
+	got := executeAndGetJSON("SELECT value FROM cpu", executor)
+	exp := `[{"series":[{"name":"cpu","columns":["time","value"],"values":[["1970-01-01T00:00:01Z",1]]}]}]`
+	if exp != got {
+		t.Fatalf("\nexp: %s\ngot: %s", exp, got)
+	}
+
+	// Rejecting a statement fails it.
+	executor.AddStatementRewriter(tsdb.StatementRewriterFunc(func(stmt influxql.Statement, database string) (influxql.Statement, error) {
+		return nil, errors.New("rejected")
+	}))
+	got = executeAndGetJSON("SELECT value FROM cpu", executor)
+	exp = `[{"error":"rejected"}]`
+	if exp != got {
+		t.Fatalf("\nexp: %s\ngot: %s", exp, got)
+	}
+}
+
+// Ensure altering the replication of a policy has the copier replicate its existing shards.
+func TestQueryExecutor_ExecuteQuery_AlterRetentionPolicyReplication(t *testing.T) {
+	store, executor := testStoreAndExecutor("")
+	defer os.RemoveAll(store.Path())
+
+	var altered int
+	executor.MetaStatementExecutor = &metaExec{fn: func(stmt influxql.Statement) *influxql.Result {
+		altered++
+		return &influxql.Result{}
+	}}
+	var replicated []string
+	executor.CopierStatementExecutor = &metaExec{fn: func(stmt influxql.Statement) *influxql.Result {
+		replicated = append(replicated, stmt.String())
+		return &influxql.Result{}
+	}}
+
+	executeAndGetJSON("ALTER RETENTION POLICY bar ON foo DURATION 1d", executor)
+	executeAndGetJSON("ALTER RETENTION POLICY bar ON foo REPLICATION 2", executor)
+	if altered != 2 {
+		t.Fatalf("unexpected altered policies: %d", altered)
+	} else if len(replicated) != 1 || replicated[0] != "ALTER RETENTION POLICY bar ON foo REPLICATION 2" {
+		t.Fatalf("unexpected replicated policies: %v", replicated)
+	}
+}
+
+// Ensure the retention policies are listed with their shard groups and disk size.
+func TestQueryExecutor_ExecuteQuery_ShowRetentionPolicies(t *testing.T) {
+	store, executor := testStoreAndExecutor("")
+	defer os.RemoveAll(store.Path())
+	executor.MetaStatementExecutor = &metaExec{fn: func(stmt influxql.Statement) *influxql.Result {
+		return &influxql.Result{Series: []*influxql.Row{{
+			Columns: []string{"name", "duration", "replicaN", "default", "resolution"},
+			Values:  [][]interface{}{{"bar", "0", 1, false, "0"}, {"baz", "0", 1, false, "0"}},
+		}}}
+	}}
+
+	size, err := store.Shard(1).DiskSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := executeAndGetJSON("SHOW RETENTION POLICIES ON foo", executor)
+	exp := fmt.Sprintf(`[{"series":[{"columns":["name","duration","replicaN","default","resolution","shardGroupN","diskBytes"],"values":[["bar","0",1,false,"0",1,%d],["baz","0",1,false,"0",0,0]]}]}]`, size)
+	if exp != got {
+		t.Fatalf("\nexp: %s\ngot: %s", exp, got)
+	}
+}
+
+// Ensure the statements after a failed one still run unless the query fails fast.
+func TestQueryExecutor_ExecuteQuery_FailFast(t *testing.T) {
+	store, executor := testStoreAndExecutor("")
+	defer os.RemoveAll(store.Path())
+	executor.MetaStatementExecutor = &metaExec{fn: func(stmt influxql.Statement) *influxql.Result {
+		if _, ok := stmt.(*influxql.ShowUsersStatement); ok {
+			return &influxql.Result{Err: errors.New("marker")}
+		}
+		return &influxql.Result{}
+	}}
+
+	execute := func(failFast bool) string {
+		q := mustParseQuery("SHOW DATABASES; SHOW USERS; SHOW DATABASES")
+		q.FailFast = failFast
+		ch, err := executor.ExecuteQuery(q, "foo", 20, tsdb.ReadPreferenceNearest, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var results []*influxql.Result
+		for r := range ch {
+			results = append(results, r)
+		}
+		b, _ := json.Marshal(results)
+		return string(b)
+	}
+
+	if got := execute(false); got != `[{},{"error":"marker"},{}]` {
+		t.Fatalf("unexpected results: %s", got)
+	} else if got := execute(true); got != `[{},{"error":"marker"},{"error":"not executed"}]` {
+		t.Fatalf("unexpected results: %s", got)
+	}
+}
+
+// Ensure the time range and points of a SELECT are limited by the user's limits.
+func TestQueryExecutor_ExecuteQuery_UserLimits(t *testing.T) {
+	store, executor := testStoreAndExecutor("")
+	defer os.RemoveAll(store.Path())
+
+	now := time.Now().UTC()
+	if err := store.WriteToShard(shardID, []tsdb.Point{
+		tsdb.NewPoint("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.0}, now.Add(-2*time.Minute)),
+		tsdb.NewPoint("cpu", map[string]string{"host": "b"}, map[string]interface{}{"value": 2.0}, now.Add(-time.Minute)),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	execute := func(query string, limits meta.UserLimits) string {
+		ch, err := executor.ExecuteQuery(mustParseQuery(query), "foo", 20, tsdb.ReadPreferenceNearest, &meta.UserInfo{Name: "susy", Limits: limits})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var results []*influxql.Result
+		for r := range ch {
+			results = append(results, r)
+		}
+		b, _ := json.Marshal(results)
+		return string(b)
+	}
+
+	// Unbounded and too long time ranges are rejected.
+	limits := meta.UserLimits{MaxRange: time.Hour}
+	if got := execute("SELECT value FROM cpu; SELECT value FROM cpu WHERE time > now() - 3h", limits); got != `[{"error":"max time range exceeded for user"},{"error":"max time range exceeded for user"}]` {
+		t.Fatalf("unexpected result: %s", got)
+	} else if got = execute("SELECT value FROM cpu WHERE time > now() - 2h", limits); got != `[{"error":"max time range exceeded for user"}]` {
+		t.Fatalf("unexpected result: %s", got)
+	} else if got = execute("SELECT count(value) FROM cpu WHERE time > now() - 1h", limits); !strings.Contains(got, `"columns":["time","count"]`) || !strings.HasSuffix(got, `,2]]}]}]`) {
+		t.Fatalf("unexpected result: %s", got)
+	}
+
+	// Reading more points than allowed fails the statement.
+	if got := execute("SELECT value FROM cpu", meta.UserLimits{MaxPoints: 1}); got != `[{"error":"max points scanned exceeded for user"}]` {
+		t.Fatalf("unexpected result: %s", got)
+	} else if got = execute("SELECT value FROM cpu", meta.UserLimits{MaxPoints: 2}); !strings.Contains(got, `"values"`) {
+		t.Fatalf("unexpected result: %s", got)
+	}
+
+	// Points scanned by aggregates count against the limit too.
+	if got := execute("SELECT count(value) FROM cpu", meta.UserLimits{MaxPoints: 1}); got != `[{"error":"max points scanned exceeded for user"}]` {
+		t.Fatalf("unexpected result: %s", got)
+	} else if got = execute("SELECT count(value) FROM cpu", meta.UserLimits{MaxPoints: 2}); !strings.Contains(got, `"values"`) {
+		t.Fatalf("unexpected result: %s", got)
+	}
+}
+
+// Ensure a user can't run more queries at once than the user's limit.
+func TestQueryExecutor_ExecuteQuery_UserLimits_MaxQueries(t *testing.T) {
+	store, executor := testStoreAndExecutor("")
+	defer os.RemoveAll(store.Path())
+
+	// Block meta statements until released.
+	release := make(chan struct{})
+	executor.MetaStatementExecutor = &metaExec{fn: func(stmt influxql.Statement) *influxql.Result {
+		<-release
+		return &influxql.Result{}
+	}}
+
+	u := &meta.UserInfo{Name: "susy", Limits: meta.UserLimits{MaxQueries: 1}}
+	ch0, err := executor.ExecuteQuery(mustParseQuery("SHOW DATABASES"), "foo", 20, tsdb.ReadPreferenceNearest, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A second query is rejected while the first runs.
+	ch1, err := executor.ExecuteQuery(mustParseQuery("SHOW DATABASES; SHOW USERS"), "foo", 20, tsdb.ReadPreferenceNearest, u)
+	if err != nil {
+		t.Fatal(err)
+	} else if r := <-ch1; r.Err != tsdb.ErrMaxQueriesExceeded {
+		t.Fatalf("unexpected error: %v", r.Err)
+	} else if r := <-ch1; r.Err != tsdb.ErrNotExecuted {
+		t.Fatalf("unexpected error: %v", r.Err)
+	}
+
+	// Other users aren't affected.
+	ch3, err := executor.ExecuteQuery(mustParseQuery("SHOW DATABASES"), "foo", 20, tsdb.ReadPreferenceNearest, &meta.UserInfo{Name: "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Once the first query finishes another can run.
+	close(release)
+	for range ch0 {
+	}
+	for range ch3 {
+	}
+	ch2, err := executor.ExecuteQuery(mustParseQuery("SHOW DATABASES"), "foo", 20, tsdb.ReadPreferenceNearest, u)
+	if err != nil {
+		t.Fatal(err)
+	} else if r := <-ch2; r.Err != nil {
+		t.Fatalf("unexpected error: %v", r.Err)
+	}
+}
+
+// Ensure a database can't run more statements at once than its limit.
+func TestQueryExecutor_ExecuteQuery_DatabaseLimits_MaxQueries(t *testing.T) {
+	store, executor := testStoreAndExecutor("")
+	defer os.RemoveAll(store.Path())
+	executor.SetDatabaseLimits(map[string]tsdb.DatabaseLimits{"foo": {MaxQueries: 1}})
+
+	// Block meta statements until released.
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	executor.MetaStatementExecutor = &metaExec{fn: func(stmt influxql.Statement) *influxql.Result {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		return &influxql.Result{}
+	}}
+
+	// Statements run asynchronously so wait for the first one to start.
+	ch0, err := executor.ExecuteQuery(mustParseQuery("SHOW DATABASES"), "foo", 20, tsdb.ReadPreferenceNearest, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	// A second statement is rejected while the first runs, whatever the user.
+	ch1, err := executor.ExecuteQuery(mustParseQuery("SHOW DATABASES; SHOW USERS"), "foo", 20, tsdb.ReadPreferenceNearest, &meta.UserInfo{Name: "susy"})
+	if err != nil {
+		t.Fatal(err)
+	} else if r := <-ch1; r.Err != tsdb.ErrDatabaseMaxQueriesExceeded {
+		t.Fatalf("unexpected error: %v", r.Err)
+	} else if r := <-ch1; r.Err != tsdb.ErrDatabaseMaxQueriesExceeded {
+		t.Fatalf("unexpected error: %v", r.Err)
+	}
+
+	// Other databases aren't affected.
+	ch3, err := executor.ExecuteQuery(mustParseQuery("SHOW DATABASES"), "bar", 20, tsdb.ReadPreferenceNearest, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Once the first statement finishes another can run.
+	close(release)
+	for range ch0 {
+	}
+	for range ch3 {
+	}
+	ch2, err := executor.ExecuteQuery(mustParseQuery("SHOW DATABASES"), "foo", 20, tsdb.ReadPreferenceNearest, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if r := <-ch2; r.Err != nil {
+		t.Fatalf("unexpected error: %v", r.Err)
+	}
+}
+
+// Ensure the SELECT statements of a database can't read more than its memory limit.
+func TestQueryExecutor_ExecuteQuery_DatabaseLimits_MaxQueryMemory(t *testing.T) {
+	store, executor := testStoreAndExecutor("")
+	defer os.RemoveAll(store.Path())
+
+	now := time.Now().UTC()
+	if err := store.WriteToShard(shardID, []tsdb.Point{
+		tsdb.NewPoint("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.0}, now.Add(-2*time.Minute)),
+		tsdb.NewPoint("cpu", map[string]string{"host": "b"}, map[string]interface{}{"value": 2.0}, now.Add(-time.Minute)),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	execute := func(query string) string {
+		ch, err := executor.ExecuteQuery(mustParseQuery(query), "foo", 20, tsdb.ReadPreferenceNearest, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var results []*influxql.Result
+		for r := range ch {
+			results = append(results, r)
+		}
+		b, _ := json.Marshal(results)
+		return string(b)
+	}
+
+	executor.SetDatabaseLimits(map[string]tsdb.DatabaseLimits{"foo": {MaxQueryMemory: 100}})
+	if got := execute("SELECT value FROM cpu"); got != `[{"error":"max query memory exceeded for database"}]` {
+		t.Fatalf("unexpected result: %s", got)
+	}
+
+	// Memory is released when a statement finishes.
+	executor.SetDatabaseLimits(map[string]tsdb.DatabaseLimits{"foo": {MaxQueryMemory: 200}})
+	for i := 0; i < 2; i++ {
+		if got := execute("SELECT value FROM cpu"); !strings.Contains(got, `"values"`) {
+			t.Fatalf("%d: unexpected result: %s", i, got)
+		}
+	}
+
+	// Removing the limits removes the memory limit.
+	executor.SetDatabaseLimits(nil)
+	executor.SetDatabaseLimits(map[string]tsdb.DatabaseLimits{"bar": {MaxQueryMemory: 1}})
+	if got := execute("SELECT value FROM cpu"); !strings.Contains(got, `"values"`) {
+		t.Fatalf("unexpected result: %s", got)
+	}
+}
+
+// Ensure SELECT statements fail, or are truncated, at the select limits.
+func TestQueryExecutor_ExecuteQuery_SelectLimits(t *testing.T) {
+	store, executor := testStoreAndExecutor("")
+	defer os.RemoveAll(store.Path())
+
+	if err := store.WriteToShard(shardID, []tsdb.Point{
+		tsdb.NewPoint("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.0}, time.Unix(1, 0)),
+		tsdb.NewPoint("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 2.0}, time.Unix(2, 0)),
+		tsdb.NewPoint("cpu", map[string]string{"host": "b"}, map[string]interface{}{"value": 3.0}, time.Unix(3, 0)),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, tt := range []struct {
+		limits tsdb.SelectLimits
+		exp    string
+	}{
+		{
+			limits: tsdb.SelectLimits{MaxPointN: 3, MaxSeriesN: 2},
+			exp:    `[{"series":[{"name":"cpu","tags":{"host":"a"},"columns":["time","value"],"values":[["1970-01-01T00:00:01Z",1],["1970-01-01T00:00:02Z",2]]}]},{"series":[{"name":"cpu","tags":{"host":"b"},"columns":["time","value"],"values":[["1970-01-01T00:00:03Z",3]]}]}]`,
+		},
+		{
+			limits: tsdb.SelectLimits{MaxPointN: 2},
+			exp:    `[{"series":[{"name":"cpu","tags":{"host":"a"},"columns":["time","value"],"values":[["1970-01-01T00:00:01Z",1],["1970-01-01T00:00:02Z",2]]}]},{"error":"max-select-point limit exceeded"}]`,
+		},
+		{
+			limits: tsdb.SelectLimits{MaxSeriesN: 1},
+			exp:    `[{"series":[{"name":"cpu","tags":{"host":"a"},"columns":["time","value"],"values":[["1970-01-01T00:00:01Z",1],["1970-01-01T00:00:02Z",2]]}]},{"error":"max-select-series limit exceeded"}]`,
+		},
+		{
+			limits: tsdb.SelectLimits{MaxPointN: 1, Truncate: true},
+			exp:    `[{"series":[{"name":"cpu","tags":{"host":"a"},"columns":["time","value"],"values":[["1970-01-01T00:00:01Z",1]]}],"partial":true}]`,
+		},
+		{
+			limits: tsdb.SelectLimits{MaxSeriesN: 1, Truncate: true},
+			exp:    `[{"series":[{"name":"cpu","tags":{"host":"a"},"columns":["time","value"],"values":[["1970-01-01T00:00:01Z",1],["1970-01-01T00:00:02Z",2]]}]},{"partial":true}]`,
+		},
+	} {
+		executor.SetSelectLimits(tt.limits)
+		if got := executeAndGetJSON("SELECT value FROM cpu GROUP BY *", executor); got != tt.exp {
+			t.Fatalf("%d: \nexp: %s\ngot: %s", i, tt.exp, got)
+		}
+	}
+}
+
+// Ensure the last result of each statement has its statistics, if requested.
+func TestQueryExecutor_ExecuteQuery_Stats(t *testing.T) {
+	store, executor := testStoreAndExecutor("")
+	defer os.RemoveAll(store.Path())
+
+	if err := store.WriteToShard(shardID, []tsdb.Point{
+		tsdb.NewPoint("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.0}, time.Unix(1, 0)),
+		tsdb.NewPoint("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 2.0}, time.Unix(2, 0)),
+		tsdb.NewPoint("cpu", map[string]string{"host": "b"}, map[string]interface{}{"value": 3.0}, time.Unix(3, 0)),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	q := mustParseQuery("SELECT value FROM cpu GROUP BY *; SELECT value FROM cpu")
+	q.Stats = true
+	ch, err := executor.ExecuteQuery(q, "foo", 20, tsdb.ReadPreferenceNearest, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var results []*influxql.Result
+	for r := range ch {
+		results = append(results, r)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("unexpected result count: %d", len(results))
+	} else if results[0].Stats != nil {
+		t.Fatalf("unexpected stats of first series: %#v", results[0].Stats)
+	}
+	for _, i := range []int{1, 2} {
+		st := results[i].Stats
+		if st == nil {
+			t.Fatalf("%d: expected stats", i)
+		} else if st.SeriesN != 2 || st.PointN != 3 || st.CacheHitN > st.PointN || st.Duration <= 0 {
+			t.Fatalf("%d: unexpected stats: %#v", i, st)
+		}
+	}
+
+	// Statistics are only added if requested.
+	if got := executeAndGetJSON("SELECT value FROM cpu", executor); strings.Contains(got, "stats") {
+		t.Fatalf("unexpected stats: %s", got)
+	}
+}
+
+// Ensure only queries of time ranges ended in the past are validated by the
+// last modification of their shards.
+func TestQueryExecutor_QueryLastModified(t *testing.T) {
+	store, executor := testStoreAndExecutor("")
+	defer os.RemoveAll(store.Path())
+	defer store.Close()
+
+	if err := store.WriteToShard(shardID, []tsdb.Point{tsdb.NewPoint(
+		"cpu",
+		map[string]string{"host": "server"},
+		map[string]interface{}{"value": 1.0},
+		time.Unix(1, 2),
+	)}); err != nil {
+		t.Fatal(err)
+	}
+	exp := store.Shard(shardID).LastModified()
+
+	for i, tt := range []struct {
+		q  string
+		ok bool
+	}{
+		{q: `SELECT * FROM cpu WHERE time < '2000-01-01T00:00:00Z'`, ok: true},
+		{q: `SELECT * FROM cpu WHERE time > '1970-01-01T00:00:00Z' AND time < '2000-01-01T00:00:00Z'; SELECT count(value) FROM cpu WHERE time < '2000-01-01T00:00:00Z'`, ok: true},
+		{q: `SELECT * FROM cpu`},
+		{q: `SELECT * FROM cpu WHERE time < now() - 1d`},
+		{q: `SELECT * FROM cpu WHERE time < '2100-01-01T00:00:00Z'`},
+		{q: `SELECT * INTO cpu2 FROM cpu WHERE time < '2000-01-01T00:00:00Z'`},
+		{q: `SELECT * FROM cpu WHERE time < '2000-01-01T00:00:00Z'; SHOW MEASUREMENTS`},
+	} {
+		q := mustParseQuery(tt.q)
+		lastModified, shards, ok := executor.QueryLastModified(q, "foo")
+		if ok != tt.ok {
+			t.Errorf("%d. unexpected ok: %v", i, ok)
+		} else if !ok {
+			continue
+		} else if !lastModified.Equal(exp) {
+			t.Errorf("%d. unexpected last modified: %s", i, lastModified)
+		} else if !reflect.DeepEqual(shards, []uint64{shardID}) {
+			t.Errorf("%d. unexpected shards: %v", i, shards)
+		} else if q.String() != mustParseQuery(tt.q).String() {
+			t.Errorf("%d. query changed: %s", i, q)
+		}
+	}
+}
+
+// Ensure SELECT INTO writes its results to the target database.
+func TestQueryExecutor_ExecuteQuery_SelectInto(t *testing.T) {
+	store, executor := testStoreAndExecutor("")
+	defer os.RemoveAll(store.Path())
+
+	if err := store.WriteToShard(shardID, []tsdb.Point{
+		tsdb.NewPoint("cpu", map[string]string{"host": "server"}, map[string]interface{}{"value": 1.0}, time.Unix(1, 0)),
+		tsdb.NewPoint("cpu", map[string]string{"host": "server"}, map[string]interface{}{"value": 2.0}, time.Unix(2, 0)),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// SELECT INTO requires a writer for its results.
+	got := executeAndGetJSON("SELECT value INTO analytics..cpu_copy FROM cpu GROUP BY *", executor)
+	if exp := `[{"error":"SELECT INTO is not supported"}]`; got != exp {
+		t.Fatalf("\nexp: %s\ngot: %s", exp, got)
+	}
+
+	w := &testIntoWriter{}
+	executor.IntoWriter = w
+
+	got = executeAndGetJSON("SELECT value INTO analytics..cpu_copy FROM cpu GROUP BY *", executor)
+	if exp := `[{"series":[{"name":"result","columns":["time","written"],"values":[["1970-01-01T00:00:00Z",2]]}]}]`; got != exp {
+		t.Fatalf("\nexp: %s\ngot: %s", exp, got)
+	} else if len(w.requests) != 1 {
+		t.Fatalf("unexpected requests: %d", len(w.requests))
+	}
+
+	req := w.requests[0]
+	if req.Database != "analytics" || req.RetentionPolicy != "foo" {
+		t.Fatalf("unexpected target: %s.%s", req.Database, req.RetentionPolicy)
+	} else if len(req.Points) != 2 {
+		t.Fatalf("unexpected points: %v", req.Points)
+	} else if exp := "cpu_copy,host=server value=1 1000000000"; req.Points[0].String() != exp {
+		t.Fatalf("\nexp: %s\ngot: %s", exp, req.Points[0].String())
+	}
+
+	// A failed write fails the statement once the rest of the rows are read.
+	if err := store.WriteToShard(shardID, []tsdb.Point{
+		tsdb.NewPoint("cpu", map[string]string{"host": "other"}, map[string]interface{}{"value": 3.0}, time.Unix(3, 0)),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	w.err = errors.New("marker")
+	got = executeAndGetJSON("SELECT value INTO analytics..cpu_copy FROM cpu GROUP BY *", executor)
+	if exp := `[{"error":"marker"}]`; got != exp {
+		t.Fatalf("\nexp: %s\ngot: %s", exp, got)
+	}
+}
+
 // ensure that authenticate doesn't return an error if the user count is zero and they're attempting
 // to create a user.
 func TestAuthenticateIfUserCountZeroAndCreateUser(t *testing.T) {
@@ -346,6 +1139,36 @@ func TestAuthenticateIfUserCountZeroAndCreateUser(t *testing.T) {
 	}
 }
 
+// ensure that users of an external authentication provider are authorized by
+// their privileges even if no users exist in the meta store.
+func TestAuthorizeExternalUserIfUserCountZero(t *testing.T) {
+	store, executor := testStoreAndExecutor("")
+	defer os.RemoveAll(store.Path())
+	executor.MetaStore = &testMetastore{userCount: 0}
+
+	u := &meta.UserInfo{Name: "susy", Privileges: map[string]influxql.Privilege{"foo": influxql.ReadPrivilege}}
+	if err := executor.Authorize(u, mustParseQuery("select * from cpu"), "foo"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if executor.Authorize(u, mustParseQuery("drop database foo"), "foo") == nil {
+		t.Fatalf("should have failed authorization of statement requiring admin privilege")
+	}
+}
+
+// ensure that write-only users are not authorized to execute any statement.
+func TestAuthorizeWriteOnlyUser(t *testing.T) {
+	store, executor := testStoreAndExecutor("")
+	defer os.RemoveAll(store.Path())
+	executor.MetaStore = &testMetastore{userCount: 1}
+
+	u := &meta.UserInfo{Name: "agent", Privileges: map[string]influxql.Privilege{"foo": influxql.WritePrivilege}, WriteOnly: true}
+	for _, s := range []string{"select * from cpu", "show measurements", "drop series from cpu", "show databases"} {
+		if executor.Authorize(u, mustParseQuery(s), "foo") == nil {
+			t.Fatalf("should have failed authorization of %q", s)
+		}
+	}
+}
+
 func testStoreAndExecutor(storePath string) (*tsdb.Store, *tsdb.QueryExecutor) {
 	if storePath == "" {
 		storePath, _ = ioutil.TempDir("", "")
@@ -371,7 +1194,7 @@ func testStoreAndExecutor(storePath string) (*tsdb.Store, *tsdb.QueryExecutor) {
 }
 
 func executeAndGetJSON(query string, executor *tsdb.QueryExecutor) string {
-	ch, err := executor.ExecuteQuery(mustParseQuery(query), "foo", 20)
+	ch, err := executor.ExecuteQuery(mustParseQuery(query), "foo", 20, tsdb.ReadPreferenceNearest, nil)
 	if err != nil {
 		panic(err.Error())
 	}
@@ -389,7 +1212,8 @@ func executeAndGetJSON(query string, executor *tsdb.QueryExecutor) string {
 }
 
 type testMetastore struct {
-	userCount int
+	userCount  int
+	resolution time.Duration
 }
 
 func (t *testMetastore) Database(name string) (*meta.DatabaseInfo, error) {
@@ -432,7 +1256,8 @@ func (t *testMetastore) Authenticate(username, password string) (*meta.UserInfo,
 
 func (t *testMetastore) RetentionPolicy(database, name string) (rpi *meta.RetentionPolicyInfo, err error) {
 	return &meta.RetentionPolicyInfo{
-		Name: "bar",
+		Name:       "bar",
+		Resolution: t.resolution,
 		ShardGroups: []meta.ShardGroupInfo{
 			{
 				ID:        uint64(1),
@@ -469,15 +1294,37 @@ func (t *testMetastore) ShardGroupsByTimeRange(database, policy string, min, max
 	}, nil
 }
 
+func (t *testMetastore) CreateShardGroupIfNotExists(database, policy string, timestamp time.Time) (*meta.ShardGroupInfo, error) {
+	a, err := t.ShardGroupsByTimeRange(database, policy, timestamp, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	return &a[0], nil
+}
+
 func (t *testMetastore) NodeID() uint64 {
 	return 1
 }
 
+// testIntoWriter records the results of SELECT INTO statements.
+type testIntoWriter struct {
+	requests []*tsdb.IntoWriteRequest
+	err      error
+}
+
+func (w *testIntoWriter) WritePointsInto(p *tsdb.IntoWriteRequest) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.requests = append(w.requests, p)
+	return nil
+}
+
 type testShardMapper struct {
 	store *tsdb.Store
 }
 
-func (t *testShardMapper) CreateMapper(shard meta.ShardInfo, stmt influxql.Statement, chunkSize int) (tsdb.Mapper, error) {
+func (t *testShardMapper) CreateMapper(shard meta.ShardInfo, stmt influxql.Statement, chunkSize int, opt *tsdb.ReadOptions) (tsdb.Mapper, error) {
 	m, err := t.store.CreateMapper(shard.ID, stmt, chunkSize)
 	return m, err
 }
@@ -0,0 +1,68 @@
+package tsdb
+
+import "sync"
+
+// queryQueue admits up to max statements to run at once. The statements over
+// the limit wait in two tiers: waiting interactive statements are admitted
+// before any waiting batch statement, so batch statements such as long
+// exports don't delay dashboard queries. A max of zero is unlimited.
+type queryQueue struct {
+	mu      sync.Mutex
+	max     int
+	running int
+	waiting [2][]chan struct{} // interactive and batch statements, in order
+}
+
+// acquire waits until a statement may run. It must be released once done.
+func (q *queryQueue) acquire(batch bool) {
+	q.mu.Lock()
+	if q.max <= 0 || (q.running < q.max && q.waitingN() == 0) {
+		q.running++
+		q.mu.Unlock()
+		return
+	}
+
+	tier := 0
+	if batch {
+		tier = 1
+	}
+	ch := make(chan struct{})
+	q.waiting[tier] = append(q.waiting[tier], ch)
+	q.mu.Unlock()
+
+	<-ch
+}
+
+// release finishes a running statement and admits the next waiting one.
+func (q *queryQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.running--
+	q.admit()
+}
+
+// setMax sets the number of statements that may run at once and admits
+// waiting statements if it was raised.
+func (q *queryQueue) setMax(n int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.max = n
+	q.admit()
+}
+
+// waitingN returns the number of statements waiting to run.
+func (q *queryQueue) waitingN() int {
+	return len(q.waiting[0]) + len(q.waiting[1])
+}
+
+// admit runs waiting statements while there is room, interactive statements
+// first. The lock must be held.
+func (q *queryQueue) admit() {
+	for i := range q.waiting {
+		for len(q.waiting[i]) > 0 && (q.max <= 0 || q.running < q.max) {
+			close(q.waiting[i][0])
+			q.waiting[i] = q.waiting[i][1:]
+			q.running++
+		}
+	}
+}
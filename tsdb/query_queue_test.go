@@ -0,0 +1,80 @@
+package tsdb
+
+import (
+	"testing"
+	"time"
+)
+
+// Ensure waiting interactive statements are admitted before batch statements.
+func TestQueryQueue_Priority(t *testing.T) {
+	var q queryQueue
+	q.setMax(1)
+	q.acquire(true)
+
+	admitted := make(chan string, 3)
+	wait := func(name string, batch bool, n int) {
+		go func() {
+			q.acquire(batch)
+			admitted <- name
+		}()
+
+		// Wait for the statement to queue.
+		for {
+			q.mu.Lock()
+			waiting := q.waitingN()
+			q.mu.Unlock()
+			if waiting == n {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	wait("batch", true, 1)
+	wait("interactive0", false, 2)
+	wait("interactive1", false, 3)
+
+	for _, exp := range []string{"interactive0", "interactive1", "batch"} {
+		q.release()
+		select {
+		case name := <-admitted:
+			if name != exp {
+				t.Fatalf("unexpected statement admitted: %s, expected %s", name, exp)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for %s", exp)
+		}
+	}
+}
+
+// Ensure raising the limit admits waiting statements and a zero limit is
+// unlimited.
+func TestQueryQueue_SetMax(t *testing.T) {
+	var q queryQueue
+	q.setMax(1)
+	q.acquire(false)
+
+	admitted := make(chan struct{})
+	go func() {
+		q.acquire(true)
+		close(admitted)
+	}()
+
+	select {
+	case <-admitted:
+		t.Fatal("unexpected statement admitted over the limit")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	q.setMax(0)
+	select {
+	case <-admitted:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for statement")
+	}
+
+	// Unlimited statements don't wait.
+	q.acquire(true)
+	if q.running != 3 {
+		t.Fatalf("unexpected running statements: %d", q.running)
+	}
+}
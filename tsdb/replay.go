@@ -0,0 +1,103 @@
+package tsdb
+
+import (
+	"sync"
+	"time"
+)
+
+// ReplayProgress tracks the opening of the shards of a store and the replay
+// of their WALs. The WAL of a shard reports its segments as it replays them.
+// A nil ReplayProgress tracks nothing.
+type ReplayProgress struct {
+	mu     sync.Mutex
+	start  time.Time
+	status ReplayStatus
+
+	// Sizes of the WALs of the shards, for estimating the time left.
+	bytesTotal int64
+	bytesDone  int64
+}
+
+// ReplayStatus is the progress of a replay. SegmentsTotal only counts the
+// segments of the WALs which started replaying. ETA is the estimated time
+// left, encoded in nanoseconds. Err is set if the replay stopped before all
+// the shards were opened because opening one of them failed.
+type ReplayStatus struct {
+	Done             bool          `json:"done"`
+	ShardsOpened     int           `json:"shardsOpened"`
+	ShardsTotal      int           `json:"shardsTotal"`
+	SegmentsReplayed int           `json:"segmentsReplayed"`
+	SegmentsTotal    int           `json:"segmentsTotal"`
+	PointsReplayed   int64         `json:"pointsReplayed"`
+	ETA              time.Duration `json:"eta"`
+	Err              string        `json:"error,omitempty"`
+}
+
+// newReplayProgress returns the progress of opening shards with walBytes of
+// WAL files in total.
+func newReplayProgress(shards int, walBytes int64, now time.Time) *ReplayProgress {
+	return &ReplayProgress{
+		start:      now,
+		status:     ReplayStatus{ShardsTotal: shards, Done: shards == 0},
+		bytesTotal: walBytes,
+	}
+}
+
+// AddSegments records that a WAL started replaying n segments.
+func (p *ReplayProgress) AddSegments(n int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status.SegmentsTotal += n
+}
+
+// SegmentReplayed records that a WAL replayed a segment of n points.
+func (p *ReplayProgress) SegmentReplayed(n int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status.SegmentsReplayed++
+	p.status.PointsReplayed += int64(n)
+}
+
+// shardOpened records that a shard with walBytes of WAL files was opened,
+// or quarantined.
+func (p *ReplayProgress) shardOpened(walBytes int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status.ShardsOpened++
+	p.bytesDone += walBytes
+	p.status.Done = p.status.ShardsOpened >= p.status.ShardsTotal
+}
+
+// stopped records that the replay finished before all the shards were
+// opened, because opening one failed with err or the store was closed.
+func (p *ReplayProgress) stopped(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status.Done = true
+	if err != nil {
+		p.status.Err = err.Error()
+	}
+}
+
+// Status returns the progress at now. The time left is estimated from the
+// rate the WAL files were replayed at so far.
+func (p *ReplayProgress) Status(now time.Time) ReplayStatus {
+	if p == nil {
+		return ReplayStatus{Done: true}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	st := p.status
+	if !st.Done && p.bytesDone > 0 {
+		elapsed := now.Sub(p.start)
+		st.ETA = time.Duration(float64(elapsed) * float64(p.bytesTotal-p.bytesDone) / float64(p.bytesDone))
+	}
+	return st
+}
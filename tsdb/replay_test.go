@@ -0,0 +1,44 @@
+package tsdb
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Ensure the time left of a replay is estimated from the WAL bytes replayed.
+func TestReplayProgress_Status(t *testing.T) {
+	start := time.Unix(0, 0)
+	p := newReplayProgress(2, 300, start)
+	p.AddSegments(3)
+	p.SegmentReplayed(10)
+	if st := p.Status(start.Add(time.Second)); !reflect.DeepEqual(st, ReplayStatus{ShardsTotal: 2, SegmentsReplayed: 1, SegmentsTotal: 3, PointsReplayed: 10}) {
+		t.Fatalf("unexpected status: %+v", st)
+	}
+
+	p.shardOpened(100)
+	if st := p.Status(start.Add(time.Minute)); st.Done || st.ShardsOpened != 1 || st.ETA != 2*time.Minute {
+		t.Fatalf("unexpected status: %+v", st)
+	}
+
+	p.shardOpened(200)
+	if st := p.Status(start.Add(time.Hour)); !st.Done || st.ETA != 0 {
+		t.Fatalf("unexpected status: %+v", st)
+	}
+
+	// A replay stopped by an error is finished.
+	p = newReplayProgress(2, 0, start)
+	p.shardOpened(0)
+	p.stopped(errors.New("failed to open shard 2"))
+	if st := p.Status(start); !st.Done || st.ShardsOpened != 1 || st.Err != "failed to open shard 2" {
+		t.Fatalf("unexpected status: %+v", st)
+	}
+
+	// A nil progress has nothing to replay.
+	var nilp *ReplayProgress
+	nilp.AddSegments(1)
+	if st := nilp.Status(start); !st.Done {
+		t.Fatalf("unexpected status: %+v", st)
+	}
+}
@@ -0,0 +1,312 @@
+package tsdb
+
+import (
+	"strings"
+	"time"
+
+	"github.com/influxdb/influxdb/influxql"
+	"github.com/influxdb/influxdb/meta"
+)
+
+// rollupAggregates maps the aggregates a rollup SELECT can read from
+// downsampled points to the aggregate the points must have been downsampled
+// with, and the aggregate reading them. Means aren't read, since the mean of
+// downsampled means isn't weighted by their point counts.
+var rollupAggregates = map[string]struct{ downsampled, read string }{
+	"count": {"count", "sum"},
+	"first": {"first", "first"},
+	"last":  {"last", "last"},
+	"max":   {"max", "max"},
+	"min":   {"min", "min"},
+	"sum":   {"sum", "sum"},
+}
+
+// rollupTier is a retention policy a rollup SELECT reads the points before a
+// time from.
+type rollupTier struct {
+	policy *meta.DownsamplePolicyInfo
+	until  time.Time
+}
+
+// rollupStatements splits a SELECT statement into statements reading the older
+// points of its source from the retention policies the downsample policies
+// write to, and the newer ones from its source, oldest first. The points
+// before the time a policy downsampled until are read from its target, and the
+// policies downsampling the target in turn are followed. Returns nil if the
+// statement can't be read from downsampled points.
+//
+// Only statements grouping by a multiple of the downsample intervals, with
+// aggregates the points were downsampled with, are split. Counts are read as
+// the sum of the downsampled counts.
+func rollupStatements(stmt *influxql.SelectStatement, di *meta.DatabaseInfo, now time.Time) ([]*influxql.SelectStatement, error) {
+	if len(stmt.Sources) != 1 || stmt.Target != nil || stmt.Limit > 0 || stmt.Offset > 0 || stmt.SLimit > 0 || stmt.SOffset > 0 {
+		return nil, nil
+	}
+	mm, ok := stmt.Sources[0].(*influxql.Measurement)
+	if !ok || mm.Regex != nil {
+		return nil, nil
+	}
+	for _, f := range stmt.SortFields {
+		if !f.Ascending {
+			return nil, nil
+		}
+	}
+
+	interval, err := stmt.GroupByInterval()
+	if err != nil {
+		return nil, err
+	} else if interval <= 0 {
+		return nil, nil
+	}
+
+	// Every field must be an aggregate of a field which can be rolled up.
+	fields := make(map[string]string)
+	for _, f := range stmt.Fields {
+		call, ok := f.Expr.(*influxql.Call)
+		if !ok || len(call.Args) != 1 {
+			return nil, nil
+		}
+		ref, ok := call.Args[0].(*influxql.VarRef)
+		if !ok {
+			return nil, nil
+		} else if _, ok := rollupAggregates[strings.ToLower(call.Name)]; !ok {
+			return nil, nil
+		}
+		fields[ref.Val] = strings.ToLower(call.Name)
+	}
+
+	// Follow the downsample policies from the source retention policy.
+	var tiers []rollupTier
+	visited := map[string]bool{mm.RetentionPolicy: true}
+	for policy := mm.RetentionPolicy; ; {
+		dpi := rollupPolicy(di, policy, interval, fields)
+		if dpi == nil || visited[dpi.TargetRetentionPolicy] {
+			break
+		}
+		visited[dpi.TargetRetentionPolicy] = true
+
+		// Buckets aren't split between tiers, and each tier ends before the
+		// one it's downsampled from.
+		until := dpi.DownsampledUntil.Truncate(interval)
+		if len(tiers) > 0 && until.After(tiers[len(tiers)-1].until) {
+			until = tiers[len(tiers)-1].until
+		}
+		tiers = append(tiers, rollupTier{policy: dpi, until: until})
+		policy = dpi.TargetRetentionPolicy
+	}
+	if len(tiers) == 0 {
+		return nil, nil
+	}
+
+	cond := influxql.Reduce(stmt.Condition, &influxql.NowValuer{Now: now})
+	tmin, tmax := influxql.TimeRange(cond)
+
+	// Build the statements from the oldest tier to the source, which is read
+	// from the time the first tier ends.
+	var a []*influxql.SelectStatement
+	for i := len(tiers); i >= 0; i-- {
+		var lo, hi time.Time
+		policy := mm.RetentionPolicy
+		if i > 0 {
+			hi = tiers[i-1].until
+			policy = tiers[i-1].policy.TargetRetentionPolicy
+		}
+		if i < len(tiers) {
+			lo = tiers[i].until
+		}
+
+		// Skip the tiers without points in the time range of the statement.
+		if !lo.IsZero() && !hi.IsZero() && !lo.Before(hi) {
+			continue
+		} else if !tmin.IsZero() && !hi.IsZero() && !tmin.Before(hi) {
+			continue
+		} else if !tmax.IsZero() && !lo.IsZero() && tmax.Before(lo) {
+			continue
+		}
+
+		other := stmt.Clone()
+		other.Sources[0].(*influxql.Measurement).RetentionPolicy = policy
+		other.Condition = rollupCondition(cond, lo, hi)
+		if i > 0 {
+			for _, f := range other.Fields {
+				call := f.Expr.(*influxql.Call)
+				if f.Alias == "" {
+					f.Alias = call.Name
+				}
+				call.Name = rollupAggregates[strings.ToLower(call.Name)].read
+			}
+		}
+		a = append(a, other)
+	}
+	if len(a) < 2 {
+		return nil, nil
+	}
+	return a, nil
+}
+
+// rollupPolicy returns the downsample policy of a retention policy the fields
+// of a statement grouped by interval can be read from. The coarsest is
+// preferred. Returns nil if there's none.
+func rollupPolicy(di *meta.DatabaseInfo, policy string, interval time.Duration, fields map[string]string) *meta.DownsamplePolicyInfo {
+	var found *meta.DownsamplePolicyInfo
+Loop:
+	for i := range di.DownsamplePolicies {
+		dpi := &di.DownsamplePolicies[i]
+		if dpi.SourceRetentionPolicy != policy || dpi.DownsampledUntil.IsZero() || dpi.Interval <= 0 || interval%dpi.Interval != 0 {
+			continue
+		}
+		for field, aggregate := range fields {
+			if strings.ToLower(dpi.Aggregate(field)) != rollupAggregates[aggregate].downsampled {
+				continue Loop
+			}
+		}
+		if found == nil || dpi.Interval > found.Interval || dpi.Interval == found.Interval && dpi.Name < found.Name {
+			found = dpi
+		}
+	}
+	return found
+}
+
+// rollupCondition limits a condition to the points from lo up to hi. Zero
+// times are unbounded.
+func rollupCondition(cond influxql.Expr, lo, hi time.Time) influxql.Expr {
+	if !lo.IsZero() {
+		cond = rollupAnd(cond, &influxql.BinaryExpr{
+			Op:  influxql.GTE,
+			LHS: &influxql.VarRef{Val: "time"},
+			RHS: &influxql.TimeLiteral{Val: lo},
+		})
+	}
+	if !hi.IsZero() {
+		cond = rollupAnd(cond, &influxql.BinaryExpr{
+			Op:  influxql.LT,
+			LHS: &influxql.VarRef{Val: "time"},
+			RHS: &influxql.TimeLiteral{Val: hi},
+		})
+	}
+	return cond
+}
+
+func rollupAnd(lhs, rhs influxql.Expr) influxql.Expr {
+	if lhs == nil {
+		return rhs
+	}
+	if _, ok := lhs.(*influxql.BinaryExpr); ok {
+		lhs = &influxql.ParenExpr{Expr: lhs}
+	}
+	return &influxql.BinaryExpr{Op: influxql.AND, LHS: lhs, RHS: rhs}
+}
+
+// rollupExecutor runs the executors of the statements a rollup SELECT is split
+// into, oldest first, and stitches the rows of each series together. The
+// executors send the series in the order of their tag sets, so the rows are
+// streamed series by series, with only the next row of each executor held.
+type rollupExecutor struct {
+	executors []Executor
+}
+
+// Execute begins execution of the executors and returns the stitched rows.
+func (e *rollupExecutor) Execute() <-chan *influxql.Row {
+	out := make(chan *influxql.Row, 0)
+	go e.execute(out)
+	return out
+}
+
+func (e *rollupExecutor) execute(out chan *influxql.Row) {
+	defer close(out)
+
+	chs := make([]<-chan *influxql.Row, len(e.executors))
+	for i, ex := range e.executors {
+		chs[i] = ex.Execute()
+	}
+	// Drain the executors on error, so they can finish.
+	defer func() {
+		for _, ch := range chs {
+			for _ = range ch {
+			}
+		}
+	}()
+
+	heads := make([]*influxql.Row, len(chs))
+	next := func(i int) error {
+		row, ok := <-chs[i]
+		if !ok {
+			heads[i] = nil
+			return nil
+		} else if row.Err != nil {
+			return row.Err
+		}
+		heads[i] = row
+		return nil
+	}
+	for i := range chs {
+		if err := next(i); err != nil {
+			out <- &influxql.Row{Err: err}
+			return
+		}
+	}
+
+	for {
+		// Send the rows of the lowest series of every executor, oldest first.
+		var key string
+		var found bool
+		for _, row := range heads {
+			if row == nil {
+				continue
+			} else if k := rollupRowKey(row); !found || k < key {
+				key, found = k, true
+			}
+		}
+		if !found {
+			return
+		}
+
+		for i := range heads {
+			for heads[i] != nil && rollupRowKey(heads[i]) == key {
+				out <- heads[i]
+				if err := next(i); err != nil {
+					out <- &influxql.Row{Err: err}
+					return
+				}
+			}
+		}
+	}
+}
+
+// rollupRowKey returns the key rows are stitched and ordered by.
+func rollupRowKey(row *influxql.Row) string {
+	return row.Name + "\x00" + string(MarshalTags(row.Tags))
+}
+
+// planRollupSelect plans a SELECT statement reading older points from
+// downsampled retention policies. Returns nil if it can't be.
+func (q *QueryExecutor) planRollupSelect(stmt *influxql.SelectStatement, chunkSize int, opt *ReadOptions) (Executor, error) {
+	if len(stmt.Sources) != 1 {
+		return nil, nil
+	}
+	mm, ok := stmt.Sources[0].(*influxql.Measurement)
+	if !ok {
+		return nil, nil
+	}
+	di, err := q.MetaStore.Database(mm.Database)
+	if err != nil {
+		return nil, err
+	} else if di == nil {
+		return nil, ErrDatabaseNotFound(mm.Database)
+	}
+
+	stmts, err := rollupStatements(stmt, di, time.Now().UTC())
+	if err != nil || stmts == nil {
+		return nil, err
+	}
+
+	e := &rollupExecutor{}
+	for _, s := range stmts {
+		ex, err := q.PlanSelect(s, chunkSize, opt)
+		if err != nil {
+			return nil, err
+		}
+		e.executors = append(e.executors, ex)
+	}
+	return e, nil
+}
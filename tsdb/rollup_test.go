@@ -0,0 +1,136 @@
+package tsdb
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/influxql"
+	"github.com/influxdb/influxdb/meta"
+)
+
+// Ensure rollup SELECTs are split across the downsampled retention policies.
+func TestRollupStatements(t *testing.T) {
+	now := time.Date(2000, 1, 10, 0, 0, 0, 0, time.UTC)
+	di := &meta.DatabaseInfo{
+		Name: "db0",
+		DownsamplePolicies: []meta.DownsamplePolicyInfo{
+			{Name: "5m", SourceRetentionPolicy: "raw", TargetRetentionPolicy: "rp5m", Interval: 5 * time.Minute, DownsampledUntil: time.Date(2000, 1, 9, 0, 30, 0, 0, time.UTC)},
+			{Name: "10m", SourceRetentionPolicy: "raw", TargetRetentionPolicy: "rp10m", Interval: 10 * time.Minute, DownsampledUntil: time.Date(2000, 1, 9, 0, 30, 0, 0, time.UTC), DefaultAggregate: "max"},
+			{Name: "1h", SourceRetentionPolicy: "raw", TargetRetentionPolicy: "rp1h", Interval: time.Hour, DownsampledUntil: time.Date(2000, 1, 9, 12, 30, 0, 0, time.UTC), Aggregates: map[string]string{"value": "count"}},
+			{Name: "1d", SourceRetentionPolicy: "rp1h", TargetRetentionPolicy: "rp1d", Interval: 24 * time.Hour, DownsampledUntil: time.Date(2000, 1, 5, 0, 0, 0, 0, time.UTC), Aggregates: map[string]string{"value": "sum"}},
+		},
+	}
+
+	for i, tt := range []struct {
+		s     string
+		stmts []string
+		err   string
+	}{
+		// Counts are read as the sum of the downsampled counts, oldest tier first.
+		{
+			s: `SELECT count(value) FROM db0.raw.cpu WHERE time > now() - 7d GROUP BY time(1h)`,
+			stmts: []string{
+				`SELECT sum(value) AS "count" FROM "db0"."rp1h".cpu WHERE (time > '2000-01-03T00:00:00Z') AND time < '2000-01-09T12:00:00Z' GROUP BY time(1h)`,
+				`SELECT count(value) FROM "db0"."raw".cpu WHERE (time > '2000-01-03T00:00:00Z') AND time >= '2000-01-09T12:00:00Z' GROUP BY time(1h)`,
+			},
+		},
+
+		// Only the policies downsampling with the aggregate are read.
+		{
+			s: `SELECT max(value) AS v FROM db0.raw.cpu WHERE time > '2000-01-08T00:00:00Z' GROUP BY time(10m), host`,
+			stmts: []string{
+				`SELECT max(value) AS "v" FROM "db0"."rp10m".cpu WHERE (time > '2000-01-08T00:00:00Z') AND time < '2000-01-09T00:30:00Z' GROUP BY time(10m), host`,
+				`SELECT max(value) AS "v" FROM "db0"."raw".cpu WHERE (time > '2000-01-08T00:00:00Z') AND time >= '2000-01-09T00:30:00Z' GROUP BY time(10m), host`,
+			},
+		},
+
+		// Tiers outside the time range aren't read.
+		{
+			s: `SELECT max(value) FROM db0.raw.cpu WHERE time > '2000-01-09T06:00:00Z' GROUP BY time(10m)`,
+		},
+
+		// Statements which can't be read from downsampled points aren't split.
+		{s: `SELECT value FROM db0.raw.cpu`},
+		{s: `SELECT mean(value) FROM db0.raw.cpu WHERE time > '2000-01-08T00:00:00Z' GROUP BY time(10m)`},
+		{s: `SELECT max(value) FROM db0.raw.cpu WHERE time > now() - 7d GROUP BY time(7m)`},
+		{s: `SELECT percentile(value, 90) FROM db0.raw.cpu WHERE time > now() - 7d GROUP BY time(1h)`},
+		{s: `SELECT mean(value) FROM db0.raw.cpu WHERE time > now() - 7d GROUP BY time(1h) LIMIT 1`},
+		{s: `SELECT mean(value) FROM db0.raw./cpu/ WHERE time > now() - 7d GROUP BY time(1h)`},
+		{s: `SELECT mean(value) FROM db0.rp1d.cpu WHERE time > now() - 7d GROUP BY time(1d)`},
+	} {
+		stmt := MustParseSelectStatement(tt.s)
+		a, err := rollupStatements(stmt, di, now)
+		if errString(err) != tt.err {
+			t.Errorf("%d. unexpected error: %s", i, err)
+			continue
+		}
+
+		var stmts []string
+		for _, s := range a {
+			stmts = append(stmts, s.String())
+		}
+		if !reflect.DeepEqual(stmts, tt.stmts) {
+			t.Errorf("%d. unexpected statements:\n\nexp=%#v\n\ngot=%#v", i, tt.stmts, stmts)
+		}
+	}
+}
+
+// Ensure the rows of each series are stitched together, series by series.
+func TestRollupExecutor_Execute(t *testing.T) {
+	e := &rollupExecutor{executors: []Executor{
+		&rowsExecutor{rows: influxql.Rows{
+			{Name: "cpu", Tags: map[string]string{"host": "a"}, Columns: []string{"time", "mean"}, Values: [][]interface{}{{1, 1.0}}},
+			{Name: "cpu", Tags: map[string]string{"host": "b"}, Columns: []string{"time", "mean"}, Values: [][]interface{}{{1, 2.0}}},
+		}},
+		&rowsExecutor{rows: influxql.Rows{
+			{Name: "cpu", Tags: map[string]string{"host": "a"}, Columns: []string{"time", "mean"}, Values: [][]interface{}{{2, 4.0}}},
+			{Name: "cpu", Tags: map[string]string{"host": "c"}, Columns: []string{"time", "mean"}, Values: [][]interface{}{{2, 3.0}}},
+		}},
+	}}
+
+	var rows influxql.Rows
+	for row := range e.Execute() {
+		rows = append(rows, row)
+	}
+
+	var values []string
+	for _, row := range rows {
+		values = append(values, fmt.Sprintf("%s=%v", row.Tags["host"], row.Values))
+	}
+	if exp := []string{"a=[[1 1]]", "a=[[2 4]]", "b=[[1 2]]", "c=[[2 3]]"}; !reflect.DeepEqual(values, exp) {
+		t.Fatalf("unexpected rows: %v", values)
+	}
+}
+
+// rowsExecutor is an executor returning a fixed set of rows.
+type rowsExecutor struct {
+	rows influxql.Rows
+}
+
+func (e *rowsExecutor) Execute() <-chan *influxql.Row {
+	out := make(chan *influxql.Row, len(e.rows))
+	for _, row := range e.rows {
+		out <- row
+	}
+	close(out)
+	return out
+}
+
+// MustParseSelectStatement parses a select statement. Panic on error.
+func MustParseSelectStatement(s string) *influxql.SelectStatement {
+	stmt, err := influxql.NewParser(strings.NewReader(s)).ParseStatement()
+	if err != nil {
+		panic(err)
+	}
+	return stmt.(*influxql.SelectStatement)
+}
+
+func errString(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return ""
+}
@@ -0,0 +1,97 @@
+package tsdb
+
+import "sync"
+
+// seriesLocks blocks the writes to series while they're rewritten. Writes
+// share the locks of the series they write to, rewrites hold them
+// exclusively. The zero value is ready to use.
+type seriesLocks struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	writers map[string]int
+	locked  map[string]struct{}
+}
+
+// init initializes the locks. The mutex must be held by the caller.
+func (l *seriesLocks) init() {
+	if l.cond == nil {
+		l.cond = sync.NewCond(&l.mu)
+		l.writers = make(map[string]int)
+		l.locked = make(map[string]struct{})
+	}
+}
+
+// anyLocked returns true if any of keys is held exclusively. The mutex must be
+// held by the caller.
+func (l *seriesLocks) anyLocked(keys []string) bool {
+	if len(l.locked) == 0 {
+		return false
+	}
+	for _, k := range keys {
+		if _, ok := l.locked[k]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// rlock waits until none of keys is held exclusively and then shares their
+// locks for a write.
+func (l *seriesLocks) rlock(keys []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.init()
+
+	for l.anyLocked(keys) {
+		l.cond.Wait()
+	}
+	for _, k := range keys {
+		l.writers[k]++
+	}
+}
+
+// runlock releases the locks of keys shared by rlock.
+func (l *seriesLocks) runlock(keys []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, k := range keys {
+		if l.writers[k]--; l.writers[k] <= 0 {
+			delete(l.writers, k)
+		}
+	}
+	if len(l.locked) > 0 {
+		l.cond.Broadcast()
+	}
+}
+
+// lock holds keys exclusively. Later writes to keys wait and the writes
+// already in progress are waited for.
+func (l *seriesLocks) lock(keys []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.init()
+
+	for l.anyLocked(keys) {
+		l.cond.Wait()
+	}
+	for _, k := range keys {
+		l.locked[k] = struct{}{}
+	}
+	for _, k := range keys {
+		for l.writers[k] > 0 {
+			l.cond.Wait()
+		}
+	}
+}
+
+// unlock releases keys held by lock.
+func (l *seriesLocks) unlock(keys []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, k := range keys {
+		delete(l.locked, k)
+	}
+	l.cond.Broadcast()
+}
@@ -0,0 +1,47 @@
+package tsdb
+
+import (
+	"testing"
+	"time"
+)
+
+// Ensure writes to a series wait while it's locked and a lock waits for the
+// writes in progress.
+func TestSeriesLocks(t *testing.T) {
+	var l seriesLocks
+	l.rlock([]string{"cpu,host=a"})
+
+	locked := make(chan struct{})
+	go func() {
+		l.lock([]string{"cpu,host=a", "cpu,host=b"})
+		close(locked)
+	}()
+
+	select {
+	case <-locked:
+		t.Fatal("expected lock to wait for the write in progress")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// Writes to other series don't wait.
+	l.rlock([]string{"mem"})
+	l.runlock([]string{"mem"})
+
+	l.runlock([]string{"cpu,host=a"})
+	<-locked
+
+	written := make(chan struct{})
+	go func() {
+		l.rlock([]string{"cpu,host=b"})
+		close(written)
+	}()
+
+	select {
+	case <-written:
+		t.Fatal("expected write to wait for the lock")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	l.unlock([]string{"cpu,host=a", "cpu,host=b"})
+	<-written
+}
@@ -7,9 +7,12 @@ import (
 	"expvar"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"math"
 	"os"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/influxdb/influxdb"
 	"github.com/influxdb/influxdb/influxql"
@@ -26,6 +29,20 @@ const (
 	statWritePointsFail = "write_points_fail"
 	statWritePointsOK   = "write_points_ok"
 	statWriteBytes      = "write_bytes"
+	statSeriesFiltered  = "series_filtered" // series ruled out by the engine's series filter
+
+	// Gauges computed when the statistics are read.
+	statDiskBytes    = "disk_bytes"
+	statSeriesN      = "series_n"
+	statCacheBytes   = "cache_bytes"
+	statLastModified = "last_modified" // unix nanoseconds
+
+	// Write ahead log gauges, if the engine has one.
+	statWALSegments          = "wal_segments"
+	statWALSegmentsBytes     = "wal_segments_bytes"
+	statWALCacheSeries       = "wal_cache_series"
+	statWALLastFlushDuration = "wal_last_flush_duration" // nanoseconds
+	statWALBacklogBytes      = "wal_backlog_bytes"
 )
 
 var (
@@ -59,6 +76,7 @@ type Shard struct {
 
 	mu                sync.RWMutex
 	measurementFields map[string]*MeasurementFields // measurement name to their fields
+	lastModified      time.Time
 
 	// expvar-based stats.
 	statMap *expvar.Map
@@ -74,7 +92,7 @@ func NewShard(id uint64, index *DatabaseIndex, path string, walPath string, opti
 	tags := map[string]string{"path": path, "id": fmt.Sprintf("%d", id), "engine": options.EngineVersion}
 	statMap := influxdb.NewStatistics(key, "shard", tags)
 
-	return &Shard{
+	s := &Shard{
 		index:             index,
 		path:              path,
 		walPath:           walPath,
@@ -85,6 +103,25 @@ func NewShard(id uint64, index *DatabaseIndex, path string, walPath string, opti
 		statMap:   statMap,
 		LogOutput: os.Stderr,
 	}
+
+	statMap.Set(statDiskBytes, expvar.Func(func() interface{} {
+		n, _ := s.DiskSize()
+		return n
+	}))
+	statMap.Set(statSeriesN, expvar.Func(func() interface{} { return s.seriesN() }))
+	statMap.Set(statCacheBytes, expvar.Func(func() interface{} { return s.cacheSize() }))
+	statMap.Set(statLastModified, expvar.Func(func() interface{} {
+		if t := s.LastModified(); !t.IsZero() {
+			return t.UnixNano()
+		}
+		return int64(0)
+	}))
+	statMap.Set(statWALSegments, expvar.Func(func() interface{} { return int64(s.walStats().SegmentN) }))
+	statMap.Set(statWALSegmentsBytes, expvar.Func(func() interface{} { return s.walStats().SegmentBytes }))
+	statMap.Set(statWALCacheSeries, expvar.Func(func() interface{} { return int64(s.walStats().CacheSeriesN) }))
+	statMap.Set(statWALLastFlushDuration, expvar.Func(func() interface{} { return int64(s.walStats().LastFlushDuration) }))
+	statMap.Set(statWALBacklogBytes, expvar.Func(func() interface{} { return s.walStats().BacklogBytes }))
+	return s
 }
 
 // Path returns the path set on the shard when it was created.
@@ -96,33 +133,29 @@ func (s *Shard) Open() error {
 		s.mu.Lock()
 		defer s.mu.Unlock()
 
-		s.index.mu.Lock()
-		defer s.index.mu.Unlock()
-
 		// Return if the shard is already open
 		if s.engine != nil {
 			return nil
 		}
 
-		// Initialize underlying engine.
-		e, err := NewEngine(s.path, s.walPath, s.options)
+		wal, err := s.openIndex()
 		if err != nil {
-			return fmt.Errorf("new engine: %s", err)
+			return err
 		}
-		s.engine = e
-
-		// Set log output on the engine.
-		s.engine.SetLogOutput(s.LogOutput)
 
-		// Open engine.
-		if err := s.engine.Open(); err != nil {
-			return fmt.Errorf("open engine: %s", err)
+		// Replay the WAL without holding the lock of the database index, so
+		// the shards of a database can replay at once.
+		if wal != nil {
+			if err := wal.OpenWAL(); err != nil {
+				return &openError{"open wal", err}
+			}
 		}
 
-		// Load metadata index.
-		if err := s.engine.LoadMetadataIndex(s.index, s.measurementFields); err != nil {
-			return fmt.Errorf("load metadata index: %s", err)
+		t, err := s.fileModTime()
+		if err != nil {
+			return &openError{"mod time", err}
 		}
+		s.lastModified = t
 
 		return nil
 	}(); err != nil {
@@ -133,6 +166,49 @@ func (s *Shard) Open() error {
 	return nil
 }
 
+// openIndex opens the engine of the shard and loads its metadata into the
+// database index. Returns the engine if its WAL is left to be opened.
+func (s *Shard) openIndex() (DeferredWALOpener, error) {
+	s.index.mu.Lock()
+	defer s.index.mu.Unlock()
+
+	// Initialize underlying engine.
+	e, err := NewEngine(s.path, s.walPath, s.options)
+	if err != nil {
+		return nil, &openError{"new engine", err}
+	}
+	s.engine = e
+
+	// Set log output on the engine.
+	s.engine.SetLogOutput(s.LogOutput)
+
+	// Open engine.
+	if err := s.engine.Open(); err != nil {
+		return nil, &openError{"open engine", err}
+	}
+
+	wal, _ := s.engine.(DeferredWALOpener)
+	if wal != nil {
+		wal.DeferWALOpen()
+	}
+
+	// Load metadata index.
+	if err := s.engine.LoadMetadataIndex(s.index, s.measurementFields); err != nil {
+		return nil, fmt.Errorf("load metadata index: %s", err)
+	}
+	return wal, nil
+}
+
+// openError is an error opening the files of a shard. It keeps the error
+// returned by the engine so the store can tell corrupt files from errors
+// which opening the shard again may not hit.
+type openError struct {
+	op  string
+	err error
+}
+
+func (e *openError) Error() string { return e.op + ": " + e.err.Error() }
+
 // Close shuts down the shard's store.
 func (s *Shard) Close() error {
 	s.mu.Lock()
@@ -160,6 +236,83 @@ func (s *Shard) DiskSize() (int64, error) {
 	return size, nil
 }
 
+// LastModified returns the last time data was written to or deleted from
+// the shard. WAL flushes and compactions don't count as modifications.
+func (s *Shard) LastModified() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastModified
+}
+
+// touch marks the shard as modified now.
+func (s *Shard) touch() {
+	s.mu.Lock()
+	s.lastModified = time.Now().UTC()
+	s.mu.Unlock()
+}
+
+// touchFields marks the shard and the fields of points as modified now.
+func (s *Shard) touchFields(points []Point) {
+	now := time.Now().UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastModified = now
+	for _, p := range points {
+		mf := s.measurementFields[p.Name()]
+		if mf == nil {
+			continue
+		}
+		if mf.lastWritten == nil {
+			mf.lastWritten = make(map[string]time.Time)
+		}
+		for name := range p.Fields() {
+			mf.lastWritten[name] = now
+		}
+	}
+}
+
+// MeasurementFieldInfo returns the fields of a measurement on the shard, sorted
+// by name. Last written times are only known for fields written since the
+// shard was opened.
+func (s *Shard) MeasurementFieldInfo(name string) []FieldInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	mf := s.measurementFields[name]
+	if mf == nil {
+		return nil
+	}
+
+	a := make([]FieldInfo, 0, len(mf.Fields))
+	for _, f := range mf.Fields {
+		a = append(a, FieldInfo{Name: f.Name, Type: f.Type, LastWritten: mf.lastWritten[f.Name]})
+	}
+	sort.Sort(fieldInfos(a))
+	return a
+}
+
+// fileModTime returns the latest modification time of the shard's data file
+// and WAL files. This is used as the last modified time on open.
+func (s *Shard) fileModTime() (time.Time, error) {
+	fi, err := os.Stat(s.path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t := fi.ModTime()
+
+	fis, err := ioutil.ReadDir(s.walPath)
+	if err != nil && !os.IsNotExist(err) {
+		return time.Time{}, err
+	}
+	for _, fi := range fis {
+		if fi.ModTime().After(t) {
+			t = fi.ModTime()
+		}
+	}
+	return t.UTC(), nil
+}
+
 // ReadOnlyTx returns a read-only transaction for the shard.  The transaction must be rolled back to
 // release resources.
 func (s *Shard) ReadOnlyTx() (Tx, error) {
@@ -178,6 +331,25 @@ func (s *Shard) FieldCodec(measurementName string) *FieldCodec {
 	return m.Codec
 }
 
+// MayContainSeries returns false if the shard holds no points for a series.
+// Shards whose engine has no series filter may hold any series.
+func (s *Shard) MayContainSeries(key string) bool {
+	sf, ok := s.engine.(SeriesFilter)
+	if !ok || sf.MayContainSeries(key) {
+		return true
+	}
+	s.statMap.Add(statSeriesFiltered, 1)
+	return false
+}
+
+// HasMeasurement returns true if points have been written to the shard for a measurement.
+func (s *Shard) HasMeasurement(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.measurementFields[name]
+	return ok
+}
+
 // struct to hold information for a field to create on a measurement
 type FieldCreate struct {
 	Measurement string
@@ -192,6 +364,19 @@ type SeriesCreate struct {
 
 // WritePoints will write the raw data points and any new metadata to the index in the shard
 func (s *Shard) WritePoints(points []Point) error {
+	// Wait for the series being rewritten.
+	keys := make([]string, len(points))
+	for i, p := range points {
+		keys[i] = string(p.Key())
+	}
+	s.index.locks.rlock(keys)
+	defer s.index.locks.runlock(keys)
+
+	return s.writePoints(points)
+}
+
+// writePoints writes points without waiting for the locks of their series.
+func (s *Shard) writePoints(points []Point) error {
 	s.statMap.Add(statWriteReq, 1)
 
 	seriesToCreate, fieldsToCreate, seriesToAddShardTo, err := s.validateSeriesAndFields(points)
@@ -257,16 +442,50 @@ func (s *Shard) WritePoints(points []Point) error {
 		return fmt.Errorf("engine: %s", err)
 	}
 	s.statMap.Add(statWritePointsOK, int64(len(points)))
+	s.touchFields(points)
 
 	return nil
 }
 
+// CreateSeries adds the series keys not yet in the shard to its index without
+// writing points, so points later written to them don't create them.
+func (s *Shard) CreateSeries(keys []string) error {
+	var seriesToCreate []*SeriesCreate
+	s.index.mu.RLock()
+	for _, key := range keys {
+		name, tags := ParseKey(key)
+		if ss := s.index.series[key]; ss == nil {
+			seriesToCreate = append(seriesToCreate, &SeriesCreate{name, NewSeries(key, tags)})
+		} else if !ss.shardIDs[s.id] {
+			seriesToCreate = append(seriesToCreate, &SeriesCreate{name, ss})
+		}
+	}
+	s.index.mu.RUnlock()
+	if len(seriesToCreate) == 0 {
+		return nil
+	}
+	s.statMap.Add(statSeriesCreate, int64(len(seriesToCreate)))
+
+	s.index.mu.Lock()
+	for _, sc := range seriesToCreate {
+		ss := s.index.CreateSeriesIndexIfNotExists(sc.Measurement, sc.Series)
+		ss.shardIDs[s.id] = true
+	}
+	s.index.mu.Unlock()
+
+	if err := s.engine.WritePoints(nil, nil, seriesToCreate); err != nil {
+		return fmt.Errorf("engine: %s", err)
+	}
+	s.touch()
+	return nil
+}
+
 func (s *Shard) ValidateAggregateFieldsInStatement(measurementName string, stmt *influxql.SelectStatement) error {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	validateType := func(aname, fname string, t influxql.DataType) error {
-		if t != influxql.Float && t != influxql.Integer {
+		if t != influxql.Float && t != influxql.Integer && t != influxql.Unsigned {
 			return fmt.Errorf("aggregate '%s' requires numerical field values. Field '%s' is of type %s",
 				aname, fname, t)
 		}
@@ -315,7 +534,11 @@ func (s *Shard) ValidateAggregateFieldsInStatement(measurementName string, stmt
 
 // DeleteSeries deletes a list of series.
 func (s *Shard) DeleteSeries(keys []string) error {
-	return s.engine.DeleteSeries(keys)
+	if err := s.engine.DeleteSeries(keys); err != nil {
+		return err
+	}
+	s.touch()
+	return nil
 }
 
 // DeleteMeasurement deletes a measurement and all underlying series.
@@ -329,10 +552,240 @@ func (s *Shard) DeleteMeasurement(name string, seriesKeys []string) error {
 
 	// Remove entry from shard index.
 	delete(s.measurementFields, name)
+	s.lastModified = time.Now().UTC()
 
 	return nil
 }
 
+// rewriteSeriesBatchN is the most points of a series read into memory at a
+// time when it's rewritten.
+var rewriteSeriesBatchN = 10000
+
+// rewriteSeries writes the points of each series of measurement name in
+// rewrites to the series it maps to. Returns the number of points written.
+// The series are written to without waiting for their locks, so the caller
+// must hold them.
+func (s *Shard) rewriteSeries(name string, rewrites map[string]seriesRewrite) (int, error) {
+	s.mu.RLock()
+	mf := s.measurementFields[name]
+	s.mu.RUnlock()
+	if mf == nil {
+		return 0, nil
+	}
+
+	var n int
+	for key, rw := range rewrites {
+		written, err := s.copySeries(key, rw.name, rw.tags, mf.Codec, nil)
+		n += written
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// copySeries writes the points of the series key, only the ones at the times
+// keep returns true for if it's set, as points of the measurement name with
+// tags. The points are read and written rewriteSeriesBatchN at a time.
+// Returns the number of points written.
+func (s *Shard) copySeries(key, name string, tags map[string]string, codec *FieldCodec, keep func(t int64) bool) (int, error) {
+	var n int
+	for seek := uint64(0); ; {
+		points, next, err := s.seriesPoints(key, name, tags, codec, keep, seek, rewriteSeriesBatchN)
+		if err != nil {
+			return n, err
+		}
+		if len(points) > 0 {
+			if err := s.writePoints(points); err != nil {
+				return n, err
+			}
+			n += len(points)
+		}
+		if next == 0 {
+			return n, nil
+		}
+		seek = next
+	}
+}
+
+// seriesPoints returns up to limit points of the series key from the time
+// seek, as points of the measurement name, only the ones at the times keep
+// returns true for if it's set. A zero limit returns all of them. Also returns
+// the time to read the next points from, or zero if there are none. The read
+// transaction is closed before the points are rewritten so the engine can
+// accept the writes.
+func (s *Shard) seriesPoints(key, name string, tags map[string]string, codec *FieldCodec, keep func(t int64) bool, seek uint64, limit int) ([]Point, uint64, error) {
+	tx, err := s.engine.Begin(false)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	c := tx.Cursor(key, Forward)
+	if c == nil {
+		return nil, 0, nil
+	}
+
+	var points []Point
+	for k, v := c.Seek(u64tob(seek)); k != nil; k, v = c.Next() {
+		if limit > 0 && len(points) == limit {
+			return points, btou64(k), nil
+		}
+		if keep != nil && !keep(int64(btou64(k))) {
+			continue
+		}
+		fields, err := codec.DecodeFieldsWithNames(v)
+		if err != nil {
+			return nil, 0, err
+		} else if len(fields) == 0 {
+			continue
+		}
+		points = append(points, NewPoint(name, Tags(tags), Fields(fields), time.Unix(0, int64(btou64(k))).UTC()))
+	}
+	return points, 0, nil
+}
+
+// deleteSeriesRange deletes the points of the series keys of measurement name
+// between min and max, inclusive. Each series with points in the range is
+// rewritten on its own with its writes blocked, so points written to it
+// meanwhile aren't lost. Returns the number of points deleted and the keys of
+// the series without points left in the shard.
+func (s *Shard) deleteSeriesRange(name string, keys []string, min, max int64) (int, []string, error) {
+	s.mu.RLock()
+	mf := s.measurementFields[name]
+	s.mu.RUnlock()
+	if mf == nil {
+		return 0, nil, nil
+	}
+
+	var n int
+	var emptied []string
+	for _, key := range keys {
+		deleted, empty, err := s.deleteRange(name, key, mf.Codec, min, max)
+		n += deleted
+		if err != nil {
+			return n, emptied, err
+		} else if empty {
+			emptied = append(emptied, key)
+		}
+	}
+	if n == 0 {
+		return 0, nil, nil
+	}
+
+	// The shard no longer has the series without points.
+	s.index.mu.Lock()
+	for _, key := range emptied {
+		if ss := s.index.series[key]; ss != nil {
+			delete(ss.shardIDs, s.id)
+		}
+	}
+	s.index.mu.Unlock()
+
+	s.touch()
+	return n, emptied, nil
+}
+
+// deleteRange deletes the points of the series key of measurement name
+// between min and max, inclusive, while holding the lock of the series. The
+// points outside of the range are staged in a series of their own, the series
+// is deleted and the staged points written back, rewriteSeriesBatchN at a
+// time. Returns the number of points deleted and true if the series has no
+// points left.
+func (s *Shard) deleteRange(name, key string, codec *FieldCodec, min, max int64) (int, bool, error) {
+	s.index.locks.lock([]string{key})
+	defer s.index.locks.unlock([]string{key})
+
+	n, err := s.seriesPointN(key, min, max)
+	if err != nil || n == 0 {
+		return 0, false, err
+	}
+
+	_, tags := ParseKey(key)
+	staging := stagingName(name)
+	stagingKey := string(MakeKey([]byte(staging), tags))
+	kept, err := s.copyEngineSeries(key, staging, tags, codec, func(t int64) bool { return t < min || t > max }, nil)
+	if err != nil {
+		s.engine.DeleteSeries([]string{stagingKey})
+		return 0, false, err
+	}
+
+	if err := s.engine.DeleteSeries([]string{key}); err != nil {
+		return 0, false, err
+	} else if kept == 0 {
+		return n, true, nil
+	}
+
+	series := []*SeriesCreate{{name, NewSeries(key, tags)}}
+	if _, err := s.copyEngineSeries(stagingKey, name, tags, codec, nil, series); err != nil {
+		return 0, false, err
+	}
+	if err := s.engine.DeleteSeries([]string{stagingKey}); err != nil {
+		return 0, false, err
+	}
+	return n, false, nil
+}
+
+// stagingName returns the name of the measurement the points of a series of
+// measurement name are staged in while it's rewritten. The NUL prefix keeps
+// it apart from the measurements written to.
+func stagingName(name string) string { return "\x00staging:" + name }
+
+// copyEngineSeries writes the points of the series key, only the ones at the
+// times keep returns true for if it's set, to the engine as points of the
+// measurement name with tags, creating series. The points are read and written
+// rewriteSeriesBatchN at a time and aren't added to the index. Returns the
+// number of points written.
+func (s *Shard) copyEngineSeries(key, name string, tags map[string]string, codec *FieldCodec, keep func(t int64) bool, series []*SeriesCreate) (int, error) {
+	var n int
+	for seek := uint64(0); ; {
+		points, next, err := s.seriesPoints(key, name, tags, codec, keep, seek, rewriteSeriesBatchN)
+		if err != nil {
+			return n, err
+		}
+		for _, p := range points {
+			data, err := codec.EncodeFields(p.Fields())
+			if err != nil {
+				return n, err
+			}
+			p.SetData(data)
+		}
+		if len(points) > 0 {
+			if err := s.engine.WritePoints(points, nil, series); err != nil {
+				return n, fmt.Errorf("engine: %s", err)
+			}
+			n += len(points)
+		}
+		if next == 0 {
+			return n, nil
+		}
+		seek = next
+	}
+}
+
+// seriesPointN returns the number of points of the series key between min and
+// max, inclusive.
+func (s *Shard) seriesPointN(key string, min, max int64) (int, error) {
+	tx, err := s.engine.Begin(false)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	c := tx.Cursor(key, Forward)
+	if c == nil {
+		return 0, nil
+	}
+
+	var n int
+	for k, _ := c.Seek(u64tob(0)); k != nil; k, _ = c.Next() {
+		if t := int64(btou64(k)); t >= min && t <= max {
+			n++
+		}
+	}
+	return n, nil
+}
+
 func (s *Shard) createFieldsAndMeasurements(fieldsToCreate []*FieldCreate) (map[string]*MeasurementFields, error) {
 	if len(fieldsToCreate) == 0 {
 		return nil, nil
@@ -427,6 +880,116 @@ func (s *Shard) validateSeriesAndFields(points []Point) ([]*SeriesCreate, []*Fie
 // SeriesCount returns the number of series buckets on the shard.
 func (s *Shard) SeriesCount() (int, error) { return s.engine.SeriesCount() }
 
+// seriesN returns the number of series on the shard, or zero if the shard
+// isn't open. Like SeriesCount, this doesn't include series only in the WAL.
+func (s *Shard) seriesN() int64 {
+	s.mu.RLock()
+	e := s.engine
+	s.mu.RUnlock()
+	if e == nil {
+		return 0
+	}
+
+	n, err := e.SeriesCount()
+	if err != nil {
+		return 0
+	}
+	return int64(n)
+}
+
+// walStats returns the state of the engine's write ahead log, if it has one.
+func (s *Shard) walStats() WALStats {
+	s.mu.RLock()
+	e := s.engine
+	s.mu.RUnlock()
+	if r, ok := e.(WALReporter); ok {
+		return r.WALStats()
+	}
+	return WALStats{}
+}
+
+// cacheSize returns the size of the points cached in memory by the engine.
+func (s *Shard) cacheSize() int64 {
+	s.mu.RLock()
+	e := s.engine
+	s.mu.RUnlock()
+	if c, ok := e.(CacheSizer); ok {
+		return c.CacheSize()
+	}
+	return 0
+}
+
+// InspectBlocks calls fn for each block of point data stored in the shard.
+// Returns ErrInspectNotSupported if the engine doesn't support it.
+func (s *Shard) InspectBlocks(fn func(BlockInfo) error) error {
+	bi, ok := s.engine.(BlockInspector)
+	if !ok {
+		return ErrInspectNotSupported
+	}
+	return bi.InspectBlocks(fn)
+}
+
+// CompactFull fully compacts the shard's data file. Returns
+// ErrCompactNotSupported if the engine doesn't support it.
+func (s *Shard) CompactFull() error {
+	s.mu.RLock()
+	e := s.engine
+	s.mu.RUnlock()
+	fc, ok := e.(FullCompactor)
+	if !ok {
+		return ErrCompactNotSupported
+	}
+	return fc.CompactFull()
+}
+
+// Verify checks that the blocks of the shard decode and that every series with
+// blocks is in the index. Engines which can't inspect their blocks are not
+// checked.
+func (s *Shard) Verify() error {
+	err := s.InspectBlocks(func(b BlockInfo) error {
+		if b.Err != nil {
+			return fmt.Errorf("series %q, block %d-%d: %s", b.Key, b.MinTime, b.MaxTime, b.Err)
+		} else if s.index.Series(b.Key) == nil {
+			return fmt.Errorf("series %q has blocks but is not indexed", b.Key)
+		}
+		return nil
+	})
+	if err == ErrInspectNotSupported {
+		return nil
+	}
+	return err
+}
+
+// PointCounts returns the number of points stored for each series key between
+// min and max, inclusive. Engines that can't count from block metadata fall
+// back to iterating over the series with a cursor.
+func (s *Shard) PointCounts(keys []string, min, max int64) (map[string]int64, error) {
+	if pc, ok := s.engine.(PointCounter); ok {
+		return pc.PointCounts(keys, min, max)
+	}
+
+	tx, err := s.engine.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	counts := make(map[string]int64, len(keys))
+	for _, key := range keys {
+		c := tx.Cursor(key, Forward)
+		if c == nil {
+			continue
+		}
+		for k, _ := c.Seek(u64tob(uint64(min))); k != nil; k, _ = c.Next() {
+			if int64(btou64(k)) > max {
+				break
+			}
+			counts[key]++
+		}
+	}
+	return counts, nil
+}
+
 // WriteTo writes the shard's data to w.
 func (s *Shard) WriteTo(w io.Writer) (int64, error) {
 	n, err := s.engine.WriteTo(w)
@@ -437,6 +1000,8 @@ func (s *Shard) WriteTo(w io.Writer) (int64, error) {
 type MeasurementFields struct {
 	Fields map[string]*Field `json:"fields"`
 	Codec  *FieldCodec
+
+	lastWritten map[string]time.Time // field name to the time it was last written
 }
 
 // MarshalBinary encodes the object to a binary format.
@@ -500,6 +1065,25 @@ type Field struct {
 	Type influxql.DataType `json:"type,omitempty"`
 }
 
+// FieldInfo describes a field of a measurement.
+type FieldInfo struct {
+	Name        string
+	Type        influxql.DataType
+	LastWritten time.Time // zero if unknown
+}
+
+// fieldInfos sorts fields by name, then type.
+type fieldInfos []FieldInfo
+
+func (a fieldInfos) Len() int      { return len(a) }
+func (a fieldInfos) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a fieldInfos) Less(i, j int) bool {
+	if a[i].Name != a[j].Name {
+		return a[i].Name < a[j].Name
+	}
+	return a[i].Type < a[j].Type
+}
+
 // FieldCodec provides encoding and decoding functionality for the fields of a given
 // Measurement. It is a distinct type to avoid locking writes on this node while
 // potentially long-running queries are executing.
@@ -562,6 +1146,9 @@ func (f *FieldCodec) EncodeFields(values map[string]interface{}) ([]byte, error)
 			}
 			buf = make([]byte, 9)
 			binary.BigEndian.PutUint64(buf[1:9], value)
+		case influxql.Unsigned:
+			buf = make([]byte, 9)
+			binary.BigEndian.PutUint64(buf[1:9], v.(uint64))
 		case influxql.Boolean:
 			value := v.(bool)
 
@@ -639,6 +1226,10 @@ func (f *FieldCodec) DecodeFields(b []byte) (map[uint8]interface{}, error) {
 			value = int64(binary.BigEndian.Uint64(b[1:9]))
 			// Move bytes forward.
 			b = b[9:]
+		case influxql.Unsigned:
+			value = binary.BigEndian.Uint64(b[1:9])
+			// Move bytes forward.
+			b = b[9:]
 		case influxql.Boolean:
 			if b[1] == 1 {
 				value = true
@@ -712,6 +1303,9 @@ func (f *FieldCodec) DecodeByID(targetID uint8, b []byte) (interface{}, error) {
 		case influxql.Integer:
 			value = int64(binary.BigEndian.Uint64(b[1:9]))
 			b = b[9:]
+		case influxql.Unsigned:
+			value = binary.BigEndian.Uint64(b[1:9])
+			b = b[9:]
 		case influxql.Boolean:
 			if b[1] == 1 {
 				value = true
@@ -737,6 +1331,34 @@ func (f *FieldCodec) DecodeByID(targetID uint8, b []byte) (interface{}, error) {
 	return 0, ErrFieldNotFound
 }
 
+// lookupByID scans a byte slice for a field with the given ID and returns the
+// field and the slice starting at its encoding. Unlike DecodeByID, the fields
+// before it are skipped without being decoded.
+func (f *FieldCodec) lookupByID(targetID uint8, b []byte) (*Field, []byte, error) {
+	for len(b) > 0 {
+		field, ok := f.fieldsByID[b[0]]
+		if !ok {
+			// See note in DecodeByID() regarding field-mapping failures.
+			return nil, nil, ErrFieldUnmappedID
+		} else if field.ID == targetID {
+			return field, b, nil
+		}
+
+		// Move bytes forward.
+		switch field.Type {
+		case influxql.Float, influxql.Integer, influxql.Unsigned:
+			b = b[9:]
+		case influxql.Boolean:
+			b = b[2:]
+		case influxql.String:
+			b = b[int(binary.BigEndian.Uint16(b[1:3]))+3:]
+		default:
+			panic(fmt.Sprintf("unsupported value type during lookup by id: %T", field.Type))
+		}
+	}
+	return nil, nil, ErrFieldNotFound
+}
+
 // DecodeByName scans a byte slice for a field with the given name, converts it to its
 // expected type, and return that value.
 func (f *FieldCodec) DecodeByName(name string, b []byte) (interface{}, error) {
@@ -1,6 +1,7 @@
 package tsdb_test
 
 import (
+	"expvar"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -10,6 +11,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/influxdb/influxdb/influxql"
 	"github.com/influxdb/influxdb/tsdb"
 	"github.com/influxdb/influxdb/tsdb/engine/b1"
 )
@@ -139,6 +141,77 @@ func TestShardWriteAddNewField(t *testing.T) {
 
 }
 
+// Ensure the field codec encodes and decodes unsigned values.
+func TestFieldCodec_Unsigned(t *testing.T) {
+	codec := tsdb.NewFieldCodec(map[string]*tsdb.Field{
+		"value": {ID: 1, Name: "value", Type: influxql.Unsigned},
+	})
+
+	b, err := codec.EncodeFields(map[string]interface{}{"value": uint64(18446744073709551615)})
+	if err != nil {
+		t.Fatal(err)
+	} else if v, err := codec.DecodeByName("value", b); err != nil || v != uint64(18446744073709551615) {
+		t.Fatalf("unexpected value: %v (%v)", v, err)
+	}
+
+	if _, err := codec.EncodeFields(map[string]interface{}{"value": int64(1)}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+// Ensure the shard reports its size on disk, series count, cache size and
+// last modification time as statistics.
+func TestShard_Statistics(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(tmpDir)
+	tmpShard := path.Join(tmpDir, "shard")
+	tmpWal := path.Join(tmpDir, "wal")
+
+	opts := tsdb.NewEngineOptions()
+	opts.Config.WALDir = filepath.Join(tmpDir, "wal")
+
+	sh := tsdb.NewShard(2, tsdb.NewDatabaseIndex(), tmpShard, tmpWal, opts)
+	if err := sh.Open(); err != nil {
+		t.Fatalf("error opening shard: %s", err.Error())
+	}
+	defer sh.Close()
+
+	if err := sh.WritePoints([]tsdb.Point{
+		tsdb.NewPoint("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.0}, time.Unix(1, 0)),
+		tsdb.NewPoint("cpu", map[string]string{"host": "b"}, map[string]interface{}{"value": 1.0}, time.Unix(1, 0)),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := sh.SeriesCount()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := shardStatistics(fmt.Sprintf("shard:%s:%d", tmpShard, 2))
+	if m == nil {
+		t.Fatal("shard statistics not registered")
+	} else if v := m.Get("series_n").String(); v != fmt.Sprint(n) {
+		t.Fatalf("unexpected series count: %s", v)
+	} else if v := m.Get("cache_bytes").String(); v == "0" {
+		t.Fatalf("unexpected cache size: %s", v)
+	} else if v := m.Get("disk_bytes").String(); v == "0" {
+		t.Fatalf("unexpected disk size: %s", v)
+	} else if v := m.Get("last_modified").String(); v == "0" {
+		t.Fatalf("unexpected last modified: %s", v)
+	}
+}
+
+// shardStatistics returns the values of the registered statistics for key.
+func shardStatistics(key string) *expvar.Map {
+	m, ok := expvar.Get(key).(*expvar.Map)
+	if !ok {
+		return nil
+	}
+	values, _ := m.Get("values").(*expvar.Map)
+	return values
+}
+
 // Ensure the shard will automatically flush the WAL after a threshold has been reached.
 func TestShard_Autoflush(t *testing.T) {
 	path, _ := ioutil.TempDir("", "shard_test")
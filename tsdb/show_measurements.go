@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/influxdb/influxdb/influxql"
 )
@@ -145,9 +146,10 @@ func (m *ShowMeasurementsMapper) Open() error {
 
 	if m.shard != nil {
 		// If a WHERE clause was specified, filter the measurements.
-		if m.stmt.Condition != nil {
+		// Time conditions are applied to the shards, not the index.
+		if cond := removeTimeCondition(m.stmt.Condition); cond != nil {
 			var err error
-			measurements, err = m.shard.index.measurementsByExpr(m.stmt.Condition)
+			measurements, err = m.shard.index.measurementsByExpr(cond)
 			if err != nil {
 				return err
 			}
@@ -155,6 +157,18 @@ func (m *ShowMeasurementsMapper) Open() error {
 			// Otherwise, get all measurements from the database.
 			measurements = m.shard.index.Measurements()
 		}
+
+		// The index is shared by all shards of a database so, if the statement
+		// is bounded by time, only list measurements with points in this shard.
+		if tmin, tmax := influxql.TimeRange(m.stmt.Condition); !tmin.IsZero() || !tmax.IsZero() {
+			a := measurements[:0]
+			for _, mm := range measurements {
+				if m.shard.HasMeasurement(mm.Name) {
+					a = append(a, mm)
+				}
+			}
+			measurements = a
+		}
 		sort.Sort(measurements)
 	}
 
@@ -235,3 +249,33 @@ func (m *ShowMeasurementsMapper) Close() {
 		m.remote.Close()
 	}
 }
+
+// removeTimeCondition returns expr without its comparisons against time.
+// Returns nil if expr only compares against time.
+func removeTimeCondition(expr influxql.Expr) influxql.Expr {
+	switch expr := expr.(type) {
+	case *influxql.BinaryExpr:
+		switch expr.Op {
+		case influxql.AND, influxql.OR:
+			lhs, rhs := removeTimeCondition(expr.LHS), removeTimeCondition(expr.RHS)
+			if lhs == nil {
+				return rhs
+			} else if rhs == nil {
+				return lhs
+			}
+			return &influxql.BinaryExpr{Op: expr.Op, LHS: lhs, RHS: rhs}
+		}
+		if ref, ok := expr.LHS.(*influxql.VarRef); ok && strings.ToLower(ref.Val) == "time" {
+			return nil
+		} else if ref, ok := expr.RHS.(*influxql.VarRef); ok && strings.ToLower(ref.Val) == "time" {
+			return nil
+		}
+		return expr
+	case *influxql.ParenExpr:
+		if e := removeTimeCondition(expr.Expr); e != nil {
+			return &influxql.ParenExpr{Expr: e}
+		}
+		return nil
+	}
+	return expr
+}
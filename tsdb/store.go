@@ -1,16 +1,24 @@
 package tsdb
 
 import (
+	"encoding/binary"
+	"expvar"
 	"fmt"
+	"io"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
+	"time"
 
+	"github.com/boltdb/bolt"
+	"github.com/influxdb/influxdb"
 	"github.com/influxdb/influxdb/influxql"
+	"github.com/influxdb/influxdb/logger"
 )
 
 func NewStore(path string) *Store {
@@ -20,14 +28,47 @@ func NewStore(path string) *Store {
 	return &Store{
 		path:          path,
 		EngineOptions: opts,
-		Logger:        log.New(os.Stderr, "[store] ", log.LstdFlags),
+		Logger:        logger.New(os.Stderr, "store"),
+		statMap:       influxdb.NewStatistics("store", "store", nil),
 	}
 }
 
 var (
 	ErrShardNotFound = fmt.Errorf("shard not found")
+
+	// ErrShardOpening is returned when changing a shard which is still being
+	// opened, with its WAL replaying.
+	ErrShardOpening = fmt.Errorf("shard opening")
+
+	// ErrWriteThrottled is returned when a write would wait for the write
+	// throttles longer than the write timeout.
+	ErrWriteThrottled = fmt.Errorf("write throttled past the write timeout")
+
+	// ErrEmptyTagValue is returned when a tag value is rewritten to an empty
+	// value, which series can't have.
+	ErrEmptyTagValue = fmt.Errorf("tag value must not be empty")
 )
 
+const (
+	statShardsQuarantined = "shards_quarantined"
+	statWritesThrottled   = "writes_throttled"
+	statWriteThrottleWait = "write_throttle_wait" // nanoseconds
+	statWritesRejected    = "writes_throttled_rejected"
+	statShardsCompacted   = "shards_compacted_full"
+)
+
+// compactColdCheckInterval is how often the store looks for shards to fully
+// compact.
+const compactColdCheckInterval = time.Minute
+
+// replayLogInterval is how often the progress of opening the shards is
+// logged.
+const replayLogInterval = 10 * time.Second
+
+// quarantineSuffix is added to the paths of shards which are moved aside
+// because they couldn't be opened, followed by the time they were moved.
+const quarantineSuffix = ".quarantined"
+
 type Store struct {
 	mu   sync.RWMutex
 	path string
@@ -36,8 +77,26 @@ type Store struct {
 	shards          map[uint64]*Shard
 
 	EngineOptions EngineOptions
-	Logger        *log.Logger
+	Logger        *logger.Logger
 	closing       chan struct{}
+	wg            sync.WaitGroup
+
+	// Last modified times of the shards when they were fully compacted.
+	// Only used by the compaction goroutine.
+	compacted map[uint64]time.Time
+
+	// Delays writes to keep them within the write throttles. Writes which
+	// would wait longer than WriteTimeout, if set, are rejected instead.
+	throttler    writeThrottler
+	WriteTimeout time.Duration
+
+	// The progress of opening the shards on disk, and the databases of the
+	// shards still being opened.
+	replay  *ReplayProgress
+	opening map[uint64]string
+
+	// expvar-based stats.
+	statMap *expvar.Map
 }
 
 // Path returns the store's root path.
@@ -64,6 +123,15 @@ func (s *Store) ShardN() int {
 	return len(s.shards)
 }
 
+// QuarantinedShardN returns the number of shards moved aside because they
+// couldn't be opened.
+func (s *Store) QuarantinedShardN() int64 {
+	if v, ok := s.statMap.Get(statShardsQuarantined).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
 func (s *Store) CreateShard(database, retentionPolicy string, shardID uint64) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -77,6 +145,8 @@ func (s *Store) CreateShard(database, retentionPolicy string, shardID uint64) er
 	// shard already exists
 	if _, ok := s.shards[shardID]; ok {
 		return nil
+	} else if s.shardOpening(shardID) {
+		return ErrShardOpening
 	}
 
 	// created the db and retention policy dirs if they don't exist
@@ -93,8 +163,7 @@ func (s *Store) CreateShard(database, retentionPolicy string, shardID uint64) er
 	// create the database index if it does not exist
 	db, ok := s.databaseIndexes[database]
 	if !ok {
-		db = NewDatabaseIndex()
-		s.databaseIndexes[database] = db
+		db = s.newDatabaseIndex(database)
 	}
 
 	shardPath := filepath.Join(s.path, database, retentionPolicy, strconv.FormatUint(shardID, 10))
@@ -108,6 +177,78 @@ func (s *Store) CreateShard(database, retentionPolicy string, shardID uint64) er
 	return nil
 }
 
+// RestoreShard creates a shard from data in the format written by Shard.WriteTo.
+// The shard must not already exist in the store.
+func (s *Store) RestoreShard(database, retentionPolicy string, shardID uint64, r io.Reader) error {
+	if sh := s.Shard(shardID); sh != nil {
+		return fmt.Errorf("shard already exists: id=%d", shardID)
+	}
+
+	// Write the data to a temporary file so a partial copy is never opened.
+	if err := os.MkdirAll(filepath.Join(s.path, database, retentionPolicy), 0700); err != nil {
+		return err
+	}
+	shardPath := filepath.Join(s.path, database, retentionPolicy, strconv.FormatUint(shardID, 10))
+	tmpPath := shardPath + ".restore"
+	if err := restoreShardFile(tmpPath, r); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// A shard may have been created while the data was being copied.
+	if _, ok := s.shards[shardID]; ok || s.shardOpening(shardID) {
+		os.Remove(tmpPath)
+		return fmt.Errorf("shard already exists: id=%d", shardID)
+	}
+	if err := os.Rename(tmpPath, shardPath); err != nil {
+		return err
+	}
+
+	walPath := filepath.Join(s.EngineOptions.Config.WALDir, database, retentionPolicy, fmt.Sprintf("%d", shardID))
+	if err := os.MkdirAll(walPath, 0700); err != nil {
+		return err
+	}
+
+	db, ok := s.databaseIndexes[database]
+	if !ok {
+		db = s.newDatabaseIndex(database)
+	}
+
+	shard := NewShard(shardID, db, shardPath, walPath, s.EngineOptions)
+	if err := shard.Open(); err != nil {
+		os.Remove(shardPath)
+		return fmt.Errorf("open restored shard: %s", err)
+	}
+	s.shards[shardID] = shard
+
+	return nil
+}
+
+// restoreShardFile writes the length-prefixed shard data from r to path.
+func restoreShardFile(path string, r io.Reader) error {
+	var n uint64
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return fmt.Errorf("read shard size: %s", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := io.CopyN(f, r, int64(n)); err != nil {
+		return fmt.Errorf("copy shard data: %s", err)
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
 // DeleteShard removes a shard from disk.
 func (s *Store) DeleteShard(shardID uint64) error {
 	s.mu.Lock()
@@ -116,6 +257,9 @@ func (s *Store) DeleteShard(shardID uint64) error {
 	// ensure shard exists
 	sh, ok := s.shards[shardID]
 	if !ok {
+		if s.shardOpening(shardID) {
+			return ErrShardOpening
+		}
 		return nil
 	}
 
@@ -132,6 +276,7 @@ func (s *Store) DeleteShard(shardID uint64) error {
 	}
 
 	delete(s.shards, shardID)
+	s.throttler.remove(shardID)
 
 	return nil
 }
@@ -140,6 +285,9 @@ func (s *Store) DeleteShard(shardID uint64) error {
 func (s *Store) DeleteDatabase(name string, shardIDs []uint64) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if s.databaseOpening(name) {
+		return ErrShardOpening
+	}
 	for _, id := range shardIDs {
 		shard := s.shards[id]
 		if shard != nil {
@@ -152,7 +300,371 @@ func (s *Store) DeleteDatabase(name string, shardIDs []uint64) error {
 	if err := os.RemoveAll(filepath.Join(s.EngineOptions.Config.WALDir, name)); err != nil {
 		return err
 	}
+	if index := s.databaseIndexes[name]; index != nil {
+		index.unregisterStatistics()
+	}
+	delete(s.databaseIndexes, name)
+	return nil
+}
+
+// newDatabaseIndex adds an index reporting its statistics for database.
+func (s *Store) newDatabaseIndex(database string) *DatabaseIndex {
+	index := NewDatabaseIndex()
+	index.registerStatistics(database)
+	s.databaseIndexes[database] = index
+	return index
+}
+
+// RenameDatabase closes the shards of a database, renames its directories
+// and reopens the shards under the new name.
+func (s *Store) RenameDatabase(name, newName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := s.databaseIndexes[name]
+	if index == nil {
+		return nil
+	} else if s.databaseOpening(name) {
+		return ErrShardOpening
+	} else if s.databaseIndexes[newName] != nil {
+		return fmt.Errorf("database already exists: %s", newName)
+	}
+
+	// Close the shards of the database before moving their files.
+	var shards []*Shard
+	for _, sh := range s.shards {
+		if sh.index != index {
+			continue
+		}
+		if err := sh.Close(); err != nil {
+			return err
+		}
+		shards = append(shards, sh)
+	}
+
+	dataDir, walDir := filepath.Join(s.path, name), filepath.Join(s.EngineOptions.Config.WALDir, name)
+	if err := os.Rename(dataDir, filepath.Join(s.path, newName)); err != nil {
+		return err
+	}
+	if err := os.Rename(walDir, filepath.Join(s.EngineOptions.Config.WALDir, newName)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	// Reopen the shards with a new index so it is loaded from the moved files.
+	index.unregisterStatistics()
 	delete(s.databaseIndexes, name)
+	index = s.newDatabaseIndex(newName)
+	for _, sh := range shards {
+		rel, err := filepath.Rel(dataDir, sh.path)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(s.path, newName, rel)
+		walPath := filepath.Join(s.EngineOptions.Config.WALDir, newName, rel)
+
+		shard := NewShard(sh.id, index, path, walPath, s.EngineOptions)
+		if err := shard.Open(); err != nil {
+			return fmt.Errorf("failed to open shard %d: %s", sh.id, err)
+		}
+		s.shards[sh.id] = shard
+	}
+
+	return nil
+}
+
+// RewriteStats reports the series and points rewritten by a maintenance
+// operation.
+type RewriteStats struct {
+	SeriesN int
+	PointN  int
+}
+
+// seriesRewrite is the measurement and tags a series is rewritten to.
+type seriesRewrite struct {
+	name string
+	tags map[string]string
+}
+
+// RenameMeasurement rewrites the series of a measurement in the local shards
+// of a database under newName and removes the original measurement. The
+// series are rewritten in batches, so an interrupted rename leaves at most the
+// batch in progress under both names.
+func (s *Store) RenameMeasurement(database, name, newName string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	index, m, err := s.measurementIndex(database, name)
+	if err != nil {
+		return err
+	} else if index.Measurement(newName) != nil {
+		return ErrMeasurementExists(newName)
+	}
+
+	rewrites := seriesRewrites(index, m, func(tags map[string]string) (string, map[string]string, bool) {
+		return newName, tags, true
+	})
+	if _, err := s.rewriteAndDropSeries(database, index, m.Name, rewrites); err != nil {
+		return err
+	}
+
+	// Series written to the measurement while it was renamed are kept.
+	if m.HasSeries() {
+		return nil
+	}
+	index.DropMeasurement(m.Name)
+	for _, sh := range s.shards {
+		if sh.index != index {
+			continue
+		}
+		if err := sh.DeleteMeasurement(m.Name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteMeasurementRange deletes the points of a measurement between min and
+// max, inclusive, from the local shards of a database in shardIDs. Series left
+// without points in any shard are removed, and so is the measurement if it has
+// no series left.
+func (s *Store) DeleteMeasurementRange(database, name string, shardIDs []uint64, min, max int64) (RewriteStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var stats RewriteStats
+	index, m, err := s.measurementIndex(database, name)
+	if err != nil {
+		return stats, err
+	}
+	keys := m.SeriesKeys()
+
+	emptied := make(map[string]struct{})
+	for _, id := range shardIDs {
+		sh := s.shards[id]
+		if sh == nil || sh.index != index {
+			continue
+		}
+
+		n, a, err := sh.deleteSeriesRange(m.Name, keys, min, max)
+		if err != nil {
+			return stats, fmt.Errorf("shard %d: %s", sh.id, err)
+		}
+		stats.PointN += n
+		for _, key := range a {
+			emptied[key] = struct{}{}
+		}
+		s.Logger.Info("deleted points", "database", database, "measurement", m.Name, "shard", sh.id, "points", n)
+	}
+
+	// Remove the series no shard has points of, blocking writes to them so
+	// one written to meanwhile isn't removed.
+	candidates := make([]string, 0, len(emptied))
+	for key := range emptied {
+		candidates = append(candidates, key)
+	}
+	index.locks.lock(candidates)
+	var drop []string
+	index.mu.RLock()
+	for _, key := range candidates {
+		if ss := index.series[key]; ss != nil && len(ss.shardIDs) == 0 {
+			drop = append(drop, key)
+		}
+	}
+	index.mu.RUnlock()
+	index.DropSeries(drop)
+	index.locks.unlock(candidates)
+	stats.SeriesN = len(drop)
+
+	if !m.HasSeries() {
+		index.DropMeasurement(m.Name)
+		for _, sh := range s.shards {
+			if sh.index != index {
+				continue
+			}
+			if err := sh.DeleteMeasurement(m.Name, nil); err != nil {
+				return stats, err
+			}
+		}
+	}
+	return stats, nil
+}
+
+// RenameTagKey rewrites the series of a measurement in the local shards of a
+// database which have the tag key with newKey instead.
+func (s *Store) RenameTagKey(database, name, key, newKey string) (RewriteStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	index, m, err := s.measurementIndex(database, name)
+	if err != nil {
+		return RewriteStats{}, err
+	} else if m.HasTagKey(newKey) {
+		return RewriteStats{}, fmt.Errorf("tag key already exists: %s", newKey)
+	}
+
+	rewrites := seriesRewrites(index, m, func(tags map[string]string) (string, map[string]string, bool) {
+		v, ok := tags[key]
+		if !ok {
+			return "", nil, false
+		}
+		other := make(map[string]string, len(tags))
+		for k, v := range tags {
+			other[k] = v
+		}
+		delete(other, key)
+		other[newKey] = v
+		return name, other, true
+	})
+	return s.rewriteAndDropSeries(database, index, name, rewrites)
+}
+
+// RenameTagValue rewrites the series of a measurement in the local shards of
+// a database which have value for the tag key with newValue instead. Series
+// which then have the same tags as an existing series are merged into it.
+func (s *Store) RenameTagValue(database, name, key, value, newValue string) (RewriteStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	index, m, err := s.measurementIndex(database, name)
+	if err != nil {
+		return RewriteStats{}, err
+	} else if newValue == "" {
+		return RewriteStats{}, ErrEmptyTagValue
+	} else if value == newValue {
+		return RewriteStats{}, nil
+	}
+
+	rewrites := seriesRewrites(index, m, func(tags map[string]string) (string, map[string]string, bool) {
+		if v, ok := tags[key]; !ok || v != value {
+			return "", nil, false
+		}
+		other := make(map[string]string, len(tags))
+		for k, v := range tags {
+			other[k] = v
+		}
+		other[key] = newValue
+		return name, other, true
+	})
+	return s.rewriteAndDropSeries(database, index, name, rewrites)
+}
+
+// measurementIndex returns the index of a database and its measurement.
+func (s *Store) measurementIndex(database, name string) (*DatabaseIndex, *Measurement, error) {
+	index := s.databaseIndexes[database]
+	if index == nil {
+		return nil, nil, ErrMeasurementNotFound(name)
+	}
+	m := index.Measurement(name)
+	if m == nil {
+		return nil, nil, ErrMeasurementNotFound(name)
+	}
+	return index, m, nil
+}
+
+// seriesRewrites returns the measurement and tags fn returns for the tags of
+// each series of m, excluding the series fn returns false for.
+func seriesRewrites(index *DatabaseIndex, m *Measurement, fn func(tags map[string]string) (string, map[string]string, bool)) map[string]seriesRewrite {
+	rewrites := make(map[string]seriesRewrite)
+	for _, key := range m.SeriesKeys() {
+		series := index.Series(key)
+		if series == nil {
+			continue
+		}
+		if name, tags, ok := fn(series.Tags); ok {
+			rewrites[key] = seriesRewrite{name: name, tags: tags}
+		}
+	}
+	return rewrites
+}
+
+// rewriteSeriesBatchSize is the most series rewritten by a batch of a
+// maintenance operation. The writes to the series of a batch are blocked until
+// it's done.
+var rewriteSeriesBatchSize = 1000
+
+// rewriteAndDropSeries writes the points of the series of measurement name in
+// rewrites to the series they map to and then removes the original series, in
+// batches of rewriteSeriesBatchSize series. The store lock must be held by the
+// caller.
+func (s *Store) rewriteAndDropSeries(database string, index *DatabaseIndex, name string, rewrites map[string]seriesRewrite) (RewriteStats, error) {
+	stats := RewriteStats{SeriesN: len(rewrites)}
+	if len(rewrites) == 0 {
+		return stats, nil
+	}
+
+	var shards []*Shard
+	for _, sh := range s.shards {
+		if sh.index == index {
+			shards = append(shards, sh)
+		}
+	}
+
+	keys := make([]string, 0, len(rewrites))
+	for key := range rewrites {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for i := 0; i < len(keys); i += rewriteSeriesBatchSize {
+		j := i + rewriteSeriesBatchSize
+		if j > len(keys) {
+			j = len(keys)
+		}
+		batch := make(map[string]seriesRewrite, j-i)
+		for _, key := range keys[i:j] {
+			batch[key] = rewrites[key]
+		}
+
+		n, err := s.rewriteSeriesBatch(index, shards, name, batch)
+		stats.PointN += n
+		if err != nil {
+			return stats, err
+		}
+		s.Logger.Info("rewrote series", "database", database, "measurement", name,
+			"series_done", j, "series_total", len(keys), "points", stats.PointN)
+	}
+	return stats, nil
+}
+
+// rewriteSeriesBatch rewrites the series in batch in shards and then removes
+// the original series. Writes to the original and rewritten series wait until
+// it's done. Returns the number of points written.
+func (s *Store) rewriteSeriesBatch(index *DatabaseIndex, shards []*Shard, name string, batch map[string]seriesRewrite) (int, error) {
+	locked := make([]string, 0, 2*len(batch))
+	for key, rw := range batch {
+		locked = append(locked, key, string(MakeKey([]byte(rw.name), rw.tags)))
+	}
+	index.locks.lock(locked)
+	defer index.locks.unlock(locked)
+
+	var n int
+	for _, sh := range shards {
+		written, err := sh.rewriteSeries(name, batch)
+		n += written
+		if err != nil {
+			return n, fmt.Errorf("shard %d: %s", sh.id, err)
+		}
+	}
+	return n, s.dropRewrittenSeries(index, batch)
+}
+
+// dropRewrittenSeries removes the series in rewrites from the index and the
+// local shards of a database. The store lock must be held by the caller.
+func (s *Store) dropRewrittenSeries(index *DatabaseIndex, rewrites map[string]seriesRewrite) error {
+	keys := make([]string, 0, len(rewrites))
+	for key := range rewrites {
+		keys = append(keys, key)
+	}
+
+	index.DropSeries(keys)
+	for _, sh := range s.shards {
+		if sh.index != index {
+			continue
+		}
+		if err := sh.DeleteSeries(keys); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -218,6 +730,67 @@ func (s *Store) DiskSize() (int64, error) {
 	return size, nil
 }
 
+// PointCounts returns the number of points stored in the local shards of a
+// database for each series key between min and max, inclusive.
+func (s *Store) PointCounts(database string, keys []string, min, max int64) (map[string]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	index := s.databaseIndexes[database]
+	counts := make(map[string]int64, len(keys))
+	for _, sh := range s.shards {
+		if sh.index != index {
+			continue
+		}
+		a, err := sh.PointCounts(keys, min, max)
+		if err != nil {
+			return nil, fmt.Errorf("shard %d: %s", sh.id, err)
+		}
+		for k, n := range a {
+			counts[k] += n
+		}
+	}
+	return counts, nil
+}
+
+// MeasurementFieldInfo returns the fields of a measurement in the local shards
+// of a database, sorted by name. A field with different types across shards is
+// returned once per type. Its last written time is the latest of its shards.
+func (s *Store) MeasurementFieldInfo(database, name string) ([]FieldInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	index := s.databaseIndexes[database]
+	if index == nil {
+		return nil, ErrDatabaseNotFound(database)
+	}
+
+	type fieldKey struct {
+		name string
+		typ  influxql.DataType
+	}
+	fields := make(map[fieldKey]FieldInfo)
+	for _, sh := range s.shards {
+		if sh.index != index {
+			continue
+		}
+		for _, f := range sh.MeasurementFieldInfo(name) {
+			k := fieldKey{f.Name, f.Type}
+			if other, ok := fields[k]; ok && !f.LastWritten.After(other.LastWritten) {
+				continue
+			}
+			fields[k] = f
+		}
+	}
+
+	a := make([]FieldInfo, 0, len(fields))
+	for _, f := range fields {
+		a = append(a, f)
+	}
+	sort.Sort(fieldInfos(a))
+	return a, nil
+}
+
 // deleteSeries loops through the local shards and deletes the series data and metadata for the passed in series keys
 func (s *Store) deleteSeries(keys []string) error {
 	s.mu.RLock()
@@ -249,96 +822,480 @@ func (s *Store) loadIndexes() error {
 	}
 	for _, db := range dbs {
 		if !db.IsDir() {
-			s.Logger.Printf("Skipping database dir: %s. Not a directory", db.Name())
+			s.Logger.Warn("Skipping database dir: not a directory", "database", db.Name())
 			continue
 		}
-		s.databaseIndexes[db.Name()] = NewDatabaseIndex()
+		s.newDatabaseIndex(db.Name())
 	}
 	return nil
 }
 
-func (s *Store) loadShards() error {
+// pendingShard is a shard found on disk which isn't opened yet.
+type pendingShard struct {
+	id       uint64
+	database string
+	path     string
+	walPath  string
+	walBytes int64 // size of the WAL files of the shard
+}
+
+// listShards returns the shards on disk of the loaded database indexes.
+func (s *Store) listShards() ([]pendingShard, error) {
+	var pending []pendingShard
+
 	// loop through the current database indexes
 	for db := range s.databaseIndexes {
 		rps, err := ioutil.ReadDir(filepath.Join(s.path, db))
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		for _, rp := range rps {
 			// retention policies should be directories.  Skip anything that is not a dir.
 			if !rp.IsDir() {
-				s.Logger.Printf("Skipping retention policy dir: %s. Not a directory", rp.Name())
+				s.Logger.Warn("Skipping retention policy dir: not a directory", "database", db, "retention_policy", rp.Name())
 				continue
 			}
 
 			shards, err := ioutil.ReadDir(filepath.Join(s.path, db, rp.Name()))
 			if err != nil {
-				return err
+				return nil, err
 			}
 			for _, sh := range shards {
 				path := filepath.Join(s.path, db, rp.Name(), sh.Name())
 				walPath := filepath.Join(s.EngineOptions.Config.WALDir, db, rp.Name(), sh.Name())
 
 				// Shard file names are numeric shardIDs
+				if strings.Contains(sh.Name(), quarantineSuffix) {
+					s.Logger.Debug("Skipping quarantined shard", "database", db, "retention_policy", rp.Name(), "path", sh.Name())
+					continue
+				}
 				shardID, err := strconv.ParseUint(sh.Name(), 10, 64)
 				if err != nil {
-					s.Logger.Printf("Skipping shard: %s. Not a valid path", rp.Name())
+					s.Logger.Warn("Skipping shard: not a valid path", "database", db, "retention_policy", rp.Name(), "path", sh.Name())
 					continue
 				}
 
-				shard := NewShard(shardID, s.databaseIndexes[db], path, walPath, s.EngineOptions)
-				err = shard.Open()
+				walBytes, err := dirSize(walPath)
 				if err != nil {
-					return fmt.Errorf("failed to open shard %d: %s", shardID, err)
+					return nil, err
+				}
+				pending = append(pending, pendingShard{id: shardID, database: db, path: path, walPath: walPath, walBytes: walBytes})
+			}
+		}
+	}
+	return pending, nil
+}
+
+// dirSize returns the size of the files in a directory. A missing directory
+// is empty.
+func dirSize(path string) (int64, error) {
+	fis, err := ioutil.ReadDir(path)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	var n int64
+	for _, fi := range fis {
+		n += fi.Size()
+	}
+	return n, nil
+}
+
+// openShards opens the pending shards, replaying their WALs, with up to the
+// configured number of shards at once. The progress of the replay is logged
+// every replayLogInterval. It stops early if closing is closed.
+func (s *Store) openShards(pending []pendingShard, opts EngineOptions, closing chan struct{}) error {
+	n := s.EngineOptions.Config.ReplayConcurrency
+	if n < 1 {
+		n = 1
+	} else if n > len(pending) {
+		n = len(pending)
+	}
+
+	start := time.Now()
+	var mu sync.Mutex
+	var firstErr error
+	logged := start
+
+	ch := make(chan pendingShard)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ps := range ch {
+				err := s.openPendingShard(ps, opts)
+
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				if now := time.Now(); now.Sub(logged) >= replayLogInterval {
+					if st := s.replay.Status(now); !st.Done {
+						s.Logger.Info("replaying shards",
+							"shards", fmt.Sprintf("%d/%d", st.ShardsOpened, st.ShardsTotal),
+							"segments", fmt.Sprintf("%d/%d", st.SegmentsReplayed, st.SegmentsTotal),
+							"points", st.PointsReplayed, "eta", st.ETA-st.ETA%time.Second)
+					}
+					logged = now
 				}
-				s.shards[shardID] = shard
+				mu.Unlock()
 			}
+		}()
+	}
+
+	// Hand out the shards until they're all opened, one fails, or the
+	// store is closed.
+	for _, ps := range pending {
+		mu.Lock()
+		err := firstErr
+		mu.Unlock()
+		if err != nil {
+			break
+		}
+
+		select {
+		case ch <- ps:
+			continue
+		case <-closing:
+		}
+		break
+	}
+	close(ch)
+	wg.Wait()
+
+	// The shards which weren't handed out are no longer opening, so writes
+	// to them and the replay aren't waited for forever.
+	if st := s.replay.Status(time.Now()); !st.Done {
+		s.mu.Lock()
+		for _, ps := range pending {
+			delete(s.opening, ps.id)
+		}
+		s.mu.Unlock()
+		s.replay.stopped(firstErr)
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+
+	if len(pending) > 0 {
+		st := s.replay.Status(time.Now())
+		s.Logger.Info("opened shards", "shards", st.ShardsOpened, "segments", st.SegmentsReplayed,
+			"points", st.PointsReplayed, "workers", n, "duration", time.Since(start))
+	}
+	return nil
+}
+
+// openPendingShard opens a pending shard and adds it to the store. A shard
+// which is corrupt is moved aside so the rest of the store can still be used.
+// Other errors, such as running out of file descriptors, are returned so the
+// shard is opened again once they're fixed.
+func (s *Store) openPendingShard(ps pendingShard, opts EngineOptions) error {
+	s.mu.RLock()
+	index := s.databaseIndexes[ps.database]
+	s.mu.RUnlock()
+
+	shard := NewShard(ps.id, index, ps.path, ps.walPath, opts)
+	err := s.openShard(shard)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.opening, ps.id)
+	s.replay.shardOpened(ps.walBytes)
+	if err != nil && isTransientOpenError(err) {
+		return fmt.Errorf("failed to open shard %d: %s", ps.id, err)
+	} else if err != nil {
+		s.Logger.Error("Quarantining shard", "database", ps.database, "shard", ps.id, "error", err)
+		if err := s.quarantineShard(shard); err != nil {
+			return fmt.Errorf("failed to quarantine shard %d: %s", ps.id, err)
+		}
+		return nil
+	}
+	s.shards[ps.id] = shard
+	s.Logger.Debug("opened shard", "database", ps.database, "shard", ps.id)
+	return nil
+}
+
+// shardOpening returns true if the shard is still being opened. The store's
+// lock must be held.
+func (s *Store) shardOpening(id uint64) bool {
+	_, ok := s.opening[id]
+	return ok
+}
+
+// databaseOpening returns true if the database has shards still being
+// opened. The store's lock must be held.
+func (s *Store) databaseOpening(database string) bool {
+	for _, db := range s.opening {
+		if db == database {
+			return true
+		}
+	}
+	return false
+}
+
+// ReplayStatus returns the progress of opening the shards of the store and
+// replaying their WALs.
+func (s *Store) ReplayStatus() ReplayStatus {
+	s.mu.RLock()
+	p := s.replay
+	s.mu.RUnlock()
+	return p.Status(time.Now())
+}
+
+// openShard opens sh and verifies it if the store is configured to. Panics
+// of the engine are returned as errors. The shard is closed if it fails.
+func (s *Store) openShard(sh *Shard) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			sh.Close()
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	if err := sh.Open(); err != nil {
+		return err
+	}
+	if s.EngineOptions.Config.VerifyShardsOnOpen {
+		if err := sh.Verify(); err != nil {
+			sh.Close()
+			return fmt.Errorf("verify: %s", err)
 		}
 	}
 	return nil
+}
+
+// isTransientOpenError returns true if err opening a shard isn't caused by
+// its files being corrupt: the lock of its data file is held by another
+// process, or files can't be opened for lack of permissions or resources.
+func isTransientOpenError(err error) bool {
+	if e, ok := err.(*openError); ok {
+		err = e.err
+	}
+	if err == bolt.ErrTimeout {
+		return true
+	}
 
+	switch e := err.(type) {
+	case *os.PathError:
+		err = e.Err
+	case *os.LinkError:
+		err = e.Err
+	case *os.SyscallError:
+		err = e.Err
+	}
+	switch err {
+	case syscall.EMFILE, syscall.ENFILE, syscall.EACCES, syscall.EPERM, syscall.ENOSPC, syscall.ENOMEM, syscall.EAGAIN:
+		return true
+	}
+	return false
+}
+
+// quarantineShard moves the data and WAL of sh aside, next to their
+// original paths.
+func (s *Store) quarantineShard(sh *Shard) error {
+	suffix := fmt.Sprintf("%s-%d", quarantineSuffix, time.Now().Unix())
+	if err := os.Rename(sh.path, sh.path+suffix); err != nil {
+		return err
+	}
+	if err := os.Rename(sh.walPath, sh.walPath+suffix); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	s.statMap.Add(statShardsQuarantined, 1)
+	return nil
 }
 
 func (s *Store) Open() error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 
 	s.closing = make(chan struct{})
 
 	s.shards = map[uint64]*Shard{}
 	s.databaseIndexes = map[string]*DatabaseIndex{}
 
-	s.Logger.Printf("Using data dir: %v", s.Path())
+	s.Logger.Info("Using data dir", "path", s.Path())
 
 	// Create directory.
 	if err := os.MkdirAll(s.path, 0777); err != nil {
+		s.mu.Unlock()
 		return err
 	}
 
 	// TODO: Start AE for Node
 	if err := s.loadIndexes(); err != nil {
+		s.mu.Unlock()
 		return err
 	}
 
-	if err := s.loadShards(); err != nil {
+	pending, err := s.listShards()
+	if err != nil {
+		s.mu.Unlock()
 		return err
 	}
 
+	// Track the replay of the WALs of the shards being opened.
+	var walBytes int64
+	s.opening = make(map[uint64]string, len(pending))
+	for _, ps := range pending {
+		walBytes += ps.walBytes
+		s.opening[ps.id] = ps.database
+	}
+	s.replay = newReplayProgress(len(pending), walBytes, time.Now())
+	opts := s.EngineOptions
+	opts.Replay = s.replay
+
+	c := s.EngineOptions.Config
+	s.throttler.set(WriteThrottles{
+		Global: WriteThrottle{PointsPerSecond: c.WriteThrottlePoints, BytesPerSecond: c.WriteThrottleBytes},
+		Shard:  WriteThrottle{PointsPerSecond: c.ShardWriteThrottlePoints, BytesPerSecond: c.ShardWriteThrottleBytes},
+	})
+
+	if d := time.Duration(c.CompactFullWriteColdDuration); d > 0 {
+		s.wg.Add(1)
+		go s.compactColdLoop(d, s.closing)
+	}
+
+	// Shards opened in the background are served as they're opened.
+	closing := s.closing
+	if c.ReplayInBackground {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			if err := s.openShards(pending, opts, closing); err != nil {
+				s.Logger.Error("failed to open shards", "error", err)
+			}
+		}()
+	}
+	s.mu.Unlock()
+
+	if !c.ReplayInBackground {
+		return s.openShards(pending, opts, closing)
+	}
 	return nil
 }
 
+// compactColdLoop fully compacts the shards which are cold for writes until
+// closing is closed.
+func (s *Store) compactColdLoop(d time.Duration, closing chan struct{}) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(compactColdCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closing:
+			return
+		case <-ticker.C:
+			s.compactColdShards(d, time.Now())
+		}
+	}
+}
+
+// compactColdShards fully compacts the shards which weren't written to in the
+// d before now. A shard is compacted once until it is written to again.
+func (s *Store) compactColdShards(d time.Duration, now time.Time) {
+	s.mu.RLock()
+	shards := make([]*Shard, 0, len(s.shards))
+	for _, sh := range s.shards {
+		shards = append(shards, sh)
+	}
+	s.mu.RUnlock()
+
+	compacted := make(map[uint64]time.Time, len(shards))
+	for _, sh := range shards {
+		t := sh.LastModified()
+		if last, ok := s.compacted[sh.id]; ok && last.Equal(t) {
+			compacted[sh.id] = t
+			continue
+		} else if now.Sub(t) < d {
+			continue
+		}
+
+		if err := sh.CompactFull(); err != nil && err != ErrCompactNotSupported {
+			s.Logger.Error("full compaction failed", "shard", sh.id, "error", err)
+			continue
+		} else if err == nil {
+			s.Logger.Info("fully compacted cold shard", "shard", sh.id, "last_modified", t)
+			s.statMap.Add(statShardsCompacted, 1)
+		}
+		compacted[sh.id] = t
+	}
+	s.compacted = compacted
+}
+
+// WriteThrottles returns the throttles of the writes to the store.
+func (s *Store) WriteThrottles() WriteThrottles { return s.throttler.get() }
+
+// SetWriteThrottles replaces the throttles of the writes to the store.
+// It is safe to call while points are being written.
+func (s *Store) SetWriteThrottles(t WriteThrottles) { s.throttler.set(t) }
+
 func (s *Store) WriteToShard(shardID uint64, points []Point) error {
+	if err := s.waitWriteThrottles(shardID, points); err != nil {
+		return err
+	}
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	sh, ok := s.shards[shardID]
 	if !ok {
+		if s.shardOpening(shardID) {
+			return ErrShardOpening
+		}
 		return ErrShardNotFound
 	}
 
 	return sh.WritePoints(points)
 }
 
+// CreateSeries adds series keys to the index of a shard without writing points,
+// creating the shard if it's not open on this node yet.
+func (s *Store) CreateSeries(database, retentionPolicy string, shardID uint64, keys []string) error {
+	s.mu.RLock()
+	sh := s.shards[shardID]
+	s.mu.RUnlock()
+	if sh == nil {
+		if err := s.CreateShard(database, retentionPolicy, shardID); err != nil {
+			return err
+		}
+		s.mu.RLock()
+		sh = s.shards[shardID]
+		s.mu.RUnlock()
+	}
+	return sh.CreateSeries(keys)
+}
+
+// waitWriteThrottles waits until a write to a shard is within the write
+// throttles. It waits without holding the store's lock.
+func (s *Store) waitWriteThrottles(shardID uint64, points []Point) error {
+	s.mu.RLock()
+	_, ok := s.shards[shardID]
+	closing := s.closing
+	s.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	d, ok := s.throttler.reserve(shardID, len(points), pointsSize(points), time.Now(), s.WriteTimeout)
+	if !ok {
+		s.statMap.Add(statWritesRejected, 1)
+		return ErrWriteThrottled
+	} else if d <= 0 {
+		return nil
+	}
+	s.statMap.Add(statWritesThrottled, 1)
+	s.statMap.Add(statWriteThrottleWait, int64(d))
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-closing:
+		return fmt.Errorf("closing")
+	}
+}
+
 func (s *Store) CreateMapper(shardID uint64, stmt influxql.Statement, chunkSize int) (Mapper, error) {
 	shard := s.Shard(shardID)
 
@@ -353,6 +1310,15 @@ func (s *Store) CreateMapper(shardID uint64, stmt influxql.Statement, chunkSize
 }
 
 func (s *Store) Close() error {
+	// Stop compacting shards before they're closed.
+	s.mu.Lock()
+	if s.closing != nil {
+		close(s.closing)
+	}
+	s.closing = nil
+	s.mu.Unlock()
+	s.wg.Wait()
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -361,10 +1327,6 @@ func (s *Store) Close() error {
 			return err
 		}
 	}
-	if s.closing != nil {
-		close(s.closing)
-	}
-	s.closing = nil
 	s.shards = nil
 	s.databaseIndexes = nil
 
@@ -1,12 +1,21 @@
 package tsdb_test
 
 import (
+	"bytes"
+	"expvar"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"testing"
 	"time"
 
+	"github.com/boltdb/bolt"
+	"github.com/influxdb/influxdb"
+	"github.com/influxdb/influxdb/influxql"
 	"github.com/influxdb/influxdb/tsdb"
 )
 
@@ -219,6 +228,101 @@ func TestStoreOpenShardBadShardPath(t *testing.T) {
 
 }
 
+// Ensure a shard which can't be opened is quarantined instead of failing the store.
+func TestStoreOpenShardQuarantine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store_test")
+	if err != nil {
+		t.Fatalf("Store.Open() failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "mydb", "myrp")
+	if err := os.MkdirAll(path, 0700); err != nil {
+		t.Fatalf("Store.Open() failed to create test db dir: %v", err)
+	}
+
+	// Corrupt shard data.
+	shardPath := filepath.Join(path, "1")
+	if err := ioutil.WriteFile(shardPath, bytes.Repeat([]byte("garbage!"), 1024), 0600); err != nil {
+		t.Fatalf("Store.Open() failed to create test shard 1: %v", err)
+	}
+
+	s := tsdb.NewStore(dir)
+	s.EngineOptions.Config.WALDir = filepath.Join(dir, "wal")
+	if err := s.Open(); err != nil {
+		t.Fatalf("Store.Open() failed: %v", err)
+	}
+	defer s.Close()
+
+	if got, exp := s.ShardN(), 0; got != exp {
+		t.Fatalf("Store.Open() shard count mismatch: got %v, exp %v", got, exp)
+	}
+	if _, err := os.Stat(shardPath); !os.IsNotExist(err) {
+		t.Fatalf("expected shard to be moved aside: %v", err)
+	}
+	if matches, err := filepath.Glob(shardPath + ".quarantined-*"); err != nil || len(matches) != 1 {
+		t.Fatalf("expected quarantined shard: %v, %v", matches, err)
+	}
+
+	// The shard can be recreated in place.
+	if err := s.CreateShard("mydb", "myrp", 1); err != nil {
+		t.Fatalf("error creating shard: %v", err)
+	}
+}
+
+// Ensure a shard which can't be opened because its file is locked fails the
+// store instead of being quarantined, and the replay is finished with the
+// error instead of the shards left waiting staying opening.
+func TestStoreOpenShardLocked(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store_test")
+	if err != nil {
+		t.Fatalf("Store.Open() failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := tsdb.NewStore(dir)
+	s.EngineOptions.Config.WALDir = filepath.Join(dir, "wal")
+	if err := s.Open(); err != nil {
+		t.Fatalf("Store.Open() failed: %v", err)
+	}
+	for _, id := range []uint64{1, 2, 3} {
+		if err := s.CreateShard("mydb", "myrp", id); err != nil {
+			t.Fatalf("error creating shard: %v", err)
+		}
+	}
+	s.Close()
+
+	// Hold the lock of the shard's file.
+	shardPath := filepath.Join(dir, "mydb", "myrp", "1")
+	db, err := bolt.Open(shardPath, 0666, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	s = tsdb.NewStore(dir)
+	s.EngineOptions.Config.WALDir = filepath.Join(dir, "wal")
+	s.EngineOptions.Config.ReplayConcurrency = 1
+	if err := s.Open(); err == nil {
+		s.Close()
+		t.Fatal("expected error opening store")
+	}
+	defer s.Close()
+
+	if _, err := os.Stat(shardPath); err != nil {
+		t.Fatalf("expected shard to be kept: %v", err)
+	}
+
+	// The last shard isn't opened once the first one fails.
+	if st := s.ReplayStatus(); !st.Done || st.Err == "" || st.ShardsOpened != 2 {
+		t.Fatalf("unexpected replay status: %+v", st)
+	}
+	p, _ := tsdb.ParsePoints([]byte("cpu val=1"))
+	if err := s.WriteToShard(3, p); err != tsdb.ErrShardNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
 func TestStoreEnsureSeriesPersistedInNewShards(t *testing.T) {
 	dir, err := ioutil.TempDir("", "store_test")
 	if err != nil {
@@ -276,6 +380,493 @@ func TestStoreEnsureSeriesPersistedInNewShards(t *testing.T) {
 	}
 }
 
+// Ensure shards can be opened in the background, with the progress of the
+// replay of their WALs reported.
+func TestStoreOpenReplayInBackground(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store_test")
+	if err != nil {
+		t.Fatalf("Store.Open() failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := tsdb.NewStore(dir)
+	s.EngineOptions.Config.WALDir = filepath.Join(dir, "wal")
+	if err := s.Open(); err != nil {
+		t.Fatalf("Store.Open() failed: %v", err)
+	}
+	p, _ := tsdb.ParsePoints([]byte("cpu val=1\ncpu,host=a val=2"))
+	for _, id := range []uint64{1, 2} {
+		if err := s.CreateShard("foo", "default", id); err != nil {
+			t.Fatalf("error creating shard: %v", err)
+		} else if err := s.WriteToShard(id, p); err != nil {
+			t.Fatalf("error writing to shard: %v", err)
+		}
+	}
+	if st := s.ReplayStatus(); !st.Done {
+		t.Fatalf("unexpected replay status: %+v", st)
+	}
+	s.Close()
+
+	s = tsdb.NewStore(dir)
+	s.EngineOptions.Config.WALDir = filepath.Join(dir, "wal")
+	s.EngineOptions.Config.ReplayInBackground = true
+	if err := s.Open(); err != nil {
+		t.Fatalf("Store.Open() failed: %v", err)
+	}
+	defer s.Close()
+
+	timeout := time.After(10 * time.Second)
+	for !s.ReplayStatus().Done {
+		select {
+		case <-timeout:
+			t.Fatalf("timed out replaying: %+v", s.ReplayStatus())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	if st := s.ReplayStatus(); st.ShardsOpened != 2 || st.ShardsTotal != 2 || st.SegmentsReplayed != st.SegmentsTotal || st.PointsReplayed != 4 {
+		t.Fatalf("unexpected replay status: %+v", st)
+	} else if s.ShardN() != 2 {
+		t.Fatalf("unexpected shard count: %d", s.ShardN())
+	}
+}
+
+// Ensure the store replays the WALs of several shards at once.
+func TestStoreOpenReplayConcurrency(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store_test")
+	if err != nil {
+		t.Fatalf("Store.Open() failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := tsdb.NewStore(dir)
+	s.EngineOptions.Config.WALDir = filepath.Join(dir, "wal")
+	if err := s.Open(); err != nil {
+		t.Fatalf("Store.Open() failed: %v", err)
+	}
+	p, _ := tsdb.ParsePoints([]byte("cpu val=1\ncpu,host=a val=2"))
+	for id := uint64(1); id <= 6; id++ {
+		if err := s.CreateShard(fmt.Sprintf("db%d", id%2), "default", id); err != nil {
+			t.Fatalf("error creating shard: %v", err)
+		} else if err := s.WriteToShard(id, p); err != nil {
+			t.Fatalf("error writing to shard: %v", err)
+		}
+	}
+	s.Close()
+
+	s = tsdb.NewStore(dir)
+	s.EngineOptions.Config.WALDir = filepath.Join(dir, "wal")
+	s.EngineOptions.Config.ReplayConcurrency = 4
+	if err := s.Open(); err != nil {
+		t.Fatalf("Store.Open() failed: %v", err)
+	}
+	defer s.Close()
+
+	if st := s.ReplayStatus(); !st.Done || st.ShardsOpened != 6 || st.SegmentsReplayed != st.SegmentsTotal || st.PointsReplayed != 12 {
+		t.Fatalf("unexpected replay status: %+v", st)
+	} else if s.ShardN() != 6 {
+		t.Fatalf("unexpected shard count: %d", s.ShardN())
+	}
+	for _, db := range []string{"db0", "db1"} {
+		if n := s.DatabaseIndex(db).SeriesN(); n != 2 {
+			t.Fatalf("unexpected series count in %s: %d", db, n)
+		}
+	}
+}
+
+// Ensure a database can be renamed with its shards.
+func TestStoreRenameDatabase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store_test")
+	if err != nil {
+		t.Fatalf("Store.Open() failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := tsdb.NewStore(dir)
+	s.EngineOptions.Config.WALDir = filepath.Join(dir, "wal")
+	if err := s.Open(); err != nil {
+		t.Fatalf("Store.Open() failed: %v", err)
+	}
+	defer s.Close()
+
+	if err := s.CreateShard("foo", "default", 1); err != nil {
+		t.Fatalf("error creating shard: %v", err)
+	}
+	p, _ := tsdb.ParsePoints([]byte("cpu val=1"))
+	if err := s.WriteToShard(1, p); err != nil {
+		t.Fatalf("error writing to shard: %v", err)
+	}
+
+	if err := s.RenameDatabase("foo", "bar"); err != nil {
+		t.Fatalf("error renaming database: %v", err)
+	}
+
+	if s.DatabaseIndex("foo") != nil {
+		t.Fatal("expected database index for foo to be removed")
+	} else if d := s.DatabaseIndex("bar"); d == nil || d.Series("cpu") == nil {
+		t.Fatal("expected series cpu to be in the index of bar")
+	}
+	if got, exp := s.Shard(1).Path(), filepath.Join(dir, "bar", "default", "1"); got != exp {
+		t.Fatalf("shard path mismatch: got %v, exp %v", got, exp)
+	}
+}
+
+// Ensure a measurement can be renamed in all the shards of a database.
+func TestStoreRenameMeasurement(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store_test")
+	if err != nil {
+		t.Fatalf("Store.Open() failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := tsdb.NewStore(dir)
+	s.EngineOptions.Config.WALDir = filepath.Join(dir, "wal")
+	if err := s.Open(); err != nil {
+		t.Fatalf("Store.Open() failed: %v", err)
+	}
+	defer s.Close()
+
+	for id, data := range map[uint64]string{
+		1: "cpu,host=a val=1 10\ncpu,host=b val=2 20",
+		2: "cpu,host=a val=3 30\nmem val=4 40",
+	} {
+		if err := s.CreateShard("foo", "default", id); err != nil {
+			t.Fatalf("error creating shard: %v", err)
+		}
+		p, _ := tsdb.ParsePoints([]byte(data))
+		if err := s.WriteToShard(id, p); err != nil {
+			t.Fatalf("error writing to shard: %v", err)
+		}
+	}
+
+	if err := s.RenameMeasurement("foo", "cpu", "mem"); err == nil || err.Error() != "measurement already exists: mem" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.RenameMeasurement("foo", "cpu", "cpu_total"); err != nil {
+		t.Fatalf("error renaming measurement: %v", err)
+	}
+
+	if s.Measurement("foo", "cpu") != nil {
+		t.Fatal("expected measurement cpu to be removed")
+	} else if s.Measurement("foo", "cpu_total") == nil {
+		t.Fatal("expected measurement cpu_total to be in the index")
+	}
+
+	counts, err := s.PointCounts("foo", []string{"cpu,host=a", "cpu_total,host=a", "cpu_total,host=b"}, 0, 100)
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(counts, map[string]int64{"cpu_total,host=a": 2, "cpu_total,host=b": 1}) {
+		t.Fatalf("unexpected counts: %v", counts)
+	}
+}
+
+// Ensure the fields of a measurement are merged across shards.
+func TestStoreMeasurementFieldInfo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store_test")
+	if err != nil {
+		t.Fatalf("Store.Open() failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := tsdb.NewStore(dir)
+	s.EngineOptions.Config.WALDir = filepath.Join(dir, "wal")
+	if err := s.Open(); err != nil {
+		t.Fatalf("Store.Open() failed: %v", err)
+	}
+	defer s.Close()
+
+	for id, data := range map[uint64]string{
+		1: "cpu,host=a value=1,status=\"ok\" 10",
+		2: "cpu,host=a value=2i 20",
+	} {
+		if err := s.CreateShard("foo", "default", id); err != nil {
+			t.Fatalf("error creating shard: %v", err)
+		}
+		p, _ := tsdb.ParsePoints([]byte(data))
+		if err := s.WriteToShard(id, p); err != nil {
+			t.Fatalf("error writing to shard: %v", err)
+		}
+	}
+
+	if _, err := s.MeasurementFieldInfo("bar", "cpu"); err == nil || err.Error() != "database not found: bar" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fields, err := s.MeasurementFieldInfo("foo", "cpu")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(fields) != 3 {
+		t.Fatalf("unexpected fields: %v", fields)
+	}
+	for i, exp := range []tsdb.FieldInfo{
+		{Name: "status", Type: influxql.String},
+		{Name: "value", Type: influxql.Float},
+		{Name: "value", Type: influxql.Integer},
+	} {
+		if f := fields[i]; f.Name != exp.Name || f.Type != exp.Type || f.LastWritten.IsZero() {
+			t.Fatalf("%d. unexpected field: %+v", i, f)
+		}
+	}
+
+	// Fields not written since the shard was opened have no last written time.
+	s.Close()
+	s2 := tsdb.NewStore(dir)
+	s2.EngineOptions.Config.WALDir = filepath.Join(dir, "wal")
+	if err := s2.Open(); err != nil {
+		t.Fatalf("Store.Open() failed: %v", err)
+	}
+	defer s2.Close()
+	if fields, err := s2.MeasurementFieldInfo("foo", "cpu"); err != nil {
+		t.Fatal(err)
+	} else if len(fields) != 3 || !fields[0].LastWritten.IsZero() {
+		t.Fatalf("unexpected fields: %v", fields)
+	}
+}
+
+// Ensure the estimated series counts are reported in the statistics of the
+// database and of its measurements until they're dropped.
+func TestStoreSeriesNEstimateStatistics(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store_test")
+	if err != nil {
+		t.Fatalf("Store.Open() failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := tsdb.NewStore(dir)
+	s.EngineOptions.Config.WALDir = filepath.Join(dir, "wal")
+	if err := s.Open(); err != nil {
+		t.Fatalf("Store.Open() failed: %v", err)
+	}
+	defer s.Close()
+
+	if err := s.CreateShard("estdb", "default", 1); err != nil {
+		t.Fatalf("error creating shard: %v", err)
+	}
+	p, _ := tsdb.ParsePoints([]byte("cpu,host=a value=1 10\ncpu,host=b value=1 10\nmem,host=a value=1 10"))
+	if err := s.WriteToShard(1, p); err != nil {
+		t.Fatalf("error writing to shard: %v", err)
+	}
+
+	if v := influxdb.DatabaseStatistics("estdb").Get("series_n_est"); v == nil || v.String() != "3" {
+		t.Fatalf("unexpected database series: %v", v)
+	} else if v := statisticsValues("measurement_series:estdb").Get("cpu"); v == nil || v.String() != "2" {
+		t.Fatalf("unexpected measurement series: %v", v)
+	}
+
+	if err := s.DeleteDatabase("estdb", []uint64{1}); err != nil {
+		t.Fatal(err)
+	}
+	if v := influxdb.DatabaseStatistics("estdb").Get("series_n_est"); v == nil || v.String() != "0" {
+		t.Fatalf("unexpected database series after drop: %v", v)
+	} else if v := statisticsValues("measurement_series:estdb").Get("cpu"); v != nil {
+		t.Fatalf("unexpected measurement series after drop: %v", v)
+	}
+}
+
+// statisticsValues returns the values of the statistics registered as key.
+func statisticsValues(key string) *expvar.Map {
+	return expvar.Get(key).(*expvar.Map).Get("values").(*expvar.Map)
+}
+
+// Ensure writes wait for the write throttles, which can be changed at runtime.
+func TestStoreWriteThrottles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store_test")
+	if err != nil {
+		t.Fatalf("Store.Open() failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := tsdb.NewStore(dir)
+	s.EngineOptions.Config.WALDir = filepath.Join(dir, "wal")
+	s.EngineOptions.Config.ShardWriteThrottlePoints = 20
+	if err := s.Open(); err != nil {
+		t.Fatalf("Store.Open() failed: %v", err)
+	}
+	defer s.Close()
+
+	if err := s.CreateShard("foo", "default", 1); err != nil {
+		t.Fatalf("error creating shard: %v", err)
+	}
+	points := func(n int) []tsdb.Point {
+		var buf bytes.Buffer
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(&buf, "cpu value=%d %d\n", i, i)
+		}
+		a, err := tsdb.ParsePoints(buf.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+
+	// A second's worth of points is written right away, the next ones wait.
+	start := time.Now()
+	if err := s.WriteToShard(1, points(20)); err != nil {
+		t.Fatal(err)
+	} else if err := s.WriteToShard(1, points(10)); err != nil {
+		t.Fatal(err)
+	} else if d := time.Since(start); d < 400*time.Millisecond {
+		t.Fatalf("expected write to be throttled: %s", d)
+	}
+
+	// Writes which would wait longer than the write timeout are rejected.
+	s.WriteTimeout = 100 * time.Millisecond
+	start = time.Now()
+	if err := s.WriteToShard(1, points(100)); err != tsdb.ErrWriteThrottled {
+		t.Fatalf("unexpected error: %v", err)
+	} else if d := time.Since(start); d > 100*time.Millisecond {
+		t.Fatalf("unexpected wait: %s", d)
+	}
+	s.WriteTimeout = 0
+
+	// Writes to a shard without a throttle don't wait.
+	s.SetWriteThrottles(tsdb.WriteThrottles{
+		Shard:  tsdb.WriteThrottle{PointsPerSecond: 1},
+		Shards: map[uint64]tsdb.WriteThrottle{1: {}},
+	})
+	if th := s.WriteThrottles(); th.Shard.PointsPerSecond != 1 || len(th.Shards) != 1 {
+		t.Fatalf("unexpected throttles: %+v", th)
+	}
+	start = time.Now()
+	if err := s.WriteToShard(1, points(100)); err != nil {
+		t.Fatal(err)
+	} else if d := time.Since(start); d > 500*time.Millisecond {
+		t.Fatalf("unexpected throttled write: %s", d)
+	}
+
+	// Closing the store stops throttled writes from waiting.
+	s.SetWriteThrottles(tsdb.WriteThrottles{Global: tsdb.WriteThrottle{BytesPerSecond: 1}})
+	errc := make(chan error)
+	go func() { errc <- s.WriteToShard(1, points(10)) }()
+	time.Sleep(100 * time.Millisecond)
+	s.Close()
+	select {
+	case err := <-errc:
+		if err == nil || err.Error() != "closing" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("throttled write didn't return")
+	}
+}
+
+// Ensure a tag key can be renamed and a tag value rewritten in the series of a measurement.
+func TestStoreRenameTag(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store_test")
+	if err != nil {
+		t.Fatalf("Store.Open() failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := tsdb.NewStore(dir)
+	s.EngineOptions.Config.WALDir = filepath.Join(dir, "wal")
+	if err := s.Open(); err != nil {
+		t.Fatalf("Store.Open() failed: %v", err)
+	}
+	defer s.Close()
+
+	if err := s.CreateShard("foo", "default", 1); err != nil {
+		t.Fatalf("error creating shard: %v", err)
+	}
+	p, _ := tsdb.ParsePoints([]byte("cpu,host=a,region=us-east val=1 10\ncpu,host=b,region=us-east-1 val=2 20\ncpu,region=us-east val=3 30"))
+	if err := s.WriteToShard(1, p); err != nil {
+		t.Fatalf("error writing to shard: %v", err)
+	}
+
+	if _, err := s.RenameTagKey("foo", "cpu", "host", "region"); err == nil || err.Error() != "tag key already exists: region" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats, err := s.RenameTagKey("foo", "cpu", "host", "hostname"); err != nil {
+		t.Fatalf("error renaming tag key: %v", err)
+	} else if stats != (tsdb.RewriteStats{SeriesN: 2, PointN: 2}) {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if _, err := s.RenameTagValue("foo", "cpu", "region", "us-east", ""); err != tsdb.ErrEmptyTagValue {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats, err := s.RenameTagValue("foo", "cpu", "region", "us-east", "us-east-1"); err != nil {
+		t.Fatalf("error renaming tag value: %v", err)
+	} else if stats != (tsdb.RewriteStats{SeriesN: 2, PointN: 2}) {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	keys := s.Measurement("foo", "cpu").SeriesKeys()
+	sort.Strings(keys)
+	if exp := []string{"cpu,hostname=a,region=us-east-1", "cpu,hostname=b,region=us-east-1", "cpu,region=us-east-1"}; !reflect.DeepEqual(keys, exp) {
+		t.Fatalf("unexpected series: %v", keys)
+	}
+
+	counts, err := s.PointCounts("foo", keys, 0, 100)
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(counts, map[string]int64{"cpu,hostname=a,region=us-east-1": 1, "cpu,hostname=b,region=us-east-1": 1, "cpu,region=us-east-1": 1}) {
+		t.Fatalf("unexpected counts: %v", counts)
+	}
+}
+
+// Ensure a shard can be restored from the data written by another store.
+func TestStoreRestoreShard(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store_test")
+	if err != nil {
+		t.Fatalf("Store.Open() failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := tsdb.NewStore(filepath.Join(dir, "src"))
+	src.EngineOptions.Config.WALDir = filepath.Join(dir, "src", "wal")
+	if err := src.Open(); err != nil {
+		t.Fatalf("Store.Open() failed: %v", err)
+	}
+	defer src.Close()
+
+	if err := src.CreateShard("foo", "default", 1); err != nil {
+		t.Fatalf("error creating shard: %v", err)
+	}
+	p, _ := tsdb.ParsePoints([]byte("cpu,host=a val=1\ncpu,host=b val=2"))
+	if err := src.WriteToShard(1, p); err != nil {
+		t.Fatalf("error writing to shard: %v", err)
+	}
+
+	// Hide bytes.Buffer's ReadFrom so bolt can read with direct I/O.
+	var buf bytes.Buffer
+	if _, err := src.Shard(1).WriteTo(struct{ io.Writer }{&buf}); err != nil {
+		t.Fatalf("error writing shard: %v", err)
+	}
+
+	dst := tsdb.NewStore(filepath.Join(dir, "dst"))
+	dst.EngineOptions.Config.WALDir = filepath.Join(dir, "dst", "wal")
+	if err := dst.Open(); err != nil {
+		t.Fatalf("Store.Open() failed: %v", err)
+	}
+	defer dst.Close()
+
+	data := buf.Bytes()
+	if err := dst.RestoreShard("foo", "default", 1, bytes.NewReader(data)); err != nil {
+		t.Fatalf("error restoring shard: %v", err)
+	}
+
+	if n, err := dst.Shard(1).SeriesCount(); err != nil {
+		t.Fatal(err)
+	} else if n != 2 {
+		t.Fatalf("unexpected series count: %d", n)
+	}
+	if d := dst.DatabaseIndex("foo"); d == nil || d.Series("cpu,host=a") == nil {
+		t.Fatal("expected series cpu,host=a to be in the index")
+	}
+
+	// Restoring over an existing shard should fail.
+	if err := dst.RestoreShard("foo", "default", 1, bytes.NewReader(data)); err == nil {
+		t.Fatal("expected error restoring existing shard")
+	}
+
+	// Truncated data should not leave a shard behind.
+	if err := dst.RestoreShard("foo", "default", 2, bytes.NewReader(data[:len(data)/2])); err == nil {
+		t.Fatal("expected error restoring truncated shard")
+	} else if dst.Shard(2) != nil {
+		t.Fatal("unexpected shard 2")
+	}
+}
+
 func BenchmarkStoreOpen_200KSeries_100Shards(b *testing.B) { benchmarkStoreOpen(b, 64, 5, 5, 1, 100) }
 
 func benchmarkStoreOpen(b *testing.B, mCnt, tkCnt, tvCnt, pntCnt, shardCnt int) {
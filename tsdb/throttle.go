@@ -0,0 +1,187 @@
+package tsdb
+
+import (
+	"sync"
+	"time"
+)
+
+// WriteThrottle is the rate points may be written at. Zero rates are unlimited.
+type WriteThrottle struct {
+	PointsPerSecond int `json:"pointsPerSecond"`
+	BytesPerSecond  int `json:"bytesPerSecond"`
+}
+
+// WriteThrottles are the throttles of the writes to a store. Writes wait until
+// both the global throttle, shared by all shards, and the throttle of the shard
+// allow them.
+type WriteThrottles struct {
+	Global WriteThrottle `json:"global"`
+
+	// Shard is the throttle of each shard not in Shards.
+	Shard  WriteThrottle            `json:"shard"`
+	Shards map[uint64]WriteThrottle `json:"shards,omitempty"`
+}
+
+// writeThrottler delays writes to keep them within the write throttles.
+// The zero value is ready to use and delays nothing.
+type writeThrottler struct {
+	mu        sync.Mutex
+	throttles WriteThrottles
+	global    *throttleBuckets
+	shards    map[uint64]*throttleBuckets
+}
+
+// set replaces the throttles. Buckets are refilled, so writes already waiting
+// keep their delays but later writes are throttled at the new rates.
+func (t *writeThrottler) set(throttles WriteThrottles) {
+	shards := make(map[uint64]WriteThrottle, len(throttles.Shards))
+	for id, st := range throttles.Shards {
+		shards[id] = st
+	}
+	throttles.Shards = shards
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.throttles = throttles
+	t.global = newThrottleBuckets(throttles.Global, time.Now())
+	t.shards = make(map[uint64]*throttleBuckets)
+}
+
+// get returns a copy of the throttles.
+func (t *writeThrottler) get() WriteThrottles {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	throttles := t.throttles
+	throttles.Shards = make(map[uint64]WriteThrottle, len(t.throttles.Shards))
+	for id, st := range t.throttles.Shards {
+		throttles.Shards[id] = st
+	}
+	return throttles
+}
+
+// reserve takes the points and bytes of a write to a shard from its buckets and
+// returns how long the write has to wait before it's within the throttles. If
+// max is set and the write would wait longer, nothing is taken and false is
+// returned, so rejected writes don't delay the later ones.
+func (t *writeThrottler) reserve(shardID uint64, points, bytes int, now time.Time, max time.Duration) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b := t.shards[shardID]
+	if b == nil {
+		st, ok := t.throttles.Shards[shardID]
+		if !ok {
+			st = t.throttles.Shard
+		}
+		b = newThrottleBuckets(st, now)
+		if t.shards == nil {
+			t.shards = make(map[uint64]*throttleBuckets)
+		}
+		t.shards[shardID] = b
+	}
+
+	d := b.reserve(points, bytes, now)
+	if t.global != nil {
+		if gd := t.global.reserve(points, bytes, now); gd > d {
+			d = gd
+		}
+	}
+	if max > 0 && d > max {
+		b.refund(points, bytes)
+		if t.global != nil {
+			t.global.refund(points, bytes)
+		}
+		return d, false
+	}
+	return d, true
+}
+
+// remove removes the buckets of a shard.
+func (t *writeThrottler) remove(shardID uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.shards, shardID)
+}
+
+// throttleBuckets are the buckets of a throttle. Unlimited rates have none.
+type throttleBuckets struct {
+	points *reserveBucket
+	bytes  *reserveBucket
+}
+
+func newThrottleBuckets(t WriteThrottle, now time.Time) *throttleBuckets {
+	b := &throttleBuckets{}
+	if t.PointsPerSecond > 0 {
+		b.points = newReserveBucket(float64(t.PointsPerSecond), now)
+	}
+	if t.BytesPerSecond > 0 {
+		b.bytes = newReserveBucket(float64(t.BytesPerSecond), now)
+	}
+	return b
+}
+
+// reserve returns the longest delay of the buckets for a write.
+func (b *throttleBuckets) reserve(points, bytes int, now time.Time) time.Duration {
+	var d time.Duration
+	if b.points != nil {
+		d = b.points.reserve(float64(points), now)
+	}
+	if b.bytes != nil {
+		if bd := b.bytes.reserve(float64(bytes), now); bd > d {
+			d = bd
+		}
+	}
+	return d
+}
+
+// refund puts back the tokens of a write reserved at the same time.
+func (b *throttleBuckets) refund(points, bytes int) {
+	if b.points != nil {
+		b.points.tokens += float64(points)
+	}
+	if b.bytes != nil {
+		b.bytes.tokens += float64(bytes)
+	}
+}
+
+// reserveBucket is a token bucket refilling at rate tokens per second, up to
+// one second's worth. Unlike a bucket that rejects takes when it's empty,
+// takes always succeed and put the bucket in debt, and the caller waits until
+// the debt is refilled. Concurrent writers are delayed in turn.
+type reserveBucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// newReserveBucket returns a full bucket refilling at rate tokens per second.
+func newReserveBucket(rate float64, now time.Time) *reserveBucket {
+	return &reserveBucket{rate: rate, tokens: rate, last: now}
+}
+
+// reserve takes n tokens at now and returns how long until the bucket is out
+// of debt.
+func (b *reserveBucket) reserve(n float64, now time.Time) time.Duration {
+	if now.After(b.last) {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.rate {
+			b.tokens = b.rate
+		}
+		b.last = now
+	}
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// pointsSize returns the size of points as they're stored: the series key,
+// the timestamp and the encoded fields of each point.
+func pointsSize(points []Point) int {
+	var n int
+	for _, p := range points {
+		n += len(p.Key()) + 8 + len(p.Data())
+	}
+	return n
+}